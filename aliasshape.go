@@ -0,0 +1,132 @@
+package codegenutil
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// aliasShape holds the rules set by the alias shape options. The zero value
+// leaves suggested names unchanged.
+type aliasShape struct {
+	maxLength      int
+	ascii          bool
+	prefix, suffix string
+}
+
+// MaxAliasLength returns an option that shortens the local package names that
+// the returned *FileImports chooses to at most n bytes, keeping the numeric
+// suffixes that tell apart packages of the same name.
+func MaxAliasLength(n int) FileImportsOption {
+	return FileImportsOption{
+		func(fi *FileImports) { fi.aliasShape.maxLength = n },
+	}
+}
+
+// ASCIIAliases returns an option that makes the returned *FileImports
+// transliterate the local names of packages whose names aren't ASCII, such as
+// "café", which is imported as "cafe". Letters that have no transliteration
+// are dropped.
+func ASCIIAliases() FileImportsOption {
+	return FileImportsOption{
+		func(fi *FileImports) { fi.aliasShape.ascii = true },
+	}
+}
+
+// AliasAffixes returns an option that makes the returned *FileImports add a
+// prefix and a suffix to the aliases it chooses, that is to the local names
+// that differ from the names of their packages. For example, with the prefix
+// "pkg", a second package named "errors" is imported as "pkgerrors2".
+func AliasAffixes(prefix, suffix string) FileImportsOption {
+	return FileImportsOption{
+		func(fi *FileImports) { fi.aliasShape.prefix, fi.aliasShape.suffix = prefix, suffix },
+	}
+}
+
+// apply returns the local name to use for pkg instead of the suggested name.
+// Blank and dot imports are left as they are.
+func (s aliasShape) apply(pkg *Package, name string) string {
+	if s == (aliasShape{}) || name == "_" || name == "." {
+		return name
+	}
+	if s.ascii {
+		name = transliterate(name)
+	}
+	affixes := 0
+	isAlias := name != pkg.Name()
+	if isAlias {
+		affixes = len(s.prefix) + len(s.suffix)
+	}
+	if s.maxLength > 0 && len(name)+affixes > s.maxLength {
+		// A shortened package name is an alias, so it has room for the
+		// affixes too.
+		if !isAlias {
+			isAlias = true
+			affixes = len(s.prefix) + len(s.suffix)
+		}
+		// Keep the numeric suffix of the name, which may be all that tells
+		// it apart from another alias.
+		head := strings.TrimRightFunc(name, func(r rune) bool { return '0' <= r && r <= '9' })
+		digits := name[len(head):]
+		n := s.maxLength - affixes - len(digits)
+		if n < 1 {
+			n = 1
+		}
+		for n < len(head) && !utf8.RuneStart(head[n]) {
+			n--
+		}
+		if n < len(head) {
+			head = head[:n]
+		}
+		name = head + digits
+	}
+	if isAlias {
+		name = s.prefix + name + s.suffix
+	}
+	return name
+}
+
+// transliterations maps the Latin letters that aren't accented ASCII letters to
+// the ASCII letters they are usually transliterated to.
+var transliterations = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'Æ': "AE", 'œ': "oe", 'Œ': "OE", 'ø': "o", 'Ø': "O",
+	'ł': "l", 'Ł': "L", 'đ': "d", 'Đ': "D", 'ð': "d", 'þ': "th", 'ı': "i",
+}
+
+// accentedLetters maps common accented Latin letters to their ASCII base
+// letters.
+var accentedLetters = map[rune]rune{}
+
+func init() {
+	for base, accented := range map[rune]string{
+		'a': "àáâãäå", 'A': "ÀÁÂÃÄÅ", 'c': "çćč", 'C': "ÇĆČ", 'e': "èéêëěę",
+		'E': "ÈÉÊËĚĘ", 'i': "ìíîï", 'I': "ÌÍÎÏ", 'n': "ñńň", 'N': "ÑŃŇ",
+		'o': "òóôõöő", 'O': "ÒÓÔÕÖŐ", 'u': "ùúûüůű", 'U': "ÙÚÛÜŮŰ", 'y': "ýÿ",
+		'Y': "Ý", 's': "śš", 'S': "ŚŠ", 'z': "źżž", 'Z': "ŹŻŽ", 'r': "ř",
+		'R': "Ř", 'g': "ğ", 'G': "Ğ",
+	} {
+		for _, r := range accented {
+			accentedLetters[r] = base
+		}
+	}
+}
+
+// transliterate returns name with its letters transliterated to ASCII, or
+// "pkg" if nothing is left of it.
+func transliterate(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case r < utf8.RuneSelf:
+			b.WriteRune(r)
+		case accentedLetters[r] != 0:
+			b.WriteRune(accentedLetters[r])
+		case transliterations[r] != "":
+			b.WriteString(transliterations[r])
+		}
+	}
+	out := strings.TrimLeft(b.String(), "0123456789")
+	if out == "" {
+		return "pkg"
+	}
+	return out
+}
@@ -0,0 +1,83 @@
+package codegenutil
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAliasShape(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        []FileImportsOption
+		importPaths []string
+		// names are the suggested local names of importPaths, if any.
+		names []string
+		want  string
+	}{
+		{
+			name:        "no options",
+			importPaths: []string{"a/errors", "b/errors", "example.com/café"},
+			want:        "errors errors2 café",
+		},
+		{
+			name:        "prefix",
+			opts:        []FileImportsOption{AliasAffixes("pkg", "")},
+			importPaths: []string{"a/errors", "b/errors", "example.com/len"},
+			want:        "errors pkgerrors2 pkglen2",
+		},
+		{
+			name:        "ascii",
+			opts:        []FileImportsOption{ASCIIAliases()},
+			importPaths: []string{"example.com/café", "example.com/straße", "example.com/日本"},
+			want:        "cafe strasse pkg",
+		},
+		{
+			name:        "max length",
+			opts:        []FileImportsOption{MaxAliasLength(6)},
+			importPaths: []string{"a/configuration", "b/configuration", "c/short"},
+			want:        "config confi2 short",
+		},
+		{
+			name:        "max length with prefix",
+			opts:        []FileImportsOption{MaxAliasLength(8), AliasAffixes("pkg", "")},
+			importPaths: []string{"a/configuration", "b/configuration", "c/short"},
+			want:        "pkgconfi pkgconf2 short",
+		},
+		{
+			name:        "all",
+			opts:        []FileImportsOption{MaxAliasLength(8), ASCIIAliases(), AliasAffixes("x", "_")},
+			importPaths: []string{"example.com/évènements", "b/events", "c/events"},
+			want:        "xevenem_ events xevent2_",
+		},
+		{
+			name:        "blank and dot imports",
+			opts:        []FileImportsOption{MaxAliasLength(1), ASCIIAliases(), AliasAffixes("pkg", "")},
+			importPaths: []string{"embed", "example.com/x", "example.com/café"},
+			names:       []string{"_", "_", "."},
+			want:        "_ _ .",
+		},
+		{
+			name:        "explicit aliases",
+			opts:        []FileImportsOption{MaxAliasLength(4), AliasAffixes("pkg", "")},
+			importPaths: []string{"github.com/pkg/errors", "example.com/configuration"},
+			names:       []string{"myerrs", "configuration"},
+			want:        "myerrs configuration",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imports := NewFileImports(AssumedPackageName("abc/xyz"), tt.opts...)
+			var got []string
+			for i, importPath := range tt.importPaths {
+				name := ""
+				if i < len(tt.names) {
+					name = tt.names[i]
+				}
+				got = append(got, imports.Add(AssumedPackageName(importPath), name).FileLocalPackageName())
+			}
+			if strings.Join(got, " ") != tt.want {
+				t.Errorf("got local names %q, want %q", strings.Join(got, " "), tt.want)
+			}
+		})
+	}
+}
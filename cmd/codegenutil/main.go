@@ -0,0 +1,112 @@
+// Command codegenutil provides tools for writing code generators with this
+// module.
+//
+// Usage:
+//
+//	codegenutil new-generator [-C dir] [-name name] -output dir dir
+//
+// The new-generator command creates the skeleton of a generator in the given
+// directory of the module rooted at the directory given by -C, which defaults
+// to the current directory. See package scaffold for the files it creates. The
+// -output flag is the directory of the package that the generator generates
+// files into. Both directories are relative to the root of the module.
+// Existing files are never overwritten.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/meta-programming/go-codegenutil/project"
+	"github.com/meta-programming/go-codegenutil/scaffold"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+const usage = "usage: codegenutil new-generator [-C dir] [-name name] -output dir dir"
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] != "new-generator" {
+		fmt.Fprintln(stderr, usage)
+		return 2
+	}
+	flags := flag.NewFlagSet("new-generator", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	root := flags.String("C", ".", "root `dir`ectory of the module")
+	name := flags.String("name", "", "`name` of the generator; the last element of its directory by default")
+	output := flags.String("output", "", "`dir`ectory of the generated package, relative to the module root (required)")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 || *output == "" {
+		fmt.Fprintln(stderr, usage)
+		flags.PrintDefaults()
+		return 2
+	}
+	dir := filepath.ToSlash(flags.Arg(0))
+	if err := newGenerator(*root, scaffold.Config{Name: *name, Dir: dir, OutputDir: filepath.ToSlash(*output)}, stdout); err != nil {
+		fmt.Fprintf(stderr, "codegenutil: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func newGenerator(root string, cfg scaffold.Config, stdout io.Writer) error {
+	module, err := modulePath(filepath.Join(root, "go.mod"))
+	if err != nil {
+		return err
+	}
+	cfg.Module = module
+	p := project.New(root)
+	if err := project.Run(context.Background(), p, nil, scaffold.Generator(cfg)); err != nil {
+		return err
+	}
+	for _, f := range p.Files() {
+		name := filepath.Join(root, filepath.FromSlash(f.Path))
+		if _, err := os.Stat(name); err == nil {
+			return fmt.Errorf("%s already exists", name)
+		}
+	}
+	if err := p.Write(); err != nil {
+		return err
+	}
+	for _, f := range p.Files() {
+		fmt.Fprintf(stdout, "created %s\n", f.Path)
+	}
+	fmt.Fprintf(stdout, "run \"go generate ./%s\" to generate the files of the example template\n", cfg.Dir)
+	return nil
+}
+
+// modulePath returns the module path declared by a go.mod file.
+func modulePath(goMod string) (string, error) {
+	content, err := os.ReadFile(goMod)
+	if err != nil {
+		return "", err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "module" {
+			if path, err := strconv.Unquote(fields[1]); err == nil {
+				return path, nil
+			}
+			return fields[1], nil
+		}
+	}
+	return "", errors.New(goMod + ": no module directive")
+}
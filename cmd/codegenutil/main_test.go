@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun_NewGenerator(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/m // comment\n\ngo 1.18\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	args := []string{"new-generator", "-C", root, "-output", "colors", "gen/colorgen"}
+	var stdout, stderr bytes.Buffer
+	if code := run(args, &stdout, &stderr); code != 0 {
+		t.Fatalf("run() exited with %d; stderr:\n%s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "created gen/colorgen/main.go\n") {
+		t.Errorf("run() printed %q, want it to list the created files", stdout.String())
+	}
+	main, err := os.ReadFile(filepath.Join(root, "gen", "colorgen", "main.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(main), `outputPackage = "example.com/m/colors"`) {
+		t.Errorf("main.go doesn't use the module path:\n%s", main)
+	}
+
+	stderr.Reset()
+	if code := run(args, &stdout, &stderr); code != 1 || !strings.Contains(stderr.String(), "already exists") {
+		t.Errorf("run() over existing files exited with %d and printed %q, want 1 and an error that files exist", code, stderr.String())
+	}
+	if code := run([]string{"unknown"}, &stdout, &stderr); code != 2 {
+		t.Errorf("run() with an unknown command exited with %d, want 2", code)
+	}
+}
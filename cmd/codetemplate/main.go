@@ -0,0 +1,236 @@
+// Command codetemplate executes a codetemplate template with data read from
+// JSON or YAML files and writes the generated Go file.
+//
+// Usage:
+//
+//	codetemplate -template file.go.tmpl -pkg import/path [-data file.json]... [-o file.go] [-go version] [-verify | -watch]
+//	codetemplate -template file.go.tmpl -schema text|go
+//
+// The data files are decoded by codetemplate.ReadData: they must be JSON or
+// YAML mappings, their top-level keys are merged, and mappings of the form
+// {"$symbol": "encoding/json.Marshal"} denote symbols.
+//
+// The -pkg flag is the import path of the package the generated file belongs
+// to; its name is assumed from the import path unless -pkgname is given.
+//
+// With -go, the generated file targets a version of Go, such as go1.20: code
+// that requires a newer version, such as type parameters or the symbols that
+// the standard library added later, fails the generation. See
+// codegenutil.WithGoVersion.
+//
+// With -watch, codetemplate keeps running after generating the output file and
+// generates it again whenever the template or a data file changes, until it is
+// interrupted.
+//
+// With -verify, the output file is not written. Instead, codetemplate exits
+// with status 1 and prints a diff if the file's content differs from the
+// generated code.
+//
+// With -schema, the template is not executed. Instead, codetemplate prints the
+// shape of the data that the template expects, as inferred by
+// codetemplate.Template.DataSchema: a line per value with -schema text, or a
+// Go type declaration with -schema go.
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/meta-programming/go-codegenutil"
+	cb "github.com/meta-programming/go-codegenutil/codebuilder"
+	"github.com/meta-programming/go-codegenutil/codetemplate"
+	"github.com/meta-programming/go-codegenutil/debugutil"
+	"github.com/meta-programming/go-codegenutil/symbolindex"
+	"github.com/meta-programming/go-codegenutil/watch"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// errStale is returned by generate when -verify finds an out of date file.
+var errStale = errors.New("generated file is out of date")
+
+// dataFiles is a flag that can be repeated.
+type dataFiles []string
+
+func (d *dataFiles) String() string { return strings.Join(*d, ",") }
+
+func (d *dataFiles) Set(value string) error {
+	*d = append(*d, value)
+	return nil
+}
+
+type options struct {
+	template    string
+	data        dataFiles
+	pkg         string
+	pkgName     string
+	output      string
+	verify      bool
+	watch       bool
+	keepImports bool
+	schema      string
+	goVersion   string
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("codetemplate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var opts options
+	fs.StringVar(&opts.template, "template", "", "template `file` to execute (required)")
+	fs.Var(&opts.data, "data", "JSON or YAML data `file`; may be repeated")
+	fs.StringVar(&opts.pkg, "pkg", "", "import `path` of the generated file's package (required)")
+	fs.StringVar(&opts.pkgName, "pkgname", "", "`name` of the generated file's package, if it differs from the one assumed from -pkg")
+	fs.StringVar(&opts.output, "o", "", "output `file`; the standard output if empty")
+	fs.BoolVar(&opts.verify, "verify", false, "check that the output file is up to date instead of writing it")
+	fs.BoolVar(&opts.watch, "watch", false, "regenerate the output file whenever the template or a data file changes")
+	fs.BoolVar(&opts.keepImports, "keep-unused-imports", false, "don't remove unused imports from the output")
+	fs.StringVar(&opts.goVersion, "go", "", "target Go `version` of the generated file, such as go1.20")
+	fs.StringVar(&opts.schema, "schema", "", "print the data that the template expects instead of executing it, in the `format` text or go")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if opts.schema != "" {
+		if opts.template == "" || fs.NArg() != 0 || (opts.schema != "text" && opts.schema != "go") {
+			fmt.Fprintln(stderr, "usage: codetemplate -template file -schema text|go")
+			fs.PrintDefaults()
+			return 2
+		}
+		if err := printSchema(&opts, stdout); err != nil {
+			fmt.Fprintf(stderr, "codetemplate: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	if opts.template == "" || opts.pkg == "" || fs.NArg() != 0 || (opts.goVersion != "" && !codegenutil.IsValidGoVersion(opts.goVersion)) || (opts.verify && opts.output == "") || (opts.watch && (opts.verify || opts.output == "")) {
+		fmt.Fprintln(stderr, "usage: codetemplate -template file -pkg import/path [-data file]... [-o file] [-go version] [-verify | -watch]")
+		fs.PrintDefaults()
+		return 2
+	}
+	err := generate(context.Background(), &opts, stdout)
+	if opts.watch {
+		// Keep watching after a failure, which the next change may fix.
+		if err != nil {
+			fmt.Fprintf(stderr, "codetemplate: %v\n", err)
+		}
+		return watchInputs(&opts, stdout, stderr)
+	}
+	if err != nil {
+		if !errors.Is(err, errStale) {
+			fmt.Fprintf(stderr, "codetemplate: %v\n", err)
+		}
+		return 1
+	}
+	return 0
+}
+
+// watchInputs regenerates the output file whenever an input changes, until
+// the command is interrupted.
+func watchInputs(opts *options, stdout, stderr io.Writer) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	task := &watch.Task{
+		Name:   opts.output,
+		Inputs: append([]string{opts.template}, opts.data...),
+		Run:    func(ctx context.Context) error { return generate(ctx, opts, stdout) },
+	}
+	fmt.Fprintf(stderr, "codetemplate: watching %s\n", strings.Join(task.Inputs, ", "))
+	err := watch.Watch(ctx, []*watch.Task{task}, watch.OnRun(func(t *watch.Task, err error) {
+		if err != nil {
+			fmt.Fprintf(stderr, "codetemplate: %v\n", err)
+		} else {
+			fmt.Fprintf(stderr, "codetemplate: regenerated %s\n", t.Name)
+		}
+	}))
+	if err != nil && !errors.Is(err, context.Canceled) {
+		fmt.Fprintf(stderr, "codetemplate: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// parse parses the template file.
+func parse(opts *options) (*codetemplate.Template, error) {
+	text, err := os.ReadFile(opts.template)
+	if err != nil {
+		return nil, err
+	}
+	var tmplOpts []codetemplate.Option
+	tmplOpts = append(tmplOpts, codetemplate.WithName(filepath.Base(opts.template)))
+	if opts.keepImports {
+		tmplOpts = append(tmplOpts, codetemplate.KeepUnusedImports())
+	}
+	return codetemplate.Parse(string(text), tmplOpts...)
+}
+
+// printSchema prints the data schema of the template in the -schema format.
+func printSchema(opts *options, stdout io.Writer) error {
+	tmpl, err := parse(opts)
+	if err != nil {
+		return err
+	}
+	schema := tmpl.DataSchema()
+	if opts.schema == "text" {
+		_, err := io.WriteString(stdout, schema.Doc())
+		return err
+	}
+	decl := cb.TypeDecl("Data", schema.GoType())
+	_, err = fmt.Fprintln(stdout, decl.GoCode(codegenutil.NewFileImports(codegenutil.AssumedPackageName("main"))))
+	return err
+}
+
+func generate(ctx context.Context, opts *options, stdout io.Writer) error {
+	tmpl, err := parse(opts)
+	if err != nil {
+		return err
+	}
+	data, err := codetemplate.ReadData(opts.data...)
+	if err != nil {
+		return err
+	}
+
+	pkg := codegenutil.AssumedPackageName(opts.pkg)
+	if opts.pkgName != "" {
+		pkg = codegenutil.ExplicitPackageName(opts.pkg, opts.pkgName)
+	}
+	var importsOpts []codegenutil.FileImportsOption
+	if opts.goVersion != "" {
+		versions, err := symbolindex.LoadStdlibVersions(ctx, "")
+		if err != nil {
+			return err
+		}
+		importsOpts = append(importsOpts, codegenutil.WithGoVersion(opts.goVersion), codegenutil.WithSymbolVersions(versions.Since))
+	}
+	var out bytes.Buffer
+	if err := tmpl.ExecuteContext(ctx, codegenutil.NewFileImports(pkg, importsOpts...), &out, data); err != nil {
+		return err
+	}
+
+	switch {
+	case opts.verify:
+		existing, err := os.ReadFile(opts.output)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if !bytes.Equal(existing, out.Bytes()) {
+			fmt.Fprintf(stdout, "%s is out of date (left: current, right: generated):\n%s\n",
+				opts.output, debugutil.SideBySide(string(existing), out.String()))
+			return errStale
+		}
+		return nil
+	case opts.output == "":
+		_, err := stdout.Write(out.Bytes())
+		return err
+	default:
+		return os.WriteFile(opts.output, out.Bytes(), 0o644)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestRun(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"enum.go.tmpl": `{{header}}
+
+var {{.name}} = {{.marshal}}
+var values = []string{ {{range .values}}{{printf "%q" .}}, {{end}} }
+`,
+		"a.json": `{"name": "overridden", "marshal": {"$symbol": "encoding/json.Marshal"}}`,
+		"b.yaml": "name: encode\nvalues: [x, y]\n",
+	})
+	want := `package mypkg
+
+import (
+	"encoding/json"
+)
+
+var encode = json.Marshal
+var values = []string{"x", "y"}
+`
+	out := filepath.Join(dir, "out.go")
+	args := []string{
+		"-template", filepath.Join(dir, "enum.go.tmpl"),
+		"-pkg", "abc.xyz/mypkg",
+		"-data", filepath.Join(dir, "a.json"),
+		"-data", filepath.Join(dir, "b.yaml"),
+		"-o", out,
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run(append(args, "-verify"), &stdout, &stderr); code != 1 {
+		t.Errorf("run(-verify) before generating exited with %d, want 1; stderr:\n%s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "out.go is out of date") {
+		t.Errorf("run(-verify) printed %q, want a message that out.go is out of date", stdout.String())
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	if code := run(args, &stdout, &stderr); code != 0 {
+		t.Fatalf("run() exited with %d; stderr:\n%s", code, stderr.String())
+	}
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("run() wrote:\n%s\nwant:\n%s", got, want)
+	}
+
+	if code := run(append(args, "-verify"), &stdout, &stderr); code != 0 {
+		t.Errorf("run(-verify) after generating exited with %d; stdout:\n%s", code, stdout.String())
+	}
+}
+
+func TestRun_schema(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"t.tmpl": "{{header}}\n{{if .Exported}}{{range .Names}}var {{.}} int\n{{end}}{{end}}",
+	})
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"text", ".Exported bool\n.Names list\n.Names[] any\n"},
+		{"go", "type Data struct {\n\tExported bool\n\tNames    []any\n}\n"},
+	}
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		if code := run([]string{"-template", filepath.Join(dir, "t.tmpl"), "-schema", tt.format}, &stdout, &stderr); code != 0 {
+			t.Fatalf("run(-schema %s) exited with %d; stderr:\n%s", tt.format, code, stderr.String())
+		}
+		if got := stdout.String(); got != tt.want {
+			t.Errorf("run(-schema %s) printed:\n%s\nwant:\n%s", tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestRun_errors(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"t.tmpl":       "{{header}}\n",
+		"bad.toml":     "",
+		"bad.json":     `{"x": {"$symbol": "not a symbol"}}`,
+		"list.yaml":    "- a\n",
+		"valid.json":   "{}",
+		"generic.tmpl": "{{header}}\n\ntype List[T any] []T\n",
+	})
+	tests := []struct {
+		name    string
+		args    []string
+		code    int
+		wantErr string
+	}{
+		{"missing flags", []string{"-template", "t.tmpl"}, 2, "usage:"},
+		{"unknown schema format", []string{"-template", "t.tmpl", "-schema", "json"}, 2, "usage:"},
+		{"invalid go version", []string{"-template", "t.tmpl", "-pkg", "p", "-go", "2.0"}, 2, "usage:"},
+		{"verify without output", []string{"-template", "t.tmpl", "-pkg", "p", "-verify"}, 2, "usage:"},
+		{"unknown extension", []string{"-template", "t.tmpl", "-pkg", "p", "-data", "bad.toml"}, 1, `unsupported data file extension ".toml"`},
+		{"invalid symbol", []string{"-template", "t.tmpl", "-pkg", "p", "-data", "bad.json"}, 1, `x: invalid symbol "not a symbol"`},
+		{"not a mapping", []string{"-template", "t.tmpl", "-pkg", "p", "-data", "list.yaml"}, 1, "list.yaml: yaml: unmarshal errors"},
+		{"newer than go version", []string{"-template", "generic.tmpl", "-pkg", "p", "-go", "1.17"}, 1, "type parameters requires go1.18, but the target Go version is go1.17"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var args []string
+			for i, arg := range tt.args {
+				if i > 0 && (tt.args[i-1] == "-template" || tt.args[i-1] == "-data") {
+					arg = filepath.Join(dir, arg)
+				}
+				args = append(args, arg)
+			}
+			var stdout, stderr bytes.Buffer
+			if code := run(args, &stdout, &stderr); code != tt.code || !strings.Contains(stderr.String(), tt.wantErr) {
+				t.Errorf("run() exited with %d and stderr:\n%s\nwant %d and stderr containing %q", code, stderr.String(), tt.code, tt.wantErr)
+			}
+		})
+	}
+}
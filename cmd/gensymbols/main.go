@@ -0,0 +1,163 @@
+// Command gensymbols prints the exported symbols of Go packages, either as
+// JSON or as a Go file that declares a *codegenutil.Symbol variable for each
+// symbol.
+//
+// Usage:
+//
+//	gensymbols [-C dir] [-format json|go] [-pkg import/path] [-o file] package...
+//
+// The packages are given as patterns of "go list", run in the directory given
+// by -C. Checking the output into version control pins the inputs of a
+// generator and makes changes to the API of the packages visible in reviews.
+//
+// The JSON output is a list of packages, each with its import path, its name
+// and its symbols, sorted by name:
+//
+//	[{"importPath": "encoding/json", "name": "json", "symbols": [
+//		{"name": "Marshal", "kind": "func", "type": "func(v any) ([]byte, error)"},
+//		...
+//	]}]
+//
+// The Go output belongs to the package whose import path is given by -pkg.
+// It declares a variable for each symbol, named after the symbol, or after
+// the package and the symbol if several packages are listed:
+//
+//	// func Marshal(v any) ([]byte, error)
+//	Marshal = codegenutil.Sym("encoding/json", "Marshal")
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/meta-programming/go-codegenutil"
+	cb "github.com/meta-programming/go-codegenutil/codebuilder"
+	"github.com/meta-programming/go-codegenutil/naming"
+	"github.com/meta-programming/go-codegenutil/symbolindex"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+type options struct {
+	dir    string
+	format string
+	pkg    string
+	output string
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("gensymbols", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var opts options
+	flags.StringVar(&opts.dir, "C", ".", "`dir`ectory to run \"go list\" in")
+	flags.StringVar(&opts.format, "format", "json", "output `format`: json or go")
+	flags.StringVar(&opts.pkg, "pkg", "", "import `path` of the generated Go file's package (required with -format go)")
+	flags.StringVar(&opts.output, "o", "", "output `file`; the standard output if empty")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 || (opts.format != "json" && opts.format != "go") || (opts.format == "go" && opts.pkg == "") {
+		fmt.Fprintln(stderr, "usage: gensymbols [-C dir] [-format json|go] [-pkg import/path] [-o file] package...")
+		flags.PrintDefaults()
+		return 2
+	}
+	if err := generate(&opts, flags.Args(), stdout); err != nil {
+		fmt.Fprintf(stderr, "gensymbols: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func generate(opts *options, patterns []string, stdout io.Writer) error {
+	indexes, err := symbolindex.Load(context.Background(), opts.dir, patterns...)
+	if err != nil {
+		return err
+	}
+	if len(indexes) == 0 {
+		return errors.New("no packages matched")
+	}
+	var out []byte
+	if opts.format == "go" {
+		out, err = goFile(codegenutil.AssumedPackageName(opts.pkg), indexes)
+	} else {
+		out, err = jsonReport(indexes)
+	}
+	if err != nil {
+		return err
+	}
+	if opts.output == "" {
+		_, err = stdout.Write(out)
+		return err
+	}
+	return os.WriteFile(opts.output, out, 0o644)
+}
+
+type jsonPackage struct {
+	ImportPath string       `json:"importPath"`
+	Name       string       `json:"name"`
+	Symbols    []jsonSymbol `json:"symbols"`
+}
+
+type jsonSymbol struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+	Type string `json:"type"`
+}
+
+func jsonReport(indexes []*symbolindex.Index) ([]byte, error) {
+	pkgs := []jsonPackage{}
+	for _, idx := range indexes {
+		pkg := jsonPackage{ImportPath: idx.Package().ImportPath(), Name: idx.Package().Name(), Symbols: []jsonSymbol{}}
+		for _, e := range idx.Entries() {
+			pkg.Symbols = append(pkg.Symbols, jsonSymbol{Name: e.Symbol.Name(), Kind: e.Kind.String(), Type: e.Type})
+		}
+		pkgs = append(pkgs, pkg)
+	}
+	out, err := json.MarshalIndent(pkgs, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+// symFunc is the function that the generated Go file calls to create symbols.
+var symFunc = codegenutil.Sym("github.com/meta-programming/go-codegenutil", "Sym")
+
+func goFile(pkg *codegenutil.Package, indexes []*symbolindex.Index) ([]byte, error) {
+	f := cb.NewFile(pkg).Doc("Code generated by gensymbols DO NOT EDIT.")
+	for _, idx := range indexes {
+		var vars []*cb.VarBuilder
+		for _, e := range idx.Entries() {
+			name := e.Symbol.Name()
+			if len(indexes) > 1 {
+				name = naming.Exported(idx.Package().Name()) + name
+			}
+			vars = append(vars, cb.Var(name).
+				Doc(declaration(e)).
+				Value(cb.Call(symFunc, cb.Lit(e.Symbol.Package().ImportPath()), cb.Lit(e.Symbol.Name()))))
+		}
+		if len(vars) > 0 {
+			f.Add(cb.Lines(
+				cb.Raw(fmt.Sprintf("// Exported symbols of package %s.", idx.Package().ImportPath())),
+				cb.Vars(vars...)))
+		}
+	}
+	return f.Render(nil)
+}
+
+// declaration returns the declaration of a symbol as it would be written in Go
+// code, without the body of a function.
+func declaration(e *symbolindex.Entry) string {
+	if e.Kind == symbolindex.Func {
+		return "func " + e.Symbol.Name() + strings.TrimPrefix(e.Type, "func")
+	}
+	return e.Kind.String() + " " + e.Symbol.Name() + " " + e.Type
+}
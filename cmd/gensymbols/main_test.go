@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRun_JSON(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-C", "../..", "./naming"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("run() exited with %d; stderr:\n%s", code, stderr.String())
+	}
+	var got []jsonPackage
+	if err := json.Unmarshal(stdout.Bytes(), &got); err != nil {
+		t.Fatalf("run() printed invalid JSON: %v\n%s", err, stdout.String())
+	}
+	if len(got) != 1 || got[0].ImportPath != "github.com/meta-programming/go-codegenutil/naming" || got[0].Name != "naming" {
+		t.Fatalf("run() printed packages %+v, want only the naming package", got)
+	}
+	want := jsonSymbol{Name: "Exported", Kind: "func", Type: "func(name string) string"}
+	if len(got[0].Symbols) == 0 || got[0].Symbols[0] != want {
+		t.Errorf("run() printed symbols %+v, want the first one to be %+v", got[0].Symbols, want)
+	}
+}
+
+func TestRun_Go(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	args := []string{"-C", "../..", "-format", "go", "-pkg", "abc.xyz/syms", "./naming", "./gogenerate"}
+	if code := run(args, &stdout, &stderr); code != 0 {
+		t.Fatalf("run() exited with %d; stderr:\n%s", code, stderr.String())
+	}
+	for _, want := range []string{
+		"// Code generated by gensymbols DO NOT EDIT.\npackage syms\n",
+		"\t// func Exported(name string) string\n\tNamingExported = codegenutil.Sym(\"github.com/meta-programming/go-codegenutil/naming\", \"Exported\")\n",
+		"\t// type Directive struct{Args []string; Filename string; Line int}\n\tGogenerateDirective = codegenutil.Sym(",
+	} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("run() printed\n%s\nwant it to contain\n%s", stdout.String(), want)
+		}
+	}
+
+	if code := run([]string{"-format", "go", "./naming"}, &stdout, &stderr); code != 2 {
+		t.Errorf("run() without -pkg exited with %d, want 2", code)
+	}
+}
@@ -0,0 +1,206 @@
+// Command genverify checks that generated files are up to date, for use as a
+// step of continuous integration. It regenerates the files in memory, without
+// writing them, and exits with status 1 and a diff of each stale file if any
+// generated file is missing or differs from its regenerated content.
+//
+// Usage:
+//
+//	genverify [-C dir] [-json] [-ignore-volatile] manifest...
+//	genverify [-C dir] [-json] [-ignore-volatile] -exec command [arg ...]
+//	genverify [-C dir] -check-determinism manifest...
+//	genverify [-C dir] -check-determinism -exec command [arg ...]
+//
+// In the first form, genverify executes the templates of manifests in the
+// format of project.LoadManifest. The files of each manifest are compared with
+// the files on disk relative to the manifest's directory.
+//
+// In the second form, genverify runs a generator program built with
+// project.Main, adding the -verify flag to its arguments, and exits with
+// status 1 if the program does. For example:
+//
+//	genverify -exec go run ./internal/gen
+//
+// The -C flag changes to dir before doing anything else. The paths of the
+// files in the report are relative to that directory.
+//
+// With -json, the report is written in the JSON format of
+// project.WriteJSONReport, which includes the diff hunks and the hashes of
+// each stale file, for tools such as bots that annotate pull requests. In the
+// second form, the -json flag is passed on to the generator program.
+//
+// With -ignore-volatile, files whose only changes are to the comments that
+// mention the version of a generator or a time stamp, such as the "Code
+// generated ... DO NOT EDIT." header, aren't reported; see
+// debugutil.IgnoreVolatileLines. In the second form, the flag is passed on to
+// the generator program.
+//
+// With -check-determinism, genverify doesn't compare the generated files with
+// the files on disk. Instead, it generates the files several times and exits
+// with status 1 and a diff of each file whose content differs between runs,
+// naming the generator and template that produced it; see
+// project.CheckDeterminism. In the second form, the flag is passed on to the
+// generator program instead of -verify.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+
+	"github.com/meta-programming/go-codegenutil/debugutil"
+	"github.com/meta-programming/go-codegenutil/project"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("genverify", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	dir := flags.String("C", "", "change to `dir` before doing anything else")
+	execute := flags.Bool("exec", false, "run the generator program given by the arguments with -verify")
+	jsonReport := flags.Bool("json", false, "write the report as JSON")
+	ignoreVolatile := flags.Bool("ignore-volatile", false, "ignore changes to version and time stamp comments")
+	checkDeterminism := flags.Bool("check-determinism", false, "report generated files whose content differs between runs")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprintln(stderr, "usage: genverify [-C dir] manifest...\n       genverify [-C dir] -exec command [arg ...]")
+		flags.PrintDefaults()
+		return 2
+	}
+
+	programFlags := []string{"-verify"}
+	if *jsonReport {
+		programFlags = append(programFlags, "-json")
+	}
+	var verifyOpts []debugutil.Option
+	if *ignoreVolatile {
+		programFlags = append(programFlags, "-ignore-volatile")
+		verifyOpts = append(verifyOpts, debugutil.IgnoreVolatileLines())
+	}
+	if *checkDeterminism {
+		programFlags = []string{"-check-determinism"}
+	}
+
+	var err error
+	switch {
+	case *execute:
+		err = runProgram(*dir, flags.Args(), programFlags, stdout, stderr)
+	case *checkDeterminism:
+		err = checkManifests(*dir, flags.Args(), stdout)
+	default:
+		err = verifyManifests(*dir, flags.Args(), *jsonReport, verifyOpts, stdout)
+	}
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, errNondeterministic), *checkDeterminism && errors.As(err, &exitErr):
+		fmt.Fprintln(stderr, "genverify: generators produce different files in different runs; make them deterministic")
+	case errors.Is(err, project.ErrVerifyStale), errors.As(err, &exitErr):
+		fmt.Fprintln(stderr, "genverify: generated files are out of date; regenerate them and commit the result")
+	default:
+		fmt.Fprintf(stderr, "genverify: %v\n", err)
+	}
+	return 1
+}
+
+func verifyManifests(dir string, names []string, jsonReport bool, verifyOpts []debugutil.Option, stdout io.Writer) error {
+	var stale []*project.StaleFile
+	for _, name := range names {
+		manifest := name
+		if !filepath.IsAbs(manifest) {
+			manifest = filepath.Join(dir, manifest)
+		}
+		m, err := project.LoadManifest(manifest)
+		if err != nil {
+			return err
+		}
+		p := project.New(m.Dir)
+		if err := project.Run(context.Background(), p, nil, m); err != nil {
+			return err
+		}
+		manifestStale, err := p.Verify(verifyOpts...)
+		if err != nil {
+			return err
+		}
+		// Report paths relative to the -C directory rather than to the
+		// manifest's directory.
+		for _, s := range manifestStale {
+			s.Path = path.Join(filepath.ToSlash(filepath.Dir(name)), s.Path)
+		}
+		stale = append(stale, manifestStale...)
+	}
+	var err error
+	if jsonReport {
+		err = project.WriteJSONReport(stdout, stale)
+	} else {
+		err = project.WriteStaleReport(stdout, stale)
+	}
+	if err != nil {
+		return err
+	}
+	if len(stale) > 0 {
+		return &project.StaleError{Files: stale}
+	}
+	return nil
+}
+
+// errNondeterministic is returned by checkManifests when a generated file
+// differs between runs.
+var errNondeterministic = errors.New("generated files differ between runs")
+
+// determinismRuns is the number of runs of each manifest with
+// -check-determinism.
+const determinismRuns = 3
+
+func checkManifests(dir string, names []string, stdout io.Writer) error {
+	var found []*project.NondeterministicFile
+	for _, name := range names {
+		manifest := name
+		if !filepath.IsAbs(manifest) {
+			manifest = filepath.Join(dir, manifest)
+		}
+		m, err := project.LoadManifest(manifest)
+		if err != nil {
+			return err
+		}
+		manifestFound, err := project.CheckDeterminism(context.Background(), m.Dir, determinismRuns, nil, m)
+		if err != nil {
+			return err
+		}
+		// Report paths relative to the -C directory rather than to the
+		// manifest's directory.
+		for _, n := range manifestFound {
+			n.Path = path.Join(filepath.ToSlash(filepath.Dir(name)), n.Path)
+		}
+		found = append(found, manifestFound...)
+	}
+	if err := project.WriteNondeterminismReport(stdout, found); err != nil {
+		return err
+	}
+	if len(found) > 0 {
+		return errNondeterministic
+	}
+	return nil
+}
+
+// runProgram runs the program given by args with the flags added to its
+// arguments.
+func runProgram(dir string, args, programFlags []string, stdout, stderr io.Writer) error {
+	args = append(args, programFlags...)
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Dir = dir
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	return cmd.Run()
+}
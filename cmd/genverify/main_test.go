@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/meta-programming/go-codegenutil/project"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"vars.go.tmpl": "{{header}}\n\nvar {{.name}} = {{.marshal}}\n",
+		"data.yaml":    "name: encode\nmarshal: {$symbol: encoding/json.Marshal}\n",
+		"codegen.json": `{"templates": [{"template": "vars.go.tmpl", "data": ["data.yaml"], "package": "abc.xyz/mypkg", "output": "vars.go"}]}`,
+		"vars.go":      "package mypkg\n\nimport (\n\t\"encoding/json\"\n)\n\nvar encode = json.Unmarshal\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-C", dir, "codegen.json"}, &stdout, &stderr); code != 1 {
+		t.Errorf("run() with a stale file exited with %d, want 1; stderr:\n%s", code, stderr.String())
+	}
+	wantDiff := `vars.go is out of date (generated by "` + filepath.Join(dir, "codegen.json") + `"):
+--- a/vars.go
++++ b/vars.go
+@@ -4,4 +4,4 @@
+ 	"encoding/json"
+ )
+ 
+-var encode = json.Unmarshal
++var encode = json.Marshal
+`
+	if !strings.Contains(stdout.String(), wantDiff) {
+		t.Errorf("run() printed\n%s\nwant it to contain\n%s", stdout.String(), wantDiff)
+	}
+	if !strings.Contains(stderr.String(), "out of date") {
+		t.Errorf("run() printed %q to stderr, want a message that files are out of date", stderr.String())
+	}
+
+	fixed := strings.Replace(files["vars.go"], "Unmarshal", "Marshal", 1)
+	if err := os.WriteFile(filepath.Join(dir, "vars.go"), []byte(fixed), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	stdout.Reset()
+	stderr.Reset()
+	if code := run([]string{"-C", dir, "codegen.json"}, &stdout, &stderr); code != 0 || stdout.Len() != 0 {
+		t.Errorf("run() with up to date files exited with %d and printed %q, want 0 and nothing; stderr:\n%s", code, stdout.String(), stderr.String())
+	}
+
+	if code := run(nil, &stdout, &stderr); code != 2 {
+		t.Errorf("run() without arguments exited with %d, want 2", code)
+	}
+}
+
+func TestRun_JSON(t *testing.T) {
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"x.go.tmpl":    "{{header}}\n",
+		"codegen.yaml": "templates:\n- {template: x.go.tmpl, package: abc.xyz/x, output: x/x.go}\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(sub, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-C", dir, "-json", "sub/codegen.yaml"}, &stdout, &stderr); code != 1 {
+		t.Errorf("run() with a missing file exited with %d, want 1; stderr:\n%s", code, stderr.String())
+	}
+	var report project.JSONReport
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		t.Fatalf("run() printed invalid JSON: %v\n%s", err, stdout.String())
+	}
+	if report.UpToDate || len(report.Files) != 1 || report.Files[0].Path != "sub/x/x.go" || report.Files[0].Status != project.Missing {
+		t.Errorf("run() reported %s, want sub/x/x.go missing", stdout.String())
+	}
+}
+
+func TestRun_checkDeterminism(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"vars.go.tmpl": "{{header}}\n\n{{range $k, $v := .}}var {{$k}} = {{$v}}\n{{end}}",
+		"data.yaml":    "a: 1\nb: 2\nc: 3\nd: 4\ne: 5\nf: 6\ng: 7\nh: 8\ni: 9\nj: 10\n",
+		"codegen.json": `{"templates": [{"template": "vars.go.tmpl", "data": ["data.yaml"], "package": "abc.xyz/mypkg", "output": "vars.go"}]}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The generated file doesn't exist, which doesn't matter to the check.
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-C", dir, "-check-determinism", "codegen.json"}, &stdout, &stderr); code != 0 || stdout.Len() != 0 {
+		t.Errorf("run(-check-determinism) exited with %d and printed %q, want 0 and nothing; stderr:\n%s", code, stdout.String(), stderr.String())
+	}
+}
@@ -0,0 +1,178 @@
+// Command pruneimports removes unused imports from Go files, like goimports
+// but without adding missing imports. Files whose imports are pruned are
+// formatted with gofmt; other files are left as they are.
+//
+// Usage:
+//
+//	pruneimports [-s] [-w | -d | -l] [path ...]
+//
+// Each path may be a file or a directory, which is searched recursively for
+// .go files; directories named testdata or vendor and those whose names start
+// with "." or "_" are skipped. Without paths, pruneimports reads the standard
+// input and writes the result to the standard output.
+//
+// By default, the pruned source of each file is printed to the standard
+// output. The flags, which are like those of gofmt, change that behavior:
+//
+//	-w	write the result to the file instead of printing it
+//	-d	print a diff of the changes instead of the result
+//	-l	print the names of the files that would change
+//
+// The -s flag also applies the simplifications of "gofmt -s", so files that
+// can be simplified change too.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/meta-programming/go-codegenutil/debugutil"
+	"github.com/meta-programming/go-codegenutil/unusedimports"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+type options struct {
+	write, diff, list bool
+	simplify          bool
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("pruneimports", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var opts options
+	flags.BoolVar(&opts.write, "w", false, "write the result to the source file instead of the standard output")
+	flags.BoolVar(&opts.diff, "d", false, "print diffs instead of rewriting files")
+	flags.BoolVar(&opts.list, "l", false, "list files whose imports would be pruned")
+	flags.BoolVar(&opts.simplify, "s", false, "simplify code like gofmt -s")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if flags.NArg() == 0 {
+		if opts.write {
+			fmt.Fprintln(stderr, "pruneimports: cannot use -w with the standard input")
+			return 2
+		}
+		src, err := io.ReadAll(stdin)
+		if err == nil {
+			err = process(&opts, "<standard input>", src, stdout)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "pruneimports: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	status := 0
+	for _, path := range flags.Args() {
+		if err := processPath(&opts, path, stdout); err != nil {
+			fmt.Fprintf(stderr, "pruneimports: %v\n", err)
+			status = 1
+		}
+	}
+	return status
+}
+
+// processPath processes a file, or the .go files in a directory tree.
+func processPath(opts *options, path string, stdout io.Writer) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return processFile(opts, path, stdout)
+	}
+	var errs []string
+	err = filepath.WalkDir(path, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			base := d.Name()
+			if name != path && (base == "testdata" || base == "vendor" || strings.HasPrefix(base, ".") || strings.HasPrefix(base, "_")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(name, ".go") && !strings.HasPrefix(d.Name(), ".") {
+			if err := processFile(opts, name, stdout); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "\npruneimports: "))
+	}
+	return nil
+}
+
+func processFile(opts *options, name string, stdout io.Writer) error {
+	src, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	return process(opts, name, src, stdout)
+}
+
+// process prunes the imports of src, which was read from the named file, and
+// reports or writes the result according to opts.
+func process(opts *options, name string, src []byte, stdout io.Writer) error {
+	prune := unusedimports.PruneUnparsed
+	if opts.simplify {
+		prune = unusedimports.PruneAndSimplifyUnparsed
+	}
+	prunedText, err := prune(name, string(src))
+	if err != nil {
+		return err
+	}
+	// PruneUnparsed doesn't print in gofmt style, so the result is formatted,
+	// and only files whose imports were pruned are considered changed.
+	pruned, err := format.Source([]byte(prunedText))
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	formattedSrc, err := format.Source(src)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	changed := !bytes.Equal(formattedSrc, pruned)
+	if !changed {
+		pruned = src
+	}
+	if opts.list && changed {
+		fmt.Fprintln(stdout, name)
+	}
+	if opts.diff && changed {
+		fmt.Fprint(stdout, debugutil.UnifiedDiff(name+".orig", name, string(src), string(pruned)))
+	}
+	if opts.write && changed {
+		info, err := os.Stat(name)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(name, pruned, info.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+	if !opts.list && !opts.diff && !opts.write {
+		_, err := stdout.Write(pruned)
+		return err
+	}
+	return nil
+}
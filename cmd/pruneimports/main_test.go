@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const unpruned = `package p
+
+import (
+	"fmt"
+	"os"
+)
+
+func f() { fmt.Println() }
+`
+
+const pruned = `package p
+
+import (
+	"fmt"
+)
+
+func f() { fmt.Println() }
+`
+
+const clean = "package p\n\nimport \"fmt\"\n\nvar _ = fmt.Sprint\n"
+
+func setup(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"a.go":             unpruned,
+		"sub/b.go":         clean,
+		"testdata/c.go":    unpruned,
+		"sub/not_go.txt":   unpruned,
+		".hidden/d.go":     unpruned,
+		"vendor/x/vend.go": unpruned,
+	} {
+		name = filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestRun(t *testing.T) {
+	t.Run("stdin", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		if code := run(nil, strings.NewReader(unpruned), &stdout, &stderr); code != 0 {
+			t.Fatalf("run() exited with %d; stderr:\n%s", code, stderr.String())
+		}
+		if stdout.String() != pruned {
+			t.Errorf("run() printed:\n%s\nwant:\n%s", stdout.String(), pruned)
+		}
+	})
+
+	t.Run("simplify", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		src := clean + "var _ = []struct{}{struct{}{}}\n"
+		want := clean + "var _ = []struct{}{{}}\n"
+		if code := run([]string{"-s"}, strings.NewReader(src), &stdout, &stderr); code != 0 {
+			t.Fatalf("run() exited with %d; stderr:\n%s", code, stderr.String())
+		}
+		if stdout.String() != want {
+			t.Errorf("run(-s) printed:\n%s\nwant:\n%s", stdout.String(), want)
+		}
+	})
+
+	t.Run("list", func(t *testing.T) {
+		dir := setup(t)
+		var stdout, stderr bytes.Buffer
+		if code := run([]string{"-l", dir}, nil, &stdout, &stderr); code != 0 {
+			t.Fatalf("run() exited with %d; stderr:\n%s", code, stderr.String())
+		}
+		if want := filepath.Join(dir, "a.go") + "\n"; stdout.String() != want {
+			t.Errorf("run(-l) printed %q, want %q", stdout.String(), want)
+		}
+	})
+
+	t.Run("diff", func(t *testing.T) {
+		dir := setup(t)
+		var stdout, stderr bytes.Buffer
+		if code := run([]string{"-d", filepath.Join(dir, "a.go"), filepath.Join(dir, "sub")}, nil, &stdout, &stderr); code != 0 {
+			t.Fatalf("run() exited with %d; stderr:\n%s", code, stderr.String())
+		}
+		if !strings.Contains(stdout.String(), "@@ -2,7 +2,6 @@") || !strings.Contains(stdout.String(), "-\t\"os\"\n") {
+			t.Errorf("run(-d) printed:\n%s\nwant a diff removing the os import", stdout.String())
+		}
+	})
+
+	t.Run("write", func(t *testing.T) {
+		dir := setup(t)
+		var stdout, stderr bytes.Buffer
+		if code := run([]string{"-w", dir}, nil, &stdout, &stderr); code != 0 {
+			t.Fatalf("run() exited with %d; stderr:\n%s", code, stderr.String())
+		}
+		for name, want := range map[string]string{"a.go": pruned, "sub/b.go": clean, "testdata/c.go": unpruned} {
+			got, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != want {
+				t.Errorf("after run(-w), %s is:\n%s\nwant:\n%s", name, got, want)
+			}
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		if code := run([]string{"-w"}, strings.NewReader(""), &stdout, &stderr); code != 2 {
+			t.Errorf("run(-w) with stdin exited with %d, want 2", code)
+		}
+		if code := run(nil, strings.NewReader("not go"), &stdout, &stderr); code != 1 {
+			t.Errorf("run() with invalid input exited with %d, want 1", code)
+		}
+		if code := run([]string{filepath.Join(t.TempDir(), "missing.go")}, nil, &stdout, &stderr); code != 1 {
+			t.Errorf("run() with a missing file exited with %d, want 1", code)
+		}
+	})
+}
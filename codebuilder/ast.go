@@ -0,0 +1,100 @@
+package codebuilder
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/printer"
+	"go/token"
+
+	"github.com/meta-programming/go-codegenutil"
+	"github.com/meta-programming/go-codegenutil/debugutil"
+)
+
+// AST returns the syntax tree of the file, after applying the transformations
+// registered with Transform. An error is returned if the generated code is not
+// syntactically valid Go.
+//
+// The builders of this package produce source text rather than syntax trees,
+// so the tree is obtained by parsing the code of the file: validity is checked
+// when AST is called, not guaranteed as the file is built. Declarations that
+// are already syntax trees can be added with Node.
+//
+// The tree can be modified, for example with the
+// golang.org/x/tools/go/ast/astutil package, and printed with go/printer or
+// go/format.
+func (f *File) AST(imports *codegenutil.FileImports) (*token.FileSet, *ast.File, error) {
+	src := f.GoCode(imports)
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return nil, nil, fmt.Errorf("generated code is not valid Go: %w\n%s", err, debugutil.WithLineNumbers(src))
+	}
+	for _, transform := range f.transforms {
+		if err := transform(fset, file); err != nil {
+			return nil, nil, err
+		}
+	}
+	return fset, file, nil
+}
+
+// Transform registers a function that modifies the syntax tree of the file
+// before it is printed by Render. Transformations run in the order they are
+// registered.
+func (f *File) Transform(fn func(fset *token.FileSet, file *ast.File) error) *File {
+	f.transforms = append(f.transforms, fn)
+	return f
+}
+
+// ParseDecl returns the syntax tree of a single top-level declaration, such as
+// one built by Func or TypeDecl, by parsing its code like AST does. Packages
+// referenced by the declaration are added to imports.
+func ParseDecl(decl Code, imports *codegenutil.FileImports) (*token.FileSet, ast.Decl, error) {
+	const prefix = "package p\n\n"
+	src := prefix + decl.GoCode(imports)
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return nil, nil, fmt.Errorf("generated code is not valid Go: %w\n%s", err, debugutil.WithLineNumbers(src))
+	}
+	if len(file.Decls) != 1 {
+		return nil, nil, fmt.Errorf("got %d declarations, want 1", len(file.Decls))
+	}
+	return fset, file.Decls[0], nil
+}
+
+// Node returns Code that prints a syntax tree node, such as an ast.Decl, an
+// ast.Expr or an ast.Stmt, with go/printer. The node's positions should belong
+// to fset, which may be nil for nodes constructed without positions.
+//
+// Qualified identifiers in the node are printed as they are; the packages they
+// refer to must be added to the file's imports separately.
+func Node(fset *token.FileSet, node ast.Node) Code {
+	if fset == nil {
+		fset = token.NewFileSet()
+	}
+	return &nodeCode{fset, node}
+}
+
+type nodeCode struct {
+	fset *token.FileSet
+	node ast.Node
+}
+
+func (c *nodeCode) GoCode(*codegenutil.FileImports) string {
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, c.fset, c.node); err != nil {
+		return fmt.Sprintf("/* error printing %T: %v */", c.node, err)
+	}
+	return buf.String()
+}
+
+func printAST(fset *token.FileSet, file *ast.File) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, file); err != nil {
+		return nil, fmt.Errorf("error printing generated file: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,85 @@
+package codebuilder
+
+import (
+	"go/ast"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/meta-programming/go-codegenutil"
+	"github.com/meta-programming/go-codegenutil/debugutil"
+)
+
+func TestFile_Transform(t *testing.T) {
+	file := NewFile(codegenutil.AssumedPackageName("abc.xyz/mypkg")).
+		Add(
+			Func("helper").Results(P("", codegenutil.Sym("time", "Duration"))).Body(Raw("return 0")),
+			Var("x").Value(Call(Ident("helper"))),
+		).
+		Transform(func(fset *token.FileSet, file *ast.File) error {
+			// Rename every identifier named helper.
+			ast.Inspect(file, func(n ast.Node) bool {
+				if id, ok := n.(*ast.Ident); ok && id.Name == "helper" {
+					id.Name = "renamed"
+				}
+				return true
+			})
+			return nil
+		})
+	got, err := file.Render(nil)
+	if err != nil {
+		t.Fatalf("Render() error: %v", err)
+	}
+	want := `package mypkg
+
+import (
+	"time"
+)
+
+func renamed() time.Duration {
+	return 0
+}
+
+var x = renamed()
+`
+	if string(got) != want {
+		t.Errorf("Render() got != want:\n%s", debugutil.SideBySide(string(got), want))
+	}
+}
+
+func TestFile_AST_invalid(t *testing.T) {
+	file := NewFile(codegenutil.AssumedPackageName("abc.xyz/mypkg")).Add(Raw("var x = ("))
+	imports := codegenutil.NewFileImports(file.Package())
+	if _, _, err := file.AST(imports); err == nil || !strings.Contains(err.Error(), "not valid Go") {
+		t.Errorf("AST() got error %v, want error for invalid code", err)
+	}
+}
+
+func TestParseDecl(t *testing.T) {
+	imports := codegenutil.NewFileImports(codegenutil.AssumedPackageName("abc.xyz/mypkg"))
+	_, decl, err := ParseDecl(TypeDecl("D", codegenutil.Sym("time", "Duration")), imports)
+	if err != nil {
+		t.Fatalf("ParseDecl() error: %v", err)
+	}
+	spec := decl.(*ast.GenDecl).Specs[0].(*ast.TypeSpec)
+	if got := spec.Name.Name; got != "D" {
+		t.Errorf("ParseDecl() type name = %q, want D", got)
+	}
+	if imports.Find(codegenutil.AssumedPackageName("time")) == nil {
+		t.Errorf("ParseDecl() did not import package time")
+	}
+	if _, _, err := ParseDecl(Lines(Var("a"), Var("b")), imports); err == nil {
+		t.Errorf("ParseDecl() with two declarations succeeded, want error")
+	}
+}
+
+func TestNode(t *testing.T) {
+	call := &ast.CallExpr{
+		Fun:  &ast.SelectorExpr{X: ast.NewIdent("strings"), Sel: ast.NewIdent("ToUpper")},
+		Args: []ast.Expr{&ast.BasicLit{Kind: token.STRING, Value: `"x"`}},
+	}
+	imports := codegenutil.NewFileImports(codegenutil.AssumedPackageName("abc.xyz/mypkg"))
+	if got, want := Binary(Node(nil, call), "+", Lit("y")).GoCode(imports), `strings.ToUpper("x") + "y"`; got != want {
+		t.Errorf("GoCode() = %q, want %q", got, want)
+	}
+}
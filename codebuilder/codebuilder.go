@@ -0,0 +1,644 @@
+// Package codebuilder constructs Go declarations programmatically.
+//
+// Templates (see the codetemplate package) are a good fit for large, mostly
+// static skeletons of code. Highly conditional code is often easier to produce
+// by composing values instead, which is what this package provides: a File
+// holds declarations built with Var, Const, TypeDecl and Func, and every
+// declaration, type and expression is a Code value that is rendered using the
+// *codegenutil.FileImports of the file being generated. Symbols from other
+// packages are imported and qualified automatically.
+//
+// Code values implement the same GoCode method as *codegenutil.Symbol, so they
+// may also be printed directly by codetemplate templates.
+//
+// Generators that print code line by line can write it to a GeneratedFile
+// instead, whose header and imports are assembled after the body.
+//
+// Builders produce source text, not go/ast nodes. Generators that post-process
+// files as syntax trees get them from File.AST, which parses the code of the
+// file, or register their changes with File.Transform.
+package codebuilder
+
+import (
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/scanner"
+	"go/token"
+	"strconv"
+	"strings"
+
+	"github.com/meta-programming/go-codegenutil"
+	"github.com/meta-programming/go-codegenutil/gogenerate"
+)
+
+// Code is a fragment of Go code, such as an expression, a type, a statement or
+// a declaration, that may refer to symbols of other packages.
+type Code interface {
+	// GoCode returns the code as it should appear in the file whose imports
+	// are given. Packages of referenced symbols are added to imports.
+	GoCode(imports *codegenutil.FileImports) string
+}
+
+// String returns code as it is written in logs and errors: as by GoCode, but
+// with the symbols of other packages qualified by their quoted import paths,
+// as by codegenutil.Symbol.String, such as []"encoding/json".RawMessage. It
+// doesn't need the imports of a file and doesn't add to any. The types and
+// expressions of this package implement fmt.Stringer with String.
+func String(code Code) string {
+	return code.GoCode(codegenutil.NewFileImports(codegenutil.BuiltinPackage, quotedImportPaths))
+}
+
+// quotedImportPaths makes the local name of each import its quoted import path.
+var quotedImportPaths = codegenutil.CustomPackageNameSuggester(func(pkg *codegenutil.Package, tryImportSpec func(string) bool) {
+	tryImportSpec(strconv.Quote(pkg.ImportPath()))
+})
+
+// Raw returns Code that is printed verbatim. It should not refer to symbols
+// of other packages, since the packages won't be imported.
+func Raw(code string) Code { return rawCode(code) }
+
+type rawCode string
+
+func (c rawCode) GoCode(*codegenutil.FileImports) string { return string(c) }
+
+// Codef returns Code formatted like fmt.Sprintf. Arguments that implement
+// Code, including *codegenutil.Symbol, are replaced by their GoCode before
+// formatting, so they should be used with the %s or %v verbs.
+func Codef(format string, args ...any) Code {
+	return &formattedCode{format, args}
+}
+
+type formattedCode struct {
+	format string
+	args   []any
+}
+
+func (c *formattedCode) String() string { return String(c) }
+
+func (c *formattedCode) GoCode(imports *codegenutil.FileImports) string {
+	args := make([]any, len(c.args))
+	for i, arg := range c.args {
+		if code, ok := arg.(Code); ok {
+			arg = code.GoCode(imports)
+		}
+		args[i] = arg
+	}
+	return fmt.Sprintf(c.format, args...)
+}
+
+// Lines returns Code that prints each of the given lines of code on its own
+// line.
+func Lines(lines ...Code) Code { return linesCode(lines) }
+
+type linesCode []Code
+
+func (c linesCode) GoCode(imports *codegenutil.FileImports) string {
+	return joinCode(imports, []Code(c), "\n")
+}
+
+// Block returns a statement with a braced body, such as an if, for or switch
+// statement. The head is the part of the statement before the opening brace,
+// for example Codef("for i := range %s", x), and each statement of the body is
+// printed on its own line.
+func Block(head Code, body ...Code) Code {
+	return &blockCode{head, body}
+}
+
+type blockCode struct {
+	head Code
+	body []Code
+}
+
+func (c *blockCode) GoCode(imports *codegenutil.FileImports) string {
+	out := &strings.Builder{}
+	out.WriteString(c.head.GoCode(imports) + " {\n")
+	for _, stmt := range c.body {
+		out.WriteString(indent(stmt.GoCode(imports)) + "\n")
+	}
+	out.WriteString("}")
+	return out.String()
+}
+
+// File is a Go source file assembled from declarations.
+type File struct {
+	pkg        *codegenutil.Package
+	doc        string
+	directives []string
+	decls      []Code
+	// declared holds the names and dependencies of the declarations added
+	// with Declare, keyed by their indexes in decls.
+	declared   map[int]*declInfo
+	transforms []func(fset *token.FileSet, file *ast.File) error
+}
+
+// NewFile returns an empty file that belongs to the given package.
+func NewFile(pkg *codegenutil.Package) *File {
+	return &File{pkg: pkg}
+}
+
+// Package returns the package of the file.
+func (f *File) Package() *codegenutil.Package { return f.pkg }
+
+// Doc sets the comment printed above the package clause.
+func (f *File) Doc(text string) *File {
+	f.doc = text
+	return f
+}
+
+// GoGenerate adds a //go:generate directive that runs the command given by
+// args to the file. Directives are printed after the imports, before the
+// declarations; see gogenerate.Format for how the arguments are quoted.
+func (f *File) GoGenerate(args ...string) *File {
+	f.directives = append(f.directives, gogenerate.Format(args...))
+	return f
+}
+
+// Add appends declarations to the file. Each declaration is separated from the
+// previous one by a blank line.
+func (f *File) Add(decls ...Code) *File {
+	f.decls = append(f.decls, decls...)
+	return f
+}
+
+// GoCode returns the unformatted source of the file: the package clause, an
+// imports block containing every package referenced by the declarations, and
+// the declarations themselves.
+func (f *File) GoCode(imports *codegenutil.FileImports) string {
+	// The declarations are printed first so that all of the packages they
+	// reference have been added to imports by the time it is printed.
+	body := joinCode(imports, f.orderedDecls(), "\n\n")
+	out := &strings.Builder{}
+	out.WriteString(docComment(f.doc))
+	if len(imports.List()) == 0 {
+		out.WriteString("package " + imports.Package().Name())
+	} else {
+		out.WriteString(imports.Format(true))
+	}
+	if len(f.directives) > 0 {
+		out.WriteString("\n\n")
+		out.WriteString(strings.Join(f.directives, "\n"))
+	}
+	if body != "" {
+		out.WriteString("\n\n")
+		out.WriteString(body)
+	}
+	out.WriteString("\n")
+	return out.String()
+}
+
+// Render returns the gofmt-formatted source of the file. If imports is nil, a
+// new *codegenutil.FileImports for the file's package is used. If the file uses
+// features that are newer than the target Go version of imports, Render
+// returns a *codegenutil.GoVersionError; see codegenutil.WithGoVersion.
+//
+// If any transformations were registered with Transform, the file is printed
+// from its transformed syntax tree.
+func (f *File) Render(imports *codegenutil.FileImports) (_ []byte, err error) {
+	if imports == nil {
+		imports = codegenutil.NewFileImports(f.pkg)
+	}
+	defer recoverGoVersionError(&err)
+	if len(f.transforms) > 0 {
+		fset, file, err := f.AST(imports)
+		if err != nil {
+			return nil, err
+		}
+		return printAST(fset, file)
+	}
+	src := f.GoCode(imports)
+	formatted, err := format.Source([]byte(src))
+	if err != nil {
+		return nil, fmt.Errorf("error formatting generated file: %w\n%s", err, src)
+	}
+	return formatted, nil
+}
+
+// VarBuilder builds a package-level variable declaration. See Var.
+type VarBuilder struct {
+	valueSpec
+}
+
+// Var returns a builder for a "var name T = value" declaration.
+func Var(name string) *VarBuilder {
+	return &VarBuilder{valueSpec{keyword: "var", name: name}}
+}
+
+// Doc sets the doc comment of the variable.
+func (b *VarBuilder) Doc(text string) *VarBuilder {
+	b.doc = text
+	return b
+}
+
+// Type sets the type of the variable. It may be omitted if a value is given.
+func (b *VarBuilder) Type(typ Code) *VarBuilder {
+	b.typ = typ
+	return b
+}
+
+// Value sets the initial value of the variable.
+func (b *VarBuilder) Value(value Code) *VarBuilder {
+	b.value = value
+	return b
+}
+
+// ConstBuilder builds a constant declaration. See Const.
+type ConstBuilder struct {
+	valueSpec
+}
+
+// Const returns a builder for a "const name T = value" declaration.
+func Const(name string) *ConstBuilder {
+	return &ConstBuilder{valueSpec{keyword: "const", name: name}}
+}
+
+// Doc sets the doc comment of the constant.
+func (b *ConstBuilder) Doc(text string) *ConstBuilder {
+	b.doc = text
+	return b
+}
+
+// Type sets the type of the constant. It may be omitted for untyped
+// constants.
+func (b *ConstBuilder) Type(typ Code) *ConstBuilder {
+	b.typ = typ
+	return b
+}
+
+// Value sets the value of the constant.
+func (b *ConstBuilder) Value(value Code) *ConstBuilder {
+	b.value = value
+	return b
+}
+
+// Consts returns a grouped constant declaration, "const ( ... )". Within a
+// group, constants without a type and value repeat the previous ones, which is
+// how iota enumerations are written:
+//
+//	Consts(
+//		Const("A").Type(Raw("Kind")).Value(Raw("iota")),
+//		Const("B"),
+//	)
+func Consts(consts ...*ConstBuilder) Code {
+	group := &declGroup{keyword: "const"}
+	for _, c := range consts {
+		group.specs = append(group.specs, &c.valueSpec)
+	}
+	return group
+}
+
+// Vars returns a grouped variable declaration, "var ( ... )".
+func Vars(vars ...*VarBuilder) Code {
+	group := &declGroup{keyword: "var"}
+	for _, v := range vars {
+		group.specs = append(group.specs, &v.valueSpec)
+	}
+	return group
+}
+
+type declGroup struct {
+	keyword string
+	specs   []*valueSpec
+}
+
+func (g *declGroup) GoCode(imports *codegenutil.FileImports) string {
+	out := &strings.Builder{}
+	out.WriteString(g.keyword + " (\n")
+	for _, s := range g.specs {
+		out.WriteString(indent(docComment(s.doc)+s.specCode(imports)) + "\n")
+	}
+	out.WriteString(")")
+	return formatFragment("package p\n\n", out.String())
+}
+
+// valueSpec holds the parts of a var or const declaration.
+type valueSpec struct {
+	keyword    string
+	doc        string
+	name       string
+	typ, value Code
+}
+
+func (s *valueSpec) GoCode(imports *codegenutil.FileImports) string {
+	return docComment(s.doc) + s.keyword + " " + s.specCode(imports)
+}
+
+// specCode returns the declaration without its doc comment and keyword, as it
+// appears in a grouped declaration.
+func (s *valueSpec) specCode(imports *codegenutil.FileImports) string {
+	out := &strings.Builder{}
+	out.WriteString(s.name)
+	if s.typ != nil {
+		out.WriteString(" " + s.typ.GoCode(imports))
+	}
+	if s.value != nil {
+		out.WriteString(" = " + s.value.GoCode(imports))
+	}
+	return out.String()
+}
+
+// TypeDeclBuilder builds a type declaration. See TypeDecl.
+type TypeDeclBuilder struct {
+	doc        string
+	name       string
+	typeParams []*Param
+	typ        Code
+	isAlias    bool
+}
+
+// TypeDecl returns a builder for a "type name T" declaration.
+func TypeDecl(name string, typ Code) *TypeDeclBuilder {
+	return &TypeDeclBuilder{name: name, typ: typ}
+}
+
+// Doc sets the doc comment of the type.
+func (b *TypeDeclBuilder) Doc(text string) *TypeDeclBuilder {
+	b.doc = text
+	return b
+}
+
+// Alias makes the declaration an alias declaration: "type name = T".
+func (b *TypeDeclBuilder) Alias() *TypeDeclBuilder {
+	b.isAlias = true
+	return b
+}
+
+// TypeParams appends type parameters to the declaration, making it a generic
+// type. The type of each parameter is its constraint, for example
+// P("K", Raw("comparable")).
+func (b *TypeDeclBuilder) TypeParams(params ...*Param) *TypeDeclBuilder {
+	b.typeParams = append(b.typeParams, params...)
+	return b
+}
+
+// Instantiated returns the declared type instantiated with its own type
+// parameters, as in "List[T]", which is how a generic type is referred to in
+// the receivers and signatures of its methods. For types without type
+// parameters it returns the type's name.
+func (b *TypeDeclBuilder) Instantiated() Expr {
+	var args []Code
+	for _, p := range b.typeParams {
+		args = append(args, Ident(p.name))
+	}
+	return Instantiate(Ident(b.name), args...)
+}
+
+// GoCode returns the type declaration.
+func (b *TypeDeclBuilder) GoCode(imports *codegenutil.FileImports) string {
+	sep := " "
+	if b.isAlias {
+		sep = " = "
+	}
+	return docComment(b.doc) + "type " + b.name + typeParamList(imports, b.typeParams) + sep + b.typ.GoCode(imports)
+}
+
+// FuncBuilder builds a function or method declaration. See Func.
+type FuncBuilder struct {
+	doc        string
+	name       string
+	recv       *Param
+	typeParams []*Param
+	params     []*Param
+	results    []*Param
+	body       []Code
+}
+
+// Param is a receiver, type parameter, parameter or result of a function.
+type Param struct {
+	name     string
+	typ      Code
+	variadic bool
+}
+
+// P returns a function parameter or result with the given name and type. The
+// name may be empty for unnamed parameters and results. For type parameters,
+// typ is the constraint.
+func P(name string, typ Code) *Param {
+	return &Param{name: name, typ: typ}
+}
+
+// Name returns the name of the parameter, which is empty for unnamed
+// parameters.
+func (p *Param) Name() string { return p.name }
+
+// Type returns the type of the parameter, or the constraint of a type
+// parameter. The type of a variadic parameter is its element type.
+func (p *Param) Type() Code { return p.typ }
+
+// Variadic makes p a variadic parameter: "name ...T". Only the final parameter
+// of a function may be variadic.
+func (p *Param) Variadic() *Param {
+	p.variadic = true
+	return p
+}
+
+// String returns the parameter as it is written in logs; see String.
+func (p *Param) String() string { return String(p) }
+
+// GoCode returns the parameter as it appears in a parameter list.
+func (p *Param) GoCode(imports *codegenutil.FileImports) string {
+	typ := p.typ.GoCode(imports)
+	if p.variadic {
+		typ = "..." + typ
+	}
+	if p.name == "" {
+		return typ
+	}
+	return p.name + " " + typ
+}
+
+// Func returns a builder for a function declaration.
+func Func(name string) *FuncBuilder {
+	return &FuncBuilder{name: name}
+}
+
+// Doc sets the doc comment of the function.
+func (b *FuncBuilder) Doc(text string) *FuncBuilder {
+	b.doc = text
+	return b
+}
+
+// Receiver makes the function a method of the receiver type typ, which is
+// usually the type or a pointer to a type declared in the same file. The name
+// may be empty if the receiver is not used.
+func (b *FuncBuilder) Receiver(name string, typ Code) *FuncBuilder {
+	b.recv = P(name, typ)
+	return b
+}
+
+// TypeParams appends type parameters to the function's signature. The type of
+// each parameter is its constraint, for example P("T", Raw("any")). Methods
+// may not have type parameters.
+func (b *FuncBuilder) TypeParams(params ...*Param) *FuncBuilder {
+	b.typeParams = append(b.typeParams, params...)
+	return b
+}
+
+// Params appends parameters to the function's signature.
+func (b *FuncBuilder) Params(params ...*Param) *FuncBuilder {
+	b.params = append(b.params, params...)
+	return b
+}
+
+// Results appends results to the function's signature.
+func (b *FuncBuilder) Results(results ...*Param) *FuncBuilder {
+	b.results = append(b.results, results...)
+	return b
+}
+
+// Body appends statements to the body of the function.
+func (b *FuncBuilder) Body(stmts ...Code) *FuncBuilder {
+	b.body = append(b.body, stmts...)
+	return b
+}
+
+// GoCode returns the function declaration.
+func (b *FuncBuilder) GoCode(imports *codegenutil.FileImports) string {
+	out := &strings.Builder{}
+	out.WriteString(docComment(b.doc))
+	out.WriteString("func ")
+	if b.recv != nil {
+		out.WriteString("(" + b.recv.GoCode(imports) + ") ")
+	}
+	out.WriteString(b.name + typeParamList(imports, b.typeParams))
+	out.WriteString(signature(imports, b.params, b.results))
+	out.WriteString(" {\n")
+	for _, stmt := range b.body {
+		out.WriteString(indent(stmt.GoCode(imports)) + "\n")
+	}
+	out.WriteString("}")
+	return out.String()
+}
+
+// FuncType returns a function type with the given parameters and results, such
+// as "func(x int) error".
+func FuncType(params, results []*Param) Code {
+	return &funcTypeCode{params, results}
+}
+
+type funcTypeCode struct {
+	params, results []*Param
+}
+
+func (c *funcTypeCode) String() string { return String(c) }
+
+func (c *funcTypeCode) GoCode(imports *codegenutil.FileImports) string {
+	return "func" + signature(imports, c.params, c.results)
+}
+
+// signature returns the parameters and results of a function type, starting
+// with the opening parenthesis of the parameter list.
+func signature(imports *codegenutil.FileImports, params, results []*Param) string {
+	sig := "(" + paramList(imports, params) + ")"
+	switch {
+	case len(results) == 1 && results[0].name == "":
+		sig += " " + results[0].GoCode(imports)
+	case len(results) > 0:
+		sig += " (" + paramList(imports, results) + ")"
+	}
+	return sig
+}
+
+// typeParamList returns a bracketed type parameter list, or the empty string if
+// there are no type parameters.
+func typeParamList(imports *codegenutil.FileImports, params []*Param) string {
+	if len(params) == 0 {
+		return ""
+	}
+	requireGoVersion(imports, "type parameters", "go1.18")
+	return "[" + paramList(imports, params) + "]"
+}
+
+func paramList(imports *codegenutil.FileImports, params []*Param) string {
+	parts := make([]string, len(params))
+	for i, p := range params {
+		parts[i] = p.GoCode(imports)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// requireGoVersion panics with a *codegenutil.GoVersionError if the target Go
+// version of imports is older than version, which introduced feature. Render
+// returns the error.
+func requireGoVersion(imports *codegenutil.FileImports, feature, version string) {
+	if err := imports.RequireGoVersion(feature, version); err != nil {
+		panic(err)
+	}
+}
+
+// recoverGoVersionError sets *err to the *codegenutil.GoVersionError that the
+// function panics with, if any, and panics again with other values.
+func recoverGoVersionError(err *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	if e, ok := r.(*codegenutil.GoVersionError); ok {
+		*err = e
+		return
+	}
+	panic(r)
+}
+
+// joinCode returns the GoCode of each element of code joined by sep.
+func joinCode(imports *codegenutil.FileImports, code []Code, sep string) string {
+	parts := make([]string, len(code))
+	for i, c := range code {
+		parts[i] = c.GoCode(imports)
+	}
+	return strings.Join(parts, sep)
+}
+
+// docComment returns text as a line comment followed by a newline, or the
+// empty string if text is empty.
+func docComment(text string) string {
+	if text == "" {
+		return ""
+	}
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight("// "+line, " ")
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// indent prefixes each non-empty line of code with a tab. Lines that continue
+// a raw string literal are left alone, since a tab would change its value.
+func indent(code string) string {
+	inRaw := rawStringLines(code)
+	lines := strings.Split(code, "\n")
+	for i, line := range lines {
+		if line != "" && !inRaw[i] {
+			lines[i] = "\t" + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// rawStringLines returns the indexes of the lines of code that begin inside a
+// raw string literal.
+func rawStringLines(code string) map[int]bool {
+	if !strings.Contains(code, "`") {
+		return nil
+	}
+	fset := token.NewFileSet()
+	file := fset.AddFile("", -1, len(code))
+	var s scanner.Scanner
+	s.Init(file, []byte(code), nil, 0)
+	inRaw := map[int]bool{}
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			return inRaw
+		}
+		if tok != token.STRING || !strings.HasPrefix(lit, "`") {
+			continue
+		}
+		// Lines are numbered from 1, so the literal's first line has index
+		// line-1 and the lines it continues on follow it.
+		line := file.Line(pos)
+		for n := strings.Count(lit, "\n"); n > 0; n-- {
+			inRaw[line-1+n] = true
+		}
+	}
+}
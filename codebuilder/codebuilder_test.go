@@ -0,0 +1,301 @@
+package codebuilder
+
+import (
+	"errors"
+	"fmt"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+
+	"github.com/meta-programming/go-codegenutil"
+	"github.com/meta-programming/go-codegenutil/debugutil"
+)
+
+func TestCode_GoCode(t *testing.T) {
+	mathMax := codegenutil.Sym("math", "Max")
+	otherMax := codegenutil.Sym("alternative/math", "Max")
+	duration := codegenutil.Sym("time", "Duration")
+
+	tests := []struct {
+		name string
+		code Code
+		want string
+	}{
+		{
+			name: "raw",
+			code: Raw("x + 1"),
+			want: "x + 1",
+		},
+		{
+			name: "codef with conflicting symbols",
+			code: Codef("%s(%s(1, 2), %d)", mathMax, otherMax, 3),
+			want: "math.Max(math2.Max(1, 2), 3)",
+		},
+		{
+			name: "nested blocks",
+			code: Block(Raw("for i := range x"), Block(Raw("if x[i] > 0"), Raw("n++"))),
+			want: "for i := range x {\n\tif x[i] > 0 {\n\t\tn++\n\t}\n}",
+		},
+		{
+			name: "blocks with a multi-line raw string",
+			code: Block(Raw("if verbose"), Block(Raw("for range x"), Raw("print(`a\n  b\n`)"))),
+			want: "if verbose {\n\tfor range x {\n\t\tprint(`a\n  b\n`)\n\t}\n}",
+		},
+		{
+			name: "var",
+			code: Var("timeout").Type(duration).Value(Codef("5 * %s", codegenutil.Sym("time", "Second"))),
+			want: "var timeout time.Duration = 5 * time.Second",
+		},
+		{
+			name: "const with doc",
+			code: Const("answer").Doc("answer is the answer.\n\nIt is final.").Value(Raw("42")),
+			want: "// answer is the answer.\n//\n// It is final.\nconst answer = 42",
+		},
+		{
+			name: "const group",
+			code: Consts(
+				Const("A").Type(Raw("Kind")).Value(Raw("iota")).Doc("A is first."),
+				Const("Bee"),
+			),
+			want: "const (\n\t// A is first.\n\tA Kind = iota\n\tBee\n)",
+		},
+		{
+			name: "var group",
+			code: Vars(Var("a").Value(Raw("1")), Var("long").Type(duration)),
+			want: "var (\n\ta    = 1\n\tlong time.Duration\n)",
+		},
+		{
+			name: "type",
+			code: TypeDecl("Durations", Codef("[]%s", duration)),
+			want: "type Durations []time.Duration",
+		},
+		{
+			name: "type alias",
+			code: TypeDecl("D", duration).Alias(),
+			want: "type D = time.Duration",
+		},
+		{
+			name: "generic type",
+			code: TypeDecl("Set", Raw("map[K]struct{}")).TypeParams(P("K", Raw("comparable"))),
+			want: "type Set[K comparable] map[K]struct{}",
+		},
+		{
+			name: "generic method",
+			code: func() Code {
+				set := TypeDecl("Set", Raw("map[K]struct{}")).TypeParams(P("K", Raw("comparable")))
+				return Func("Has").Receiver("s", set.Instantiated()).Params(P("k", Raw("K"))).Results(P("", Raw("bool"))).Body(Raw("_, ok := s[k]"), Raw("return ok"))
+			}(),
+			want: "func (s Set[K]) Has(k K) bool {\n\t_, ok := s[k]\n\treturn ok\n}",
+		},
+		{
+			name: "func without results",
+			code: Func("f").Params(P("a", Raw("int")), P("b", Raw("int"))).Body(Raw("println(a, b)")),
+			want: "func f(a int, b int) {\n\tprintln(a, b)\n}",
+		},
+		{
+			name: "func with named results and nested statements",
+			code: Func("f").
+				Results(P("n", Raw("int")), P("err", Raw("error"))).
+				Body(Lines(Raw("if true {"), Raw("\treturn 1, nil"), Raw("}")), Raw("return 0, nil")),
+			want: "func f() (n int, err error) {\n\tif true {\n\t\treturn 1, nil\n\t}\n\treturn 0, nil\n}",
+		},
+		{
+			name: "func with a multi-line raw string",
+			code: Func("usage").Results(P("", Raw("string"))).
+				Body(Raw("const q = '`'"), Raw("return `Usage:\n\tcmd [flags]\n\n` + string(q)")),
+			want: "func usage() string {\n\tconst q = '`'\n\treturn `Usage:\n\tcmd [flags]\n\n` + string(q)\n}",
+		},
+		{
+			name: "method",
+			code: Func("String").Receiver("d", Codef("*%s", codegenutil.Sym("abc.xyz/mypkg", "Durations"))).
+				Results(P("", Raw("string"))).
+				Body(Codef("return %s(*d)", codegenutil.Sym("fmt", "Sprint"))),
+			want: "func (d *Durations) String() string {\n\treturn fmt.Sprint(*d)\n}",
+		},
+		{
+			name: "method with unnamed receiver",
+			code: Func("Close").Receiver("", Raw("closer")).Results(P("", Raw("error"))).Body(Raw("return nil")),
+			want: "func (closer) Close() error {\n\treturn nil\n}",
+		},
+		{
+			name: "generic variadic func",
+			code: Func("Max").
+				TypeParams(P("T", codegenutil.Sym("golang.org/x/exp/constraints", "Ordered"))).
+				Params(P("first", Raw("T")), P("rest", Raw("T")).Variadic()).
+				Results(P("", Raw("T"))).
+				Body(Raw("return first")),
+			want: "func Max[T constraints.Ordered](first T, rest ...T) T {\n\treturn first\n}",
+		},
+		{
+			name: "func type",
+			code: FuncType([]*Param{P("d", duration)}, []*Param{P("", Raw("error"))}),
+			want: "func(d time.Duration) error",
+		},
+		{
+			name: "unnamed params and results",
+			code: Func("f").Params(P("", duration), P("", Raw("string")).Variadic()).Results(P("", Raw("int")), P("", Raw("error"))),
+			want: "func f(time.Duration, ...string) (int, error) {\n}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imports := codegenutil.NewFileImports(codegenutil.AssumedPackageName("abc.xyz/mypkg"))
+			if got := tt.code.GoCode(imports); got != tt.want {
+				t.Errorf("GoCode() got != want:\n%s", debugutil.SideBySide(got, tt.want))
+			}
+		})
+	}
+}
+
+func TestString(t *testing.T) {
+	mathMax := codegenutil.Sym("math", "Max")
+	otherMax := codegenutil.Sym("alternative/math", "Max")
+	rawMessage := codegenutil.Sym("encoding/json", "RawMessage")
+
+	tests := []struct {
+		code Code
+		want string
+	}{
+		{Call(mathMax, Call(otherMax, Ident("a"), Lit(1)), Lit(2)), `"math".Max("alternative/math".Max(a, 1), 2)`},
+		{TypeOf(types.NewSlice(types.NewNamed(types.NewTypeName(token.NoPos, types.NewPackage("encoding/json", "json"), "RawMessage", nil), types.Typ[types.Byte], nil))), `[]"encoding/json".RawMessage`},
+		{FuncType([]*Param{P("m", rawMessage)}, []*Param{P("", Raw("error"))}), `func(m "encoding/json".RawMessage) error`},
+		{Codef("*%s", rawMessage), `*"encoding/json".RawMessage`},
+		{Struct(F("M", rawMessage)), "struct {\n\tM \"encoding/json\".RawMessage\n}"},
+		{Tilde(Raw("int")), "~int"},
+		{Union(Tilde(Raw("int")), T(rawMessage)), `~int | "encoding/json".RawMessage`},
+	}
+	for _, tt := range tests {
+		if got := String(tt.code); got != tt.want {
+			t.Errorf("String() = %s, want %s", got, tt.want)
+		}
+		// The types of the package implement fmt.Stringer.
+		if got := fmt.Sprint(tt.code); got != tt.want {
+			t.Errorf("fmt.Sprint() = %s, want %s", got, tt.want)
+		}
+	}
+}
+
+func TestFile_Render(t *testing.T) {
+	tests := []struct {
+		name    string
+		decls   []Code
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "no declarations",
+			want: "package mypkg\n",
+		},
+		{
+			name:  "declarations without imports",
+			decls: []Code{Var("x").Value(Raw("1")), Var("y").Value(Raw("2"))},
+			want:  "package mypkg\n\nvar x = 1\n\nvar y = 2\n",
+		},
+		{
+			name: "symbol from the file's own package",
+			decls: []Code{
+				Var("y").Value(codegenutil.Sym("abc.xyz/mypkg", "x")),
+			},
+			want: "package mypkg\n\nvar y = x\n",
+		},
+		{
+			name:    "invalid code",
+			decls:   []Code{Raw("var x = (")},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewFile(codegenutil.AssumedPackageName("abc.xyz/mypkg")).Add(tt.decls...).Render(nil)
+			if gotErr := err != nil; gotErr != tt.wantErr {
+				t.Fatalf("Render() got error %v, wantErr = %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if string(got) != tt.want {
+				t.Errorf("Render() got != want:\n%s", debugutil.SideBySide(string(got), tt.want))
+			}
+		})
+	}
+}
+
+func TestFile_Render_goVersion(t *testing.T) {
+	pkg := codegenutil.AssumedPackageName("abc.xyz/mypkg")
+	tests := []struct {
+		name    string
+		decl    Code
+		want    string
+		wantErr string
+	}{
+		{
+			name: "any fallback",
+			decl: Var("x").Type(Any()),
+			want: "var x interface{}",
+		},
+		{
+			name:    "generic function",
+			decl:    Func("Id").TypeParams(P("T", Any())).Params(P("v", Raw("T"))).Results(P("", Raw("T"))).Body(Raw("return v")),
+			wantErr: "type parameters requires go1.18, but the target Go version is go1.17",
+		},
+		{
+			name:    "instantiation",
+			decl:    Var("x").Value(Call(Instantiate(codegenutil.Sym("example.com/gen", "Zero"), Raw("int")))),
+			wantErr: "type parameters requires go1.18",
+		},
+		{
+			name:    "constraint",
+			decl:    TypeDecl("Number", Integer()),
+			wantErr: "type sets requires go1.18",
+		},
+		{
+			name:    "comparable",
+			decl:    TypeDecl("Key", Constraint().Embed(Comparable())),
+			wantErr: "comparable requires go1.18",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imports := codegenutil.NewFileImports(pkg, codegenutil.WithGoVersion("go1.17"))
+			got, err := NewFile(pkg).Add(tt.decl).Render(imports)
+			if tt.wantErr != "" {
+				if !errors.Is(err, codegenutil.ErrGoVersion) || !strings.HasPrefix(err.Error(), tt.wantErr) {
+					t.Errorf("Render() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Render() error: %v", err)
+			}
+			if want := "package mypkg\n\n" + tt.want + "\n"; string(got) != want {
+				t.Errorf("Render() (want|got):\n%s", debugutil.SideBySide(string(got), want))
+			}
+		})
+	}
+}
+
+func TestFile_GoGenerate(t *testing.T) {
+	got, err := NewFile(codegenutil.AssumedPackageName("abc.xyz/mypkg")).
+		GoGenerate("stringer", "-type", "Color").
+		GoGenerate("sh", "-c", "echo hi > out.txt").
+		Add(Var("d").Type(codegenutil.Sym("time", "Duration"))).
+		Render(nil)
+	if err != nil {
+		t.Fatalf("Render() error: %v", err)
+	}
+	want := `package mypkg
+
+import (
+	"time"
+)
+
+//go:generate stringer -type Color
+//go:generate sh -c "echo hi > out.txt"
+
+var d time.Duration
+`
+	if string(got) != want {
+		t.Errorf("Render() got != want:\n%s", debugutil.SideBySide(string(got), want))
+	}
+}
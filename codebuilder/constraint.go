@@ -0,0 +1,188 @@
+package codebuilder
+
+import (
+	"strings"
+
+	"github.com/meta-programming/go-codegenutil"
+)
+
+// Term is a term of a union in a type constraint, such as int or ~string. See
+// T and Tilde.
+type Term struct {
+	typ   Code
+	tilde bool
+}
+
+// T returns a union term that matches the type typ only.
+func T(typ Code) *Term {
+	return &Term{typ: typ}
+}
+
+// Tilde returns a union term that matches all the types whose underlying type
+// is typ, written ~typ.
+func Tilde(typ Code) *Term {
+	return &Term{typ: typ, tilde: true}
+}
+
+// Type returns the type of the term.
+func (t *Term) Type() Code { return t.typ }
+
+// IsTilde reports whether the term matches the types whose underlying type is
+// its type.
+func (t *Term) IsTilde() bool { return t.tilde }
+
+// String returns the term as it is written in logs; see String.
+func (t *Term) String() string { return String(t) }
+
+// GoCode returns the term as it appears in a union.
+func (t *Term) GoCode(imports *codegenutil.FileImports) string {
+	requireGoVersion(imports, "type sets", "go1.18")
+	if t.tilde {
+		return "~" + t.typ.GoCode(imports)
+	}
+	return t.typ.GoCode(imports)
+}
+
+// Union returns the union of terms, such as ~int | ~int64, which can be the
+// constraint of a type parameter without an enclosing interface, as in
+// [T ~int | ~int64]. Constraints that are declared as types are built with
+// Constraint.
+func Union(terms ...*Term) Code {
+	return codeFunc(func(imports *codegenutil.FileImports) string { return union(imports, terms) })
+}
+
+func union(imports *codegenutil.FileImports, terms []*Term) string {
+	parts := make([]string, len(terms))
+	for i, t := range terms {
+		parts[i] = t.GoCode(imports)
+	}
+	return strings.Join(parts, " | ")
+}
+
+// ConstraintBuilder builds a constraint interface. See Constraint.
+type ConstraintBuilder struct {
+	embeds  []Code
+	terms   []*Term
+	methods []*constraintMethod
+}
+
+type constraintMethod struct {
+	name            string
+	params, results []*Param
+}
+
+// Constraint returns a builder for a constraint interface whose type set is
+// the union of terms, such as
+//
+//	interface {
+//		~int | ~int64
+//	}
+//
+// The result is usually passed to TypeDecl, or used as the constraint of a type
+// parameter.
+func Constraint(terms ...*Term) *ConstraintBuilder {
+	return &ConstraintBuilder{terms: terms}
+}
+
+// Or adds terms to the union of the constraint.
+func (b *ConstraintBuilder) Or(terms ...*Term) *ConstraintBuilder {
+	b.terms = append(b.terms, terms...)
+	return b
+}
+
+// Embed embeds constraints in the constraint, such as comparable or
+// fmt.Stringer, which the types that satisfy it must also satisfy.
+func (b *ConstraintBuilder) Embed(constraints ...Code) *ConstraintBuilder {
+	b.embeds = append(b.embeds, constraints...)
+	return b
+}
+
+// Method adds a method that the types that satisfy the constraint must have.
+func (b *ConstraintBuilder) Method(name string, params, results []*Param) *ConstraintBuilder {
+	b.methods = append(b.methods, &constraintMethod{name, params, results})
+	return b
+}
+
+// Terms returns the terms of the union of the constraint.
+func (b *ConstraintBuilder) Terms() []*Term { return b.terms }
+
+// Inline returns the union of the constraint, without an enclosing interface,
+// if the constraint has no embedded constraints or methods, and the constraint
+// itself otherwise. It is meant for type parameter lists, as in
+// [T ~int | ~int64].
+func (b *ConstraintBuilder) Inline() Code {
+	if len(b.embeds) > 0 || len(b.methods) > 0 || len(b.terms) == 0 {
+		return b
+	}
+	return Union(b.terms...)
+}
+
+// String returns the interface type as it is written in logs; see String.
+func (b *ConstraintBuilder) String() string { return String(b) }
+
+// GoCode returns the interface type, with the embedded constraints first, then
+// the union and the methods, each on its own line.
+func (b *ConstraintBuilder) GoCode(imports *codegenutil.FileImports) string {
+	var lines []string
+	for _, e := range b.embeds {
+		lines = append(lines, e.GoCode(imports))
+	}
+	if len(b.terms) > 0 {
+		lines = append(lines, union(imports, b.terms))
+	}
+	for _, m := range b.methods {
+		lines = append(lines, m.name+signature(imports, m.params, m.results))
+	}
+	if len(lines) == 0 {
+		return "interface{}"
+	}
+	return "interface {\n" + indent(strings.Join(lines, "\n")) + "\n}"
+}
+
+// Any returns the any constraint, which every type satisfies.
+func Any() Code { return codegenutil.BuiltinPackage.Symbol("any") }
+
+// Comparable returns the comparable constraint, which the types that support
+// == and != satisfy.
+func Comparable() Code { return codegenutil.BuiltinPackage.Symbol("comparable") }
+
+// Signed returns a constraint that the signed integer types satisfy, like
+// that of golang.org/x/exp/constraints.
+func Signed() *ConstraintBuilder {
+	return Constraint(tildes("int", "int8", "int16", "int32", "int64")...)
+}
+
+// Unsigned returns a constraint that the unsigned integer types satisfy.
+func Unsigned() *ConstraintBuilder {
+	return Constraint(tildes("uint", "uint8", "uint16", "uint32", "uint64", "uintptr")...)
+}
+
+// Integer returns a constraint that the integer types satisfy.
+func Integer() *ConstraintBuilder {
+	return Signed().Or(Unsigned().Terms()...)
+}
+
+// Float returns a constraint that the floating-point types satisfy.
+func Float() *ConstraintBuilder {
+	return Constraint(tildes("float32", "float64")...)
+}
+
+// Complex returns a constraint that the complex types satisfy.
+func Complex() *ConstraintBuilder {
+	return Constraint(tildes("complex64", "complex128")...)
+}
+
+// Ordered returns a constraint that the types that support the operators < <=
+// >= and > satisfy, like cmp.Ordered.
+func Ordered() *ConstraintBuilder {
+	return Integer().Or(Float().Terms()...).Or(tildes("string")...)
+}
+
+// tildes returns the tilde terms of the predeclared types with the given names.
+func tildes(names ...string) []*Term {
+	terms := make([]*Term, len(names))
+	for i, name := range names {
+		terms[i] = Tilde(codegenutil.BuiltinPackage.Symbol(name))
+	}
+	return terms
+}
@@ -0,0 +1,99 @@
+package codebuilder
+
+import (
+	"testing"
+
+	"github.com/meta-programming/go-codegenutil"
+	"github.com/meta-programming/go-codegenutil/debugutil"
+)
+
+func TestConstraintBuilder_GoCode(t *testing.T) {
+	duration := codegenutil.Sym("time", "Duration")
+	stringer := codegenutil.Sym("fmt", "Stringer")
+
+	tests := []struct {
+		name string
+		code Code
+		want string
+	}{
+		{
+			name: "empty",
+			code: Constraint(),
+			want: "interface{}",
+		},
+		{
+			name: "union",
+			code: Constraint(Tilde(Raw("int")), T(duration)).Or(Tilde(Raw("string"))),
+			want: "interface {\n\t~int | time.Duration | ~string\n}",
+		},
+		{
+			name: "embeds and methods",
+			code: Constraint(Tilde(Raw("int"))).
+				Embed(Comparable(), stringer).
+				Method("Set", []*Param{P("s", Raw("string"))}, []*Param{P("", Raw("error"))}),
+			want: "interface {\n\tcomparable\n\tfmt.Stringer\n\t~int\n\tSet(s string) error\n}",
+		},
+		{
+			name: "inline union",
+			code: Func("Max").TypeParams(P("T", Float().Inline())).Params(P("a", Raw("T")), P("b", Raw("T"))).Results(P("", Raw("T"))).Body(Raw("return a")),
+			want: "func Max[T ~float32 | ~float64](a T, b T) T {\n\treturn a\n}",
+		},
+		{
+			name: "inline with methods",
+			code: Constraint(Tilde(Raw("int"))).Method("String", nil, []*Param{P("", Raw("string"))}).Inline(),
+			want: "interface {\n\t~int\n\tString() string\n}",
+		},
+		{
+			name: "union without interface",
+			code: Union(Tilde(Raw("int")), T(duration)),
+			want: "~int | time.Duration",
+		},
+		{
+			name: "ordered in a type declaration",
+			code: TypeDecl("Ordered", Ordered()),
+			want: "type Ordered interface {\n\t~int | ~int8 | ~int16 | ~int32 | ~int64 | ~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr | ~float32 | ~float64 | ~string\n}",
+		},
+		{
+			name: "common constraints",
+			code: Lines(P("A", Any()), P("C", Comparable()), P("X", Complex().Inline())),
+			want: "A any\nC comparable\nX ~complex64 | ~complex128",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imports := codegenutil.NewFileImports(codegenutil.AssumedPackageName("abc.xyz/mypkg"))
+			if got := tt.code.GoCode(imports); got != tt.want {
+				t.Errorf("GoCode() got != want:\n%s", debugutil.SideBySide(got, tt.want))
+			}
+		})
+	}
+}
+
+func TestConstraint_render(t *testing.T) {
+	imports := codegenutil.NewFileImports(codegenutil.AssumedPackageName("abc.xyz/mypkg"))
+	got, err := NewFile(imports.Package()).Add(
+		TypeDecl("Number", Integer().Or(Float().Terms()...)),
+		TypeDecl("Named", Constraint(Tilde(Raw("string"))).Embed(codegenutil.Sym("fmt", "Stringer"))),
+	).Render(imports)
+	if err != nil {
+		t.Fatalf("Render() error: %v", err)
+	}
+	want := `package mypkg
+
+import (
+	"fmt"
+)
+
+type Number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 | ~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr | ~float32 | ~float64
+}
+
+type Named interface {
+	fmt.Stringer
+	~string
+}
+`
+	if string(got) != want {
+		t.Errorf("Render() got != want:\n%s", debugutil.SideBySide(string(got), want))
+	}
+}
@@ -0,0 +1,81 @@
+package codebuilder
+
+import "sort"
+
+// declInfo is the name and the dependencies of a declaration added with
+// File.Declare.
+type declInfo struct {
+	name string
+	deps []string
+}
+
+// Declare appends a declaration of the named symbol to the file, like Add,
+// and records the names of the other declarations of the file that it
+// depends on. Declarations are printed in dependency order, so that readers
+// find each declaration after those it uses, whatever order the generator
+// discovered them in: the declarations are printed in the order in which they
+// were added, except that those a declaration depends on are moved just
+// before it if they would otherwise come after it. Dependencies on names that
+// aren't declared in the file are ignored, and so are the dependencies that
+// close a cycle. If several declarations have the same name, such as the
+// String methods of different types, dependencies on it refer to the first.
+func (f *File) Declare(name string, decl Code, dependsOn ...string) *File {
+	if f.declared == nil {
+		f.declared = map[int]*declInfo{}
+	}
+	f.declared[len(f.decls)] = &declInfo{name, dependsOn}
+	f.decls = append(f.decls, decl)
+	return f
+}
+
+// orderedDecls returns the declarations of the file in the order in which they
+// are printed; see Declare.
+func (f *File) orderedDecls() []Code {
+	if len(f.declared) == 0 {
+		return f.decls
+	}
+	// byName maps names to the first declaration of each, so that the
+	// order doesn't depend on the iteration order of f.declared.
+	byName := map[string]int{}
+	for i := range f.decls {
+		if info := f.declared[i]; info != nil {
+			if _, ok := byName[info.name]; !ok {
+				byName[info.name] = i
+			}
+		}
+	}
+	const (
+		unvisited = iota
+		visiting
+		printed
+	)
+	state := make([]int, len(f.decls))
+	out := make([]Code, 0, len(f.decls))
+	var visit func(i int)
+	visit = func(i int) {
+		if state[i] != unvisited {
+			return
+		}
+		state[i] = visiting
+		if info := f.declared[i]; info != nil {
+			var deps []int
+			for _, dep := range info.deps {
+				if j, ok := byName[dep]; ok {
+					deps = append(deps, j)
+				}
+			}
+			// Dependencies are printed in the order in which they were
+			// added rather than the order in which they are listed.
+			sort.Ints(deps)
+			for _, j := range deps {
+				visit(j)
+			}
+		}
+		state[i] = printed
+		out = append(out, f.decls[i])
+	}
+	for i := range f.decls {
+		visit(i)
+	}
+	return out
+}
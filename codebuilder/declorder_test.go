@@ -0,0 +1,74 @@
+package codebuilder
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/meta-programming/go-codegenutil"
+)
+
+func TestFile_Declare(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(f *File)
+		want  string
+	}{
+		{
+			name: "dependencies first",
+			build: func(f *File) {
+				f.Declare("a", Raw("// a"), "b", "c")
+				f.Declare("b", Raw("// b"), "c")
+				f.Add(Raw("// unnamed"))
+				f.Declare("c", Raw("// c"))
+			},
+			want: "c b a unnamed",
+		},
+		{
+			name: "stable ties",
+			build: func(f *File) {
+				f.Declare("x", Raw("// x"))
+				f.Declare("y", Raw("// y"), "external.Thing")
+				f.Declare("z", Raw("// z"), "x")
+			},
+			want: "x y z",
+		},
+		{
+			name: "cycle",
+			build: func(f *File) {
+				f.Declare("Node", Raw("// Node"), "Edge")
+				f.Declare("Edge", Raw("// Edge"), "Node", "Weight")
+				f.Declare("Weight", Raw("// Weight"))
+			},
+			want: "Weight Edge Node",
+		},
+		{
+			name: "duplicate names",
+			build: func(f *File) {
+				f.Declare("Kind", Raw("// Kind"))
+				f.Declare("Print", Raw("// Print"), "String")
+				f.Declare("String", Raw("// first String"))
+				f.Declare("String", Raw("// second String"))
+			},
+			want: "Kind first String Print second String",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Repeat the rendering, which mustn't depend on map order.
+			for i := 0; i < 20; i++ {
+				f := NewFile(codegenutil.AssumedPackageName("abc.xyz/mypkg"))
+				tt.build(f)
+				code := f.GoCode(codegenutil.NewFileImports(f.Package()))
+				var got []string
+				for _, line := range strings.Split(code, "\n") {
+					if strings.HasPrefix(line, "// ") {
+						got = append(got, strings.TrimPrefix(line, "// "))
+					}
+				}
+				if strings.Join(got, " ") != tt.want {
+					t.Fatalf("declarations printed in order %q, want %q", strings.Join(got, " "), tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package codebuilder
+
+import (
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strconv"
+
+	"github.com/meta-programming/go-codegenutil"
+)
+
+// Zero returns the zero value of typ: 0, "", false, nil, or a composite literal
+// such as T{} for struct and array types. The zero value of a type created
+// with TypeOf is derived from its underlying type. Other named types, whose
+// underlying types are unknown, have the zero value *new(T).
+func Zero(typ Code) Expr {
+	// The precedence is that of *new(T), the loosest of the zero values.
+	return newExpr(token.UnaryPrec, func(imports *codegenutil.FileImports) string {
+		t := typ.GoCode(imports)
+		if tc, ok := typ.(*typeCode); ok {
+			return zeroOf(tc.typ, t)
+		}
+		parsed, err := parser.ParseExpr(t)
+		if err != nil {
+			return "*new(" + t + ")"
+		}
+		switch parsed := parsed.(type) {
+		case *ast.Ident:
+			if obj := types.Universe.Lookup(parsed.Name); obj != nil {
+				return zeroOf(obj.Type(), t)
+			}
+		case *ast.StarExpr, *ast.MapType, *ast.ChanType, *ast.FuncType, *ast.InterfaceType:
+			return "nil"
+		case *ast.ArrayType:
+			if parsed.Len == nil {
+				return "nil"
+			}
+			return t + "{}"
+		case *ast.StructType:
+			return t + "{}"
+		}
+		return "*new(" + t + ")"
+	})
+}
+
+// zeroOf returns the zero value of a go/types type that is printed as t.
+func zeroOf(typ types.Type, t string) string {
+	switch u := typ.Underlying().(type) {
+	case *types.Basic:
+		switch {
+		case u.Info()&types.IsBoolean != 0:
+			return "false"
+		case u.Info()&types.IsString != 0:
+			return `""`
+		case u.Info()&types.IsNumeric != 0:
+			return "0"
+		}
+		return "nil"
+	case *types.Pointer, *types.Slice, *types.Map, *types.Chan, *types.Signature:
+		return "nil"
+	case *types.Interface:
+		if _, ok := typ.(*types.TypeParam); ok {
+			return "*new(" + t + ")"
+		}
+		return "nil"
+	case *types.Struct, *types.Array:
+		return t + "{}"
+	}
+	return "*new(" + t + ")"
+}
+
+// Return returns a return statement of the results.
+func Return(results ...Code) Code {
+	return codeFunc(func(imports *codegenutil.FileImports) string {
+		if len(results) == 0 {
+			return "return"
+		}
+		return "return " + joinCode(imports, results, ", ")
+	})
+}
+
+// ReturnErr returns a statement that returns err from a function with the given
+// results, whose last one is an error, and the zero values of the others. It
+// panics if there are no results.
+func ReturnErr(results []*Param, err Code) Code {
+	if len(results) == 0 {
+		panic(errors.New("codebuilder.ReturnErr: the function has no results to return the error as"))
+	}
+	var values []Code
+	for _, r := range results[:len(results)-1] {
+		values = append(values, Zero(r.Type()))
+	}
+	return Return(append(values, err)...)
+}
+
+// IfErr returns the error handling statement that is idiomatic after a call
+// that sets err, for a function with the given results, whose last one is an
+// error:
+//
+//	if err != nil {
+//		return 0, fmt.Errorf("format: %w", args..., err)
+//	}
+//
+// The other results are zero values; see Zero. If format is empty, err is
+// returned as it is. Like ReturnErr, the statement panics when it is printed
+// if there are no results.
+func IfErr(results []*Param, format string, args ...Code) Code {
+	return codeFunc(func(imports *codegenutil.FileImports) string {
+		err := Ident("err")
+		if format != "" {
+			lit := Raw(strconv.Quote(format + ": %w"))
+			err = Call(codegenutil.Sym("fmt", "Errorf"), append(append([]Code{lit}, args...), err)...)
+		}
+		return Block(Raw("if err != nil"), ReturnErr(results, err)).GoCode(imports)
+	})
+}
+
+// IfErr is like the IfErr function for the results of the function, which
+// may be set after IfErr is called, as long as the function has results when
+// the statement is printed.
+func (b *FuncBuilder) IfErr(format string, args ...Code) Code {
+	return codeFunc(func(imports *codegenutil.FileImports) string {
+		return IfErr(b.results, format, args...).GoCode(imports)
+	})
+}
+
+// codeFunc is Code printed by a function.
+type codeFunc func(imports *codegenutil.FileImports) string
+
+func (c codeFunc) String() string { return String(c) }
+
+func (c codeFunc) GoCode(imports *codegenutil.FileImports) string { return c(imports) }
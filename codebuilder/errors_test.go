@@ -0,0 +1,103 @@
+package codebuilder
+
+import (
+	"go/types"
+	"strings"
+	"testing"
+
+	"github.com/meta-programming/go-codegenutil"
+	"github.com/meta-programming/go-codegenutil/debugutil"
+)
+
+func TestZero(t *testing.T) {
+	named := types.NewNamed(types.NewTypeName(0, types.NewPackage("abc.xyz/dep", "dep"), "Point", nil), types.NewStruct(nil, nil), nil)
+	duration := types.NewNamed(types.NewTypeName(0, types.NewPackage("time", "time"), "Duration", nil), types.Typ[types.Int64], nil)
+	tests := []struct {
+		typ  Code
+		want string
+	}{
+		{Raw("int"), "0"},
+		{Raw("string"), `""`},
+		{Raw("bool"), "false"},
+		{Raw("error"), "nil"},
+		{Raw("[]byte"), "nil"},
+		{Raw("map[string]int"), "nil"},
+		{Raw("*T"), "nil"},
+		{Raw("func()"), "nil"},
+		{Raw("[2]int"), "[2]int{}"},
+		{Raw("struct{}"), "struct{}{}"},
+		{codegenutil.Sym("time", "Duration"), "*new(time.Duration)"},
+		{TypeOf(duration), "0"},
+		{TypeOf(named), "dep.Point{}"},
+		{TypeOf(types.NewPointer(named)), "nil"},
+	}
+	imports := codegenutil.NewFileImports(codegenutil.AssumedPackageName("abc.xyz/mypkg"))
+	for _, tt := range tests {
+		if got := Zero(tt.typ).GoCode(imports); got != tt.want {
+			t.Errorf("Zero(%s) = %q, want %q", tt.typ.GoCode(imports), got, tt.want)
+		}
+	}
+}
+
+func TestIfErr(t *testing.T) {
+	fn := Func("load").Params(P("name", Raw("string")))
+	fn.Body(
+		Raw("data, err := read(name)"),
+		fn.IfErr("reading %s", Ident("name")),
+		Raw("n, err := parse(data)"),
+		fn.IfErr(""),
+		Return(Ident("n"), Raw("nil")),
+	)
+	fn.Results(P("", Raw("int")), P("", codegenutil.Sym("abc.xyz/dep", "Config")), P("", Raw("error")))
+	got, err := NewFile(codegenutil.AssumedPackageName("abc.xyz/mypkg")).Add(fn).Render(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `package mypkg
+
+import (
+	"fmt"
+
+	"abc.xyz/dep"
+)
+
+func load(name string) (int, dep.Config, error) {
+	data, err := read(name)
+	if err != nil {
+		return 0, *new(dep.Config), fmt.Errorf("reading %s: %w", name, err)
+	}
+	n, err := parse(data)
+	if err != nil {
+		return 0, *new(dep.Config), err
+	}
+	return n, nil
+}
+`
+	if string(got) != want {
+		t.Errorf("Render() (got|want):\n%s", debugutil.SideBySide(string(got), want))
+	}
+}
+
+func TestIfErr_noResults(t *testing.T) {
+	imports := codegenutil.NewFileImports(codegenutil.AssumedPackageName("abc.xyz/mypkg"))
+	fn := Func("run")
+	tests := []struct {
+		name string
+		code func() Code
+	}{
+		{"ReturnErr", func() Code { return ReturnErr(nil, Ident("err")) }},
+		{"IfErr", func() Code { return IfErr(nil, "") }},
+		{"FuncBuilder.IfErr", func() Code { return fn.IfErr("running") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				r := recover()
+				if err, ok := r.(error); !ok || !strings.Contains(err.Error(), "no results") {
+					t.Errorf("printing %s without results panicked with %v, want an error about the results", tt.name, r)
+				}
+			}()
+			tt.code().GoCode(imports)
+		})
+	}
+}
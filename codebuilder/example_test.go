@@ -0,0 +1,54 @@
+package codebuilder_test
+
+import (
+	"fmt"
+
+	"github.com/meta-programming/go-codegenutil"
+	cb "github.com/meta-programming/go-codegenutil/codebuilder"
+)
+
+func Example() {
+	file := cb.NewFile(codegenutil.AssumedPackageName("abc.xyz/mypkg")).
+		Doc("Package mypkg does neat things.")
+
+	stringer := codegenutil.Sym("fmt", "Stringer")
+	file.Add(
+		cb.Const("defaultName").Value(cb.Raw(`"world"`)),
+		cb.Var("greeters").
+			Doc("greeters are called by Greet.").
+			Type(cb.Codef("[]%s", stringer)),
+		cb.Func("Greet").
+			Doc("Greet prints a greeting.").
+			Params(cb.P("name", cb.Raw("string"))).
+			Results(cb.P("", codegenutil.Sym("", "error"))).
+			Body(
+				cb.Codef(`_, err := %s("hello %%s\n", name)`, codegenutil.Sym("fmt", "Printf")),
+				cb.Raw("return err"),
+			),
+	)
+
+	src, err := file.Render(nil)
+	if err != nil {
+		fmt.Printf("error rendering file: %v", err)
+		return
+	}
+	fmt.Print(string(src))
+	// Output:
+	// // Package mypkg does neat things.
+	// package mypkg
+	//
+	// import (
+	// 	"fmt"
+	// )
+	//
+	// const defaultName = "world"
+	//
+	// // greeters are called by Greet.
+	// var greeters []fmt.Stringer
+	//
+	// // Greet prints a greeting.
+	// func Greet(name string) error {
+	// 	_, err := fmt.Printf("hello %s\n", name)
+	// 	return err
+	// }
+}
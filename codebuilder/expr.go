@@ -0,0 +1,295 @@
+package codebuilder
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/meta-programming/go-codegenutil"
+)
+
+// Expr is a Go expression that knows its operator precedence, so that it is
+// parenthesized correctly when used as an operand of another Expr.
+//
+// Any Code may be used as an operand. Code that isn't an Expr is parsed after
+// it is printed to determine whether it needs parentheses.
+type Expr interface {
+	Code
+	// String returns the expression as it is written in logs; see String.
+	String() string
+	// precedence returns the precedence of the outermost operator of the
+	// expression: the token.Token.Precedence of a binary operator,
+	// token.UnaryPrec for unary expressions or primaryPrec for operands and
+	// primary expressions such as calls.
+	precedence() int
+}
+
+// primaryPrec is the precedence of operands and primary expressions, which
+// bind more tightly than any operator.
+const primaryPrec = token.HighestPrec
+
+type expr struct {
+	prec   int
+	render func(imports *codegenutil.FileImports) string
+}
+
+func (e *expr) String() string { return String(e) }
+
+func (e *expr) GoCode(imports *codegenutil.FileImports) string { return e.render(imports) }
+
+func (e *expr) precedence() int { return e.prec }
+
+func newExpr(prec int, render func(imports *codegenutil.FileImports) string) Expr {
+	return &expr{prec, render}
+}
+
+// operand returns the code of x, parenthesized if its precedence is less than
+// minPrec.
+func operand(imports *codegenutil.FileImports, x Code, minPrec int) string {
+	code, prec := codeAndPrecedence(imports, x)
+	if prec < minPrec {
+		return "(" + code + ")"
+	}
+	return code
+}
+
+func codeAndPrecedence(imports *codegenutil.FileImports, x Code) (string, int) {
+	code := x.GoCode(imports)
+	switch x := x.(type) {
+	case Expr:
+		return code, x.precedence()
+	case *codegenutil.Symbol:
+		return code, primaryPrec
+	}
+	parsed, err := parser.ParseExpr(code)
+	if err != nil {
+		return code, token.LowestPrec
+	}
+	switch parsed := parsed.(type) {
+	case *ast.BinaryExpr:
+		return code, parsed.Op.Precedence()
+	case *ast.UnaryExpr, *ast.StarExpr:
+		return code, token.UnaryPrec
+	case *ast.FuncType, *ast.ChanType:
+		// Types that start with a keyword extend as far to the right as
+		// possible, so they are parenthesized like binary expressions.
+		return code, token.LowestPrec
+	}
+	return code, primaryPrec
+}
+
+// Ident returns an identifier expression, such as the name of a local variable.
+// Use *codegenutil.Symbol for package-level identifiers.
+func Ident(name string) Expr {
+	return newExpr(primaryPrec, func(*codegenutil.FileImports) string { return name })
+}
+
+// Lit returns a literal expression for a boolean, numeric or string value, or
+// nil. Strings are quoted. NaN and infinite floats, which have no literals, are
+// written as calls of math.NaN and math.Inf, converted to float32 for float32
+// values. Lit panics for other types.
+func Lit(value any) Expr {
+	var code string
+	switch v := value.(type) {
+	case nil:
+		code = "nil"
+	case string:
+		code = strconv.Quote(v)
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, uintptr:
+		code = fmt.Sprint(v)
+	case float32:
+		if call := nonFiniteFloat(float64(v)); call != nil {
+			return Convert(Raw("float32"), call)
+		}
+		code = floatLit(float64(v), 32)
+	case float64:
+		if call := nonFiniteFloat(v); call != nil {
+			return call
+		}
+		code = floatLit(v, 64)
+	default:
+		panic(fmt.Errorf("codebuilder.Lit: unsupported type %T", value))
+	}
+	if strings.HasPrefix(code, "-") {
+		return newExpr(token.UnaryPrec, func(*codegenutil.FileImports) string { return code })
+	}
+	return newExpr(primaryPrec, func(*codegenutil.FileImports) string { return code })
+}
+
+// floatLit formats a float so that it is an untyped floating-point constant.
+func floatLit(v float64, bitSize int) string {
+	code := strconv.FormatFloat(v, 'g', -1, bitSize)
+	if !strings.ContainsAny(code, ".e") {
+		code += ".0"
+	}
+	return code
+}
+
+// nonFiniteFloat returns the call of package math that returns v if v is NaN or
+// infinite, and nil otherwise.
+func nonFiniteFloat(v float64) Expr {
+	switch {
+	case math.IsNaN(v):
+		return Call(codegenutil.Sym("math", "NaN"))
+	case math.IsInf(v, 1):
+		return Call(codegenutil.Sym("math", "Inf"), Lit(1))
+	case math.IsInf(v, -1):
+		return Call(codegenutil.Sym("math", "Inf"), Lit(-1))
+	}
+	return nil
+}
+
+// Call returns a call expression: fn(args...).
+func Call(fn Code, args ...Code) Expr {
+	return newExpr(primaryPrec, func(imports *codegenutil.FileImports) string {
+		return operand(imports, fn, primaryPrec) + "(" + joinCode(imports, args, ", ") + ")"
+	})
+}
+
+// CallVariadic returns a call expression whose final argument is a slice
+// passed to a variadic parameter: fn(args...)...
+func CallVariadic(fn Code, args ...Code) Expr {
+	return newExpr(primaryPrec, func(imports *codegenutil.FileImports) string {
+		return operand(imports, fn, primaryPrec) + "(" + joinCode(imports, args, ", ") + "...)"
+	})
+}
+
+// Sel returns a selector expression: x.name.
+func Sel(x Code, name string) Expr {
+	return newExpr(primaryPrec, func(imports *codegenutil.FileImports) string {
+		return operand(imports, x, primaryPrec) + "." + name
+	})
+}
+
+// Index returns an index expression, x[index]. Multiple indices may be used to
+// instantiate generic functions and types: x[T1, T2].
+func Index(x Code, indices ...Code) Expr {
+	return newExpr(primaryPrec, func(imports *codegenutil.FileImports) string {
+		return operand(imports, x, primaryPrec) + "[" + joinCode(imports, indices, ", ") + "]"
+	})
+}
+
+// Instantiate returns an instantiation of a generic function or type, such as
+// a *codegenutil.Symbol, with the given type arguments: generic[T1, T2]. If
+// there are no type arguments, generic is returned as is.
+func Instantiate(generic Code, typeArgs ...Code) Expr {
+	if len(typeArgs) == 0 {
+		return newExpr(primaryPrec, func(imports *codegenutil.FileImports) string {
+			return operand(imports, generic, primaryPrec)
+		})
+	}
+	return newExpr(primaryPrec, func(imports *codegenutil.FileImports) string {
+		requireGoVersion(imports, "type parameters", "go1.18")
+		return operand(imports, generic, primaryPrec) + "[" + joinCode(imports, typeArgs, ", ") + "]"
+	})
+}
+
+// SliceExpr returns a slice expression, x[low:high]. Either bound may be nil.
+func SliceExpr(x, low, high Code) Expr {
+	return newExpr(primaryPrec, func(imports *codegenutil.FileImports) string {
+		bound := func(c Code) string {
+			if c == nil {
+				return ""
+			}
+			return c.GoCode(imports)
+		}
+		return operand(imports, x, primaryPrec) + "[" + bound(low) + ":" + bound(high) + "]"
+	})
+}
+
+// TypeAssert returns a type assertion: x.(typ).
+func TypeAssert(x, typ Code) Expr {
+	return newExpr(primaryPrec, func(imports *codegenutil.FileImports) string {
+		return operand(imports, x, primaryPrec) + ".(" + typ.GoCode(imports) + ")"
+	})
+}
+
+// Convert returns a conversion of x to typ: typ(x). Types that would be
+// ambiguous in a conversion, such as pointer and function types, are
+// parenthesized: (*T)(x).
+func Convert(typ, x Code) Expr {
+	return newExpr(primaryPrec, func(imports *codegenutil.FileImports) string {
+		t := typ.GoCode(imports)
+		if conversionNeedsParens(t) {
+			t = "(" + t + ")"
+		}
+		return t + "(" + x.GoCode(imports) + ")"
+	})
+}
+
+// conversionNeedsParens reports whether the type must be parenthesized to be
+// converted to: pointer types, receive-only channel types and function types,
+// whose results would otherwise absorb the operand.
+func conversionNeedsParens(typ string) bool {
+	parsed, err := parser.ParseExpr(typ)
+	if err != nil {
+		return strings.HasPrefix(typ, "*") || strings.HasPrefix(typ, "<-") || strings.HasPrefix(typ, "func")
+	}
+	switch parsed := parsed.(type) {
+	case *ast.StarExpr, *ast.FuncType:
+		return true
+	case *ast.ChanType:
+		return parsed.Dir == ast.RECV
+	case *ast.UnaryExpr:
+		return parsed.Op == token.ARROW
+	}
+	return false
+}
+
+// Paren returns x in parentheses.
+func Paren(x Code) Expr {
+	return newExpr(primaryPrec, func(imports *codegenutil.FileImports) string {
+		return "(" + x.GoCode(imports) + ")"
+	})
+}
+
+// Unary returns a unary expression such as -x, !x, *x, &x or <-x. Unary panics
+// if op is not a unary operator.
+func Unary(op string, x Code) Expr {
+	switch op {
+	case "+", "-", "!", "^", "*", "&", "<-":
+	default:
+		panic(fmt.Errorf("codebuilder.Unary: invalid operator %q", op))
+	}
+	return newExpr(token.UnaryPrec, func(imports *codegenutil.FileImports) string {
+		code := operand(imports, x, token.UnaryPrec)
+		// Operators must not merge with the operand's leading operator
+		// into a different token, as in "--x" or "&&x".
+		if (op == "-" || op == "+" || op == "&") && strings.HasPrefix(code, op) {
+			code = "(" + code + ")"
+		}
+		return op + code
+	})
+}
+
+// Binary returns a binary expression, x op y, where op is an arithmetic,
+// comparison or logical operator. Operands are parenthesized as needed to
+// preserve the structure of the expression; Binary(Binary(a, "+", b), "*", c)
+// is printed as (a + b) * c. Binary panics if op is not a binary operator.
+func Binary(x Code, op string, y Code) Expr {
+	tok, ok := binaryOps[op]
+	if !ok {
+		panic(fmt.Errorf("codebuilder.Binary: invalid operator %q", op))
+	}
+	prec := tok.Precedence()
+	return newExpr(prec, func(imports *codegenutil.FileImports) string {
+		// Binary operators are left associative, so a right operand with
+		// the same precedence must be parenthesized.
+		return operand(imports, x, prec) + " " + op + " " + operand(imports, y, prec+1)
+	})
+}
+
+var binaryOps = func() map[string]token.Token {
+	ops := map[string]token.Token{}
+	for tok := token.ADD; tok <= token.AND_NOT; tok++ {
+		ops[tok.String()] = tok
+	}
+	for _, tok := range []token.Token{token.LAND, token.LOR, token.EQL, token.NEQ, token.LSS, token.LEQ, token.GTR, token.GEQ} {
+		ops[tok.String()] = tok
+	}
+	return ops
+}()
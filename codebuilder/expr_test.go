@@ -0,0 +1,73 @@
+package codebuilder
+
+import (
+	"math"
+	"testing"
+
+	"github.com/meta-programming/go-codegenutil"
+)
+
+func TestExpr(t *testing.T) {
+	a, b, c := Ident("a"), Ident("b"), Ident("c")
+	buffer := codegenutil.Sym("bytes", "Buffer")
+
+	tests := []struct {
+		name string
+		code Code
+		want string
+	}{
+		{"literals", Call(Ident("f"), Lit("x\n"), Lit(1), Lit(2.0), Lit(-1), Lit(true), Lit(nil)), `f("x\n", 1, 2.0, -1, true, nil)`},
+		{"float literals", Call(Ident("f"), Lit(1e100), Lit(float32(0.5)), Lit(-2.5)), "f(1e+100, 0.5, -2.5)"},
+		{"non-finite floats", Call(Ident("f"), Lit(math.NaN()), Lit(math.Inf(1)), Lit(math.Inf(-1))), "f(math.NaN(), math.Inf(1), math.Inf(-1))"},
+		{"non-finite float32", Binary(Lit(float32(math.Inf(-1))), "<", a), "float32(math.Inf(-1)) < a"},
+		{"left associative", Binary(Binary(a, "-", b), "-", c), "a - b - c"},
+		{"right operand", Binary(a, "-", Binary(b, "-", c)), "a - (b - c)"},
+		{"higher precedence operand", Binary(a, "+", Binary(b, "*", c)), "a + b * c"},
+		{"lower precedence operand", Binary(Binary(a, "+", b), "*", c), "(a + b) * c"},
+		{"logical", Binary(Binary(a, "||", b), "&&", Unary("!", c)), "(a || b) && !c"},
+		{"comparison", Binary(Call(Ident("len"), a), ">", Lit(0)), "len(a) > 0"},
+		{"unary of binary", Unary("-", Binary(a, "+", b)), "-(a + b)"},
+		{"unary of unary", Unary("-", Unary("-", a)), "-(-a)"},
+		{"address of address-like", Unary("&", Unary("&", a)), "&(&a)"},
+		{"deref", Unary("*", Unary("*", a)), "**a"},
+		{"selector of unary", Sel(Unary("*", a), "x"), "(*a).x"},
+		{"selector of call", Sel(Call(Sel(a, "Get")), "Name"), "a.Get().Name"},
+		{"selector of raw binary", Sel(Raw("a + b"), "x"), "(a + b).x"},
+		{"selector of raw operand", Sel(Raw("a[0]"), "x"), "a[0].x"},
+		{"selector of symbol", Sel(codegenutil.Sym("os", "Stdout"), "Name"), "os.Stdout.Name"},
+		{"index", Index(Lit(-1), a), "(-1)[a]"},
+		{"generic instantiation", Index(codegenutil.Sym("golang.org/x/exp/slices", "Sort"), Raw("int")), "slices.Sort[int]"},
+		{"instantiate symbol", Instantiate(codegenutil.Sym("sync/atomic", "Pointer"), Codef("[]%s", buffer)), "atomic.Pointer[[]bytes.Buffer]"},
+		{"instantiate without type arguments", Instantiate(Raw("a + b")), "(a + b)"},
+		{"slice", SliceExpr(a, nil, Binary(b, "+", Lit(1))), "a[:b + 1]"},
+		{"type assertion", TypeAssert(Unary("<-", a), Codef("*%s", buffer)), "(<-a).(*bytes.Buffer)"},
+		{"conversion to pointer", Convert(Codef("*%s", buffer), a), "(*bytes.Buffer)(a)"},
+		{"conversion to func", Convert(Raw("func(int) error"), a), "(func(int) error)(a)"},
+		{"conversion to named", Convert(codegenutil.Sym("time", "Duration"), Binary(a, "*", b)), "time.Duration(a * b)"},
+		{"conversion to receive-only chan", Convert(Raw("<-chan int"), a), "(<-chan int)(a)"},
+		{"conversion to chan", Convert(Raw("chan<- int"), a), "chan<- int(a)"},
+		{"conversion to type named like a keyword", Convert(codegenutil.Sym("example.com/funcs", "Handler"), a), "funcs.Handler(a)"},
+		{"conversion to generic", Convert(Raw("Set[int]"), a), "Set[int](a)"},
+		{"call of func literal", Call(Raw("func() {}")), "func() {}()"},
+		{"variadic call", CallVariadic(Ident("append"), a, b), "append(a, b...)"},
+		{"paren", Binary(Paren(Binary(a, "*", b)), "*", c), "(a * b) * c"},
+		{"shift", Binary(a, "<<", Binary(b, "&^", c)), "a << (b &^ c)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imports := codegenutil.NewFileImports(codegenutil.AssumedPackageName("abc.xyz/mypkg"))
+			if got := tt.code.GoCode(imports); got != tt.want {
+				t.Errorf("GoCode() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBinary_invalidOperator(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Binary() with invalid operator did not panic")
+		}
+	}()
+	Binary(Ident("a"), "=", Ident("b"))
+}
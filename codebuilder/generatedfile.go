@@ -0,0 +1,81 @@
+package codebuilder
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+
+	"github.com/meta-programming/go-codegenutil"
+)
+
+// GeneratedFile is a Go source file whose body is written before its header,
+// for generators that print code as they walk their inputs instead of building
+// declarations with File. Printing a symbol to the body imports its package,
+// and Content assembles the package clause, the imports and the body once the
+// body is complete, so no placeholder for the imports is needed.
+type GeneratedFile struct {
+	imports *codegenutil.FileImports
+	doc     string
+	body    bytes.Buffer
+}
+
+// NewGeneratedFile returns a GeneratedFile with an empty body whose imports
+// are added to imports.
+func NewGeneratedFile(imports *codegenutil.FileImports) *GeneratedFile {
+	return &GeneratedFile{imports: imports}
+}
+
+// Imports returns the imports of the file.
+func (g *GeneratedFile) Imports() *codegenutil.FileImports { return g.imports }
+
+// Doc sets the comment printed above the package clause.
+func (g *GeneratedFile) Doc(text string) *GeneratedFile {
+	g.doc = text
+	return g
+}
+
+// Write appends p to the body of the file. It never fails.
+func (g *GeneratedFile) Write(p []byte) (int, error) {
+	return g.body.Write(p)
+}
+
+// P appends a line made of its arguments to the body of the file. Symbols and
+// Code values are printed with the imports of the file, strings are printed
+// as they are, and other values are printed with fmt.Sprint.
+func (g *GeneratedFile) P(args ...any) {
+	var scratch []byte
+	for _, arg := range args {
+		switch arg := arg.(type) {
+		case *codegenutil.Symbol:
+			scratch = arg.AppendGoCode(scratch[:0], g.imports)
+			g.body.Write(scratch)
+		case Code:
+			g.body.WriteString(arg.GoCode(g.imports))
+		case string:
+			g.body.WriteString(arg)
+		default:
+			fmt.Fprint(&g.body, arg)
+		}
+	}
+	g.body.WriteByte('\n')
+}
+
+// Content returns the gofmt-formatted source of the file: the package clause,
+// an imports block containing every package referenced by the body, and the
+// body. It may be called again after more of the body is written.
+func (g *GeneratedFile) Content() ([]byte, error) {
+	var src bytes.Buffer
+	src.WriteString(docComment(g.doc))
+	if len(g.imports.List()) == 0 {
+		src.WriteString("package " + g.imports.Package().Name())
+	} else {
+		src.WriteString(g.imports.Format(true))
+	}
+	src.WriteString("\n\n")
+	src.Write(g.body.Bytes())
+	formatted, err := format.Source(src.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("error formatting generated file: %w\n%s", err, src.String())
+	}
+	return formatted, nil
+}
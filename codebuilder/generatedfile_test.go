@@ -0,0 +1,42 @@
+package codebuilder
+
+import (
+	"testing"
+
+	"github.com/meta-programming/go-codegenutil"
+	"github.com/meta-programming/go-codegenutil/debugutil"
+)
+
+func TestGeneratedFile_Content(t *testing.T) {
+	g := NewGeneratedFile(codegenutil.NewFileImports(codegenutil.AssumedPackageName("abc.xyz/mypkg")))
+	g.Doc("Code generated by test. DO NOT EDIT.")
+	g.P("func hello() {")
+	g.P(codegenutil.Sym("fmt", "Println"), "(", Lit("hello"), ", ", 42, ")")
+	g.P(Call(codegenutil.Sym("os", "Exit"), Lit(0)))
+	g.P("}")
+	got, err := g.Content()
+	if err != nil {
+		t.Fatalf("Content() error: %v", err)
+	}
+	want := `// Code generated by test. DO NOT EDIT.
+package mypkg
+
+import (
+	"fmt"
+	"os"
+)
+
+func hello() {
+	fmt.Println("hello", 42)
+	os.Exit(0)
+}
+`
+	if string(got) != want {
+		t.Errorf("Content() (got|want):\n%s", debugutil.SideBySide(string(got), want))
+	}
+
+	empty, err := NewGeneratedFile(codegenutil.NewFileImports(codegenutil.AssumedPackageName("abc.xyz/mypkg"))).Content()
+	if err != nil || string(empty) != "package mypkg\n" {
+		t.Errorf("Content() of an empty file = %q, %v, want %q", empty, err, "package mypkg\n")
+	}
+}
@@ -0,0 +1,134 @@
+package codebuilder
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"strings"
+
+	"github.com/meta-programming/go-codegenutil"
+)
+
+// Snippet is a fragment of Go code whose references to other packages are
+// qualified according to the imports of the file it is added to. See
+// ParseSnippet.
+type Snippet struct {
+	src  string
+	refs []snippetRef
+}
+
+// snippetRef is a qualified identifier in the source of a Snippet.
+type snippetRef struct {
+	start, end int
+	sym        *codegenutil.Symbol
+}
+
+// ParseSnippet parses src, which may be a list of declarations, a list of
+// statements or an expression, and returns it as a Snippet.
+//
+// Qualified identifiers "x.Name" in src whose package name x is a key of
+// imports, and is not declared by src itself, refer to the symbol Name of the
+// corresponding package. When the snippet is added to a file, each of them is
+// replaced by the symbol qualified with the file's local name for the package,
+// and the package is imported. This makes it possible to keep fragments of
+// generated code as ordinary Go source, for example:
+//
+//	ParseSnippet(`data, err := json.Marshal(v)`, map[string]*codegenutil.Package{
+//		"json": codegenutil.AssumedPackageName("encoding/json"),
+//	})
+//
+// Everything else in src, including comments and formatting, is kept as is.
+func ParseSnippet(src string, imports map[string]*codegenutil.Package) (*Snippet, error) {
+	var firstErr *scanner.Error
+	for _, mode := range snippetModes {
+		fset := token.NewFileSet()
+		node, err := mode.parse(fset, src)
+		if err != nil {
+			// Report the error of the mode that got furthest into src, which
+			// is most likely the kind of code that was intended.
+			list, ok := err.(scanner.ErrorList)
+			if !ok || len(list) == 0 {
+				return nil, err
+			}
+			if e := list[0]; firstErr == nil || e.Pos.Offset-len(mode.prefix) > firstErr.Pos.Offset {
+				firstErr = &scanner.Error{Pos: snippetPosition(src, e.Pos.Offset-len(mode.prefix)), Msg: e.Msg}
+			}
+			continue
+		}
+		s := &Snippet{src: src}
+		ast.Inspect(node, func(n ast.Node) bool {
+			sel, ok := n.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			id, ok := sel.X.(*ast.Ident)
+			if !ok || id.Obj != nil || imports[id.Name] == nil {
+				return true
+			}
+			s.refs = append(s.refs, snippetRef{
+				start: fset.Position(sel.Pos()).Offset - len(mode.prefix),
+				end:   fset.Position(sel.End()).Offset - len(mode.prefix),
+				sym:   imports[id.Name].Symbol(sel.Sel.Name),
+			})
+			return false
+		})
+		return s, nil
+	}
+	return nil, fmt.Errorf("error parsing snippet: %w", firstErr)
+}
+
+// snippetPosition returns the position of an offset in src. Offsets outside of
+// src, which belong to the code a snippet is wrapped in, are clamped.
+func snippetPosition(src string, offset int) token.Position {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > len(src) {
+		offset = len(src)
+	}
+	line := strings.Count(src[:offset], "\n") + 1
+	column := offset - strings.LastIndex(src[:offset], "\n")
+	return token.Position{Offset: offset, Line: line, Column: column}
+}
+
+// snippetMode is a way of parsing a snippet: the snippet is wrapped in prefix
+// and suffix to make a file.
+type snippetMode struct {
+	prefix, suffix string
+}
+
+var snippetModes = []snippetMode{
+	{"package p\n\n", ""},
+	{"package p\n\nfunc _() {\n", "\n}"},
+	{"package p\n\nvar _ = ", "\n"},
+}
+
+func (m snippetMode) parse(fset *token.FileSet, src string) (ast.Node, error) {
+	return parser.ParseFile(fset, "", m.prefix+src+m.suffix, parser.ParseComments)
+}
+
+// Symbols returns the symbols that the snippet refers to, in the order they
+// appear in the snippet.
+func (s *Snippet) Symbols() []*codegenutil.Symbol {
+	var syms []*codegenutil.Symbol
+	for _, ref := range s.refs {
+		syms = append(syms, ref.sym)
+	}
+	return syms
+}
+
+// GoCode returns the source of the snippet with its qualified identifiers
+// qualified for imports.
+func (s *Snippet) GoCode(imports *codegenutil.FileImports) string {
+	var out strings.Builder
+	last := 0
+	for _, ref := range s.refs {
+		out.WriteString(s.src[last:ref.start])
+		out.WriteString(ref.sym.GoCode(imports))
+		last = ref.end
+	}
+	out.WriteString(s.src[last:])
+	return out.String()
+}
@@ -0,0 +1,91 @@
+package codebuilder
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/meta-programming/go-codegenutil"
+)
+
+func TestParseSnippet(t *testing.T) {
+	imports := map[string]*codegenutil.Package{
+		"json": codegenutil.AssumedPackageName("encoding/json"),
+		"xml":  codegenutil.AssumedPackageName("encoding/xml"),
+		"mine": codegenutil.AssumedPackageName("abc.xyz/mypkg"),
+		"tmpl": codegenutil.ExplicitPackageName("text/template", "template"),
+	}
+	tests := []struct {
+		name    string
+		src     string
+		want    string
+		wantErr string
+	}{
+		{
+			name: "statements",
+			src:  "data, err := json.Marshal(v) // marshal v\nif err != nil {\n\treturn err\n}",
+			want: "data, err := json2.Marshal(v) // marshal v\nif err != nil {\n\treturn err\n}",
+		},
+		{
+			name: "expression",
+			src:  "tmpl.Must(tmpl.New(xml.Header))",
+			want: "template.Must(template.New(xml.Header))",
+		},
+		{
+			name: "declarations with own package",
+			src:  "func f(x mine.T) json.RawMessage {\n\treturn nil\n}",
+			want: "func f(x T) json2.RawMessage {\n\treturn nil\n}",
+		},
+		{
+			name: "shadowed package name",
+			src:  "json := thing()\njson.Marshal(xml.Name{})",
+			want: "json := thing()\njson.Marshal(xml.Name{})",
+		},
+		{
+			name: "unknown package",
+			src:  "v.x = other.Thing",
+			want: "v.x = other.Thing",
+		},
+		{
+			name:    "syntax error",
+			src:     "if x {\n\ty := \n}",
+			wantErr: "error parsing snippet: 3:1: expected operand",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := ParseSnippet(tt.src, imports)
+			if err != nil {
+				if tt.wantErr == "" || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseSnippet() got error %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr != "" {
+				t.Fatalf("ParseSnippet() succeeded, want error containing %q", tt.wantErr)
+			}
+			// A conflicting import shows that references are re-qualified.
+			fileImports := codegenutil.NewFileImports(codegenutil.AssumedPackageName("abc.xyz/mypkg"),
+				codegenutil.WithImports(codegenutil.AssumedPackageName("other/json")))
+			if got := s.GoCode(fileImports); got != tt.want {
+				t.Errorf("GoCode() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSnippet_Symbols(t *testing.T) {
+	s, err := ParseSnippet("json.Marshal(time.Now())", map[string]*codegenutil.Package{
+		"json": codegenutil.AssumedPackageName("encoding/json"),
+		"time": codegenutil.AssumedPackageName("time"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, sym := range s.Symbols() {
+		got = append(got, sym.Package().ImportPath()+"."+sym.Name())
+	}
+	if want := "encoding/json.Marshal time.Now"; strings.Join(got, " ") != want {
+		t.Errorf("Symbols() = %q, want %q", strings.Join(got, " "), want)
+	}
+}
@@ -0,0 +1,186 @@
+package codebuilder
+
+import (
+	"go/format"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/meta-programming/go-codegenutil"
+)
+
+// StructBuilder builds a struct type. See Struct.
+type StructBuilder struct {
+	fields []*Field
+}
+
+// Struct returns a builder for a struct type with the given fields. The
+// result is usually passed to TypeDecl.
+func Struct(fields ...*Field) *StructBuilder {
+	return &StructBuilder{fields: fields}
+}
+
+// Fields appends fields to the struct.
+func (b *StructBuilder) Fields(fields ...*Field) *StructBuilder {
+	b.fields = append(b.fields, fields...)
+	return b
+}
+
+// Insert inserts fields before the field at index i. Insert(0, ...) adds
+// fields to the front of the struct and Insert(b.Len(), ...) is the same as
+// Fields(...).
+func (b *StructBuilder) Insert(i int, fields ...*Field) *StructBuilder {
+	b.fields = append(b.fields[:i], append(append([]*Field{}, fields...), b.fields[i:]...)...)
+	return b
+}
+
+// Remove removes the field with the given name. The name of an embedded field
+// is its unqualified type name. Remove does nothing if there is no such field.
+func (b *StructBuilder) Remove(name string) *StructBuilder {
+	for i, f := range b.fields {
+		if f.Name() == name {
+			b.fields = append(b.fields[:i], b.fields[i+1:]...)
+			break
+		}
+	}
+	return b
+}
+
+// Sort reorders the fields using the given less function. The sort is stable,
+// so fields that compare equal keep their relative order.
+func (b *StructBuilder) Sort(less func(a, b *Field) bool) *StructBuilder {
+	sort.SliceStable(b.fields, func(i, j int) bool { return less(b.fields[i], b.fields[j]) })
+	return b
+}
+
+// Len returns the number of fields in the struct.
+func (b *StructBuilder) Len() int { return len(b.fields) }
+
+// Field returns the i'th field of the struct, for 0 <= i < Len().
+func (b *StructBuilder) Field(i int) *Field { return b.fields[i] }
+
+// String returns the struct type as it is written in logs; see String.
+func (b *StructBuilder) String() string { return String(b) }
+
+// GoCode returns the struct type, with field names, types and tags aligned the
+// same way gofmt aligns them.
+func (b *StructBuilder) GoCode(imports *codegenutil.FileImports) string {
+	if len(b.fields) == 0 {
+		return "struct{}"
+	}
+	out := &strings.Builder{}
+	out.WriteString("struct {\n")
+	for _, f := range b.fields {
+		out.WriteString(indent(f.GoCode(imports)) + "\n")
+	}
+	out.WriteString("}")
+	return alignType(out.String())
+}
+
+// Field is a field of a struct. See F and Embed.
+type Field struct {
+	doc  string
+	name string
+	typ  Code
+	tags []string
+}
+
+// F returns a struct field with the given name and type.
+func F(name string, typ Code) *Field {
+	return &Field{name: name, typ: typ}
+}
+
+// Embed returns an embedded struct field of the given type, such as a symbol
+// or a pointer to a symbol.
+func Embed(typ Code) *Field {
+	return &Field{typ: typ}
+}
+
+// Name returns the name of the field. The name of an embedded field is its
+// unqualified type name, which is only known if the type is a
+// *codegenutil.Symbol or is printed like one by Raw or Codef.
+func (f *Field) Name() string {
+	if f.name != "" || f.typ == nil {
+		return f.name
+	}
+	if sym, ok := f.typ.(*codegenutil.Symbol); ok {
+		return sym.Name()
+	}
+	typ := f.typ.GoCode(codegenutil.NewFileImports(codegenutil.ExplicitPackageName("", "")))
+	typ = strings.TrimPrefix(typ, "*")
+	if i := strings.IndexByte(typ, '['); i >= 0 {
+		typ = typ[:i]
+	}
+	if i := strings.LastIndexByte(typ, '.'); i >= 0 {
+		typ = typ[i+1:]
+	}
+	return typ
+}
+
+// Type returns the type of the field.
+func (f *Field) Type() Code { return f.typ }
+
+// IsEmbedded reports whether the field is an embedded field.
+func (f *Field) IsEmbedded() bool { return f.name == "" }
+
+// Doc sets the doc comment of the field.
+func (f *Field) Doc(text string) *Field {
+	f.doc = text
+	return f
+}
+
+// Tag adds a key:"value" pair to the field's tag, for example
+// Tag("json", "name,omitempty"). Pairs appear in the order they are added.
+func (f *Field) Tag(key, value string) *Field {
+	f.tags = append(f.tags, key+":"+strconv.Quote(value))
+	return f
+}
+
+// RawTag appends tag, which should be in the conventional space-separated
+// key:"value" format, to the field's tag.
+func (f *Field) RawTag(tag string) *Field {
+	if tag != "" {
+		f.tags = append(f.tags, tag)
+	}
+	return f
+}
+
+// String returns the field as it is written in logs; see String.
+func (f *Field) String() string { return String(f) }
+
+// GoCode returns the field as it appears in a struct type.
+func (f *Field) GoCode(imports *codegenutil.FileImports) string {
+	out := &strings.Builder{}
+	out.WriteString(docComment(f.doc))
+	if f.name != "" {
+		out.WriteString(f.name + " ")
+	}
+	out.WriteString(f.typ.GoCode(imports))
+	if len(f.tags) > 0 {
+		tag := strings.Join(f.tags, " ")
+		if strconv.CanBackquote(tag) {
+			out.WriteString(" `" + tag + "`")
+		} else {
+			out.WriteString(" " + strconv.Quote(tag))
+		}
+	}
+	return out.String()
+}
+
+// alignType formats a type expression with gofmt, which aligns the columns of
+// struct fields.
+func alignType(typ string) string {
+	return formatFragment("package p\n\ntype _ ", typ)
+}
+
+// formatFragment formats code with gofmt after prepending prefix, which must
+// make code parsable as a file, and returns the formatted code without the
+// prefix. If the result can't be parsed, code is returned unchanged; the error
+// will be reported when the file containing it is formatted.
+func formatFragment(prefix, code string) string {
+	formatted, err := format.Source([]byte(prefix + code))
+	if err != nil {
+		return code
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(string(formatted), prefix), "\n")
+}
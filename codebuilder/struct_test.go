@@ -0,0 +1,95 @@
+package codebuilder
+
+import (
+	"testing"
+
+	"github.com/meta-programming/go-codegenutil"
+	"github.com/meta-programming/go-codegenutil/debugutil"
+)
+
+func TestStructBuilder_GoCode(t *testing.T) {
+	duration := codegenutil.Sym("time", "Duration")
+	mutex := codegenutil.Sym("sync", "Mutex")
+
+	tests := []struct {
+		name string
+		code Code
+		want string
+	}{
+		{
+			name: "empty",
+			code: Struct(),
+			want: "struct{}",
+		},
+		{
+			name: "aligned fields and tags",
+			code: Struct(
+				F("ID", Raw("int")).Tag("json", "id"),
+				F("Timeout", duration).Tag("json", "timeout,omitempty").Tag("yaml", "timeout"),
+				F("x", Raw("string")),
+			),
+			want: "struct {\n" +
+				"\tID      int           `json:\"id\"`\n" +
+				"\tTimeout time.Duration `json:\"timeout,omitempty\" yaml:\"timeout\"`\n" +
+				"\tx       string\n" +
+				"}",
+		},
+		{
+			name: "embedded fields and docs",
+			code: Struct(
+				Embed(mutex),
+				F("count", Raw("int")).Doc("count is guarded by the mutex."),
+				Embed(Codef("*%s", codegenutil.Sym("strings", "Builder"))),
+			),
+			want: "struct {\n" +
+				"\tsync.Mutex\n" +
+				"\t// count is guarded by the mutex.\n" +
+				"\tcount int\n" +
+				"\t*strings.Builder\n" +
+				"}",
+		},
+		{
+			name: "tag that can't be backquoted",
+			code: Struct(F("X", Raw("int")).RawTag("a:\"`\"")),
+			want: "struct {\n\tX int \"a:\\\"`\\\"\"\n}",
+		},
+		{
+			name: "ordering",
+			code: Struct(F("B", Raw("int")), F("C", Raw("int")), Embed(mutex)).
+				Insert(0, F("A", Raw("int"))).
+				Remove("C").
+				Sort(func(a, b *Field) bool { return a.IsEmbedded() && !b.IsEmbedded() }),
+			want: "struct {\n\tsync.Mutex\n\tA int\n\tB int\n}",
+		},
+		{
+			name: "in a type declaration",
+			code: TypeDecl("T", Struct(F("a", Raw("int")), F("bb", Raw("int")))),
+			want: "type T struct {\n\ta  int\n\tbb int\n}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imports := codegenutil.NewFileImports(codegenutil.AssumedPackageName("abc.xyz/mypkg"))
+			if got := tt.code.GoCode(imports); got != tt.want {
+				t.Errorf("GoCode() got != want:\n%s", debugutil.SideBySide(got, tt.want))
+			}
+		})
+	}
+}
+
+func TestField_Name(t *testing.T) {
+	tests := []struct {
+		field *Field
+		want  string
+	}{
+		{F("x", Raw("int")), "x"},
+		{Embed(codegenutil.Sym("sync", "Mutex")), "Mutex"},
+		{Embed(Codef("*%s", codegenutil.Sym("strings", "Builder"))), "Builder"},
+		{Embed(Raw("List[int]")), "List"},
+	}
+	for _, tt := range tests {
+		if got := tt.field.Name(); got != tt.want {
+			t.Errorf("Name() = %q, want %q", got, tt.want)
+		}
+	}
+}
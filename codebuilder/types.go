@@ -0,0 +1,119 @@
+package codebuilder
+
+import (
+	"go/types"
+
+	"github.com/meta-programming/go-codegenutil"
+)
+
+// TypeOf returns Code for a type from the go/types package. Named types from
+// other packages are qualified using the file's imports, and named types from
+// the file's own package are not qualified.
+func TypeOf(typ types.Type) Code {
+	return &typeCode{typ}
+}
+
+type typeCode struct {
+	typ types.Type
+}
+
+func (c *typeCode) String() string { return String(c) }
+
+func (c *typeCode) GoCode(imports *codegenutil.FileImports) string {
+	return types.TypeString(c.typ, Qualifier(imports))
+}
+
+// TypeParamsOf returns the type parameters of a generic go/types type or
+// signature as parameters for FuncBuilder.TypeParams and
+// TypeDeclBuilder.TypeParams. The constraint of each parameter is rendered
+// with TypeOf.
+func TypeParamsOf(list *types.TypeParamList) []*Param {
+	var params []*Param
+	for i := 0; i < list.Len(); i++ {
+		tp := list.At(i)
+		params = append(params, P(tp.Obj().Name(), TypeOf(tp.Constraint())))
+	}
+	return params
+}
+
+// ParamsOf returns the variables of a go/types parameter or result tuple as
+// parameters. If variadic is true, the last variable, whose type is a slice,
+// becomes a variadic parameter of the slice's element type.
+func ParamsOf(tuple *types.Tuple, variadic bool) []*Param {
+	var params []*Param
+	for i := 0; i < tuple.Len(); i++ {
+		v := tuple.At(i)
+		if variadic && i == tuple.Len()-1 {
+			params = append(params, P(v.Name(), TypeOf(v.Type().(*types.Slice).Elem())).Variadic())
+			continue
+		}
+		params = append(params, P(v.Name(), TypeOf(v.Type())))
+	}
+	return params
+}
+
+// SignatureOf returns the function type of a go/types signature, such as
+// "func(x int, opts ...Option) error". If withRecv is true, the receiver of a
+// method signature becomes the first parameter, as in the type of the method
+// expression T.Method; otherwise the receiver is dropped. Type parameters are
+// dropped, since function types can't have them.
+//
+// Parameter and result names are kept, unless the receiver is named and the
+// parameters are not or vice versa, in which case all parameter names are
+// dropped.
+func SignatureOf(sig *types.Signature, withRecv bool) Code {
+	params := ParamsOf(sig.Params(), sig.Variadic())
+	if recv := sig.Recv(); withRecv && recv != nil {
+		params = append([]*Param{P(recv.Name(), TypeOf(recv.Type()))}, params...)
+	}
+	return FuncType(consistentNames(params), ParamsOf(sig.Results(), false))
+}
+
+// consistentNames returns params, or unnamed copies of them if some are named
+// and others are not.
+func consistentNames(params []*Param) []*Param {
+	named := 0
+	for _, p := range params {
+		if p.name != "" {
+			named++
+		}
+	}
+	if named == 0 || named == len(params) {
+		return params
+	}
+	unnamed := make([]*Param, len(params))
+	for i, p := range params {
+		unnamed[i] = &Param{typ: p.typ, variadic: p.variadic}
+	}
+	return unnamed
+}
+
+// Qualifier returns a types.Qualifier that adds the packages it is called with
+// to imports and returns their file-local names, or the empty string for the
+// file's own package. It can be used to print go/types objects with
+// functions like types.TypeString and types.ObjectString.
+func Qualifier(imports *codegenutil.FileImports) types.Qualifier {
+	return func(pkg *types.Package) string {
+		p := PackageOf(pkg)
+		if imports.IsFilePackage(p) {
+			return ""
+		}
+		return imports.Add(p, "").FileLocalPackageName()
+	}
+}
+
+// PackageOf returns the *codegenutil.Package for a go/types package, using the
+// package's actual name rather than one assumed from its import path.
+func PackageOf(pkg *types.Package) *codegenutil.Package {
+	if pkg == nil {
+		return codegenutil.BuiltinPackage
+	}
+	return codegenutil.ExplicitPackageName(pkg.Path(), pkg.Name())
+}
+
+// SymbolOf returns the *codegenutil.Symbol for a package-level go/types object,
+// such as a named type or function. Objects without a package, such as
+// predeclared types, are placed in codegenutil.BuiltinPackage.
+func SymbolOf(obj types.Object) *codegenutil.Symbol {
+	return PackageOf(obj.Pkg()).Symbol(obj.Name())
+}
@@ -0,0 +1,129 @@
+package codebuilder
+
+import (
+	"go/types"
+	"testing"
+
+	"github.com/meta-programming/go-codegenutil"
+	"github.com/meta-programming/go-codegenutil/internal/typestest"
+)
+
+func TestTypeOf(t *testing.T) {
+	pkg := typestest.Check(t, "abc.xyz/mypkg", `package mypkg
+
+import (
+	"math/rand"
+	"time"
+)
+
+type Local struct{}
+
+var (
+	a map[string]time.Duration
+	b func(*rand.Rand, ...Local) error
+	c []struct{ X int }
+)
+`)
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"a", "map[string]time.Duration"},
+		{"b", "func(*rand.Rand, ...Local) error"},
+		{"c", "[]struct{X int}"},
+	}
+	for _, tt := range tests {
+		imports := codegenutil.NewFileImports(codegenutil.AssumedPackageName("abc.xyz/mypkg"))
+		if got := TypeOf(pkg.Scope().Lookup(tt.name).Type()).GoCode(imports); got != tt.want {
+			t.Errorf("TypeOf(%s) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	// Types from another package must be qualified with the package's
+	// actual name even when it conflicts with an existing import.
+	imports := codegenutil.NewFileImports(codegenutil.AssumedPackageName("other/pkg"), codegenutil.WithImports(codegenutil.AssumedPackageName("other/mypkg")))
+	if got, want := TypeOf(pkg.Scope().Lookup("b").Type()).GoCode(imports), "func(*rand.Rand, ...mypkg2.Local) error"; got != want {
+		t.Errorf("TypeOf(b) in another package = %q, want %q", got, want)
+	}
+}
+
+func TestTypeParamsOf(t *testing.T) {
+	pkg := typestest.Check(t, "abc.xyz/mypkg", `package mypkg
+
+import "fmt"
+
+type Number interface{ ~int | ~int64 | ~float64 }
+
+type Tree[K Number, V interface{ fmt.Stringer; comparable }, S ~[]V] struct{}
+
+func Map[T, U any](s []T, f func(T) U) []U { return nil }
+`)
+	tree := pkg.Scope().Lookup("Tree").Type().(*types.Named)
+	mapFunc := pkg.Scope().Lookup("Map").Type().(*types.Signature)
+	imports := codegenutil.NewFileImports(codegenutil.AssumedPackageName("abc.xyz/other"))
+	tests := []struct {
+		name string
+		code Code
+		want string
+	}{
+		{
+			name: "type with constraint interfaces",
+			code: TypeDecl("Tree", Raw("struct{}")).TypeParams(TypeParamsOf(tree.TypeParams())...),
+			want: "type Tree[K mypkg.Number, V interface{fmt.Stringer; comparable}, S ~[]V] struct{}",
+		},
+		{
+			name: "func",
+			code: Func("Map").TypeParams(TypeParamsOf(mapFunc.TypeParams())...),
+			want: "func Map[T any, U any]() {\n}",
+		},
+		{
+			name: "constraint union",
+			code: TypeOf(pkg.Scope().Lookup("Number").Type().Underlying()),
+			want: "interface{~int | ~int64 | ~float64}",
+		},
+	}
+	for _, tt := range tests {
+		if got := tt.code.GoCode(imports); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSignatureOf(t *testing.T) {
+	pkg := typestest.Check(t, "abc.xyz/mypkg", `package mypkg
+
+import "io"
+
+type T struct{}
+
+func (t *T) Named(w io.Writer, args ...any) (n int, err error) { return 0, nil }
+
+func (T) Unnamed(int, []string) error { return nil }
+
+func (t T) Mixed(int) {}
+
+func Generic[E any](s []E, f func(E) bool) []E { return nil }
+`)
+	method := func(name string) *types.Signature {
+		obj, _, _ := types.LookupFieldOrMethod(types.NewPointer(pkg.Scope().Lookup("T").Type()), true, pkg, name)
+		return obj.Type().(*types.Signature)
+	}
+	tests := []struct {
+		name     string
+		sig      *types.Signature
+		withRecv bool
+		want     string
+	}{
+		{"named", method("Named"), false, "func(w io.Writer, args ...any) (n int, err error)"},
+		{"named with receiver", method("Named"), true, "func(t *mypkg.T, w io.Writer, args ...any) (n int, err error)"},
+		{"unnamed with receiver", method("Unnamed"), true, "func(mypkg.T, int, []string) error"},
+		{"mixed with receiver", method("Mixed"), true, "func(mypkg.T, int)"},
+		{"generic", pkg.Scope().Lookup("Generic").Type().(*types.Signature), false, "func(s []E, f func(E) bool) []E"},
+	}
+	for _, tt := range tests {
+		imports := codegenutil.NewFileImports(codegenutil.AssumedPackageName("abc.xyz/other"))
+		if got := SignatureOf(tt.sig, tt.withRecv).GoCode(imports); got != tt.want {
+			t.Errorf("%s: SignatureOf() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
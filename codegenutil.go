@@ -22,6 +22,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"unicode"
 	"unicode/utf8"
 )
@@ -58,7 +59,7 @@ func (p *Package) Name() string {
 
 // Symbol returns a new Symbol within the given package.
 func (p *Package) Symbol(idName string) *Symbol {
-	return &Symbol{p, idName}
+	return &Symbol{p, intern(idName)}
 }
 
 // IsBuiltin returns true if the package represents the builtin package, which
@@ -67,22 +68,97 @@ func (p *Package) IsBuiltin() bool {
 	return p.importPath == BuiltinPackage.importPath
 }
 
+// String returns the package as it is written in logs and errors: its quoted
+// import path, such as "encoding/json", preceded by its name if the name
+// differs from the one that ImportPathToAssumedName assumes, as in an import
+// spec with an alias: mathx "example.com/math". The builtin package is
+// written builtin. Unlike formatting a symbol with GoCode, String never
+// imports anything.
+func (p *Package) String() string {
+	if p.IsBuiltin() {
+		return "builtin"
+	}
+	quoted := strconv.Quote(p.importPath)
+	if p.name != ImportPathToAssumedName(p.importPath) {
+		return p.name + " " + quoted
+	}
+	return quoted
+}
+
+// ExternalTest returns the package of the external test files of p, whose
+// package clauses name p with a "_test" suffix, such as "package foo_test".
+// The files are in the directory of p, so the package has the import path of p,
+// but p must still be imported by them; see FileImports.IsFilePackage.
+func (p *Package) ExternalTest() *Package {
+	return &Package{p.importPath, intern(p.name + "_test")}
+}
+
+// IsExternalTest reports whether p is the package of external test files, whose
+// name has a "_test" suffix.
+func (p *Package) IsExternalTest() bool {
+	return strings.HasSuffix(p.name, "_test")
+}
+
 // FileImports captures information about import entries in a Go file and the
 // package of the Go file itself.
 type FileImports struct {
 	filePackage *Package
-	specs       []*ImportSpec
+	index       importIndex
+
+	// base holds imports shared with other files. It is frozen, so it is
+	// read without locking. It is nil unless WithBase is used.
+	base *FileImports
+	// frozen is 1 once Freeze is called. It is accessed atomically.
+	frozen int32
 
-	byLocalPackageName map[string]*ImportSpec
-	byImportPath       map[string]*ImportSpec
+	// nextSuffix is the first numeric suffix that may be free for aliases of
+	// each package name generated by the default suggester, so that
+	// importing many packages of the same name doesn't probe the same taken
+	// suffixes over and over.
+	nextSuffix map[string]int
 
 	// suggestPackageNames is a function that suggests a package name for
 	// an import path.
 	suggestPackageNames func(pkg *Package, tryImportSpec func(localPackageName string) (acceptable bool))
 
+	// symbols records the symbols rendered with the FileImports, keyed by
+	// import path and name. It is nil unless RecordSymbols is used.
+	symbols map[[2]string]*Symbol
+
+	// budget is the import budget set by WithImportBudget, or nil.
+	budget *importBudget
+
+	// aliasShape holds the rules of MaxAliasLength, ASCIIAliases and
+	// AliasAffixes.
+	aliasShape aliasShape
+
+	// separateDecls is set by SeparateImportDecls, which also sets
+	// sideEffectComment.
+	separateDecls     bool
+	sideEffectComment string
+
+	// preferredNames maps import paths to the local names set by
+	// PreferLocalNames, and reservedNames maps those names back to their
+	// import paths. Both are nil unless PreferLocalNames is used.
+	preferredNames, reservedNames map[string]string
+
+	// logger is set by WithLogger.
+	logger Logger
+
+	// goVersion is the target Go version set by WithGoVersion, and
+	// symbolVersions is the function set by WithSymbolVersions.
+	goVersion      string
+	symbolVersions func(*Symbol) string
+
 	rwMutex *sync.RWMutex
 }
 
+// importBudget is the import budget of a FileImports.
+type importBudget struct {
+	max        int
+	onExceeded func(fi *FileImports, spec *ImportSpec, count int)
+}
+
 // FileImportsOption is an option that can be passed to NewImportsFor to customize
 // its behavior.
 type FileImportsOption struct {
@@ -111,6 +187,9 @@ type FileImportsOption struct {
 // stop suggesting package names. The arguments to the callback are the package
 // name to use and whether or not that package name should be considered an
 // alias.
+//
+// Unlike the default suggester, a custom suggester may suggest predeclared
+// identifiers, which the import would shadow; see IsPredeclared.
 func CustomPackageNameSuggester(fn func(pkg *Package, tryImportSpec func(localPackageName string) (acceptable bool))) FileImportsOption {
 	return FileImportsOption{
 		func(i *FileImports) { i.suggestPackageNames = fn },
@@ -129,15 +208,85 @@ func WithImports(pkgs ...*Package) FileImportsOption {
 	}
 }
 
+// SeparateImportDecls returns an option that makes the returned *FileImports
+// format each group of imports as a separate import declaration: the imports
+// of the standard library, the other imports, and the imports for side effects
+// only, whose names are "_", in that order. The declaration of the imports for
+// side effects is preceded by the comment given by sideEffectComment, if it
+// isn't empty, which usually explains why the packages are imported.
+func SeparateImportDecls(sideEffectComment string) FileImportsOption {
+	return FileImportsOption{
+		func(fi *FileImports) {
+			fi.separateDecls = true
+			fi.sideEffectComment = sideEffectComment
+		},
+	}
+}
+
+// WithAliasedImports returns an option that adds the provided packages to the
+// returned *FileImports with the aliases that are the keys of the map, in the
+// order of the aliases. As with Add, a package whose alias is already taken is
+// imported with a generated alias instead.
+func WithAliasedImports(pkgs map[string]*Package) FileImportsOption {
+	return FileImportsOption{
+		func(fi *FileImports) {
+			aliases := make([]string, 0, len(pkgs))
+			for alias := range pkgs {
+				aliases = append(aliases, alias)
+			}
+			sort.Strings(aliases)
+			for _, alias := range aliases {
+				fi.Add(pkgs[alias], alias)
+			}
+		},
+	}
+}
+
+// WithImportBudget returns an option that calls onExceeded whenever Add
+// imports a package into the returned *FileImports and that brings the number
+// of its imports, including those of its base, above max. It is called with
+// the new import spec and the number of imports, after Add releases its lock,
+// so it may use the FileImports, for example to list its imports. The budget
+// catches generated files that import much more than they should, such as a
+// template that ranges over every package of a repository.
+func WithImportBudget(max int, onExceeded func(fi *FileImports, spec *ImportSpec, count int)) FileImportsOption {
+	return FileImportsOption{
+		func(fi *FileImports) { fi.budget = &importBudget{max, onExceeded} },
+	}
+}
+
+// CompactImports returns an option that makes the returned *FileImports use
+// less memory for files with hundreds of imports, such as generated
+// registries, at the cost of slower additions: imports are kept in slices
+// sorted by import path and by package name, which are searched with binary
+// search, rather than in maps, and their ImportSpecs are allocated in batches.
+func CompactImports() FileImportsOption {
+	return FileImportsOption{
+		func(fi *FileImports) {
+			idx := &sortedIndex{}
+			for _, spec := range fi.index.appendSpecs(nil) {
+				idx.add(spec.fileLocalPackageName, spec.pkg, spec.isExplicit)
+			}
+			fi.index = idx
+		},
+	}
+}
+
+// RecordSymbols returns an option that makes the returned *FileImports record
+// the symbols that are formatted with it by Symbol.GoCode. See Symbols.
+func RecordSymbols() FileImportsOption {
+	return FileImportsOption{
+		func(fi *FileImports) { fi.symbols = map[[2]string]*Symbol{} },
+	}
+}
+
 // NewFileImports returns a new *FileImports object with no imports.
 func NewFileImports(p *Package, opts ...FileImportsOption) *FileImports {
 	fi := &FileImports{
-		p,
-		nil,
-		map[string]*ImportSpec{},
-		map[string]*ImportSpec{},
-		nil,
-		&sync.RWMutex{},
+		filePackage: p,
+		index:       newMapIndex(),
+		nextSuffix:  map[string]int{},
+		rwMutex:     &sync.RWMutex{},
 	}
 	for _, x := range opts {
 		x.apply(fi)
@@ -154,9 +303,57 @@ func (fi *FileImports) Package() *Package { return fi.filePackage }
 // It is possible to have multiple imports of a package, and this function will
 // return the first.
 func (fi *FileImports) Find(p *Package) *ImportSpec {
+	if fi.base != nil {
+		if spec := fi.base.index.byImportPath(p.ImportPath()); spec != nil {
+			return spec
+		}
+	}
+	if fi.isFrozen() {
+		return fi.index.byImportPath(p.ImportPath())
+	}
 	fi.rwMutex.RLock()
 	defer fi.rwMutex.RUnlock()
-	return fi.byImportPath[p.ImportPath()]
+	return fi.index.byImportPath(p.ImportPath())
+}
+
+// IsFilePackage reports whether pkg is the package of the file, whose symbols
+// aren't qualified. The package tested by an external test file, such as
+// "example.com/foo" for a file of the package "foo_test" with the same import
+// path, isn't the package of the file: it is imported like any other package.
+func (fi *FileImports) IsFilePackage(pkg *Package) bool {
+	if pkg.ImportPath() != fi.filePackage.ImportPath() {
+		return false
+	}
+	return fi.filePackage.IsExternalTest() == pkg.IsExternalTest()
+}
+
+// LocalNameFor returns the name that qualifies the symbols of pkg in the file
+// and true if pkg is already imported, without importing it otherwise. The name
+// is empty for the builtin package and the package of the file, whose symbols
+// aren't qualified.
+func (fi *FileImports) LocalNameFor(pkg *Package) (string, bool) {
+	if pkg.IsBuiltin() || fi.IsFilePackage(pkg) {
+		return "", true
+	}
+	spec := fi.Find(pkg)
+	if spec == nil {
+		return "", false
+	}
+	return spec.FileLocalPackageName(), true
+}
+
+// MustQualify returns the symbol formatted as by Symbol.GoCode, but panics
+// with an error wrapping ErrImportNotFound instead of importing its package if
+// it isn't imported yet. Unlike GoCode, it doesn't record the symbol.
+func (fi *FileImports) MustQualify(s *Symbol) string {
+	name, ok := fi.LocalNameFor(s.Package())
+	if !ok {
+		panic(fmt.Errorf("cannot qualify %s.%s: %w for package %q", s.Package().Name(), s.Name(), ErrImportNotFound, s.Package().ImportPath()))
+	}
+	if name == "" {
+		return s.Name()
+	}
+	return name + "." + s.Name()
 }
 
 // Add adds an import to the given package using the given alias.
@@ -166,42 +363,195 @@ func (fi *FileImports) Find(p *Package) *ImportSpec {
 //
 // If the package name or alias conflicts with an existing import, an alias will
 // be generated.
+//
+// Add panics with a *FrozenError if the package isn't imported yet and the
+// FileImports is frozen; see TryAdd.
 func (fi *FileImports) Add(pkg *Package, alias string) *ImportSpec {
+	spec, err := fi.TryAdd(pkg, alias)
+	if err != nil {
+		panic(err)
+	}
+	return spec
+}
+
+// TryAdd is like Add, but returns a *FrozenError instead of panicking if the
+// package isn't imported yet and the FileImports is frozen.
+func (fi *FileImports) TryAdd(pkg *Package, alias string) (*ImportSpec, error) {
+	// Most calls are for packages that are already imported, which only
+	// need a read lock, or no lock at all if they are in the frozen base.
+	if existingSpec := fi.Find(pkg); existingSpec != nil {
+		return existingSpec, nil
+	}
+	if fi.isFrozen() {
+		return nil, &FrozenError{File: fi.filePackage, Package: pkg}
+	}
+	spec, count, wanted, err := fi.add(pkg, alias)
+	if err != nil {
+		return nil, err
+	}
+	if fi.logger != nil && count > 0 {
+		fi.logImport(spec, wanted)
+	}
+	if fi.budget != nil && count > fi.budget.max {
+		fi.budget.onExceeded(fi, spec, count)
+	}
+	return spec, nil
+}
+
+// add adds an import of pkg with the lock held, if it isn't imported yet, and
+// returns its spec and the number of imports after adding it, or 0 if it was
+// already imported, and the first local name that was tried for it. It returns
+// a *FrozenError if the FileImports was frozen since TryAdd checked it.
+func (fi *FileImports) add(pkg *Package, alias string) (spec *ImportSpec, count int, wanted string, err error) {
 	fi.rwMutex.Lock()
 	defer fi.rwMutex.Unlock()
 
-	existingSpec := fi.byImportPath[pkg.ImportPath()]
+	existingSpec := fi.index.byImportPath(pkg.ImportPath())
 	if existingSpec != nil {
-		return existingSpec
+		return existingSpec, 0, "", nil
+	}
+	// Freeze takes the lock, so the index can't be frozen while it is held.
+	if fi.isFrozen() {
+		return nil, 0, "", &FrozenError{File: fi.filePackage, Package: pkg}
 	}
 
 	suggester := fi.suggestPackageNames
 	if suggester == nil {
-		suggester = defaultSuggestPackageNames
+		suggester = fi.defaultSuggestPackageNames
 	}
 	var finalSpec *ImportSpec
-	suggester(pkg, func(suggestedPackageName string) (acceptable bool) {
-		if _, conflicts := fi.byLocalPackageName[suggestedPackageName]; conflicts {
+	suggesting := false
+	tryImportSpec := func(suggestedPackageName string) (acceptable bool) {
+		// Only the names the suggester chooses are shaped; explicit and
+		// preferred aliases are used as they are.
+		if suggesting {
+			suggestedPackageName = fi.aliasShape.apply(pkg, suggestedPackageName)
+		}
+		if wanted == "" {
+			wanted = suggestedPackageName
+		}
+		// Any number of packages may be imported for side effects only.
+		taken := suggestedPackageName != "_" && (fi.index.hasLocalName(suggestedPackageName) || (fi.base != nil && fi.base.index.hasLocalName(suggestedPackageName)))
+		if taken {
 			return false // keep sugesting
 		}
+		// Suggestions don't take the names preferred by other packages,
+		// which may be imported later.
+		if owner, ok := fi.reservedNames[suggestedPackageName]; suggesting && ok && owner != pkg.ImportPath() {
+			return false
+		}
 		isExplicit := suggestedPackageName != pkg.Name()
-		finalSpec = &ImportSpec{suggestedPackageName, pkg, isExplicit}
-		fi.byLocalPackageName[suggestedPackageName] = finalSpec
-		fi.byImportPath[pkg.ImportPath()] = finalSpec
-		fi.specs = append(fi.specs, finalSpec)
+		finalSpec = fi.index.add(suggestedPackageName, pkg, isExplicit)
 		return true // finished with suggestions
-	})
+	}
+	if alias == "" {
+		alias = fi.preferredNames[pkg.ImportPath()]
+	}
+	if alias == "" || !tryImportSpec(alias) {
+		suggesting = true
+		suggester(pkg, tryImportSpec)
+	}
 	if finalSpec == nil {
-		panic(fmt.Errorf("no acceptable suggestion found for importing %q", pkg.ImportPath()))
+		panic(fmt.Errorf("no acceptable suggestion found for importing %q: %w", pkg.ImportPath(), ErrAliasConflict))
+	}
+	count = fi.index.size()
+	if fi.base != nil {
+		count += fi.base.index.size()
+	}
+	return finalSpec, count, wanted, nil
+}
+
+// logImport logs that spec was added, after wanted was tried as its local
+// name.
+func (fi *FileImports) logImport(spec *ImportSpec, wanted string) {
+	file := fi.filePackage.ImportPath()
+	path := spec.pkg.ImportPath()
+	name := spec.fileLocalPackageName
+	if wanted != "" && wanted != name {
+		fi.logger.Debug("import alias conflict resolved", "file", file, "path", path, "wanted", wanted, "name", name)
+	}
+	fi.logger.Debug("import added", "file", file, "path", path, "name", name)
+}
+
+// Symbols returns the distinct symbols formatted with the FileImports, sorted
+// by import path and name, if the FileImports was created with the
+// RecordSymbols option. Symbols of the builtin package are not recorded.
+func (fi *FileImports) Symbols() []*Symbol {
+	fi.rwMutex.RLock()
+	var out []*Symbol
+	for _, s := range fi.symbols {
+		out = append(out, s)
 	}
-	return finalSpec
+	fi.rwMutex.RUnlock()
+
+	sort.Slice(out, func(i, j int) bool {
+		if a, b := out[i].Package().ImportPath(), out[j].Package().ImportPath(); a != b {
+			return a < b
+		}
+		return out[i].Name() < out[j].Name()
+	})
+	return out
+}
+
+func (fi *FileImports) recordSymbol(s *Symbol) {
+	// The symbols field is only set by NewFileImports, so it can be read
+	// without locking.
+	if fi.symbols == nil || fi.isFrozen() {
+		return
+	}
+	fi.rwMutex.Lock()
+	defer fi.rwMutex.Unlock()
+	key := [2]string{s.Package().ImportPath(), s.Name()}
+	if _, ok := fi.symbols[key]; !ok {
+		fi.symbols[key] = s
+	}
+}
+
+// RemoveUnused removes the imports of the file's own layer for which used
+// returns false and returns them, sorted by import path. It lets generators
+// that track which packages they reference drop the imports they added
+// speculatively before rendering the file. The imports of the base set by
+// WithBase are shared with other files, so they are kept. The symbols recorded
+// for the removed packages are forgotten.
+//
+// Names freed by removed imports may be reused by later imports. RemoveUnused
+// panics if the FileImports is frozen.
+func (fi *FileImports) RemoveUnused(used func(*ImportSpec) bool) []*ImportSpec {
+	fi.rwMutex.Lock()
+	// The check is made with the lock held, which Freeze takes.
+	if fi.isFrozen() {
+		fi.rwMutex.Unlock()
+		panic(fmt.Errorf("cannot remove imports from a file of package %s after its imports were frozen", fi.filePackage.Name()))
+	}
+	removed := fi.index.removeIf(func(spec *ImportSpec) bool { return !used(spec) })
+	if len(removed) > 0 {
+		fi.nextSuffix = map[string]int{}
+		removedPaths := map[string]bool{}
+		for _, spec := range removed {
+			removedPaths[spec.PackageName().ImportPath()] = true
+		}
+		for key := range fi.symbols {
+			if removedPaths[key[0]] {
+				delete(fi.symbols, key)
+			}
+		}
+	}
+	fi.rwMutex.Unlock()
+
+	sort.Slice(removed, func(i, j int) bool {
+		return removed[i].PackageName().ImportPath() < removed[j].PackageName().ImportPath()
+	})
+	return removed
 }
 
 // List returns all of the import specs for the FileImports object.
 func (fi *FileImports) List() []*ImportSpec {
-	fi.rwMutex.RLock()
 	var out []*ImportSpec
-	out = append(out, fi.specs...)
+	if fi.base != nil {
+		out = fi.base.index.appendSpecs(out)
+	}
+	fi.rwMutex.RLock()
+	out = fi.index.appendSpecs(out)
 	fi.rwMutex.RUnlock()
 
 	sort.Slice(out, func(i, j int) bool {
@@ -217,17 +567,28 @@ func (fi *FileImports) String() string {
 
 // Format returns prints a valid Go imports block containing all of the imports.
 // If true is passed, a package statement is included above the imports block.
+//
+// The imports are laid out as goimports lays them out, so running goimports or
+// gofmt on a generated file doesn't change its imports: the imports of the
+// standard library come first, followed by a blank line and the other imports,
+// and each group is sorted by import path. See SeparateImportDecls for another
+// layout.
 func (fi *FileImports) Format(includePackageStatement bool) string {
 	imports := fi.List()
-	var aliasedLines, simpleLines, blankLines []string
+	var stdLines, otherLines, blankLines []string
 
 	for _, impt := range imports {
-		if impt.IsExplicit() && impt.FileLocalPackageName() == "_" {
-			blankLines = append(blankLines, fmt.Sprintf("\t%s %q", impt.FileLocalPackageName(), impt.PackageName().ImportPath()))
-		} else if impt.IsExplicit() {
-			aliasedLines = append(aliasedLines, fmt.Sprintf("\t%s %q", impt.FileLocalPackageName(), impt.PackageName().ImportPath()))
-		} else {
-			simpleLines = append(simpleLines, fmt.Sprintf("\t%q", impt.PackageName().ImportPath()))
+		line := fmt.Sprintf("\t%q", impt.PackageName().ImportPath())
+		if impt.IsExplicit() {
+			line = fmt.Sprintf("\t%s %q", impt.FileLocalPackageName(), impt.PackageName().ImportPath())
+		}
+		switch {
+		case fi.separateDecls && impt.FileLocalPackageName() == "_":
+			blankLines = append(blankLines, line)
+		case isStandardImportPath(impt.PackageName().ImportPath()):
+			stdLines = append(stdLines, line)
+		default:
+			otherLines = append(otherLines, line)
 		}
 	}
 	sections := []string{}
@@ -240,17 +601,51 @@ func (fi *FileImports) Format(includePackageStatement bool) string {
 			sections[0] = "\n" + sections[0]
 		}
 	}
-	addSection(simpleLines)
-	addSection(aliasedLines)
-	addSection(blankLines)
 
-	formattedImports := fmt.Sprintf("import (%s)", strings.Join(sections, "\n"))
+	var formattedImports string
+	if fi.separateDecls {
+		var decls []string
+		for _, lines := range [][]string{stdLines, otherLines, blankLines} {
+			if len(lines) > 0 {
+				decls = append(decls, fmt.Sprintf("import (\n%s\n)", strings.Join(lines, "\n")))
+			}
+		}
+		if len(blankLines) > 0 && fi.sideEffectComment != "" {
+			decls[len(decls)-1] = lineComment(fi.sideEffectComment) + decls[len(decls)-1]
+		}
+		formattedImports = strings.Join(decls, "\n\n")
+	}
+	if formattedImports == "" {
+		addSection(stdLines)
+		addSection(otherLines)
+		formattedImports = fmt.Sprintf("import (%s)", strings.Join(sections, "\n"))
+	}
 	if !includePackageStatement {
 		return formattedImports
 	}
 	return fmt.Sprintf(`package %s
 
-%s`, fi.Package().Name(), fi.String())
+%s`, fi.Package().Name(), formattedImports)
+}
+
+// lineComment returns text as a comment of // lines, each followed by a
+// newline.
+func lineComment(text string) string {
+	var out strings.Builder
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		out.WriteString(strings.TrimRight("// "+line, " ") + "\n")
+	}
+	return out.String()
+}
+
+// isStandardImportPath reports whether goimports groups the import path with
+// the packages of the standard library, whose first path elements have no dots.
+func isStandardImportPath(importPath string) bool {
+	first := importPath
+	if i := strings.Index(importPath, "/"); i >= 0 {
+		first = importPath[:i]
+	}
+	return !strings.Contains(first, ".")
 }
 
 // ImportSpec is an entry within the set of imports of a Go file. It does not
@@ -303,11 +698,89 @@ func (s *Symbol) Name() string { return s.name }
 // The Imports argument is the set of imports currently imported in the file. If
 // the symbol's import is not in the set of import specs.
 func (s *Symbol) GoCode(imports *FileImports) string {
-	if s.Package().ImportPath() == imports.filePackage.ImportPath() || s.Package().IsBuiltin() {
+	if code, ok := s.goVersionFallback(imports); ok {
+		return code
+	}
+	qualifier := s.qualifier(imports)
+	if qualifier == "" {
 		return s.Name()
 	}
+	return qualifier + "." + s.Name()
+}
 
-	return imports.Add(s.Package(), "").FileLocalPackageName() + "." + s.Name()
+// String returns the symbol as it is written in logs and errors: its quoted
+// import path and its name separated by a dot, such as
+// "alternative/math".Max, in the syntax of ParseSymbol. Symbols of the
+// builtin package are written without import path, such as int. Unlike GoCode,
+// String doesn't need a FileImports and never imports the symbol's package.
+func (s *Symbol) String() string {
+	if s.pkg.IsBuiltin() {
+		return s.name
+	}
+	return strconv.Quote(s.pkg.importPath) + "." + s.name
+}
+
+// AppendGoCode appends the symbol formatted as by GoCode to dst and returns the
+// extended buffer. Unlike GoCode, it doesn't allocate when dst has enough
+// capacity, which matters to programs that format many symbols.
+func (s *Symbol) AppendGoCode(dst []byte, imports *FileImports) []byte {
+	if code, ok := s.goVersionFallback(imports); ok {
+		return append(dst, code...)
+	}
+	if qualifier := s.qualifier(imports); qualifier != "" {
+		dst = append(dst, qualifier...)
+		dst = append(dst, '.')
+	}
+	return append(dst, s.Name()...)
+}
+
+// qualifier returns the name that qualifies the symbol in a file with the given
+// imports, importing its package if needed, or "" if the symbol is used
+// unqualified.
+func (s *Symbol) qualifier(imports *FileImports) string {
+	if s.Package().IsBuiltin() {
+		return ""
+	}
+	imports.recordSymbol(s)
+	if imports.IsFilePackage(s.Package()) {
+		return ""
+	}
+	spec, err := imports.TryAdd(s.Package(), "")
+	if err != nil {
+		err.(*FrozenError).Symbol = s
+		panic(err)
+	}
+	return spec.FileLocalPackageName()
+}
+
+// ParseSymbol parses a symbol written as an import path and an identifier
+// separated by a dot, such as "encoding/json.Marshal". The import path may be
+// quoted, as in `"gopkg.in/yaml.v3".Marshal`. The package name is assumed from
+// the import path, as with AssumedPackageName. An identifier without an import
+// path, such as "int", is a symbol of the builtin package.
+func ParseSymbol(s string) (*Symbol, error) {
+	var importPath, name string
+	if strings.HasPrefix(s, `"`) {
+		end := strings.Index(s[1:], `"`) + 1
+		if end == 0 || !strings.HasPrefix(s[end+1:], ".") {
+			return nil, fmt.Errorf("invalid symbol %q: want \"import/path\".Name", s)
+		}
+		importPath, name = s[1:end], s[end+2:]
+		if importPath == "" {
+			return nil, fmt.Errorf("invalid symbol %q: empty import path", s)
+		}
+	} else if i := strings.LastIndex(s, "."); i >= 0 {
+		importPath, name = s[:i], s[i+1:]
+		if importPath == "" {
+			return nil, fmt.Errorf("invalid symbol %q: empty import path", s)
+		}
+	} else {
+		name = s
+	}
+	if !IsValidIdentifier(name) {
+		return nil, fmt.Errorf("invalid symbol %q: %q is %w", s, name, ErrInvalidIdentifier)
+	}
+	return AssumedPackageName(importPath).Symbol(name), nil
 }
 
 // AssumedPackageName returns the assumed name of the package according the
@@ -319,10 +792,53 @@ func (s *Symbol) GoCode(imports *FileImports) string {
 // imported package." The file being loaded is not available in gopoet (and many
 // go tools), so this function needs to be used.
 //
+// The name is guessed by ImportPathToAssumedName, unless another heuristic is
+// set with SetPackageNameHeuristic.
+func AssumedPackageName(importPath string) *Package {
+	if importPath == "" {
+		return &Package{} // builtin package
+	}
+	heuristic := ImportPathToAssumedName
+	if h, _ := packageNameHeuristic.Load().(func(string) string); h != nil {
+		heuristic = h
+	}
+	return &Package{intern(importPath), intern(heuristic(importPath))}
+}
+
+// packageNameHeuristic holds the function set by SetPackageNameHeuristic.
+var packageNameHeuristic atomic.Value
+
+// SetPackageNameHeuristic replaces the function that AssumedPackageName, Sym
+// and ParseSymbol use to guess the name of a package from its import path.
+// Passing nil restores the default, ImportPathToAssumedName. The function is
+// only called with non-empty import paths, and it must return a valid
+// identifier.
+//
+// SetPackageNameHeuristic is meant to be called once, when a program starts,
+// by programs whose packages don't follow the usual naming conventions.
+func SetPackageNameHeuristic(fn func(importPath string) string) {
+	packageNameHeuristic.Store(fn)
+}
+
+// ImportPathToAssumedName returns the name that a package with the given
+// import path most likely declares in its package clause. It is the default
+// heuristic of AssumedPackageName:
+//
+//   - A major version suffix, as in "example.com/foo/v2", is skipped.
+//   - The name is lower case, and a "go-" prefix is removed.
+//   - Combining marks, which can't appear in identifiers, are dropped, so that
+//     decomposed accented letters don't cut the name short.
+//   - The name is the first run of identifier characters, without leading
+//     digits and underscores, so "gopkg.in/yaml.v3" is named "yaml" and
+//     "example.com/my-lib" is named "my".
+//   - If no such run remains, as in "example.com/123", the name is "pkg".
+//
+// The result is always a valid identifier other than "_".
+//
 // Note: path.Base differs from the package name guesser used by most
 // tools. See https://pkg.go.dev/golang.org/x/tools/internal/imports#ImportPathToAssumedName.
-func AssumedPackageName(importPath string) *Package {
-	// Contents of this function are taken from
+func ImportPathToAssumedName(importPath string) string {
+	// Contents of this function are adapted from
 	// https://pkg.go.dev/golang.org/x/tools@v0.1.10/internal/imports#ImportPathToAssumedName,
 	// which has the following license:
 	//
@@ -353,9 +869,20 @@ func AssumedPackageName(importPath string) *Package {
 	// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
 	// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
 
-	if importPath == "" {
-		return &Package{} // builtin package
+	base := path.Base(importPath)
+	if isMajorVersion(base) {
+		dir := path.Dir(importPath)
+		if dir != "." {
+			base = path.Base(dir)
+		}
 	}
+	base = strings.TrimPrefix(strings.ToLower(base), "go-")
+	base = strings.Map(func(ch rune) rune {
+		if unicode.In(ch, unicode.Mn, unicode.Mc, unicode.Me) {
+			return -1
+		}
+		return ch
+	}, base)
 
 	// notIdentifier reports whether ch is an invalid identifier character.
 	notIdentifier := func(ch rune) bool {
@@ -364,42 +891,57 @@ func AssumedPackageName(importPath string) *Package {
 			ch == '_' ||
 			ch >= utf8.RuneSelf && (unicode.IsLetter(ch) || unicode.IsDigit(ch)))
 	}
-	base := path.Base(importPath)
-	if strings.HasPrefix(base, "v") {
-		if _, err := strconv.Atoi(base[1:]); err == nil {
-			dir := path.Dir(importPath)
-			if dir != "." {
-				base = path.Base(dir)
-			}
+	for _, field := range strings.FieldsFunc(base, notIdentifier) {
+		field = strings.TrimLeftFunc(field, func(ch rune) bool { return ch == '_' || unicode.IsDigit(ch) })
+		if field != "" {
+			return field
 		}
 	}
-	base = strings.TrimPrefix(base, "go-")
-	if i := strings.IndexFunc(base, notIdentifier); i >= 0 {
-		base = base[:i]
+	return "pkg"
+}
+
+// isMajorVersion reports whether an element of an import path is a major
+// version suffix such as "v2".
+func isMajorVersion(elem string) bool {
+	if len(elem) < 2 || elem[0] != 'v' {
+		return false
 	}
-	return &Package{importPath, base}
+	for _, ch := range elem[1:] {
+		if ch < '0' || ch > '9' {
+			return false
+		}
+	}
+	return true
 }
 
 // ExplicitPackageName is used to construct an explicit PackageName in case
 // AssumedPackageName is insufficient.
 func ExplicitPackageName(importPath, packageName string) *Package {
-	return &Package{importPath, packageName}
+	return &Package{intern(importPath), intern(packageName)}
 }
 
 // defaultSuggestPackageNames calls callback with a series of suggested package names
 // for the given importPath and assumed package name until the callback returns
-// false.
-func defaultSuggestPackageNames(pkg *Package, tryImportSpec func(localPackageName string) (accepted bool)) {
+// false. It must be called with fi.rwMutex locked.
+func (fi *FileImports) defaultSuggestPackageNames(pkg *Package, tryImportSpec func(localPackageName string) (accepted bool)) {
 	packageNameInPackageClause := pkg.Name()
 
-	if tryImportSpec(packageNameInPackageClause) {
+	// Importing a package named like a predeclared identifier, such as
+	// "len", under its name would shadow the identifier in the file.
+	if !IsPredeclared(packageNameInPackageClause) && tryImportSpec(packageNameInPackageClause) {
 		return
 	}
 
+	// The suffixes before nextSuffix are still taken, unless RemoveUnused
+	// removed imports, in which case it resets nextSuffix.
 	const maxIterations = 1000
-	for suffix := 2; suffix <= maxIterations; suffix++ {
-		packageName := fmt.Sprintf("%s%d", packageNameInPackageClause, suffix)
-		if tryImportSpec(packageName) {
+	suffix := fi.nextSuffix[packageNameInPackageClause]
+	if suffix == 0 {
+		suffix = 2
+	}
+	for ; suffix <= maxIterations; suffix++ {
+		if tryImportSpec(packageNameInPackageClause + strconv.Itoa(suffix)) {
+			fi.nextSuffix[packageNameInPackageClause] = suffix + 1
 			return
 		}
 	}
@@ -411,7 +953,9 @@ const (
 	// See https://github.com/google/re2/wiki/Syntax and
 	//letterREConst        = `(?:_|\p{L})`
 	letterREConst        = `[_\p{L}]`
-	letterOrDigitREConst = `[_\p{L}0-9]`
+	// A letter or a unicode_digit, which is any decimal digit:
+	// https://go.dev/ref/spec#unicode_digit
+	letterOrDigitREConst = `[_\p{L}\p{Nd}]`
 	// identifierRegexpConst is an expression for an identifier.
 	identifierRegexpConst = `(?:` + letterREConst + letterOrDigitREConst + `*)`
 )
@@ -1,9 +1,41 @@
 package codegenutil
 
 import (
+	"fmt"
+	"strings"
+	"sync"
 	"testing"
+
+	"github.com/meta-programming/go-codegenutil/debugutil"
 )
 
+func FuzzAssumedPackageName(f *testing.F) {
+	for _, importPath := range []string{"go.lang/x/tools/v2", "gopkg.in/inf.v0", "example.com/cafe\u0301s", "example.com/123", "a/_", "/", "."} {
+		f.Add(importPath)
+	}
+	f.Fuzz(func(t *testing.T, importPath string) {
+		if importPath == "" {
+			return
+		}
+		name := AssumedPackageName(importPath).Name()
+		if !IsValidIdentifier(name) || name == "_" {
+			t.Errorf("AssumedPackageName(%q).Name() = %q, want a valid package name", importPath, name)
+		}
+	})
+}
+
+func TestSetPackageNameHeuristic(t *testing.T) {
+	SetPackageNameHeuristic(func(importPath string) string { return "custom" })
+	got := AssumedPackageName("abc/xyz").Name()
+	SetPackageNameHeuristic(nil)
+	if got != "custom" {
+		t.Errorf("AssumedPackageName() with a custom heuristic has name %q, want %q", got, "custom")
+	}
+	if got := AssumedPackageName("abc/xyz").Name(); got != "xyz" {
+		t.Errorf("AssumedPackageName() after SetPackageNameHeuristic(nil) has name %q, want %q", got, "xyz")
+	}
+}
+
 func TestIdentifierRegexp(t *testing.T) {
 	tests := []struct {
 		id   string
@@ -13,6 +45,8 @@ func TestIdentifierRegexp(t *testing.T) {
 		{"_helloWorld123", true},
 		{"a", true},
 		{"_ó3", true},
+		{"a٢", true},
+		{"٢a", false},
 		{"b_b", true},
 		{"A_b", true},
 		{"A b", false},
@@ -42,6 +76,51 @@ func TestAssumedPackageName(t *testing.T) {
 			importPath: "go.lang/x/go-tools/v2",
 			want:       &Package{importPath: "go.lang/x/go-tools/v2", name: "tools"},
 		},
+		{
+			importPath: "gopkg.in/inf.v0",
+			want:       &Package{importPath: "gopkg.in/inf.v0", name: "inf"},
+		},
+		{
+			importPath: "github.com/Masterminds/Sprig",
+			want:       &Package{importPath: "github.com/Masterminds/Sprig", name: "sprig"},
+		},
+		{
+			importPath: "example.com/Go-Kit",
+			want:       &Package{importPath: "example.com/Go-Kit", name: "kit"},
+		},
+		{
+			importPath: "example.com/mañana",
+			want:       &Package{importPath: "example.com/mañana", name: "mañana"},
+		},
+		{
+			// "e" followed by a combining acute accent.
+			importPath: "example.com/cafe\u0301s",
+			want:       &Package{importPath: "example.com/cafe\u0301s", name: "cafes"},
+		},
+		{
+			importPath: "example.com/-foo",
+			want:       &Package{importPath: "example.com/-foo", name: "foo"},
+		},
+		{
+			importPath: "example.com/3d",
+			want:       &Package{importPath: "example.com/3d", name: "d"},
+		},
+		{
+			importPath: "example.com/123",
+			want:       &Package{importPath: "example.com/123", name: "pkg"},
+		},
+		{
+			importPath: "example.com/_",
+			want:       &Package{importPath: "example.com/_", name: "pkg"},
+		},
+		{
+			importPath: "example.com/foo/v+1",
+			want:       &Package{importPath: "example.com/foo/v+1", name: "v"},
+		},
+		{
+			importPath: "v2",
+			want:       &Package{importPath: "v2", name: "v2"},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.importPath, func(t *testing.T) {
@@ -82,12 +161,547 @@ func TestSymbol_GoCode(t *testing.T) {
 			imports: NewFileImports(AssumedPackageName("abc/xyz")),
 			want:    "Foo",
 		},
+		{
+			name:    "builtin",
+			sym:     BuiltinPackage.Symbol("int"),
+			imports: NewFileImports(AssumedPackageName("abc/xyz")),
+			want:    "int",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			if got := tt.sym.GoCode(tt.imports); got != tt.want {
 				t.Errorf("%v.GoCode() = %q, want %q", tt.sym, got, tt.want)
 			}
+			if got := string(tt.sym.AppendGoCode([]byte("x := "), tt.imports)); got != "x := "+tt.want {
+				t.Errorf("%v.AppendGoCode() = %q, want %q", tt.sym, got, "x := "+tt.want)
+			}
+		})
+	}
+}
+
+func BenchmarkSymbol_AppendGoCode(b *testing.B) {
+	imports := NewFileImports(AssumedPackageName("abc/xyz"))
+	sym := Sym("example.com/foo/bar", "Baz")
+	b.Run("GoCode", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = sym.GoCode(imports)
+		}
+	})
+	b.Run("AppendGoCode", func(b *testing.B) {
+		b.ReportAllocs()
+		var buf []byte
+		for i := 0; i < b.N; i++ {
+			buf = sym.AppendGoCode(buf[:0], imports)
+		}
+	})
+}
+
+func TestFileImports_Symbols(t *testing.T) {
+	imports := NewFileImports(AssumedPackageName("abc/xyz"), RecordSymbols())
+	for _, sym := range []*Symbol{
+		Sym("time", "Now"),
+		Sym("abc/xyz", "Local"),
+		BuiltinPackage.Symbol("int"),
+		Sym("encoding/json", "Marshal"),
+		Sym("time", "Now"),
+	} {
+		sym.GoCode(imports)
+	}
+	var got []string
+	for _, sym := range imports.Symbols() {
+		got = append(got, sym.Package().ImportPath()+"."+sym.Name())
+	}
+	if want := "abc/xyz.Local encoding/json.Marshal time.Now"; strings.Join(got, " ") != want {
+		t.Errorf("Symbols() = %q, want %q", strings.Join(got, " "), want)
+	}
+
+	notRecording := NewFileImports(AssumedPackageName("abc/xyz"))
+	Sym("time", "Now").GoCode(notRecording)
+	if got := notRecording.Symbols(); len(got) != 0 {
+		t.Errorf("Symbols() without RecordSymbols = %v, want none", got)
+	}
+}
+
+func TestWithAliasedImports(t *testing.T) {
+	imports := NewFileImports(AssumedPackageName("abc/xyz"), WithAliasedImports(map[string]*Package{
+		"pb":      AssumedPackageName("example.com/api/v1/apipb"),
+		"fmt":     AssumedPackageName("fmt"),
+		"apipb":   AssumedPackageName("example.com/other/apipb"),
+		"yamlenc": AssumedPackageName("gopkg.in/yaml.v3"),
+	}))
+	if got := Sym("example.com/api/v1/apipb", "Req").GoCode(imports); got != "pb.Req" {
+		t.Errorf("GoCode() = %q, want %q", got, "pb.Req")
+	}
+	if got := Sym("example.com/api/v2/apipb", "Req").GoCode(imports); got != "apipb2.Req" {
+		t.Errorf("GoCode() = %q, want %q", got, "apipb2.Req")
+	}
+	// An alias that is the name of its package isn't written.
+	want := `import (
+	"fmt"
+
+	pb "example.com/api/v1/apipb"
+	apipb2 "example.com/api/v2/apipb"
+	"example.com/other/apipb"
+	yamlenc "gopkg.in/yaml.v3"
+)`
+	if got := imports.String(); got != want {
+		t.Errorf("unexpected imports:\n%s", debugutil.SideBySide(got, want))
+	}
+}
+
+func TestWithImportBudget(t *testing.T) {
+	var exceeded []string
+	imports := NewFileImports(AssumedPackageName("abc/xyz"), WithImportBudget(2, func(fi *FileImports, spec *ImportSpec, count int) {
+		exceeded = append(exceeded, fmt.Sprintf("%s:%d:%d", spec.PackageName().ImportPath(), count, len(fi.List())))
+	}))
+	for _, sym := range []*Symbol{Sym("fmt", "Println"), Sym("os", "Exit"), Sym("fmt", "Sprint"), Sym("io", "EOF"), Sym("os", "Args"), Sym("sort", "Strings")} {
+		sym.GoCode(imports)
+	}
+	if want := "io:3:3 sort:4:4"; strings.Join(exceeded, " ") != want {
+		t.Errorf("onExceeded called with %q, want %q", strings.Join(exceeded, " "), want)
+	}
+}
+
+func TestFileImports_RemoveUnused(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		opts []FileImportsOption
+	}{
+		{"map index", nil},
+		{"compact", []FileImportsOption{CompactImports()}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			imports := NewFileImports(AssumedPackageName("abc/xyz"), append(tt.opts, RecordSymbols())...)
+			for _, sym := range []*Symbol{Sym("fmt", "Println"), Sym("os", "Exit"), Sym("example.com/a/os", "X"), Sym("example.com/b/os", "Y"), Sym("io", "EOF")} {
+				sym.GoCode(imports)
+			}
+			imports.Add(AssumedPackageName("embed"), "_")
+			imports.Add(AssumedPackageName("example.com/plugin"), "_")
+
+			used := map[string]bool{"fmt": true, "example.com/b/os": true, "embed": true}
+			var removed []string
+			for _, spec := range imports.RemoveUnused(func(spec *ImportSpec) bool { return used[spec.PackageName().ImportPath()] }) {
+				removed = append(removed, spec.PackageName().ImportPath())
+			}
+			if got, want := strings.Join(removed, " "), "example.com/a/os example.com/plugin io os"; got != want {
+				t.Errorf("RemoveUnused() removed %q, want %q", got, want)
+			}
+			if got := len(imports.Symbols()); got != 2 {
+				t.Errorf("len(Symbols()) = %d after RemoveUnused, want 2", got)
+			}
+			// The names freed by the removed imports may be reused.
+			if got := Sym("example.com/c/os", "Z").GoCode(imports); got != "os.Z" {
+				t.Errorf("GoCode() = %q, want %q", got, "os.Z")
+			}
+			if got := Sym("example.com/d/os", "W").GoCode(imports); got != "os2.W" {
+				t.Errorf("GoCode() = %q, want %q", got, "os2.W")
+			}
+			imports.Add(AssumedPackageName("example.com/plugin"), "_")
+			want := `import (
+	_ "embed"
+	"fmt"
+
+	os3 "example.com/b/os"
+	"example.com/c/os"
+	os2 "example.com/d/os"
+	_ "example.com/plugin"
+)`
+			if got := imports.String(); got != want {
+				t.Errorf("unexpected imports:\n%s", debugutil.SideBySide(got, want))
+			}
+		})
+	}
+}
+
+func TestFileImports_RemoveUnused_base(t *testing.T) {
+	base := NewFileImports(AssumedPackageName("abc/xyz"), WithImports(AssumedPackageName("fmt")))
+	imports := NewFileImports(AssumedPackageName("abc/xyz"), WithBase(base), WithImports(AssumedPackageName("os")))
+	removed := imports.RemoveUnused(func(*ImportSpec) bool { return false })
+	if len(removed) != 1 || removed[0].PackageName().ImportPath() != "os" {
+		t.Errorf("RemoveUnused() = %v, want the os import only", removed)
+	}
+	if got := len(imports.List()); got != 1 {
+		t.Errorf("len(List()) = %d after RemoveUnused, want 1", got)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("RemoveUnused() on a frozen FileImports didn't panic")
+		}
+	}()
+	base.RemoveUnused(func(*ImportSpec) bool { return false })
+}
+
+func TestFileImports_Add_predeclared(t *testing.T) {
+	imports := NewFileImports(AssumedPackageName("abc/xyz"))
+	for _, tt := range []struct{ importPath, want string }{
+		{"example.com/util/len", "len2.X"},
+		{"example.com/go-copy", "copy2.X"},
+		{"example.com/min", "min2.X"},
+		{"example.com/lens", "lens.X"},
+	} {
+		if got := Sym(tt.importPath, "X").GoCode(imports); got != tt.want {
+			t.Errorf("GoCode() of %s.X = %q, want %q", tt.importPath, got, tt.want)
+		}
+	}
+	if got := PredeclaredIdentifiers(); len(got) == 0 || got[0] != "any" || !IsPredeclared("new") || IsPredeclared("fmt") {
+		t.Errorf("PredeclaredIdentifiers() = %v", got)
+	}
+}
+
+func TestFileImports_LocalNameFor(t *testing.T) {
+	imports := NewFileImports(AssumedPackageName("abc/xyz"), WithAliasedImports(map[string]*Package{"yamlv3": AssumedPackageName("gopkg.in/yaml.v3")}))
+	for _, tt := range []struct {
+		pkg    *Package
+		want   string
+		wantOK bool
+	}{
+		{AssumedPackageName("gopkg.in/yaml.v3"), "yamlv3", true},
+		{AssumedPackageName("abc/xyz"), "", true},
+		{BuiltinPackage, "", true},
+		{AssumedPackageName("fmt"), "", false},
+	} {
+		if got, ok := imports.LocalNameFor(tt.pkg); got != tt.want || ok != tt.wantOK {
+			t.Errorf("LocalNameFor(%q) = %q, %v, want %q, %v", tt.pkg.ImportPath(), got, ok, tt.want, tt.wantOK)
+		}
+	}
+	if got := len(imports.List()); got != 1 {
+		t.Errorf("LocalNameFor() changed the imports to %v", imports.List())
+	}
+
+	if got := imports.MustQualify(Sym("gopkg.in/yaml.v3", "Node")); got != "yamlv3.Node" {
+		t.Errorf("MustQualify() = %q, want %q", got, "yamlv3.Node")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("MustQualify() of a symbol of a package that isn't imported didn't panic")
+		}
+	}()
+	imports.MustQualify(Sym("fmt", "Println"))
+}
+
+func TestFileImports_externalTest(t *testing.T) {
+	foo := AssumedPackageName("example.com/foo")
+	for _, filePackage := range []*Package{foo.ExternalTest(), ExplicitPackageName("example.com/foo", "foo_test")} {
+		imports := NewFileImports(filePackage)
+		if got := Sym("example.com/foo", "New").GoCode(imports); got != "foo.New" {
+			t.Errorf("GoCode() in package %s = %q, want %q", filePackage.Name(), got, "foo.New")
+		}
+		if got := filePackage.Symbol("helper").GoCode(imports); got != "helper" {
+			t.Errorf("GoCode() of a symbol of the test package = %q, want %q", got, "helper")
+		}
+		if want := "package foo_test\n\nimport (\n\t\"example.com/foo\"\n)"; imports.Format(true) != want {
+			t.Errorf("Format() = %q, want %q", imports.Format(true), want)
+		}
+	}
+	// In an internal test file, the package is the file's own.
+	if got := Sym("example.com/foo", "New").GoCode(NewFileImports(foo)); got != "New" {
+		t.Errorf("GoCode() in package foo = %q, want %q", got, "New")
+	}
+}
+
+func TestFileImports_Add_sameName(t *testing.T) {
+	imports := NewFileImports(AssumedPackageName("abc/xyz"))
+	// A package whose name looks like a generated alias takes that name.
+	imports.Add(AssumedPackageName("c/v1/pb3"), "")
+	var got []string
+	for i := 0; i < 5; i++ {
+		spec := imports.Add(AssumedPackageName(fmt.Sprintf("p%d/pb", i)), "")
+		got = append(got, spec.FileLocalPackageName())
+	}
+	if want := "pb pb2 pb4 pb5 pb6"; strings.Join(got, " ") != want {
+		t.Errorf("Add() chose names %q, want %q", strings.Join(got, " "), want)
+	}
+}
+
+func BenchmarkFileImports_Add_sameName(b *testing.B) {
+	var pkgs []*Package
+	for i := 0; i < 500; i++ {
+		pkgs = append(pkgs, AssumedPackageName(fmt.Sprintf("example.com/gen/p%d/pb", i)))
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		imports := NewFileImports(AssumedPackageName("abc/xyz"))
+		for _, pkg := range pkgs {
+			imports.Add(pkg, "")
+		}
+	}
+}
+
+func TestWithBase(t *testing.T) {
+	base := NewFileImports(AssumedPackageName("abc/xyz"))
+	base.Add(AssumedPackageName("example.com/a/log"), "")
+	base.Add(AssumedPackageName("fmt"), "")
+
+	imports := NewFileImports(AssumedPackageName("abc/xyz"), WithBase(base))
+	if got := Sym("fmt", "Println").GoCode(imports); got != "fmt.Println" {
+		t.Errorf("GoCode() = %q, want %q", got, "fmt.Println")
+	}
+	if got := Sym("log", "Printf").GoCode(imports); got != "log2.Printf" {
+		t.Errorf("GoCode() = %q, want %q", got, "log2.Printf")
+	}
+	if got := base.Find(AssumedPackageName("log")); got != nil {
+		t.Errorf("base.Find(log) = %v, want nil", got)
+	}
+	want := `import (
+	"fmt"
+	log2 "log"
+
+	"example.com/a/log"
+)`
+	if got := imports.String(); got != want {
+		t.Errorf("unexpected imports:\n%s", debugutil.SideBySide(got, want))
+	}
+}
+
+func TestFreeze(t *testing.T) {
+	imports := NewFileImports(AssumedPackageName("abc/xyz"), WithImports(AssumedPackageName("fmt"))).Freeze()
+	if spec, err := imports.TryAdd(AssumedPackageName("fmt"), ""); err != nil || spec.FileLocalPackageName() != "fmt" {
+		t.Errorf("TryAdd() of an imported package = %v, %v, want fmt", spec, err)
+	}
+	_, err := imports.TryAdd(AssumedPackageName("os"), "")
+	if _, ok := err.(*FrozenError); !ok {
+		t.Errorf("TryAdd() on a frozen FileImports got error %v, want *FrozenError", err)
+	}
+	defer func() {
+		want := `cannot import "os" into a file of package xyz after its imports were frozen (formatting os.Exit)`
+		if err, _ := recover().(*FrozenError); err == nil || err.Error() != want {
+			t.Errorf("GoCode() on a frozen FileImports panicked with %v, want %q", err, want)
+		}
+	}()
+	Sym("os", "Exit").GoCode(imports)
+}
+
+func TestFreeze_concurrent(t *testing.T) {
+	imports := NewFileImports(AssumedPackageName("abc/xyz"))
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				_, err := imports.TryAdd(AssumedPackageName(fmt.Sprintf("example.com/w%d/p%d", w, i)), "")
+				if _, ok := err.(*FrozenError); err != nil && !ok {
+					t.Errorf("TryAdd() got error %v, want nil or *FrozenError", err)
+				}
+			}
+		}(w)
+	}
+	// No import may be added once Freeze returns.
+	frozen := len(imports.Freeze().List())
+	wg.Wait()
+	if got := len(imports.List()); got != frozen {
+		t.Errorf("got %d imports after the workers stopped, want the %d imported when frozen", got, frozen)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("RemoveUnused() on a frozen FileImports didn't panic")
+		}
+	}()
+	imports.RemoveUnused(func(*ImportSpec) bool { return false })
+}
+
+func TestWithBase_concurrent(t *testing.T) {
+	base := NewFileImports(AssumedPackageName("abc/xyz"))
+	for i := 0; i < 10; i++ {
+		base.Add(AssumedPackageName(fmt.Sprintf("example.com/p%d", i)), "")
+	}
+	base.Freeze()
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			imports := NewFileImports(AssumedPackageName("abc/xyz"), WithBase(base))
+			for i := 0; i < 10; i++ {
+				Sym(fmt.Sprintf("example.com/p%d", i), "X").GoCode(imports)
+				Sym(fmt.Sprintf("example.com/w%d/p%d", w, i), "X").GoCode(imports)
+			}
+			if got := len(imports.List()); got != 20 {
+				t.Errorf("worker %d: got %d imports, want 20", w, got)
+			}
+		}(w)
+	}
+	wg.Wait()
+	if got := len(base.List()); got != 10 {
+		t.Errorf("base has %d imports, want 10", got)
+	}
+}
+
+func BenchmarkFileImports_sharedBase(b *testing.B) {
+	var syms []*Symbol
+	for i := 0; i < 64; i++ {
+		syms = append(syms, Sym(fmt.Sprintf("example.com/p%d", i), "X"))
+	}
+	newBase := func() *FileImports {
+		base := NewFileImports(AssumedPackageName("abc/xyz"))
+		for _, sym := range syms {
+			sym.GoCode(base)
+		}
+		return base
+	}
+	for _, bm := range []struct {
+		name string
+		base *FileImports
+	}{
+		{"shared", newBase()},
+		{"frozen", newBase().Freeze()},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetParallelism(64)
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					for _, sym := range syms {
+						sym.GoCode(bm.base)
+					}
+				}
+			})
+		})
+	}
+}
+
+func TestParseSymbol(t *testing.T) {
+	tests := []struct {
+		in         string
+		importPath string
+		pkgName    string
+		name       string
+		wantErr    bool
+	}{
+		{in: "encoding/json.Marshal", importPath: "encoding/json", pkgName: "json", name: "Marshal"},
+		{in: "gopkg.in/yaml.v3.Marshal", importPath: "gopkg.in/yaml.v3", pkgName: "yaml", name: "Marshal"},
+		{in: `"example.com/x.y".Z`, importPath: "example.com/x.y", pkgName: "x", name: "Z"},
+		{in: "int", importPath: "", pkgName: "", name: "int"},
+		{in: "encoding/json.", wantErr: true},
+		{in: ".Marshal", wantErr: true},
+		{in: `"".Marshal`, wantErr: true},
+		{in: `"unterminated.Marshal`, wantErr: true},
+		{in: "a/b.c-d", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseSymbol(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseSymbol(%q) succeeded, want error", tt.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseSymbol(%q) error: %v", tt.in, err)
+			continue
+		}
+		if got.Package().ImportPath() != tt.importPath || got.Package().Name() != tt.pkgName || got.Name() != tt.name {
+			t.Errorf("ParseSymbol(%q) = (%q, %q).%s, want (%q, %q).%s", tt.in,
+				got.Package().ImportPath(), got.Package().Name(), got.Name(), tt.importPath, tt.pkgName, tt.name)
+		}
+	}
+}
+
+func TestSymbol_String(t *testing.T) {
+	tests := []struct {
+		value fmt.Stringer
+		want  string
+	}{
+		{Sym("alternative/math", "Max"), `"alternative/math".Max`},
+		{ExplicitPackageName("example.com/go-yaml", "yaml").Symbol("Marshal"), `"example.com/go-yaml".Marshal`},
+		{BuiltinPackage.Symbol("int"), "int"},
+		{AssumedPackageName("encoding/json"), `"encoding/json"`},
+		{ExplicitPackageName("example.com/go-yaml", "yaml"), `"example.com/go-yaml"`},
+		{ExplicitPackageName("example.com/math", "mathx"), `mathx "example.com/math"`},
+		{BuiltinPackage, "builtin"},
+	}
+	for _, tt := range tests {
+		if got := tt.value.String(); got != tt.want {
+			t.Errorf("String() = %s, want %s", got, tt.want)
+		}
+		if got := fmt.Sprint(tt.value); got != tt.want {
+			t.Errorf("fmt.Sprint() = %s, want %s", got, tt.want)
+		}
+	}
+	for _, s := range []string{`"alternative/math".Max`, "int"} {
+		if sym, err := ParseSymbol(s); err != nil || sym.String() != s {
+			t.Errorf("ParseSymbol(%s).String() = %v, %v, want %s", s, sym, err, s)
+		}
+	}
+}
+
+func TestSetInterner(t *testing.T) {
+	in := NewInterner()
+	SetInterner(in)
+	defer SetInterner(nil)
+
+	for i := 0; i < 3; i++ {
+		Sym("abc/xyz", "Foo")
+		ExplicitPackageName("abc/xyz", "other").Symbol("Bar")
+		if _, err := ParseSymbol("abc/xyz.Foo"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// "abc/xyz", "xyz", "Foo", "other" and "Bar".
+	if got := in.Len(); got != 5 {
+		t.Errorf("Len() = %d, want 5", got)
+	}
+	if got := Sym("abc/xyz", "Foo").Package().ImportPath(); got != "abc/xyz" {
+		t.Errorf("ImportPath() with an Interner = %q, want %q", got, "abc/xyz")
+	}
+
+	SetInterner(nil)
+	Sym("abc/xyz", "Baz")
+	if got := in.Len(); got != 5 {
+		t.Errorf("Len() after SetInterner(nil) = %d, want 5", got)
+	}
+}
+
+func TestCompactImports(t *testing.T) {
+	var pkgs []*Package
+	for i := 0; i < 200; i++ {
+		pkgs = append(pkgs, AssumedPackageName(fmt.Sprintf("example.com/p%d/%s", i%7, []string{"json", "pb", "v2", "util"}[i%4])))
+	}
+	pkgs = append(pkgs, ExplicitPackageName("example.com/x", "_"))
+	regular := NewFileImports(AssumedPackageName("abc/xyz"))
+	compact := NewFileImports(AssumedPackageName("abc/xyz"), WithImports(pkgs[0]), CompactImports())
+	for _, pkg := range pkgs {
+		regular.Add(pkg, "")
+		compact.Add(pkg, "")
+	}
+	if got, want := compact.Format(true), regular.Format(true); got != want {
+		t.Errorf("Format() with CompactImports differs from Format() without (want|got):\n%s", debugutil.SideBySide(want, got))
+	}
+	for _, pkg := range pkgs {
+		if got, want := compact.Find(pkg).FileLocalPackageName(), regular.Find(pkg).FileLocalPackageName(); got != want {
+			t.Errorf("Find(%q) with CompactImports has name %q, want %q", pkg.ImportPath(), got, want)
+		}
+	}
+	if got := compact.Find(AssumedPackageName("example.com/missing")); got != nil {
+		t.Errorf("Find() of a missing package with CompactImports = %v, want nil", got)
+	}
+}
+
+func BenchmarkFileImports_wide(b *testing.B) {
+	var syms []*Symbol
+	for i := 0; i < 600; i++ {
+		syms = append(syms, Sym(fmt.Sprintf("example.com/registry/p%d/svc%d", i, i), "Register"))
+	}
+	for _, bm := range []struct {
+		name string
+		opts []FileImportsOption
+	}{
+		{"default", nil},
+		{"compact", []FileImportsOption{CompactImports()}},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				imports := NewFileImports(AssumedPackageName("abc/xyz"), bm.opts...)
+				for _, sym := range syms {
+					sym.GoCode(imports)
+				}
+				for _, sym := range syms {
+					sym.GoCode(imports)
+				}
+			}
 		})
 	}
 }
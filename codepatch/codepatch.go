@@ -0,0 +1,287 @@
+// Package codepatch inserts and replaces declarations in existing Go source
+// files without disturbing the rest of the file.
+//
+// Rewriting a file by parsing it and printing the modified syntax tree tends to
+// misplace comments and drop blank lines. A Patch instead edits the original
+// text: only the declarations being replaced and the import declarations are
+// touched, and everything else, including comments and blank lines, is kept
+// byte for byte. The result is formatted with gofmt, which preserves comments.
+package codepatch
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/meta-programming/go-codegenutil"
+	cb "github.com/meta-programming/go-codegenutil/codebuilder"
+)
+
+// Patch is an existing Go source file being modified.
+type Patch struct {
+	src     []byte
+	fset    *token.FileSet
+	file    *ast.File
+	imports *codegenutil.FileImports
+	// existing records the import paths that the file already imports.
+	existing map[string]bool
+}
+
+// Parse returns a Patch for the source of a file in the given package.
+func Parse(pkg *codegenutil.Package, src []byte) (*Patch, error) {
+	p := &Patch{src: src}
+	if err := p.parse(); err != nil {
+		return nil, err
+	}
+	p.imports = codegenutil.NewFileImports(pkg)
+	p.existing = map[string]bool{}
+	for _, spec := range p.file.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			return nil, err
+		}
+		imported := codegenutil.AssumedPackageName(path)
+		if spec.Name != nil {
+			if spec.Name.Name == "_" || spec.Name.Name == "." {
+				continue
+			}
+			imported = codegenutil.ExplicitPackageName(path, spec.Name.Name)
+		}
+		p.imports.Add(imported, "")
+		p.existing[path] = true
+	}
+	return p, nil
+}
+
+func (p *Patch) parse() error {
+	p.fset = token.NewFileSet()
+	file, err := parser.ParseFile(p.fset, "", p.src, parser.ParseComments)
+	if err != nil {
+		return fmt.Errorf("error parsing file: %w", err)
+	}
+	p.file = file
+	return nil
+}
+
+// Imports returns the imports of the file. Generated declarations are printed
+// with the local package names already used by the file, and packages they add
+// are inserted into the file by Upsert.
+func (p *Patch) Imports() *codegenutil.FileImports { return p.imports }
+
+// Upsert replaces the declarations of the file that declare the same names as
+// decl, or appends decl to the end of the file if there are none. Methods are
+// identified by their receiver type and name, as in "T.Method".
+//
+// If an existing declaration declares several names, such as a grouped const
+// declaration, it is replaced as a whole when any of its names match.
+func (p *Patch) Upsert(decl cb.Code) error {
+	code := decl.GoCode(p.imports)
+	_, parsed, err := parseDecl(code)
+	if err != nil {
+		return err
+	}
+	names := map[string]bool{}
+	for _, name := range declNames(parsed) {
+		names[name] = true
+	}
+
+	// Replace the first matching declaration and delete the others.
+	type edit struct {
+		start, end int
+		text       string
+	}
+	var edits []edit
+	for _, d := range p.file.Decls {
+		match := false
+		for _, name := range declNames(d) {
+			match = match || names[name]
+		}
+		if !match {
+			continue
+		}
+		start, end := p.declRange(d)
+		text := ""
+		if len(edits) == 0 {
+			text = code
+		}
+		edits = append(edits, edit{start, end, text})
+	}
+	if len(edits) == 0 {
+		edits = append(edits, edit{len(p.src), len(p.src), "\n" + code + "\n"})
+	}
+	sort.Slice(edits, func(i, j int) bool { return edits[i].start > edits[j].start })
+	for _, e := range edits {
+		p.src = splice(p.src, e.start, e.end, e.text)
+	}
+	if err := p.parse(); err != nil {
+		return err
+	}
+	return p.addImports()
+}
+
+// Remove deletes the declarations of the named identifier, including their doc
+// comments, and reports whether there were any.
+func (p *Patch) Remove(name string) (bool, error) {
+	var ranges [][2]int
+	for _, d := range p.file.Decls {
+		for _, n := range declNames(d) {
+			if n == name {
+				start, end := p.declRange(d)
+				ranges = append(ranges, [2]int{start, end})
+				break
+			}
+		}
+	}
+	for i := len(ranges) - 1; i >= 0; i-- {
+		p.src = splice(p.src, ranges[i][0], ranges[i][1], "")
+	}
+	if len(ranges) == 0 {
+		return false, nil
+	}
+	return true, p.parse()
+}
+
+// Bytes returns the gofmt-formatted source of the modified file.
+func (p *Patch) Bytes() ([]byte, error) {
+	formatted, err := format.Source(p.src)
+	if err != nil {
+		return nil, fmt.Errorf("error formatting patched file: %w", err)
+	}
+	return formatted, nil
+}
+
+// addImports inserts import declarations for packages that were added to the
+// imports by generated code.
+func (p *Patch) addImports() error {
+	var lines []string
+	for _, spec := range p.imports.List() {
+		path := spec.PackageName().ImportPath()
+		if p.existing[path] {
+			continue
+		}
+		p.existing[path] = true
+		if spec.IsExplicit() {
+			lines = append(lines, fmt.Sprintf("%s %q", spec.FileLocalPackageName(), path))
+		} else {
+			lines = append(lines, strconv.Quote(path))
+		}
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+
+	// Add to the last parenthesized import declaration if there is one, or
+	// add a new declaration after the last import or the package clause.
+	var last *ast.GenDecl
+	for _, d := range p.file.Decls {
+		if gen, ok := d.(*ast.GenDecl); ok && gen.Tok == token.IMPORT {
+			last = gen
+		}
+	}
+	switch {
+	case last != nil && last.Rparen.IsValid():
+		at := p.offset(last.Rparen)
+		p.src = splice(p.src, at, at, "\t"+strings.Join(lines, "\n\t")+"\n")
+	case last != nil:
+		at := p.offset(last.End())
+		p.src = splice(p.src, at, at, "\n\nimport (\n\t"+strings.Join(lines, "\n\t")+"\n)")
+	default:
+		at := p.offset(p.file.Name.End())
+		p.src = splice(p.src, at, at, "\n\nimport (\n\t"+strings.Join(lines, "\n\t")+"\n)")
+	}
+	return p.parse()
+}
+
+// declRange returns the byte offsets of a declaration including its doc
+// comment.
+func (p *Patch) declRange(d ast.Decl) (start, end int) {
+	pos := d.Pos()
+	switch d := d.(type) {
+	case *ast.FuncDecl:
+		if d.Doc != nil {
+			pos = d.Doc.Pos()
+		}
+	case *ast.GenDecl:
+		if d.Doc != nil {
+			pos = d.Doc.Pos()
+		}
+	}
+	return p.offset(pos), p.offset(d.End())
+}
+
+func (p *Patch) offset(pos token.Pos) int {
+	return p.fset.Position(pos).Offset
+}
+
+func splice(src []byte, start, end int, text string) []byte {
+	var out bytes.Buffer
+	out.Write(src[:start])
+	out.WriteString(text)
+	out.Write(src[end:])
+	return out.Bytes()
+}
+
+func parseDecl(code string) (*token.FileSet, ast.Decl, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", "package p\n\n"+code, parser.ParseComments)
+	if err != nil {
+		return nil, nil, fmt.Errorf("generated declaration is not valid Go: %w", err)
+	}
+	if len(file.Decls) != 1 {
+		return nil, nil, fmt.Errorf("got %d declarations, want 1", len(file.Decls))
+	}
+	return fset, file.Decls[0], nil
+}
+
+// declNames returns the names declared by a top-level declaration. Methods
+// are named "T.Method", where T is the receiver's base type name.
+func declNames(d ast.Decl) []string {
+	switch d := d.(type) {
+	case *ast.FuncDecl:
+		if d.Recv == nil || len(d.Recv.List) == 0 {
+			return []string{d.Name.Name}
+		}
+		return []string{receiverTypeName(d.Recv.List[0].Type) + "." + d.Name.Name}
+	case *ast.GenDecl:
+		var names []string
+		for _, spec := range d.Specs {
+			switch spec := spec.(type) {
+			case *ast.TypeSpec:
+				names = append(names, spec.Name.Name)
+			case *ast.ValueSpec:
+				for _, n := range spec.Names {
+					if n.Name != "_" {
+						names = append(names, n.Name)
+					}
+				}
+			}
+		}
+		return names
+	}
+	return nil
+}
+
+func receiverTypeName(expr ast.Expr) string {
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.ParenExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.Ident:
+			return e.Name
+		default:
+			return ""
+		}
+	}
+}
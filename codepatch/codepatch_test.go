@@ -0,0 +1,107 @@
+package codepatch
+
+import (
+	"testing"
+
+	"github.com/meta-programming/go-codegenutil"
+	cb "github.com/meta-programming/go-codegenutil/codebuilder"
+	"github.com/meta-programming/go-codegenutil/debugutil"
+)
+
+const input = `// Package mypkg is hand-written, mostly.
+package mypkg
+
+import (
+	// Comments in the import block survive.
+	m "math"
+)
+
+// Keep this comment.
+var pi = m.Pi
+
+
+// Old is replaced.
+func Old() int {
+	return 1 // the old value
+}
+
+type T struct{}
+
+// String is replaced too.
+func (t *T) String() string { return "" }
+
+// Trailing comment.
+`
+
+func TestPatch(t *testing.T) {
+	pkg := codegenutil.AssumedPackageName("abc.xyz/mypkg")
+	p, err := Parse(pkg, []byte(input))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	upserts := []cb.Code{
+		cb.Func("Old").Doc("Old is new.").Results(cb.P("", cb.Raw("float64"))).
+			Body(cb.Codef("return %s(2)", codegenutil.Sym("math", "Sqrt"))),
+		cb.Func("String").Receiver("t", cb.Raw("*T")).Results(cb.P("", cb.Raw("string"))).
+			Body(cb.Codef("return %s(t)", codegenutil.Sym("fmt", "Sprint"))),
+		cb.Var("timeout").Value(codegenutil.Sym("time", "Second")),
+	}
+	for _, decl := range upserts {
+		if err := p.Upsert(decl); err != nil {
+			t.Fatalf("Upsert() error: %v", err)
+		}
+	}
+	if removed, err := p.Remove("pi"); err != nil || !removed {
+		t.Fatalf("Remove() = %v, %v, want true, nil", removed, err)
+	}
+	got, err := p.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes() error: %v", err)
+	}
+	want := `// Package mypkg is hand-written, mostly.
+package mypkg
+
+import (
+	// Comments in the import block survive.
+	"fmt"
+	m "math"
+	"time"
+)
+
+// Old is new.
+func Old() float64 {
+	return m.Sqrt(2)
+}
+
+type T struct{}
+
+func (t *T) String() string {
+	return fmt.Sprint(t)
+}
+
+// Trailing comment.
+
+var timeout = time.Second
+`
+	if string(got) != want {
+		t.Errorf("Bytes() got != want:\n%s", debugutil.SideBySide(string(got), want))
+	}
+}
+
+func TestPatch_noImports(t *testing.T) {
+	p, err := Parse(codegenutil.AssumedPackageName("abc.xyz/mypkg"), []byte("package mypkg\n\n// x is kept.\nvar x = 1\n"))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	if err := p.Upsert(cb.Var("y").Type(codegenutil.Sym("time", "Duration"))); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+	got, err := p.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes() error: %v", err)
+	}
+	want := "package mypkg\n\nimport (\n\t\"time\"\n)\n\n// x is kept.\nvar x = 1\n\nvar y time.Duration\n"
+	if string(got) != want {
+		t.Errorf("Bytes() got != want:\n%s", debugutil.SideBySide(string(got), want))
+	}
+}
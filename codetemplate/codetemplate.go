@@ -19,10 +19,16 @@ other than fmt.Fprint to format objects.
 package codetemplate
 
 import (
+	"bytes"
+	"context"
 	"crypto/sha256"
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
+	"sync"
+	"text/template/parse"
+	"time"
 
 	"github.com/meta-programming/go-codegenutil"
 	"github.com/meta-programming/go-codegenutil/template"
@@ -38,34 +44,77 @@ func KeepUnusedImports() Option {
 	return Option{func(t *Template) { t.formatter = nil }}
 }
 
+// Simplify makes the template apply the simplifications of "gofmt -s" to the
+// generated code, such as eliding the types of nested composite literals. See
+// unusedimports.SimplifyUnparsed.
+func Simplify() Option {
+	return Option{func(t *Template) { t.simplify = true }}
+}
+
 // WithName specifies the name of the text template creates.
 func WithName(templateName string) Option {
 	return Option{func(t *Template) { t.templateName = templateName }}
 }
 
+// WithErrorOutputLines specifies how many of the last lines of output produced
+// before an execution error are included in the *ExecError. The default is 10;
+// negative values are treated as 0.
+func WithErrorOutputLines(n int) Option {
+	if n < 0 {
+		n = 0
+	}
+	return Option{func(t *Template) { t.errorOutputLines = n }}
+}
+
 // WithFuncs specifies the name of the text template creates.
 func WithFuncs(funcs template.FuncMap) Option {
 	return Option{func(t *Template) {
-		t.transformers = append(t.transformers, func(tmpl *template.Template) {
-			tmpl.Funcs(funcs)
-		})
+		for name, fn := range funcs {
+			t.funcs[name] = fn
+		}
 	}}
 }
 
+// WithLogger makes the template log its executions at the debug level, with
+// their durations and the number of imports of the generated files, and the
+// errors of failed executions.
+func WithLogger(l codegenutil.Logger) Option {
+	return Option{func(t *Template) { t.logger = l }}
+}
+
+// OnSymbolsUsed makes the template call fn after each successful execution
+// with the symbols of other packages that the generated file refers to, and
+// the number of references to each, for tools that review generated code, such
+// as those that flag calls to sensitive packages. See codegenutil.SymbolsUsed.
+// Execute fails if the generated file isn't valid Go, which is only possible
+// with KeepUnusedImports. fn may be called concurrently by concurrent
+// executions.
+func OnSymbolsUsed(fn func(ec *ExecContext, uses []*codegenutil.SymbolUse)) Option {
+	return Option{func(t *Template) { t.onSymbolsUsed = fn }}
+}
+
 // Template is a Go code generation template. See Parse() for details.
+//
+// A Template is safe for concurrent use: once parsed, it can be shared by the
+// workers of a pool, which may all call Execute at once without copying it.
 type Template struct {
-	tt                 *template.Template
+	tt                 EngineTemplate
 	importsPlaceholder string
 	headerPlaceholder  string
 
 	templateName string
-	// called in successon on the template during construction
-	transformers []func(tmpl *template.Template)
-	formatter    func(filename, code string) (string, error)
+	engine       Engine
+	// functions passed to the engine in addition to imports and header
+	funcs            map[string]any
+	formatter        func(filename, code string) (string, error)
+	simplify         bool
+	errorOutputLines int
+	logger           codegenutil.Logger
+	onSymbolsUsed    func(*ExecContext, []*codegenutil.SymbolUse)
 }
 
 // Parse returns a new template by passing tmplText to the parser in
-// "text/template".
+// "text/template", or to the Engine specified using WithEngine.
 //
 // The template is evaluated with additional "pipeline" functions:
 //
@@ -77,35 +126,87 @@ type Template struct {
 //             A function that takes no arguments and outputs a package statement
 //             and imports block, a.ka. PackageClause and ImportDecl in the Go spec:
 //             https://go.dev/ref/spec#SourceFile.
+//    ctx
+//             A function that takes no arguments and returns the *ExecContext
+//             of the current execution, such as {{ctx.Package.Name}}.
+//    fileImports
+//             A function that takes no arguments and returns the
+//             *codegenutil.FileImports of the file being generated, the same
+//             as {{ctx.Imports}}, so that templates can call its methods.
+//    localName
+//             A function that takes a package and an optional alias, imports
+//             the package if it isn't imported yet, and outputs the name that
+//             qualifies its symbols in the file, such as
+//             {{localName "gopkg.in/yaml.v3" "yaml"}}. The package may be a
+//             *codegenutil.Package, a *codegenutil.Symbol, or an import path.
+//             The name is empty for the builtin package and the package of
+//             the file.
+//    codef
+//             A function that takes a format and arguments and outputs them
+//             formatted like fmt.Sprintf, except that arguments with a GoCode
+//             method, such as symbols, are formatted as they would be if they
+//             were printed, such as {{codef "var %s %s = %s" .name .type .value}}.
+//             Unlike "printf", it qualifies and imports symbols.
+//    convert
+//             A function that takes a type and an expression and outputs a
+//             conversion of the expression to the type, parenthesizing the
+//             type as needed, such as {{convert "func(int) error" "f"}} for
+//             (func(int) error)(f). See codebuilder.Convert.
+//    assert
+//             A function that takes an expression and a type and outputs a
+//             type assertion, parenthesizing the expression as needed, such
+//             as {{assert "<-ch" .bufferPtr}} for (<-ch).(*bytes.Buffer). See
+//             codebuilder.TypeAssert.
+//
+// The arguments of convert and assert may be symbols, other values with a
+// GoCode method such as codebuilder.Code, or strings of Go code. Functions
+// passed with WithFuncs replace fileImports, localName, codef, convert and
+// assert if they have the same names.
 func Parse(tmplText string, opts ...Option) (*Template, error) {
 	h := sha256.New()
 	h.Write([]byte(tmplText))
-	importsPlaceholder := fmt.Sprintf("<PLACEHOLDER FOR IMPORTS %x>", h.Sum(nil))
-	headerPlaceholder := fmt.Sprintf("<PLACEHOLDER FOR PACKAGE STATEMENT AND IMPORTS %x>", h.Sum(nil))
+	importsPlaceholder := fmt.Sprintf("%sIMPORTS %x>", placeholderPrefix, h.Sum(nil))
+	headerPlaceholder := fmt.Sprintf("%sPACKAGE STATEMENT AND IMPORTS %x>", placeholderPrefix, h.Sum(nil))
 
 	out := &Template{
 		importsPlaceholder: importsPlaceholder,
 		headerPlaceholder:  headerPlaceholder,
 		formatter:          unusedimports.PruneUnparsed,
 		templateName:       "generated.go",
+		engine:             ForkEngine(),
+		funcs:              map[string]any{},
+		errorOutputLines:   10,
 	}
 	for _, opt := range opts {
 		opt.apply(out)
 	}
+	if out.simplify {
+		if out.formatter == nil {
+			out.formatter = unusedimports.SimplifyUnparsed
+		} else {
+			out.formatter = unusedimports.PruneAndSimplifyUnparsed
+		}
+	}
 
-	t := template.New(out.templateName)
-	for _, transformer := range out.transformers {
-		transformer(t)
+	funcs := exprFuncs()
+	for name, fn := range out.funcs {
+		funcs[name] = fn
+	}
+	funcs["imports"] = func() string {
+		return importsPlaceholder
+	}
+	funcs["header"] = func() string {
+		return headerPlaceholder
+	}
+	// Replaced with functions bound to the real context during Execute.
+	funcs["ctx"] = func() *ExecContext { return nil }
+	for name, fn := range importFuncs(nil) {
+		if _, ok := out.funcs[name]; !ok {
+			funcs[name] = fn
+		}
 	}
 
-	t, err := t.Funcs(template.FuncMap{
-		"imports": func() string {
-			return importsPlaceholder
-		},
-		"header": func() string {
-			return headerPlaceholder
-		},
-	}).Option("missingkey=error").Parse(tmplText)
+	t, err := out.engine.Parse(out.templateName, tmplText, funcs)
 	if err != nil {
 		return nil, err
 	}
@@ -113,42 +214,252 @@ func Parse(tmplText string, opts ...Option) (*Template, error) {
 	return out, nil
 }
 
+// ParseTrees returns the parse trees of the template and of the templates it
+// defines, keyed by template name, for use by tools that analyze templates.
+// The {{header}} and {{imports}} placeholders appear in the trees as calls to
+// functions of the same name.
+//
+// The trees are shared with the template and must not be modified. ParseTrees
+// returns nil if the template's Engine does not implement ParseTreeTemplate.
+func (t *Template) ParseTrees() map[string]*parse.Tree {
+	ptt, ok := t.tt.(ParseTreeTemplate)
+	if !ok {
+		return nil
+	}
+	return ptt.ParseTrees()
+}
+
+// Execute applies the template to data, formatting symbols using imports, and
+// writes the generated code to wr. The template is not modified, so Execute may
+// be called from multiple goroutines at once with different imports.
+//
+// A panic in a template function or in the GoCode method of a printed value,
+// such as a *codegenutil.FrozenError, is returned as an *ExecError instead.
+// If imports has a target Go version, symbols that are newer than the target
+// fail the execution with an error wrapping a *codegenutil.GoVersionError, as
+// do type parameters in the generated file; see codegenutil.WithGoVersion.
+// With the default engine, its message includes the location of the action in
+// the template and the action itself, such as <{{.field}}>.
 func (t *Template) Execute(imports *codegenutil.FileImports, wr io.Writer, data any) error {
-	execT, err := t.tt.Clone()
+	return t.ExecuteContext(context.Background(), imports, wr, data)
+}
+
+// ExecuteContext is like Execute, but stops executing the template once ctx is
+// done and returns an error wrapping ctx.Err(), so that generation embedded in
+// servers and build systems can be cancelled or bounded by a deadline. The
+// default engine checks ctx before each iteration of a range action and each
+// template invocation; other engines may only check it before executing.
+func (t *Template) ExecuteContext(ctx context.Context, imports *codegenutil.FileImports, wr io.Writer, data any) error {
+	if t.logger == nil {
+		return t.execute(ctx, imports, wr, data)
+	}
+	start := time.Now()
+	err := t.execute(ctx, imports, wr, data)
 	if err != nil {
-		return fmt.Errorf("error with Clone: %w", err)
+		t.logger.Debug("template execution failed", "template", t.templateName, "package", imports.Package().ImportPath(), "error", err)
+		return err
 	}
-	execT.Printer(false, t.makePrinter(imports))
+	t.logger.Debug("template executed", "template", t.templateName, "package", imports.Package().ImportPath(), "imports", len(imports.List()), "duration", time.Since(start))
+	return nil
+}
 
-	pass1Buf := &strings.Builder{}
+func (t *Template) execute(ctx context.Context, imports *codegenutil.FileImports, wr io.Writer, data any) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	pass1Buf := getBuffer()
+	defer putBuffer(pass1Buf)
 	// Pass 1
-	if err := execT.Execute(pass1Buf, data); err != nil {
+	execContext := &ExecContext{
+		TemplateName: t.templateName,
+		Package:      imports.Package(),
+		Imports:      imports,
+	}
+	execFuncs := importFuncs(imports)
+	for name := range t.funcs {
+		delete(execFuncs, name)
+	}
+	execFuncs["ctx"] = func() *ExecContext { return execContext }
+	if err := t.executeEngine(pass1Buf, data, &ExecOptions{
+		Print:   t.makePrinter(imports),
+		Funcs:   execFuncs,
+		Context: ctx,
+	}); err != nil {
+		// The placeholders of the output are shown as the template actions
+		// that print them.
+		output := strings.NewReplacer(t.headerPlaceholder, "{{header}}", t.importsPlaceholder, "{{imports}}").Replace(pass1Buf.String())
+		return newExecError(err, output, t.errorOutputLines)
+	}
+	if err := ctx.Err(); err != nil {
 		return err
 	}
 
-	withImports := strings.ReplaceAll(pass1Buf.String(), t.importsPlaceholder, imports.Format(false))
-	withHeader := strings.ReplaceAll(withImports, t.headerPlaceholder, imports.Format(true))
+	// Pass 2
+	pass2Buf := getBuffer()
+	defer putBuffer(pass2Buf)
+	t.replacePlaceholders(pass2Buf, pass1Buf.Bytes(), imports)
 
-	formatted, err := withHeader, error(nil)
+	out := pass2Buf.Bytes()
 	if t.formatter != nil {
-		formatted, err = t.formatter("", withHeader)
+		formatted, err := t.formatter("", pass2Buf.String())
+		if err != nil {
+			return fmt.Errorf("error formatting template output: %w", err)
+		}
+		out = []byte(formatted)
 	}
-	if err != nil {
-		return fmt.Errorf("error formatting template output: %w", err)
+	if err := checkTypeParams(imports, out); err != nil {
+		return err
 	}
-
-	if _, err := wr.Write([]byte(formatted)); err != nil {
+	if _, err := wr.Write(out); err != nil {
 		return err
 	}
-
+	if t.onSymbolsUsed != nil {
+		uses, err := codegenutil.SymbolsUsed(out)
+		if err != nil {
+			return fmt.Errorf("error listing the symbols used by the template output: %w", err)
+		}
+		t.onSymbolsUsed(execContext, uses)
+	}
 	return nil
 }
 
-func (t *Template) makePrinter(imports *codegenutil.FileImports) template.FormatFunc {
+// executeEngine executes the engine's template, recovering the panics that the
+// engine doesn't turn into errors itself, such as those of custom Engines.
+func (t *Template) executeEngine(wr io.Writer, data any, opts *ExecOptions) (err error) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		if e, ok := r.(error); ok {
+			err = fmt.Errorf("template: %s: panic: %w", t.templateName, e)
+		} else {
+			err = fmt.Errorf("template: %s: panic: %v", t.templateName, r)
+		}
+	}()
+	return t.tt.Execute(wr, data, opts)
+}
+
+// placeholderPrefix is the common prefix of the imports and header
+// placeholders.
+const placeholderPrefix = "<PLACEHOLDER FOR "
+
+// replacePlaceholders writes src to dst with the imports and header
+// placeholders replaced, in a single pass over src. The replacements are only
+// formatted if their placeholder appears.
+func (t *Template) replacePlaceholders(dst *bytes.Buffer, src []byte, imports *codegenutil.FileImports) {
+	var importsText, headerText *string
+	format := func(cache **string, includePackageStatement bool) string {
+		if *cache == nil {
+			s := imports.Format(includePackageStatement)
+			*cache = &s
+		}
+		return **cache
+	}
+	for {
+		i := bytes.Index(src, []byte(placeholderPrefix))
+		if i < 0 {
+			dst.Write(src)
+			return
+		}
+		dst.Write(src[:i])
+		src = src[i:]
+		switch {
+		case bytes.HasPrefix(src, []byte(t.importsPlaceholder)):
+			dst.WriteString(format(&importsText, false))
+			src = src[len(t.importsPlaceholder):]
+		case bytes.HasPrefix(src, []byte(t.headerPlaceholder)):
+			dst.WriteString(format(&headerText, true))
+			src = src[len(t.headerPlaceholder):]
+		default:
+			dst.WriteString(placeholderPrefix)
+			src = src[len(placeholderPrefix):]
+		}
+	}
+}
+
+// maxPooledBufferSize is the capacity above which buffers aren't returned to
+// bufferPool, so that a single huge output doesn't stay in memory.
+const maxPooledBufferSize = 1 << 20
+
+// bufferPool holds the buffers of Execute, which are reused across executions
+// to reduce allocations when generating many files.
+var bufferPool = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+
+func getBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer)
+}
+
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBufferSize {
+		return
+	}
+	buf.Reset()
+	bufferPool.Put(buf)
+}
+
+// ExecContext describes the file a Template is being executed for. It is
+// returned by the "ctx" template function so that shared sub-templates can
+// adapt to the file they are rendered into.
+type ExecContext struct {
+	// TemplateName is the name of the executing Template. See WithName.
+	TemplateName string
+	// Package is the package of the file being generated.
+	Package *codegenutil.Package
+	// Imports is the set of imports of the file being generated.
+	Imports *codegenutil.FileImports
+}
+
+// ExecError is returned by Execute when the template fails to execute. Its
+// message includes the last lines of output produced before the failure.
+type ExecError struct {
+	// Err is the error returned by the template engine. For the default
+	// engine, it includes the location of the failure within the template.
+	Err error
+	// Output contains the last lines of output produced before the failure.
+	Output string
+	// OutputLine is the line number of the first line of Output.
+	OutputLine int
+}
+
+func newExecError(err error, output string, maxLines int) *ExecError {
+	lines := strings.Split(output, "\n")
+	first := 0
+	if len(lines) > maxLines {
+		first = len(lines) - maxLines
+	}
+	return &ExecError{
+		Err:        err,
+		Output:     strings.Join(lines[first:], "\n"),
+		OutputLine: first + 1,
+	}
+}
+
+func (e *ExecError) Error() string {
+	if e.Output == "" {
+		return e.Err.Error()
+	}
+	lines := strings.Split(e.Output, "\n")
+	width := len(strconv.Itoa(e.OutputLine + len(lines) - 1))
+	for i, line := range lines {
+		lines[i] = fmt.Sprintf("%*d: %s", width, e.OutputLine+i, line)
+	}
+	return fmt.Sprintf("%v\noutput before the error:\n%s", e.Err, strings.Join(lines, "\n"))
+}
+
+func (e *ExecError) Unwrap() error { return e.Err }
+
+func (t *Template) makePrinter(imports *codegenutil.FileImports) PrintFunc {
 	// TODO: Add an option to NewTemplate that allows customizing this function.
+	// The printer is only called by the goroutine that executes the template,
+	// so symbols can share a buffer.
+	var buf []byte
 	return func(w io.Writer, raw any) (n int, err error) {
 		outStr := ""
 		switch obj := raw.(type) {
+		case *codegenutil.Symbol:
+			buf = obj.AppendGoCode(buf[:0], imports)
+			return w.Write(buf)
 		case interface {
 			GoCode(*codegenutil.FileImports) string
 		}:
@@ -157,6 +468,6 @@ func (t *Template) makePrinter(imports *codegenutil.FileImports) template.Format
 			outStr = fmt.Sprint(raw)
 		}
 
-		return w.Write([]byte(outStr))
+		return io.WriteString(w, outStr)
 	}
 }
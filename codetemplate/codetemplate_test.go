@@ -2,10 +2,20 @@ package codetemplate
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/meta-programming/go-codegenutil"
+	cb "github.com/meta-programming/go-codegenutil/codebuilder"
 	"github.com/meta-programming/go-codegenutil/debugutil"
+	"github.com/meta-programming/go-codegenutil/internal/logtest"
 )
 
 func TestTemplate_Execute(t *testing.T) {
@@ -40,9 +50,8 @@ const myNum {{.numType}} = 42
 package mypkg
 
 import (
-	"math"
-
 	math2 "alternative/math"
+	"math"
 )
 
 // Doesn't do anything special, really.
@@ -50,6 +59,54 @@ var myThing = math.Max
 var myThing2 = math2.Max
 
 const myNum int64 = 42
+`,
+		},
+		{
+			name: "placeholder text",
+			template: `package mypkg
+
+{{imports}}
+
+// <PLACEHOLDER FOR nothing>
+var x = {{.mysym}}
+`,
+			imports: codegenutil.NewFileImports(pkg1),
+			data: map[string]*codegenutil.Symbol{
+				"mysym": codegenutil.AssumedPackageName("math").Symbol("Max"),
+			},
+			want: `package mypkg
+
+import (
+	"math"
+)
+
+// <PLACEHOLDER FOR nothing>
+var x = math.Max
+`,
+		},
+		{
+			name: "convert and assert",
+			template: `{{header}}
+
+var f = {{convert "func(int) error" "g"}}
+var d = {{convert .duration "n * m"}}
+var b = {{assert "<-ch" .buffer}}
+`,
+			imports: codegenutil.NewFileImports(pkg1),
+			data: map[string]any{
+				"duration": codegenutil.Sym("time", "Duration"),
+				"buffer":   cb.Unary("*", codegenutil.Sym("bytes", "Buffer")),
+			},
+			want: `package mypkg
+
+import (
+	"bytes"
+	"time"
+)
+
+var f = (func(int) error)(g)
+var d = time.Duration(n * m)
+var b = (<-ch).(*bytes.Buffer)
 `,
 		},
 	}
@@ -73,3 +130,578 @@ const myNum int64 = 42
 		})
 	}
 }
+
+func TestTemplate_Execute_textTemplateEngine(t *testing.T) {
+	tmpl, err := Parse(`{{header}}
+
+var x = {{gocode .sym}}
+`, WithEngine(TextTemplateEngine()))
+	if err != nil {
+		t.Fatalf("Parse got error %v", err)
+	}
+	wr := &bytes.Buffer{}
+	if err := tmpl.Execute(codegenutil.NewFileImports(codegenutil.AssumedPackageName("abc.xyz/mypkg")), wr, map[string]any{
+		"sym": codegenutil.Sym("math", "Pi"),
+	}); err != nil {
+		t.Fatalf("Template.Execute() error = %v", err)
+	}
+	want := `package mypkg
+
+import (
+	"math"
+)
+
+var x = math.Pi
+`
+	if got := wr.String(); got != want {
+		t.Errorf("Template.Execute() generated unexpected output (want|got):\n%s", debugutil.SideBySide(got, want))
+	}
+}
+
+func TestTemplate_Execute_concurrent(t *testing.T) {
+	for _, engine := range []Engine{ForkEngine(), TextTemplateEngine()} {
+		tmpl, err := Parse(`{{header}}
+
+// {{ctx.Package.Name}}
+var x = {{gocode .}}
+`, WithEngine(engine), WithFuncs(map[string]any{
+			// Only needed by the default engine, which formats symbols
+			// without it.
+			"gocode": func(v any) any { return v },
+		}))
+		if err != nil {
+			t.Fatalf("Parse got error %v", err)
+		}
+		var wg sync.WaitGroup
+		errs := make([]error, 50)
+		for i := range errs {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				pkg := codegenutil.AssumedPackageName(fmt.Sprintf("abc.xyz/pkg%d", i))
+				wr := &bytes.Buffer{}
+				if err := tmpl.Execute(codegenutil.NewFileImports(pkg), wr, codegenutil.Sym("math", "Pi")); err != nil {
+					errs[i] = err
+					return
+				}
+				want := fmt.Sprintf("package pkg%d\n\nimport (\n\t\"math\"\n)\n\n// pkg%d\nvar x = math.Pi\n", i, i)
+				if got := wr.String(); got != want {
+					errs[i] = fmt.Errorf("generated unexpected output (want|got):\n%s", debugutil.SideBySide(got, want))
+				}
+			}(i)
+		}
+		wg.Wait()
+		for i, err := range errs {
+			if err != nil {
+				t.Errorf("Template.Execute() #%d with %T: %v", i, engine, err)
+			}
+		}
+	}
+}
+
+// errWriter fails every write with err.
+type errWriter struct{ err error }
+
+func (w errWriter) Write([]byte) (int, error) { return 0, w.err }
+
+func TestForkEngine_printError(t *testing.T) {
+	tmpl, err := ForkEngine().Parse("t", "{{/* comment */ -}}\n{{.}}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	errDiskFull := errors.New("disk full")
+	err = tmpl.Execute(errWriter{errDiskFull}, 1, &ExecOptions{
+		Print: func(w io.Writer, value any) (int, error) { return fmt.Fprint(w, value) },
+	})
+	if !errors.Is(err, errDiskFull) || !strings.Contains(err.Error(), "t:2:2: disk full") {
+		t.Errorf("Execute() error = %v, want the location of the action and %v", err, errDiskFull)
+	}
+}
+
+func TestTemplate_ParseTrees(t *testing.T) {
+	for _, engine := range []Engine{ForkEngine(), TextTemplateEngine()} {
+		tmpl, err := Parse(`{{header}}
+{{define "sub"}}var {{.name}} = 1{{end}}
+{{template "sub" .}}
+`, WithName("file.go"), WithEngine(engine))
+		if err != nil {
+			t.Fatalf("Parse got error %v", err)
+		}
+		trees := tmpl.ParseTrees()
+		var names []string
+		for name := range trees {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if got, want := strings.Join(names, ","), "file.go,sub"; got != want {
+			t.Errorf("ParseTrees() has templates %q, want %q", got, want)
+		}
+		if got, want := trees["sub"].Root.String(), "var {{.name}} = 1"; got != want {
+			t.Errorf("ParseTrees()[%q] = %q, want %q", "sub", got, want)
+		}
+	}
+}
+
+func TestTemplate_Execute_ctx(t *testing.T) {
+	tmpl, err := Parse(`{{define "decl"}}// {{ctx.TemplateName}} in package {{ctx.Package.Name}}
+var x = 1{{end}}{{header}}
+
+{{template "decl"}}
+`, WithName("x.go"))
+	if err != nil {
+		t.Fatalf("Parse got error %v", err)
+	}
+	wr := &bytes.Buffer{}
+	if err := tmpl.Execute(codegenutil.NewFileImports(codegenutil.AssumedPackageName("abc.xyz/mypkg")), wr, nil); err != nil {
+		t.Fatalf("Template.Execute() error = %v", err)
+	}
+	want := `package mypkg
+
+import ()
+
+// x.go in package mypkg
+var x = 1
+`
+	if got := wr.String(); got != want {
+		t.Errorf("Template.Execute() generated unexpected output (want|got):\n%s", debugutil.SideBySide(got, want))
+	}
+}
+
+func TestTemplate_Execute_importFuncs(t *testing.T) {
+	tmpl, err := Parse(`{{header}}
+
+{{$yaml := localName "gopkg.in/yaml.v3" "yamlv3"}}
+var _ = {{$yaml}}.Marshal
+var _ = {{localName .fmt}}.Sprint
+// {{with $n := localName "abc.xyz/mypkg"}}{{$n}}{{else}}unqualified{{end}}
+// {{(fileImports).Package.Name}}
+`)
+	if err != nil {
+		t.Fatalf("Parse got error %v", err)
+	}
+	wr := &bytes.Buffer{}
+	imports := codegenutil.NewFileImports(codegenutil.AssumedPackageName("abc.xyz/mypkg"))
+	if err := tmpl.Execute(imports, wr, map[string]any{"fmt": codegenutil.Sym("fmt", "Println")}); err != nil {
+		t.Fatalf("Template.Execute() error = %v", err)
+	}
+	want := `package mypkg
+
+import (
+	"fmt"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+var _ = yamlv3.Marshal
+var _ = fmt.Sprint
+
+// unqualified
+// mypkg
+`
+	if got := wr.String(); got != want {
+		t.Errorf("Template.Execute() generated unexpected output (want|got):\n%s", debugutil.SideBySide(got, want))
+	}
+
+	if err := tmpl.Execute(imports.Freeze(), &bytes.Buffer{}, map[string]any{"fmt": codegenutil.Sym("os", "Exit")}); err == nil {
+		t.Errorf("Template.Execute() with frozen imports got no error, want an error from localName")
+	}
+}
+
+func TestTemplate_Execute_simplify(t *testing.T) {
+	tmpl, err := Parse(`{{header}}
+
+var x = []{{.t}}{ {{range .values}}{{$.t}}{ {{.}} }, {{end}} }
+`, Simplify())
+	if err != nil {
+		t.Fatalf("Parse got error %v", err)
+	}
+	wr := &bytes.Buffer{}
+	err = tmpl.Execute(codegenutil.NewFileImports(codegenutil.AssumedPackageName("abc.xyz/mypkg"), codegenutil.WithImports(codegenutil.AssumedPackageName("os"))), wr, map[string]any{
+		"t":      codegenutil.Sym("example.com/pair", "Pair"),
+		"values": []string{"1", "2"},
+	})
+	if err != nil {
+		t.Fatalf("Template.Execute() error = %v", err)
+	}
+	want := `package mypkg
+
+import (
+	"example.com/pair"
+)
+
+var x = []pair.Pair{{1}, {2}}
+`
+	if got := wr.String(); got != want {
+		t.Errorf("Template.Execute() generated unexpected output (want|got):\n%s", debugutil.SideBySide(got, want))
+	}
+}
+
+func TestTemplate_Execute_codef(t *testing.T) {
+	tmpl, err := Parse(`{{header}}
+
+{{codef "var %s %s = %s" .name .type .value}}
+{{codef "var n%d = %q" 1 "x"}}
+`)
+	if err != nil {
+		t.Fatalf("Parse got error %v", err)
+	}
+	wr := &bytes.Buffer{}
+	err = tmpl.Execute(codegenutil.NewFileImports(codegenutil.AssumedPackageName("abc.xyz/mypkg")), wr, map[string]any{
+		"name":  "d",
+		"type":  codegenutil.Sym("time", "Duration"),
+		"value": cb.Call(codegenutil.Sym("example.com/time", "Parse"), cb.Raw(`"1s"`)),
+	})
+	if err != nil {
+		t.Fatalf("Template.Execute() error = %v", err)
+	}
+	want := `package mypkg
+
+import (
+	"time"
+
+	time2 "example.com/time"
+)
+
+var d time.Duration = time2.Parse("1s")
+var n1 = "x"
+`
+	if got := wr.String(); got != want {
+		t.Errorf("Template.Execute() generated unexpected output (want|got):\n%s", debugutil.SideBySide(got, want))
+	}
+}
+
+func TestTemplate_Execute_panic(t *testing.T) {
+	tmpl, err := Parse(`{{header}}
+
+var x = {{.ok}}
+var y = {{.frozen}}
+`, WithName("x.go"))
+	if err != nil {
+		t.Fatalf("Parse got error %v", err)
+	}
+	imports := codegenutil.NewFileImports(codegenutil.AssumedPackageName("abc.xyz/mypkg"), codegenutil.WithImports(codegenutil.AssumedPackageName("fmt"))).Freeze()
+	err = tmpl.Execute(imports, &bytes.Buffer{}, map[string]any{
+		"ok":     codegenutil.Sym("fmt", "Sprint"),
+		"frozen": codegenutil.Sym("os", "Exit"),
+	})
+	var frozenErr *codegenutil.FrozenError
+	if !errors.As(err, &frozenErr) {
+		t.Fatalf("Template.Execute() error = %v, want a *codegenutil.FrozenError", err)
+	}
+	for _, want := range []string{"x.go:4:10", "<{{.frozen}}>", "panic: cannot import \"os\""} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Template.Execute() error = %v, want it to contain %q", err, want)
+		}
+	}
+
+	tmpl, err = Parse(`{{boom}}`, WithEngine(panicEngine{}))
+	if err != nil {
+		t.Fatalf("Parse got error %v", err)
+	}
+	err = tmpl.Execute(imports, &bytes.Buffer{}, nil)
+	if want := "template: generated.go: panic: boom"; err == nil || err.Error() != want {
+		t.Errorf("Template.Execute() with a panicking engine error = %v, want %q", err, want)
+	}
+}
+
+// panicEngine is an Engine whose templates panic when executed.
+type panicEngine struct{}
+
+func (panicEngine) Parse(name, text string, funcs map[string]any) (EngineTemplate, error) {
+	return panicEngine{}, nil
+}
+
+func (panicEngine) Clone() (EngineTemplate, error) { return panicEngine{}, nil }
+
+func (panicEngine) Execute(w io.Writer, data any, opts *ExecOptions) error { panic("boom") }
+
+func TestTemplate_Execute_errorOutput(t *testing.T) {
+	tmpl, err := Parse(`{{header}}
+
+{{range .}}var x{{.}} = {{if lt . 3}}{{.}}{{else}}{{.Missing}}{{end}}
+{{end}}`, WithErrorOutputLines(3))
+	if err != nil {
+		t.Fatalf("Parse got error %v", err)
+	}
+	err = tmpl.Execute(codegenutil.NewFileImports(codegenutil.AssumedPackageName("abc.xyz/mypkg")), &bytes.Buffer{}, []int{0, 1, 2, 3})
+	var execErr *ExecError
+	if !errors.As(err, &execErr) {
+		t.Fatalf("Template.Execute() error = %v, want *ExecError", err)
+	}
+	want := `template: generated.go:3:52: executing "generated.go" at <.Missing>: can't evaluate field Missing in type int
+output before the error:
+4: var x1 = 1
+5: var x2 = 2
+6: var x3 = `
+	if got := err.Error(); got != want {
+		t.Errorf("Template.Execute() error (want|got):\n%s", debugutil.SideBySide(got, want))
+	}
+
+	for _, tt := range []struct {
+		lines int
+		want  string
+	}{
+		{10, "output before the error:\n1: {{header}}\n2: \n3: var x0 = 0\n4: var x1 = "},
+		{-1, "can't evaluate field Missing in type int"},
+	} {
+		tmpl, err := Parse("{{header}}\n\n{{range .}}var x{{.}} = {{if lt . 1}}{{.}}{{else}}{{.Missing}}{{end}}\n{{end}}", WithErrorOutputLines(tt.lines))
+		if err != nil {
+			t.Fatalf("Parse got error %v", err)
+		}
+		err = tmpl.Execute(codegenutil.NewFileImports(codegenutil.AssumedPackageName("abc.xyz/mypkg")), &bytes.Buffer{}, []int{0, 1})
+		if got := err.Error(); !strings.HasSuffix(got, tt.want) {
+			t.Errorf("Template.Execute() with %d error output lines got error %q, want suffix %q", tt.lines, got, tt.want)
+		}
+	}
+}
+
+func TestTemplate_Execute_missingKey(t *testing.T) {
+	for _, engine := range []Engine{ForkEngine(), TextTemplateEngine()} {
+		tmpl, err := Parse("{{header}}\n\n{{if .debug}}var debug = true{{end}}\nvar x = {{.value}}\nvar y = {{gocode .sym}}\n",
+			WithEngine(engine), WithFuncs(map[string]any{
+				// Only needed by the default engine, which formats symbols
+				// without it.
+				"gocode": func(v any) any { return v },
+			}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		imports := codegenutil.NewFileImports(codegenutil.AssumedPackageName("abc.xyz/mypkg"))
+		sym := codegenutil.Sym("math", "Pi")
+		err = tmpl.Execute(imports, io.Discard, map[string]any{"value": 1, "sym": sym})
+		if err != nil {
+			t.Errorf("Template.Execute() with %T and a missing condition got error %v", engine, err)
+		}
+		for _, data := range []map[string]any{{"debug": true, "sym": sym}, {"value": 1}} {
+			err = tmpl.Execute(imports, io.Discard, data)
+			key := "value"
+			if _, ok := data["sym"]; !ok {
+				key = "sym"
+			}
+			if err == nil || !strings.Contains(err.Error(), fmt.Sprintf("key %q", key)) {
+				t.Errorf("Template.Execute() with %T and %v got error %v, want an error for key %q", engine, data, err, key)
+			}
+		}
+	}
+}
+
+func TestTemplate_Dump(t *testing.T) {
+	tmpl, err := Parse(`{{header}}
+{{define "sub"}}{{if .x}}var x = {{.x}}{{else}}var x = 0{{end}}{{end}}
+{{template "sub" .}}
+`, WithName("file.go"))
+	if err != nil {
+		t.Fatalf("Parse got error %v", err)
+	}
+	want := `template "file.go"
+  file.go:1:2 Placeholder {{header}} (package clause and imports)
+  file.go:1:10 Text "\n"
+  file.go:2:70 Text "\n"
+  file.go:3:11 Template {{template "sub" .}}
+  file.go:3:20 Text "\n"
+template "sub"
+  file.go:2:21 If {{if .x}}
+    file.go:2:25 Text "var x = "
+    file.go:2:35 Action {{.x}}
+  Else
+    file.go:2:47 Text "var x = 0"
+`
+	if got := tmpl.Dump(); got != want {
+		t.Errorf("Dump() (want|got):\n%s", debugutil.SideBySide(got, want))
+	}
+}
+
+func BenchmarkTemplate_Execute(b *testing.B) {
+	tmpl, err := Parse(`{{header}}
+
+{{range .}}var {{.Name}} = {{.}}
+{{end}}`, KeepUnusedImports())
+	if err != nil {
+		b.Fatal(err)
+	}
+	var syms []*codegenutil.Symbol
+	for i := 0; i < 1000; i++ {
+		syms = append(syms, codegenutil.Sym("abc.xyz/pkg"+strconv.Itoa(i%10), "Sym"+strconv.Itoa(i)))
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		imports := codegenutil.NewFileImports(codegenutil.AssumedPackageName("abc.xyz/mypkg"))
+		if err := tmpl.Execute(imports, io.Discard, syms); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestTemplate_Execute_logger(t *testing.T) {
+	logger := &logtest.Recorder{}
+	tmpl, err := Parse(`{{header}}
+
+var x = {{.}}
+`, WithName("x.go"), WithLogger(logger))
+	if err != nil {
+		t.Fatal(err)
+	}
+	imports := codegenutil.NewFileImports(codegenutil.AssumedPackageName("abc.xyz/mypkg"))
+	if err := tmpl.Execute(imports, io.Discard, codegenutil.Sym("strings", "ToUpper")); err != nil {
+		t.Fatal(err)
+	}
+	if err := tmpl.Execute(imports, io.Discard, codegenutil.Sym("strings", "ToLower")); err != nil {
+		t.Fatal(err)
+	}
+	failing, err := Parse(`{{.Missing}}`, WithName("failing.go"), WithLogger(logger))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := failing.Execute(imports, io.Discard, 1); err == nil {
+		t.Fatal("Execute() succeeded, want error")
+	}
+	events := logger.Events()
+	wantPrefixes := []string{
+		"template executed template=x.go package=abc.xyz/mypkg imports=1 duration=",
+		"template executed template=x.go package=abc.xyz/mypkg imports=1 duration=",
+		"template execution failed template=failing.go package=abc.xyz/mypkg error=",
+	}
+	if len(events) != len(wantPrefixes) {
+		t.Fatalf("logged events:\n%q\nwant %d events", events, len(wantPrefixes))
+	}
+	for i, event := range events {
+		if !strings.HasPrefix(event, wantPrefixes[i]) {
+			t.Errorf("event %d = %q, want prefix %q", i, event, wantPrefixes[i])
+		}
+	}
+}
+
+func TestTemplate_OnSymbolsUsed(t *testing.T) {
+	var got []string
+	tmpl, err := Parse(`{{header}}
+
+var x = {{.exec}}("ls")
+var y = {{.exec}}
+var _ = {{.marshal}}
+`, WithName("x.go"), OnSymbolsUsed(func(ec *ExecContext, uses []*codegenutil.SymbolUse) {
+		for _, u := range uses {
+			got = append(got, fmt.Sprintf("%s: %s.%s %d", ec.TemplateName, u.Package, u.Name, u.Count))
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	imports := codegenutil.NewFileImports(codegenutil.AssumedPackageName("abc.xyz/mypkg"))
+	if err := tmpl.Execute(imports, io.Discard, map[string]any{
+		"exec":    codegenutil.Sym("os/exec", "Command"),
+		"marshal": codegenutil.Sym("example.com/json/v2", "Marshal"),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"x.go: example.com/json/v2.Marshal 1", "x.go: os/exec.Command 2"}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("OnSymbolsUsed() got %q, want %q", got, want)
+	}
+}
+
+func TestTemplate_Execute_goVersion(t *testing.T) {
+	pkg := codegenutil.AssumedPackageName("abc.xyz/mypkg")
+	since := func(sym *codegenutil.Symbol) string {
+		if sym.Package().ImportPath() == "slices" {
+			return "go1.21"
+		}
+		return ""
+	}
+	tests := []struct {
+		name     string
+		template string
+		data     any
+		version  string
+		want     string
+		wantErr  string
+	}{
+		{
+			name:     "any fallback",
+			template: "{{header}}\n\nvar x {{.}}\n",
+			data:     codegenutil.Sym("", "any"),
+			version:  "go1.17",
+			want:     "package mypkg\n\nimport ()\n\nvar x interface{}\n",
+		},
+		{
+			name:     "newer symbol",
+			template: "{{header}}\n\nvar x = {{.}}[[]int]\n",
+			data:     codegenutil.Sym("slices", "Max"),
+			version:  "go1.20",
+			wantErr:  `"slices".Max requires go1.21, but the target Go version is go1.20`,
+		},
+		{
+			name:     "supported symbol",
+			template: "{{header}}\n\nvar x = {{.}}[[]int]\n",
+			data:     codegenutil.Sym("slices", "Max"),
+			version:  "1.21",
+			want:     "package mypkg\n\nimport (\n\t\"slices\"\n)\n\nvar x = slices.Max[[]int]\n",
+		},
+		{
+			name:     "generic function in the template",
+			template: "{{header}}\n\nfunc Id[T {{.}}](v T) T {\n\treturn v\n}\n",
+			data:     codegenutil.Sym("", "comparable"),
+			version:  "go1.18",
+			want:     "package mypkg\n\nimport ()\n\nfunc Id[T comparable](v T) T {\n\treturn v\n}\n",
+		},
+		{
+			name:     "generic type in the template",
+			template: "{{header}}\n\ntype List[T any] []T\n",
+			version:  "go1.17",
+			wantErr:  "type parameters requires go1.18, but the target Go version is go1.17",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := Parse(tt.template)
+			if err != nil {
+				t.Fatal(err)
+			}
+			imports := codegenutil.NewFileImports(pkg, codegenutil.WithGoVersion(tt.version), codegenutil.WithSymbolVersions(since))
+			var out strings.Builder
+			err = tmpl.Execute(imports, &out, tt.data)
+			if tt.wantErr != "" {
+				if !errors.Is(err, codegenutil.ErrGoVersion) || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Template.Execute() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Template.Execute() error: %v", err)
+			}
+			if got := out.String(); got != tt.want {
+				t.Errorf("Template.Execute() (want|got):\n%s", debugutil.SideBySide(got, tt.want))
+			}
+		})
+	}
+}
+
+func TestTemplate_ExecuteContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	iterations := 0
+	tmpl, err := Parse(`{{range .}}{{step}}{{end}}`, WithName("loop.go"), WithFuncs(map[string]any{
+		"step": func() string {
+			// The execution stops at the next iteration after cancel.
+			if iterations++; iterations == 3 {
+				cancel()
+			}
+			return ""
+		},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	imports := codegenutil.NewFileImports(codegenutil.AssumedPackageName("abc.xyz/mypkg"))
+	err = tmpl.ExecuteContext(ctx, imports, io.Discard, make([]int, 100))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("ExecuteContext() got error %v, want context.Canceled", err)
+	}
+	if iterations != 3 {
+		t.Errorf("ExecuteContext() ran %d iterations when the context was cancelled during the third, want 3", iterations)
+	}
+
+	iterations = 0
+	if err := tmpl.ExecuteContext(ctx, imports, io.Discard, make([]int, 100)); !errors.Is(err, context.Canceled) || iterations != 0 {
+		t.Errorf("ExecuteContext() with a done context got error %v after %d iterations, want context.Canceled before any", err, iterations)
+	}
+}
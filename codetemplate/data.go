@@ -0,0 +1,118 @@
+package codetemplate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/meta-programming/go-codegenutil"
+	"gopkg.in/yaml.v3"
+)
+
+// ReadData decodes data files for use as the data of a template.
+//
+// The files are decoded according to their extensions, which must be .json,
+// .yaml or .yml. Each must contain a mapping; the top-level keys of the files
+// are merged, with later files taking precedence. A mapping whose only key is
+// "$symbol" is replaced by the *codegenutil.Symbol that its value denotes in
+// the syntax of codegenutil.ParseSymbol, so that templates can refer to
+// symbols of other packages:
+//
+//	{"marshal": {"$symbol": "encoding/json.Marshal"}}
+//
+// A file that can't be decoded is reported by a *DataError.
+func ReadData(names ...string) (map[string]any, error) {
+	data := map[string]any{}
+	for _, name := range names {
+		if err := readData(name, data); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// ErrTemplateData is matched by the errors of data that a template can't use,
+// such as a *DataError or a localName argument that isn't a package.
+var ErrTemplateData = errors.New("invalid template data")
+
+// DataError is returned by ReadData for a data file that can't be decoded. It
+// matches ErrTemplateData.
+type DataError struct {
+	// File is the name of the data file.
+	File string
+	// Key is the top-level key whose value is invalid, if any.
+	Key string
+	// Err is the error decoding the file or the value.
+	Err error
+}
+
+func (e *DataError) Error() string {
+	if e.Key != "" {
+		return fmt.Sprintf("%s: %s: %v", e.File, e.Key, e.Err)
+	}
+	return fmt.Sprintf("%s: %v", e.File, e.Err)
+}
+
+func (e *DataError) Unwrap() error { return e.Err }
+
+// Is reports whether target is ErrTemplateData.
+func (e *DataError) Is(target error) bool { return target == ErrTemplateData }
+
+// readData decodes a data file and adds its top-level keys to data.
+func readData(name string, data map[string]any) error {
+	content, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	var decoded map[string]any
+	switch ext := filepath.Ext(name); ext {
+	case ".json":
+		err = json.Unmarshal(content, &decoded)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &decoded)
+	default:
+		return &DataError{File: name, Err: fmt.Errorf("unsupported data file extension %q, want .json, .yaml or .yml", ext)}
+	}
+	if err != nil {
+		return &DataError{File: name, Err: err}
+	}
+	for key, value := range decoded {
+		if data[key], err = resolveSymbols(value); err != nil {
+			return &DataError{File: name, Key: key, Err: err}
+		}
+	}
+	return nil
+}
+
+// resolveSymbols replaces the {"$symbol": "..."} mappings in a decoded value
+// with symbols.
+func resolveSymbols(value any) (any, error) {
+	switch v := value.(type) {
+	case map[string]any:
+		if s, ok := v["$symbol"]; ok && len(v) == 1 {
+			str, ok := s.(string)
+			if !ok {
+				return nil, fmt.Errorf("$symbol must be a string, got %T", s)
+			}
+			return codegenutil.ParseSymbol(str)
+		}
+		for key, elem := range v {
+			resolved, err := resolveSymbols(elem)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = resolved
+		}
+	case []any:
+		for i, elem := range v {
+			resolved, err := resolveSymbols(elem)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = resolved
+		}
+	}
+	return value, nil
+}
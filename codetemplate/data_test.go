@@ -0,0 +1,49 @@
+package codetemplate
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/meta-programming/go-codegenutil"
+)
+
+func TestReadData_errors(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"data.toml":   `a = 1`,
+		"bad.json":    `{`,
+		"symbol.json": `{"marshal": {"$symbol": 1}}`,
+		"ident.yaml":  `marshal: {$symbol: encoding/json.not-an-identifier}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name    string
+		wantKey string
+		wantErr error
+	}{
+		{name: "data.toml"},
+		{name: "bad.json"},
+		{name: "symbol.json", wantKey: "marshal"},
+		{name: "ident.yaml", wantKey: "marshal", wantErr: codegenutil.ErrInvalidIdentifier},
+	}
+	for _, tt := range tests {
+		_, err := ReadData(filepath.Join(dir, tt.name))
+		var dataErr *DataError
+		if !errors.Is(err, ErrTemplateData) || !errors.As(err, &dataErr) {
+			t.Errorf("ReadData(%s) got error %v, want *DataError", tt.name, err)
+			continue
+		}
+		if dataErr.File != filepath.Join(dir, tt.name) || dataErr.Key != tt.wantKey {
+			t.Errorf("ReadData(%s) got error for file %s, key %q, want key %q", tt.name, dataErr.File, dataErr.Key, tt.wantKey)
+		}
+		if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+			t.Errorf("ReadData(%s) got error %v, want an error matching %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
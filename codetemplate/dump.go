@@ -0,0 +1,115 @@
+package codetemplate
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template/parse"
+)
+
+// Dump returns a readable, indented description of the parse trees of the
+// template and the templates it defines, for debugging. Each node is printed
+// with its location in the template text, and the {{header}}, {{imports}} and
+// {{ctx}} actions are marked as placeholders.
+//
+// Dump returns the empty string if the template's Engine does not implement
+// ParseTreeTemplate.
+func (t *Template) Dump() string {
+	trees := t.ParseTrees()
+	var names []string
+	for name := range trees {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	d := &treeDumper{}
+	for _, name := range names {
+		d.tree = trees[name]
+		d.line(0, nil, "template %q", name)
+		d.node(1, d.tree.Root)
+	}
+	return d.out.String()
+}
+
+// placeholderDescriptions describes the functions that codetemplate adds to
+// every template.
+var placeholderDescriptions = map[string]string{
+	"header":  "package clause and imports",
+	"imports": "imports",
+	"ctx":     "execution context",
+}
+
+type treeDumper struct {
+	tree *parse.Tree
+	out  strings.Builder
+}
+
+func (d *treeDumper) line(depth int, node parse.Node, format string, args ...any) {
+	d.out.WriteString(strings.Repeat("  ", depth))
+	if node != nil {
+		location, _ := d.tree.ErrorContext(node)
+		d.out.WriteString(location + " ")
+	}
+	fmt.Fprintf(&d.out, format, args...)
+	d.out.WriteString("\n")
+}
+
+func (d *treeDumper) node(depth int, node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			d.node(depth, child)
+		}
+	case *parse.TextNode:
+		d.line(depth, n, "Text %s", strconv.Quote(string(n.Text)))
+	case *parse.CommentNode:
+		d.line(depth, n, "Comment %s", n)
+	case *parse.ActionNode:
+		if name, ok := placeholderName(n); ok {
+			d.line(depth, n, "Placeholder %s (%s)", n, placeholderDescriptions[name])
+			return
+		}
+		d.line(depth, n, "Action %s", n)
+	case *parse.IfNode:
+		d.branch(depth, "If", n, n.Pipe, n.List, n.ElseList)
+	case *parse.RangeNode:
+		d.branch(depth, "Range", n, n.Pipe, n.List, n.ElseList)
+	case *parse.WithNode:
+		d.branch(depth, "With", n, n.Pipe, n.List, n.ElseList)
+	case *parse.TemplateNode:
+		d.line(depth, n, "Template %s", n)
+	case *parse.BreakNode:
+		d.line(depth, n, "Break")
+	case *parse.ContinueNode:
+		d.line(depth, n, "Continue")
+	default:
+		d.line(depth, node, "%T %s", node, node)
+	}
+}
+
+func (d *treeDumper) branch(depth int, kind string, node parse.Node, pipe *parse.PipeNode, list, elseList *parse.ListNode) {
+	d.line(depth, node, "%s {{%s %s}}", kind, strings.ToLower(kind), pipe)
+	d.node(depth+1, list)
+	if elseList != nil {
+		d.line(depth, nil, "Else")
+		d.node(depth+1, elseList)
+	}
+}
+
+// placeholderName reports whether the action is a call to one of the functions
+// described in placeholderDescriptions.
+func placeholderName(n *parse.ActionNode) (string, bool) {
+	if len(n.Pipe.Decl) != 0 || len(n.Pipe.Cmds) != 1 || len(n.Pipe.Cmds[0].Args) != 1 {
+		return "", false
+	}
+	ident, ok := n.Pipe.Cmds[0].Args[0].(*parse.IdentifierNode)
+	if !ok {
+		return "", false
+	}
+	_, ok = placeholderDescriptions[ident.Ident]
+	return ident.Ident, ok
+}
@@ -0,0 +1,375 @@
+package codetemplate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+	"text/template/parse"
+
+	"github.com/meta-programming/go-codegenutil/template"
+)
+
+// PrintFunc writes the textual representation of a value printed by a template
+// action to w.
+type PrintFunc func(w io.Writer, value any) (n int, err error)
+
+// Engine parses the text of a template. A Template uses an Engine so that the
+// template language implementation can be swapped out.
+type Engine interface {
+	// Parse parses text as the body of a template with the given name. funcs
+	// contains the functions available to the template in addition to the
+	// engine's builtins.
+	//
+	// Indexing a map with a key that is not present should yield a value that
+	// is false in conditionals, as with "text/template"'s default
+	// "missingkey" option, and printing it should fail with an error that
+	// names the key.
+	Parse(name, text string, funcs map[string]any) (EngineTemplate, error)
+}
+
+// EngineTemplate is a template parsed by an Engine.
+type EngineTemplate interface {
+	// Clone returns a copy of the template that may be executed independently.
+	Clone() (EngineTemplate, error)
+
+	// Execute applies the template to data and writes the output to w.
+	//
+	// Execute must not modify the template, and it must be safe to call
+	// Execute from multiple goroutines at once.
+	Execute(w io.Writer, data any, opts *ExecOptions) error
+}
+
+// ExecOptions holds the parameters of a single execution of an EngineTemplate.
+type ExecOptions struct {
+	// Print must be used to write values printed by actions in the template.
+	Print PrintFunc
+
+	// Funcs replaces the implementations of functions of the same name that
+	// were passed to Engine.Parse for the duration of the execution.
+	Funcs map[string]any
+
+	// Context is the context of Template.ExecuteContext. Engines that can
+	// stop an execution midway should stop it with the context's error once
+	// it is done; the others are only stopped between executions.
+	Context context.Context
+}
+
+// ParseTreeTemplate is implemented by EngineTemplates that are based on the
+// "text/template/parse" package.
+type ParseTreeTemplate interface {
+	// ParseTrees returns the parse tree of the template and of each template
+	// associated with it, keyed by template name.
+	ParseTrees() map[string]*parse.Tree
+}
+
+// WithEngine specifies the Engine used to parse and execute the template. The
+// default is ForkEngine().
+func WithEngine(engine Engine) Option {
+	return Option{func(t *Template) { t.engine = engine }}
+}
+
+// ForkEngine returns the default Engine, which is based on this module's fork
+// of "text/template" and passes every printed value to the PrintFunc.
+func ForkEngine() Engine { return forkEngine{} }
+
+type forkEngine struct{}
+
+func (forkEngine) Parse(name, text string, funcs map[string]any) (EngineTemplate, error) {
+	t, err := template.New(name).Funcs(funcs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	return &forkTemplate{t}, nil
+}
+
+type forkTemplate struct {
+	tt *template.Template
+}
+
+func (ft *forkTemplate) Clone() (EngineTemplate, error) {
+	t, err := ft.tt.Clone()
+	if err != nil {
+		return nil, err
+	}
+	return &forkTemplate{t}, nil
+}
+
+func (ft *forkTemplate) ParseTrees() map[string]*parse.Tree {
+	trees := map[string]*parse.Tree{}
+	for _, t := range ft.tt.Templates() {
+		if t.Tree != nil {
+			trees[t.Name()] = t.Tree
+		}
+	}
+	return trees
+}
+
+func (ft *forkTemplate) Execute(w io.Writer, data any, opts *ExecOptions) error {
+	return ft.tt.ExecuteWith(w, data, &template.ExecOptions{
+		Printer: func(w io.Writer, a any, pc template.PrintContext) (n int, err error) {
+			// The fork returns the errors of writing the output without
+			// the location of the action, so it is added here.
+			n, err = opts.Print(w, a)
+			if err != nil {
+				err = fmt.Errorf("%s: %w", pc.Location(), err)
+			}
+			return n, err
+		},
+		Funcs:   opts.Funcs,
+		Context: opts.Context,
+	})
+}
+
+// TextTemplateEngine returns an Engine based on the standard library's
+// "text/template" package.
+//
+// Because "text/template" always prints values using the fmt package, symbols
+// are not automatically formatted by this engine. Instead, templates must pass
+// such values to the "gocode" function, as in {{gocode .mySymbol}}, which
+// formats its argument the way the default engine would print it.
+//
+// Like the default engine, the engine fails to print a missing value, such as
+// the value of a key that is not in a map, instead of printing "<no value>".
+func TextTemplateEngine() Engine { return textEngine{} }
+
+type textEngine struct{}
+
+// textEngineGoCodeFunc is the name of the function "text/template" templates
+// use to format values.
+const textEngineGoCodeFunc = "gocode"
+
+// textEngineValueFunc is the name of the function that textEngine adds to the
+// printing actions of templates to check that the printed value is present.
+const textEngineValueFunc = "codetemplateValue"
+
+func (textEngine) Parse(name, text string, funcs map[string]any) (EngineTemplate, error) {
+	allFuncs := texttemplate.FuncMap{
+		textEngineGoCodeFunc: func(any) (string, error) {
+			return "", fmt.Errorf("%s called outside of Execute", textEngineGoCodeFunc)
+		},
+		textEngineValueFunc: func(key string, value any) (any, error) {
+			switch {
+			case value != nil:
+				return value, nil
+			case key != "":
+				return nil, fmt.Errorf("no value for key %q", key)
+			default:
+				return nil, errors.New("no value")
+			}
+		},
+	}
+	for name, fn := range funcs {
+		allFuncs[name] = fn
+	}
+	t, err := texttemplate.New(name).Funcs(allFuncs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	// The trees are copied before the values printed by their actions are
+	// checked, so that ParseTrees returns the trees of the text.
+	trees := map[string]*parse.Tree{}
+	for _, t := range t.Templates() {
+		if t.Tree != nil {
+			trees[t.Name()] = t.Tree.Copy()
+			checkPrintedValues(t.Tree.Root)
+		}
+	}
+	return &textTemplate{tt: t, trees: trees, funcs: allFuncs}, nil
+}
+
+// checkPrintedValues makes the actions below node that print a value pass it
+// to the textEngineValueFunc function, which fails if the value is missing.
+// The value passed to a call of textEngineGoCodeFunc is checked instead of
+// its result.
+func checkPrintedValues(node parse.Node) {
+	switch node := node.(type) {
+	case *parse.ListNode:
+		if node != nil {
+			for _, n := range node.Nodes {
+				checkPrintedValues(n)
+			}
+		}
+	case *parse.IfNode:
+		checkPrintedValues(node.List)
+		checkPrintedValues(node.ElseList)
+	case *parse.RangeNode:
+		checkPrintedValues(node.List)
+		checkPrintedValues(node.ElseList)
+	case *parse.WithNode:
+		checkPrintedValues(node.List)
+		checkPrintedValues(node.ElseList)
+	case *parse.ActionNode:
+		if len(node.Pipe.Decl) > 0 {
+			return
+		}
+		last := node.Pipe.Cmds[len(node.Pipe.Cmds)-1]
+		fn, ok := last.Args[0].(*parse.IdentifierNode)
+		if ok && fn.Ident == textEngineGoCodeFunc && len(last.Args) == 2 {
+			arg := last.Args[1]
+			last.Args[1] = &parse.PipeNode{
+				NodeType: parse.NodePipe,
+				Pos:      arg.Position(),
+				Line:     node.Line,
+				Cmds:     []*parse.CommandNode{valueCheck(arg.Position(), arg, arg)},
+			}
+			return
+		}
+		var key parse.Node = last
+		if len(last.Args) == 1 {
+			key = last.Args[0]
+		}
+		node.Pipe.Cmds = append(node.Pipe.Cmds, valueCheck(last.Position(), key))
+	}
+}
+
+// valueCheck returns a command that calls textEngineValueFunc with the name
+// of the key that key looks up, and args.
+func valueCheck(pos parse.Pos, key parse.Node, args ...parse.Node) *parse.CommandNode {
+	name := ""
+	switch key := key.(type) {
+	case *parse.FieldNode:
+		name = key.Ident[len(key.Ident)-1]
+	case *parse.ChainNode:
+		if len(key.Field) > 0 {
+			name = key.Field[len(key.Field)-1]
+		}
+	case *parse.VariableNode:
+		if len(key.Ident) > 1 {
+			name = key.Ident[len(key.Ident)-1]
+		}
+	}
+	fn := parse.NewIdentifier(textEngineValueFunc).SetPos(pos)
+	nameArg := &parse.StringNode{NodeType: parse.NodeString, Pos: pos, Quoted: strconv.Quote(name), Text: name}
+	return &parse.CommandNode{
+		NodeType: parse.NodeCommand,
+		Pos:      pos,
+		Args:     append([]parse.Node{fn, nameArg}, args...),
+	}
+}
+
+type textTemplate struct {
+	tt *texttemplate.Template
+	// trees are the parse trees of the text, which are returned by
+	// ParseTrees. The trees of tt check the printed values.
+	trees map[string]*parse.Tree
+	// funcs are the functions passed to Parse.
+	funcs texttemplate.FuncMap
+	// bound holds *boundTextTemplates, which are reused across executions
+	// so that the template is only cloned when executions overlap.
+	bound sync.Pool
+}
+
+// boundTextTemplate is a copy of a "text/template" template whose functions
+// call the implementations given by the ExecOptions of the current execution.
+// It is used by one execution at a time.
+type boundTextTemplate struct {
+	tt    *texttemplate.Template
+	funcs texttemplate.FuncMap
+	opts  *ExecOptions
+	// funcTypes are the types of the functions that were replaced by
+	// functions that call the implementation in opts.Funcs.
+	funcTypes map[string]reflect.Type
+}
+
+func (tt *textTemplate) Clone() (EngineTemplate, error) {
+	t, err := tt.tt.Clone()
+	if err != nil {
+		return nil, err
+	}
+	return &textTemplate{tt: t, trees: tt.trees, funcs: tt.funcs}, nil
+}
+
+func (tt *textTemplate) ParseTrees() map[string]*parse.Tree {
+	trees := map[string]*parse.Tree{}
+	for name, tree := range tt.trees {
+		trees[name] = tree
+	}
+	return trees
+}
+
+func (tt *textTemplate) Execute(w io.Writer, data any, opts *ExecOptions) error {
+	// "text/template" functions can only be replaced by modifying the
+	// template, so a copy is bound to opts instead. Copies are pooled to
+	// avoid the cost of Clone on every execution.
+	b, err := tt.getBound()
+	if err != nil {
+		return err
+	}
+	defer tt.bound.Put(b)
+	b.bind(opts)
+	defer b.bind(nil)
+	return b.tt.Execute(w, data)
+}
+
+func (tt *textTemplate) getBound() (*boundTextTemplate, error) {
+	if b, ok := tt.bound.Get().(*boundTextTemplate); ok {
+		return b, nil
+	}
+	t, err := tt.tt.Clone()
+	if err != nil {
+		return nil, fmt.Errorf("error with Clone: %w", err)
+	}
+	b := &boundTextTemplate{tt: t, funcs: tt.funcs, funcTypes: map[string]reflect.Type{}}
+	t.Funcs(texttemplate.FuncMap{
+		textEngineGoCodeFunc: func(value any) (string, error) {
+			out := &strings.Builder{}
+			if _, err := b.opts.Print(out, value); err != nil {
+				return "", err
+			}
+			return out.String(), nil
+		},
+	})
+	return b, nil
+}
+
+// bind makes the functions of the template call the implementations of opts
+// until the next call to bind.
+func (b *boundTextTemplate) bind(opts *ExecOptions) {
+	b.opts = opts
+	if opts == nil {
+		return
+	}
+	funcs := texttemplate.FuncMap{}
+	for name, fn := range opts.Funcs {
+		fnType := reflect.TypeOf(fn)
+		if b.funcTypes[name] == fnType {
+			continue
+		}
+		b.funcTypes[name] = fnType
+		funcs[name] = b.forward(name, fnType).Interface()
+	}
+	// Functions that aren't replaced by opts call the functions passed to
+	// Parse, which must be restored if their type differs.
+	for name, fnType := range b.funcTypes {
+		if _, ok := opts.Funcs[name]; !ok && fnType != reflect.TypeOf(b.funcs[name]) {
+			delete(b.funcTypes, name)
+			funcs[name] = b.funcs[name]
+		}
+	}
+	if len(funcs) > 0 {
+		b.tt.Funcs(funcs)
+	}
+}
+
+// forward returns a function of type fnType that calls the function with the
+// given name in the Funcs of the current execution, or else the function of
+// that name passed to Parse.
+func (b *boundTextTemplate) forward(name string, fnType reflect.Type) reflect.Value {
+	return reflect.MakeFunc(fnType, func(args []reflect.Value) []reflect.Value {
+		impl, ok := b.opts.Funcs[name]
+		if !ok {
+			impl = b.funcs[name]
+		}
+		fn := reflect.ValueOf(impl)
+		if fnType.IsVariadic() {
+			return fn.CallSlice(args)
+		}
+		return fn.Call(args)
+	})
+}
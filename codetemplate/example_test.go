@@ -43,9 +43,8 @@ func main() {
 	// package mypkg
 	//
 	// import (
-	// 	"math"
-	//
 	// 	math2 "alternative/math"
+	// 	"math"
 	// )
 	//
 	// var result1 = math.Max(1, 2)
@@ -100,9 +99,8 @@ func main() {
 	// package mypkg
 	//
 	// import (
-	// 	"math"
-	//
 	// 	math2 "alternative/math"
+	// 	"math"
 	// )
 	//
 	// // Log isn't used in the output, so the import declaration is deleted.
@@ -0,0 +1,30 @@
+package codetemplate
+
+import (
+	"fmt"
+
+	cb "github.com/meta-programming/go-codegenutil/codebuilder"
+)
+
+// exprFuncs returns the functions that build expressions whose operands must
+// be parenthesized correctly, which every template has unless it defines
+// functions of the same names. See Parse.
+func exprFuncs() map[string]any {
+	return map[string]any{
+		"convert": func(typ, x any) cb.Expr { return cb.Convert(codeOf(typ), codeOf(x)) },
+		"assert":  func(x, typ any) cb.Expr { return cb.TypeAssert(codeOf(x), codeOf(typ)) },
+	}
+}
+
+// codeOf returns the value of a template function argument as code: values
+// with a GoCode method, such as symbols, are used as they are, and other
+// values are printed with fmt.Sprint.
+func codeOf(v any) cb.Code {
+	switch v := v.(type) {
+	case cb.Code:
+		return v
+	case string:
+		return cb.Raw(v)
+	}
+	return cb.Raw(fmt.Sprint(v))
+}
@@ -0,0 +1,41 @@
+package codetemplate
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+
+	"github.com/meta-programming/go-codegenutil"
+)
+
+// checkTypeParams returns a *codegenutil.GoVersionError if the target Go
+// version of imports predates type parameters and the generated file src
+// declares or instantiates generic functions or types in the text of the
+// template, which symbols and codebuilder values can't report. Files that
+// don't parse aren't checked.
+func checkTypeParams(imports *codegenutil.FileImports, src []byte) error {
+	versionErr := imports.RequireGoVersion("type parameters", "go1.18")
+	if versionErr == nil {
+		return nil
+	}
+	file, err := parser.ParseFile(token.NewFileSet(), "", src, parser.SkipObjectResolution)
+	if err != nil {
+		return nil
+	}
+	generic := false
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.TypeSpec:
+			generic = generic || n.TypeParams != nil
+		case *ast.FuncType:
+			generic = generic || n.TypeParams != nil
+		case *ast.IndexListExpr:
+			generic = true
+		}
+		return !generic
+	})
+	if generic {
+		return versionErr
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package codetemplate
+
+import (
+	"fmt"
+
+	"github.com/meta-programming/go-codegenutil"
+)
+
+// importFuncs returns the functions that give templates access to the imports
+// of the file being generated and format code with them, bound to imports.
+// Parse binds them to nil imports, and Execute replaces them. See Parse.
+func importFuncs(imports *codegenutil.FileImports) map[string]any {
+	return map[string]any{
+		"fileImports": func() *codegenutil.FileImports { return imports },
+		"localName": func(pkg any, alias ...string) (string, error) {
+			if imports == nil {
+				return "", fmt.Errorf("localName called outside of Execute")
+			}
+			if len(alias) > 1 {
+				return "", fmt.Errorf("localName takes at most one alias, got %d", len(alias))
+			}
+			p, err := packageOf(pkg)
+			if err != nil {
+				return "", err
+			}
+			if name, ok := imports.LocalNameFor(p); ok {
+				return name, nil
+			}
+			a := ""
+			if len(alias) == 1 {
+				a = alias[0]
+			}
+			spec, err := imports.TryAdd(p, a)
+			if err != nil {
+				return "", err
+			}
+			return spec.FileLocalPackageName(), nil
+		},
+		"codef": func(format string, args ...any) (string, error) {
+			if imports == nil {
+				return "", fmt.Errorf("codef called outside of Execute")
+			}
+			return codef(imports, format, args...), nil
+		},
+	}
+}
+
+// codef formats args like fmt.Sprintf, but values with a GoCode method, such as
+// symbols and codebuilder.Code, are first replaced by their code, so that
+// they are qualified and imported like printed values are.
+func codef(imports *codegenutil.FileImports, format string, args ...any) string {
+	formatted := make([]any, len(args))
+	for i, arg := range args {
+		if code, ok := arg.(interface {
+			GoCode(*codegenutil.FileImports) string
+		}); ok {
+			arg = code.GoCode(imports)
+		}
+		formatted[i] = arg
+	}
+	return fmt.Sprintf(format, formatted...)
+}
+
+// packageOf returns the package of a localName argument, which is a
+// *codegenutil.Package, a *codegenutil.Symbol, or an import path whose package
+// name is assumed from the path.
+func packageOf(v any) (*codegenutil.Package, error) {
+	switch v := v.(type) {
+	case *codegenutil.Package:
+		return v, nil
+	case *codegenutil.Symbol:
+		return v.Package(), nil
+	case string:
+		return codegenutil.AssumedPackageName(v), nil
+	}
+	return nil, fmt.Errorf("cannot get a package from %v of type %T: %w", v, v, ErrTemplateData)
+}
@@ -0,0 +1,306 @@
+package codetemplate
+
+import (
+	"go/token"
+	"sort"
+	"strings"
+	"text/template/parse"
+
+	"github.com/meta-programming/go-codegenutil"
+	cb "github.com/meta-programming/go-codegenutil/codebuilder"
+)
+
+// DataKind is the kind of a template data value, as inferred from the way the
+// template uses it.
+type DataKind string
+
+// The kinds of data values that DataSchema infers.
+const (
+	// AnyData is a value that the template prints or passes to functions, whose
+	// type can't be inferred.
+	AnyData DataKind = "any"
+	// BoolData is a value that the template only uses as the condition of if
+	// and with actions.
+	BoolData DataKind = "bool"
+	// ListData is a value that the template ranges over, such as a slice.
+	ListData DataKind = "list"
+	// StructData is a value whose fields the template refers to, such as a
+	// struct or a map with string keys.
+	StructData DataKind = "struct"
+)
+
+// DataField is a value of the data of a template: the data itself, one of its
+// fields, or the elements of a list.
+type DataField struct {
+	// Name is the name of the field, empty for the data itself and for the
+	// elements of a list.
+	Name string
+	Kind DataKind
+	// Fields are the fields of a StructData value, sorted by name.
+	Fields []*DataField
+	// Elem is the element of a ListData value.
+	Elem *DataField
+
+	fields map[string]*DataField
+	// cond is set if the value is the condition of an if or with action,
+	// value if it is used any other way, such as printed.
+	cond, value, ranged bool
+}
+
+// DataSchema is the shape of the data that a template expects, as returned by
+// DataSchema.
+type DataSchema struct {
+	// Data is the data passed to Execute.
+	Data *DataField
+	// Funcs are the names of the functions that the template calls, sorted.
+	Funcs []string
+}
+
+// DataSchema infers the shape of the data that the template expects from the
+// fields that it refers to and the way it uses them, so that the users of a
+// template know what to pass to Execute without reading it. Only the template
+// itself and the templates that it invokes are analyzed, and templates that
+// invoke themselves are only followed once; values that are only printed, or
+// only passed to functions, are AnyData.
+//
+// DataSchema returns nil if the template's Engine does not implement
+// ParseTreeTemplate.
+func (t *Template) DataSchema() *DataSchema {
+	trees := t.ParseTrees()
+	root, ok := trees[t.templateName]
+	if !ok {
+		return nil
+	}
+	a := &schemaAnalyzer{trees: trees, funcs: map[string]bool{}, visiting: map[*parse.Tree]bool{}}
+	data := &DataField{}
+	a.template(root, data)
+	s := &DataSchema{Data: data}
+	for name := range a.funcs {
+		s.Funcs = append(s.Funcs, name)
+	}
+	sort.Strings(s.Funcs)
+	data.finish()
+	return s
+}
+
+// Doc returns a description of the schema with one line per value, such as
+// ".Items[].Name any", followed by the functions that the template calls.
+func (s *DataSchema) Doc() string {
+	var out strings.Builder
+	s.Data.doc(&out, "")
+	if len(s.Funcs) > 0 {
+		out.WriteString("functions: " + strings.Join(s.Funcs, ", ") + "\n")
+	}
+	return out.String()
+}
+
+func (f *DataField) doc(out *strings.Builder, path string) {
+	if path == "" && f.Kind != StructData {
+		out.WriteString(". " + string(f.Kind) + "\n")
+	} else if path != "" {
+		out.WriteString(path + " " + string(f.Kind) + "\n")
+	}
+	for _, field := range f.Fields {
+		field.doc(out, path+"."+field.Name)
+	}
+	if f.Elem != nil {
+		f.Elem.doc(out, path+"[]")
+	}
+}
+
+// GoType returns a Go type that the data of the template can have: a struct for
+// StructData values, a map[string]any for those whose field names aren't
+// exported, a slice for ListData values, bool and any.
+func (s *DataSchema) GoType() cb.Code {
+	return s.Data.goType()
+}
+
+func (f *DataField) goType() cb.Code {
+	switch f.Kind {
+	case BoolData:
+		return codegenutil.BuiltinPackage.Symbol("bool")
+	case ListData:
+		return cb.Codef("[]%s", f.Elem.goType())
+	case StructData:
+		var fields []*cb.Field
+		for _, field := range f.Fields {
+			if !token.IsExported(field.Name) {
+				return cb.Codef("map[string]%s", cb.Any())
+			}
+			fields = append(fields, cb.F(field.Name, field.goType()))
+		}
+		return cb.Struct(fields...)
+	}
+	return cb.Any()
+}
+
+// field returns the field of f with the given name, making f a struct.
+func (f *DataField) field(name string) *DataField {
+	if f.fields == nil {
+		f.fields = map[string]*DataField{}
+	}
+	field, ok := f.fields[name]
+	if !ok {
+		field = &DataField{Name: name}
+		f.fields[name] = field
+	}
+	return field
+}
+
+// elem returns the element of f, making f a list.
+func (f *DataField) elem() *DataField {
+	f.ranged = true
+	if f.Elem == nil {
+		f.Elem = &DataField{}
+	}
+	return f.Elem
+}
+
+// finish sets the exported fields of f and its fields from their uses.
+func (f *DataField) finish() {
+	switch {
+	case f.ranged:
+		f.Kind = ListData
+	case len(f.fields) > 0:
+		f.Kind = StructData
+	case f.cond && !f.value:
+		f.Kind = BoolData
+	default:
+		f.Kind = AnyData
+	}
+	for _, field := range f.fields {
+		f.Fields = append(f.Fields, field)
+	}
+	sort.Slice(f.Fields, func(i, j int) bool { return f.Fields[i].Name < f.Fields[j].Name })
+	for _, field := range f.Fields {
+		field.finish()
+	}
+	if f.Elem != nil {
+		f.Elem.finish()
+	}
+}
+
+type schemaAnalyzer struct {
+	trees map[string]*parse.Tree
+	funcs map[string]bool
+	// visiting are the templates being analyzed, which aren't analyzed again
+	// when they invoke themselves.
+	visiting map[*parse.Tree]bool
+}
+
+// schemaVars are the variables in scope, which refer to data values.
+type schemaVars map[string]*DataField
+
+func (v schemaVars) copy() schemaVars {
+	c := make(schemaVars, len(v))
+	for name, f := range v {
+		c[name] = f
+	}
+	return c
+}
+
+func (a *schemaAnalyzer) template(tree *parse.Tree, dot *DataField) {
+	if a.visiting[tree] {
+		return
+	}
+	a.visiting[tree] = true
+	defer delete(a.visiting, tree)
+	a.list(tree.Root, dot, schemaVars{"$": dot})
+}
+
+func (a *schemaAnalyzer) list(list *parse.ListNode, dot *DataField, vars schemaVars) {
+	if list == nil {
+		return
+	}
+	for _, node := range list.Nodes {
+		switch n := node.(type) {
+		case *parse.ActionNode:
+			if _, ok := placeholderName(n); ok {
+				continue
+			}
+			if v := a.pipe(n.Pipe, dot, vars); len(n.Pipe.Decl) == 0 {
+				v.value = true
+			}
+		case *parse.IfNode:
+			a.pipe(n.Pipe, dot, vars).cond = true
+			a.list(n.List, dot, vars.copy())
+			a.list(n.ElseList, dot, vars.copy())
+		case *parse.WithNode:
+			v := a.pipe(n.Pipe, dot, vars)
+			v.cond = true
+			a.list(n.List, v, vars.copy())
+			a.list(n.ElseList, dot, vars.copy())
+		case *parse.RangeNode:
+			inner := vars.copy()
+			elem := a.pipe(n.Pipe, dot, inner).elem()
+			switch len(n.Pipe.Decl) {
+			case 1:
+				inner[n.Pipe.Decl[0].Ident[0]] = elem
+			case 2:
+				inner[n.Pipe.Decl[0].Ident[0]] = &DataField{}
+				inner[n.Pipe.Decl[1].Ident[0]] = elem
+			}
+			a.list(n.List, elem, inner)
+			a.list(n.ElseList, dot, vars.copy())
+		case *parse.TemplateNode:
+			arg := &DataField{}
+			if n.Pipe != nil {
+				arg = a.pipe(n.Pipe, dot, vars)
+			}
+			if tree, ok := a.trees[n.Name]; ok {
+				a.template(tree, arg)
+			}
+		}
+	}
+}
+
+// pipe analyzes a pipeline and returns the value that it evaluates to, which is
+// the data value it refers to if it is a single field or variable, and a value
+// that isn't part of the data otherwise. Variables that the pipeline declares
+// are added to vars; those of a range are set by the caller.
+func (a *schemaAnalyzer) pipe(pipe *parse.PipeNode, dot *DataField, vars schemaVars) *DataField {
+	result := &DataField{}
+	for _, cmd := range pipe.Cmds {
+		if len(cmd.Args) == 1 && len(pipe.Cmds) == 1 {
+			result = a.arg(cmd.Args[0], dot, vars)
+			continue
+		}
+		for _, arg := range cmd.Args {
+			a.arg(arg, dot, vars).value = true
+		}
+	}
+	for _, decl := range pipe.Decl {
+		vars[decl.Ident[0]] = result
+	}
+	return result
+}
+
+// arg analyzes an argument of a command and returns the value it refers to.
+func (a *schemaAnalyzer) arg(node parse.Node, dot *DataField, vars schemaVars) *DataField {
+	switch n := node.(type) {
+	case *parse.DotNode:
+		return dot
+	case *parse.FieldNode:
+		return fieldPath(dot, n.Ident)
+	case *parse.VariableNode:
+		v, ok := vars[n.Ident[0]]
+		if !ok {
+			v = &DataField{}
+		}
+		return fieldPath(v, n.Ident[1:])
+	case *parse.ChainNode:
+		return fieldPath(a.arg(n.Node, dot, vars), n.Field)
+	case *parse.PipeNode:
+		return a.pipe(n, dot, vars.copy())
+	case *parse.IdentifierNode:
+		a.funcs[n.Ident] = true
+	}
+	return &DataField{}
+}
+
+func fieldPath(f *DataField, names []string) *DataField {
+	for _, name := range names {
+		f = f.field(name)
+	}
+	return f
+}
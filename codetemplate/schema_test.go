@@ -0,0 +1,115 @@
+package codetemplate
+
+import (
+	"testing"
+
+	"github.com/meta-programming/go-codegenutil"
+	cb "github.com/meta-programming/go-codegenutil/codebuilder"
+	"github.com/meta-programming/go-codegenutil/debugutil"
+)
+
+func TestTemplate_DataSchema(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		wantDoc  string
+		wantType string
+	}{
+		{
+			name: "fields, lists and conditions",
+			template: `{{header}}
+{{if .Enabled}}const enabled = true{{end}}
+{{range .Items}}var {{.Name}} = {{printf "%q" .Value}}
+{{end}}
+{{with .Config}}const debug = {{.Debug}}{{end}}`,
+			wantDoc: `.Config struct
+.Config.Debug any
+.Enabled bool
+.Items list
+.Items[] struct
+.Items[].Name any
+.Items[].Value any
+functions: printf
+`,
+			wantType: "struct {\n\tConfig struct {\n\t\tDebug any\n\t}\n\tEnabled bool\n\tItems   []struct {\n\t\tName  any\n\t\tValue any\n\t}\n}",
+		},
+		{
+			name: "variables and templates",
+			template: `{{header}}
+{{define "decl"}}var {{.name}} = {{$.prefix}}{{.value}}{{end}}
+{{range $i, $d := .decls}}{{template "decl" $d}}
+{{end}}{{$t := .types}}{{range $t}}type {{.}} int
+{{end}}`,
+			wantDoc: `.decls list
+.decls[] struct
+.decls[].name any
+.decls[].prefix any
+.decls[].value any
+.types list
+.types[] any
+`,
+			wantType: "map[string]any",
+		},
+		{
+			name:     "printed data",
+			template: "{{header}}\nvar x = {{.}}",
+			wantDoc:  ". any\n",
+			wantType: "any",
+		},
+		{
+			name: "recursive template",
+			template: `{{header}}
+{{define "node"}}// {{.Name}}
+{{range .Children}}{{template "node" .}}{{end}}{{end}}
+{{template "node" .Root}}`,
+			wantDoc: `.Root struct
+.Root.Children list
+.Root.Children[] any
+.Root.Name any
+`,
+			wantType: "struct {\n\tRoot struct {\n\t\tChildren []any\n\t\tName     any\n\t}\n}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := Parse(tt.template)
+			if err != nil {
+				t.Fatalf("Parse got error %v", err)
+			}
+			schema := tmpl.DataSchema()
+			if got := schema.Doc(); got != tt.wantDoc {
+				t.Errorf("Doc() (want|got):\n%s", debugutil.SideBySide(got, tt.wantDoc))
+			}
+			imports := codegenutil.NewFileImports(codegenutil.AssumedPackageName("abc.xyz/mypkg"))
+			if got := schema.GoType().GoCode(imports); got != tt.wantType {
+				t.Errorf("GoType() (want|got):\n%s", debugutil.SideBySide(got, tt.wantType))
+			}
+		})
+	}
+}
+
+func TestTemplate_DataSchema_render(t *testing.T) {
+	tmpl, err := Parse(`{{header}}
+{{range .Consts}}const {{.Name}} = {{.Value}}
+{{end}}`)
+	if err != nil {
+		t.Fatalf("Parse got error %v", err)
+	}
+	imports := codegenutil.NewFileImports(codegenutil.AssumedPackageName("abc.xyz/mypkg"))
+	got, err := cb.NewFile(imports.Package()).Add(cb.TypeDecl("Data", tmpl.DataSchema().GoType())).Render(imports)
+	if err != nil {
+		t.Fatalf("Render() error: %v", err)
+	}
+	want := `package mypkg
+
+type Data struct {
+	Consts []struct {
+		Name  any
+		Value any
+	}
+}
+`
+	if string(got) != want {
+		t.Errorf("Render() (want|got):\n%s", debugutil.SideBySide(string(got), want))
+	}
+}
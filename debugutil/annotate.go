@@ -0,0 +1,128 @@
+package debugutil
+
+import (
+	"errors"
+	"fmt"
+	"go/scanner"
+	"go/token"
+	"go/types"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// annotationContext is the number of lines printed before the line of an
+// error by AnnotateError.
+const annotationContext = 2
+
+// AnnotateError returns a description of err with the lines of src around
+// each position mentioned by err, and a caret under the column of the
+// position.
+//
+// The positions are taken from go/scanner errors (returned by go/parser and
+// go/format), go/types errors and from error messages of the form
+// "file:line:column: message". If err has no position, AnnotateError returns
+// err.Error().
+func AnnotateError(src string, err error) string {
+	if err == nil {
+		return ""
+	}
+	diags := diagnostics(err)
+	if len(diags) == 0 {
+		return err.Error()
+	}
+	return annotate(src, diags)
+}
+
+// annotate returns the diagnostics with the lines of src around each of them.
+func annotate(src string, diags []Diagnostic) string {
+	lines := strings.Split(src, "\n")
+	var blocks []string
+	for _, d := range diags {
+		block := []string{d.String()}
+		if d.Pos.Line >= 1 && d.Pos.Line <= len(lines) {
+			first := d.Pos.Line - annotationContext
+			if first < 1 {
+				first = 1
+			}
+			excerpt := strings.Join(lines[first-1:d.Pos.Line], "\n")
+			block = append(block, WithLineNumbers(excerpt, WithStartLine(first)))
+			if d.Pos.Column >= 1 {
+				numberWidth := len(strconv.Itoa(d.Pos.Line))
+				block = append(block, strings.Repeat(" ", numberWidth+2)+caret(lines[d.Pos.Line-1], d.Pos.Column))
+			}
+		}
+		blocks = append(blocks, strings.Join(block, "\n"))
+	}
+	return strings.Join(blocks, "\n\n")
+}
+
+// Diagnostic is an error at a position of a Go source file.
+type Diagnostic struct {
+	// Pos is the position of the error. Its filename may be empty.
+	Pos token.Position
+	// Msg is the error message, without the position.
+	Msg string
+	// Soft reports whether the error is a go/types error that doesn't
+	// prevent the rest of the package from being type-checked, such as an
+	// unused variable or import.
+	Soft bool
+}
+
+// String returns the diagnostic in the "line:column: message" format of
+// compilers, or only the message if the position is unknown.
+func (d Diagnostic) String() string {
+	if d.Pos.Line == 0 {
+		return d.Msg
+	}
+	return fmt.Sprintf("%d:%d: %s", d.Pos.Line, d.Pos.Column, d.Msg)
+}
+
+// errorPrefix matches the position at the start of an error message.
+var errorPrefix = regexp.MustCompile(`^(?:[^\s:]*:)?(\d+):(\d+): `)
+
+// diagnostics returns the diagnostics of the positions mentioned by err.
+func diagnostics(err error) []Diagnostic {
+	var list scanner.ErrorList
+	if errors.As(err, &list) {
+		var out []Diagnostic
+		for _, e := range list {
+			out = append(out, Diagnostic{Pos: e.Pos, Msg: e.Msg})
+		}
+		return out
+	}
+	var scanErr *scanner.Error
+	if errors.As(err, &scanErr) {
+		return []Diagnostic{{Pos: scanErr.Pos, Msg: scanErr.Msg}}
+	}
+	var typeErr types.Error
+	if errors.As(err, &typeErr) && typeErr.Fset != nil {
+		return []Diagnostic{typeErrorDiagnostic(typeErr)}
+	}
+	msg := err.Error()
+	if m := errorPrefix.FindStringSubmatch(msg); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		column, _ := strconv.Atoi(m[2])
+		return []Diagnostic{{Pos: token.Position{Line: line, Column: column}, Msg: msg[len(m[0]):]}}
+	}
+	return nil
+}
+
+// caret returns a line with a caret under the given 1-based byte column of
+// line. Tabs before the column are kept so that the caret lines up however
+// tabs are displayed.
+func caret(line string, column int) string {
+	var b strings.Builder
+	for i, r := range line {
+		if i >= column-1 {
+			break
+		}
+		if r == '\t' {
+			b.WriteRune('\t')
+		} else {
+			b.WriteRune(' ')
+		}
+	}
+	b.WriteRune('^')
+	return b.String()
+}
@@ -0,0 +1,105 @@
+package debugutil
+
+import (
+	"errors"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"testing"
+)
+
+func TestAnnotateError(t *testing.T) {
+	parseErr := func(src string) error {
+		_, err := parser.ParseFile(token.NewFileSet(), "gen.go", src, 0)
+		if err == nil {
+			t.Fatalf("ParseFile(%q) succeeded, want an error", src)
+		}
+		return err
+	}
+	typeErr := func(src string) error {
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, "gen.go", src, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conf := types.Config{Importer: importer.Default()}
+		_, err = conf.Check("p", fset, []*ast.File{f}, nil)
+		if err == nil {
+			t.Fatalf("Check(%q) succeeded, want an error", src)
+		}
+		return err
+	}
+
+	tests := []struct {
+		name string
+		src  string
+		err  func(src string) error
+		want string
+	}{
+		{
+			name: "parse error",
+			src:  "package p\n\nfunc f() {\n\tx := )\n}\n",
+			err:  parseErr,
+			want: "4:7: expected operand, found ')'\n" +
+				"2: \n" +
+				"3: func f() {\n" +
+				"4: \tx := )\n" +
+				"   \t     ^\n" +
+				"\n" +
+				"5:3: expected ';', found 'EOF'\n" +
+				"3: func f() {\n" +
+				"4: \tx := )\n" +
+				"5: }\n" +
+				"    ^",
+		},
+		{
+			name: "several parse errors",
+			src:  "package p\nvar = 1\nvar = 2\n",
+			err:  parseErr,
+			want: "2:5: expected 'IDENT', found '='\n" +
+				"1: package p\n" +
+				"2: var = 1\n" +
+				"       ^\n" +
+				"\n" +
+				"3:5: expected 'IDENT', found '='\n" +
+				"1: package p\n" +
+				"2: var = 1\n" +
+				"3: var = 2\n" +
+				"       ^",
+		},
+		{
+			name: "type error",
+			src:  "package p\n\nvar x = y\n",
+			err:  typeErr,
+			want: "3:9: undefined: y\n" +
+				"1: package p\n" +
+				"2: \n" +
+				"3: var x = y\n" +
+				"           ^",
+		},
+		{
+			name: "position in message",
+			src:  "a\nb\n",
+			err:  func(string) error { return errors.New("gen.go:2:1: bad b") },
+			want: "2:1: bad b\n" +
+				"1: a\n" +
+				"2: b\n" +
+				"   ^",
+		},
+		{
+			name: "no position",
+			src:  "a\n",
+			err:  func(string) error { return errors.New("something failed") },
+			want: "something failed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AnnotateError(tt.src, tt.err(tt.src)); got != tt.want {
+				t.Errorf("AnnotateError() = \n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
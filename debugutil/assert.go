@@ -0,0 +1,46 @@
+package debugutil
+
+import (
+	"go/format"
+	"testing"
+)
+
+// AssertGoCodeEqual reports a test error if the Go source files want and got
+// aren't equivalent as defined by GoEquivalent. The error shows the
+// structural diff of GoDiff followed by want and got side by side, or the
+// annotated syntax errors if either doesn't parse.
+//
+// The options customize the diffs; see WithColor and SideBySide.
+func AssertGoCodeEqual(t testing.TB, want, got string, opts ...Option) {
+	t.Helper()
+	for _, src := range []struct{ name, code string }{{"want", want}, {"got", got}} {
+		if _, err := goOutline(src.code); err != nil {
+			t.Errorf("%s is not valid Go:\n%s", src.name, AnnotateError(src.code, err))
+			return
+		}
+	}
+	diff, err := goDiff("want", "got", want, got, opts)
+	if err != nil {
+		t.Fatalf("error comparing Go code: %v", err)
+	}
+	if diff != "" {
+		t.Errorf("Go code differs from want (-want +got):\n%s\nwant | got:\n%s", diff, SideBySide(want, got, opts...))
+	}
+}
+
+// AssertFormatted reports a test error if the Go source file src isn't
+// formatted like gofmt would, with a diff of the changes gofmt would make, or
+// with the annotated syntax errors if src doesn't parse.
+//
+// The options customize the diff; see WithColor.
+func AssertFormatted(t testing.TB, src string, opts ...Option) {
+	t.Helper()
+	formatted, err := format.Source([]byte(src))
+	if err != nil {
+		t.Errorf("source is not valid Go:\n%s", AnnotateError(src, err))
+		return
+	}
+	if diff := UnifiedDiff("got", "gofmt", src, string(formatted), opts...); diff != "" {
+		t.Errorf("source is not formatted (-got +gofmt):\n%s", diff)
+	}
+}
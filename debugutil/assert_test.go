@@ -0,0 +1,94 @@
+package debugutil
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAssertGoCodeEqual(t *testing.T) {
+	tests := []struct {
+		name      string
+		want, got string
+		// wantErrors are substrings of the reported error, if any.
+		wantErrors []string
+	}{
+		{
+			name: "equivalent",
+			want: "package p\n\nimport (\n\t\"os\"\n\t\"fmt\"\n)\n\nvar x = fmt.Sprint(os.Args)\n",
+			got:  "package p\nimport (\"fmt\"; \"os\")\nvar x = fmt.Sprint(  os.Args  )",
+		},
+		{
+			name: "different",
+			want: "package p\n\nvar x = 1\n",
+			got:  "package p\n\nvar x = 2\n",
+			wantErrors: []string{
+				"--- want\n+++ got\n",
+				"-var x = 1\n+var x = 2\n",
+				"3: var x = 1Δ var x = 2",
+			},
+		},
+		{
+			name:       "invalid",
+			want:       "package p\n",
+			got:        "package p\n\nvar x = )\n",
+			wantErrors: []string{"got is not valid Go:\n3:9: expected operand, found ')'\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tb := &recordingTB{}
+			AssertGoCodeEqual(tb, tt.want, tt.got)
+			checkRecordedError(t, tb, tt.wantErrors)
+		})
+	}
+}
+
+func TestAssertFormatted(t *testing.T) {
+	tests := []struct {
+		name       string
+		src        string
+		wantErrors []string
+	}{
+		{
+			name: "formatted",
+			src:  "package p\n\nvar x = 1\n",
+		},
+		{
+			name:       "not formatted",
+			src:        "package p\n\nvar x  =  1\n",
+			wantErrors: []string{"--- got\n+++ gofmt\n@@ -1,3 +1,3 @@\n package p\n \n-var x  =  1\n+var x = 1\n"},
+		},
+		{
+			name:       "invalid",
+			src:        "package p\n\nvar x = )\n",
+			wantErrors: []string{"source is not valid Go:\n3:9: expected operand, found ')'\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tb := &recordingTB{}
+			AssertFormatted(tb, tt.src)
+			checkRecordedError(t, tb, tt.wantErrors)
+		})
+	}
+}
+
+// checkRecordedError checks that tb recorded no error if wantErrors is empty,
+// or a single error that contains each of wantErrors otherwise.
+func checkRecordedError(t *testing.T, tb *recordingTB, wantErrors []string) {
+	t.Helper()
+	if len(wantErrors) == 0 {
+		if len(tb.errors) != 0 {
+			t.Errorf("got errors %q, want none", tb.errors)
+		}
+		return
+	}
+	if len(tb.errors) != 1 {
+		t.Fatalf("got errors %q, want one", tb.errors)
+	}
+	for _, want := range wantErrors {
+		if !strings.Contains(tb.errors[0], want) {
+			t.Errorf("got error\n%s\nwant it to contain\n%s", tb.errors[0], want)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package debugutil
+
+import (
+	"os"
+	"strings"
+)
+
+// ColorMode determines whether output is colored with ANSI escape sequences.
+type ColorMode int
+
+const (
+	// ColorNever disables colors. It is the default.
+	ColorNever ColorMode = iota
+	// ColorAlways enables colors.
+	ColorAlways
+	// ColorAuto enables colors if the standard output is a terminal, unless
+	// the NO_COLOR environment variable is set to a non-empty value or TERM
+	// is "dumb".
+	ColorAuto
+)
+
+// WithColor returns an option that colors diffs: removed lines are red, added
+// lines are green and the markers of changed lines are yellow.
+func WithColor(mode ColorMode) Option {
+	return Option{func(c *config) { c.color = colorEnabled(mode) }}
+}
+
+// stdoutIsTerminal reports whether the standard output is a terminal. It is a
+// variable so that tests can replace it.
+var stdoutIsTerminal = func() bool {
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func colorEnabled(mode ColorMode) bool {
+	switch mode {
+	case ColorAlways:
+		return true
+	case ColorAuto:
+		return os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb" && stdoutIsTerminal()
+	}
+	return false
+}
+
+// ANSI escape sequences.
+const (
+	ansiReset  = "\x1b[0m"
+	ansiBold   = "\x1b[1m"
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+	ansiCyan   = "\x1b[36m"
+)
+
+// paint wraps s in an escape sequence if colors are enabled and s isn't empty.
+func (c *config) paint(color, s string) string {
+	if !c.color || s == "" {
+		return s
+	}
+	return color + s + ansiReset
+}
+
+// paintHunk colors the lines of a hunk of a unified diff.
+func (c *config) paintHunk(hunk string) string {
+	if !c.color {
+		return hunk
+	}
+	lines := strings.SplitAfter(hunk, "\n")
+	for i, line := range lines {
+		text := strings.TrimSuffix(line, "\n")
+		newline := line[len(text):]
+		switch {
+		case i == 0:
+			text = c.paint(ansiCyan, text)
+		case strings.HasPrefix(text, "-"):
+			text = c.paint(ansiRed, text)
+		case strings.HasPrefix(text, "+"):
+			text = c.paint(ansiGreen, text)
+		}
+		lines[i] = text + newline
+	}
+	return strings.Join(lines, "")
+}
@@ -0,0 +1,62 @@
+package debugutil
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWithColor_UnifiedDiff(t *testing.T) {
+	got := UnifiedDiff("a", "b", "x\n-- y\n", "x\nz\n", WithColor(ColorAlways))
+	want := "\x1b[1m--- a\x1b[0m\n" +
+		"\x1b[1m+++ b\x1b[0m\n" +
+		"\x1b[36m@@ -1,2 +1,2 @@\x1b[0m\n" +
+		" x\n" +
+		"\x1b[31m--- y\x1b[0m\n" +
+		"\x1b[32m+z\x1b[0m\n"
+	if got != want {
+		t.Errorf("UnifiedDiff() = %q, want %q", got, want)
+	}
+	if got := UnifiedDiff("a", "b", "x\n", "y\n", WithColor(ColorNever)); strings.Contains(got, "\x1b") {
+		t.Errorf("UnifiedDiff() with ColorNever = %q, want no escape sequences", got)
+	}
+}
+
+func TestWithColor_SideBySide(t *testing.T) {
+	got := SideBySide("same\nold\ngone", "same\nnew", WithColor(ColorAlways))
+	want := "1: same| same\n" +
+		"2: \x1b[31mold\x1b[0m \x1b[33mΔ\x1b[0m \x1b[32mnew\x1b[0m\n" +
+		"3: \x1b[31mgone\x1b[0m\x1b[33mΔ\x1b[0m "
+	if got != want {
+		t.Errorf("SideBySide() = %q, want %q", got, want)
+	}
+	plain := SideBySide("same\nold\ngone", "same\nnew")
+	if want := "1: same| same\n2: old Δ new\n3: goneΔ "; plain != want {
+		t.Errorf("SideBySide() without colors = %q, want %q", plain, want)
+	}
+}
+
+func TestColorAuto(t *testing.T) {
+	defer func(orig func() bool) { stdoutIsTerminal = orig }(stdoutIsTerminal)
+	tests := []struct {
+		name     string
+		terminal bool
+		noColor  string
+		term     string
+		want     bool
+	}{
+		{name: "terminal", terminal: true, term: "xterm", want: true},
+		{name: "not a terminal", terminal: false, term: "xterm", want: false},
+		{name: "NO_COLOR", terminal: true, noColor: "1", term: "xterm", want: false},
+		{name: "dumb terminal", terminal: true, term: "dumb", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("NO_COLOR", tt.noColor)
+			t.Setenv("TERM", tt.term)
+			stdoutIsTerminal = func() bool { return tt.terminal }
+			if got := colorEnabled(ColorAuto); got != tt.want {
+				t.Errorf("colorEnabled(ColorAuto) = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -9,69 +9,93 @@ import (
 )
 
 // WithLineNumbers returns a version of the string with line numbers printed on
-// the left hand side of each line.
-func WithLineNumbers(str string) string {
+// the left hand side of each line. Lines are numbered from 1 unless
+// WithStartLine is given, and lines are marked in a gutter with
+// WithHighlight.
+func WithLineNumbers(str string, opts ...Option) string {
+	c := newConfig(opts)
 	lines := strings.Split(str, "\n")
 
-	widthNeeded := len(strconv.Itoa(len(lines)))
+	widthNeeded := len(strconv.Itoa(c.startLine + len(lines) - 1))
 	format := "%" + strconv.Itoa(widthNeeded) + "d: %s"
 	for i, line := range lines {
-		lines[i] = fmt.Sprintf(format, i+1, line)
+		n := c.startLine + i
+		lines[i] = c.gutter(n) + fmt.Sprintf(format, n, line)
 	}
 	return strings.Join(lines, "\n")
 }
 
 // SideBySide returns a string with a and b printed side by side, separated by
 // pipe character (for equal lines) or a delta character (for different lines).
-func SideBySide(a, b string) string {
+// With WithColor, lines that only exist in a are red, lines that only exist in
+// b are green, and the sides of lines that differ are red and green. The layout
+// can be changed with WithMaxWidth, WithSeparators and WithContext.
+func SideBySide(a, b string, opts ...Option) string {
+	c := newConfig(opts)
 	linesA := strings.Split(replaceTabs(a), "\n")
 	linesB := strings.Split(replaceTabs(b), "\n")
-	lhsWidth := maxWidth(linesA)
 
-	lineOrBlank := func(lines []string, i int) (string, bool) {
-		if i < len(lines) {
-			return lines[i], true
+	var rows []*sideBySideRow
+	for i := 0; i < len(linesA) || i < len(linesB); i++ {
+		row := &sideBySideRow{}
+		if i < len(linesA) {
+			row.a, row.existsA = linesA[i], true
 		}
-		return "", false
+		if i < len(linesB) {
+			row.b, row.existsB = linesB[i], true
+		}
+		rows = append(rows, row)
 	}
+	numberWidth := len(strconv.Itoa(len(rows)))
+	rows = c.hideEqualRuns(rows)
 
-	var outLines []string
-	for i := 0; ; i++ {
-		if i >= len(linesA) && i >= len(linesB) {
-			break
+	lhsWidth := 0
+	for _, row := range rows {
+		for _, cell := range c.cells(row.a) {
+			if w := width(cell); w > lhsWidth {
+				lhsWidth = w
+			}
 		}
+	}
 
-		lineA, existsA := lineOrBlank(linesA, i)
-		lineB, existsB := lineOrBlank(linesB, i)
-		sep := "|"
-		if lineA != lineB || existsA != existsB {
-			sep = "Δ"
+	var outLines []string
+	lineNumber := 0
+	for _, row := range rows {
+		if row.hidden > 0 {
+			lineNumber += row.hidden
+			outLines = append(outLines, fmt.Sprintf("%*s  %s", numberWidth, "", hiddenLinesMessage(row.hidden)))
+			continue
+		}
+		lineNumber++
+		cellsA, cellsB := c.cells(row.a), c.cells(row.b)
+		for i := 0; i < len(cellsA) || i < len(cellsB); i++ {
+			var cellA, cellB string
+			if i < len(cellsA) {
+				cellA = cellsA[i]
+			}
+			if i < len(cellsB) {
+				cellB = cellsB[i]
+			}
+			left, sep, right := pad(cellA, lhsWidth), c.equalSep, cellB
+			if row.differs() {
+				sep = c.paint(ansiYellow, c.differentSep)
+				if row.existsA {
+					left = c.paint(ansiRed, cellA) + pad("", lhsWidth-width(cellA))
+				}
+				if row.existsB {
+					right = c.paint(ansiGreen, cellB)
+				}
+			}
+			number := fmt.Sprintf("%*d:", numberWidth, lineNumber)
+			if i > 0 {
+				number = strings.Repeat(" ", numberWidth+1)
+			}
+			outLines = append(outLines, fmt.Sprintf("%s %s%s %s", number, left, sep, right))
 		}
-		outLines = append(outLines, fmt.Sprintf("%s%s %s", pad(lineA, lhsWidth), sep, lineB))
 	}
-	return WithLineNumbers(strings.Join(outLines, "\n"))
+	return strings.Join(outLines, "\n")
 }
 
 func replaceTabs(str string) string {
 	return strings.ReplaceAll(str, "\t", "  ")
 }
-
-func maxWidth(lines []string) int {
-	max := 0
-	for _, line := range lines {
-		l := len(line)
-		if l > max {
-			max = l
-		}
-	}
-	return max
-}
-
-func pad(line string, width int) string {
-	out := line
-	padSize := width - len(line)
-	for i := 0; i < padSize; i++ {
-		out += " "
-	}
-	return out
-}
@@ -0,0 +1,213 @@
+package debugutil
+
+import (
+	"fmt"
+	"strings"
+)
+
+// UnifiedDiff returns the differences between a and b in the unified format of
+// "diff -u", with three lines of context around each change, or the empty
+// string if they are equal. The names label the two inputs in the header.
+// Colors are enabled with WithColor, and lines are ignored with IgnoreLines.
+func UnifiedDiff(aName, bName, a, b string, opts ...Option) string {
+	c := newConfig(opts)
+	hunks := c.diffHunks(a, b)
+	if len(hunks) == 0 {
+		return ""
+	}
+	var out strings.Builder
+	fmt.Fprintf(&out, "%s\n%s\n", c.paint(ansiBold, "--- "+aName), c.paint(ansiBold, "+++ "+bName))
+	for _, h := range hunks {
+		out.WriteString(c.paintHunk(h))
+	}
+	return out.String()
+}
+
+// DiffHunks returns the hunks of the unified diff of a and b, each starting
+// with its "@@" line and ending with a newline, or nil if a and b are equal.
+// It is useful for reports that present the changes to a file separately.
+// Lines are ignored with IgnoreLines; other options have no effect.
+func DiffHunks(a, b string, opts ...Option) []string {
+	return newConfig(opts).diffHunks(a, b)
+}
+
+func (c *config) diffHunks(a, b string) []string {
+	if a == b {
+		return nil
+	}
+	return hunks(c.diffLines(splitLines(a), splitLines(b)), 3)
+}
+
+// splitLines splits s into lines that keep their line terminators, so that a
+// missing newline at the end of the input is reported.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// editKind is the kind of an edit of a line diff.
+type editKind byte
+
+const (
+	editEqual  editKind = ' '
+	editDelete editKind = '-'
+	editInsert editKind = '+'
+)
+
+type edit struct {
+	kind editKind
+	line string
+}
+
+// diffLines returns a shortest edit script that turns a into b, computed from
+// the longest common subsequence of their lines. Lines are compared by their
+// keys; see lineKey.
+func (c *config) diffLines(a, b []string) []edit {
+	keysA, keysB := make([]string, len(a)), make([]string, len(b))
+	for i, line := range a {
+		keysA[i] = c.lineKey(line)
+	}
+	for i, line := range b {
+		keysB[i] = c.lineKey(line)
+	}
+
+	// Trim the common prefix and suffix, which are usually most of the input,
+	// to keep the table small.
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && keysA[prefix] == keysB[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && keysA[len(a)-1-suffix] == keysB[len(b)-1-suffix] {
+		suffix++
+	}
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	midKeysA, midKeysB := keysA[prefix:len(a)-suffix], keysB[prefix:len(b)-suffix]
+
+	// lcs[i][j] is the length of the longest common subsequence of midA[i:]
+	// and midB[j:].
+	lcs := make([][]int, len(midA)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(midB)+1)
+	}
+	for i := len(midA) - 1; i >= 0; i-- {
+		for j := len(midB) - 1; j >= 0; j-- {
+			if midKeysA[i] == midKeysB[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var edits []edit
+	for _, line := range a[:prefix] {
+		edits = append(edits, edit{editEqual, line})
+	}
+	i, j := 0, 0
+	for i < len(midA) || j < len(midB) {
+		switch {
+		case i < len(midA) && j < len(midB) && midKeysA[i] == midKeysB[j]:
+			edits = append(edits, edit{editEqual, midA[i]})
+			i++
+			j++
+		case j == len(midB) || (i < len(midA) && lcs[i+1][j] >= lcs[i][j+1]):
+			edits = append(edits, edit{editDelete, midA[i]})
+			i++
+		default:
+			edits = append(edits, edit{editInsert, midB[j]})
+			j++
+		}
+	}
+	for _, line := range a[len(a)-suffix:] {
+		edits = append(edits, edit{editEqual, line})
+	}
+	return edits
+}
+
+// hunks formats the changes of an edit script as unified diff hunks with the
+// given number of context lines.
+func hunks(edits []edit, context int) []string {
+	var out []string
+	for start := 0; start < len(edits); {
+		// Find the next change.
+		for start < len(edits) && edits[start].kind == editEqual {
+			start++
+		}
+		if start == len(edits) {
+			break
+		}
+		// Extend the hunk until a run of more than 2*context equal lines.
+		end := start
+		for end < len(edits) {
+			if edits[end].kind != editEqual {
+				end++
+				continue
+			}
+			run := end
+			for run < len(edits) && edits[run].kind == editEqual {
+				run++
+			}
+			if run == len(edits) || run-end > 2*context {
+				break
+			}
+			end = run
+		}
+		first := start - context
+		if first < 0 {
+			first = 0
+		}
+		last := end + context
+		if last > len(edits) {
+			last = len(edits)
+		}
+
+		// Count the lines of each side before and in the hunk.
+		lineA, lineB := 1, 1
+		for _, e := range edits[:first] {
+			if e.kind != editInsert {
+				lineA++
+			}
+			if e.kind != editDelete {
+				lineB++
+			}
+		}
+		var body strings.Builder
+		countA, countB := 0, 0
+		for _, e := range edits[first:last] {
+			if e.kind != editInsert {
+				countA++
+			}
+			if e.kind != editDelete {
+				countB++
+			}
+			body.WriteByte(byte(e.kind))
+			body.WriteString(e.line)
+			if !strings.HasSuffix(e.line, "\n") {
+				body.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		out = append(out, fmt.Sprintf("@@ -%s +%s @@\n%s", hunkRange(lineA, countA), hunkRange(lineB, countB), body.String()))
+		start = last
+	}
+	return out
+}
+
+// hunkRange formats the line range of one side of a hunk.
+func hunkRange(start, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start-1)
+	case 1:
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
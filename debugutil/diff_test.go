@@ -0,0 +1,101 @@
+package debugutil
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	lines := func(n int, change map[int]string) string {
+		var out []string
+		for i := 1; i <= n; i++ {
+			if line, ok := change[i]; ok {
+				if line != "" {
+					out = append(out, line)
+				}
+				continue
+			}
+			out = append(out, "line "+string(rune('a'+i-1)))
+		}
+		return strings.Join(out, "\n") + "\n"
+	}
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{name: "equal", a: "x\n", b: "x\n", want: ""},
+		{
+			name: "two hunks",
+			a:    lines(20, nil),
+			b:    lines(20, map[int]string{2: "changed", 18: ""}),
+			want: `--- a
++++ b
+@@ -1,5 +1,5 @@
+ line a
+-line b
++changed
+ line c
+ line d
+ line e
+@@ -15,6 +15,5 @@
+ line o
+ line p
+ line q
+-line r
+ line s
+ line t
+`,
+		},
+		{
+			name: "insert into empty",
+			a:    "",
+			b:    "x",
+			want: "--- a\n+++ b\n@@ -0,0 +1 @@\n+x\n\\ No newline at end of file\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := UnifiedDiff("a", "b", tt.a, tt.b); got != tt.want {
+				t.Errorf("UnifiedDiff() got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffHunks(t *testing.T) {
+	if got := DiffHunks("x\n", "x\n"); got != nil {
+		t.Errorf("DiffHunks() of equal inputs = %q, want nil", got)
+	}
+	got := DiffHunks("a\nb\nc\nd\ne\nf\ng\nh\ni\n", "A\nb\nc\nd\ne\nf\ng\nh\nI\n")
+	want := []string{"@@ -1,4 +1,4 @@\n-a\n+A\n b\n c\n d\n", "@@ -6,4 +6,4 @@\n f\n g\n h\n-i\n+I\n"}
+	if strings.Join(got, "") != strings.Join(want, "") || len(got) != len(want) {
+		t.Errorf("DiffHunks() = %q, want %q", got, want)
+	}
+}
+
+// TestUnifiedDiff_patch checks that diffs apply with patch(1), if it is
+// installed.
+func TestUnifiedDiff_patch(t *testing.T) {
+	if _, err := exec.LookPath("patch"); err != nil {
+		t.Skip("patch is not installed")
+	}
+	a := "package p\n\nimport \"fmt\"\n\nfunc f() {\n\tfmt.Println()\n}\n"
+	b := "package p\n\nfunc f() {\n\tprintln()\n}\n\nfunc g() {}\n"
+	dir := t.TempDir()
+	file := filepath.Join(dir, "f.go")
+	if err := os.WriteFile(file, []byte(a), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command("patch", file)
+	cmd.Stdin = strings.NewReader(UnifiedDiff("a/f.go", "b/f.go", a, b))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("patch failed: %v\n%s", err, out)
+	}
+	if got, _ := os.ReadFile(file); string(got) != b {
+		t.Errorf("patched file:\n%s\nwant:\n%s", got, b)
+	}
+}
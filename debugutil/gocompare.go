@@ -0,0 +1,255 @@
+package debugutil
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// GoEquivalent reports whether a and b are the same Go source file except for
+// their formatting, the order of their imports and the whitespace in their
+// comments. It returns an error if either doesn't parse.
+func GoEquivalent(a, b string) (bool, error) {
+	diff, err := GoDiff(a, b)
+	return diff == "", err
+}
+
+// GoDiff returns a diff of the structure of the Go source files a and b, or
+// the empty string if they are equivalent as defined by GoEquivalent.
+//
+// Rather than the lines of the sources, the diff compares their canonical
+// outlines: the sorted imports, followed by one line per statement or
+// declaration, with tokens separated by single spaces, nested blocks indented
+// and comments normalized. Differences that are only a matter of formatting
+// therefore don't appear in the diff.
+func GoDiff(a, b string, opts ...Option) (string, error) {
+	return goDiff("a", "b", a, b, opts)
+}
+
+func goDiff(aName, bName, a, b string, opts []Option) (string, error) {
+	outlineA, err := goOutline(a)
+	if err != nil {
+		return "", fmt.Errorf("error parsing %s: %w", aName, err)
+	}
+	outlineB, err := goOutline(b)
+	if err != nil {
+		return "", fmt.Errorf("error parsing %s: %w", bName, err)
+	}
+	return UnifiedDiff(aName, bName, outlineA, outlineB, opts...), nil
+}
+
+type goToken struct {
+	tok token.Token
+	lit string
+}
+
+// goOutline returns the canonical outline of a Go source file.
+func goOutline(src string) (string, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return "", err
+	}
+
+	var imports []string
+	for _, spec := range file.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			return "", err
+		}
+		line := "import " + strconv.Quote(path)
+		if spec.Name != nil {
+			line = "import " + spec.Name.Name + " " + strconv.Quote(path)
+		}
+		imports = append(imports, line)
+	}
+	sort.Strings(imports)
+
+	// Tokens of import declarations are skipped, since the imports were
+	// listed above.
+	var importDecls []*ast.GenDecl
+	for _, decl := range file.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.IMPORT {
+			importDecls = append(importDecls, gen)
+		}
+	}
+	inImports := func(pos token.Pos) bool {
+		for _, decl := range importDecls {
+			if decl.Pos() <= pos && pos < decl.End() {
+				return true
+			}
+		}
+		return false
+	}
+
+	var tokens []goToken
+	var s scanner.Scanner
+	tf := fset.File(file.Pos())
+	s.Init(tf, []byte(src), nil, scanner.ScanComments)
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if inImports(pos) {
+			continue
+		}
+		switch {
+		case tok == token.COMMENT:
+			lit = normalizeComment(lit)
+			if n := len(tokens); n > 0 && isLineComment(tokens[n-1]) && strings.HasPrefix(lit, "//") {
+				// Merge consecutive line comments, so that
+				// rewrapping a comment doesn't change it.
+				tokens[n-1].lit = strings.TrimSpace(tokens[n-1].lit + " " + strings.TrimPrefix(lit, "// "))
+				continue
+			}
+		case tok == token.SEMICOLON:
+			lit = ";"
+		case tok.IsOperator() || tok.IsKeyword():
+			lit = tok.String()
+		}
+		tokens = append(tokens, goToken{tok, lit})
+	}
+	// The imports are listed after the package clause.
+	var out []string
+	for _, line := range outlineLines(dropOptionalTokens(tokens)) {
+		out = append(out, line)
+		if strings.HasPrefix(line, "package ") {
+			out = append(out, imports...)
+		}
+	}
+	return strings.Join(out, "\n") + "\n", nil
+}
+
+func isLineComment(t goToken) bool {
+	return t.tok == token.COMMENT && strings.HasPrefix(t.lit, "//")
+}
+
+// normalizeComment collapses the whitespace of a comment.
+func normalizeComment(text string) string {
+	if strings.HasPrefix(text, "/*") {
+		return "/* " + strings.Join(strings.Fields(strings.TrimSuffix(text[2:], "*/")), " ") + " */"
+	}
+	return strings.TrimSpace("// " + strings.Join(strings.Fields(text[2:]), " "))
+}
+
+// dropOptionalTokens removes the semicolons and commas that are optional, such
+// as the semicolons of the lines before a closing brace and the trailing commas
+// of multi-line composite literals, as well as repeated semicolons. Semicolons
+// are moved before the comments that precede them, so that a comment at the
+// end of a line doesn't change where a statement ends.
+func dropOptionalTokens(tokens []goToken) []goToken {
+	var out []goToken
+	for i, t := range tokens {
+		switch t.tok {
+		case token.SEMICOLON:
+			j := len(out)
+			for j > 0 && out[j-1].tok == token.COMMENT {
+				j--
+			}
+			if j == 0 || isOpening(out[j-1].tok) || out[j-1].tok == token.SEMICOLON || isClosing(nextToken(tokens, i, true)) {
+				continue
+			}
+			out = append(out[:j], append([]goToken{t}, out[j:]...)...)
+			continue
+		case token.COMMA:
+			if isClosing(nextToken(tokens, i, false)) {
+				continue
+			}
+		}
+		out = append(out, t)
+	}
+	return out
+}
+
+// nextToken returns the next token after tokens[i] that isn't a comment, and,
+// if skipSemicolons is set, isn't a semicolon either. It returns token.EOF if
+// there is none.
+func nextToken(tokens []goToken, i int, skipSemicolons bool) token.Token {
+	for _, t := range tokens[i+1:] {
+		if t.tok != token.COMMENT && (!skipSemicolons || t.tok != token.SEMICOLON) {
+			return t.tok
+		}
+	}
+	return token.EOF
+}
+
+func isOpening(tok token.Token) bool {
+	return tok == token.LBRACE || tok == token.LPAREN || tok == token.LBRACK
+}
+
+func isClosing(tok token.Token) bool {
+	return tok == token.RBRACE || tok == token.RPAREN || tok == token.RBRACK || tok == token.EOF
+}
+
+// outlineLines lays out tokens with one statement or declaration per line.
+func outlineLines(tokens []goToken) []string {
+	var lines []string
+	var line []goToken
+	depth := 0
+	flush := func() {
+		if len(line) > 0 {
+			lines = append(lines, strings.Repeat("\t", depth)+joinTokens(line))
+			line = nil
+		}
+	}
+	for _, t := range tokens {
+		switch t.tok {
+		case token.SEMICOLON:
+			flush()
+		case token.COMMENT:
+			flush()
+			line = append(line, t)
+			flush()
+		case token.LBRACE:
+			line = append(line, t)
+			flush()
+			depth++
+		case token.RBRACE:
+			flush()
+			if depth > 0 {
+				depth--
+			}
+			line = append(line, t)
+		default:
+			line = append(line, t)
+		}
+	}
+	flush()
+	return lines
+}
+
+// joinTokens joins the tokens of a line with spaces, except around brackets,
+// selectors and commas.
+func joinTokens(tokens []goToken) string {
+	var b strings.Builder
+	for i, t := range tokens {
+		if i > 0 && needsSpace(tokens[i-1], t) {
+			b.WriteByte(' ')
+		}
+		b.WriteString(t.lit)
+	}
+	return b.String()
+}
+
+func needsSpace(prev, t goToken) bool {
+	switch {
+	case prev.tok == token.LPAREN || prev.tok == token.LBRACK || prev.tok == token.PERIOD:
+		return false
+	case t.tok == token.RPAREN || t.tok == token.RBRACK || t.tok == token.COMMA || t.tok == token.PERIOD:
+		return false
+	case t.tok == token.LPAREN || t.tok == token.LBRACK:
+		// Calls, index expressions and type parameters.
+		return !(prev.tok == token.IDENT || prev.tok == token.RPAREN || prev.tok == token.RBRACK)
+	case prev.tok == token.RBRACK:
+		// Slice, array and map types, such as []*T and map[K]V.
+		return !(t.tok == token.IDENT || t.tok == token.MUL || t.tok == token.FUNC || t.tok == token.MAP ||
+			t.tok == token.CHAN || t.tok == token.STRUCT || t.tok == token.INTERFACE)
+	}
+	return true
+}
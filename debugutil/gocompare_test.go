@@ -0,0 +1,105 @@
+package debugutil
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGoEquivalent(t *testing.T) {
+	const base = `package p
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Greet prints a greeting
+// for name.
+func Greet(name string) {
+	values := []string{"a", "b"}
+	if name != "" {
+		fmt.Println(strings.ToUpper(name), values) // loud
+	}
+}
+`
+	tests := []struct {
+		name string
+		b    string
+		want bool
+	}{
+		{name: "identical", b: base, want: true},
+		{
+			name: "formatting",
+			b: `package p
+import "strings"
+import "fmt"
+//   Greet prints a greeting for name.
+func Greet(name string) { values := []string{
+		"a",
+		"b",
+	}; if name != "" { fmt.Println(strings.ToUpper(name), values) /* loud */ } }
+`,
+			want: false, // The comment kind differs.
+		},
+		{
+			name: "formatting and import order",
+			b: `package p
+import ("strings"; "fmt")
+//   Greet prints a greeting for name.
+func Greet(name string) { values := []string{
+		"a",
+		"b",
+	}; if name != "" { fmt.Println(strings.ToUpper(name), values) // loud
+	} }
+`,
+			want: true,
+		},
+		{
+			name: "different code",
+			b:    strings.Replace(base, "ToUpper", "ToLower", 1),
+			want: false,
+		},
+		{
+			name: "different imports",
+			b:    strings.Replace(base, `"fmt"`, `f "fmt"`, 1),
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GoEquivalent(base, tt.b)
+			if err != nil {
+				t.Fatalf("GoEquivalent() error: %v", err)
+			}
+			if got != tt.want {
+				diff, _ := GoDiff(base, tt.b)
+				t.Errorf("GoEquivalent() = %v, want %v; diff:\n%s", got, tt.want, diff)
+			}
+		})
+	}
+}
+
+func TestGoDiff(t *testing.T) {
+	a := "package p\n\nfunc f() int {\n\tx := 1\n\treturn x\n}\n"
+	b := "package p\nfunc f() int { x := 2; return x }\n"
+	got, err := GoDiff(a, b)
+	if err != nil {
+		t.Fatalf("GoDiff() error: %v", err)
+	}
+	want := `--- a
++++ b
+@@ -1,5 +1,5 @@
+ package p
+ func f() int {
+-	x := 1
++	x := 2
+ 	return x
+ }
+`
+	if got != want {
+		t.Errorf("GoDiff() got:\n%s\nwant:\n%s", got, want)
+	}
+	if _, err := GoDiff(a, "package p\nfunc {"); err == nil || !strings.Contains(err.Error(), "error parsing b") {
+		t.Errorf("GoDiff() of invalid code got error %v, want a parse error for b", err)
+	}
+}
@@ -0,0 +1,60 @@
+package debugutil
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// update is the -update flag of test binaries, which makes Golden rewrite
+// golden files. It is nil in other programs.
+var update *bool
+
+func init() {
+	// The flag is only registered in test binaries, which are named after
+	// their package with a .test suffix, so that programs that import this
+	// package don't get an -update flag.
+	name := strings.TrimSuffix(filepath.Base(os.Args[0]), ".exe")
+	if strings.HasSuffix(name, ".test") && flag.Lookup("update") == nil {
+		update = flag.Bool("update", false, "rewrite golden files instead of comparing with them")
+	}
+}
+
+// Golden compares got with the content of a golden file, such as
+// "testdata/foo.golden", and reports a test error with a diff if they differ
+// or if the file doesn't exist.
+//
+// When the test binary is run with the -update flag, as in
+// "go test -update", Golden instead writes got to the file, creating its
+// directory if needed. The flag is registered by this package, so tests that
+// use Golden must not define their own -update flag.
+//
+// The options customize the comparison and the diff; see WithColor and
+// IgnoreVolatileLines.
+func Golden(t testing.TB, got, goldenFile string, opts ...Option) {
+	t.Helper()
+	if update != nil && *update {
+		if err := os.MkdirAll(filepath.Dir(goldenFile), 0o755); err != nil {
+			t.Fatalf("error updating golden file: %v", err)
+		}
+		if err := os.WriteFile(goldenFile, []byte(got), 0o644); err != nil {
+			t.Fatalf("error updating golden file: %v", err)
+		}
+		t.Logf("updated golden file %s", goldenFile)
+		return
+	}
+	want, err := os.ReadFile(goldenFile)
+	if errors.Is(err, os.ErrNotExist) {
+		t.Errorf("golden file %s doesn't exist; run the test with -update to create it", goldenFile)
+		return
+	}
+	if err != nil {
+		t.Fatalf("error reading golden file: %v", err)
+	}
+	if diff := UnifiedDiff(goldenFile, "got", string(want), got, opts...); diff != "" {
+		t.Errorf("result differs from golden file %s (run the test with -update to update it):\n%s", goldenFile, diff)
+	}
+}
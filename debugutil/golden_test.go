@@ -0,0 +1,60 @@
+package debugutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// recordingTB records the errors reported by Golden.
+type recordingTB struct {
+	testing.TB
+	errors []string
+}
+
+func (tb *recordingTB) Helper() {}
+
+func (tb *recordingTB) Errorf(format string, args ...any) {
+	tb.errors = append(tb.errors, fmt.Sprintf(format, args...))
+}
+
+func (tb *recordingTB) Fatalf(format string, args ...any) {
+	tb.Errorf(format, args...)
+}
+
+func (tb *recordingTB) Logf(format string, args ...any) {}
+
+func TestGolden(t *testing.T) {
+	if update == nil {
+		t.Fatal("-update flag not registered in test binary")
+	}
+	defer func(orig bool) { *update = orig }(*update)
+	*update = false
+	golden := filepath.Join(t.TempDir(), "testdata", "out.golden")
+
+	tb := &recordingTB{}
+	Golden(tb, "a\nb\n", golden)
+	if len(tb.errors) != 1 || !strings.Contains(tb.errors[0], "doesn't exist; run the test with -update") {
+		t.Errorf("Golden() without a golden file reported %q, want an error that it doesn't exist", tb.errors)
+	}
+
+	*update = true
+	tb = &recordingTB{}
+	Golden(tb, "a\nb\n", golden)
+	if content, err := os.ReadFile(golden); err != nil || string(content) != "a\nb\n" || len(tb.errors) != 0 {
+		t.Errorf("Golden() with -update wrote %q (error %v) and reported %q, want the result written", content, err, tb.errors)
+	}
+
+	*update = false
+	tb = &recordingTB{}
+	Golden(tb, "a\nb\n", golden)
+	if len(tb.errors) != 0 {
+		t.Errorf("Golden() with an equal result reported %q, want no errors", tb.errors)
+	}
+	Golden(tb, "a\nc\n", golden)
+	if len(tb.errors) != 1 || !strings.Contains(tb.errors[0], "@@ -1,2 +1,2 @@\n a\n-b\n+c\n") {
+		t.Errorf("Golden() with a different result reported %q, want a diff", tb.errors)
+	}
+}
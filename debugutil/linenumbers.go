@@ -0,0 +1,35 @@
+package debugutil
+
+// WithStartLine returns an option that makes WithLineNumbers number lines from
+// n rather than 1, for printing an excerpt of a file.
+func WithStartLine(n int) Option {
+	return Option{func(c *config) { c.startLine = n }}
+}
+
+// WithHighlight returns an option that makes WithLineNumbers mark the lines
+// from first to last inclusive, such as the line of an error, with the gutter
+// marker. The option can be given several times to highlight several ranges.
+// With WithColor, the marker is yellow.
+func WithHighlight(first, last int) Option {
+	return Option{func(c *config) { c.highlights = append(c.highlights, [2]int{first, last}) }}
+}
+
+// WithGutterMarker returns an option that replaces the marker of the lines
+// highlighted by WithHighlight, which is ">" by default.
+func WithGutterMarker(marker string) Option {
+	return Option{func(c *config) { c.gutterMarker = marker }}
+}
+
+// gutter returns the gutter printed before the number of line n, which is empty
+// if no line is highlighted.
+func (c *config) gutter(n int) string {
+	if len(c.highlights) == 0 {
+		return ""
+	}
+	for _, h := range c.highlights {
+		if n >= h[0] && n <= h[1] {
+			return c.paint(ansiYellow, c.gutterMarker) + " "
+		}
+	}
+	return pad("", width(c.gutterMarker)) + " "
+}
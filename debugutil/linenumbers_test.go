@@ -0,0 +1,49 @@
+package debugutil
+
+import "testing"
+
+func TestWithLineNumbers(t *testing.T) {
+	tests := []struct {
+		name string
+		str  string
+		opts []Option
+		want string
+	}{
+		{
+			name: "default",
+			str:  "a\nb",
+			want: "1: a\n2: b",
+		},
+		{
+			name: "start line",
+			str:  "a\nb\nc",
+			opts: []Option{WithStartLine(8)},
+			want: " 8: a\n 9: b\n10: c",
+		},
+		{
+			name: "highlight",
+			str:  "a\nb\nc\nd",
+			opts: []Option{WithHighlight(2, 2), WithHighlight(4, 5)},
+			want: "  1: a\n> 2: b\n  3: c\n> 4: d",
+		},
+		{
+			name: "gutter marker",
+			str:  "a\nb",
+			opts: []Option{WithStartLine(10), WithHighlight(11, 11), WithGutterMarker("=>")},
+			want: "   10: a\n=> 11: b",
+		},
+		{
+			name: "color",
+			str:  "a\nb",
+			opts: []Option{WithHighlight(1, 1), WithColor(ColorAlways)},
+			want: "\x1b[33m>\x1b[0m 1: a\n  2: b",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WithLineNumbers(tt.str, tt.opts...); got != tt.want {
+				t.Errorf("WithLineNumbers() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package debugutil
+
+import (
+	"go/types"
+	"regexp"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// Option customizes the output of the functions of this package that accept
+// options.
+type Option struct {
+	apply func(*config)
+}
+
+type config struct {
+	color       bool
+	ignoreLines []*regexp.Regexp
+	importer    types.Importer
+	analyzers   []*analysis.Analyzer
+
+	// SideBySide layout.
+	maxWidth     int
+	overflow     Overflow
+	equalSep     string
+	differentSep string
+	context      int
+
+	// WithLineNumbers layout.
+	startLine    int
+	highlights   [][2]int
+	gutterMarker string
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{equalSep: "|", differentSep: "Δ", context: -1, startLine: 1, gutterMarker: ">"}
+	for _, opt := range opts {
+		opt.apply(c)
+	}
+	return c
+}
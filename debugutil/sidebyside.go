@@ -0,0 +1,122 @@
+package debugutil
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Overflow determines what SideBySide does with lines that are wider than the
+// maximum column width.
+type Overflow int
+
+const (
+	// Truncate cuts long lines and ends them with an ellipsis.
+	Truncate Overflow = iota
+	// Wrap continues long lines on as many rows as needed.
+	Wrap
+)
+
+// WithMaxWidth returns an option that limits the width of each column of
+// SideBySide to the given number of characters. Longer lines are truncated or
+// wrapped depending on overflow.
+func WithMaxWidth(width int, overflow Overflow) Option {
+	return Option{func(c *config) {
+		c.maxWidth = width
+		c.overflow = overflow
+	}}
+}
+
+// WithSeparators returns an option that replaces the separators SideBySide
+// prints between the columns: equal for lines that are the same on both sides
+// ("|" by default) and different for lines that differ ("Δ" by default).
+func WithSeparators(equal, different string) Option {
+	return Option{func(c *config) {
+		c.equalSep = equal
+		c.differentSep = different
+	}}
+}
+
+// WithContext returns an option that makes SideBySide only print the lines
+// that differ and the given number of lines around them. Each run of hidden
+// equal lines is replaced by a single row that says how many lines it hides.
+func WithContext(lines int) Option {
+	return Option{func(c *config) { c.context = lines }}
+}
+
+// sideBySideRow is a pair of lines printed next to each other.
+type sideBySideRow struct {
+	a, b             string
+	existsA, existsB bool
+	hidden           int // number of hidden lines if this row stands for them
+}
+
+func (r *sideBySideRow) differs() bool {
+	return r.a != r.b || r.existsA != r.existsB
+}
+
+// hideEqualRuns replaces the rows that are further than c.context rows away
+// from a difference by placeholder rows.
+func (c *config) hideEqualRuns(rows []*sideBySideRow) []*sideBySideRow {
+	if c.context < 0 {
+		return rows
+	}
+	visible := make([]bool, len(rows))
+	for i, row := range rows {
+		if !row.differs() {
+			continue
+		}
+		for j := i - c.context; j <= i+c.context; j++ {
+			if j >= 0 && j < len(rows) {
+				visible[j] = true
+			}
+		}
+	}
+	var out []*sideBySideRow
+	for i, row := range rows {
+		if visible[i] {
+			out = append(out, row)
+			continue
+		}
+		if len(out) == 0 || out[len(out)-1].hidden == 0 {
+			out = append(out, &sideBySideRow{})
+		}
+		out[len(out)-1].hidden++
+	}
+	return out
+}
+
+// cells splits a line into the pieces printed on successive rows of a column.
+func (c *config) cells(line string) []string {
+	if c.maxWidth <= 0 || width(line) <= c.maxWidth {
+		return []string{line}
+	}
+	runes := []rune(line)
+	if c.overflow == Truncate {
+		return []string{string(runes[:c.maxWidth-1]) + "…"}
+	}
+	var out []string
+	for len(runes) > c.maxWidth {
+		out = append(out, string(runes[:c.maxWidth]))
+		runes = runes[c.maxWidth:]
+	}
+	return append(out, string(runes))
+}
+
+func hiddenLinesMessage(n int) string {
+	if n == 1 {
+		return "⋯ 1 equal line ⋯"
+	}
+	return fmt.Sprintf("⋯ %d equal lines ⋯", n)
+}
+
+func width(s string) int {
+	return utf8.RuneCountInString(s)
+}
+
+func pad(line string, w int) string {
+	if padSize := w - width(line); padSize > 0 {
+		return line + strings.Repeat(" ", padSize)
+	}
+	return line
+}
@@ -0,0 +1,88 @@
+package debugutil
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSideBySide(t *testing.T) {
+	long := strings.Repeat("x\n", 10)
+	tests := []struct {
+		name string
+		a, b string
+		opts []Option
+		want string
+	}{
+		{
+			name: "default",
+			a:    "same\nold\ngone",
+			b:    "same\nnew",
+			want: "1: same| same\n" +
+				"2: old Δ new\n" +
+				"3: goneΔ ",
+		},
+		{
+			name: "truncate",
+			a:    "abcdefgh\nab",
+			b:    "abcdefgh\nabc",
+			opts: []Option{WithMaxWidth(5, Truncate)},
+			want: "1: abcd…| abcd…\n" +
+				"2: ab   Δ abc",
+		},
+		{
+			name: "wrap",
+			a:    "abcdefgh\nab",
+			b:    "ab\nabcdefghijk",
+			opts: []Option{WithMaxWidth(4, Wrap)},
+			want: "1: abcdΔ ab\n" +
+				"   efghΔ \n" +
+				"2: ab  Δ abcd\n" +
+				"       Δ efgh\n" +
+				"       Δ ijk",
+		},
+		{
+			name: "separators",
+			a:    "a\nb",
+			b:    "a\nc",
+			opts: []Option{WithSeparators(" = ", " ≠ ")},
+			want: "1: a =  a\n" +
+				"2: b ≠  c",
+		},
+		{
+			name: "context",
+			a:    "first\n" + long + "old\n" + long + "last",
+			b:    "first\n" + long + "new\n" + long + "last",
+			opts: []Option{WithContext(2)},
+			want: "    ⋯ 9 equal lines ⋯\n" +
+				"10: x  | x\n" +
+				"11: x  | x\n" +
+				"12: oldΔ new\n" +
+				"13: x  | x\n" +
+				"14: x  | x\n" +
+				"    ⋯ 9 equal lines ⋯",
+		},
+		{
+			name: "context without differences",
+			a:    "a\nb\nc",
+			b:    "a\nb\nc",
+			opts: []Option{WithContext(1)},
+			want: "   ⋯ 3 equal lines ⋯",
+		},
+		{
+			name: "context single hidden line",
+			a:    "a\nb\nc",
+			b:    "a\nb\nd",
+			opts: []Option{WithContext(1)},
+			want: "   ⋯ 1 equal line ⋯\n" +
+				"2: b| b\n" +
+				"3: cΔ d",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SideBySide(tt.a, tt.b, tt.opts...); got != tt.want {
+				t.Errorf("SideBySide() = \n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
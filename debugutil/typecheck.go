@@ -0,0 +1,77 @@
+package debugutil
+
+import (
+	"errors"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"sort"
+)
+
+// WithImporter returns an option that makes TypeCheck import packages with
+// imp. The default importer type-checks imported packages from source, which
+// works without compiled export data.
+func WithImporter(imp types.Importer) Option {
+	return Option{func(c *config) { c.importer = imp }}
+}
+
+// TypeCheck parses and type-checks a Go source file, typically the output of a
+// generator in a unit test, and returns the syntax and type errors found in
+// it, sorted by position. When there are errors, listing is the annotated
+// listing of the errors in src returned by AnnotateError; otherwise it is
+// empty.
+//
+// The file is checked as the only file of its package, so it may not refer
+// to declarations of other files of the package. Packages are imported with
+// the importer given by WithImporter.
+//
+// For example:
+//
+//	if diags, listing := debugutil.TypeCheck(out); len(diags) > 0 {
+//		t.Errorf("generated code doesn't type-check:\n%s", listing)
+//	}
+func TypeCheck(src string, opts ...Option) (diags []Diagnostic, listing string) {
+	c := newConfig(opts)
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ParseComments|parser.AllErrors)
+	if err != nil {
+		// The syntax errors are already sorted by the parser.
+		diags = diagnostics(err)
+		if len(diags) == 0 {
+			diags = []Diagnostic{{Msg: err.Error()}}
+		}
+		return diags, annotate(src, diags)
+	}
+
+	imp := c.importer
+	if imp == nil {
+		imp = importer.ForCompiler(fset, "source", nil)
+	}
+	conf := types.Config{
+		Importer: imp,
+		Error: func(err error) {
+			var typeErr types.Error
+			if errors.As(err, &typeErr) {
+				diags = append(diags, typeErrorDiagnostic(typeErr))
+			}
+		},
+	}
+	// The errors are reported to conf.Error.
+	_, _ = conf.Check(f.Name.Name, fset, []*ast.File{f}, nil)
+	if len(diags) == 0 {
+		return nil, ""
+	}
+	sort.SliceStable(diags, func(i, j int) bool {
+		if diags[i].Pos.Line != diags[j].Pos.Line {
+			return diags[i].Pos.Line < diags[j].Pos.Line
+		}
+		return diags[i].Pos.Column < diags[j].Pos.Column
+	})
+	return diags, annotate(src, diags)
+}
+
+func typeErrorDiagnostic(err types.Error) Diagnostic {
+	return Diagnostic{Pos: err.Fset.Position(err.Pos), Msg: err.Msg, Soft: err.Soft}
+}
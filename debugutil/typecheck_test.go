@@ -0,0 +1,91 @@
+package debugutil
+
+import (
+	"go/importer"
+	"go/token"
+	"reflect"
+	"testing"
+)
+
+func TestTypeCheck(t *testing.T) {
+	tests := []struct {
+		name        string
+		src         string
+		opts        []Option
+		wantDiags   []Diagnostic
+		wantListing string
+		// softOnly makes the test only check that there is a single soft
+		// error, because its message depends on the version of Go.
+		softOnly bool
+	}{
+		{
+			name: "valid",
+			src:  "package p\n\nimport \"strings\"\n\nvar S = strings.ToUpper(\"x\")\n",
+		},
+		{
+			name: "syntax error",
+			src:  "package p\n\nvar x = )\nvar y = 1\n",
+			wantDiags: []Diagnostic{
+				{Pos: token.Position{Offset: 19, Line: 3, Column: 9}, Msg: "expected operand, found ')'"},
+				{Pos: token.Position{Offset: 21, Line: 4, Column: 1}, Msg: "expected ';', found 'var'"},
+			},
+			wantListing: "3:9: expected operand, found ')'\n" +
+				"1: package p\n" +
+				"2: \n" +
+				"3: var x = )\n" +
+				"           ^\n" +
+				"\n" +
+				"4:1: expected ';', found 'var'\n" +
+				"2: \n" +
+				"3: var x = )\n" +
+				"4: var y = 1\n" +
+				"   ^",
+		},
+		{
+			name: "type errors",
+			src:  "package p\n\nvar x = y\n\nfunc f() int {\n\treturn z\n}\n",
+			wantDiags: []Diagnostic{
+				{Pos: token.Position{Offset: 19, Line: 3, Column: 9}, Msg: "undefined: y"},
+				{Pos: token.Position{Offset: 45, Line: 6, Column: 9}, Msg: "undefined: z"},
+			},
+			wantListing: "3:9: undefined: y\n" +
+				"1: package p\n" +
+				"2: \n" +
+				"3: var x = y\n" +
+				"           ^\n" +
+				"\n" +
+				"6:9: undefined: z\n" +
+				"4: \n" +
+				"5: func f() int {\n" +
+				"6: \treturn z\n" +
+				"   \t       ^",
+		},
+		{
+			name:     "soft error",
+			src:      "package p\n\nimport \"strings\"\n",
+			softOnly: true,
+		},
+		{
+			name: "custom importer",
+			src:  "package p\n\nimport \"strings\"\n\nvar S = strings.ToUpper(\"x\")\n",
+			opts: []Option{WithImporter(importer.ForCompiler(token.NewFileSet(), "source", nil))},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diags, listing := TypeCheck(tt.src, tt.opts...)
+			if tt.softOnly {
+				if len(diags) != 1 || !diags[0].Soft || listing == "" {
+					t.Errorf("TypeCheck() = %+v, %q; want a single soft error", diags, listing)
+				}
+				return
+			}
+			if !reflect.DeepEqual(diags, tt.wantDiags) {
+				t.Errorf("TypeCheck() diagnostics = %+v, want %+v", diags, tt.wantDiags)
+			}
+			if listing != tt.wantListing {
+				t.Errorf("TypeCheck() listing = \n%s\nwant\n%s", listing, tt.wantListing)
+			}
+		})
+	}
+}
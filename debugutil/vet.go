@@ -0,0 +1,277 @@
+package debugutil
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"reflect"
+	"sort"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/assign"
+	"golang.org/x/tools/go/analysis/passes/atomic"
+	"golang.org/x/tools/go/analysis/passes/bools"
+	"golang.org/x/tools/go/analysis/passes/composite"
+	"golang.org/x/tools/go/analysis/passes/copylock"
+	"golang.org/x/tools/go/analysis/passes/errorsas"
+	"golang.org/x/tools/go/analysis/passes/httpresponse"
+	"golang.org/x/tools/go/analysis/passes/ifaceassert"
+	"golang.org/x/tools/go/analysis/passes/loopclosure"
+	"golang.org/x/tools/go/analysis/passes/lostcancel"
+	"golang.org/x/tools/go/analysis/passes/nilfunc"
+	"golang.org/x/tools/go/analysis/passes/printf"
+	"golang.org/x/tools/go/analysis/passes/shift"
+	"golang.org/x/tools/go/analysis/passes/stdmethods"
+	"golang.org/x/tools/go/analysis/passes/stringintconv"
+	"golang.org/x/tools/go/analysis/passes/structtag"
+	"golang.org/x/tools/go/analysis/passes/tests"
+	"golang.org/x/tools/go/analysis/passes/unmarshal"
+	"golang.org/x/tools/go/analysis/passes/unreachable"
+	"golang.org/x/tools/go/analysis/passes/unsafeptr"
+	"golang.org/x/tools/go/analysis/passes/unusedresult"
+)
+
+// WithAnalyzers returns an option that makes Vet run analyzers instead of
+// VetAnalyzers. Their prerequisites, such as the inspect analyzer, are run as
+// needed, but only the findings of analyzers are reported.
+func WithAnalyzers(analyzers ...*analysis.Analyzer) Option {
+	return Option{func(c *config) { c.analyzers = analyzers }}
+}
+
+// VetAnalyzers returns the analyzers that Vet runs by default: those of go vet
+// that work on the Go files of a package alone, such as printf, copylocks and
+// structtag. The analyzers of assembly, cgo and build tags are left out.
+func VetAnalyzers() []*analysis.Analyzer {
+	return []*analysis.Analyzer{
+		assign.Analyzer,
+		atomic.Analyzer,
+		bools.Analyzer,
+		composite.Analyzer,
+		copylock.Analyzer,
+		errorsas.Analyzer,
+		httpresponse.Analyzer,
+		ifaceassert.Analyzer,
+		loopclosure.Analyzer,
+		lostcancel.Analyzer,
+		nilfunc.Analyzer,
+		printf.Analyzer,
+		shift.Analyzer,
+		stdmethods.Analyzer,
+		stringintconv.Analyzer,
+		structtag.Analyzer,
+		tests.Analyzer,
+		unmarshal.Analyzer,
+		unreachable.Analyzer,
+		unsafeptr.Analyzer,
+		unusedresult.Analyzer,
+	}
+}
+
+// Finding is a problem that an analyzer reports in a Go file checked by Vet.
+type Finding struct {
+	// Analyzer is the name of the analyzer, such as "printf".
+	Analyzer string
+	// Pos is the position of the problem. Its filename is empty.
+	Pos token.Position
+	// Msg is the message of the analyzer, without the position.
+	Msg string
+}
+
+// String returns the finding in the "line:column: analyzer: message" format of
+// go vet.
+func (f Finding) String() string {
+	return fmt.Sprintf("%d:%d: %s: %s", f.Pos.Line, f.Pos.Column, f.Analyzer, f.Msg)
+}
+
+// Vet runs vet checks on a Go source file, typically the output of a generator
+// in a unit test, in memory and without the go command, and returns their
+// findings sorted by position. When there are findings, listing is the
+// annotated listing of the findings in src, like that of TypeCheck; otherwise
+// it is empty.
+//
+// The file is type-checked like TypeCheck does, as the only file of its
+// package, with the importer given by WithImporter. Vet returns an error,
+// whose message includes the annotated listing of the errors, if the file
+// doesn't parse or type-check, or if an analyzer fails. The analyzers are
+// those of VetAnalyzers, unless WithAnalyzers is given. Facts about imported
+// packages, such as which of their functions are printf wrappers, aren't
+// known.
+//
+// For example:
+//
+//	if findings, listing, err := debugutil.Vet(out); err != nil {
+//		t.Fatal(err)
+//	} else if len(findings) > 0 {
+//		t.Errorf("generated code doesn't pass vet:\n%s", listing)
+//	}
+func Vet(src string, opts ...Option) (findings []Finding, listing string, err error) {
+	c := newConfig(opts)
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		_, listing := TypeCheck(src, opts...)
+		return nil, "", fmt.Errorf("vet: source doesn't parse:\n%s", listing)
+	}
+	imp := c.importer
+	if imp == nil {
+		imp = importer.ForCompiler(fset, "source", nil)
+	}
+	info := &types.Info{
+		Types:      map[ast.Expr]types.TypeAndValue{},
+		Defs:       map[*ast.Ident]types.Object{},
+		Uses:       map[*ast.Ident]types.Object{},
+		Implicits:  map[ast.Node]types.Object{},
+		Selections: map[*ast.SelectorExpr]*types.Selection{},
+		Scopes:     map[ast.Node]*types.Scope{},
+	}
+	conf := types.Config{Importer: imp, Error: func(error) {}}
+	pkg, err := conf.Check(f.Name.Name, fset, []*ast.File{f}, info)
+	if err != nil {
+		_, listing := TypeCheck(src, opts...)
+		return nil, "", fmt.Errorf("vet: source doesn't type-check:\n%s", listing)
+	}
+
+	analyzers := c.analyzers
+	if analyzers == nil {
+		analyzers = VetAnalyzers()
+	}
+	v := &vetter{
+		fset:    fset,
+		files:   []*ast.File{f},
+		pkg:     pkg,
+		info:    info,
+		sizes:   types.SizesFor("gc", "amd64"),
+		results: map[*analysis.Analyzer]any{},
+		facts:   map[factKey]analysis.Fact{},
+		report:  map[*analysis.Analyzer]bool{},
+	}
+	for _, a := range analyzers {
+		v.report[a] = true
+	}
+	for _, a := range analyzers {
+		if err := v.run(a); err != nil {
+			return nil, "", err
+		}
+	}
+	if len(v.findings) == 0 {
+		return nil, "", nil
+	}
+	sort.SliceStable(v.findings, func(i, j int) bool {
+		if v.findings[i].Pos.Line != v.findings[j].Pos.Line {
+			return v.findings[i].Pos.Line < v.findings[j].Pos.Line
+		}
+		return v.findings[i].Pos.Column < v.findings[j].Pos.Column
+	})
+	diags := make([]Diagnostic, len(v.findings))
+	for i, finding := range v.findings {
+		diags[i] = Diagnostic{Pos: finding.Pos, Msg: finding.Analyzer + ": " + finding.Msg}
+	}
+	return v.findings, annotate(src, diags), nil
+}
+
+// vetter runs analyzers on a single package, which is the only package whose
+// facts are known.
+type vetter struct {
+	fset  *token.FileSet
+	files []*ast.File
+	pkg   *types.Package
+	info  *types.Info
+	sizes types.Sizes
+
+	// results are the results of the analyzers that were run.
+	results map[*analysis.Analyzer]any
+	facts   map[factKey]analysis.Fact
+	// report are the analyzers whose findings are reported.
+	report   map[*analysis.Analyzer]bool
+	findings []Finding
+}
+
+// factKey identifies a fact about an object, or about the package if obj is
+// nil.
+type factKey struct {
+	obj types.Object
+	typ reflect.Type
+}
+
+// run runs a after its prerequisites, unless it was already run.
+func (v *vetter) run(a *analysis.Analyzer) error {
+	if _, ok := v.results[a]; ok {
+		return nil
+	}
+	resultOf := map[*analysis.Analyzer]any{}
+	for _, req := range a.Requires {
+		if err := v.run(req); err != nil {
+			return err
+		}
+		resultOf[req] = v.results[req]
+	}
+	pass := &analysis.Pass{
+		Analyzer:   a,
+		Fset:       v.fset,
+		Files:      v.files,
+		Pkg:        v.pkg,
+		TypesInfo:  v.info,
+		TypesSizes: v.sizes,
+		ResultOf:   resultOf,
+		Report: func(d analysis.Diagnostic) {
+			if v.report[a] {
+				v.findings = append(v.findings, Finding{Analyzer: a.Name, Pos: v.fset.Position(d.Pos), Msg: d.Message})
+			}
+		},
+		ImportObjectFact:  func(obj types.Object, fact analysis.Fact) bool { return v.importFact(obj, fact) },
+		ExportObjectFact:  func(obj types.Object, fact analysis.Fact) { v.facts[factKey{obj, reflect.TypeOf(fact)}] = fact },
+		ImportPackageFact: func(pkg *types.Package, fact analysis.Fact) bool { return pkg == v.pkg && v.importFact(nil, fact) },
+		ExportPackageFact: func(fact analysis.Fact) { v.facts[factKey{nil, reflect.TypeOf(fact)}] = fact },
+		AllObjectFacts: func() []analysis.ObjectFact {
+			var facts []analysis.ObjectFact
+			for key, fact := range v.facts {
+				if key.obj != nil && v.hasFactType(a, key.typ) {
+					facts = append(facts, analysis.ObjectFact{Object: key.obj, Fact: fact})
+				}
+			}
+			return facts
+		},
+		AllPackageFacts: func() []analysis.PackageFact {
+			var facts []analysis.PackageFact
+			for key, fact := range v.facts {
+				if key.obj == nil && v.hasFactType(a, key.typ) {
+					facts = append(facts, analysis.PackageFact{Package: v.pkg, Fact: fact})
+				}
+			}
+			return facts
+		},
+	}
+	result, err := a.Run(pass)
+	if err != nil {
+		return fmt.Errorf("vet: %s: %w", a.Name, err)
+	}
+	if result != nil && reflect.TypeOf(result) != a.ResultType {
+		return errors.New("vet: " + a.Name + ": result of the wrong type")
+	}
+	v.results[a] = result
+	return nil
+}
+
+// importFact copies the fact of obj with the type of fact to fact, and
+// reports whether there is one.
+func (v *vetter) importFact(obj types.Object, fact analysis.Fact) bool {
+	known, ok := v.facts[factKey{obj, reflect.TypeOf(fact)}]
+	if !ok {
+		return false
+	}
+	reflect.ValueOf(fact).Elem().Set(reflect.ValueOf(known).Elem())
+	return true
+}
+
+func (v *vetter) hasFactType(a *analysis.Analyzer, typ reflect.Type) bool {
+	for _, fact := range a.FactTypes {
+		if reflect.TypeOf(fact) == typ {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package debugutil
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/analysis/passes/printf"
+)
+
+func TestVet(t *testing.T) {
+	tests := []struct {
+		name         string
+		src          string
+		opts         []Option
+		wantFindings []string
+		wantListing  string
+		wantErr      string
+	}{
+		{
+			name: "clean",
+			src:  "package p\n\nimport \"fmt\"\n\nfunc F(n int) string { return fmt.Sprintf(\"%d\", n) }\n",
+		},
+		{
+			name: "findings",
+			src: `package p
+
+import (
+	"fmt"
+	"sync"
+)
+
+type T struct {
+	Name string ` + "`json:name`" + `
+}
+
+func F(mu sync.Mutex) string {
+	return fmt.Sprintf("%d", "x")
+}
+`,
+			wantFindings: []string{
+				`9:2: structtag: struct field tag ` + "`json:name`" + ` not compatible with reflect.StructTag.Get: bad syntax for struct tag value`,
+				`12:11: copylocks: F passes lock by value: sync.Mutex`,
+				`13:9: printf: fmt.Sprintf format %d has arg "x" of wrong type string`,
+			},
+			wantListing: "13:9: printf: fmt.Sprintf format %d has arg \"x\" of wrong type string\n" +
+				"11: \n" +
+				"12: func F(mu sync.Mutex) string {\n" +
+				"13: \treturn fmt.Sprintf(\"%d\", \"x\")\n" +
+				"    \t       ^",
+		},
+		{
+			name:         "printf wrapper",
+			src:          "package p\n\nimport \"fmt\"\n\nfunc logf(format string, args ...interface{}) { fmt.Printf(format, args...) }\n\nfunc F() { logf(\"%s\") }\n",
+			opts:         []Option{WithAnalyzers(printf.Analyzer)},
+			wantFindings: []string{`7:12: printf: p.logf format %s reads arg #1, but call has 0 args`},
+		},
+		{
+			name:    "type errors",
+			src:     "package p\n\nvar x = y\n",
+			wantErr: "vet: source doesn't type-check:\n3:9: undefined: y",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings, listing, err := Vet(tt.src, tt.opts...)
+			if tt.wantErr != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+					t.Fatalf("Vet() error = %v, want prefix %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Vet() error: %v", err)
+			}
+			var got []string
+			for _, f := range findings {
+				got = append(got, f.String())
+			}
+			if !reflect.DeepEqual(got, tt.wantFindings) {
+				t.Errorf("Vet() findings:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.wantFindings, "\n"))
+			}
+			if tt.wantListing != "" && !strings.HasSuffix(listing, tt.wantListing) {
+				t.Errorf("Vet() listing:\n%s\nwant suffix:\n%s", listing, tt.wantListing)
+			}
+		})
+	}
+}
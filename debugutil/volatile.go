@@ -0,0 +1,53 @@
+package debugutil
+
+import (
+	"regexp"
+	"strconv"
+)
+
+// volatileLines match the lines that IgnoreVolatileLines ignores. Only
+// comments are matched, so that differences in code are always reported.
+var volatileLines = []*regexp.Regexp{
+	// Headers of generated files, which often name the generator and its
+	// version.
+	regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`),
+	// Comments that mention a version, such as "protoc-gen-go v1.28.0",
+	// "Module: example.com/gen@v0.3.1" or "go1.21.3".
+	regexp.MustCompile(`^\s*//.*(\bv\d+\.\d+|\bgo\d+\.\d+)`),
+	// Comments that mention a time stamp, such as "2006-01-02T15:04:05Z".
+	regexp.MustCompile(`^\s*//.*\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}`),
+}
+
+// IgnoreLines returns an option that makes diffs treat lines matching one of
+// the patterns as equal to the lines of the other side that match the same
+// pattern, so that differences in those lines alone don't make inputs
+// differ. The lines are still shown as context of other differences.
+func IgnoreLines(patterns ...*regexp.Regexp) Option {
+	return Option{func(c *config) { c.ignoreLines = append(c.ignoreLines, patterns...) }}
+}
+
+// IgnoreVolatileLines returns an option that ignores the comments that change
+// when a generator is upgraded or rerun rather than when its output changes:
+// the "Code generated ... DO NOT EDIT." header, and comments that mention a
+// version (such as "v1.2.3" or "go1.21") or a time stamp (such as
+// "2006-01-02 15:04"). See IgnoreLines.
+func IgnoreVolatileLines() Option {
+	return IgnoreLines(volatileLines...)
+}
+
+// lineKey returns the string used to compare a line, which keeps its line
+// terminator, with other lines.
+func (c *config) lineKey(line string) string {
+	text := line
+	if n := len(text); n > 0 && text[n-1] == '\n' {
+		text = text[:n-1]
+	}
+	for i, pattern := range c.ignoreLines {
+		if pattern.MatchString(text) {
+			// The key can't be equal to a line, which has no NUL byte in
+			// text files, but still distinguishes a missing final newline.
+			return "\x00" + strconv.Itoa(i) + line[len(text):]
+		}
+	}
+	return line
+}
@@ -0,0 +1,74 @@
+package debugutil
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestIgnoreLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		opts []Option
+		want string
+	}{
+		{
+			name: "header only",
+			a:    "// Code generated by gen v1.0.0. DO NOT EDIT.\n\npackage p\n",
+			b:    "// Code generated by gen v1.1.0. DO NOT EDIT.\n\npackage p\n",
+			opts: []Option{IgnoreVolatileLines()},
+		},
+		{
+			name: "version and time stamp comments",
+			a:    "// versions:\n// \tprotoc-gen-go v1.28.0\n// Generated at 2022-05-01T10:00:00Z.\npackage p\n",
+			b:    "// versions:\n// \tprotoc-gen-go v1.30.1\n// Generated at 2023-01-02 11:30.\npackage p\n",
+			opts: []Option{IgnoreVolatileLines()},
+		},
+		{
+			name: "other changes",
+			a:    "// Code generated by gen v1.0.0. DO NOT EDIT.\n\nvar x = 1\n",
+			b:    "// Code generated by gen v1.1.0. DO NOT EDIT.\n\nvar x = 2\n",
+			opts: []Option{IgnoreVolatileLines()},
+			want: "--- a\n+++ b\n" +
+				"@@ -1,3 +1,3 @@\n" +
+				" // Code generated by gen v1.0.0. DO NOT EDIT.\n" +
+				" \n" +
+				"-var x = 1\n" +
+				"+var x = 2\n",
+		},
+		{
+			name: "code that mentions a version",
+			a:    "var v = \"v1.0.0\"\n",
+			b:    "var v = \"v1.1.0\"\n",
+			opts: []Option{IgnoreVolatileLines()},
+			want: "--- a\n+++ b\n" +
+				"@@ -1 +1 @@\n" +
+				"-var v = \"v1.0.0\"\n" +
+				"+var v = \"v1.1.0\"\n",
+		},
+		{
+			name: "custom pattern",
+			a:    "id: 123\nname: x\n",
+			b:    "id: 456\nname: x\n",
+			opts: []Option{IgnoreLines(regexp.MustCompile(`^id: `))},
+		},
+		{
+			name: "missing final newline",
+			a:    "id: 123\n",
+			b:    "id: 456",
+			opts: []Option{IgnoreLines(regexp.MustCompile(`^id: `))},
+			want: "--- a\n+++ b\n" +
+				"@@ -1 +1 @@\n" +
+				"-id: 123\n" +
+				"+id: 456\n" +
+				"\\ No newline at end of file\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := UnifiedDiff("a", "b", tt.a, tt.b, tt.opts...); got != tt.want {
+				t.Errorf("UnifiedDiff() = \n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package codegenutil
+
+import "errors"
+
+// Errors that the errors of this package and of the packages that use it wrap,
+// so that callers can tell failures apart with errors.Is.
+var (
+	// ErrAliasConflict is wrapped by the errors of imports that can't be
+	// given a local name that doesn't conflict with the other imports.
+	ErrAliasConflict = errors.New("import alias conflict")
+	// ErrInvalidIdentifier is wrapped by the errors of names that must be Go
+	// identifiers but aren't.
+	ErrInvalidIdentifier = errors.New("not an identifier")
+	// ErrImportNotFound is wrapped by the errors of symbols whose packages
+	// aren't imported and can't be, and of import paths that match no
+	// package. A *FrozenError matches it.
+	ErrImportNotFound = errors.New("import not found")
+	// ErrGoVersion is wrapped by the errors of code that requires a newer
+	// version of Go than the target version of its file. A *GoVersionError
+	// matches it.
+	ErrGoVersion = errors.New("requires a newer Go version")
+)
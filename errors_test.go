@@ -0,0 +1,42 @@
+package codegenutil
+
+import (
+	"errors"
+	"testing"
+)
+
+// recovered returns the error that f panics with.
+func recovered(f func()) (err error) {
+	defer func() { err, _ = recover().(error) }()
+	f()
+	return nil
+}
+
+func TestErrors(t *testing.T) {
+	noSuggestions := CustomPackageNameSuggester(func(pkg *Package, tryImportSpec func(string) bool) {})
+	conflicting := NewFileImports(AssumedPackageName("abc/xyz"), WithImports(AssumedPackageName("example.com/fmt")), noSuggestions)
+	frozen := NewFileImports(AssumedPackageName("abc/xyz")).Freeze()
+	_, parseErr := ParseSymbol("a/b.c-d")
+	_, frozenErr := frozen.TryAdd(AssumedPackageName("os"), "")
+
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"alias conflict", recovered(func() { conflicting.Add(AssumedPackageName("fmt"), "") }), ErrAliasConflict},
+		{"invalid identifier", parseErr, ErrInvalidIdentifier},
+		{"unqualifiable symbol", recovered(func() { frozen.MustQualify(Sym("fmt", "Println")) }), ErrImportNotFound},
+		{"frozen", frozenErr, ErrImportNotFound},
+		{"frozen symbol", recovered(func() { Sym("os", "Exit").GoCode(frozen) }), ErrImportNotFound},
+	}
+	for _, tt := range tests {
+		if !errors.Is(tt.err, tt.want) {
+			t.Errorf("%s: got error %v, want an error matching %v", tt.name, tt.err, tt.want)
+		}
+	}
+	var fe *FrozenError
+	if !errors.As(frozenErr, &fe) || fe.Package.ImportPath() != "os" {
+		t.Errorf("TryAdd() on a frozen FileImports got error %v, want *FrozenError for os", frozenErr)
+	}
+}
@@ -0,0 +1,86 @@
+package codegenutil
+
+import (
+	"go/format"
+	"testing"
+
+	"github.com/meta-programming/go-codegenutil/debugutil"
+	"golang.org/x/tools/imports"
+)
+
+func TestFileImports_Format_goimports(t *testing.T) {
+	tests := []struct {
+		name    string
+		imports map[string]string // import path to alias
+	}{
+		{"std only", map[string]string{"fmt": "", "os": "", "encoding/json": ""}},
+		{"other only", map[string]string{"example.com/b": "", "example.com/a": "x"}},
+		{
+			name: "mixed",
+			imports: map[string]string{
+				"fmt":                 "",
+				"log":                 "stdlog",
+				"alternative/math":    "math2",
+				"math":                "",
+				"example.com/log":     "",
+				"gopkg.in/yaml.v3":    "",
+				"example.com/z/embed": "_",
+				"github.com/a/b/v2":   "b2",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fi := NewFileImports(AssumedPackageName("abc.xyz/mypkg"))
+			for importPath, alias := range tt.imports {
+				fi.Add(AssumedPackageName(importPath), alias)
+			}
+			src := []byte(fi.Format(true) + "\n")
+			gofmted, err := format.Source(src)
+			if err != nil {
+				t.Fatalf("format.Source() error: %v", err)
+			}
+			if string(gofmted) != string(src) {
+				t.Errorf("gofmt changed the imports (got|gofmt):\n%s", debugutil.SideBySide(string(src), string(gofmted)))
+			}
+			goimported, err := imports.Process("x.go", src, &imports.Options{FormatOnly: true, Comments: true, TabIndent: true, TabWidth: 8})
+			if err != nil {
+				t.Fatalf("imports.Process() error: %v", err)
+			}
+			if string(goimported) != string(src) {
+				t.Errorf("goimports changed the imports (got|goimports):\n%s", debugutil.SideBySide(string(src), string(goimported)))
+			}
+		})
+	}
+}
+
+func TestSeparateImportDecls(t *testing.T) {
+	fi := NewFileImports(AssumedPackageName("abc.xyz/mypkg"), SeparateImportDecls("Register the drivers."))
+	fi.Add(AssumedPackageName("example.com/sql/postgres"), "_")
+	fi.Add(AssumedPackageName("example.com/sql/sqlite"), "_")
+	fi.Add(AssumedPackageName("database/sql"), "")
+	fi.Add(AssumedPackageName("example.com/sql/sqlutil"), "")
+	got := fi.Format(true) + "\n"
+	want := `package mypkg
+
+import (
+	"database/sql"
+)
+
+import (
+	"example.com/sql/sqlutil"
+)
+
+// Register the drivers.
+import (
+	_ "example.com/sql/postgres"
+	_ "example.com/sql/sqlite"
+)
+`
+	if got != want {
+		t.Errorf("Format() (got|want):\n%s", debugutil.SideBySide(got, want))
+	}
+	if gofmted, err := format.Source([]byte(got)); err != nil || string(gofmted) != got {
+		t.Errorf("gofmt changed the imports to %q, %v", gofmted, err)
+	}
+}
@@ -0,0 +1,63 @@
+package codegenutil
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// Freeze makes the FileImports immutable and returns it. Reads of a frozen
+// FileImports, such as Find, List and the formatting of symbols whose packages
+// it imports, don't take locks, so many goroutines can share it without
+// contention. Adding a package that isn't imported yet fails with a
+// *FrozenError, and symbols are no longer recorded.
+//
+// A frozen FileImports is usually the base of the FileImports of many files;
+// see WithBase. Freezing also protects files whose import block is written
+// before the rest of their code, such as code appended to an existing file:
+// a symbol of a package that the block doesn't import would make the file
+// uncompilable, so formatting it panics instead.
+func (fi *FileImports) Freeze() *FileImports {
+	// Taking the lock waits for concurrent writes to complete.
+	fi.rwMutex.Lock()
+	defer fi.rwMutex.Unlock()
+	atomic.StoreInt32(&fi.frozen, 1)
+	return fi
+}
+
+// FrozenError is the error of importing a package into a frozen FileImports.
+// It matches ErrImportNotFound.
+type FrozenError struct {
+	// File is the package of the file of the FileImports.
+	File *Package
+	// Package is the package that isn't imported.
+	Package *Package
+	// Symbol is the symbol that was being formatted, if any.
+	Symbol *Symbol
+}
+
+func (e *FrozenError) Error() string {
+	msg := fmt.Sprintf("cannot import %q into a file of package %s after its imports were frozen", e.Package.ImportPath(), e.File.Name())
+	if e.Symbol != nil {
+		msg += fmt.Sprintf(" (formatting %s.%s)", e.Symbol.Package().Name(), e.Symbol.Name())
+	}
+	return msg
+}
+
+// Is reports whether target is ErrImportNotFound.
+func (e *FrozenError) Is(target error) bool { return target == ErrImportNotFound }
+
+func (fi *FileImports) isFrozen() bool {
+	return atomic.LoadInt32(&fi.frozen) == 1
+}
+
+// WithBase returns an option that layers the returned *FileImports on top of
+// base, which is frozen if it isn't already. The imports of base are imports
+// of the file too, and their package names are reserved, but packages that
+// aren't in base are added to the file's own layer. The layer has its own lock
+// so that the workers that generate different files from a shared set of
+// imports don't contend with each other.
+func WithBase(base *FileImports) FileImportsOption {
+	return FileImportsOption{
+		func(fi *FileImports) { fi.base = base.Freeze() },
+	}
+}
@@ -0,0 +1,247 @@
+// Package accessorgen generates getter, setter and "With" methods for the
+// fields of struct types described by go/types.
+//
+// The methods must be added to a file in the package that declares the struct
+// type. For a field "name string" of a type Person, the generated methods are
+// similar to:
+//
+//	// Name returns the value of the name field.
+//	func (p *Person) Name() string { return p.name }
+//
+//	// SetName sets the value of the name field.
+//	func (p *Person) SetName(name string) { p.name = name }
+//
+//	// WithName returns a copy of p with the name field set to name.
+//	func (p Person) WithName(name string) Person { p.name = name; return p }
+package accessorgen
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"strings"
+
+	cb "github.com/meta-programming/go-codegenutil/codebuilder"
+	"github.com/meta-programming/go-codegenutil/naming"
+)
+
+// Kind is a set of kinds of accessor methods.
+type Kind int
+
+const (
+	// Getters are methods that return the value of a field.
+	Getters Kind = 1 << iota
+	// Setters are methods that set the value of a field using a pointer
+	// receiver.
+	Setters
+	// Withers are methods that return a copy of the receiver with the value
+	// of a field replaced.
+	Withers
+)
+
+// Naming determines the names of generated methods. Each function is passed
+// the name of a field and returns the name of the method. A nil function uses
+// the corresponding function of DefaultNaming.
+type Naming struct {
+	Getter, Setter, With func(field string) string
+}
+
+// DefaultNaming names getters after the exported form of the field's name, or
+// "GetName" if the field is already exported, setters "SetName" and withers
+// "WithName".
+var DefaultNaming = Naming{
+	Getter: func(field string) string {
+		if token.IsExported(field) {
+			return "Get" + field
+		}
+		return naming.Exported(field)
+	},
+	Setter: func(field string) string { return "Set" + naming.Exported(field) },
+	With:   func(field string) string { return "With" + naming.Exported(field) },
+}
+
+// Option customizes the methods produced by Generate and GenerateForType.
+type Option struct {
+	apply func(*config)
+}
+
+type config struct {
+	kinds  Kind
+	fields []string
+	naming Naming
+}
+
+// WithKinds returns an option that selects which kinds of methods are
+// generated, for example WithKinds(Getters|Setters). The default is Getters.
+func WithKinds(kinds Kind) Option {
+	return Option{func(c *config) { c.kinds = kinds }}
+}
+
+// WithFields returns an option that generates methods only for the named
+// fields, in the given order. By default, methods are generated for every
+// field that isn't embedded, in declaration order. Embedded fields may be
+// selected using the name of their type.
+func WithFields(names ...string) Option {
+	return Option{func(c *config) { c.fields = append(c.fields, names...) }}
+}
+
+// WithNaming returns an option that customizes the names of generated
+// methods.
+func WithNaming(n Naming) Option {
+	return Option{func(c *config) {
+		if n.Getter != nil {
+			c.naming.Getter = n.Getter
+		}
+		if n.Setter != nil {
+			c.naming.Setter = n.Setter
+		}
+		if n.With != nil {
+			c.naming.With = n.With
+		}
+	}}
+}
+
+// GenerateForType returns accessor methods for the struct type with the given
+// name in pkg, which is usually obtained by loading a package with
+// golang.org/x/tools/go/packages. Type parameters of generic types are included
+// in the receiver type.
+func GenerateForType(pkg *types.Package, typeName string, opts ...Option) ([]cb.Code, error) {
+	obj, ok := pkg.Scope().Lookup(typeName).(*types.TypeName)
+	if !ok {
+		return nil, fmt.Errorf("no type named %q in package %q", typeName, pkg.Path())
+	}
+	named, ok := obj.Type().(*types.Named)
+	if !ok {
+		return nil, fmt.Errorf("%s.%s is not a named type", pkg.Path(), typeName)
+	}
+	st, ok := named.Underlying().(*types.Struct)
+	if !ok {
+		return nil, fmt.Errorf("%s.%s is not a struct type", pkg.Path(), typeName)
+	}
+	recvType := typeName
+	if tparams := named.TypeParams(); tparams.Len() > 0 {
+		var names []string
+		for i := 0; i < tparams.Len(); i++ {
+			names = append(names, tparams.At(i).Obj().Name())
+		}
+		recvType += "[" + strings.Join(names, ", ") + "]"
+	}
+	existing := map[string]bool{}
+	for i := 0; i < named.NumMethods(); i++ {
+		existing[named.Method(i).Name()] = true
+	}
+	return generate(recvType, st, existing, opts)
+}
+
+// Generate returns accessor methods for the fields of st, which is the
+// underlying type of the type named typeName. If the type is generic, typeName
+// should include its type parameters, as in "List[T]".
+func Generate(typeName string, st *types.Struct, opts ...Option) ([]cb.Code, error) {
+	return generate(typeName, st, nil, opts)
+}
+
+func generate(recvType string, st *types.Struct, existingMethods map[string]bool, opts []Option) ([]cb.Code, error) {
+	c := &config{kinds: Getters, naming: DefaultNaming}
+	for _, opt := range opts {
+		opt.apply(c)
+	}
+	fields, err := selectFields(st, c.fields)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", recvType, err)
+	}
+
+	// Generated method names may not collide with each other, the struct's
+	// fields or existing methods.
+	taken := map[string]string{}
+	for name := range existingMethods {
+		taken[name] = "existing method"
+	}
+	for i := 0; i < st.NumFields(); i++ {
+		taken[st.Field(i).Name()] = "field"
+	}
+	claim := func(method string, f *types.Var) error {
+		if what, ok := taken[method]; ok {
+			return fmt.Errorf("%s: method %s for field %s conflicts with %s %s", recvType, method, f.Name(), what, method)
+		}
+		taken[method] = "method"
+		return nil
+	}
+
+	recv := naming.ReceiverName(recvType)
+	var decls []cb.Code
+	for _, f := range fields {
+		typ := cb.TypeOf(f.Type())
+		param := paramName(f.Name(), recv)
+		if c.kinds&Getters != 0 {
+			name := c.naming.Getter(f.Name())
+			if err := claim(name, f); err != nil {
+				return nil, err
+			}
+			decls = append(decls, cb.Func(name).
+				Doc(fmt.Sprintf("%s returns the value of the %s field.", name, f.Name())).
+				Receiver(recv, cb.Raw("*"+recvType)).
+				Results(cb.P("", typ)).
+				Body(cb.Codef("return %s.%s", recv, f.Name())))
+		}
+		if c.kinds&Setters != 0 {
+			name := c.naming.Setter(f.Name())
+			if err := claim(name, f); err != nil {
+				return nil, err
+			}
+			decls = append(decls, cb.Func(name).
+				Doc(fmt.Sprintf("%s sets the value of the %s field.", name, f.Name())).
+				Receiver(recv, cb.Raw("*"+recvType)).
+				Params(cb.P(param, typ)).
+				Body(cb.Codef("%s.%s = %s", recv, f.Name(), param)))
+		}
+		if c.kinds&Withers != 0 {
+			name := c.naming.With(f.Name())
+			if err := claim(name, f); err != nil {
+				return nil, err
+			}
+			decls = append(decls, cb.Func(name).
+				Doc(fmt.Sprintf("%s returns a copy of %s with the %s field set to %s.", name, recv, f.Name(), param)).
+				Receiver(recv, cb.Raw(recvType)).
+				Params(cb.P(param, typ)).
+				Results(cb.P("", cb.Raw(recvType))).
+				Body(cb.Codef("%s.%s = %s", recv, f.Name(), param), cb.Codef("return %s", recv)))
+		}
+	}
+	return decls, nil
+}
+
+// selectFields returns the fields of st with the given names, or all fields
+// that aren't embedded if names is empty.
+func selectFields(st *types.Struct, names []string) ([]*types.Var, error) {
+	var fields []*types.Var
+	if len(names) == 0 {
+		for i := 0; i < st.NumFields(); i++ {
+			if f := st.Field(i); !f.Embedded() {
+				fields = append(fields, f)
+			}
+		}
+		return fields, nil
+	}
+	byName := map[string]*types.Var{}
+	for i := 0; i < st.NumFields(); i++ {
+		byName[st.Field(i).Name()] = st.Field(i)
+	}
+	for _, name := range names {
+		f, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("no field named %q", name)
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// paramName returns the name of the parameter of setters and withers: the
+// unexported form of the field name, or "v" if that isn't usable.
+func paramName(field, recv string) string {
+	name := naming.Unexported(field)
+	if name == recv || name == "_" || token.IsKeyword(name) {
+		return "v"
+	}
+	return name
+}
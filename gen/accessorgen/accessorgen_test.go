@@ -0,0 +1,145 @@
+package accessorgen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/meta-programming/go-codegenutil"
+	cb "github.com/meta-programming/go-codegenutil/codebuilder"
+	"github.com/meta-programming/go-codegenutil/debugutil"
+	"github.com/meta-programming/go-codegenutil/internal/typestest"
+	"github.com/meta-programming/go-codegenutil/naming"
+)
+
+const input = `package mypkg
+
+import "time"
+
+type Person struct {
+	name    string
+	Age     int
+	Timeout time.Duration
+	p       int
+	time.Location
+}
+
+func (p *Person) Name() string { return p.name }
+
+type List[T any] struct {
+	items []T
+}
+`
+
+func TestGenerateForType(t *testing.T) {
+	pkg := typestest.Check(t, "abc.xyz/mypkg", input)
+	tests := []struct {
+		name     string
+		typeName string
+		opts     []Option
+		want     string
+		wantErr  string
+	}{
+		{
+			name:     "all kinds",
+			typeName: "Person",
+			opts:     []Option{WithFields("Timeout", "p"), WithKinds(Getters | Setters | Withers)},
+			want: `package mypkg
+
+import (
+	"time"
+)
+
+// GetTimeout returns the value of the Timeout field.
+func (p *Person) GetTimeout() time.Duration {
+	return p.Timeout
+}
+
+// SetTimeout sets the value of the Timeout field.
+func (p *Person) SetTimeout(timeout time.Duration) {
+	p.Timeout = timeout
+}
+
+// WithTimeout returns a copy of p with the Timeout field set to timeout.
+func (p Person) WithTimeout(timeout time.Duration) Person {
+	p.Timeout = timeout
+	return p
+}
+
+// P returns the value of the p field.
+func (p *Person) P() int {
+	return p.p
+}
+
+// SetP sets the value of the p field.
+func (p *Person) SetP(v int) {
+	p.p = v
+}
+
+// WithP returns a copy of p with the p field set to v.
+func (p Person) WithP(v int) Person {
+	p.p = v
+	return p
+}
+`,
+		},
+		{
+			name:     "generic type with custom naming",
+			typeName: "List",
+			opts: []Option{
+				WithKinds(Setters),
+				WithNaming(Naming{Setter: func(field string) string { return "Replace" + naming.Exported(field) }}),
+			},
+			want: `package mypkg
+
+// ReplaceItems sets the value of the items field.
+func (l *List[T]) ReplaceItems(items []T) {
+	l.items = items
+}
+`,
+		},
+		{
+			name:     "conflict with existing method",
+			typeName: "Person",
+			opts:     []Option{WithFields("name")},
+			wantErr:  "method Name for field name conflicts with existing method Name",
+		},
+		{
+			name:     "conflict with field",
+			typeName: "Person",
+			opts:     []Option{WithFields("Age"), WithNaming(Naming{Getter: func(field string) string { return field }})},
+			wantErr:  "method Age for field Age conflicts with field Age",
+		},
+		{
+			name:     "unknown field",
+			typeName: "Person",
+			opts:     []Option{WithFields("missing")},
+			wantErr:  `no field named "missing"`,
+		},
+		{
+			name:     "not a struct",
+			typeName: "Missing",
+			wantErr:  `no type named "Missing"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decls, err := GenerateForType(pkg, tt.typeName, tt.opts...)
+			if err != nil {
+				if tt.wantErr == "" || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("GenerateForType() got error %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr != "" {
+				t.Fatalf("GenerateForType() succeeded, want error containing %q", tt.wantErr)
+			}
+			got, err := cb.NewFile(codegenutil.AssumedPackageName("abc.xyz/mypkg")).Add(decls...).Render(nil)
+			if err != nil {
+				t.Fatalf("Render() error: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("GenerateForType() got != want:\n%s", debugutil.SideBySide(string(got), tt.want))
+			}
+		})
+	}
+}
@@ -0,0 +1,277 @@
+// Package buildergen generates fluent builder types for structs.
+//
+// For a struct type Person with fields Name and Age, the generated code is
+// similar to:
+//
+//	// PersonBuilder builds Person values.
+//	type PersonBuilder struct { ... }
+//
+//	// NewPersonBuilder returns an empty PersonBuilder.
+//	func NewPersonBuilder() *PersonBuilder { ... }
+//
+//	// Name sets the Name field of the Person being built.
+//	func (b *PersonBuilder) Name(name string) *PersonBuilder { ... }
+//
+//	// Age sets the Age field of the Person being built.
+//	func (b *PersonBuilder) Age(age int) *PersonBuilder { ... }
+//
+//	// Build returns the Person, or an error if a required field is unset or
+//	// validation fails.
+//	func (b *PersonBuilder) Build() (Person, error) { ... }
+//
+// Structs may be described using go/types, or by the *codebuilder.StructBuilder
+// used to generate them. Builders for generic structs have the same type
+// parameters as the struct, so that a List[T] is built by a ListBuilder[T].
+package buildergen
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+
+	"github.com/meta-programming/go-codegenutil"
+	cb "github.com/meta-programming/go-codegenutil/codebuilder"
+	"github.com/meta-programming/go-codegenutil/naming"
+)
+
+// Option customizes the code produced by the Generate functions.
+type Option struct {
+	apply func(*config)
+}
+
+type config struct {
+	builderName string
+	fields      []string
+	required    []string
+	validate    string
+	typeParams  []*cb.Param
+}
+
+// WithBuilderName returns an option that sets the name of the builder type. The
+// default is the struct type's name followed by "Builder".
+func WithBuilderName(name string) Option {
+	return Option{func(c *config) { c.builderName = name }}
+}
+
+// WithFields returns an option that generates setters only for the named
+// fields, in the given order. By default, setters are generated for every
+// field that isn't embedded or blank, in declaration order. Embedded fields may
+// be selected using the name of their type.
+func WithFields(names ...string) Option {
+	return Option{func(c *config) { c.fields = append(c.fields, names...) }}
+}
+
+// WithRequired returns an option that makes Build return an error unless the
+// setters of the named fields have been called.
+func WithRequired(names ...string) Option {
+	return Option{func(c *config) { c.required = append(c.required, names...) }}
+}
+
+// WithValidateMethod returns an option that makes Build call the named method
+// of the struct type, which must have the signature "func() error", and return
+// its error.
+func WithValidateMethod(name string) Option {
+	return Option{func(c *config) { c.validate = name }}
+}
+
+// WithTypeParams returns an option that declares the type parameters of a
+// generic struct type, for use with Generate and GenerateFromBuilder.
+// GenerateForType takes the type parameters from the type itself.
+func WithTypeParams(params ...*cb.Param) Option {
+	return Option{func(c *config) { c.typeParams = append(c.typeParams, params...) }}
+}
+
+// field is a field of the struct that a setter is generated for.
+type field struct {
+	name     string
+	typ      cb.Code
+	embedded bool
+}
+
+// Generate returns the declarations of a builder for st, which is the
+// underlying type of the type named typeName.
+func Generate(typeName string, st *types.Struct, opts ...Option) ([]cb.Code, error) {
+	var fields []field
+	for i := 0; i < st.NumFields(); i++ {
+		f := st.Field(i)
+		fields = append(fields, field{f.Name(), cb.TypeOf(f.Type()), f.Embedded()})
+	}
+	return generate(typeName, fields, opts)
+}
+
+// GenerateForType returns the declarations of a builder for the struct type
+// with the given name in pkg.
+func GenerateForType(pkg *types.Package, typeName string, opts ...Option) ([]cb.Code, error) {
+	obj, ok := pkg.Scope().Lookup(typeName).(*types.TypeName)
+	if !ok {
+		return nil, fmt.Errorf("no type named %q in package %q", typeName, pkg.Path())
+	}
+	named, ok := obj.Type().(*types.Named)
+	if !ok {
+		return nil, fmt.Errorf("%s.%s is not a named type", pkg.Path(), typeName)
+	}
+	st, ok := named.Underlying().(*types.Struct)
+	if !ok {
+		return nil, fmt.Errorf("%s.%s is not a struct type", pkg.Path(), typeName)
+	}
+	if tparams := named.TypeParams(); tparams.Len() > 0 {
+		opts = append([]Option{WithTypeParams(cb.TypeParamsOf(tparams)...)}, opts...)
+	}
+	return Generate(typeName, st, opts...)
+}
+
+// GenerateFromBuilder returns the declarations of a builder for the struct
+// type declared as "type typeName st".
+func GenerateFromBuilder(typeName string, st *cb.StructBuilder, opts ...Option) ([]cb.Code, error) {
+	var fields []field
+	for i := 0; i < st.Len(); i++ {
+		f := st.Field(i)
+		fields = append(fields, field{f.Name(), f.Type(), f.IsEmbedded()})
+	}
+	return generate(typeName, fields, opts)
+}
+
+func generate(typeName string, all []field, opts []Option) ([]cb.Code, error) {
+	c := &config{builderName: typeName + "Builder"}
+	for _, opt := range opts {
+		opt.apply(c)
+	}
+	fields, err := selectFields(all, c.fields)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", typeName, err)
+	}
+	required := map[string]bool{}
+	for _, name := range c.required {
+		if !containsField(fields, name) {
+			return nil, fmt.Errorf("%s: required field %q has no setter", typeName, name)
+		}
+		required[name] = true
+	}
+
+	const recv = "b"
+	var typeArgs []cb.Code
+	for _, p := range c.typeParams {
+		typeArgs = append(typeArgs, cb.Ident(p.Name()))
+	}
+	valueType := cb.Instantiate(cb.Ident(typeName), typeArgs...)
+	builderType := cb.Instantiate(cb.Ident(c.builderName), typeArgs...)
+	methods := map[string]bool{"Build": true}
+	var setters []cb.Code
+	for _, f := range fields {
+		name := naming.Exported(f.name)
+		if methods[name] {
+			return nil, fmt.Errorf("%s: setter %s for field %s conflicts with another method", typeName, name, f.name)
+		}
+		methods[name] = true
+		param := paramName(f.name)
+		body := []cb.Code{cb.Codef("%s.value.%s = %s", recv, f.name, param)}
+		if required[f.name] {
+			body = append(body, cb.Codef("%s.set.%s = true", recv, f.name))
+		}
+		body = append(body, cb.Codef("return %s", recv))
+		setters = append(setters, cb.Func(name).
+			Doc(fmt.Sprintf("%s sets the %s field of the %s being built.", name, f.name, typeName)).
+			Receiver(recv, cb.Codef("*%s", builderType)).
+			Params(cb.P(param, f.typ)).
+			Results(cb.P("", cb.Codef("*%s", builderType))).
+			Body(body...))
+	}
+
+	builderStruct := cb.Struct(cb.F("value", valueType))
+	if len(c.required) > 0 {
+		set := cb.Struct()
+		for _, name := range c.required {
+			set.Fields(cb.F(name, cb.Raw("bool")))
+		}
+		builderStruct.Fields(cb.F("set", set).Doc("set records which required fields have been set."))
+	}
+
+	decls := []cb.Code{
+		cb.TypeDecl(c.builderName, builderStruct).
+			Doc(fmt.Sprintf("%s builds %s values.", c.builderName, typeName)).
+			TypeParams(c.typeParams...),
+		cb.Func("New" + naming.Exported(c.builderName)).
+			Doc(fmt.Sprintf("New%s returns an empty %s.", naming.Exported(c.builderName), c.builderName)).
+			TypeParams(c.typeParams...).
+			Results(cb.P("", cb.Codef("*%s", builderType))).
+			Body(cb.Codef("return &%s{}", builderType)),
+	}
+	decls = append(decls, setters...)
+	return append(decls, buildMethod(c, typeName, valueType, builderType, recv)), nil
+}
+
+func buildMethod(c *config, typeName string, valueType, builderType cb.Code, recv string) cb.Code {
+	errorf := codegenutil.Sym("fmt", "Errorf")
+	doc := fmt.Sprintf("Build returns the %s.", typeName)
+	var body []cb.Code
+	if len(c.required) > 0 {
+		doc = fmt.Sprintf("Build returns the %s, or an error if a required field is unset.", typeName)
+		body = append(body, cb.Raw("var missing []string"))
+		for _, name := range c.required {
+			body = append(body, cb.Codef("if !%s.set.%s {\n\tmissing = append(missing, %q)\n}", recv, name, name))
+		}
+		body = append(body, cb.Codef("if len(missing) > 0 {\n\treturn %s{}, %s(\"%s is missing required fields: %%s\", %s(missing, \", \"))\n}",
+			valueType, errorf, typeName, codegenutil.Sym("strings", "Join")))
+	}
+	if c.validate != "" {
+		if len(c.required) == 0 {
+			doc = fmt.Sprintf("Build returns the %s, or an error if it is invalid.", typeName)
+		} else {
+			doc = fmt.Sprintf("Build returns the %s, or an error if a required field is unset or it is invalid.", typeName)
+		}
+		body = append(body, cb.Codef("if err := %s.value.%s(); err != nil {\n\treturn %s{}, err\n}", recv, c.validate, valueType))
+	}
+	body = append(body, cb.Codef("return %s.value, nil", recv))
+	return cb.Func("Build").
+		Doc(doc).
+		Receiver(recv, cb.Codef("*%s", builderType)).
+		Results(cb.P("", valueType), cb.P("", cb.Raw("error"))).
+		Body(body...)
+}
+
+// selectFields returns the fields with the given names, or all fields that
+// aren't embedded or blank if names is empty.
+func selectFields(all []field, names []string) ([]field, error) {
+	var fields []field
+	if len(names) == 0 {
+		for _, f := range all {
+			if !f.embedded && f.name != "_" {
+				fields = append(fields, f)
+			}
+		}
+		return fields, nil
+	}
+	for _, name := range names {
+		found := false
+		for _, f := range all {
+			if f.name == name {
+				fields = append(fields, f)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("no field named %q", name)
+		}
+	}
+	return fields, nil
+}
+
+func containsField(fields []field, name string) bool {
+	for _, f := range fields {
+		if f.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// paramName returns the name of a setter's parameter: the unexported form of
+// the field name, or "v" if that isn't usable.
+func paramName(field string) string {
+	name := naming.Unexported(field)
+	if name == "b" || name == "_" || token.IsKeyword(name) {
+		return "v"
+	}
+	return name
+}
@@ -0,0 +1,258 @@
+package buildergen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/meta-programming/go-codegenutil"
+	cb "github.com/meta-programming/go-codegenutil/codebuilder"
+	"github.com/meta-programming/go-codegenutil/debugutil"
+	"github.com/meta-programming/go-codegenutil/internal/typestest"
+)
+
+func TestGenerateForType(t *testing.T) {
+	pkg := typestest.Check(t, "abc.xyz/mypkg", `package mypkg
+
+import "time"
+
+type Server struct {
+	Addr    string
+	timeout time.Duration
+	_       int
+}
+
+func (s Server) Validate() error { return nil }
+
+type Pair[K comparable, V any] struct {
+	Key   K
+	Value []V
+}
+`)
+	tests := []struct {
+		name     string
+		typeName string
+		opts     []Option
+		want     string
+		wantErr  string
+	}{
+		{
+			name:     "defaults",
+			typeName: "Server",
+			want: `package mypkg
+
+import (
+	"time"
+)
+
+// ServerBuilder builds Server values.
+type ServerBuilder struct {
+	value Server
+}
+
+// NewServerBuilder returns an empty ServerBuilder.
+func NewServerBuilder() *ServerBuilder {
+	return &ServerBuilder{}
+}
+
+// Addr sets the Addr field of the Server being built.
+func (b *ServerBuilder) Addr(addr string) *ServerBuilder {
+	b.value.Addr = addr
+	return b
+}
+
+// Timeout sets the timeout field of the Server being built.
+func (b *ServerBuilder) Timeout(timeout time.Duration) *ServerBuilder {
+	b.value.timeout = timeout
+	return b
+}
+
+// Build returns the Server.
+func (b *ServerBuilder) Build() (Server, error) {
+	return b.value, nil
+}
+`,
+		},
+		{
+			name:     "required and validated",
+			typeName: "Server",
+			opts:     []Option{WithBuilderName("serverBuilder"), WithFields("Addr"), WithRequired("Addr"), WithValidateMethod("Validate")},
+			want: `package mypkg
+
+import (
+	"fmt"
+	"strings"
+)
+
+// serverBuilder builds Server values.
+type serverBuilder struct {
+	value Server
+	// set records which required fields have been set.
+	set struct {
+		Addr bool
+	}
+}
+
+// NewServerBuilder returns an empty serverBuilder.
+func NewServerBuilder() *serverBuilder {
+	return &serverBuilder{}
+}
+
+// Addr sets the Addr field of the Server being built.
+func (b *serverBuilder) Addr(addr string) *serverBuilder {
+	b.value.Addr = addr
+	b.set.Addr = true
+	return b
+}
+
+// Build returns the Server, or an error if a required field is unset or it is invalid.
+func (b *serverBuilder) Build() (Server, error) {
+	var missing []string
+	if !b.set.Addr {
+		missing = append(missing, "Addr")
+	}
+	if len(missing) > 0 {
+		return Server{}, fmt.Errorf("Server is missing required fields: %s", strings.Join(missing, ", "))
+	}
+	if err := b.value.Validate(); err != nil {
+		return Server{}, err
+	}
+	return b.value, nil
+}
+`,
+		},
+		{
+			name:     "required field without setter",
+			typeName: "Server",
+			opts:     []Option{WithFields("Addr"), WithRequired("timeout")},
+			wantErr:  `required field "timeout" has no setter`,
+		},
+		{
+			name:     "generic",
+			typeName: "Pair",
+			opts:     []Option{WithRequired("Key")},
+			want: `package mypkg
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PairBuilder builds Pair values.
+type PairBuilder[K comparable, V any] struct {
+	value Pair[K, V]
+	// set records which required fields have been set.
+	set struct {
+		Key bool
+	}
+}
+
+// NewPairBuilder returns an empty PairBuilder.
+func NewPairBuilder[K comparable, V any]() *PairBuilder[K, V] {
+	return &PairBuilder[K, V]{}
+}
+
+// Key sets the Key field of the Pair being built.
+func (b *PairBuilder[K, V]) Key(key K) *PairBuilder[K, V] {
+	b.value.Key = key
+	b.set.Key = true
+	return b
+}
+
+// Value sets the Value field of the Pair being built.
+func (b *PairBuilder[K, V]) Value(value []V) *PairBuilder[K, V] {
+	b.value.Value = value
+	return b
+}
+
+// Build returns the Pair, or an error if a required field is unset.
+func (b *PairBuilder[K, V]) Build() (Pair[K, V], error) {
+	var missing []string
+	if !b.set.Key {
+		missing = append(missing, "Key")
+	}
+	if len(missing) > 0 {
+		return Pair[K, V]{}, fmt.Errorf("Pair is missing required fields: %s", strings.Join(missing, ", "))
+	}
+	return b.value, nil
+}
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decls, err := GenerateForType(pkg, tt.typeName, tt.opts...)
+			if err != nil {
+				if tt.wantErr == "" || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("GenerateForType() got error %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr != "" {
+				t.Fatalf("GenerateForType() succeeded, want error containing %q", tt.wantErr)
+			}
+			got, err := cb.NewFile(codegenutil.AssumedPackageName("abc.xyz/mypkg")).Add(decls...).Render(nil)
+			if err != nil {
+				t.Fatalf("Render() error: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("GenerateForType() got != want:\n%s", debugutil.SideBySide(string(got), tt.want))
+			}
+		})
+	}
+}
+
+func TestGenerateFromBuilder(t *testing.T) {
+	st := cb.Struct(
+		cb.F("Name", cb.Raw("string")),
+		cb.Embed(codegenutil.Sym("sync", "Mutex")),
+		cb.F("Deadline", codegenutil.Sym("time", "Time")),
+	)
+	decls, err := GenerateFromBuilder("Job", st, WithFields("Deadline"))
+	if err != nil {
+		t.Fatalf("GenerateFromBuilder() error: %v", err)
+	}
+	got, err := cb.NewFile(codegenutil.AssumedPackageName("abc.xyz/mypkg")).
+		Add(cb.TypeDecl("Job", st)).
+		Add(decls...).
+		Render(nil)
+	if err != nil {
+		t.Fatalf("Render() error: %v", err)
+	}
+	want := `package mypkg
+
+import (
+	"sync"
+	"time"
+)
+
+type Job struct {
+	Name string
+	sync.Mutex
+	Deadline time.Time
+}
+
+// JobBuilder builds Job values.
+type JobBuilder struct {
+	value Job
+}
+
+// NewJobBuilder returns an empty JobBuilder.
+func NewJobBuilder() *JobBuilder {
+	return &JobBuilder{}
+}
+
+// Deadline sets the Deadline field of the Job being built.
+func (b *JobBuilder) Deadline(deadline time.Time) *JobBuilder {
+	b.value.Deadline = deadline
+	return b
+}
+
+// Build returns the Job.
+func (b *JobBuilder) Build() (Job, error) {
+	return b.value, nil
+}
+`
+	if string(got) != want {
+		t.Errorf("GenerateFromBuilder() got != want:\n%s", debugutil.SideBySide(string(got), want))
+	}
+}
@@ -0,0 +1,189 @@
+// Package delegategen generates methods that forward calls to a field of a
+// struct type, for writing wrappers and decorators.
+//
+// For a type Conn with a field "inner net.Conn", forwarding the Close method
+// produces:
+//
+//	// Close calls c.inner.Close.
+//	func (c *Conn) Close() error {
+//		return c.inner.Close()
+//	}
+//
+// With WithHookMethod("observe"), each forwarding method first calls a method
+// of the outer type with the name of the forwarded method and defers the
+// function it returns:
+//
+//	func (c *Conn) Close() error {
+//		defer c.observe("Close")()
+//		return c.inner.Close()
+//	}
+package delegategen
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"strings"
+
+	cb "github.com/meta-programming/go-codegenutil/codebuilder"
+	"github.com/meta-programming/go-codegenutil/naming"
+)
+
+// Option customizes the code produced by GenerateForType.
+type Option struct {
+	apply func(*config)
+}
+
+type config struct {
+	methods    []string
+	hookMethod string
+}
+
+// WithMethods returns an option that forwards only the named methods, in the
+// given order. By default, every exported method of the field's type that the
+// outer type doesn't already declare is forwarded, in alphabetical order.
+func WithMethods(names ...string) Option {
+	return Option{func(c *config) { c.methods = append(c.methods, names...) }}
+}
+
+// WithHookMethod returns an option that instruments each forwarding method with
+// a call to the named method of the outer type, which must have the signature
+// "func(method string) func()". The hook is called before the call is
+// forwarded, and the function it returns is deferred.
+func WithHookMethod(name string) Option {
+	return Option{func(c *config) { c.hookMethod = name }}
+}
+
+// GenerateForType returns methods of the struct type with the given name in
+// pkg that forward to the named field. The methods have pointer receivers.
+func GenerateForType(pkg *types.Package, typeName, field string, opts ...Option) ([]cb.Code, error) {
+	c := &config{}
+	for _, opt := range opts {
+		opt.apply(c)
+	}
+	obj, ok := pkg.Scope().Lookup(typeName).(*types.TypeName)
+	if !ok {
+		return nil, fmt.Errorf("no type named %q in package %q", typeName, pkg.Path())
+	}
+	named, ok := obj.Type().(*types.Named)
+	if !ok || named.TypeParams().Len() > 0 {
+		return nil, fmt.Errorf("%s.%s is not a non-generic named type", pkg.Path(), typeName)
+	}
+	st, ok := named.Underlying().(*types.Struct)
+	if !ok {
+		return nil, fmt.Errorf("%s.%s is not a struct type", pkg.Path(), typeName)
+	}
+	var fieldVar *types.Var
+	for i := 0; i < st.NumFields(); i++ {
+		if st.Field(i).Name() == field {
+			fieldVar = st.Field(i)
+		}
+	}
+	if fieldVar == nil {
+		return nil, fmt.Errorf("%s: no field named %q", typeName, field)
+	}
+
+	declared := map[string]bool{}
+	for i := 0; i < named.NumMethods(); i++ {
+		declared[named.Method(i).Name()] = true
+	}
+	available := methodSet(fieldVar.Type())
+	var methods []*types.Func
+	if len(c.methods) == 0 {
+		for _, m := range available {
+			if m.Exported() && !declared[m.Name()] {
+				methods = append(methods, m)
+			}
+		}
+	} else {
+		byName := map[string]*types.Func{}
+		for _, m := range available {
+			byName[m.Name()] = m
+		}
+		for _, name := range c.methods {
+			m, ok := byName[name]
+			if !ok {
+				return nil, fmt.Errorf("%s: field %s has no method %q", typeName, field, name)
+			}
+			if declared[name] {
+				return nil, fmt.Errorf("%s: method %s is already declared", typeName, name)
+			}
+			methods = append(methods, m)
+		}
+	}
+
+	recv := naming.ReceiverName(typeName)
+	var decls []cb.Code
+	for _, m := range methods {
+		decls = append(decls, forward(c, typeName, recv, field, m))
+	}
+	return decls, nil
+}
+
+// methodSet returns the methods that can be called on an addressable value of
+// type typ, sorted by name.
+func methodSet(typ types.Type) []*types.Func {
+	switch typ.Underlying().(type) {
+	case *types.Interface, *types.Pointer:
+	default:
+		typ = types.NewPointer(typ)
+	}
+	mset := types.NewMethodSet(typ)
+	var methods []*types.Func
+	for i := 0; i < mset.Len(); i++ {
+		methods = append(methods, mset.At(i).Obj().(*types.Func))
+	}
+	return methods
+}
+
+func forward(c *config, typeName, recv, field string, m *types.Func) cb.Code {
+	sig := m.Type().(*types.Signature)
+	var params, results []*cb.Param
+	var args []string
+	for i := 0; i < sig.Params().Len(); i++ {
+		p := sig.Params().At(i)
+		name := p.Name()
+		if name == "" || name == "_" || name == recv || token.IsKeyword(name) {
+			name = fmt.Sprintf("arg%d", i)
+		}
+		if sig.Variadic() && i == sig.Params().Len()-1 {
+			params = append(params, cb.P(name, cb.TypeOf(p.Type().(*types.Slice).Elem())).Variadic())
+			args = append(args, name+"...")
+		} else {
+			params = append(params, cb.P(name, cb.TypeOf(p.Type())))
+			args = append(args, name)
+		}
+	}
+	// Results keep their names unless one of them would be unnamed or shadow
+	// the receiver, since names must be given to all results or none.
+	named := true
+	for i := 0; i < sig.Results().Len(); i++ {
+		if name := sig.Results().At(i).Name(); name == "" || name == recv {
+			named = false
+		}
+	}
+	for i := 0; i < sig.Results().Len(); i++ {
+		r := sig.Results().At(i)
+		name := ""
+		if named {
+			name = r.Name()
+		}
+		results = append(results, cb.P(name, cb.TypeOf(r.Type())))
+	}
+
+	var body []cb.Code
+	if c.hookMethod != "" {
+		body = append(body, cb.Codef("defer %s.%s(%q)()", recv, c.hookMethod, m.Name()))
+	}
+	call := fmt.Sprintf("%s.%s.%s(%s)", recv, field, m.Name(), strings.Join(args, ", "))
+	if len(results) > 0 {
+		call = "return " + call
+	}
+	body = append(body, cb.Raw(call))
+	return cb.Func(m.Name()).
+		Doc(fmt.Sprintf("%s calls %s.%s.%s.", m.Name(), recv, field, m.Name())).
+		Receiver(recv, cb.Raw("*"+typeName)).
+		Params(params...).
+		Results(results...).
+		Body(body...)
+}
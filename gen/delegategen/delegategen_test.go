@@ -0,0 +1,122 @@
+package delegategen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/meta-programming/go-codegenutil"
+	cb "github.com/meta-programming/go-codegenutil/codebuilder"
+	"github.com/meta-programming/go-codegenutil/debugutil"
+	"github.com/meta-programming/go-codegenutil/internal/typestest"
+)
+
+const input = `package wrap
+
+import (
+	"io"
+	"strings"
+)
+
+type Reader struct {
+	inner io.ReadCloser
+}
+
+func (r *Reader) Close() error { return nil }
+
+func (r *Reader) observe(method string) func() { return func() {} }
+
+type Builder struct {
+	sb strings.Builder
+}
+`
+
+func TestGenerateForType(t *testing.T) {
+	pkg := typestest.Check(t, "abc.xyz/wrap", input)
+	tests := []struct {
+		name     string
+		typeName string
+		field    string
+		opts     []Option
+		want     string
+		wantErr  string
+	}{
+		{
+			name:     "interface field skips declared methods",
+			typeName: "Reader",
+			field:    "inner",
+			opts:     []Option{WithHookMethod("observe")},
+			want: `package wrap
+
+// Read calls r.inner.Read.
+func (r *Reader) Read(p []byte) (n int, err error) {
+	defer r.observe("Read")()
+	return r.inner.Read(p)
+}
+`,
+		},
+		{
+			name:     "pointer methods of struct field",
+			typeName: "Builder",
+			field:    "sb",
+			opts:     []Option{WithMethods("WriteString", "Len", "Reset")},
+			want: `package wrap
+
+// WriteString calls b.sb.WriteString.
+func (b *Builder) WriteString(s string) (int, error) {
+	return b.sb.WriteString(s)
+}
+
+// Len calls b.sb.Len.
+func (b *Builder) Len() int {
+	return b.sb.Len()
+}
+
+// Reset calls b.sb.Reset.
+func (b *Builder) Reset() {
+	b.sb.Reset()
+}
+`,
+		},
+		{
+			name:     "already declared",
+			typeName: "Reader",
+			field:    "inner",
+			opts:     []Option{WithMethods("Close")},
+			wantErr:  "method Close is already declared",
+		},
+		{
+			name:     "unknown method",
+			typeName: "Reader",
+			field:    "inner",
+			opts:     []Option{WithMethods("Write")},
+			wantErr:  `field inner has no method "Write"`,
+		},
+		{
+			name:     "unknown field",
+			typeName: "Reader",
+			field:    "outer",
+			wantErr:  `no field named "outer"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decls, err := GenerateForType(pkg, tt.typeName, tt.field, tt.opts...)
+			if err != nil {
+				if tt.wantErr == "" || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("GenerateForType() got error %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr != "" {
+				t.Fatalf("GenerateForType() succeeded, want error containing %q", tt.wantErr)
+			}
+			got, err := cb.NewFile(codegenutil.AssumedPackageName("abc.xyz/wrap")).Add(decls...).Render(nil)
+			if err != nil {
+				t.Fatalf("Render() error: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("GenerateForType() got != want:\n%s", debugutil.SideBySide(string(got), tt.want))
+			}
+		})
+	}
+}
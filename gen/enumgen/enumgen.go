@@ -0,0 +1,297 @@
+// Package enumgen generates enumeration types: a named integer type, a block of
+// constants declared with iota, and methods for printing, validating and
+// parsing values.
+//
+// For example, Generate("Color", Values("Red", "Green"), WithParse()) produces
+// code like the following:
+//
+//	type Color int
+//
+//	const (
+//		Red Color = iota
+//		Green
+//	)
+//
+//	func (c Color) String() string { ... }
+//	func (c Color) IsValid() bool { ... }
+//	func ParseColor(s string) (Color, error) { ... }
+package enumgen
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/meta-programming/go-codegenutil"
+	cb "github.com/meta-programming/go-codegenutil/codebuilder"
+	"github.com/meta-programming/go-codegenutil/naming"
+)
+
+// Value is a member of an enumeration.
+type Value struct {
+	// Name is the name of the generated constant.
+	Name string
+	// String is returned by the String method and accepted by the parse
+	// function. Defaults to Name.
+	String string
+	// Doc is the doc comment of the constant.
+	Doc string
+}
+
+// Values returns a Value for each of the given constant names.
+func Values(names ...string) []Value {
+	values := make([]Value, len(names))
+	for i, name := range names {
+		values[i] = Value{Name: name}
+	}
+	return values
+}
+
+// Option customizes the code produced by Generate.
+type Option struct {
+	apply func(*config)
+}
+
+type config struct {
+	doc          string
+	underlying   cb.Code
+	zeroIsValid  bool
+	genParse     bool
+	genText      bool
+	receiverName string
+	// locals are the names of the parameters and variables of the generated
+	// functions, which are chosen not to shadow the values.
+	locals map[string]string
+}
+
+// WithDoc returns an option that sets the doc comment of the enum type.
+func WithDoc(text string) Option {
+	return Option{func(c *config) { c.doc = text }}
+}
+
+// WithUnderlyingType returns an option that sets the underlying type of the enum
+// type, which must be an integer type. The default is int. Invalid values of
+// the unsigned types uint, uint8, uint16, uint32, uint64, uintptr and byte are
+// printed as unsigned numbers, and those of other types as signed numbers.
+func WithUnderlyingType(typ cb.Code) Option {
+	return Option{func(c *config) { c.underlying = typ }}
+}
+
+// WithInvalidZero returns an option that starts the values at iota + 1 so that
+// the zero value of the enum type is not a valid value.
+func WithInvalidZero() Option {
+	return Option{func(c *config) { c.zeroIsValid = false }}
+}
+
+// WithParse returns an option that generates a ParseT function, where T is the
+// name of the enum type, that returns the value whose String matches its
+// argument.
+func WithParse() Option {
+	return Option{func(c *config) { c.genParse = true }}
+}
+
+// WithText returns an option that generates MarshalText and UnmarshalText
+// methods so the enum is encoded by name in JSON and other text formats. It
+// implies WithParse.
+func WithText() Option {
+	return Option{func(c *config) {
+		c.genParse = true
+		c.genText = true
+	}}
+}
+
+// Generate returns the declarations of an enum type with the given name and
+// values. The declarations are added to a *codebuilder.File with its Add method.
+func Generate(name string, values []Value, opts ...Option) ([]cb.Code, error) {
+	c := &config{underlying: cb.Raw("int"), zeroIsValid: true}
+	for _, opt := range opts {
+		opt.apply(c)
+	}
+	if err := validate(name, values); err != nil {
+		return nil, err
+	}
+	taken := map[string]bool{name: true}
+	for _, v := range values {
+		taken[v.Name] = true
+	}
+	c.receiverName = freeName(naming.ReceiverName(name), taken)
+	c.locals = map[string]string{}
+	for _, local := range []string{"s", "text", "v", "err"} {
+		c.locals[local] = freeName(local, taken)
+	}
+
+	typ := cb.Raw(name)
+	decls := []cb.Code{
+		cb.TypeDecl(name, c.underlying).Doc(c.doc),
+		constBlock(c, typ, values),
+		stringMethod(c, name, values),
+		isValidMethod(c, name, values),
+	}
+	if c.genParse {
+		decls = append(decls, parseFunc(c, name, values))
+	}
+	if c.genText {
+		decls = append(decls, marshalTextMethod(c, name), unmarshalTextMethod(c, name))
+	}
+	return decls, nil
+}
+
+func validate(name string, values []Value) error {
+	if !codegenutil.IsValidIdentifier(name) {
+		return fmt.Errorf("invalid enum type name %q: %w", name, codegenutil.ErrInvalidIdentifier)
+	}
+	if len(values) == 0 {
+		return fmt.Errorf("enum %s has no values", name)
+	}
+	names := map[string]bool{}
+	strs := map[string]bool{}
+	for _, v := range values {
+		if !codegenutil.IsValidIdentifier(v.Name) {
+			return fmt.Errorf("enum %s: invalid value name %q: %w", name, v.Name, codegenutil.ErrInvalidIdentifier)
+		}
+		if names[v.Name] {
+			return fmt.Errorf("enum %s: duplicate value name %q", name, v.Name)
+		}
+		names[v.Name] = true
+		str := valueString(v)
+		if strs[str] {
+			return fmt.Errorf("enum %s: duplicate value string %q", name, str)
+		}
+		strs[str] = true
+	}
+	return nil
+}
+
+// freeName returns name, or name followed by the smallest number from 2 on
+// that makes it free, if it is taken, and takes it.
+func freeName(name string, taken map[string]bool) string {
+	free := name
+	for i := 2; taken[free]; i++ {
+		free = fmt.Sprintf("%s%d", name, i)
+	}
+	taken[free] = true
+	return free
+}
+
+// isUnsigned reports whether typ is one of the predeclared unsigned integer
+// types.
+func isUnsigned(typ cb.Code) bool {
+	switch cb.String(typ) {
+	case "uint", "uint8", "uint16", "uint32", "uint64", "uintptr", "byte":
+		return true
+	}
+	return false
+}
+
+// int64Type returns the 64-bit integer type that holds every value of the enum
+// type.
+func int64Type(c *config) string {
+	if isUnsigned(c.underlying) {
+		return "uint64"
+	}
+	return "int64"
+}
+
+// formatInt returns the expression that formats the integer x of the enum
+// type in base 10.
+func formatInt(c *config, x string) cb.Code {
+	if isUnsigned(c.underlying) {
+		return cb.Codef("%s(uint64(%s), 10)", codegenutil.Sym("strconv", "FormatUint"), x)
+	}
+	return cb.Codef("%s(int64(%s), 10)", codegenutil.Sym("strconv", "FormatInt"), x)
+}
+
+func valueString(v Value) string {
+	if v.String != "" {
+		return v.String
+	}
+	return v.Name
+}
+
+func constBlock(c *config, typ cb.Code, values []Value) cb.Code {
+	first := "iota"
+	if !c.zeroIsValid {
+		first = "iota + 1"
+	}
+	var consts []*cb.ConstBuilder
+	for i, v := range values {
+		constant := cb.Const(v.Name).Doc(v.Doc)
+		if i == 0 {
+			constant.Type(typ).Value(cb.Raw(first))
+		}
+		consts = append(consts, constant)
+	}
+	return cb.Consts(consts...)
+}
+
+func stringMethod(c *config, name string, values []Value) cb.Code {
+	var cases []cb.Code
+	for _, v := range values {
+		cases = append(cases, cb.Codef("case %s:\n\treturn %s", v.Name, strconv.Quote(valueString(v))))
+	}
+	return cb.Func("String").
+		Doc(fmt.Sprintf("String returns the name of the %s value.", name)).
+		Receiver(c.receiverName, cb.Raw(name)).
+		Results(cb.P("", cb.Raw("string"))).
+		Body(
+			cb.Codef("switch %s {", c.receiverName),
+			cb.Lines(cases...),
+			cb.Raw("}"),
+			cb.Codef(`return %q + %s + ")"`, name+"(", formatInt(c, c.receiverName)),
+		)
+}
+
+func isValidMethod(c *config, name string, values []Value) cb.Code {
+	return cb.Func("IsValid").
+		Doc(fmt.Sprintf("IsValid reports whether %s is one of the declared %s values.", c.receiverName, name)).
+		Receiver(c.receiverName, cb.Raw(name)).
+		Results(cb.P("", cb.Raw("bool"))).
+		Body(cb.Codef("return %s >= %s && %s <= %s", c.receiverName, values[0].Name, c.receiverName, values[len(values)-1].Name))
+}
+
+func parseFunc(c *config, name string, values []Value) cb.Code {
+	str := c.locals["s"]
+	var cases []cb.Code
+	for _, v := range values {
+		cases = append(cases, cb.Codef("case %s:\n\treturn %s, nil", strconv.Quote(valueString(v)), v.Name))
+	}
+	return cb.Func("Parse"+name).
+		Doc(fmt.Sprintf("Parse%s returns the %s value whose String method returns %s.", name, name, str)).
+		Params(cb.P(str, cb.Raw("string"))).
+		Results(cb.P("", cb.Raw(name)), cb.P("", cb.Raw("error"))).
+		Body(
+			cb.Codef("switch %s {", str),
+			cb.Lines(cases...),
+			cb.Raw("}"),
+			cb.Codef("return 0, %s(%s, %s)", codegenutil.Sym("fmt", "Errorf"), strconv.Quote("invalid "+name+" %q"), str),
+		)
+}
+
+func marshalTextMethod(c *config, name string) cb.Code {
+	return cb.Func("MarshalText").
+		Doc("MarshalText implements encoding.TextMarshaler.").
+		Receiver(c.receiverName, cb.Raw(name)).
+		Results(cb.P("", cb.Raw("[]byte")), cb.P("", cb.Raw("error"))).
+		Body(
+			cb.Codef("if !%s.IsValid() {", c.receiverName),
+			cb.Codef("\treturn nil, %s(%s, %s(%s))", codegenutil.Sym("fmt", "Errorf"), strconv.Quote("invalid "+name+" %d"), int64Type(c), c.receiverName),
+			cb.Raw("}"),
+			cb.Codef("return []byte(%s.String()), nil", c.receiverName),
+		)
+}
+
+func unmarshalTextMethod(c *config, name string) cb.Code {
+	text, v, err := c.locals["text"], c.locals["v"], c.locals["err"]
+	return cb.Func("UnmarshalText").
+		Doc("UnmarshalText implements encoding.TextUnmarshaler.").
+		Receiver(c.receiverName, cb.Raw("*"+name)).
+		Params(cb.P(text, cb.Raw("[]byte"))).
+		Results(cb.P("", cb.Raw("error"))).
+		Body(
+			cb.Lines(
+				cb.Codef("%s, %s := Parse%s(string(%s))", v, err, name, text),
+				cb.Codef("if %s != nil {\n\treturn %s\n}", err, err),
+				cb.Codef("*%s = %s", c.receiverName, v),
+				cb.Raw("return nil"),
+			),
+		)
+}
@@ -0,0 +1,250 @@
+package enumgen
+
+import (
+	"testing"
+
+	"github.com/meta-programming/go-codegenutil"
+	cb "github.com/meta-programming/go-codegenutil/codebuilder"
+	"github.com/meta-programming/go-codegenutil/debugutil"
+)
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		name    string
+		enum    string
+		values  []Value
+		opts    []Option
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "defaults",
+			enum:   "Color",
+			values: Values("Red", "Green"),
+			want: `package mypkg
+
+import (
+	"strconv"
+)
+
+type Color int
+
+const (
+	Red Color = iota
+	Green
+)
+
+// String returns the name of the Color value.
+func (c Color) String() string {
+	switch c {
+	case Red:
+		return "Red"
+	case Green:
+		return "Green"
+	}
+	return "Color(" + strconv.FormatInt(int64(c), 10) + ")"
+}
+
+// IsValid reports whether c is one of the declared Color values.
+func (c Color) IsValid() bool {
+	return c >= Red && c <= Green
+}
+`,
+		},
+		{
+			name: "all options",
+			enum: "Level",
+			values: []Value{
+				{Name: "LevelLow", String: "low", Doc: "LevelLow is low."},
+				{Name: "LevelHigh", String: "high"},
+			},
+			opts: []Option{
+				WithDoc("Level is a level."),
+				WithUnderlyingType(cb.Raw("uint8")),
+				WithInvalidZero(),
+				WithText(),
+			},
+			want: `package mypkg
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Level is a level.
+type Level uint8
+
+const (
+	// LevelLow is low.
+	LevelLow Level = iota + 1
+	LevelHigh
+)
+
+// String returns the name of the Level value.
+func (l Level) String() string {
+	switch l {
+	case LevelLow:
+		return "low"
+	case LevelHigh:
+		return "high"
+	}
+	return "Level(" + strconv.FormatUint(uint64(l), 10) + ")"
+}
+
+// IsValid reports whether l is one of the declared Level values.
+func (l Level) IsValid() bool {
+	return l >= LevelLow && l <= LevelHigh
+}
+
+// ParseLevel returns the Level value whose String method returns s.
+func ParseLevel(s string) (Level, error) {
+	switch s {
+	case "low":
+		return LevelLow, nil
+	case "high":
+		return LevelHigh, nil
+	}
+	return 0, fmt.Errorf("invalid Level %q", s)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (l Level) MarshalText() ([]byte, error) {
+	if !l.IsValid() {
+		return nil, fmt.Errorf("invalid Level %d", uint64(l))
+	}
+	return []byte(l.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (l *Level) UnmarshalText(text []byte) error {
+	v, err := ParseLevel(string(text))
+	if err != nil {
+		return err
+	}
+	*l = v
+	return nil
+}
+`,
+		},
+		{
+			name:   "values named like locals",
+			enum:   "Cat",
+			values: Values("c", "s", "v", "err", "text", "v2"),
+			opts:   []Option{WithUnderlyingType(cb.Raw("uint")), WithText()},
+			want: `package mypkg
+
+import (
+	"fmt"
+	"strconv"
+)
+
+type Cat uint
+
+const (
+	c Cat = iota
+	s
+	v
+	err
+	text
+	v2
+)
+
+// String returns the name of the Cat value.
+func (c2 Cat) String() string {
+	switch c2 {
+	case c:
+		return "c"
+	case s:
+		return "s"
+	case v:
+		return "v"
+	case err:
+		return "err"
+	case text:
+		return "text"
+	case v2:
+		return "v2"
+	}
+	return "Cat(" + strconv.FormatUint(uint64(c2), 10) + ")"
+}
+
+// IsValid reports whether c2 is one of the declared Cat values.
+func (c2 Cat) IsValid() bool {
+	return c2 >= c && c2 <= v2
+}
+
+// ParseCat returns the Cat value whose String method returns s2.
+func ParseCat(s2 string) (Cat, error) {
+	switch s2 {
+	case "c":
+		return c, nil
+	case "s":
+		return s, nil
+	case "v":
+		return v, nil
+	case "err":
+		return err, nil
+	case "text":
+		return text, nil
+	case "v2":
+		return v2, nil
+	}
+	return 0, fmt.Errorf("invalid Cat %q", s2)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (c2 Cat) MarshalText() ([]byte, error) {
+	if !c2.IsValid() {
+		return nil, fmt.Errorf("invalid Cat %d", uint64(c2))
+	}
+	return []byte(c2.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (c2 *Cat) UnmarshalText(text2 []byte) error {
+	v3, err2 := ParseCat(string(text2))
+	if err2 != nil {
+		return err2
+	}
+	*c2 = v3
+	return nil
+}
+`,
+		},
+		{
+			name:    "no values",
+			enum:    "Color",
+			wantErr: true,
+		},
+		{
+			name:    "invalid value name",
+			enum:    "Color",
+			values:  Values("Red", "dark-red"),
+			wantErr: true,
+		},
+		{
+			name:    "duplicate string",
+			enum:    "Color",
+			values:  []Value{{Name: "Red"}, {Name: "DarkRed", String: "Red"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decls, err := Generate(tt.enum, tt.values, tt.opts...)
+			if gotErr := err != nil; gotErr != tt.wantErr {
+				t.Fatalf("Generate() got error %v, wantErr = %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			got, err := cb.NewFile(codegenutil.AssumedPackageName("abc.xyz/mypkg")).Add(decls...).Render(nil)
+			if err != nil {
+				t.Fatalf("Render() error: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Generate() got != want:\n%s", debugutil.SideBySide(string(got), tt.want))
+			}
+		})
+	}
+}
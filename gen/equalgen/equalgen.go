@@ -0,0 +1,329 @@
+// Package equalgen generates structural Equal and Hash methods for struct
+// types described by go/types.
+//
+// The generated methods have the signatures
+//
+//	func (t T) Equal(other T) bool
+//	func (t T) Hash() uint64
+//
+// Fields are compared with their own Equal method if they have one, with ==
+// if their type is comparable, and element by element for slices, arrays and
+// maps. Fields are hashed with their own Hash method if they have one, and
+// otherwise by kind: numbers, strings and booleans are hashed by value and
+// arrays and slices element by element. Maps, pointers, interfaces, channels
+// and functions can't be hashed; such fields must be ignored or given a custom
+// hash function with WithHasher.
+//
+// Values that are Equal have the same Hash as long as custom comparators and
+// hash functions are consistent with each other.
+package equalgen
+
+import (
+	"fmt"
+	"go/types"
+
+	"github.com/meta-programming/go-codegenutil"
+	cb "github.com/meta-programming/go-codegenutil/codebuilder"
+	"github.com/meta-programming/go-codegenutil/naming"
+)
+
+// Option customizes the code produced by this package.
+type Option struct {
+	apply func(*config)
+}
+
+type config struct {
+	ignore      map[string]bool
+	comparators map[string]cb.Code
+	hashers     map[string]cb.Code
+}
+
+// WithIgnore returns an option that excludes the named fields from Equal and
+// Hash.
+func WithIgnore(fields ...string) Option {
+	return Option{func(c *config) {
+		for _, f := range fields {
+			c.ignore[f] = true
+		}
+	}}
+}
+
+// WithComparator returns an option that makes Equal compare the named field by
+// calling fn, which must be a function of the form "func(a, b T) bool" where T
+// is the field's type.
+func WithComparator(field string, fn cb.Code) Option {
+	return Option{func(c *config) { c.comparators[field] = fn }}
+}
+
+// WithHasher returns an option that makes Hash hash the named field by calling
+// fn, which must be a function of the form "func(v T) uint64" where T is the
+// field's type.
+func WithHasher(field string, fn cb.Code) Option {
+	return Option{func(c *config) { c.hashers[field] = fn }}
+}
+
+// GenerateForType returns the Equal and Hash methods of the struct type with
+// the given name in pkg.
+func GenerateForType(pkg *types.Package, typeName string, opts ...Option) ([]cb.Code, error) {
+	g, err := newGenerator(pkg, typeName, opts)
+	if err != nil {
+		return nil, err
+	}
+	equal, err := g.equalMethod()
+	if err != nil {
+		return nil, err
+	}
+	hash, err := g.hashMethod()
+	if err != nil {
+		return nil, err
+	}
+	return []cb.Code{equal, hash}, nil
+}
+
+// Equal returns the Equal method of the struct type with the given name in pkg.
+func Equal(pkg *types.Package, typeName string, opts ...Option) (cb.Code, error) {
+	g, err := newGenerator(pkg, typeName, opts)
+	if err != nil {
+		return nil, err
+	}
+	return g.equalMethod()
+}
+
+// Hash returns the Hash method of the struct type with the given name in pkg.
+func Hash(pkg *types.Package, typeName string, opts ...Option) (cb.Code, error) {
+	g, err := newGenerator(pkg, typeName, opts)
+	if err != nil {
+		return nil, err
+	}
+	return g.hashMethod()
+}
+
+type generator struct {
+	c     *config
+	pkg   *types.Package
+	named *types.Named
+	st    *types.Struct
+	recv  string
+}
+
+func newGenerator(pkg *types.Package, typeName string, opts []Option) (*generator, error) {
+	c := &config{ignore: map[string]bool{}, comparators: map[string]cb.Code{}, hashers: map[string]cb.Code{}}
+	for _, opt := range opts {
+		opt.apply(c)
+	}
+	obj, ok := pkg.Scope().Lookup(typeName).(*types.TypeName)
+	if !ok {
+		return nil, fmt.Errorf("no type named %q in package %q", typeName, pkg.Path())
+	}
+	named, ok := obj.Type().(*types.Named)
+	if !ok || named.TypeParams().Len() > 0 {
+		return nil, fmt.Errorf("%s.%s is not a non-generic named type", pkg.Path(), typeName)
+	}
+	st, ok := named.Underlying().(*types.Struct)
+	if !ok {
+		return nil, fmt.Errorf("%s.%s is not a struct type", pkg.Path(), typeName)
+	}
+	for _, fields := range []map[string]cb.Code{c.comparators, c.hashers} {
+		for name := range fields {
+			if !hasField(st, name) {
+				return nil, fmt.Errorf("%s: no field named %q", typeName, name)
+			}
+		}
+	}
+	for name := range c.ignore {
+		if !hasField(st, name) {
+			return nil, fmt.Errorf("%s: no field named %q", typeName, name)
+		}
+	}
+	return &generator{c, pkg, named, st, naming.ReceiverName(typeName)}, nil
+}
+
+func hasField(st *types.Struct, name string) bool {
+	for i := 0; i < st.NumFields(); i++ {
+		if st.Field(i).Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// fields returns the fields that aren't ignored or blank.
+func (g *generator) fields() []*types.Var {
+	var fields []*types.Var
+	for i := 0; i < g.st.NumFields(); i++ {
+		if f := g.st.Field(i); !g.c.ignore[f.Name()] && f.Name() != "_" {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+func (g *generator) equalMethod() (cb.Code, error) {
+	typeName := g.named.Obj().Name()
+	var body []cb.Code
+	for _, f := range g.fields() {
+		x, y := g.recv+"."+f.Name(), "other."+f.Name()
+		if fn, ok := g.c.comparators[f.Name()]; ok {
+			body = append(body, cb.Block(cb.Codef("if !%s(%s, %s)", fn, x, y), cb.Raw("return false")))
+			continue
+		}
+		stmts, err := g.equalStmts(f.Type(), x, y, 1)
+		if err != nil {
+			return nil, fmt.Errorf("%s: cannot compare field %s: %w", typeName, f.Name(), err)
+		}
+		body = append(body, stmts...)
+	}
+	body = append(body, cb.Raw("return true"))
+	return cb.Func("Equal").
+		Doc(fmt.Sprintf("Equal reports whether %s and other are equal.", g.recv)).
+		Receiver(g.recv, cb.Raw(typeName)).
+		Params(cb.P("other", cb.Raw(typeName))).
+		Results(cb.P("", cb.Raw("bool"))).
+		Body(body...), nil
+}
+
+// equalStmts returns statements that return false if x and y, which are of
+// type typ, are not equal. Loop variables are numbered with depth to avoid
+// shadowing those of enclosing loops.
+func (g *generator) equalStmts(typ types.Type, x, y string, depth int) ([]cb.Code, error) {
+	returnFalse := cb.Raw("return false")
+	if g.hasMethod(typ, "Equal") {
+		return []cb.Code{cb.Block(cb.Codef("if !%s.Equal(%s)", x, y), returnFalse)}, nil
+	}
+	if types.Comparable(typ) {
+		return []cb.Code{cb.Block(cb.Codef("if %s != %s", x, y), returnFalse)}, nil
+	}
+	switch u := typ.Underlying().(type) {
+	case *types.Slice:
+		return g.equalElems(u.Elem(), x, y, depth, true)
+	case *types.Array:
+		return g.equalElems(u.Elem(), x, y, depth, false)
+	case *types.Map:
+		k, v, w := fmt.Sprintf("k%d", depth), fmt.Sprintf("v%d", depth), fmt.Sprintf("w%d", depth)
+		elem, err := g.equalStmts(u.Elem(), v, w, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		loop := append([]cb.Code{
+			cb.Codef("%s, ok := %s[%s]", w, y, k),
+			cb.Block(cb.Raw("if !ok"), returnFalse),
+		}, elem...)
+		return []cb.Code{
+			cb.Block(cb.Codef("if len(%s) != len(%s)", x, y), returnFalse),
+			cb.Block(cb.Codef("for %s, %s := range %s", k, v, x), loop...),
+		}, nil
+	}
+	return nil, fmt.Errorf("type %s is not comparable and has no Equal method", types.TypeString(typ, types.RelativeTo(g.pkg)))
+}
+
+func (g *generator) equalElems(elem types.Type, x, y string, depth int, checkLen bool) ([]cb.Code, error) {
+	i := fmt.Sprintf("i%d", depth)
+	stmts, err := g.equalStmts(elem, x+"["+i+"]", y+"["+i+"]", depth+1)
+	if err != nil {
+		return nil, err
+	}
+	var out []cb.Code
+	if checkLen {
+		out = append(out, cb.Block(cb.Codef("if len(%s) != len(%s)", x, y), cb.Raw("return false")))
+	}
+	return append(out, cb.Block(cb.Codef("for %s := range %s", i, x), stmts...)), nil
+}
+
+func (g *generator) hashMethod() (cb.Code, error) {
+	typeName := g.named.Obj().Name()
+	body := []cb.Code{
+		cb.Codef("hash := %s()", codegenutil.Sym("hash/fnv", "New64a")),
+		cb.Raw("var buf [8]byte"),
+		cb.Block(cb.Raw("writeUint64 := func(v uint64)"),
+			cb.Codef("%s.PutUint64(buf[:], v)", codegenutil.Sym("encoding/binary", "LittleEndian")),
+			cb.Raw("hash.Write(buf[:])"),
+		),
+	}
+	for _, f := range g.fields() {
+		x := g.recv + "." + f.Name()
+		if fn, ok := g.c.hashers[f.Name()]; ok {
+			body = append(body, cb.Codef("writeUint64(%s(%s))", fn, x))
+			continue
+		}
+		stmts, err := g.hashStmts(f.Type(), x, 1)
+		if err != nil {
+			return nil, fmt.Errorf("%s: cannot hash field %s: %w", typeName, f.Name(), err)
+		}
+		body = append(body, stmts...)
+	}
+	body = append(body, cb.Raw("return hash.Sum64()"))
+	return cb.Func("Hash").
+		Doc(fmt.Sprintf("Hash returns a hash of %s that is the same for values that are Equal.", g.recv)).
+		Receiver(g.recv, cb.Raw(typeName)).
+		Results(cb.P("", cb.Raw("uint64"))).
+		Body(body...), nil
+}
+
+// hashStmts returns statements that write x, which is of type typ, to the
+// hash.
+func (g *generator) hashStmts(typ types.Type, x string, depth int) ([]cb.Code, error) {
+	if g.hasMethod(typ, "Hash") {
+		return []cb.Code{cb.Codef("writeUint64(%s.Hash())", x)}, nil
+	}
+	switch u := typ.Underlying().(type) {
+	case *types.Basic:
+		info := u.Info()
+		switch {
+		case info&types.IsBoolean != 0:
+			return []cb.Code{cb.Codef("if %s {\n\twriteUint64(1)\n} else {\n\twriteUint64(0)\n}", x)}, nil
+		case info&types.IsInteger != 0:
+			return []cb.Code{cb.Codef("writeUint64(uint64(%s))", x)}, nil
+		case info&types.IsFloat != 0:
+			// Positive and negative zero are equal but have different
+			// bits.
+			return []cb.Code{cb.Codef("if %s == 0 {\n\twriteUint64(0)\n} else {\n\twriteUint64(%s(float64(%s)))\n}",
+				x, codegenutil.Sym("math", "Float64bits"), x)}, nil
+		case info&types.IsString != 0:
+			return []cb.Code{
+				cb.Codef("writeUint64(uint64(len(%s)))", x),
+				cb.Codef("%s(hash, string(%s))", codegenutil.Sym("io", "WriteString"), x),
+			}, nil
+		}
+	case *types.Slice:
+		return g.hashElems(u.Elem(), x, depth)
+	case *types.Array:
+		return g.hashElems(u.Elem(), x, depth)
+	}
+	return nil, fmt.Errorf("type %s can't be hashed and has no Hash method", types.TypeString(typ, types.RelativeTo(g.pkg)))
+}
+
+func (g *generator) hashElems(elem types.Type, x string, depth int) ([]cb.Code, error) {
+	v := fmt.Sprintf("v%d", depth)
+	stmts, err := g.hashStmts(elem, v, depth+1)
+	if err != nil {
+		return nil, err
+	}
+	return []cb.Code{
+		cb.Codef("writeUint64(uint64(len(%s)))", x),
+		cb.Block(cb.Codef("for _, %s := range %s", v, x), stmts...),
+	}, nil
+}
+
+// hasMethod reports whether an addressable value of type typ has an Equal or
+// Hash method with the signature generated by this package. The type being
+// generated is assumed to have both.
+func (g *generator) hasMethod(typ types.Type, name string) bool {
+	if types.Identical(typ, g.named) {
+		return true
+	}
+	obj, _, _ := types.LookupFieldOrMethod(typ, true, g.pkg, name)
+	fn, ok := obj.(*types.Func)
+	if !ok {
+		return false
+	}
+	sig := fn.Type().(*types.Signature)
+	switch name {
+	case "Equal":
+		return sig.Params().Len() == 1 && types.Identical(sig.Params().At(0).Type(), typ) &&
+			sig.Results().Len() == 1 && types.Identical(sig.Results().At(0).Type(), types.Typ[types.Bool])
+	case "Hash":
+		return sig.Params().Len() == 0 &&
+			sig.Results().Len() == 1 && types.Identical(sig.Results().At(0).Type(), types.Typ[types.Uint64])
+	}
+	return false
+}
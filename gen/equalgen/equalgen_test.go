@@ -0,0 +1,167 @@
+package equalgen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/meta-programming/go-codegenutil"
+	cb "github.com/meta-programming/go-codegenutil/codebuilder"
+	"github.com/meta-programming/go-codegenutil/debugutil"
+	"github.com/meta-programming/go-codegenutil/internal/typestest"
+)
+
+const input = `package mypkg
+
+import "time"
+
+type ID string
+
+type Node struct {
+	ID       ID
+	Weight   float64
+	Visible  bool
+	Created  time.Time
+	Children []Node
+	Attrs    map[string][]int
+	cache    *int
+	OnChange func()
+}
+`
+
+func TestGenerateForType(t *testing.T) {
+	pkg := typestest.Check(t, "abc.xyz/mypkg", input)
+	tests := []struct {
+		name    string
+		opts    []Option
+		want    string
+		wantErr string
+	}{
+		{
+			name: "overrides",
+			opts: []Option{
+				WithIgnore("cache", "OnChange"),
+				WithComparator("Created", codegenutil.Sym("abc.xyz/mypkg/timeutil", "SameSecond")),
+				WithHasher("Created", codegenutil.Sym("abc.xyz/mypkg/timeutil", "HashSecond")),
+				WithHasher("Attrs", cb.Raw("hashAttrs")),
+			},
+			want: `package mypkg
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"io"
+	"math"
+
+	"abc.xyz/mypkg/timeutil"
+)
+
+// Equal reports whether n and other are equal.
+func (n Node) Equal(other Node) bool {
+	if n.ID != other.ID {
+		return false
+	}
+	if n.Weight != other.Weight {
+		return false
+	}
+	if n.Visible != other.Visible {
+		return false
+	}
+	if !timeutil.SameSecond(n.Created, other.Created) {
+		return false
+	}
+	if len(n.Children) != len(other.Children) {
+		return false
+	}
+	for i1 := range n.Children {
+		if !n.Children[i1].Equal(other.Children[i1]) {
+			return false
+		}
+	}
+	if len(n.Attrs) != len(other.Attrs) {
+		return false
+	}
+	for k1, v1 := range n.Attrs {
+		w1, ok := other.Attrs[k1]
+		if !ok {
+			return false
+		}
+		if len(v1) != len(w1) {
+			return false
+		}
+		for i2 := range v1 {
+			if v1[i2] != w1[i2] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Hash returns a hash of n that is the same for values that are Equal.
+func (n Node) Hash() uint64 {
+	hash := fnv.New64a()
+	var buf [8]byte
+	writeUint64 := func(v uint64) {
+		binary.LittleEndian.PutUint64(buf[:], v)
+		hash.Write(buf[:])
+	}
+	writeUint64(uint64(len(n.ID)))
+	io.WriteString(hash, string(n.ID))
+	if n.Weight == 0 {
+		writeUint64(0)
+	} else {
+		writeUint64(math.Float64bits(float64(n.Weight)))
+	}
+	if n.Visible {
+		writeUint64(1)
+	} else {
+		writeUint64(0)
+	}
+	writeUint64(timeutil.HashSecond(n.Created))
+	writeUint64(uint64(len(n.Children)))
+	for _, v1 := range n.Children {
+		writeUint64(v1.Hash())
+	}
+	writeUint64(hashAttrs(n.Attrs))
+	return hash.Sum64()
+}
+`,
+		},
+		{
+			name:    "function field",
+			opts:    []Option{WithIgnore("cache"), WithHasher("Attrs", cb.Raw("hashAttrs"))},
+			wantErr: "cannot compare field OnChange: type func() is not comparable",
+		},
+		{
+			name:    "pointer field",
+			opts:    []Option{WithIgnore("OnChange", "Created"), WithHasher("Attrs", cb.Raw("hashAttrs"))},
+			wantErr: "cannot hash field cache: type *int can't be hashed",
+		},
+		{
+			name:    "unknown field",
+			opts:    []Option{WithIgnore("missing")},
+			wantErr: `no field named "missing"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decls, err := GenerateForType(pkg, "Node", tt.opts...)
+			if err != nil {
+				if tt.wantErr == "" || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("GenerateForType() got error %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr != "" {
+				t.Fatalf("GenerateForType() succeeded, want error containing %q", tt.wantErr)
+			}
+			got, err := cb.NewFile(codegenutil.AssumedPackageName("abc.xyz/mypkg")).Add(decls...).Render(nil)
+			if err != nil {
+				t.Fatalf("Render() error: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("GenerateForType() got != want:\n%s", debugutil.SideBySide(string(got), tt.want))
+			}
+		})
+	}
+}
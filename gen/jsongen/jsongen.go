@@ -0,0 +1,218 @@
+// Package jsongen generates MarshalJSON and UnmarshalJSON methods for types
+// that need custom JSON handling: structs that reject unknown fields, enums
+// encoded as strings and discriminated unions of struct types.
+package jsongen
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/meta-programming/go-codegenutil"
+	cb "github.com/meta-programming/go-codegenutil/codebuilder"
+	"github.com/meta-programming/go-codegenutil/naming"
+)
+
+var (
+	jsonMarshal    = codegenutil.Sym("encoding/json", "Marshal")
+	jsonUnmarshal  = codegenutil.Sym("encoding/json", "Unmarshal")
+	jsonRawMessage = codegenutil.Sym("encoding/json", "RawMessage")
+	jsonNewDecoder = codegenutil.Sym("encoding/json", "NewDecoder")
+	bytesNewReader = codegenutil.Sym("bytes", "NewReader")
+	fmtErrorf      = codegenutil.Sym("fmt", "Errorf")
+)
+
+// Strict returns an UnmarshalJSON method for the struct type with the given
+// name that fails if the input contains fields that don't belong to the
+// struct.
+func Strict(typeName string) cb.Code {
+	recv := naming.ReceiverName(typeName)
+	return cb.Func("UnmarshalJSON").
+		Doc("UnmarshalJSON implements json.Unmarshaler. Unknown fields are an error.").
+		Receiver(recv, cb.Raw("*"+typeName)).
+		Params(cb.P("data", cb.Raw("[]byte"))).
+		Results(cb.P("", cb.Raw("error"))).
+		Body(
+			cb.Codef("// plain has the fields of %s but not its methods, which", typeName),
+			cb.Raw("// prevents the decoder from calling UnmarshalJSON recursively."),
+			cb.Codef("type plain %s", typeName),
+			cb.Codef("dec := %s(%s(data))", jsonNewDecoder, bytesNewReader),
+			cb.Raw("dec.DisallowUnknownFields()"),
+			cb.Codef("return dec.Decode((*plain)(%s))", recv),
+		)
+}
+
+// EnumValue associates a constant with the JSON string that represents it.
+type EnumValue struct {
+	// Const is the name of the constant.
+	Const string
+	// JSON is the string that represents the constant in JSON.
+	JSON string
+}
+
+// Enum returns MarshalJSON and UnmarshalJSON methods for the enum type with the
+// given name that encode its values as JSON strings. Values not in the list are
+// errors in both directions.
+func Enum(typeName string, values []EnumValue) ([]cb.Code, error) {
+	if len(values) == 0 {
+		return nil, fmt.Errorf("enum %s has no values", typeName)
+	}
+	recv := naming.ReceiverName(typeName)
+	seen := map[string]bool{}
+	var marshalCases, unmarshalCases []cb.Code
+	for _, v := range values {
+		if seen[v.JSON] {
+			return nil, fmt.Errorf("enum %s: duplicate JSON string %q", typeName, v.JSON)
+		}
+		seen[v.JSON] = true
+		encoded, err := json.Marshal(v.JSON)
+		if err != nil {
+			return nil, err
+		}
+		marshalCases = append(marshalCases, cb.Codef("case %s:\n\treturn []byte(%s), nil", v.Const, strconv.Quote(string(encoded))))
+		unmarshalCases = append(unmarshalCases, cb.Codef("case %s:\n\t*%s = %s", strconv.Quote(v.JSON), recv, v.Const))
+	}
+	marshal := cb.Func("MarshalJSON").
+		Doc("MarshalJSON implements json.Marshaler.").
+		Receiver(recv, cb.Raw(typeName)).
+		Results(cb.P("", cb.Raw("[]byte")), cb.P("", cb.Raw("error"))).
+		Body(
+			cb.Codef("switch %s {", recv),
+			cb.Lines(marshalCases...),
+			cb.Raw("}"),
+			cb.Codef("return nil, %s(%s)", fmtErrorf, strconv.Quote("cannot marshal invalid "+typeName+" value")),
+		)
+	unmarshal := cb.Func("UnmarshalJSON").
+		Doc("UnmarshalJSON implements json.Unmarshaler.").
+		Receiver(recv, cb.Raw("*"+typeName)).
+		Params(cb.P("data", cb.Raw("[]byte"))).
+		Results(cb.P("", cb.Raw("error"))).
+		Body(
+			cb.Raw("var text string"),
+			cb.Codef("if err := %s(data, &text); err != nil {\n\treturn err\n}", jsonUnmarshal),
+			cb.Raw("switch text {"),
+			cb.Lines(unmarshalCases...),
+			cb.Codef("default:\n\treturn %s(%s, text)", fmtErrorf, strconv.Quote("invalid "+typeName+" %q")),
+			cb.Raw("}"),
+			cb.Raw("return nil"),
+		)
+	return []cb.Code{marshal, unmarshal}, nil
+}
+
+// Union describes a discriminated union: a struct type with a single field,
+// Value, holding one of several variant types, which is encoded as the JSON
+// object of the variant with an added discriminator field that identifies the
+// variant.
+type Union struct {
+	// Name is the name of the generated union type.
+	Name string
+	// Doc is the doc comment of the union type.
+	Doc string
+	// Interface is the type of the Value field, which all variants
+	// implement. Use Raw("any") if the variants have no methods in common.
+	Interface cb.Code
+	// Discriminator is the name of the JSON field that identifies the
+	// variant. Variants should not have a field with the same name.
+	Discriminator string
+	// Variants are the possible types of Value.
+	Variants []Variant
+}
+
+// Variant is one of the types a Union may hold.
+type Variant struct {
+	// Tag is the value of the discriminator field for this variant.
+	Tag string
+	// Type is the type of the variant, usually a struct type.
+	Type cb.Code
+	// Pointer indicates that Value holds pointers to Type.
+	Pointer bool
+}
+
+// Generate returns the declarations of the union type and its MarshalJSON and
+// UnmarshalJSON methods.
+func (u *Union) Generate() ([]cb.Code, error) {
+	if !codegenutil.IsValidIdentifier(u.Name) {
+		return nil, fmt.Errorf("invalid union type name %q: %w", u.Name, codegenutil.ErrInvalidIdentifier)
+	}
+	if u.Discriminator == "" {
+		return nil, fmt.Errorf("union %s has no discriminator", u.Name)
+	}
+	if len(u.Variants) == 0 {
+		return nil, fmt.Errorf("union %s has no variants", u.Name)
+	}
+	seen := map[string]bool{}
+	for _, v := range u.Variants {
+		if seen[v.Tag] {
+			return nil, fmt.Errorf("union %s: duplicate tag %q", u.Name, v.Tag)
+		}
+		seen[v.Tag] = true
+	}
+	recv := naming.ReceiverName(u.Name)
+	decl := cb.TypeDecl(u.Name, cb.Struct(cb.F("Value", u.Interface))).Doc(u.Doc)
+	return []cb.Code{decl, u.marshal(recv), u.unmarshal(recv)}, nil
+}
+
+func (u *Union) marshal(recv string) cb.Code {
+	var cases []cb.Code
+	for _, v := range u.Variants {
+		cases = append(cases, cb.Codef("case %s:\n\ttag = %s", variantType(v), strconv.Quote(v.Tag)))
+	}
+	return cb.Func("MarshalJSON").
+		Doc(fmt.Sprintf("MarshalJSON implements json.Marshaler. The JSON object of the value has an\nadditional %q field that identifies its type.", u.Discriminator)).
+		Receiver(recv, cb.Raw(u.Name)).
+		Results(cb.P("", cb.Raw("[]byte")), cb.P("", cb.Raw("error"))).
+		Body(
+			cb.Raw("var tag string"),
+			cb.Codef("switch %s.Value.(type) {", recv),
+			cb.Raw("case nil:\n\treturn []byte(\"null\"), nil"),
+			cb.Lines(cases...),
+			cb.Codef("default:\n\treturn nil, %s(\"%s: unsupported value type %%T\", %s.Value)", fmtErrorf, u.Name, recv),
+			cb.Raw("}"),
+			cb.Codef("data, err := %s(%s.Value)", jsonMarshal, recv),
+			cb.Raw("if err != nil {\n\treturn nil, err\n}"),
+			// A nil pointer variant has no fields to add the tag to.
+			cb.Raw("if string(data) == \"null\" {\n\treturn data, nil\n}"),
+			cb.Codef("var fields map[string]%s", jsonRawMessage),
+			cb.Codef("if err := %s(data, &fields); err != nil {\n\treturn nil, err\n}", jsonUnmarshal),
+			cb.Codef("fields[%s], _ = %s(tag)", strconv.Quote(u.Discriminator), jsonMarshal),
+			cb.Codef("return %s(fields)", jsonMarshal),
+		)
+}
+
+func (u *Union) unmarshal(recv string) cb.Code {
+	head := cb.Struct(cb.F("Tag", cb.Raw("string")).Tag("json", u.Discriminator))
+	var cases []cb.Code
+	for _, v := range u.Variants {
+		var decode cb.Code
+		if v.Pointer {
+			decode = cb.Codef("variant := &%s{}\nif err := %s(data, variant); err != nil {\n\treturn err\n}", v.Type, jsonUnmarshal)
+		} else {
+			decode = cb.Codef("var variant %s\nif err := %s(data, &variant); err != nil {\n\treturn err\n}", v.Type, jsonUnmarshal)
+		}
+		cases = append(cases, cb.Codef("case %s:", strconv.Quote(v.Tag)), decode, cb.Codef("%s.Value = variant", recv))
+	}
+	return cb.Func("UnmarshalJSON").
+		Doc("UnmarshalJSON implements json.Unmarshaler.").
+		Receiver(recv, cb.Raw("*"+u.Name)).
+		Params(cb.P("data", cb.Raw("[]byte"))).
+		Results(cb.P("", cb.Raw("error"))).
+		Body(
+			cb.Raw(`if string(data) == "null" {`),
+			cb.Codef("\t%s.Value = nil\n\treturn nil", recv),
+			cb.Raw("}"),
+			cb.Codef("var head %s", head),
+			cb.Codef("if err := %s(data, &head); err != nil {\n\treturn err\n}", jsonUnmarshal),
+			cb.Raw("switch head.Tag {"),
+			cb.Lines(cases...),
+			cb.Codef("default:\n\treturn %s(%s, head.Tag)", fmtErrorf, strconv.Quote(u.Name+": unknown "+u.Discriminator+" %q")),
+			cb.Raw("}"),
+			cb.Raw("return nil"),
+		)
+}
+
+func variantType(v Variant) cb.Code {
+	if v.Pointer {
+		return cb.Codef("*%s", v.Type)
+	}
+	return v.Type
+}
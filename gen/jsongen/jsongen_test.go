@@ -0,0 +1,245 @@
+package jsongen
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/meta-programming/go-codegenutil"
+	cb "github.com/meta-programming/go-codegenutil/codebuilder"
+	"github.com/meta-programming/go-codegenutil/debugutil"
+)
+
+func render(t *testing.T, decls ...cb.Code) string {
+	t.Helper()
+	got, err := cb.NewFile(codegenutil.AssumedPackageName("abc.xyz/shapes")).Add(decls...).Render(nil)
+	if err != nil {
+		t.Fatalf("Render() error: %v", err)
+	}
+	return string(got)
+}
+
+func TestStrict(t *testing.T) {
+	got := render(t, Strict("Config"))
+	want := `package shapes
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// UnmarshalJSON implements json.Unmarshaler. Unknown fields are an error.
+func (c *Config) UnmarshalJSON(data []byte) error {
+	// plain has the fields of Config but not its methods, which
+	// prevents the decoder from calling UnmarshalJSON recursively.
+	type plain Config
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode((*plain)(c))
+}
+`
+	if got != want {
+		t.Errorf("Strict() got != want:\n%s", debugutil.SideBySide(got, want))
+	}
+}
+
+func TestEnum(t *testing.T) {
+	decls, err := Enum("Color", []EnumValue{{"Red", "red"}, {"DarkRed", `dark "red"`}})
+	if err != nil {
+		t.Fatalf("Enum() error: %v", err)
+	}
+	got := render(t, decls...)
+	want := `package shapes
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// MarshalJSON implements json.Marshaler.
+func (c Color) MarshalJSON() ([]byte, error) {
+	switch c {
+	case Red:
+		return []byte("\"red\""), nil
+	case DarkRed:
+		return []byte("\"dark \\\"red\\\"\""), nil
+	}
+	return nil, fmt.Errorf("cannot marshal invalid Color value")
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (c *Color) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	switch text {
+	case "red":
+		*c = Red
+	case "dark \"red\"":
+		*c = DarkRed
+	default:
+		return fmt.Errorf("invalid Color %q", text)
+	}
+	return nil
+}
+`
+	if got != want {
+		t.Errorf("Enum() got != want:\n%s", debugutil.SideBySide(got, want))
+	}
+
+	if _, err := Enum("Color", []EnumValue{{"Red", "red"}, {"Crimson", "red"}}); err == nil || !strings.Contains(err.Error(), "duplicate") {
+		t.Errorf("Enum() with duplicate strings got error %v, want duplicate error", err)
+	}
+}
+
+func TestUnion_Generate(t *testing.T) {
+	u := &Union{
+		Name:          "AnyShape",
+		Doc:           "AnyShape holds any Shape.",
+		Interface:     codegenutil.Sym("abc.xyz/shapes", "Shape"),
+		Discriminator: "kind",
+		Variants: []Variant{
+			{Tag: "circle", Type: codegenutil.Sym("abc.xyz/shapes", "Circle"), Pointer: true},
+			{Tag: "square", Type: codegenutil.Sym("abc.xyz/shapes/square", "Square")},
+		},
+	}
+	decls, err := u.Generate()
+	if err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+	got := render(t, decls...)
+	want := `package shapes
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"abc.xyz/shapes/square"
+)
+
+// AnyShape holds any Shape.
+type AnyShape struct {
+	Value Shape
+}
+
+// MarshalJSON implements json.Marshaler. The JSON object of the value has an
+// additional "kind" field that identifies its type.
+func (a AnyShape) MarshalJSON() ([]byte, error) {
+	var tag string
+	switch a.Value.(type) {
+	case nil:
+		return []byte("null"), nil
+	case *Circle:
+		tag = "circle"
+	case square.Square:
+		tag = "square"
+	default:
+		return nil, fmt.Errorf("AnyShape: unsupported value type %T", a.Value)
+	}
+	data, err := json.Marshal(a.Value)
+	if err != nil {
+		return nil, err
+	}
+	if string(data) == "null" {
+		return data, nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	fields["kind"], _ = json.Marshal(tag)
+	return json.Marshal(fields)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (a *AnyShape) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		a.Value = nil
+		return nil
+	}
+	var head struct {
+		Tag string ` + "`json:\"kind\"`" + `
+	}
+	if err := json.Unmarshal(data, &head); err != nil {
+		return err
+	}
+	switch head.Tag {
+	case "circle":
+		variant := &Circle{}
+		if err := json.Unmarshal(data, variant); err != nil {
+			return err
+		}
+		a.Value = variant
+	case "square":
+		var variant square.Square
+		if err := json.Unmarshal(data, &variant); err != nil {
+			return err
+		}
+		a.Value = variant
+	default:
+		return fmt.Errorf("AnyShape: unknown kind %q", head.Tag)
+	}
+	return nil
+}
+`
+	if got != want {
+		t.Errorf("Generate() got != want:\n%s", debugutil.SideBySide(got, want))
+	}
+
+	u.Variants = append(u.Variants, Variant{Tag: "circle", Type: cb.Raw("Ellipse")})
+	if _, err := u.Generate(); err == nil || !strings.Contains(err.Error(), `duplicate tag "circle"`) {
+		t.Errorf("Generate() with duplicate tags got error %v, want duplicate tag error", err)
+	}
+}
+
+func TestUnion_Generate_nilPointer(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs go test on the generated code")
+	}
+	u := &Union{
+		Name:          "AnyShape",
+		Interface:     cb.Raw("any"),
+		Discriminator: "kind",
+		Variants:      []Variant{{Tag: "circle", Type: cb.Raw("Circle"), Pointer: true}},
+	}
+	decls, err := u.Generate()
+	if err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":    "module abc.xyz/shapes\n\ngo 1.18\n",
+		"shapes.go": render(t, append(decls, cb.TypeDecl("Circle", cb.Struct(cb.F("R", cb.Raw("int")))))...),
+		"shapes_test.go": `package shapes
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestNilPointer(t *testing.T) {
+	data, err := json.Marshal(AnyShape{Value: (*Circle)(nil)})
+	if err != nil || string(data) != "null" {
+		t.Fatalf("Marshal() = %s, %v, want null", data, err)
+	}
+	var got AnyShape
+	if err := json.Unmarshal(data, &got); err != nil || got.Value != nil {
+		t.Fatalf("Unmarshal(%s) = %v, %v, want nil", data, got.Value, err)
+	}
+}
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cmd := exec.Command("go", "test", ".")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("go test on the generated code failed: %v\n%s", err, out)
+	}
+}
@@ -0,0 +1,238 @@
+// Package mockgen generates test doubles for interfaces described by go/types.
+//
+// For an interface Store with a method "Get(key string) (string, error)", the
+// generated mock is similar to:
+//
+//	// MockStore is a test double for Store.
+//	type MockStore struct {
+//		// GetFunc implements Get. If it is nil, Get returns zero values.
+//		GetFunc func(key string) (string, error)
+//		// GetCalls records the arguments of each call to Get.
+//		GetCalls []MockStoreGetCall
+//		...
+//	}
+//
+//	// MockStoreGetCall holds the arguments of a call to MockStore.Get.
+//	type MockStoreGetCall struct {
+//		Key string
+//	}
+//
+//	func (m *MockStore) Get(key string) (r0 string, r1 error) { ... }
+//
+// Mocks are safe for concurrent use, except that the Func fields should be set
+// before the mock is used.
+package mockgen
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"github.com/meta-programming/go-codegenutil"
+	cb "github.com/meta-programming/go-codegenutil/codebuilder"
+	"github.com/meta-programming/go-codegenutil/naming"
+)
+
+// Option customizes the code produced by Generate and GenerateForType.
+type Option struct {
+	apply func(*config)
+}
+
+type config struct {
+	mockName string
+}
+
+// WithMockName returns an option that sets the name of the mock type. The
+// default is "Mock" followed by the exported form of the interface's name.
+func WithMockName(name string) Option {
+	return Option{func(c *config) { c.mockName = name }}
+}
+
+// GenerateForType returns the declarations of a mock of the interface type with
+// the given name in pkg. The mock may be generated in any package that can
+// refer to the interface's methods and their parameter types.
+func GenerateForType(pkg *types.Package, typeName string, opts ...Option) ([]cb.Code, error) {
+	obj, ok := pkg.Scope().Lookup(typeName).(*types.TypeName)
+	if !ok {
+		return nil, fmt.Errorf("no type named %q in package %q", typeName, pkg.Path())
+	}
+	if named, ok := obj.Type().(*types.Named); ok && named.TypeParams().Len() > 0 {
+		return nil, fmt.Errorf("%s.%s: generic interfaces are not supported", pkg.Path(), typeName)
+	}
+	iface, ok := obj.Type().Underlying().(*types.Interface)
+	if !ok {
+		return nil, fmt.Errorf("%s.%s is not an interface type", pkg.Path(), typeName)
+	}
+	return Generate(typeName, iface, opts...)
+}
+
+// Generate returns the declarations of a mock of iface, which is the underlying
+// type of the type named typeName.
+func Generate(typeName string, iface *types.Interface, opts ...Option) ([]cb.Code, error) {
+	c := &config{mockName: "Mock" + naming.Exported(typeName)}
+	for _, opt := range opts {
+		opt.apply(c)
+	}
+	if !iface.IsMethodSet() {
+		return nil, fmt.Errorf("%s is a constraint interface and can't be mocked", typeName)
+	}
+
+	mock := cb.Struct(cb.F("mu", codegenutil.Sym("sync", "Mutex")))
+	var decls []cb.Code
+	var methods []cb.Code
+	for i := 0; i < iface.NumMethods(); i++ {
+		m := iface.Method(i)
+		sig := m.Type().(*types.Signature)
+		params, results := methodVars(sig)
+		callType := c.mockName + m.Name() + "Call"
+
+		mock.Fields(
+			cb.F(m.Name()+"Func", funcType(params, results)).
+				Doc(fmt.Sprintf("%sFunc implements %s. If it is nil, %s returns zero values.", m.Name(), m.Name(), m.Name())),
+			cb.F(m.Name()+"Calls", cb.Raw("[]"+callType)).
+				Doc(fmt.Sprintf("%sCalls records the arguments of each call to %s.", m.Name(), m.Name())),
+		)
+
+		call := cb.Struct()
+		fieldNames := map[string]bool{}
+		for i, p := range params {
+			// Parameters such as id and ID have the same exported name.
+			name := naming.Exported(p.name)
+			for k := i; fieldNames[name]; k++ {
+				name = fmt.Sprintf("Arg%d", k)
+			}
+			fieldNames[name] = true
+			call.Fields(cb.F(name, p.typ))
+		}
+		decls = append(decls, cb.TypeDecl(callType, call).
+			Doc(fmt.Sprintf("%s holds the arguments of a call to %s.%s.", callType, c.mockName, m.Name())))
+		methods = append(methods, mockMethod(c, m.Name(), callType, params, results))
+	}
+	decls = append([]cb.Code{
+		cb.TypeDecl(c.mockName, mock).Doc(fmt.Sprintf("%s is a test double for %s.", c.mockName, typeName)),
+	}, decls...)
+	return append(decls, methods...), nil
+}
+
+func mockMethod(c *config, name, callType string, params, results []*variable) cb.Code {
+	var paramCode, resultCode []*cb.Param
+	var callArgs, fnArgs []string
+	for _, p := range params {
+		fnArg := p.name
+		if p.variadic {
+			fnArg += "..."
+		}
+		paramCode = append(paramCode, p.param(true))
+		callArgs = append(callArgs, p.name)
+		fnArgs = append(fnArgs, fnArg)
+	}
+	for _, r := range results {
+		resultCode = append(resultCode, r.param(true))
+	}
+	var body []cb.Code
+	body = append(body,
+		cb.Raw("m.mu.Lock()"),
+		cb.Codef("m.%sCalls = append(m.%sCalls, %s{%s})", name, name, callType, strings.Join(callArgs, ", ")),
+		cb.Codef("fn := m.%sFunc", name),
+		cb.Raw("m.mu.Unlock()"),
+	)
+	if len(results) == 0 {
+		body = append(body, cb.Codef("if fn != nil {\n\tfn(%s)\n}", strings.Join(fnArgs, ", ")))
+	} else {
+		body = append(body,
+			cb.Raw("if fn == nil {\n\treturn\n}"),
+			cb.Codef("return fn(%s)", strings.Join(fnArgs, ", ")))
+	}
+	return cb.Func(name).
+		Doc(fmt.Sprintf("%s records the call and calls %sFunc.", name, name)).
+		Receiver("m", cb.Raw("*"+c.mockName)).
+		Params(paramCode...).
+		Results(resultCode...).
+		Body(body...)
+}
+
+// variable is a parameter or result of a mocked method.
+type variable struct {
+	name string
+	// typ is the type of the variable, which is a slice for variadic
+	// parameters.
+	typ      cb.Code
+	variadic bool
+	// elem is the element type of a variadic parameter.
+	elem cb.Code
+}
+
+// param returns the variable as it appears in a signature.
+func (v *variable) param(named bool) *cb.Param {
+	name := ""
+	if named {
+		name = v.name
+	}
+	if v.variadic {
+		return cb.P(name, v.elem).Variadic()
+	}
+	return cb.P(name, v.typ)
+}
+
+// methodVars returns the parameters and results of sig with names that are
+// usable in the generated method: unnamed and blank variables and those that
+// collide with identifiers used by the method body or with other variables are
+// given names made of "arg" or "r" and their index.
+func methodVars(sig *types.Signature) (params, results []*variable) {
+	taken := map[string]bool{"m": true, "fn": true}
+	// The names of the signature are kept if they are usable, so they are
+	// taken before any name is made up.
+	usable := func(tuple *types.Tuple) []bool {
+		ok := make([]bool, tuple.Len())
+		for i := range ok {
+			name := tuple.At(i).Name()
+			if name != "" && name != "_" && !taken[name] && !token.IsKeyword(name) {
+				ok[i] = true
+				taken[name] = true
+			}
+		}
+		return ok
+	}
+	paramsUsable, resultsUsable := usable(sig.Params()), usable(sig.Results())
+	params = newVars(sig.Params(), paramsUsable, "arg", sig.Variadic(), taken)
+	results = newVars(sig.Results(), resultsUsable, "r", false, taken)
+	return params, results
+}
+
+// newVars returns the variables of tuple, keeping the names that are usable
+// and making up the others from prefix and an index that makes them distinct
+// from the taken names.
+func newVars(tuple *types.Tuple, usable []bool, prefix string, variadic bool, taken map[string]bool) []*variable {
+	var vars []*variable
+	for i := 0; i < tuple.Len(); i++ {
+		v := tuple.At(i)
+		name := v.Name()
+		if !usable[i] {
+			name = fmt.Sprintf("%s%d", prefix, i)
+			for k := i + 1; taken[name]; k++ {
+				name = fmt.Sprintf("%s%d", prefix, k)
+			}
+			taken[name] = true
+		}
+		vr := &variable{name: name, typ: cb.TypeOf(v.Type())}
+		if variadic && i == tuple.Len()-1 {
+			vr.variadic = true
+			vr.elem = cb.TypeOf(v.Type().(*types.Slice).Elem())
+		}
+		vars = append(vars, vr)
+	}
+	return vars
+}
+
+// funcType returns the type of a mock's Func field.
+func funcType(params, results []*variable) cb.Code {
+	var paramCode, resultCode []*cb.Param
+	for _, p := range params {
+		paramCode = append(paramCode, p.param(true))
+	}
+	for _, r := range results {
+		resultCode = append(resultCode, r.param(false))
+	}
+	return cb.FuncType(paramCode, resultCode)
+}
@@ -0,0 +1,175 @@
+package mockgen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/meta-programming/go-codegenutil"
+	cb "github.com/meta-programming/go-codegenutil/codebuilder"
+	"github.com/meta-programming/go-codegenutil/debugutil"
+	"github.com/meta-programming/go-codegenutil/internal/typestest"
+)
+
+func TestGenerateForType(t *testing.T) {
+	pkg := typestest.Check(t, "abc.xyz/store", `package store
+
+import "io"
+
+type Store interface {
+	io.Closer
+	Get(key string) (value string, ok bool)
+	Log(m string, args ...any)
+}
+
+type Number interface{ ~int | ~float64 }
+
+type NotInterface struct{}
+`)
+	tests := []struct {
+		name     string
+		typeName string
+		opts     []Option
+		want     string
+		wantErr  string
+	}{
+		{
+			name:     "interface with embedded and variadic methods",
+			typeName: "Store",
+			opts:     []Option{WithMockName("FakeStore")},
+			want: `package storetest
+
+import (
+	"sync"
+)
+
+// FakeStore is a test double for Store.
+type FakeStore struct {
+	mu sync.Mutex
+	// CloseFunc implements Close. If it is nil, Close returns zero values.
+	CloseFunc func() error
+	// CloseCalls records the arguments of each call to Close.
+	CloseCalls []FakeStoreCloseCall
+	// GetFunc implements Get. If it is nil, Get returns zero values.
+	GetFunc func(key string) (string, bool)
+	// GetCalls records the arguments of each call to Get.
+	GetCalls []FakeStoreGetCall
+	// LogFunc implements Log. If it is nil, Log returns zero values.
+	LogFunc func(arg0 string, args ...any)
+	// LogCalls records the arguments of each call to Log.
+	LogCalls []FakeStoreLogCall
+}
+
+// FakeStoreCloseCall holds the arguments of a call to FakeStore.Close.
+type FakeStoreCloseCall struct{}
+
+// FakeStoreGetCall holds the arguments of a call to FakeStore.Get.
+type FakeStoreGetCall struct {
+	Key string
+}
+
+// FakeStoreLogCall holds the arguments of a call to FakeStore.Log.
+type FakeStoreLogCall struct {
+	Arg0 string
+	Args []any
+}
+
+// Close records the call and calls CloseFunc.
+func (m *FakeStore) Close() (r0 error) {
+	m.mu.Lock()
+	m.CloseCalls = append(m.CloseCalls, FakeStoreCloseCall{})
+	fn := m.CloseFunc
+	m.mu.Unlock()
+	if fn == nil {
+		return
+	}
+	return fn()
+}
+
+// Get records the call and calls GetFunc.
+func (m *FakeStore) Get(key string) (value string, ok bool) {
+	m.mu.Lock()
+	m.GetCalls = append(m.GetCalls, FakeStoreGetCall{key})
+	fn := m.GetFunc
+	m.mu.Unlock()
+	if fn == nil {
+		return
+	}
+	return fn(key)
+}
+
+// Log records the call and calls LogFunc.
+func (m *FakeStore) Log(arg0 string, args ...any) {
+	m.mu.Lock()
+	m.LogCalls = append(m.LogCalls, FakeStoreLogCall{arg0, args})
+	fn := m.LogFunc
+	m.mu.Unlock()
+	if fn != nil {
+		fn(arg0, args...)
+	}
+}
+`,
+		},
+		{
+			name:     "constraint",
+			typeName: "Number",
+			wantErr:  "constraint interface",
+		},
+		{
+			name:     "not an interface",
+			typeName: "NotInterface",
+			wantErr:  "not an interface type",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decls, err := GenerateForType(pkg, tt.typeName, tt.opts...)
+			if err != nil {
+				if tt.wantErr == "" || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("GenerateForType() got error %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr != "" {
+				t.Fatalf("GenerateForType() succeeded, want error containing %q", tt.wantErr)
+			}
+			got, err := cb.NewFile(codegenutil.AssumedPackageName("abc.xyz/store/storetest")).Add(decls...).Render(nil)
+			if err != nil {
+				t.Fatalf("Render() error: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("GenerateForType() got != want:\n%s", debugutil.SideBySide(string(got), tt.want))
+			}
+		})
+	}
+}
+
+func TestGenerate_nameCollisions(t *testing.T) {
+	pkg := typestest.Check(t, "abc.xyz/store", `package store
+
+type Store interface {
+	Put(id string, ID int, _ bool, arg2 string, r0 int) (int, error)
+	Find(x, X string, fn func()) (m int)
+}
+`)
+	decls, err := GenerateForType(pkg, "Store")
+	if err != nil {
+		t.Fatalf("GenerateForType() error: %v", err)
+	}
+	got, err := cb.NewFile(codegenutil.AssumedPackageName("abc.xyz/storetest")).Add(decls...).Render(nil)
+	if err != nil {
+		t.Fatalf("Render() error: %v", err)
+	}
+	if diags, listing := debugutil.TypeCheck(string(got)); len(diags) > 0 {
+		t.Errorf("generated mock doesn't type-check:\n%s", listing)
+	}
+	for _, want := range []string{
+		"func (m *MockStore) Put(id string, ID int, arg3 bool, arg2 string, r0 int) (r1 int, r2 error) {",
+		"func (m *MockStore) Find(x string, X string, arg2 func()) (r0 int) {",
+		"\tId   string\n\tID   int\n\tArg3 bool\n\tArg2 string\n\tR0   int\n",
+		"\tX    string\n\tArg1 string\n\tArg2 func()\n",
+	} {
+		if !strings.Contains(string(got), want) {
+			t.Errorf("generated mock doesn't contain %q:\n%s", want, got)
+		}
+	}
+}
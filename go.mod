@@ -2,4 +2,12 @@ module github.com/meta-programming/go-codegenutil
 
 go 1.18
 
-require golang.org/x/tools v0.1.11
+require (
+	github.com/fsnotify/fsnotify v1.5.1
+	golang.org/x/mod v0.6.0-dev.0.20220419223038-86c51ed26bb4
+	golang.org/x/sync v0.7.0
+	golang.org/x/tools v0.1.11
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require golang.org/x/sys v0.0.0-20211019181941-9d821ace8654 // indirect
@@ -0,0 +1,163 @@
+// Package gogenerate emits and parses the //go:generate directives that are
+// run by "go generate".
+//
+// Directives are formatted and split like the go command does: arguments are
+// separated by spaces or tabs, and an argument that is empty or contains
+// spaces is written as a double-quoted Go string. Environment variables such
+// as $GOFILE are not expanded, since their values depend on how go generate is
+// run.
+package gogenerate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const prefix = "//go:generate"
+
+// Directive is a //go:generate directive.
+type Directive struct {
+	// Args are the command and its arguments, unquoted.
+	Args []string
+	// Filename is the name of the file that contains the directive, if it was
+	// parsed from a file.
+	Filename string
+	// Line is the 1-based line number of the directive in its file.
+	Line int
+}
+
+// String returns the directive formatted as a line comment. See Format.
+func (d *Directive) String() string { return Format(d.Args...) }
+
+// Format returns a //go:generate directive that runs the command given by
+// args, without a trailing newline. Arguments are quoted as needed for go
+// generate to split them back into args.
+//
+// For example, Format("stringer", "-type", "Color") returns
+// "//go:generate stringer -type Color".
+func Format(args ...string) string {
+	var b strings.Builder
+	b.WriteString(prefix)
+	for _, arg := range args {
+		b.WriteByte(' ')
+		b.WriteString(quote(arg))
+	}
+	return b.String()
+}
+
+// quote returns arg quoted if go generate wouldn't read it back as a single
+// argument otherwise.
+func quote(arg string) string {
+	if arg == "" || arg[0] == '"' || strings.IndexFunc(arg, func(r rune) bool {
+		return r == ' ' || r == '\t' || !unicode.IsPrint(r)
+	}) >= 0 {
+		return strconv.Quote(arg)
+	}
+	return arg
+}
+
+// Parse returns the //go:generate directives of a Go source file, in the order
+// they appear. Like go generate, Parse only looks for lines that start with
+// "//go:generate" followed by a space or a tab; it doesn't parse the file as
+// Go code.
+func Parse(filename string, src []byte) ([]*Directive, error) {
+	var directives []*Directive
+	for i, text := range strings.Split(string(src), "\n") {
+		line := i + 1
+		text = strings.TrimSuffix(text, "\r")
+		if !strings.HasPrefix(text, prefix+" ") && !strings.HasPrefix(text, prefix+"\t") {
+			continue
+		}
+		args, err := split(text[len(prefix):])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", filename, line, err)
+		}
+		directives = append(directives, &Directive{Args: args, Filename: filename, Line: line})
+	}
+	return directives, nil
+}
+
+// split splits the arguments of a directive like go generate does.
+func split(line string) ([]string, error) {
+	var args []string
+	for {
+		line = strings.TrimLeft(line, " \t")
+		if line == "" {
+			return args, nil
+		}
+		if line[0] != '"' {
+			end := strings.IndexAny(line, " \t")
+			if end < 0 {
+				end = len(line)
+			}
+			args = append(args, line[:end])
+			line = line[end:]
+			continue
+		}
+		end := closingQuote(line)
+		if end < 0 {
+			return nil, fmt.Errorf("mismatched quoted string in go:generate directive")
+		}
+		arg, err := strconv.Unquote(line[:end+1])
+		if err != nil {
+			return nil, fmt.Errorf("bad quoted string in go:generate directive: %s", line[:end+1])
+		}
+		args = append(args, arg)
+		line = line[end+1:]
+		if line != "" && line[0] != ' ' && line[0] != '\t' {
+			return nil, fmt.Errorf("expected space after quoted argument in go:generate directive")
+		}
+	}
+}
+
+// closingQuote returns the index of the quote that ends the quoted string at
+// the start of s, or -1 if there is none.
+func closingQuote(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+// ParseDir returns the //go:generate directives of the .go files in dir,
+// ordered by file name and line, which is the order go generate runs them in.
+// Subdirectories are not searched, and files are not filtered by build
+// constraints.
+func ParseDir(dir string) ([]*Directive, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		name := e.Name()
+		if !e.IsDir() && strings.HasSuffix(name, ".go") && !strings.HasPrefix(name, ".") && !strings.HasPrefix(name, "_") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	var directives []*Directive
+	for _, name := range names {
+		filename := filepath.Join(dir, name)
+		src, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := Parse(filename, src)
+		if err != nil {
+			return nil, err
+		}
+		directives = append(directives, parsed...)
+	}
+	return directives, nil
+}
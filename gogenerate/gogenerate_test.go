@@ -0,0 +1,85 @@
+package gogenerate
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"stringer", "-type", "Color"}, "//go:generate stringer -type Color"},
+		{[]string{"sh", "-c", "echo hi"}, `//go:generate sh -c "echo hi"`},
+		{[]string{"x", "", `"q"`, `a"b`, "tab\there", "nl\n"}, `//go:generate x "" "\"q\"" a"b "tab\there" "nl\n"`},
+		{[]string{"go", "run", "$GOFILE"}, "//go:generate go run $GOFILE"},
+	}
+	for _, tt := range tests {
+		got := Format(tt.args...)
+		if got != tt.want {
+			t.Errorf("Format(%q) = %s, want %s", tt.args, got, tt.want)
+		}
+		parsed, err := Parse("x.go", []byte(got))
+		if err != nil {
+			t.Fatalf("Parse(%s) error: %v", got, err)
+		}
+		if len(parsed) != 1 || !reflect.DeepEqual(parsed[0].Args, tt.args) {
+			t.Errorf("Parse(%s) didn't return the formatted arguments %q", got, tt.args)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	src := "package p\r\n" +
+		"//go:generate stringer -type=Color\r\n" +
+		"  //go:generate indented lines are ignored\n" +
+		"//go:generatenot a directive\n" +
+		"//go:generate\t\"quoted arg\"  plain\n"
+	got, err := Parse("p.go", []byte(src))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	want := []*Directive{
+		{Args: []string{"stringer", "-type=Color"}, Filename: "p.go", Line: 2},
+		{Args: []string{"quoted arg", "plain"}, Filename: "p.go", Line: 5},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse() = %+v, want %+v", got, want)
+	}
+
+	for _, bad := range []string{`//go:generate "unterminated`, `//go:generate "a"b`, `//go:generate "\q"`} {
+		if _, err := Parse("bad.go", []byte("package p\n"+bad+"\n")); err == nil || !strings.HasPrefix(err.Error(), "bad.go:2: ") {
+			t.Errorf("Parse(%s) got error %v, want an error at bad.go:2", bad, err)
+		}
+	}
+}
+
+func TestParseDir(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"b.go":        "package p\n//go:generate b\n",
+		"a.go":        "package p\n\n//go:generate a1\n//go:generate a2\n",
+		"_ignored.go": "//go:generate ignored\n",
+		"notgo.txt":   "//go:generate ignored\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	directives, err := ParseDir(dir)
+	if err != nil {
+		t.Fatalf("ParseDir() error: %v", err)
+	}
+	var got []string
+	for _, d := range directives {
+		got = append(got, filepath.Base(d.Filename)+":"+d.String())
+	}
+	want := []string{"a.go://go:generate a1", "a.go://go:generate a2", "b.go://go:generate b"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseDir() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,147 @@
+package codegenutil
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// GoVersionError is the error of code that requires a newer version of Go than
+// the target version of the file it is generated into; see WithGoVersion.
+// Symbols and the builders of the codebuilder package panic with it when they
+// are formatted, and codebuilder.File.Render and codetemplate.Template.Execute
+// return it. It matches ErrGoVersion.
+type GoVersionError struct {
+	// Feature is the feature of the language or the symbol that requires
+	// Version, such as "type parameters" or "slices".Max.
+	Feature string
+	// Version is the version of Go that introduced the feature, such as
+	// "go1.21", and Target is the target version of the file.
+	Version, Target string
+}
+
+func (e *GoVersionError) Error() string {
+	return fmt.Sprintf("%s requires %s, but the target Go version is %s", e.Feature, e.Version, e.Target)
+}
+
+// Is reports whether target is ErrGoVersion.
+func (e *GoVersionError) Is(target error) bool { return target == ErrGoVersion }
+
+// WithGoVersion returns an option that makes the file target the Go version
+// version, such as "go1.20" or "1.20" as in go.mod files, so that the code
+// generated into it compiles for modules that use older toolchains. The
+// predeclared any is written interface{} before go1.18, and the code that
+// requires a newer version fails with a *GoVersionError: type parameters and
+// comparable before go1.18, min, max and clear before go1.21, and the symbols
+// of the standard library that WithSymbolVersions reports as newer.
+//
+// WithGoVersion panics if version isn't a Go version; see IsValidGoVersion.
+func WithGoVersion(version string) FileImportsOption {
+	if !IsValidGoVersion(version) {
+		panic(fmt.Sprintf("codegenutil: invalid Go version %q, want a version such as go1.20", version))
+	}
+	if !strings.HasPrefix(version, "go") {
+		version = "go" + version
+	}
+	return FileImportsOption{
+		func(fi *FileImports) { fi.goVersion = version },
+	}
+}
+
+// IsValidGoVersion reports whether version is a Go 1 version that
+// WithGoVersion accepts, such as "go1.20", "go1.21.3", "go1.22rc1" or "1.20".
+func IsValidGoVersion(version string) bool {
+	_, ok := goMinorVersion(version)
+	return ok
+}
+
+// WithSymbolVersions returns an option that makes a file with a target Go
+// version reject the symbols that are newer than the target, according to
+// since, which returns the version of Go that introduced a symbol, such as
+// "go1.21" for "slices".Max, or the empty string if it is unknown. See
+// symbolindex.LoadStdlibVersions.
+func WithSymbolVersions(since func(*Symbol) string) FileImportsOption {
+	return FileImportsOption{
+		func(fi *FileImports) { fi.symbolVersions = since },
+	}
+}
+
+// GoVersion returns the target Go version of the file, such as "go1.20", or
+// the empty string if the file has none. See WithGoVersion.
+func (fi *FileImports) GoVersion() string { return fi.goVersion }
+
+// RequireGoVersion returns a *GoVersionError if the target Go version of the
+// file is older than version, the version that introduced feature, and nil
+// otherwise, or if the file has no target version. Generators call it before
+// writing code that uses a feature of the language that their output would
+// need, such as type parameters.
+func (fi *FileImports) RequireGoVersion(feature, version string) error {
+	if fi.goVersion == "" || !goVersionBefore(fi.goVersion, version) {
+		return nil
+	}
+	return &GoVersionError{Feature: feature, Version: version, Target: fi.goVersion}
+}
+
+// builtinVersions are the versions of Go that introduced the predeclared
+// identifiers that are newer than go1.
+var builtinVersions = map[string]string{
+	"any":        "go1.18",
+	"comparable": "go1.18",
+	"min":        "go1.21",
+	"max":        "go1.21",
+	"clear":      "go1.21",
+}
+
+// goVersionFallback returns the code of s in a file whose target Go version is
+// older than the version that introduced s, if s has a replacement, such as
+// interface{} for any. It panics with a *GoVersionError if s has none.
+func (s *Symbol) goVersionFallback(imports *FileImports) (string, bool) {
+	if imports.goVersion == "" {
+		return "", false
+	}
+	version := ""
+	if s.pkg.IsBuiltin() {
+		version = builtinVersions[s.name]
+	} else if imports.symbolVersions != nil {
+		version = imports.symbolVersions(s)
+	}
+	if version == "" || !goVersionBefore(imports.goVersion, version) {
+		return "", false
+	}
+	if s.pkg.IsBuiltin() && s.name == "any" {
+		return "interface{}", true
+	}
+	panic(&GoVersionError{Feature: s.String(), Version: version, Target: imports.goVersion})
+}
+
+// goVersionBefore reports whether the Go version a is older than b. Versions
+// are compared by their minor versions, which introduce language features and
+// APIs.
+func goVersionBefore(a, b string) bool {
+	minorA, _ := goMinorVersion(a)
+	minorB, _ := goMinorVersion(b)
+	return minorA < minorB
+}
+
+// goMinorVersion returns the minor version of a Go 1 version such as "go1.20",
+// "go1.21.3", "go1.22rc1" or "1.20", which is 0 for "go1".
+func goMinorVersion(version string) (int, bool) {
+	rest := strings.TrimPrefix(version, "go")
+	if !strings.HasPrefix(rest, "1") {
+		return 0, false
+	}
+	rest = rest[1:]
+	if rest == "" {
+		return 0, true
+	}
+	if rest[0] != '.' {
+		return 0, false
+	}
+	rest = rest[1:]
+	end := 0
+	for end < len(rest) && '0' <= rest[end] && rest[end] <= '9' {
+		end++
+	}
+	minor, err := strconv.Atoi(rest[:end])
+	return minor, err == nil
+}
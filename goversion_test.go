@@ -0,0 +1,69 @@
+package codegenutil
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestWithGoVersion(t *testing.T) {
+	versions := map[string]string{"slices.Max": "go1.21", "strings.Cut": "go1.18"}
+	since := func(s *Symbol) string { return versions[s.Package().ImportPath()+"."+s.Name()] }
+	tests := []struct {
+		version string
+		sym     *Symbol
+		want    string
+		wantErr string
+	}{
+		{"go1.17", BuiltinPackage.Symbol("any"), "interface{}", ""},
+		{"go1.18", BuiltinPackage.Symbol("any"), "any", ""},
+		{"1.17", BuiltinPackage.Symbol("comparable"), "", "comparable requires go1.18, but the target Go version is go1.17"},
+		{"go1.20.3", BuiltinPackage.Symbol("min"), "", "min requires go1.21, but the target Go version is go1.20.3"},
+		{"go1.21rc1", BuiltinPackage.Symbol("clear"), "clear", ""},
+		{"go1.20", Sym("slices", "Max"), "", `"slices".Max requires go1.21, but the target Go version is go1.20`},
+		{"go1.20", Sym("strings", "Cut"), "strings.Cut", ""},
+		{"go1", Sym("example.com/x", "Y"), "x.Y", ""},
+	}
+	for _, tt := range tests {
+		imports := NewFileImports(AssumedPackageName("abc.xyz/mypkg"), WithGoVersion(tt.version), WithSymbolVersions(since))
+		err := recovered(func() {
+			if got := tt.sym.GoCode(imports); got != tt.want {
+				t.Errorf("%s.GoCode() with target %s = %q, want %q", tt.sym, tt.version, got, tt.want)
+			}
+			if got := string(tt.sym.AppendGoCode(nil, imports)); got != tt.want {
+				t.Errorf("%s.AppendGoCode() with target %s = %q, want %q", tt.sym, tt.version, got, tt.want)
+			}
+		})
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("%s.GoCode() with target %s panicked with %v", tt.sym, tt.version, err)
+		case tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr || !errors.Is(err, ErrGoVersion)):
+			t.Errorf("%s.GoCode() with target %s panicked with %v, want %q", tt.sym, tt.version, err, tt.wantErr)
+		}
+	}
+
+	if got := NewFileImports(AssumedPackageName("abc.xyz/mypkg")).GoVersion(); got != "" {
+		t.Errorf("GoVersion() without WithGoVersion = %q, want empty", got)
+	}
+	imports := NewFileImports(AssumedPackageName("abc.xyz/mypkg"), WithGoVersion("1.17"))
+	if got := imports.GoVersion(); got != "go1.17" {
+		t.Errorf("GoVersion() = %q, want go1.17", got)
+	}
+	var versionErr *GoVersionError
+	if err := imports.RequireGoVersion("type parameters", "go1.18"); !errors.As(err, &versionErr) || versionErr.Feature != "type parameters" {
+		t.Errorf("RequireGoVersion(type parameters, go1.18) = %v, want a *GoVersionError", err)
+	}
+	if err := imports.RequireGoVersion("//go:build lines", "go1.17"); err != nil {
+		t.Errorf("RequireGoVersion(go1.17) = %v, want nil", err)
+	}
+}
+
+func TestIsValidGoVersion(t *testing.T) {
+	for version, want := range map[string]bool{
+		"go1": true, "go1.20": true, "1.20": true, "go1.21.3": true, "go1.22rc1": true,
+		"": false, "go": false, "go2": false, "go1.": false, "go10.1": false, "go1.x": false,
+	} {
+		if got := IsValidGoVersion(version); got != want {
+			t.Errorf("IsValidGoVersion(%q) = %v, want %v", version, got, want)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package codegenutil
+
+import "sort"
+
+// importIndex holds the import specs of a FileImports.
+type importIndex interface {
+	// byImportPath returns the spec that imports the import path, or nil.
+	byImportPath(importPath string) *ImportSpec
+	// hasLocalName reports whether a spec uses the file-local package name.
+	hasLocalName(name string) bool
+	// add adds a new spec, whose import path and local name aren't used by
+	// the specs of the index, and returns it.
+	add(fileLocalPackageName string, pkg *Package, isExplicit bool) *ImportSpec
+	// appendSpecs appends the specs to out.
+	appendSpecs(out []*ImportSpec) []*ImportSpec
+	// size returns the number of specs.
+	size() int
+	// removeIf removes the specs for which drop returns true and returns
+	// them.
+	removeIf(drop func(*ImportSpec) bool) []*ImportSpec
+}
+
+// mapIndex is the default importIndex, which looks up specs in maps.
+type mapIndex struct {
+	specs              []*ImportSpec
+	byLocalPackageName map[string]*ImportSpec
+	byPath             map[string]*ImportSpec
+}
+
+func newMapIndex() *mapIndex {
+	return &mapIndex{
+		byLocalPackageName: map[string]*ImportSpec{},
+		byPath:             map[string]*ImportSpec{},
+	}
+}
+
+func (idx *mapIndex) byImportPath(importPath string) *ImportSpec {
+	return idx.byPath[importPath]
+}
+
+func (idx *mapIndex) hasLocalName(name string) bool {
+	_, ok := idx.byLocalPackageName[name]
+	return ok
+}
+
+func (idx *mapIndex) add(fileLocalPackageName string, pkg *Package, isExplicit bool) *ImportSpec {
+	spec := &ImportSpec{fileLocalPackageName, pkg, isExplicit}
+	idx.byLocalPackageName[fileLocalPackageName] = spec
+	idx.byPath[pkg.ImportPath()] = spec
+	idx.specs = append(idx.specs, spec)
+	return spec
+}
+
+func (idx *mapIndex) appendSpecs(out []*ImportSpec) []*ImportSpec {
+	return append(out, idx.specs...)
+}
+
+func (idx *mapIndex) size() int { return len(idx.specs) }
+
+func (idx *mapIndex) removeIf(drop func(*ImportSpec) bool) []*ImportSpec {
+	var removed []*ImportSpec
+	kept := idx.specs[:0]
+	for _, spec := range idx.specs {
+		if !drop(spec) {
+			kept = append(kept, spec)
+			continue
+		}
+		removed = append(removed, spec)
+		delete(idx.byPath, spec.pkg.ImportPath())
+		if idx.byLocalPackageName[spec.fileLocalPackageName] == spec {
+			delete(idx.byLocalPackageName, spec.fileLocalPackageName)
+		}
+	}
+	for i := len(kept); i < len(idx.specs); i++ {
+		idx.specs[i] = nil
+	}
+	idx.specs = kept
+	// Several blank imports share the name "_"; keep it mapped while any
+	// of them remain.
+	for _, spec := range kept {
+		if _, ok := idx.byLocalPackageName[spec.fileLocalPackageName]; !ok {
+			idx.byLocalPackageName[spec.fileLocalPackageName] = spec
+		}
+	}
+	return removed
+}
+
+// specChunkSize is the number of ImportSpecs that sortedIndex allocates at
+// once.
+const specChunkSize = 64
+
+// sortedIndex is the importIndex of CompactImports. It keeps the specs in two
+// slices sorted by import path and by local name, which are searched with
+// binary search, and allocates specs in chunks.
+type sortedIndex struct {
+	byPath []*ImportSpec
+	byName []*ImportSpec
+	// chunk holds the specs allocated for future adds.
+	chunk []ImportSpec
+}
+
+func (idx *sortedIndex) searchPath(importPath string) int {
+	return sort.Search(len(idx.byPath), func(i int) bool { return idx.byPath[i].pkg.ImportPath() >= importPath })
+}
+
+func (idx *sortedIndex) searchName(name string) int {
+	return sort.Search(len(idx.byName), func(i int) bool { return idx.byName[i].fileLocalPackageName >= name })
+}
+
+func (idx *sortedIndex) byImportPath(importPath string) *ImportSpec {
+	if i := idx.searchPath(importPath); i < len(idx.byPath) && idx.byPath[i].pkg.ImportPath() == importPath {
+		return idx.byPath[i]
+	}
+	return nil
+}
+
+func (idx *sortedIndex) hasLocalName(name string) bool {
+	i := idx.searchName(name)
+	return i < len(idx.byName) && idx.byName[i].fileLocalPackageName == name
+}
+
+func (idx *sortedIndex) add(fileLocalPackageName string, pkg *Package, isExplicit bool) *ImportSpec {
+	if len(idx.chunk) == 0 {
+		idx.chunk = make([]ImportSpec, specChunkSize)
+	}
+	spec := &idx.chunk[0]
+	idx.chunk = idx.chunk[1:]
+	*spec = ImportSpec{fileLocalPackageName, pkg, isExplicit}
+	idx.byPath = insertSpec(idx.byPath, idx.searchPath(pkg.ImportPath()), spec)
+	idx.byName = insertSpec(idx.byName, idx.searchName(fileLocalPackageName), spec)
+	return spec
+}
+
+func (idx *sortedIndex) appendSpecs(out []*ImportSpec) []*ImportSpec {
+	return append(out, idx.byPath...)
+}
+
+func (idx *sortedIndex) size() int { return len(idx.byPath) }
+
+func (idx *sortedIndex) removeIf(drop func(*ImportSpec) bool) []*ImportSpec {
+	var removed []*ImportSpec
+	keptPaths := idx.byPath[:0]
+	for _, spec := range idx.byPath {
+		if drop(spec) {
+			removed = append(removed, spec)
+		} else {
+			keptPaths = append(keptPaths, spec)
+		}
+	}
+	if len(removed) == 0 {
+		return nil
+	}
+	for i := len(keptPaths); i < len(idx.byPath); i++ {
+		idx.byPath[i] = nil
+	}
+	idx.byPath = keptPaths
+	isRemoved := map[*ImportSpec]bool{}
+	for _, spec := range removed {
+		isRemoved[spec] = true
+	}
+	keptNames := idx.byName[:0]
+	for _, spec := range idx.byName {
+		if !isRemoved[spec] {
+			keptNames = append(keptNames, spec)
+		}
+	}
+	for i := len(keptNames); i < len(idx.byName); i++ {
+		idx.byName[i] = nil
+	}
+	idx.byName = keptNames
+	return removed
+}
+
+func insertSpec(specs []*ImportSpec, i int, spec *ImportSpec) []*ImportSpec {
+	specs = append(specs, nil)
+	copy(specs[i+1:], specs[i:])
+	specs[i] = spec
+	return specs
+}
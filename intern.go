@@ -0,0 +1,73 @@
+package codegenutil
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Interner is a registry of strings that keeps a single copy of each distinct
+// string it is given. Long-running code generation services that create
+// millions of Packages and Symbols for the same import paths and identifiers
+// can use it, with SetInterner, to share the memory of those strings.
+//
+// An Interner is safe for concurrent use. It never forgets a string, so it
+// grows with the number of distinct strings it is given.
+type Interner struct {
+	mu      sync.RWMutex
+	strings map[string]string
+}
+
+// NewInterner returns an empty Interner.
+func NewInterner() *Interner {
+	return &Interner{strings: map[string]string{}}
+}
+
+// Intern returns a string equal to s. It returns the same string, sharing its
+// memory, for all of the strings equal to s given to the Interner.
+func (in *Interner) Intern(s string) string {
+	in.mu.RLock()
+	interned, ok := in.strings[s]
+	in.mu.RUnlock()
+	if ok {
+		return interned
+	}
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if interned, ok := in.strings[s]; ok {
+		return interned
+	}
+	// s is copied so that the registry doesn't keep alive a larger string
+	// that s is a substring of.
+	interned = string([]byte(s))
+	in.strings[interned] = interned
+	return interned
+}
+
+// Len returns the number of distinct strings in the Interner.
+func (in *Interner) Len() int {
+	in.mu.RLock()
+	defer in.mu.RUnlock()
+	return len(in.strings)
+}
+
+// interner holds the *Interner set by SetInterner.
+var interner atomic.Value
+
+// SetInterner makes the functions that construct Packages and Symbols, such as
+// AssumedPackageName, ExplicitPackageName, Sym, ParseSymbol and
+// Package.Symbol, intern import paths, package names and symbol names with
+// in. Interning is disabled by default and when in is nil.
+//
+// SetInterner is meant to be called once, when a program starts.
+func SetInterner(in *Interner) {
+	interner.Store(in)
+}
+
+// intern returns s interned by the Interner set by SetInterner, if any.
+func intern(s string) string {
+	in, _ := interner.Load().(*Interner)
+	if in == nil {
+		return s
+	}
+	return in.Intern(s)
+}
@@ -0,0 +1,36 @@
+// Package logtest records the events logged to a codegenutil.Logger for
+// tests.
+package logtest
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Recorder is a codegenutil.Logger that records the events logged to it. It
+// is safe for concurrent use.
+type Recorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+// Debug records an event as its message followed by its arguments, formatted
+// as key=value pairs.
+func (r *Recorder) Debug(msg string, args ...any) {
+	var b strings.Builder
+	b.WriteString(msg)
+	for i := 0; i+1 < len(args); i += 2 {
+		fmt.Fprintf(&b, " %v=%v", args[i], args[i+1])
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, b.String())
+}
+
+// Events returns the events recorded so far, in the order they were logged.
+func (r *Recorder) Events() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.events...)
+}
@@ -0,0 +1,58 @@
+// Package typestest type checks Go source for tests of code that consumes
+// go/types objects.
+package typestest
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"testing"
+)
+
+// Check type checks a single-file package with the given import path and
+// fails the test if there are any errors. Imports are loaded from source so
+// that tests don't depend on compiled export data.
+func Check(t testing.TB, path, src string) *types.Package {
+	t.Helper()
+	pkg, _, _ := CheckInfo(t, path, src)
+	return pkg
+}
+
+// CheckInfo type checks src like Check and also returns the syntax of the file
+// and the types.Info recorded for it, with all of its maps populated.
+func CheckInfo(t testing.TB, path, src string) (*types.Package, *types.Info, *ast.File) {
+	t.Helper()
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "input.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("error parsing input: %v", err)
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	info := &types.Info{
+		Types:      map[ast.Expr]types.TypeAndValue{},
+		Instances:  map[*ast.Ident]types.Instance{},
+		Defs:       map[*ast.Ident]types.Object{},
+		Uses:       map[*ast.Ident]types.Object{},
+		Implicits:  map[ast.Node]types.Object{},
+		Selections: map[*ast.SelectorExpr]*types.Selection{},
+		Scopes:     map[ast.Node]*types.Scope{},
+	}
+	pkg, err := conf.Check(path, fset, []*ast.File{f}, info)
+	if err != nil {
+		t.Fatalf("error type checking input: %v", err)
+	}
+	return pkg, info, f
+}
+
+// Lookup type checks src like Check and returns the package-level object with
+// the given name, failing the test if there is no such object.
+func Lookup(t testing.TB, path, src, name string) types.Object {
+	t.Helper()
+	obj := Check(t, path, src).Scope().Lookup(name)
+	if obj == nil {
+		t.Fatalf("no object named %q in package %q", name, path)
+	}
+	return obj
+}
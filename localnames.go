@@ -0,0 +1,69 @@
+package codegenutil
+
+import (
+	"go/parser"
+	"go/token"
+	"strconv"
+)
+
+// PreferLocalNames returns an option that makes the returned *FileImports
+// import the packages whose import paths are keys of names with the local
+// names they map to, when they are free, rather than with the names that would
+// otherwise be suggested. Other packages aren't given those names.
+//
+// Passing the local names of the previous version of a generated file, as
+// returned by ParseLocalNames or FileImports.LocalNames, keeps aliases stable
+// across regenerations: a package that was imported as "math2" is still
+// imported as "math2" after the package that was imported as "math" is no
+// longer used, so unrelated changes don't rename aliases throughout the file.
+func PreferLocalNames(names map[string]string) FileImportsOption {
+	return FileImportsOption{
+		func(fi *FileImports) {
+			fi.preferredNames = map[string]string{}
+			fi.reservedNames = map[string]string{}
+			for importPath, name := range names {
+				fi.preferredNames[importPath] = name
+				fi.reservedNames[name] = importPath
+			}
+		},
+	}
+}
+
+// LocalNames returns a map from the import paths of the imports to their local
+// names, which can be saved and passed to PreferLocalNames when the file is
+// generated again.
+func (fi *FileImports) LocalNames() map[string]string {
+	names := map[string]string{}
+	for _, spec := range fi.List() {
+		names[spec.PackageName().ImportPath()] = spec.FileLocalPackageName()
+	}
+	return names
+}
+
+// ParseLocalNames parses the imports of a Go source file, such as the
+// previous version of a generated file, and returns a map from their import
+// paths to their local names. The local name of an import without an alias is
+// assumed from its import path, as with AssumedPackageName. Blank and dot
+// imports are ignored.
+func ParseLocalNames(src []byte) (map[string]string, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), "", src, parser.ImportsOnly)
+	if err != nil {
+		return nil, err
+	}
+	names := map[string]string{}
+	for _, imp := range file.Imports {
+		importPath, err := strconv.Unquote(imp.Path.Value)
+		if err != nil {
+			return nil, err
+		}
+		name := AssumedPackageName(importPath).Name()
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+		if name == "_" || name == "." {
+			continue
+		}
+		names[importPath] = name
+	}
+	return names, nil
+}
@@ -0,0 +1,69 @@
+package codegenutil
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPreferLocalNames(t *testing.T) {
+	previous := NewFileImports(AssumedPackageName("abc/xyz"))
+	for _, importPath := range []string{"math", "example.com/math", "example.com/other/math", "fmt"} {
+		previous.Add(AssumedPackageName(importPath), "")
+	}
+
+	// The regenerated file no longer uses "math" and uses a new package of
+	// the same name.
+	imports := NewFileImports(AssumedPackageName("abc/xyz"), PreferLocalNames(previous.LocalNames()))
+	var got []string
+	for _, importPath := range []string{"example.com/new/math", "example.com/other/math", "example.com/math", "fmt"} {
+		got = append(got, Sym(importPath, "X").GoCode(imports))
+	}
+	want := []string{"math4.X", "math3.X", "math2.X", "fmt.X"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GoCode() with the previous local names = %q, want %q", got, want)
+	}
+}
+
+func TestPreferLocalNames_aliasShape(t *testing.T) {
+	opts := []FileImportsOption{MaxAliasLength(8), AliasAffixes("pkg", "")}
+	importPaths := []string{"errors", "github.com/pkg/errors", "example.com/configuration"}
+	previous := NewFileImports(AssumedPackageName("abc/xyz"), opts...)
+	for _, importPath := range importPaths {
+		previous.Add(AssumedPackageName(importPath), "")
+	}
+
+	imports := NewFileImports(AssumedPackageName("abc/xyz"), append(opts, PreferLocalNames(previous.LocalNames()))...)
+	for _, importPath := range importPaths[1:] {
+		imports.Add(AssumedPackageName(importPath), "")
+	}
+	want := previous.LocalNames()
+	delete(want, "errors")
+	if got := imports.LocalNames(); !reflect.DeepEqual(got, want) {
+		t.Errorf("LocalNames() with the previous local names = %q, want %q", got, want)
+	}
+}
+
+func TestParseLocalNames(t *testing.T) {
+	got, err := ParseLocalNames([]byte(`package xyz
+
+import (
+	"fmt"
+	math2 "example.com/math"
+	_ "embed"
+	. "example.com/dot"
+	"gopkg.in/yaml.v3"
+)
+
+var x = 1
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"fmt": "fmt", "example.com/math": "math2", "gopkg.in/yaml.v3": "yaml"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseLocalNames() = %v, want %v", got, want)
+	}
+	if _, err := ParseLocalNames([]byte("not go")); err == nil {
+		t.Errorf("ParseLocalNames() of invalid source succeeded, want error")
+	}
+}
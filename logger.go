@@ -0,0 +1,18 @@
+package codegenutil
+
+// Logger receives the debug events of the packages of this module, such as
+// the imports that a FileImports adds, so that large generation runs can be
+// diagnosed without adding print statements. The arguments that follow the
+// message are alternating keys and values. It is satisfied by *slog.Logger.
+type Logger interface {
+	Debug(msg string, args ...any)
+}
+
+// WithLogger returns an option that makes the returned *FileImports log the
+// imports it adds and, when the name of a package is taken, the alias it
+// chose instead, at the debug level.
+func WithLogger(l Logger) FileImportsOption {
+	return FileImportsOption{
+		func(fi *FileImports) { fi.logger = l },
+	}
+}
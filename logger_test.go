@@ -0,0 +1,26 @@
+package codegenutil
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/meta-programming/go-codegenutil/internal/logtest"
+)
+
+func TestWithLogger(t *testing.T) {
+	logger := &logtest.Recorder{}
+	imports := NewFileImports(AssumedPackageName("abc.xyz/mypkg"), WithLogger(logger))
+	imports.Add(ExplicitPackageName("gopkg.in/yaml.v2", "yaml"), "")
+	imports.Add(ExplicitPackageName("gopkg.in/yaml.v3", "yaml"), "")
+	imports.Add(ExplicitPackageName("gopkg.in/yaml.v3", "yaml"), "")
+	imports.Add(AssumedPackageName("strings"), "str")
+	want := []string{
+		"import added file=abc.xyz/mypkg path=gopkg.in/yaml.v2 name=yaml",
+		"import alias conflict resolved file=abc.xyz/mypkg path=gopkg.in/yaml.v3 wanted=yaml name=yaml2",
+		"import added file=abc.xyz/mypkg path=gopkg.in/yaml.v3 name=yaml2",
+		"import added file=abc.xyz/mypkg path=strings name=str",
+	}
+	if got := logger.Events(); !reflect.DeepEqual(got, want) {
+		t.Errorf("logged events:\n%q\nwant:\n%q", got, want)
+	}
+}
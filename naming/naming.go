@@ -0,0 +1,59 @@
+// Package naming converts between the identifier forms used by code
+// generators, such as the exported and unexported forms of a name and the
+// conventional receiver name of a type.
+package naming
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Exported returns name with its first letter in upper case, which makes it an
+// exported identifier if it is a letter.
+func Exported(name string) string {
+	r, size := utf8.DecodeRuneInString(name)
+	if r == utf8.RuneError {
+		return name
+	}
+	return string(unicode.ToUpper(r)) + name[size:]
+}
+
+// Unexported returns name with its leading upper case letters in lower case,
+// which makes it an unexported identifier. A leading initialism is lowered as
+// a unit, so "ID" becomes "id" and "URLPath" becomes "urlPath".
+func Unexported(name string) string {
+	runes := []rune(name)
+	upper := 0
+	for upper < len(runes) && unicode.IsUpper(runes[upper]) {
+		upper++
+	}
+	switch {
+	case upper == 0:
+		return name
+	case upper == 1 || upper == len(runes):
+		// "Name" → "name", "ID" → "id".
+	case !unicode.IsLetter(runes[upper]):
+		// "ID2" → "id2".
+	default:
+		// The last upper case letter starts the next word: "URLPath" → "urlPath".
+		upper--
+	}
+	for i := 0; i < upper; i++ {
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
+}
+
+// ReceiverName returns the conventional receiver name for methods of the named
+// type: its first letter in lower case. Type arguments and a leading "*" are
+// ignored, so "*List[T]" yields "l". If typeName doesn't start with a letter,
+// ReceiverName returns "x".
+func ReceiverName(typeName string) string {
+	typeName = strings.TrimLeft(typeName, "*")
+	r, _ := utf8.DecodeRuneInString(typeName)
+	if !unicode.IsLetter(r) {
+		return "x"
+	}
+	return string(unicode.ToLower(r))
+}
@@ -0,0 +1,56 @@
+package naming
+
+import "testing"
+
+func TestExported(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"", ""},
+		{"name", "Name"},
+		{"Name", "Name"},
+		{"_x", "_x"},
+		{"élan", "Élan"},
+	}
+	for _, tt := range tests {
+		if got := Exported(tt.name); got != tt.want {
+			t.Errorf("Exported(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestUnexported(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"", ""},
+		{"name", "name"},
+		{"Name", "name"},
+		{"ID", "id"},
+		{"ID2", "id2"},
+		{"URLPath", "urlPath"},
+		{"HTTPServer", "httpServer"},
+		{"Élan", "élan"},
+	}
+	for _, tt := range tests {
+		if got := Unexported(tt.name); got != tt.want {
+			t.Errorf("Unexported(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestReceiverName(t *testing.T) {
+	tests := []struct {
+		typeName, want string
+	}{
+		{"Color", "c"},
+		{"*List[T]", "l"},
+		{"_private", "x"},
+		{"Über", "ü"},
+	}
+	for _, tt := range tests {
+		if got := ReceiverName(tt.typeName); got != tt.want {
+			t.Errorf("ReceiverName(%q) = %q, want %q", tt.typeName, got, tt.want)
+		}
+	}
+}
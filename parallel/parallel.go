@@ -0,0 +1,166 @@
+// Package parallel runs independent generation tasks concurrently, such as the
+// executions of templates for many files, with a bound on the number of tasks
+// that run at once.
+//
+// It is the supported way to parallelize generation with the types of this
+// module. The types that tasks typically share are safe for concurrent use: a
+// parsed codetemplate.Template can be executed by several tasks at once, and
+// files can be added to a project.Project from several tasks at once. Each
+// task must use its own codegenutil.FileImports, because the imports of a
+// file depend on the order in which its symbols are formatted.
+package parallel
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// Task is a unit of work run by Run.
+type Task struct {
+	// Name identifies the task in errors and progress reports.
+	Name string
+	// Run performs the task.
+	Run func(ctx context.Context) error
+}
+
+// Option customizes Run.
+type Option struct {
+	apply func(*config)
+}
+
+type config struct {
+	limit      int
+	collectAll bool
+	onProgress func(Progress)
+}
+
+// WithLimit returns an option that sets the maximum number of tasks that run at
+// once. The default is runtime.GOMAXPROCS(0).
+func WithLimit(n int) Option {
+	return Option{func(c *config) { c.limit = n }}
+}
+
+// CollectAll returns an option that makes Run run all of the tasks even if
+// some fail, and return the errors of all of the failed tasks. By default, Run
+// fails fast: it cancels the context of the running tasks and doesn't start
+// the remaining ones once a task fails.
+func CollectAll() Option {
+	return Option{func(c *config) { c.collectAll = true }}
+}
+
+// Progress reports the completion of a task.
+type Progress struct {
+	// Task is the task that completed.
+	Task *Task
+	// Err is the error returned by the task, or nil if it succeeded.
+	Err error
+	// Done is the number of tasks that have completed, including this one.
+	Done int
+	// Total is the number of tasks given to Run.
+	Total int
+}
+
+// OnProgress returns an option that makes Run call fn after each task
+// completes, for example to print a progress bar. The calls are not
+// concurrent, so fn doesn't need to synchronize its state.
+func OnProgress(fn func(Progress)) Option {
+	return Option{func(c *config) { c.onProgress = fn }}
+}
+
+// TaskError is the error of a task that failed.
+type TaskError struct {
+	// Task is the name of the task.
+	Task string
+	// Err is the error returned by the task.
+	Err error
+}
+
+func (e *TaskError) Error() string { return fmt.Sprintf("task %q: %v", e.Task, e.Err) }
+
+func (e *TaskError) Unwrap() error { return e.Err }
+
+// Errors is returned by Run with the CollectAll option when tasks fail. The
+// errors are in the order of the tasks.
+type Errors []*TaskError
+
+func (e Errors) Error() string {
+	lines := make([]string, len(e))
+	for i, err := range e {
+		lines[i] = err.Error()
+	}
+	return fmt.Sprintf("%d of the tasks failed:\n%s", len(e), strings.Join(lines, "\n"))
+}
+
+// Run runs the tasks concurrently and waits for them to complete. At most
+// WithLimit tasks run at once; they are started in the order they are given.
+//
+// By default, Run returns the *TaskError of the first task that fails, and
+// the tasks that haven't started yet are skipped. With CollectAll, every task
+// is run and Run returns Errors if any of them fails. If ctx is done before
+// all of the tasks have started, the remaining tasks are skipped and Run
+// returns ctx.Err() unless a task failed.
+func Run(ctx context.Context, tasks []*Task, opts ...Option) error {
+	c := &config{limit: runtime.GOMAXPROCS(0)}
+	for _, opt := range opts {
+		opt.apply(c)
+	}
+
+	var g *errgroup.Group
+	taskCtx := ctx
+	if c.collectAll {
+		g = &errgroup.Group{}
+	} else {
+		g, taskCtx = errgroup.WithContext(ctx)
+	}
+	if c.limit > 0 {
+		g.SetLimit(c.limit)
+	}
+
+	var mu sync.Mutex
+	done, skipped := 0, false
+	taskErrs := make([]error, len(tasks))
+	for i, t := range tasks {
+		i, t := i, t
+		g.Go(func() error {
+			if taskCtx.Err() != nil {
+				mu.Lock()
+				skipped = true
+				mu.Unlock()
+				return nil
+			}
+			err := t.Run(taskCtx)
+			mu.Lock()
+			done++
+			taskErrs[i] = err
+			if c.onProgress != nil {
+				c.onProgress(Progress{Task: t, Err: err, Done: done, Total: len(tasks)})
+			}
+			mu.Unlock()
+			if err != nil && !c.collectAll {
+				return &TaskError{t.Name, err}
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return err
+	}
+	var errs Errors
+	for i, err := range taskErrs {
+		if err != nil {
+			errs = append(errs, &TaskError{tasks[i].Name, err})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	if skipped {
+		return ctx.Err()
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package parallel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	var running, maxRunning int32
+	var mu sync.Mutex
+	ran := map[string]bool{}
+	var tasks []*Task
+	for i := 0; i < 20; i++ {
+		name := fmt.Sprintf("task%d", i)
+		tasks = append(tasks, &Task{Name: name, Run: func(ctx context.Context) error {
+			n := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			mu.Lock()
+			if n > maxRunning {
+				maxRunning = n
+			}
+			ran[name] = true
+			mu.Unlock()
+			return nil
+		}})
+	}
+	var progress []int
+	err := Run(context.Background(), tasks, WithLimit(3), OnProgress(func(p Progress) {
+		if p.Err != nil || p.Total != len(tasks) {
+			t.Errorf("OnProgress() got %+v, want no error and a total of %d", p, len(tasks))
+		}
+		progress = append(progress, p.Done)
+	}))
+	if err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if len(ran) != len(tasks) {
+		t.Errorf("Run() ran %d tasks, want %d", len(ran), len(tasks))
+	}
+	if maxRunning > 3 {
+		t.Errorf("Run() ran %d tasks at once, want at most 3", maxRunning)
+	}
+	for i, done := range progress {
+		if done != i+1 {
+			t.Errorf("OnProgress() got Done = %v, want 1 to %d in order", progress, len(tasks))
+			break
+		}
+	}
+}
+
+func TestRun_failFast(t *testing.T) {
+	errBoom := errors.New("boom")
+	var started int32
+	tasks := []*Task{{Name: "fails", Run: func(ctx context.Context) error {
+		atomic.AddInt32(&started, 1)
+		return errBoom
+	}}}
+	for i := 0; i < 10; i++ {
+		tasks = append(tasks, &Task{Name: fmt.Sprint(i), Run: func(ctx context.Context) error {
+			atomic.AddInt32(&started, 1)
+			return nil
+		}})
+	}
+	err := Run(context.Background(), tasks, WithLimit(1))
+	var taskErr *TaskError
+	if !errors.As(err, &taskErr) || taskErr.Task != "fails" || !errors.Is(err, errBoom) {
+		t.Fatalf("Run() error = %v, want the *TaskError of task \"fails\"", err)
+	}
+	if started != 1 {
+		t.Errorf("Run() started %d tasks, want only the failing one", started)
+	}
+}
+
+func TestRun_collectAll(t *testing.T) {
+	var tasks []*Task
+	for i := 0; i < 6; i++ {
+		i := i
+		tasks = append(tasks, &Task{Name: fmt.Sprint(i), Run: func(ctx context.Context) error {
+			if i%2 == 1 {
+				return fmt.Errorf("odd %d", i)
+			}
+			return nil
+		}})
+	}
+	failures := 0
+	err := Run(context.Background(), tasks, CollectAll(), OnProgress(func(p Progress) {
+		if p.Err != nil {
+			failures++
+		}
+	}))
+	var errs Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Run() error = %v, want Errors", err)
+	}
+	want := "3 of the tasks failed:\ntask \"1\": odd 1\ntask \"3\": odd 3\ntask \"5\": odd 5"
+	if err.Error() != want {
+		t.Errorf("Run() error = %q, want %q", err, want)
+	}
+	if failures != 3 {
+		t.Errorf("OnProgress() reported %d failures, want 3", failures)
+	}
+}
+
+func TestRun_canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	ran := false
+	err := Run(ctx, []*Task{{Name: "x", Run: func(ctx context.Context) error {
+		ran = true
+		return nil
+	}}})
+	if !errors.Is(err, context.Canceled) || ran {
+		t.Errorf("Run() with a canceled context returned %v and ran = %v, want context.Canceled and no task run", err, ran)
+	}
+}
@@ -0,0 +1,39 @@
+package codegenutil
+
+import "sort"
+
+// predeclared is the set of the predeclared identifiers of Go, which are
+// declared in the universe block: https://go.dev/ref/spec#Predeclared_identifiers
+var predeclared = map[string]bool{
+	// Types.
+	"any": true, "bool": true, "byte": true, "comparable": true,
+	"complex64": true, "complex128": true, "error": true, "float32": true,
+	"float64": true, "int": true, "int8": true, "int16": true, "int32": true,
+	"int64": true, "rune": true, "string": true, "uint": true, "uint8": true,
+	"uint16": true, "uint32": true, "uint64": true, "uintptr": true,
+	// Constants.
+	"true": true, "false": true, "iota": true,
+	// Zero value.
+	"nil": true,
+	// Functions.
+	"append": true, "cap": true, "clear": true, "close": true, "complex": true,
+	"copy": true, "delete": true, "imag": true, "len": true, "make": true,
+	"max": true, "min": true, "new": true, "panic": true, "print": true,
+	"println": true, "real": true, "recover": true,
+}
+
+// IsPredeclared reports whether name is a predeclared identifier of Go, such
+// as "len" or "string". A file that imports a package with such a name shadows
+// the identifier, so the default package name suggester of FileImports gives
+// those packages another name, and custom suggesters may want to do the same.
+func IsPredeclared(name string) bool { return predeclared[name] }
+
+// PredeclaredIdentifiers returns the predeclared identifiers of Go, sorted.
+func PredeclaredIdentifiers() []string {
+	names := make([]string, 0, len(predeclared))
+	for name := range predeclared {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
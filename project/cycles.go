@@ -0,0 +1,311 @@
+package project
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ImportCycle is a cycle of imports between the packages generated in a
+// project.
+type ImportCycle struct {
+	// Imports are the imports of the cycle: each one imports the package that
+	// the next one is from, and the last one imports the package of the first.
+	Imports []*PackageImport
+}
+
+// PackageImport is the import of a generated package by another.
+type PackageImport struct {
+	// From is the import path of the importing package, and To that of the
+	// imported package.
+	From, To string
+	// References are the references to the symbols of the imported package
+	// that make the import necessary, in the order of the files and of their
+	// lines.
+	References []*SymbolReference
+}
+
+// SymbolReference is a reference to a symbol of an imported package in a
+// generated file.
+type SymbolReference struct {
+	// Path is the path of the generated file.
+	Path string
+	// Line is the line of the reference in the file.
+	Line int
+	// Generator is the name of the generator that produced the file.
+	Generator string
+	// Symbol is the reference as it is written, such as "b.Client". It is the
+	// import path of the package for dot imports and imports for side
+	// effects, which the file doesn't qualify symbols with.
+	Symbol string
+}
+
+func (r *SymbolReference) String() string {
+	return fmt.Sprintf("%s:%d: %s (generated by %s)", r.Path, r.Line, r.Symbol, generatorName(r.Generator))
+}
+
+// ImportCycleError is returned by CheckImportCycles if the generated packages
+// import each other.
+type ImportCycleError struct {
+	Cycles []*ImportCycle
+}
+
+func (e *ImportCycleError) Error() string {
+	var b strings.Builder
+	for i, cycle := range e.Cycles {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		var paths []string
+		for _, imp := range cycle.Imports {
+			paths = append(paths, imp.From)
+		}
+		paths = append(paths, cycle.Imports[0].From)
+		fmt.Fprintf(&b, "import cycle: %s", strings.Join(paths, " -> "))
+		for _, imp := range cycle.Imports {
+			fmt.Fprintf(&b, "\n\t%s imports %s:", imp.From, imp.To)
+			for _, ref := range imp.References {
+				fmt.Fprintf(&b, "\n\t\t%s", ref)
+			}
+		}
+	}
+	return b.String()
+}
+
+// CheckImportCycles returns an *ImportCycleError if the Go packages generated
+// in the project import each other in cycles, which the Go compiler would
+// reject without saying which generator produced the offending references.
+// See ImportCycles.
+func (p *Project) CheckImportCycles(modulePath string) error {
+	if cycles := p.ImportCycles(modulePath); len(cycles) > 0 {
+		return &ImportCycleError{cycles}
+	}
+	return nil
+}
+
+// ImportCycles returns the import cycles between the Go packages generated in
+// the project, whose import paths are modulePath joined with their
+// directories, ordered by the smallest import path of each cycle.
+//
+// Only the imports of the generated files are considered: cycles through
+// files that aren't part of the project aren't found. Test files are ignored,
+// as are files that don't parse, which the compiler reports better.
+func (p *Project) ImportCycles(modulePath string) []*ImportCycle {
+	type parsedFile struct {
+		f    *File
+		fset *token.FileSet
+		file *ast.File
+	}
+	var files []*parsedFile
+	// names maps the import paths of the generated packages to their names.
+	names := map[string]string{}
+	for _, f := range p.Files() {
+		if path.Ext(f.Path) != ".go" || strings.HasSuffix(f.Path, "_test.go") {
+			continue
+		}
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, f.Path, f.Content, parser.SkipObjectResolution)
+		if err != nil {
+			continue
+		}
+		files = append(files, &parsedFile{f, fset, file})
+		names[packagePath(modulePath, path.Dir(f.Path))] = file.Name.Name
+	}
+
+	imports := map[string]map[string]*PackageImport{}
+	for _, pf := range files {
+		from := packagePath(modulePath, path.Dir(pf.f.Path))
+		for _, spec := range pf.file.Imports {
+			to, err := strconv.Unquote(spec.Path.Value)
+			if err != nil || names[to] == "" {
+				continue
+			}
+			if imports[from] == nil {
+				imports[from] = map[string]*PackageImport{}
+			}
+			imp := imports[from][to]
+			if imp == nil {
+				imp = &PackageImport{From: from, To: to}
+				imports[from][to] = imp
+			}
+			imp.References = append(imp.References, references(pf.fset, pf.f, pf.file, spec, names[to])...)
+		}
+	}
+	return findCycles(imports)
+}
+
+// packagePath returns the import path of the package in the directory dir of
+// a project of the module modulePath.
+func packagePath(modulePath, dir string) string {
+	if dir == "." {
+		return modulePath
+	}
+	return modulePath + "/" + dir
+}
+
+// references returns the references of the file f, parsed as file, to the
+// package imported by spec, whose name is pkgName.
+func references(fset *token.FileSet, f *File, file *ast.File, spec *ast.ImportSpec, pkgName string) []*SymbolReference {
+	localName := pkgName
+	if spec.Name != nil {
+		localName = spec.Name.Name
+	}
+	if localName == "_" || localName == "." {
+		return []*SymbolReference{{
+			Path:      f.Path,
+			Line:      fset.Position(spec.Pos()).Line,
+			Generator: f.Generator,
+			Symbol:    spec.Path.Value,
+		}}
+	}
+	var refs []*SymbolReference
+	ast.Inspect(file, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		if x, ok := sel.X.(*ast.Ident); ok && x.Name == localName {
+			refs = append(refs, &SymbolReference{
+				Path:      f.Path,
+				Line:      fset.Position(sel.Pos()).Line,
+				Generator: f.Generator,
+				Symbol:    localName + "." + sel.Sel.Name,
+			})
+		}
+		return true
+	})
+	return refs
+}
+
+// findCycles returns a shortest cycle through the packages of each strongly
+// connected component of the import graph that has one.
+func findCycles(imports map[string]map[string]*PackageImport) []*ImportCycle {
+	var cycles []*ImportCycle
+	for _, component := range stronglyConnected(imports) {
+		start := component[0]
+		inComponent := map[string]bool{}
+		for _, pkg := range component {
+			inComponent[pkg] = true
+		}
+		if len(component) == 1 && imports[start][start] == nil {
+			continue
+		}
+		// Breadth-first search for the shortest path back to start.
+		prev := map[string]*PackageImport{}
+		queue := []string{start}
+		var last *PackageImport
+		for len(queue) > 0 && last == nil {
+			from := queue[0]
+			queue = queue[1:]
+			for _, to := range sortedKeys(imports[from]) {
+				imp := imports[from][to]
+				if to == start {
+					last = imp
+					break
+				}
+				if _, seen := prev[to]; !seen && inComponent[to] {
+					prev[to] = imp
+					queue = append(queue, to)
+				}
+			}
+		}
+		cycle := &ImportCycle{Imports: []*PackageImport{last}}
+		for pkg := last.From; pkg != start; pkg = prev[pkg].From {
+			cycle.Imports = append([]*PackageImport{prev[pkg]}, cycle.Imports...)
+		}
+		cycles = append(cycles, cycle)
+	}
+	sort.Slice(cycles, func(i, j int) bool { return cycles[i].Imports[0].From < cycles[j].Imports[0].From })
+	return cycles
+}
+
+// stronglyConnected returns the strongly connected components of the import
+// graph, each sorted by import path, using Tarjan's algorithm.
+func stronglyConnected(imports map[string]map[string]*PackageImport) [][]string {
+	index := map[string]int{}
+	lowLink := map[string]int{}
+	onStack := map[string]bool{}
+	var stack []string
+	var components [][]string
+	var visit func(pkg string)
+	visit = func(pkg string) {
+		index[pkg] = len(index)
+		lowLink[pkg] = index[pkg]
+		stack = append(stack, pkg)
+		onStack[pkg] = true
+		for _, to := range sortedKeys(imports[pkg]) {
+			if _, visited := index[to]; !visited {
+				visit(to)
+				if lowLink[to] < lowLink[pkg] {
+					lowLink[pkg] = lowLink[to]
+				}
+			} else if onStack[to] && index[to] < lowLink[pkg] {
+				lowLink[pkg] = index[to]
+			}
+		}
+		if lowLink[pkg] != index[pkg] {
+			return
+		}
+		var component []string
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component = append(component, top)
+			if top == pkg {
+				break
+			}
+		}
+		sort.Strings(component)
+		components = append(components, component)
+	}
+	for _, pkg := range sortedKeys(imports) {
+		if _, visited := index[pkg]; !visited {
+			visit(pkg)
+		}
+	}
+	return components
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// readModulePath returns the module path declared by the go.mod file in the
+// directory root.
+func readModulePath(root string) (string, error) {
+	content, err := os.ReadFile(filepath.Join(root, "go.mod"))
+	if err != nil {
+		return "", err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "module" {
+			if modulePath, err := strconv.Unquote(fields[1]); err == nil {
+				return modulePath, nil
+			}
+			return fields[1], nil
+		}
+	}
+	return "", errors.New(filepath.Join(root, "go.mod") + ": no module directive")
+}
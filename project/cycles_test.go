@@ -0,0 +1,84 @@
+package project
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProject_ImportCycles(t *testing.T) {
+	files := map[string]string{
+		"a/a.go": "package a\n\nimport \"example.com/m/b\"\n\nvar A = b.B\n",
+		"b/b.go": "package b\n\nimport (\n\tbb \"example.com/m/c\"\n\t\"fmt\"\n)\n\nvar B = fmt.Sprint(bb.C,\n\tbb.D)\n",
+		"c/c.go": "package c\n\nimport _ \"example.com/m/a\"\n\nvar C, D = 1, 2\n",
+		// d imports a, but isn't part of the cycle.
+		"d/d.go": "package d\n\nimport \"example.com/m/a\"\n\nvar D = a.A\n",
+		// Test files don't count.
+		"d/d_test.go": "package d\n\nimport \"example.com/m/e\"\n",
+		"e/e.go":      "package e\n\nimport \"example.com/m/d\"\n\nvar E = d.D\n",
+	}
+	generators := map[string]string{"a/a.go": "agen", "b/b.go": "bgen", "c/c.go": "cgen"}
+	var gens []Generator
+	for path, content := range files {
+		path, content := path, content
+		name := generators[path]
+		if name == "" {
+			name = path
+		}
+		gens = append(gens, GeneratorFunc(name, func(ctx context.Context, p *Project, inputs any) error {
+			return p.AddFile(path, []byte(content))
+		}))
+	}
+	p := New(t.TempDir())
+	if err := Run(context.Background(), p, nil, gens...); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	err := p.CheckImportCycles("example.com/m")
+	var cycleErr *ImportCycleError
+	if !errors.As(err, &cycleErr) || len(cycleErr.Cycles) != 1 {
+		t.Fatalf("CheckImportCycles() error = %v, want an *ImportCycleError with one cycle", err)
+	}
+	want := `import cycle: example.com/m/a -> example.com/m/b -> example.com/m/c -> example.com/m/a
+	example.com/m/a imports example.com/m/b:
+		a/a.go:5: b.B (generated by "agen")
+	example.com/m/b imports example.com/m/c:
+		b/b.go:8: bb.C (generated by "bgen")
+		b/b.go:9: bb.D (generated by "bgen")
+	example.com/m/c imports example.com/m/a:
+		c/c.go:3: "example.com/m/a" (generated by "cgen")`
+	if got := err.Error(); got != want {
+		t.Errorf("CheckImportCycles() error:\n%s\nwant:\n%s", got, want)
+	}
+
+	if err := p.CheckImportCycles("example.com/other"); err != nil {
+		t.Errorf("CheckImportCycles() of another module got error %v, want nil", err)
+	}
+}
+
+func TestRunMain_importCycle(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/m\n\ngo 1.18\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	gen := GeneratorFunc("gen", func(ctx context.Context, p *Project, inputs any) error {
+		if err := p.AddFile("a/a.go", []byte("package a\n\nimport \"example.com/m/b\"\n\nvar A = b.B\n")); err != nil {
+			return err
+		}
+		return p.AddFile("b/b.go", []byte("package b\n\nimport \"example.com/m/a\"\n\nvar B = a.A\n"))
+	})
+	var stdout, stderr bytes.Buffer
+	if code := runMain(context.Background(), []string{"-C", root}, &stdout, &stderr, nil, []Generator{gen}); code != 1 {
+		t.Errorf("runMain() exited with %d, want 1", code)
+	}
+	if !strings.Contains(stderr.String(), "import cycle: example.com/m/a -> example.com/m/b -> example.com/m/a") {
+		t.Errorf("runMain() printed %q, want an import cycle", stderr.String())
+	}
+	if _, err := os.Stat(filepath.Join(root, "a", "a.go")); err == nil {
+		t.Errorf("runMain() wrote a/a.go despite the import cycle")
+	}
+}
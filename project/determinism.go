@@ -0,0 +1,154 @@
+package project
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/meta-programming/go-codegenutil/debugutil"
+)
+
+// NondeterministicFile describes a generated file whose content differs between
+// runs of its generator with the same inputs.
+type NondeterministicFile struct {
+	// Path is the slash-separated path of the file relative to the root of the
+	// project.
+	Path string
+	// Generator is the name of the generator that produced the file.
+	Generator string
+	// Template is the path of the template that produced the file, if the
+	// generator is a *Manifest.
+	Template string
+	// Contents are the contents of the file in each run. The content of a run
+	// is nil if the file wasn't produced by that run.
+	Contents [][]byte
+}
+
+// differingRun returns the index of the first run whose content differs from
+// the first, or -1 if every run produced the same content.
+func (n *NondeterministicFile) differingRun() int {
+	for i, content := range n.Contents[1:] {
+		if (content == nil) != (n.Contents[0] == nil) || !bytes.Equal(content, n.Contents[0]) {
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// Diff returns the differences between the first run and the first run that
+// produced different content, in the unified format of "diff -u".
+func (n *NondeterministicFile) Diff() string {
+	i := n.differingRun()
+	if i < 0 {
+		return ""
+	}
+	label := func(run int) string {
+		if n.Contents[run] == nil {
+			return "/dev/null"
+		}
+		return fmt.Sprintf("run%d/%s", run+1, n.Path)
+	}
+	return debugutil.UnifiedDiff(label(0), label(i), string(n.Contents[0]), string(n.Contents[i]))
+}
+
+// Hint returns a likely cause of the differences, or the empty string if there
+// is no obvious one. Lines that are only reordered between runs usually come
+// from ranging over a map, whose iteration order is randomized.
+func (n *NondeterministicFile) Hint() string {
+	i := n.differingRun()
+	if i < 0 || n.Contents[0] == nil || n.Contents[i] == nil {
+		return ""
+	}
+	a := strings.Split(string(n.Contents[0]), "\n")
+	b := strings.Split(string(n.Contents[i]), "\n")
+	if len(a) != len(b) {
+		return ""
+	}
+	sort.Strings(a)
+	sort.Strings(b)
+	for j := range a {
+		if a[j] != b[j] {
+			return ""
+		}
+	}
+	return "the runs produced the same lines in a different order; this usually comes from ranging over a map, so sort its keys first"
+}
+
+// CheckDeterminism runs each of the generators the given number of times, at
+// least twice, with the same inputs and returns the files whose content
+// differs between the runs, sorted by path. It doesn't modify the file system.
+//
+// The runtime randomizes the iteration order of maps each time they are
+// ranged over, so generators that depend on it produce different output in
+// different runs of the same process; more runs make that more likely to be
+// noticed.
+func CheckDeterminism(ctx context.Context, root string, runs int, inputs any, generators ...Generator) ([]*NondeterministicFile, error) {
+	if runs < 2 {
+		runs = 2
+	}
+	var found []*NondeterministicFile
+	for _, g := range generators {
+		byPath := map[string]*NondeterministicFile{}
+		for run := 0; run < runs; run++ {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			view := New(root)
+			if err := g.Generate(ctx, view, inputs); err != nil {
+				return nil, fmt.Errorf("generator %q: run %d: %w", g.Name(), run+1, err)
+			}
+			for _, f := range view.Files() {
+				n := byPath[f.Path]
+				if n == nil {
+					n = &NondeterministicFile{Path: f.Path, Generator: g.Name(), Contents: make([][]byte, runs)}
+					byPath[f.Path] = n
+				}
+				n.Contents[run] = append([]byte{}, f.Content...)
+			}
+		}
+		for _, n := range byPath {
+			if n.differingRun() < 0 {
+				continue
+			}
+			if m, ok := g.(*Manifest); ok {
+				for _, step := range m.Templates {
+					for _, output := range step.outputs() {
+						if output == n.Path {
+							n.Template = step.Template
+						}
+					}
+				}
+			}
+			found = append(found, n)
+		}
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].Path < found[j].Path })
+	return found, nil
+}
+
+// WriteNondeterminismReport writes a report of nondeterministic files for
+// people to w: a line naming the generator and template of each file, a hint
+// of the likely cause if there is one, and the diff between two of its runs.
+func WriteNondeterminismReport(w io.Writer, files []*NondeterministicFile) error {
+	for _, n := range files {
+		source := fmt.Sprintf("generator %q", n.Generator)
+		if n.Template != "" {
+			source += fmt.Sprintf(", template %s", n.Template)
+		}
+		if _, err := fmt.Fprintf(w, "%s differs between runs (%s):\n", n.Path, source); err != nil {
+			return err
+		}
+		if hint := n.Hint(); hint != "" {
+			if _, err := fmt.Fprintf(w, "hint: %s\n", hint); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "%s\n", n.Diff()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
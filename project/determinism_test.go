@@ -0,0 +1,66 @@
+package project
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+// rotatingGenerator returns a generator whose a.txt lists the same lines in a
+// different order in each run, as if it ranged over a map.
+func rotatingGenerator() Generator {
+	run := 0
+	return GeneratorFunc("rotating", func(ctx context.Context, p *Project, inputs any) error {
+		lines := []string{"a", "b", "c"}
+		lines = append(lines[run%3:], lines[:run%3]...)
+		run++
+		if err := p.AddFile("stable.txt", []byte("stable\n")); err != nil {
+			return err
+		}
+		return p.AddFile("a.txt", []byte(strings.Join(lines, "\n")+"\n"))
+	})
+}
+
+func TestCheckDeterminism(t *testing.T) {
+	files, err := CheckDeterminism(context.Background(), t.TempDir(), 3, nil, rotatingGenerator())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Path != "a.txt" || files[0].Generator != "rotating" {
+		t.Fatalf("CheckDeterminism() = %+v, want a.txt of generator rotating", files)
+	}
+	if len(files[0].Contents) != 3 {
+		t.Errorf("got %d contents, want one per run", len(files[0].Contents))
+	}
+	if hint := files[0].Hint(); !strings.Contains(hint, "ranging over a map") {
+		t.Errorf("Hint() = %q, want a hint about maps", hint)
+	}
+	wantDiff := `--- run1/a.txt
++++ run2/a.txt
+@@ -1,3 +1,3 @@
+-a
+ b
+ c
++a
+`
+	if got := files[0].Diff(); got != wantDiff {
+		t.Errorf("Diff() = %q, want %q", got, wantDiff)
+	}
+}
+
+func TestRunMain_checkDeterminism(t *testing.T) {
+	stable := GeneratorFunc("stable", func(ctx context.Context, p *Project, inputs any) error {
+		return p.AddFile("a.txt", []byte("a\n"))
+	})
+	var stdout, stderr bytes.Buffer
+	if code := runMain(context.Background(), []string{"-C", t.TempDir(), "-check-determinism"}, &stdout, &stderr, nil, []Generator{stable}); code != 0 || stdout.Len() != 0 {
+		t.Errorf("runMain(-check-determinism) of a deterministic generator exited with %d and printed %q, want 0 and nothing", code, stdout.String())
+	}
+	if code := runMain(context.Background(), []string{"-C", t.TempDir(), "-check-determinism"}, &stdout, &stderr, nil, []Generator{rotatingGenerator()}); code != 1 {
+		t.Errorf("runMain(-check-determinism) of a nondeterministic generator exited with %d, want 1", code)
+	}
+	if want := `a.txt differs between runs (generator "rotating"):` + "\nhint: "; !strings.HasPrefix(stdout.String(), want) {
+		t.Errorf("runMain(-check-determinism) printed\n%s\nwant it to start with\n%s", stdout.String(), want)
+	}
+}
@@ -0,0 +1,255 @@
+package project
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing/fstest"
+)
+
+// WritableFS is a file system that the files of a project are written to. Its
+// names are slash-separated paths relative to the root of the project, as
+// those of io/fs, and "." is the root.
+//
+// DirFS writes to a directory of the operating system and MemFS keeps the
+// files in memory, for tests or for build systems that collect the outputs of
+// generators themselves.
+type WritableFS interface {
+	fs.FS
+	// MkdirAll creates the directory name and its missing parents, like
+	// os.MkdirAll.
+	MkdirAll(name string, perm fs.FileMode) error
+	// WriteFile writes data to the file name, whose parent directory must
+	// exist, creating it if needed, like os.WriteFile.
+	WriteFile(name string, data []byte, perm fs.FileMode) error
+	// Rename renames the file oldname to newname, replacing it if it exists,
+	// like os.Rename.
+	Rename(oldname, newname string) error
+	// Remove removes the file or empty directory name, like os.Remove.
+	Remove(name string) error
+}
+
+// DirFS returns a WritableFS for the directory tree rooted at dir of the
+// operating system.
+func DirFS(dir string) WritableFS {
+	return &dirFS{FS: os.DirFS(dir), dir: dir}
+}
+
+type dirFS struct {
+	fs.FS
+	dir string
+}
+
+// join returns the operating system name of the file name of d.
+func (d *dirFS) join(op, name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	return filepath.Join(d.dir, filepath.FromSlash(name)), nil
+}
+
+func (d *dirFS) MkdirAll(name string, perm fs.FileMode) error {
+	name, err := d.join("mkdir", name)
+	if err != nil {
+		return err
+	}
+	return os.MkdirAll(name, perm)
+}
+
+func (d *dirFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	name, err := d.join("open", name)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(name, data, perm)
+}
+
+func (d *dirFS) Rename(oldname, newname string) error {
+	oldname, err := d.join("rename", oldname)
+	if err != nil {
+		return err
+	}
+	if newname, err = d.join("rename", newname); err != nil {
+		return err
+	}
+	return os.Rename(oldname, newname)
+}
+
+func (d *dirFS) Remove(name string) error {
+	name, err := d.join("remove", name)
+	if err != nil {
+		return err
+	}
+	return os.Remove(name)
+}
+
+// MemFS is a WritableFS that keeps its files in memory. It is safe for
+// concurrent use. The zero value is an empty file system.
+type MemFS struct {
+	mu    sync.Mutex
+	files fstest.MapFS
+}
+
+// Open opens the file name.
+func (m *MemFS) Open(name string) (fs.File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// The files opened by MapFS keep the data of the file, which MemFS never
+	// modifies in place, and directories list their entries when opened.
+	return m.files.Open(name)
+}
+
+// Paths returns the names of the regular files of m, sorted.
+func (m *MemFS) Paths() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var paths []string
+	for name, f := range m.files {
+		if f.Mode.IsRegular() {
+			paths = append(paths, name)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// stat returns the mode of the file name, which MapFS also gives to the
+// implicit parent directories of its files, and whether it exists. m must be
+// locked.
+func (m *MemFS) stat(name string) (fs.FileMode, bool) {
+	if f, ok := m.files[name]; ok {
+		return f.Mode, true
+	}
+	if name == "." {
+		return fs.ModeDir | 0o755, true
+	}
+	for other := range m.files {
+		if strings.HasPrefix(other, name+"/") {
+			return fs.ModeDir | 0o755, true
+		}
+	}
+	return 0, false
+}
+
+// checkParent returns an error if the parent directory of name doesn't exist.
+// m must be locked.
+func (m *MemFS) checkParent(op, name string) error {
+	if !fs.ValidPath(name) || name == "." {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	mode, ok := m.stat(path.Dir(name))
+	if !ok {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	if !mode.IsDir() {
+		return &fs.PathError{Op: op, Path: name, Err: errNotDir}
+	}
+	return nil
+}
+
+// Errors of MemFS, in addition to those of io/fs.
+var (
+	errNotDir   = errors.New("not a directory")
+	errNotEmpty = errors.New("directory not empty")
+)
+
+func (m *MemFS) MkdirAll(name string, perm fs.FileMode) error {
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrInvalid}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if name == "." {
+		return nil
+	}
+	var missing []string
+	for dir := name; dir != "."; dir = path.Dir(dir) {
+		mode, ok := m.stat(dir)
+		if ok && !mode.IsDir() {
+			return &fs.PathError{Op: "mkdir", Path: name, Err: errNotDir}
+		}
+		if ok {
+			break
+		}
+		missing = append(missing, dir)
+	}
+	if m.files == nil {
+		m.files = fstest.MapFS{}
+	}
+	for _, dir := range missing {
+		m.files[dir] = &fstest.MapFile{Mode: fs.ModeDir | perm.Perm()}
+	}
+	return nil
+}
+
+func (m *MemFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.checkParent("open", name); err != nil {
+		return err
+	}
+	mode := perm.Perm()
+	if existing, ok := m.stat(name); ok {
+		if existing.IsDir() {
+			return &fs.PathError{Op: "open", Path: name, Err: fs.ErrExist}
+		}
+		mode = existing
+	}
+	if m.files == nil {
+		m.files = fstest.MapFS{}
+	}
+	m.files[name] = &fstest.MapFile{Data: append([]byte(nil), data...), Mode: mode}
+	return nil
+}
+
+// Rename renames the file oldname to newname. Unlike os.Rename, it doesn't
+// rename directories.
+func (m *MemFS) Rename(oldname, newname string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.checkParent("rename", oldname); err != nil {
+		return err
+	}
+	if err := m.checkParent("rename", newname); err != nil {
+		return err
+	}
+	f, ok := m.files[oldname]
+	if !ok {
+		return &fs.PathError{Op: "rename", Path: oldname, Err: fs.ErrNotExist}
+	}
+	if !f.Mode.IsRegular() {
+		return &fs.PathError{Op: "rename", Path: oldname, Err: fs.ErrInvalid}
+	}
+	if mode, ok := m.stat(newname); ok && mode.IsDir() {
+		return &fs.PathError{Op: "rename", Path: newname, Err: fs.ErrExist}
+	}
+	delete(m.files, oldname)
+	m.files[newname] = f
+	return nil
+}
+
+func (m *MemFS) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.checkParent("remove", name); err != nil {
+		return err
+	}
+	mode, ok := m.stat(name)
+	if !ok {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+	if mode.IsDir() {
+		for other := range m.files {
+			if strings.HasPrefix(other, name+"/") {
+				return &fs.PathError{Op: "remove", Path: name, Err: errNotEmpty}
+			}
+		}
+	}
+	delete(m.files, name)
+	return nil
+}
@@ -0,0 +1,75 @@
+package project
+
+import (
+	"errors"
+	"io/fs"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestMemFS(t *testing.T) {
+	m := &MemFS{}
+	if err := m.WriteFile("a/b.txt", []byte("b"), 0o644); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("WriteFile() without a parent directory got error %v, want fs.ErrNotExist", err)
+	}
+	if err := m.MkdirAll("a/c", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a/b.txt", "a/c/d.txt", "e.txt"} {
+		if err := m.WriteFile(name, []byte(name), 0o644); err != nil {
+			t.Fatalf("WriteFile(%q) error: %v", name, err)
+		}
+	}
+	if err := m.MkdirAll("e.txt/f", 0o755); err == nil {
+		t.Error("MkdirAll() below a file succeeded")
+	}
+	if err := m.Rename("e.txt", "a/e.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Remove("a/c"); err == nil {
+		t.Error("Remove() of a non-empty directory succeeded")
+	}
+	if err := m.Remove("a/c/d.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Remove("a/c"); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a/b.txt", "a/e.txt"}; !reflect.DeepEqual(m.Paths(), want) {
+		t.Errorf("Paths() = %q, want %q", m.Paths(), want)
+	}
+	if got, err := fs.ReadFile(m, "a/e.txt"); err != nil || string(got) != "e.txt" {
+		t.Errorf("ReadFile(a/e.txt) = %q, %v; want %q", got, err, "e.txt")
+	}
+	if err := fstest.TestFS(m, "a/b.txt", "a/e.txt"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNewFS(t *testing.T) {
+	m := &MemFS{}
+	if err := m.MkdirAll("gen", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	p := NewFS(m)
+	for path, content := range map[string]string{"gen/a.go": "package gen\n", "gen/sub/b.go": "package sub\n"} {
+		if err := p.AddFile(path, []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, opts := range [][]WriteOption{{DeleteOrphans()}, {Transactional(), DeleteOrphans()}} {
+		if err := m.WriteFile("gen/old.go", []byte("// Code generated by gen. DO NOT EDIT.\n\npackage gen\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := p.Write(opts...); err != nil {
+			t.Fatalf("Write() error: %v", err)
+		}
+		if want := []string{"gen/a.go", "gen/sub/b.go"}; !reflect.DeepEqual(m.Paths(), want) {
+			t.Errorf("Write() left %q, want %q", m.Paths(), want)
+		}
+		if stale, err := p.Verify(); err != nil || len(stale) != 0 {
+			t.Errorf("Verify() after Write() got %d stale files, error %v; want none", len(stale), err)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package project
+
+import (
+	"context"
+	"fmt"
+	"path"
+)
+
+// Generator is a code generator that adds files to a Project.
+type Generator interface {
+	// Name returns a short name that identifies the generator in errors and
+	// in the Generator field of the files it produces.
+	Name() string
+	// Generate adds the generator's files to p. The inputs are those passed to
+	// Run, which are shared by all of the generators being run.
+	Generate(ctx context.Context, p *Project, inputs any) error
+}
+
+// Run runs the generators in order and adds the files they produce to p.
+//
+// Each generator is given an empty project with the same root as p, so
+// generators are independent of each other and cannot observe each other's
+// output. Once a generator returns, its files are added to p, and Run returns a
+// *ConflictError if one of them has the same path as a file that p already
+// has. If a generator fails, the files it produced are discarded and Run
+// returns its error.
+func Run(ctx context.Context, p *Project, inputs any, generators ...Generator) error {
+	names := map[string]bool{}
+	for _, g := range generators {
+		if names[g.Name()] {
+			return fmt.Errorf("more than one generator is named %q", g.Name())
+		}
+		names[g.Name()] = true
+	}
+	for _, g := range generators {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		view := p.view()
+		if err := g.Generate(ctx, view, inputs); err != nil {
+			return fmt.Errorf("generator %q: %w", g.Name(), err)
+		}
+		if err := p.merge(view, g.Name()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// merge adds the files of src to p, attributing them to the named generator.
+// Nothing is added if any of the files conflicts with a file of p.
+func (p *Project) merge(src *Project, generator string) error {
+	files := src.Files()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, f := range files {
+		if existing, ok := p.files[f.Path]; ok {
+			return &ConflictError{Path: f.Path, Existing: existing.Generator, New: generator}
+		}
+		if err := p.checkPackageDoc(&File{Path: f.Path, Content: f.Content, Generator: generator, packageDoc: f.packageDoc}); err != nil {
+			return err
+		}
+	}
+	for _, f := range files {
+		f.Generator = generator
+		p.files[f.Path] = f
+		if f.packageDoc {
+			p.docs[path.Dir(f.Path)] = f.Path
+		}
+	}
+	return nil
+}
+
+// GeneratorFunc returns a Generator with the given name that calls fn.
+func GeneratorFunc(name string, fn func(ctx context.Context, p *Project, inputs any) error) Generator {
+	return generatorFunc{name, fn}
+}
+
+type generatorFunc struct {
+	name string
+	fn   func(ctx context.Context, p *Project, inputs any) error
+}
+
+func (g generatorFunc) Name() string { return g.name }
+
+func (g generatorFunc) Generate(ctx context.Context, p *Project, inputs any) error {
+	return g.fn(ctx, p, inputs)
+}
@@ -0,0 +1,174 @@
+package project
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/meta-programming/go-codegenutil/debugutil"
+)
+
+// Main is the main function of a generator program. It runs the generators
+// with Run and writes the files they produce, and exits with a non-zero status
+// if that fails. Main parses the following flags of the command line:
+//
+//	-C dir	the root directory of the project (default ".")
+//	-progress
+//		report the progress of writing the files on the standard error
+//	-verify	don't write the files; instead, report the generated files that
+//		are missing or out of date and exit with status 1 if there are any
+//	-plan	don't write the files; instead, report the files that writing
+//		them would create, update or leave unchanged, and the orphaned
+//		generated files that -delete-orphans would delete; see Plan
+//	-delete-orphans
+//		delete the generated files that the generators no longer
+//		produce; see DeleteOrphans
+//	-json	with -verify or -plan, write the report as JSON; see
+//		WriteJSONReport and WriteJSONPlan
+//	-ignore-volatile
+//		with -verify, ignore changes to the version and time stamp
+//		comments of generated files; see debugutil.IgnoreVolatileLines
+//	-symbols-report file
+//		once the files are written, write the report of the symbols of
+//		other packages that the generated Go files refer to to file as
+//		JSON; see WithSymbolsReport
+//	-check-determinism
+//		don't write the files; instead, run the generators several
+//		times, report the files whose content differs between runs and
+//		exit with status 1 if there are any; see CheckDeterminism
+//
+// The -verify flag is what the genverify command adds to the command lines of
+// generator programs to check that their output is up to date.
+//
+// If the root directory has a go.mod file, Main also fails if the generated
+// packages import each other in cycles; see CheckImportCycles.
+func Main(inputs any, generators ...Generator) {
+	os.Exit(runMain(context.Background(), os.Args[1:], os.Stdout, os.Stderr, inputs, generators))
+}
+
+func runMain(ctx context.Context, args []string, stdout, stderr io.Writer, inputs any, generators []Generator) int {
+	flags := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	root := flags.String("C", ".", "root `dir`ectory of the project")
+	progress := flags.Bool("progress", false, "report the progress of writing the files")
+	verify := flags.Bool("verify", false, "report stale generated files instead of writing them")
+	plan := flags.Bool("plan", false, "report the changes that writing the files would make instead of writing them")
+	deleteOrphans := flags.Bool("delete-orphans", false, "delete generated files that the generators no longer produce")
+	jsonReport := flags.Bool("json", false, "with -verify or -plan, write the report as JSON")
+	ignoreVolatile := flags.Bool("ignore-volatile", false, "with -verify, ignore changes to version and time stamp comments")
+	symbolsReport := flags.String("symbols-report", "", "write the report of the symbols that the generated files use to `file` as JSON")
+	checkDeterminism := flags.Bool("check-determinism", false, "report generated files whose content differs between runs instead of writing them")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 0 {
+		fmt.Fprintf(stderr, "unexpected arguments: %q\n", flags.Args())
+		return 2
+	}
+
+	if *checkDeterminism {
+		return runCheckDeterminism(ctx, *root, stdout, stderr, inputs, generators)
+	}
+
+	p := New(*root)
+	if err := Run(ctx, p, inputs, generators...); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if modulePath, err := readModulePath(*root); err == nil {
+		if err := p.CheckImportCycles(modulePath); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+	}
+	if *plan {
+		return runPlan(p, *jsonReport, stdout, stderr)
+	}
+	if !*verify {
+		var writeOpts []WriteOption
+		if *deleteOrphans {
+			writeOpts = append(writeOpts, DeleteOrphans())
+		}
+		if *progress {
+			writeOpts = append(writeOpts, OnWriteProgress(func(wp WriteProgress) {
+				fmt.Fprintf(stderr, "\rwriting files: %d/%d", wp.Done, wp.Total)
+				if wp.Done == wp.Total {
+					fmt.Fprintln(stderr)
+				}
+			}))
+		}
+		var report bytes.Buffer
+		if *symbolsReport != "" {
+			writeOpts = append(writeOpts, WithSymbolsReport(&report))
+		}
+		if err := p.WriteContext(ctx, writeOpts...); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		if *symbolsReport != "" {
+			if err := os.WriteFile(*symbolsReport, report.Bytes(), 0o644); err != nil {
+				fmt.Fprintln(stderr, err)
+				return 1
+			}
+		}
+		return 0
+	}
+	var verifyOpts []debugutil.Option
+	if *ignoreVolatile {
+		verifyOpts = append(verifyOpts, debugutil.IgnoreVolatileLines())
+	}
+	stale, err := p.Verify(verifyOpts...)
+	if err == nil {
+		if *jsonReport {
+			err = WriteJSONReport(stdout, stale)
+		} else {
+			err = WriteStaleReport(stdout, stale)
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if len(stale) > 0 {
+		return 1
+	}
+	return 0
+}
+
+func runPlan(p *Project, jsonReport bool, stdout, stderr io.Writer) int {
+	plan, err := p.Plan()
+	if err == nil {
+		if jsonReport {
+			err = WriteJSONPlan(stdout, plan)
+		} else {
+			err = WritePlan(stdout, plan)
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// determinismRuns is the number of runs of each generator with
+// -check-determinism.
+const determinismRuns = 3
+
+func runCheckDeterminism(ctx context.Context, root string, stdout, stderr io.Writer, inputs any, generators []Generator) int {
+	files, err := CheckDeterminism(ctx, root, determinismRuns, inputs, generators...)
+	if err == nil {
+		err = WriteNondeterminismReport(stdout, files)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if len(files) > 0 {
+		return 1
+	}
+	return 0
+}
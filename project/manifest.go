@@ -0,0 +1,235 @@
+package project
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/meta-programming/go-codegenutil"
+	"github.com/meta-programming/go-codegenutil/codetemplate"
+	"github.com/meta-programming/go-codegenutil/symbolindex"
+	"gopkg.in/yaml.v3"
+)
+
+// Manifest describes a generation that executes codetemplate templates, so
+// that files can be generated, and verified, without writing a generator
+// program. A Manifest is a Generator.
+//
+// Manifests are stored in JSON or YAML files such as:
+//
+//	templates:
+//	- template: gen/enum.go.tmpl
+//	  data: [gen/colors.yaml]
+//	  package: example.com/m/colors
+//	  output: colors/colors_gen.go
+//
+// Paths in a manifest are slash-separated and relative to the directory of the
+// manifest file, which is usually the root of the project the files are
+// generated into.
+type Manifest struct {
+	// Path is the file the manifest was loaded from, which is the name of the
+	// manifest as a Generator.
+	Path string `json:"-" yaml:"-"`
+	// Dir is the directory that the template and data paths are relative to.
+	// LoadManifest sets it to the directory of the manifest file.
+	Dir string `json:"-" yaml:"-"`
+	// Templates are the templates to execute.
+	Templates []*TemplateStep `json:"templates" yaml:"templates"`
+}
+
+// TemplateStep is the execution of a template that produces a file of a
+// Manifest.
+type TemplateStep struct {
+	// Template is the path of the template file.
+	Template string `json:"template" yaml:"template"`
+	// Data are the paths of data files, which are read with
+	// codetemplate.ReadData.
+	Data []string `json:"data,omitempty" yaml:"data,omitempty"`
+	// Package is the import path of the generated file's package.
+	Package string `json:"package" yaml:"package"`
+	// PackageName is the name of the generated file's package, if it differs
+	// from the name assumed from Package.
+	PackageName string `json:"packageName,omitempty" yaml:"packageName,omitempty"`
+	// Output is the path of the generated file relative to the root of the
+	// project.
+	Output string `json:"output" yaml:"output"`
+	// KeepUnusedImports disables the removal of unused imports from the
+	// generated file.
+	KeepUnusedImports bool `json:"keepUnusedImports,omitempty" yaml:"keepUnusedImports,omitempty"`
+	// Platforms, if set, makes the template produce a variant of Output for
+	// each platform, such as "linux/amd64" or "windows", named as by
+	// Platform.FilePath. The template's "platform" function returns the
+	// Platform of the variant being generated, such as {{platform.GOOS}}.
+	Platforms []string `json:"platforms,omitempty" yaml:"platforms,omitempty"`
+	// BuildTags starts each platform variant with a //go:build line. See
+	// WithBuildTags.
+	BuildTags bool `json:"buildTags,omitempty" yaml:"buildTags,omitempty"`
+	// GoVersion, if set, is the target Go version of the generated file, such
+	// as "go1.20": code that requires a newer version fails the step. See
+	// codegenutil.WithGoVersion.
+	GoVersion string `json:"goVersion,omitempty" yaml:"goVersion,omitempty"`
+}
+
+// platforms returns the parsed Platforms of the step.
+func (step *TemplateStep) platforms() ([]Platform, error) {
+	var out []Platform
+	for _, s := range step.Platforms {
+		pl, err := ParsePlatform(s)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, pl)
+	}
+	return out, nil
+}
+
+// outputs returns the paths of the files produced by the step.
+func (step *TemplateStep) outputs() []string {
+	platforms, err := step.platforms()
+	if err != nil || len(platforms) == 0 {
+		return []string{step.Output}
+	}
+	var out []string
+	for _, pl := range platforms {
+		out = append(out, pl.FilePath(step.Output))
+	}
+	return out
+}
+
+// LoadManifest reads a manifest from a file whose extension is .json, .yaml or
+// .yml.
+func LoadManifest(name string) (*Manifest, error) {
+	content, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	m := &Manifest{Path: name, Dir: filepath.Dir(name)}
+	switch ext := filepath.Ext(name); ext {
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(content))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(m)
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(content))
+		dec.KnownFields(true)
+		err = dec.Decode(m)
+	default:
+		return nil, fmt.Errorf("%s: unsupported manifest extension %q, want .json, .yaml or .yml", name, ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	for i, step := range m.Templates {
+		if step.Template == "" || step.Package == "" || step.Output == "" {
+			return nil, fmt.Errorf("%s: template %d: template, package and output are required", name, i)
+		}
+		if _, err := step.platforms(); err != nil {
+			return nil, fmt.Errorf("%s: template %d: %w", name, i, err)
+		}
+		if step.GoVersion != "" && !codegenutil.IsValidGoVersion(step.GoVersion) {
+			return nil, fmt.Errorf("%s: template %d: invalid goVersion %q", name, i, step.GoVersion)
+		}
+	}
+	return m, nil
+}
+
+// Name returns the path of the manifest. It implements Generator.
+func (m *Manifest) Name() string { return m.Path }
+
+// Generate executes the templates of the manifest and adds the files they
+// produce to p. The inputs are ignored. It implements Generator.
+func (m *Manifest) Generate(ctx context.Context, p *Project, inputs any) error {
+	// The versions of the standard library are only loaded for the steps
+	// that target a Go version.
+	var versions *symbolindex.StdlibVersions
+	for _, step := range m.Templates {
+		if step.GoVersion != "" {
+			var err error
+			if versions, err = symbolindex.LoadStdlibVersions(ctx, ""); err != nil {
+				return err
+			}
+			break
+		}
+	}
+	for _, step := range m.Templates {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		platforms, err := step.platforms()
+		if err != nil {
+			return fmt.Errorf("%s: %w", step.Output, err)
+		}
+		if len(platforms) == 0 {
+			content, err := m.execute(ctx, step, Platform{}, versions)
+			if err != nil {
+				return fmt.Errorf("%s: %w", step.Output, err)
+			}
+			if err := p.AddFile(step.Output, content); err != nil {
+				return err
+			}
+			continue
+		}
+		var opts []PlatformOption
+		if step.BuildTags {
+			opts = append(opts, WithBuildTags())
+		}
+		gen := func(pl Platform) ([]byte, error) { return m.execute(ctx, step, pl, versions) }
+		if err := p.AddPlatformFiles(step.Output, platforms, gen, opts...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// execute executes the template of step for the given platform, which is the
+// zero Platform unless the step has platforms. versions is nil unless a step
+// of the manifest has a GoVersion.
+func (m *Manifest) execute(ctx context.Context, step *TemplateStep, pl Platform, versions *symbolindex.StdlibVersions) ([]byte, error) {
+	text, err := os.ReadFile(m.path(step.Template))
+	if err != nil {
+		return nil, err
+	}
+	opts := []codetemplate.Option{
+		codetemplate.WithName(filepath.Base(step.Template)),
+		codetemplate.WithFuncs(map[string]any{"platform": func() Platform { return pl }}),
+	}
+	if step.KeepUnusedImports {
+		opts = append(opts, codetemplate.KeepUnusedImports())
+	}
+	tmpl, err := codetemplate.Parse(string(text), opts...)
+	if err != nil {
+		return nil, err
+	}
+	var dataFiles []string
+	for _, name := range step.Data {
+		dataFiles = append(dataFiles, m.path(name))
+	}
+	data, err := codetemplate.ReadData(dataFiles...)
+	if err != nil {
+		return nil, err
+	}
+	pkg := codegenutil.AssumedPackageName(step.Package)
+	if step.PackageName != "" {
+		pkg = codegenutil.ExplicitPackageName(step.Package, step.PackageName)
+	}
+	var importsOpts []codegenutil.FileImportsOption
+	if step.GoVersion != "" {
+		if !codegenutil.IsValidGoVersion(step.GoVersion) {
+			return nil, fmt.Errorf("invalid goVersion %q", step.GoVersion)
+		}
+		importsOpts = append(importsOpts, codegenutil.WithGoVersion(step.GoVersion), codegenutil.WithSymbolVersions(versions.Since))
+	}
+	var out bytes.Buffer
+	if err := tmpl.ExecuteContext(ctx, codegenutil.NewFileImports(pkg, importsOpts...), &out, data); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// path returns the file path of a slash-separated path of the manifest.
+func (m *Manifest) path(name string) string {
+	return filepath.Join(m.Dir, filepath.FromSlash(name))
+}
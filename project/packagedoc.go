@@ -0,0 +1,140 @@
+package project
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/meta-programming/go-codegenutil"
+)
+
+// PackageDoc describes the doc.go file of a generated package, which carries
+// the package comment of the package. See AddPackageDoc.
+type PackageDoc struct {
+	// Package is the package of the file.
+	Package *codegenutil.Package
+	// Dir is the slash-separated directory of the package relative to the
+	// root of the project.
+	Dir string
+	// GeneratedBy is the name of the generator, which the file's header names
+	// in a "Code generated by ... DO NOT EDIT." line.
+	GeneratedBy string
+	// Header, if set, is the text of the header comment instead, such as the
+	// String of a *provenance.Provenance. It must contain such a line.
+	Header string
+	// Paragraphs are the paragraphs of the package comment, which are usually
+	// assembled from the inputs of the generator. The first one should start
+	// with "Package name".
+	Paragraphs []string
+	// ImportComment adds a canonical import comment to the package clause,
+	// such as package foo // import "example.com/foo", so that the go command
+	// rejects imports of the package by other paths in GOPATH mode.
+	ImportComment bool
+}
+
+// Path returns the path of the doc.go file.
+func (d *PackageDoc) Path() string { return path.Join(d.Dir, "doc.go") }
+
+// Content returns the content of the doc.go file.
+func (d *PackageDoc) Content() ([]byte, error) {
+	header := d.Header
+	if header == "" {
+		if d.GeneratedBy == "" {
+			return nil, fmt.Errorf("%s: the package doc needs GeneratedBy or Header", d.Path())
+		}
+		header = fmt.Sprintf("Code generated by %s DO NOT EDIT.", d.GeneratedBy)
+	}
+	var b strings.Builder
+	// The header is separated from the package comment by a blank line so
+	// that it isn't part of the package doc.
+	b.WriteString(lineComments(header))
+	b.WriteString("\n")
+	for i, paragraph := range d.Paragraphs {
+		if i > 0 {
+			b.WriteString("//\n")
+		}
+		b.WriteString(lineComments(paragraph))
+	}
+	b.WriteString("package " + d.Package.Name())
+	if d.ImportComment {
+		b.WriteString(" // import " + strconv.Quote(d.Package.ImportPath()))
+	}
+	b.WriteString("\n")
+	return []byte(b.String()), nil
+}
+
+// lineComments returns text as line comments.
+func lineComments(text string) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight("// "+line, " ")
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// AddPackageDoc adds the doc.go file described by doc to the project.
+//
+// The project then makes sure that the file is the only one of the package
+// that carries a package comment: adding another .go file with a package
+// comment to the directory of the package fails, as does AddPackageDoc if the
+// project already has one. Package comments of several files are concatenated
+// by go doc, so generators that add files to a package should leave the
+// package comment to the doc.go file.
+func (p *Project) AddPackageDoc(doc *PackageDoc) error {
+	content, err := doc.Content()
+	if err != nil {
+		return err
+	}
+	return p.add(&File{Path: doc.Path(), Content: content, packageDoc: true})
+}
+
+// checkPackageDoc returns an error if f conflicts with the package docs of p,
+// which must be locked.
+func (p *Project) checkPackageDoc(f *File) error {
+	if path.Ext(f.Path) != ".go" {
+		return nil
+	}
+	dir := path.Dir(f.Path)
+	if f.packageDoc {
+		for _, other := range p.files {
+			if path.Dir(other.Path) == dir && hasPackageComment(other) {
+				return &PackageDocError{Path: other.Path, DocPath: f.Path, Generator: other.Generator}
+			}
+		}
+		return nil
+	}
+	if docPath, ok := p.docs[dir]; ok && hasPackageComment(f) {
+		return &PackageDocError{Path: f.Path, DocPath: docPath, Generator: f.Generator}
+	}
+	return nil
+}
+
+// PackageDocError is returned when a Go file with a package comment is added to
+// a package whose doc.go file was added with AddPackageDoc, or the other way
+// around.
+type PackageDocError struct {
+	// Path is the path of the file with the package comment.
+	Path string
+	// DocPath is the path of the doc.go file.
+	DocPath string
+	// Generator is the name of the generator of the file with the package
+	// comment, if known.
+	Generator string
+}
+
+func (e *PackageDocError) Error() string {
+	return fmt.Sprintf("%s, generated by %s, has a package comment, but the package doc is generated in %s", e.Path, generatorName(e.Generator), e.DocPath)
+}
+
+// hasPackageComment reports whether f is a Go file with a package comment.
+// Files that don't parse are ignored.
+func hasPackageComment(f *File) bool {
+	if path.Ext(f.Path) != ".go" {
+		return false
+	}
+	parsed, err := parser.ParseFile(token.NewFileSet(), f.Path, f.Content, parser.PackageClauseOnly|parser.ParseComments)
+	return err == nil && parsed.Doc != nil
+}
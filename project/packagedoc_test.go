@@ -0,0 +1,72 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/meta-programming/go-codegenutil"
+)
+
+func TestProject_AddPackageDoc(t *testing.T) {
+	p := New(t.TempDir())
+	doc := &PackageDoc{
+		Package:       codegenutil.AssumedPackageName("example.com/m/colors"),
+		Dir:           "colors",
+		GeneratedBy:   "enumgen",
+		Paragraphs:    []string{"Package colors defines the colors of the palette.", "Colors are generated from palette.yaml."},
+		ImportComment: true,
+	}
+	if err := p.AddPackageDoc(doc); err != nil {
+		t.Fatalf("AddPackageDoc() error: %v", err)
+	}
+	f, ok := p.File("colors/doc.go")
+	if !ok {
+		t.Fatalf("AddPackageDoc() didn't add colors/doc.go")
+	}
+	want := `// Code generated by enumgen DO NOT EDIT.
+
+// Package colors defines the colors of the palette.
+//
+// Colors are generated from palette.yaml.
+package colors // import "example.com/m/colors"
+`
+	if string(f.Content) != want {
+		t.Errorf("AddPackageDoc() added\n%s\nwant\n%s", f.Content, want)
+	}
+
+	if err := p.AddFile("colors/colors.go", []byte("// Code generated by enumgen DO NOT EDIT.\n\npackage colors\n")); err != nil {
+		t.Errorf("AddFile() of a file without a package comment got error %v", err)
+	}
+	var docErr *PackageDocError
+	if err := p.AddFile("colors/more.go", []byte("// Package colors is documented twice.\npackage colors\n")); !errors.As(err, &docErr) || docErr.DocPath != "colors/doc.go" {
+		t.Errorf("AddFile() of a file with a package comment got error %v, want *PackageDocError", err)
+	}
+	if err := p.AddFile("other/other.go", []byte("// Package other is another package.\npackage other\n")); err != nil {
+		t.Errorf("AddFile() of a file with a package comment in another package got error %v", err)
+	}
+	if err := p.AddPackageDoc(&PackageDoc{Package: codegenutil.AssumedPackageName("example.com/m/other"), Dir: "other", GeneratedBy: "x"}); !errors.As(err, &docErr) || docErr.Path != "other/other.go" {
+		t.Errorf("AddPackageDoc() for a package with a package comment got error %v, want *PackageDocError", err)
+	}
+	if err := p.AddPackageDoc(&PackageDoc{Package: codegenutil.AssumedPackageName("example.com/m/x"), Dir: "x"}); err == nil {
+		t.Errorf("AddPackageDoc() without GeneratedBy or Header succeeded, want an error")
+	}
+}
+
+func TestRun_packageDoc(t *testing.T) {
+	docGen := GeneratorFunc("docs", func(ctx context.Context, p *Project, inputs any) error {
+		return p.AddPackageDoc(&PackageDoc{Package: codegenutil.AssumedPackageName("example.com/m/a"), Dir: "a", GeneratedBy: "docs", Paragraphs: []string{"Package a is generated."}})
+	})
+	fileGen := GeneratorFunc("files", func(ctx context.Context, p *Project, inputs any) error {
+		return p.AddFile("a/a.go", []byte("// Package a is generated here too.\npackage a\n"))
+	})
+	var docErr *PackageDocError
+	err := Run(context.Background(), New(t.TempDir()), nil, docGen, fileGen)
+	if !errors.As(err, &docErr) || docErr.Generator != "files" {
+		t.Errorf("Run() got error %v, want a *PackageDocError for the files generator", err)
+	}
+	want := `a/a.go, generated by "files", has a package comment, but the package doc is generated in a/doc.go`
+	if err == nil || err.Error() != want {
+		t.Errorf("Run() got error %v, want %q", err, want)
+	}
+}
@@ -0,0 +1,227 @@
+package project
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Action is what writing a project would do to a file.
+type Action string
+
+// Actions of a Plan.
+const (
+	// Create means that the file doesn't exist on disk and would be written.
+	Create Action = "create"
+	// Update means that the file on disk has different content and would be
+	// rewritten.
+	Update Action = "update"
+	// Delete means that the file is an orphan: a generated file on disk that
+	// the project no longer produces. Write only deletes orphans with
+	// DeleteOrphans.
+	Delete Action = "delete"
+	// Unchanged means that the file on disk is up to date.
+	Unchanged Action = "unchanged"
+)
+
+// PlannedChange is the action that writing a project would take on a file.
+type PlannedChange struct {
+	// Path is the slash-separated path of the file relative to the root of the
+	// project.
+	Path string `json:"path"`
+	// Action is the action on the file.
+	Action Action `json:"action"`
+	// Generator is the name of the generator that produced the file, if any.
+	Generator string `json:"generator,omitempty"`
+	// SHA256Before is the hex-encoded SHA-256 hash of the file on disk. It is
+	// empty if the file doesn't exist.
+	SHA256Before string `json:"sha256Before,omitempty"`
+	// SHA256After is the hex-encoded SHA-256 hash of the generated file. It is
+	// empty if the file would be deleted.
+	SHA256After string `json:"sha256After,omitempty"`
+}
+
+// Plan is the set of changes that writing a project would make.
+type Plan struct {
+	// Changes are the changes, sorted by path, including the files that
+	// would be left unchanged.
+	Changes []*PlannedChange `json:"changes"`
+}
+
+// Plan compares the files of the project with the files on disk and returns
+// what Write would create, update, delete with DeleteOrphans, or leave
+// untouched, without modifying the file system, so that tools can show the
+// changes of a large regeneration before applying them.
+//
+// Orphans are looked for in the directories that the project writes files to,
+// but not in their subdirectories: a file there is an orphan if it isn't part
+// of the project and has a "Code generated ... DO NOT EDIT." header.
+//
+// opts are the options of the Write to plan. Only FormatGoFiles changes the
+// plan, whose files are then compared with and hashed as formatted.
+func (p *Project) Plan(opts ...WriteOption) (*Plan, error) {
+	c := &writeConfig{}
+	for _, opt := range opts {
+		opt.apply(c)
+	}
+	plan := &Plan{Changes: []*PlannedChange{}}
+	for _, f := range p.Files() {
+		content, err := fileContent(f, c.formatGo)
+		if err != nil {
+			return nil, err
+		}
+		change := &PlannedChange{Path: f.Path, Generator: f.Generator, SHA256After: hash(content)}
+		current, err := fs.ReadFile(p.fsys, f.Path)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			change.Action = Create
+		case err != nil:
+			return nil, err
+		default:
+			change.SHA256Before = hash(current)
+			change.Action = Update
+			if bytes.Equal(current, content) {
+				change.Action = Unchanged
+			}
+		}
+		plan.Changes = append(plan.Changes, change)
+	}
+	orphans, err := p.orphans()
+	if err != nil {
+		return nil, err
+	}
+	for _, orphan := range orphans {
+		current, err := fs.ReadFile(p.fsys, orphan)
+		if err != nil {
+			return nil, err
+		}
+		plan.Changes = append(plan.Changes, &PlannedChange{Path: orphan, Action: Delete, SHA256Before: hash(current)})
+	}
+	sort.Slice(plan.Changes, func(i, j int) bool { return plan.Changes[i].Path < plan.Changes[j].Path })
+	return plan, nil
+}
+
+// Count returns the number of changes with the given action.
+func (pl *Plan) Count(action Action) int {
+	n := 0
+	for _, c := range pl.Changes {
+		if c.Action == action {
+			n++
+		}
+	}
+	return n
+}
+
+// Summary returns a one-line summary of the plan, such as "Plan: 2 to create,
+// 1 to update, 0 to delete, 10 unchanged."
+func (pl *Plan) Summary() string {
+	return fmt.Sprintf("Plan: %d to create, %d to update, %d to delete, %d unchanged.", pl.Count(Create), pl.Count(Update), pl.Count(Delete), pl.Count(Unchanged))
+}
+
+// planSymbols are the symbols that WritePlan prints before the paths of the
+// changes, as terraform does.
+var planSymbols = map[Action]string{Create: "+", Update: "~", Delete: "-"}
+
+// WritePlan writes the plan for people to w: a line for each file that would
+// change, prefixed with "+" if it would be created, "~" if it would be
+// updated or "-" if it would be deleted, followed by the summary.
+func WritePlan(w io.Writer, plan *Plan) error {
+	for _, c := range plan.Changes {
+		symbol, ok := planSymbols[c.Action]
+		if !ok {
+			continue
+		}
+		line := symbol + " " + c.Path
+		if c.Generator != "" {
+			line += fmt.Sprintf(" (generated by %q)", c.Generator)
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintln(w, plan.Summary())
+	return err
+}
+
+// WriteJSONPlan writes the plan to w as indented JSON.
+func WriteJSONPlan(w io.Writer, plan *Plan) error {
+	out, err := json.MarshalIndent(plan, "", "\t")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(out, '\n'))
+	return err
+}
+
+// orphans returns the paths of the orphaned generated files in the
+// directories of the files of the project, sorted.
+func (p *Project) orphans() ([]string, error) {
+	dirs := map[string]bool{}
+	for _, f := range p.Files() {
+		dirs[path.Dir(f.Path)] = true
+	}
+	var orphans []string
+	for _, dir := range sortedKeys(dirs) {
+		entries, err := fs.ReadDir(p.fsys, dir)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			filePath := path.Join(dir, e.Name())
+			if !e.Type().IsRegular() {
+				continue
+			}
+			if _, ok := p.File(filePath); ok {
+				continue
+			}
+			generated, err := isGeneratedFile(p.fsys, filePath)
+			if err != nil {
+				return nil, err
+			}
+			if generated {
+				orphans = append(orphans, filePath)
+			}
+		}
+	}
+	return orphans, nil
+}
+
+// generatedHeader matches the line that marks a file as generated, by the
+// convention of "go help generate".
+var generatedHeader = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+// isGeneratedFile reports whether the named file of fsys has a generated
+// header before its first line that isn't blank or a line comment.
+func isGeneratedFile(fsys fs.FS, name string) (bool, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if generatedHeader.MatchString(line) {
+			return true, nil
+		}
+		if line != "" && !strings.HasPrefix(line, "//") {
+			return false, nil
+		}
+	}
+	// Binary files may have very long lines, which aren't generated headers.
+	if scanner.Err() == bufio.ErrTooLong {
+		return false, nil
+	}
+	return false, scanner.Err()
+}
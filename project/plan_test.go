@@ -0,0 +1,132 @@
+package project
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProject_Plan(t *testing.T) {
+	root := t.TempDir()
+	for name, content := range map[string]string{
+		"same.txt":        "same\n",
+		"changed.txt":     "old\n",
+		"dir/orphan.go":   "// Copyright 2026 The Authors.\n\n// Code generated by gen. DO NOT EDIT.\n\npackage dir\n",
+		"dir/handmade.go": "package dir\n\n// Code generated by gen. DO NOT EDIT.\n",
+		"other/orphan.go": "// Code generated by gen. DO NOT EDIT.\n\npackage other\n",
+	} {
+		name = filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p := New(root)
+	for path, content := range map[string]string{"same.txt": "same\n", "changed.txt": "new\n", "dir/new.go": "package dir\n"} {
+		if err := p.AddFile(path, []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	plan, err := p.Plan()
+	if err != nil {
+		t.Fatalf("Plan() error: %v", err)
+	}
+	want := []*PlannedChange{
+		{Path: "changed.txt", Action: Update, SHA256Before: hash([]byte("old\n")), SHA256After: hash([]byte("new\n"))},
+		{Path: "dir/new.go", Action: Create, SHA256After: hash([]byte("package dir\n"))},
+		{Path: "dir/orphan.go", Action: Delete, SHA256Before: hash([]byte("// Copyright 2026 The Authors.\n\n// Code generated by gen. DO NOT EDIT.\n\npackage dir\n"))},
+		{Path: "same.txt", Action: Unchanged, SHA256Before: hash([]byte("same\n")), SHA256After: hash([]byte("same\n"))},
+	}
+	if len(plan.Changes) != len(want) {
+		t.Fatalf("Plan() got %d changes, want %d", len(plan.Changes), len(want))
+	}
+	for i, got := range plan.Changes {
+		if *got != *want[i] {
+			t.Errorf("Plan() change %d = %+v, want %+v", i, got, want[i])
+		}
+	}
+
+	var out bytes.Buffer
+	if err := WritePlan(&out, plan); err != nil {
+		t.Fatal(err)
+	}
+	wantOut := `~ changed.txt
++ dir/new.go
+- dir/orphan.go
+Plan: 1 to create, 1 to update, 1 to delete, 1 unchanged.
+`
+	if out.String() != wantOut {
+		t.Errorf("WritePlan() wrote\n%s\nwant\n%s", out.String(), wantOut)
+	}
+
+	if _, err := os.Stat(filepath.Join(root, "dir", "new.go")); !os.IsNotExist(err) {
+		t.Errorf("Plan() wrote dir/new.go")
+	}
+	if err := p.Write(DeleteOrphans()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "dir", "orphan.go")); !os.IsNotExist(err) {
+		t.Errorf("Write(DeleteOrphans()) left dir/orphan.go")
+	}
+	for _, name := range []string{"dir/handmade.go", "other/orphan.go"} {
+		if _, err := os.Stat(filepath.Join(root, filepath.FromSlash(name))); err != nil {
+			t.Errorf("Write(DeleteOrphans()) deleted %s: %v", name, err)
+		}
+	}
+	plan, err = p.Plan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := plan.Summary(); got != "Plan: 0 to create, 0 to update, 0 to delete, 3 unchanged." {
+		t.Errorf("Plan().Summary() after Write() = %q", got)
+	}
+}
+
+func TestProject_Plan_formatGoFiles(t *testing.T) {
+	p := New(t.TempDir())
+	if err := p.AddFile("x.go", []byte("package x\nvar  x=1\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Write(FormatGoFiles()); err != nil {
+		t.Fatal(err)
+	}
+	plan, err := p.Plan(FormatGoFiles())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := plan.Summary(); got != "Plan: 0 to create, 0 to update, 0 to delete, 1 unchanged." {
+		t.Errorf("Plan(FormatGoFiles()).Summary() after Write(FormatGoFiles()) = %q", got)
+	}
+	if got, want := plan.Changes[0].SHA256After, hash([]byte("package x\n\nvar x = 1\n")); got != want {
+		t.Errorf("Plan(FormatGoFiles()) SHA256After = %s, want the hash of the formatted file %s", got, want)
+	}
+	plan, err = p.Plan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := plan.Count(Update); got != 1 {
+		t.Errorf("Plan() after Write(FormatGoFiles()) got %d updates, want 1", got)
+	}
+}
+
+func TestRunMain_plan(t *testing.T) {
+	gen := GeneratorFunc("gen", func(ctx context.Context, p *Project, inputs any) error {
+		return p.AddFile("a.txt", []byte("a\n"))
+	})
+	root := t.TempDir()
+	var stdout, stderr bytes.Buffer
+	if code := runMain(context.Background(), []string{"-C", root, "-plan"}, &stdout, &stderr, nil, []Generator{gen}); code != 0 {
+		t.Fatalf("runMain(-plan) exited with %d; stderr:\n%s", code, stderr.String())
+	}
+	if want := "+ a.txt (generated by \"gen\")\nPlan: 1 to create, 0 to update, 0 to delete, 0 unchanged.\n"; stdout.String() != want {
+		t.Errorf("runMain(-plan) printed %q, want %q", stdout.String(), want)
+	}
+	if _, err := os.Stat(filepath.Join(root, "a.txt")); !os.IsNotExist(err) {
+		t.Errorf("runMain(-plan) wrote a.txt")
+	}
+}
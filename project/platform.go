@@ -0,0 +1,162 @@
+package project
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Platform is an operating system and architecture that a variant of a
+// generated file is built for. Either may be empty, in which case the variant
+// is built for any operating system or architecture.
+type Platform struct {
+	GOOS, GOARCH string
+}
+
+// ParsePlatform parses a platform written as "linux/amd64", or as "linux" or
+// "amd64" for a platform of any architecture or operating system.
+func ParsePlatform(s string) (Platform, error) {
+	var pl Platform
+	if goos, goarch, ok := strings.Cut(s, "/"); ok {
+		pl = Platform{goos, goarch}
+	} else if knownArch[s] {
+		pl.GOARCH = s
+	} else {
+		pl.GOOS = s
+	}
+	if err := pl.validate(); err != nil {
+		return Platform{}, err
+	}
+	return pl, nil
+}
+
+func (pl Platform) validate() error {
+	if pl.GOOS == "" && pl.GOARCH == "" {
+		return fmt.Errorf("invalid platform: GOOS and GOARCH are both empty")
+	}
+	if pl.GOOS != "" && !knownOS[pl.GOOS] {
+		return fmt.Errorf("invalid platform %q: unknown GOOS %q", pl, pl.GOOS)
+	}
+	if pl.GOARCH != "" && !knownArch[pl.GOARCH] {
+		return fmt.Errorf("invalid platform %q: unknown GOARCH %q", pl, pl.GOARCH)
+	}
+	return nil
+}
+
+// String returns the platform in the form parsed by ParsePlatform.
+func (pl Platform) String() string {
+	switch {
+	case pl.GOOS == "":
+		return pl.GOARCH
+	case pl.GOARCH == "":
+		return pl.GOOS
+	}
+	return pl.GOOS + "/" + pl.GOARCH
+}
+
+// Constraint returns the build constraint expression that matches the
+// platform, such as "linux && amd64".
+func (pl Platform) Constraint() string {
+	var terms []string
+	for _, term := range []string{pl.GOOS, pl.GOARCH} {
+		if term != "" {
+			terms = append(terms, term)
+		}
+	}
+	return strings.Join(terms, " && ")
+}
+
+// FilePath returns the path of the variant of the Go file at filePath for the
+// platform, which has the platform's GOOS and GOARCH as suffixes, such as
+// "foo/bar_linux_amd64.go" for "foo/bar.go". The go command only builds such
+// a file for the platform.
+func (pl Platform) FilePath(filePath string) string {
+	ext := path.Ext(filePath)
+	base := strings.TrimSuffix(filePath, ext)
+	for _, term := range []string{pl.GOOS, pl.GOARCH} {
+		if term != "" {
+			base += "_" + term
+		}
+	}
+	return base + ext
+}
+
+// PlatformOption customizes AddPlatformFiles.
+type PlatformOption struct {
+	apply func(*platformConfig)
+}
+
+type platformConfig struct {
+	buildTags bool
+}
+
+// WithBuildTags returns an option that makes AddPlatformFiles start each
+// variant with a //go:build line for its platform, as the generated files of
+// golang.org/x/sys do, in addition to naming it after the platform.
+func WithBuildTags() PlatformOption {
+	return PlatformOption{func(c *platformConfig) { c.buildTags = true }}
+}
+
+// AddPlatformFiles adds a variant of the Go file at filePath for each of the
+// platforms, whose path is given by Platform.FilePath and whose content is
+// returned by gen, such as bar_linux.go and bar_windows.go for bar.go. Nothing
+// is added if gen fails for any platform or if the paths of two variants are
+// the same.
+func (p *Project) AddPlatformFiles(filePath string, platforms []Platform, gen func(Platform) ([]byte, error), opts ...PlatformOption) error {
+	cfg := &platformConfig{}
+	for _, opt := range opts {
+		opt.apply(cfg)
+	}
+	if path.Ext(filePath) != ".go" {
+		return fmt.Errorf("invalid file path %q: platform variants must be .go files", filePath)
+	}
+
+	variants := p.view()
+	for _, pl := range platforms {
+		if err := pl.validate(); err != nil {
+			return err
+		}
+		content, err := gen(pl)
+		if err != nil {
+			return fmt.Errorf("%s: %w", pl.FilePath(filePath), err)
+		}
+		if cfg.buildTags {
+			content = withBuildConstraint(content, pl.Constraint())
+		}
+		if err := variants.AddFile(pl.FilePath(filePath), content); err != nil {
+			return err
+		}
+	}
+	return p.merge(variants, "")
+}
+
+// withBuildConstraint returns content preceded by a //go:build line with the
+// constraint expr. A blank line follows it so that it isn't part of the doc
+// comment of the package clause.
+func withBuildConstraint(content []byte, expr string) []byte {
+	var out bytes.Buffer
+	out.WriteString("//go:build " + expr + "\n\n")
+	out.Write(content)
+	return out.Bytes()
+}
+
+// knownOS and knownArch are the values of GOOS and GOARCH that the go command
+// recognizes in file names, from go/build.
+var (
+	knownOS = map[string]bool{
+		"aix": true, "android": true, "darwin": true, "dragonfly": true,
+		"freebsd": true, "hurd": true, "illumos": true, "ios": true, "js": true,
+		"linux": true, "nacl": true, "netbsd": true, "openbsd": true,
+		"plan9": true, "solaris": true, "wasip1": true, "windows": true,
+		"zos": true,
+	}
+	knownArch = map[string]bool{
+		"386": true, "amd64": true, "amd64p32": true, "arm": true, "armbe": true,
+		"arm64": true, "arm64be": true, "loong64": true, "mips": true,
+		"mipsle": true, "mips64": true, "mips64le": true, "mips64p32": true,
+		"mips64p32le": true, "ppc": true, "ppc64": true, "ppc64le": true,
+		"riscv": true, "riscv64": true, "s390": true, "s390x": true,
+		"sparc": true, "sparc64": true, "wasm": true,
+	}
+)
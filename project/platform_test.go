@@ -0,0 +1,132 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParsePlatform(t *testing.T) {
+	for _, tt := range []struct {
+		in, want, constraint, path string
+	}{
+		{"linux/amd64", "linux/amd64", "linux && amd64", "a/b_linux_amd64.go"},
+		{"windows", "windows", "windows", "a/b_windows.go"},
+		{"arm64", "arm64", "arm64", "a/b_arm64.go"},
+	} {
+		pl, err := ParsePlatform(tt.in)
+		if err != nil {
+			t.Errorf("ParsePlatform(%q) error: %v", tt.in, err)
+			continue
+		}
+		if got := pl.String(); got != tt.want {
+			t.Errorf("ParsePlatform(%q).String() = %q, want %q", tt.in, got, tt.want)
+		}
+		if got := pl.Constraint(); got != tt.constraint {
+			t.Errorf("ParsePlatform(%q).Constraint() = %q, want %q", tt.in, got, tt.constraint)
+		}
+		if got := pl.FilePath("a/b.go"); got != tt.path {
+			t.Errorf("ParsePlatform(%q).FilePath() = %q, want %q", tt.in, got, tt.path)
+		}
+	}
+	for _, in := range []string{"", "/", "linux/z80", "beos", "beos/amd64"} {
+		if _, err := ParsePlatform(in); err == nil {
+			t.Errorf("ParsePlatform(%q) succeeded, want an error", in)
+		}
+	}
+}
+
+func TestProject_AddPlatformFiles(t *testing.T) {
+	p := New(t.TempDir())
+	platforms := []Platform{{GOOS: "linux"}, {GOOS: "windows", GOARCH: "amd64"}}
+	gen := func(pl Platform) ([]byte, error) {
+		return []byte(fmt.Sprintf("// Package x is for %s.\npackage x\n", pl)), nil
+	}
+	if err := p.AddPlatformFiles("x/sys.go", platforms, gen, WithBuildTags()); err != nil {
+		t.Fatalf("AddPlatformFiles() error: %v", err)
+	}
+	var got []string
+	for _, f := range p.Files() {
+		got = append(got, f.Path+":\n"+string(f.Content))
+	}
+	want := []string{
+		"x/sys_linux.go:\n//go:build linux\n\n// Package x is for linux.\npackage x\n",
+		"x/sys_windows_amd64.go:\n//go:build windows && amd64\n\n// Package x is for windows/amd64.\npackage x\n",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("AddPlatformFiles() added:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	var conflict *ConflictError
+	if err := p.AddPlatformFiles("x/sys.go", platforms[:1], gen); !errors.As(err, &conflict) {
+		t.Errorf("AddPlatformFiles() of existing variants got error %v, want *ConflictError", err)
+	}
+	genErr := errors.New("unsupported")
+	err := p.AddPlatformFiles("y/sys.go", platforms, func(pl Platform) ([]byte, error) {
+		if pl.GOOS == "windows" {
+			return nil, genErr
+		}
+		return gen(pl)
+	})
+	if !errors.Is(err, genErr) || !strings.Contains(err.Error(), "y/sys_windows_amd64.go") {
+		t.Errorf("AddPlatformFiles() got error %v, want the error of gen for y/sys_windows_amd64.go", err)
+	}
+	if _, ok := p.File("y/sys_linux.go"); ok {
+		t.Errorf("AddPlatformFiles() added y/sys_linux.go although gen failed for another platform")
+	}
+}
+
+func TestManifest_platforms(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"gen/sys.go.tmpl": "{{header}}\n\nconst OS = {{printf \"%q\" platform.GOOS}}\n",
+		"codegen.yaml": `templates:
+- template: gen/sys.go.tmpl
+  package: abc.xyz/sys
+  output: sys/os.go
+  platforms: [linux, darwin/arm64]
+  buildTags: true
+`,
+	}
+	for name, content := range files {
+		name = filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m, err := LoadManifest(filepath.Join(dir, "codegen.yaml"))
+	if err != nil {
+		t.Fatalf("LoadManifest() error: %v", err)
+	}
+	p := New(dir)
+	if err := Run(context.Background(), p, nil, m); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	for path, want := range map[string]string{
+		"sys/os_linux.go":        "//go:build linux\n\npackage sys\n\nimport ()\n\nconst OS = \"linux\"\n",
+		"sys/os_darwin_arm64.go": "//go:build darwin && arm64\n\npackage sys\n\nimport ()\n\nconst OS = \"darwin\"\n",
+	} {
+		f, ok := p.File(path)
+		if !ok {
+			t.Errorf("Run() didn't produce %s", path)
+			continue
+		}
+		if string(f.Content) != want || f.Generator != m.Name() {
+			t.Errorf("Run() produced %s by %q:\n%s\nwant:\n%s", path, f.Generator, f.Content, want)
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "bad.yaml"), []byte("templates:\n- {template: x, package: x, output: x.go, platforms: [beos]}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadManifest(filepath.Join(dir, "bad.yaml")); err == nil || !strings.Contains(err.Error(), "unknown GOOS") {
+		t.Errorf("LoadManifest() of a manifest with an unknown platform got error %v, want unknown GOOS", err)
+	}
+}
@@ -0,0 +1,171 @@
+// Package project collects the files produced by code generators and writes
+// them to disk.
+//
+// A Project is rooted at a directory, usually the root of a module, and holds
+// generated files keyed by their slash-separated path relative to that
+// directory. Files are added in memory and only written by Write, so a failed
+// generation run leaves the file system untouched. Projects created by NewFS
+// write to a WritableFS instead, such as a MemFS in tests.
+//
+// Generators that implement the Generator interface can be composed with Run,
+// which gives each of them its own view of the project and reports an error if
+// two generators produce the same file.
+package project
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	cb "github.com/meta-programming/go-codegenutil/codebuilder"
+)
+
+// Project is a set of generated files rooted at a directory. It is safe for
+// concurrent use.
+type Project struct {
+	root string
+	fsys WritableFS
+
+	mu    sync.Mutex
+	files map[string]*File
+	// docs maps the directories of the packages whose doc.go files were
+	// added with AddPackageDoc to the paths of those files.
+	docs map[string]string
+}
+
+// File is a generated file of a Project.
+type File struct {
+	// Path is the slash-separated path of the file relative to the root of the
+	// project.
+	Path string
+	// Content is the content of the file.
+	Content []byte
+	// Generator is the name of the generator that produced the file, or the
+	// empty string if the file was added outside of Run.
+	Generator string
+
+	// packageDoc is set for the doc.go files added by AddPackageDoc.
+	packageDoc bool
+}
+
+// New returns an empty project rooted at the directory root.
+func New(root string) *Project {
+	return newProject(root, DirFS(root))
+}
+
+// NewFS returns an empty project whose files are read from and written to
+// fsys, whose root is the root of the project.
+func NewFS(fsys WritableFS) *Project {
+	return newProject("", fsys)
+}
+
+func newProject(root string, fsys WritableFS) *Project {
+	return &Project{root: root, fsys: fsys, files: map[string]*File{}, docs: map[string]string{}}
+}
+
+// view returns an empty project with the same root and file system as p, to
+// which a generator adds files before they are merged into p.
+func (p *Project) view() *Project { return newProject(p.root, p.fsys) }
+
+// Root returns the directory the project is rooted at, or the empty string if
+// it was created by NewFS.
+func (p *Project) Root() string { return p.root }
+
+// FS returns the file system that the project writes its files to.
+func (p *Project) FS() WritableFS { return p.fsys }
+
+// ConflictError is returned when a file is added to a project that already has
+// a file with the same path.
+type ConflictError struct {
+	// Path is the path of the file.
+	Path string
+	// Existing and New are the names of the generators that produced the
+	// existing and the new file. Either may be empty.
+	Existing, New string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s is generated by both %s and %s", e.Path, generatorName(e.Existing), generatorName(e.New))
+}
+
+func generatorName(name string) string {
+	if name == "" {
+		return "an unnamed generator"
+	}
+	return fmt.Sprintf("%q", name)
+}
+
+// AddFile adds a file with the given content to the project. The path must be
+// a slash-separated path that is relative to the root of the project and stays
+// within it. AddFile returns a *ConflictError if the project already has a
+// file with the same path.
+func (p *Project) AddFile(filePath string, content []byte) error {
+	return p.add(&File{Path: filePath, Content: content})
+}
+
+// AddGoFile renders f and adds it to the project. See AddFile.
+func (p *Project) AddGoFile(filePath string, f *cb.File) error {
+	content, err := f.Render(nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", filePath, err)
+	}
+	return p.AddFile(filePath, content)
+}
+
+func (p *Project) add(f *File) error {
+	clean, err := cleanPath(f.Path)
+	if err != nil {
+		return err
+	}
+	f.Path = clean
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if existing, ok := p.files[clean]; ok {
+		return &ConflictError{Path: clean, Existing: existing.Generator, New: f.Generator}
+	}
+	if err := p.checkPackageDoc(f); err != nil {
+		return err
+	}
+	p.files[clean] = f
+	if f.packageDoc {
+		p.docs[path.Dir(clean)] = clean
+	}
+	return nil
+}
+
+// cleanPath returns the canonical form of a file path, or an error if it
+// doesn't denote a file within the project.
+func cleanPath(filePath string) (string, error) {
+	clean := path.Clean(filePath)
+	if filePath == "" || path.IsAbs(clean) || filepath.IsAbs(filePath) || clean == "." || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("invalid file path %q: must be relative to the project root and within it", filePath)
+	}
+	return clean, nil
+}
+
+// File returns the file with the given path, if any.
+func (p *Project) File(filePath string) (*File, bool) {
+	clean, err := cleanPath(filePath)
+	if err != nil {
+		return nil, false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	f, ok := p.files[clean]
+	return f, ok
+}
+
+// Files returns the files of the project sorted by path.
+func (p *Project) Files() []*File {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	files := make([]*File, 0, len(p.files))
+	for _, f := range p.files {
+		files = append(files, f)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files
+}
@@ -0,0 +1,116 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/meta-programming/go-codegenutil"
+	cb "github.com/meta-programming/go-codegenutil/codebuilder"
+)
+
+func TestProject_AddFile(t *testing.T) {
+	p := New(t.TempDir())
+	if err := p.AddFile("a/./b.go", []byte("package a\n")); err != nil {
+		t.Fatalf("AddFile() error: %v", err)
+	}
+	if _, ok := p.File("a/b.go"); !ok {
+		t.Errorf("File(%q) not found after AddFile", "a/b.go")
+	}
+	var conflict *ConflictError
+	if err := p.AddFile("a/b.go", nil); !errors.As(err, &conflict) || conflict.Path != "a/b.go" {
+		t.Errorf("AddFile() of an existing file got error %v, want *ConflictError for a/b.go", err)
+	}
+	for _, path := range []string{"", ".", "../x.go", "a/../../x.go", "/abs/x.go"} {
+		if err := p.AddFile(path, nil); err == nil || !strings.Contains(err.Error(), "invalid file path") {
+			t.Errorf("AddFile(%q) got error %v, want invalid file path", path, err)
+		}
+	}
+}
+
+func TestProject_Write(t *testing.T) {
+	root := t.TempDir()
+	p := New(root)
+	f := cb.NewFile(codegenutil.AssumedPackageName("abc.xyz/mypkg")).
+		Add(cb.Var("d").Type(codegenutil.Sym("time", "Duration")))
+	if err := p.AddGoFile("mypkg/d.go", f); err != nil {
+		t.Fatalf("AddGoFile() error: %v", err)
+	}
+	if err := p.Write(); err != nil {
+		t.Fatalf("Write() error: %v", err)
+	}
+	got, err := os.ReadFile(filepath.Join(root, "mypkg", "d.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "package mypkg\n\nimport (\n\t\"time\"\n)\n\nvar d time.Duration\n"; string(got) != want {
+		t.Errorf("Write() wrote %q, want %q", got, want)
+	}
+}
+
+func TestRun(t *testing.T) {
+	write := func(name string, paths ...string) Generator {
+		return GeneratorFunc(name, func(ctx context.Context, p *Project, inputs any) error {
+			for _, path := range paths {
+				if err := p.AddFile(path, []byte(inputs.(string))); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+
+	t.Run("independent generators", func(t *testing.T) {
+		p := New(t.TempDir())
+		if err := Run(context.Background(), p, "content", write("a", "a.go"), write("b", "b.go", "sub/b.go")); err != nil {
+			t.Fatalf("Run() error: %v", err)
+		}
+		var got []string
+		for _, f := range p.Files() {
+			got = append(got, f.Generator+":"+f.Path+":"+string(f.Content))
+		}
+		if want := "a:a.go:content b:b.go:content b:sub/b.go:content"; strings.Join(got, " ") != want {
+			t.Errorf("Run() produced %q, want %q", strings.Join(got, " "), want)
+		}
+	})
+
+	t.Run("conflict", func(t *testing.T) {
+		p := New(t.TempDir())
+		err := Run(context.Background(), p, "", write("a", "x.go"), write("b", "y.go", "x.go"))
+		var conflict *ConflictError
+		if !errors.As(err, &conflict) || *conflict != (ConflictError{Path: "x.go", Existing: "a", New: "b"}) {
+			t.Fatalf("Run() got error %v, want conflict on x.go between a and b", err)
+		}
+		if want := `x.go is generated by both "a" and "b"`; err.Error() != want {
+			t.Errorf("Run() got error %q, want %q", err, want)
+		}
+		if _, ok := p.File("y.go"); ok {
+			t.Errorf("Run() kept y.go from the conflicting generator")
+		}
+	})
+
+	t.Run("generator error", func(t *testing.T) {
+		p := New(t.TempDir())
+		fail := GeneratorFunc("fail", func(context.Context, *Project, any) error { return errors.New("boom") })
+		if err := Run(context.Background(), p, "", fail); err == nil || err.Error() != `generator "fail": boom` {
+			t.Errorf("Run() got error %v, want generator \"fail\": boom", err)
+		}
+	})
+
+	t.Run("duplicate names", func(t *testing.T) {
+		if err := Run(context.Background(), New(t.TempDir()), "", write("a"), write("a")); err == nil {
+			t.Errorf("Run() with duplicate generator names succeeded, want error")
+		}
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if err := Run(ctx, New(t.TempDir()), "", write("a", "a.go")); !errors.Is(err, context.Canceled) {
+			t.Errorf("Run() got error %v, want context.Canceled", err)
+		}
+	})
+}
@@ -0,0 +1,54 @@
+package project
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+
+	"github.com/meta-programming/go-codegenutil"
+)
+
+// FileSymbols is the report of the symbols of other packages that a generated
+// Go file refers to. See SymbolsUsed.
+type FileSymbols struct {
+	// Path is the slash-separated path of the file relative to the root of the
+	// project.
+	Path string `json:"path"`
+	// Generator is the name of the generator that produced the file.
+	Generator string `json:"generator,omitempty"`
+	// Symbols are the symbols that the file refers to, with the number of
+	// references to each, sorted by import path and name.
+	Symbols []*codegenutil.SymbolUse `json:"symbols"`
+}
+
+// SymbolsUsed returns the report of the symbols of other packages that each Go
+// file of the project refers to, sorted by path, for tools that review
+// generated code, such as those that flag generated code that starts calling
+// sensitive packages. See codegenutil.SymbolsUsed. It returns an error if a Go
+// file doesn't parse.
+func (p *Project) SymbolsUsed() ([]*FileSymbols, error) {
+	report := []*FileSymbols{}
+	for _, f := range p.Files() {
+		if path.Ext(f.Path) != ".go" {
+			continue
+		}
+		uses, err := codegenutil.SymbolsUsed(f.Content)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Path, err)
+		}
+		report = append(report, &FileSymbols{Path: f.Path, Generator: f.Generator, Symbols: uses})
+	}
+	return report, nil
+}
+
+// WriteJSONSymbols writes the report of the symbols used by the generated files
+// to w as indented JSON.
+func WriteJSONSymbols(w io.Writer, files []*FileSymbols) error {
+	out, err := json.MarshalIndent(files, "", "\t")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(out, '\n'))
+	return err
+}
@@ -0,0 +1,78 @@
+package project
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProject_SymbolsUsed(t *testing.T) {
+	p := NewFS(&MemFS{})
+	for path, content := range map[string]string{
+		"a/a.go":     "package a\n\nimport \"os/exec\"\n\nvar c = exec.Command(\"ls\")\nvar d = exec.Command(\"pwd\")\n",
+		"b/b.go":     "package b\n",
+		"README.txt": "not go",
+	} {
+		if err := p.AddFile(path, []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var out bytes.Buffer
+	if err := p.Write(WithSymbolsReport(&out)); err != nil {
+		t.Fatalf("Write() error: %v", err)
+	}
+	want := `[
+	{
+		"path": "a/a.go",
+		"symbols": [
+			{
+				"package": "os/exec",
+				"name": "Command",
+				"count": 2
+			}
+		]
+	},
+	{
+		"path": "b/b.go",
+		"symbols": []
+	}
+]
+`
+	if out.String() != want {
+		t.Errorf("Write(WithSymbolsReport()) reported:\n%s\nwant:\n%s", out.String(), want)
+	}
+
+	fsys := &MemFS{}
+	invalid := NewFS(fsys)
+	if err := invalid.AddFile("c.go", []byte("package")); err != nil {
+		t.Fatal(err)
+	}
+	if err := invalid.Write(WithSymbolsReport(&out)); err == nil || !strings.HasPrefix(err.Error(), "c.go: ") {
+		t.Errorf("Write(WithSymbolsReport()) of invalid Go got error %v, want an error for c.go", err)
+	}
+	if paths := fsys.Paths(); len(paths) != 0 {
+		t.Errorf("Write(WithSymbolsReport()) wrote %q despite the error", paths)
+	}
+}
+
+func TestRunMain_symbolsReport(t *testing.T) {
+	gen := GeneratorFunc("gen", func(ctx context.Context, p *Project, inputs any) error {
+		return p.AddFile("a.go", []byte("package a\n\nimport _ \"embed\"\n"))
+	})
+	root := t.TempDir()
+	report := filepath.Join(t.TempDir(), "symbols.json")
+	var stdout, stderr bytes.Buffer
+	if code := runMain(context.Background(), []string{"-C", root, "-symbols-report", report}, &stdout, &stderr, nil, []Generator{gen}); code != 0 {
+		t.Fatalf("runMain(-symbols-report) exited with %d; stderr:\n%s", code, stderr.String())
+	}
+	got, err := os.ReadFile(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(got), `"generator": "gen"`) || !strings.Contains(string(got), `"package": "embed"`) {
+		t.Errorf("runMain(-symbols-report) wrote report:\n%s\nwant the embed import of a.go by gen", got)
+	}
+}
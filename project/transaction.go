@@ -0,0 +1,217 @@
+package project
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path"
+	"strconv"
+	"sync"
+)
+
+// stagedFile is a file staged by a transactional Write.
+type stagedFile struct {
+	// name is the name of the file in the file system of the project, and
+	// staged that of its staged content.
+	name, staged string
+	// previous is the content of the file on disk before the write. It is nil
+	// if the file didn't exist.
+	previous []byte
+	// mode is the permission bits of the file on disk, if it existed.
+	mode fs.FileMode
+}
+
+// transaction is the state of a transactional Write, which rollback undoes.
+type transaction struct {
+	fsys WritableFS
+	// dirs are the directories that the transaction created, in the order of
+	// their creation.
+	dirs []string
+	// committed are the staged files moved into place, and deleted the orphans
+	// removed, whose previous content is kept.
+	committed, deleted []*stagedFile
+}
+
+// writeTransaction writes the files of p, and deletes the orphans, all or
+// nothing. See Transactional.
+func (p *Project) writeTransaction(ctx context.Context, c *writeConfig, orphans []string) (err error) {
+	tx := &transaction{fsys: p.fsys}
+	defer func() {
+		if err != nil {
+			tx.rollback()
+			c.debug("transaction rolled back", "error", err, "committed", len(tx.committed), "deleted", len(tx.deleted))
+			return
+		}
+		c.debug("transaction committed", "written", len(tx.committed), "deleted", len(tx.deleted))
+	}()
+	if err := tx.mkdirAll("."); err != nil {
+		return err
+	}
+	stage, err := tx.mkdirStage()
+	if err != nil {
+		return err
+	}
+	defer tx.removeStage(stage)
+
+	// Stage the files that aren't up to date concurrently.
+	var mu sync.Mutex
+	staged := map[string]*stagedFile{}
+	err = p.forEachFile(ctx, c, func(f *File) (bool, error) {
+		content, err := fileContent(f, c.formatGo)
+		if err != nil {
+			return false, err
+		}
+		if err := validate(f.Path, content); err != nil {
+			return false, err
+		}
+		sf := &stagedFile{name: f.Path, mode: 0o644}
+		if sf.previous, err = readExisting(p.fsys, sf.name, &sf.mode); err != nil {
+			return false, err
+		}
+		if sf.previous != nil && bytes.Equal(sf.previous, content) {
+			return true, nil
+		}
+		mu.Lock()
+		sf.staged = path.Join(stage, strconv.Itoa(len(staged)))
+		staged[f.Path] = sf
+		mu.Unlock()
+		if err := p.fsys.WriteFile(sf.staged, content, sf.mode); err != nil {
+			return false, fmt.Errorf("%s: staging: %w", f.Path, err)
+		}
+		return false, nil
+	})
+	if err != nil {
+		return err
+	}
+	// Once files are moved into place, the transaction is completed even if
+	// ctx is done, since rolling back takes as long.
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// Move the staged files into place in order, so that rolling back is
+	// deterministic.
+	for _, filePath := range sortedKeys(staged) {
+		sf := staged[filePath]
+		if err := tx.mkdirAll(path.Dir(sf.name)); err != nil {
+			return err
+		}
+		if err := p.fsys.Rename(sf.staged, sf.name); err != nil {
+			return err
+		}
+		tx.committed = append(tx.committed, sf)
+	}
+	for _, orphan := range orphans {
+		sf := &stagedFile{name: orphan, mode: 0o644}
+		if sf.previous, err = readExisting(p.fsys, sf.name, &sf.mode); err != nil {
+			return err
+		}
+		if sf.previous == nil {
+			continue
+		}
+		if err := p.fsys.Remove(sf.name); err != nil {
+			return err
+		}
+		tx.deleted = append(tx.deleted, sf)
+		c.debug("orphan deleted", "file", orphan)
+	}
+	return nil
+}
+
+// validate returns an error if the file at filePath, with the given content,
+// is a Go file that doesn't parse.
+func validate(filePath string, content []byte) error {
+	if path.Ext(filePath) != ".go" {
+		return nil
+	}
+	_, err := parser.ParseFile(token.NewFileSet(), filePath, content, parser.SkipObjectResolution)
+	return err
+}
+
+// readExisting returns the content of the named file of fsys and sets *mode to
+// its permission bits, or returns nil if it doesn't exist.
+func readExisting(fsys fs.FS, name string, mode *fs.FileMode) ([]byte, error) {
+	content, err := fs.ReadFile(fsys, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	info, err := fs.Stat(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	*mode = info.Mode().Perm()
+	return content, nil
+}
+
+// mkdirAll creates the directory dir and its missing parents, recording them
+// so that rollback removes them.
+func (tx *transaction) mkdirAll(dir string) error {
+	var missing []string
+	for d := dir; ; d = path.Dir(d) {
+		if _, err := fs.Stat(tx.fsys, d); err == nil {
+			break
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		missing = append(missing, d)
+		if d == "." {
+			break
+		}
+	}
+	for i := len(missing) - 1; i >= 0; i-- {
+		if err := tx.fsys.MkdirAll(missing[i], 0o755); err != nil {
+			return err
+		}
+		tx.dirs = append(tx.dirs, missing[i])
+	}
+	return nil
+}
+
+// mkdirStage creates a new directory at the root of the file system in which
+// the files are staged.
+func (tx *transaction) mkdirStage() (string, error) {
+	for i := 0; ; i++ {
+		stage := fmt.Sprintf(".codegen-stage-%d-%d", os.Getpid(), i)
+		_, err := fs.Stat(tx.fsys, stage)
+		if errors.Is(err, fs.ErrNotExist) {
+			return stage, tx.fsys.MkdirAll(stage, 0o755)
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+}
+
+// removeStage removes the staging directory stage and the staged files that
+// weren't moved into place.
+func (tx *transaction) removeStage(stage string) {
+	entries, _ := fs.ReadDir(tx.fsys, stage)
+	for _, e := range entries {
+		tx.fsys.Remove(path.Join(stage, e.Name()))
+	}
+	tx.fsys.Remove(stage)
+}
+
+// rollback restores the files and directories changed by the transaction, as
+// far as it can.
+func (tx *transaction) rollback() {
+	for _, sf := range append(tx.committed, tx.deleted...) {
+		if sf.previous == nil {
+			tx.fsys.Remove(sf.name)
+		} else {
+			tx.fsys.WriteFile(sf.name, sf.previous, sf.mode)
+		}
+	}
+	for i := len(tx.dirs) - 1; i >= 0; i-- {
+		// Only empty directories are removed.
+		tx.fsys.Remove(tx.dirs[i])
+	}
+}
@@ -0,0 +1,206 @@
+package project
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+
+	"github.com/meta-programming/go-codegenutil/debugutil"
+)
+
+// StaleFile describes a generated file whose content on disk differs from the
+// content of the file in the project.
+type StaleFile struct {
+	// Path is the slash-separated path of the file relative to the root of the
+	// project.
+	Path string
+	// Current is the content of the file on disk. It is nil if the file
+	// doesn't exist.
+	Current []byte
+	// Generated is the content of the file in the project.
+	Generated []byte
+	// Generator is the name of the generator that produced the file.
+	Generator string
+}
+
+// Status describes why a generated file is stale.
+type Status string
+
+// Statuses of stale files.
+const (
+	// Missing means that the file doesn't exist on disk.
+	Missing Status = "missing"
+	// Modified means that the content of the file on disk differs from the
+	// generated content.
+	Modified Status = "modified"
+)
+
+// Status returns the status of the file.
+func (s *StaleFile) Status() Status {
+	if s.Missing() {
+		return Missing
+	}
+	return Modified
+}
+
+// Missing reports whether the file doesn't exist on disk.
+func (s *StaleFile) Missing() bool { return s.Current == nil }
+
+// Diff returns the changes that writing the project would make to the file, in
+// the unified format of "diff -u".
+func (s *StaleFile) Diff() string {
+	current := "a/" + s.Path
+	if s.Missing() {
+		current = "/dev/null"
+	}
+	return debugutil.UnifiedDiff(current, "b/"+s.Path, string(s.Current), string(s.Generated))
+}
+
+// Verify compares the files of the project with the files on disk and returns
+// those that Write would create or change, sorted by path. It doesn't modify
+// the file system, so it can be used to check in continuous integration that
+// generated files are up to date.
+//
+// The options decide which differences are ignored; with
+// debugutil.IgnoreVolatileLines, files whose only changes are to the version
+// or time stamp comments of generators aren't stale.
+func (p *Project) Verify(opts ...debugutil.Option) ([]*StaleFile, error) {
+	var stale []*StaleFile
+	for _, f := range p.Files() {
+		current, err := fs.ReadFile(p.fsys, f.Path)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			current = nil
+		case err != nil:
+			return nil, err
+		case bytes.Equal(current, f.Content):
+			continue
+		case len(opts) > 0 && debugutil.DiffHunks(string(current), string(f.Content), opts...) == nil:
+			continue
+		}
+		stale = append(stale, &StaleFile{Path: f.Path, Current: current, Generated: f.Content, Generator: f.Generator})
+	}
+	return stale, nil
+}
+
+// ErrVerifyStale is matched by the errors that report generated files that are
+// out of date, such as a *StaleError.
+var ErrVerifyStale = errors.New("generated files are out of date")
+
+// StaleError is returned by CheckUpToDate when generated files are stale. It
+// matches ErrVerifyStale.
+type StaleError struct {
+	// Files are the stale files, sorted by path.
+	Files []*StaleFile
+}
+
+func (e *StaleError) Error() string {
+	paths := make([]string, len(e.Files))
+	for i, s := range e.Files {
+		paths[i] = s.Path
+	}
+	return fmt.Sprintf("%v: %s", ErrVerifyStale, strings.Join(paths, ", "))
+}
+
+// Is reports whether target is ErrVerifyStale.
+func (e *StaleError) Is(target error) bool { return target == ErrVerifyStale }
+
+// CheckUpToDate is like Verify, but returns a *StaleError if any file is stale,
+// so that tests can check that generated files are up to date with a single
+// call.
+func (p *Project) CheckUpToDate(opts ...debugutil.Option) error {
+	stale, err := p.Verify(opts...)
+	if err != nil {
+		return err
+	}
+	if len(stale) > 0 {
+		return &StaleError{Files: stale}
+	}
+	return nil
+}
+
+// WriteStaleReport writes a report of stale files for people to w: a line
+// stating why each file is stale, followed by the diff of its changes.
+func WriteStaleReport(w io.Writer, stale []*StaleFile) error {
+	for _, s := range stale {
+		reason := "is out of date"
+		if s.Missing() {
+			reason = "is missing"
+		}
+		if s.Generator != "" {
+			reason += fmt.Sprintf(" (generated by %q)", s.Generator)
+		}
+		if _, err := fmt.Fprintf(w, "%s %s:\n%s\n", s.Path, reason, s.Diff()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// JSONReport is the machine-readable report written by WriteJSONReport.
+type JSONReport struct {
+	// UpToDate reports whether there are no stale files.
+	UpToDate bool `json:"upToDate"`
+	// Files are the stale files.
+	Files []*JSONReportFile `json:"files"`
+}
+
+// JSONReportFile describes a stale file in a JSONReport.
+type JSONReportFile struct {
+	// Path is the slash-separated path of the file relative to the root of the
+	// project.
+	Path string `json:"path"`
+	// Status is the status of the file.
+	Status Status `json:"status"`
+	// Generator is the name of the generator that produced the file.
+	Generator string `json:"generator,omitempty"`
+	// Hunks are the hunks of the unified diff from the file on disk to the
+	// generated file. See debugutil.DiffHunks.
+	Hunks []string `json:"hunks"`
+	// SHA256Before is the hex-encoded SHA-256 hash of the file on disk. It is
+	// empty if the file is missing.
+	SHA256Before string `json:"sha256Before,omitempty"`
+	// SHA256After is the hex-encoded SHA-256 hash of the generated file.
+	SHA256After string `json:"sha256After"`
+}
+
+// NewJSONReport returns the machine-readable report of stale files.
+func NewJSONReport(stale []*StaleFile) *JSONReport {
+	report := &JSONReport{UpToDate: len(stale) == 0, Files: []*JSONReportFile{}}
+	for _, s := range stale {
+		f := &JSONReportFile{
+			Path:        s.Path,
+			Status:      s.Status(),
+			Generator:   s.Generator,
+			Hunks:       append([]string{}, debugutil.DiffHunks(string(s.Current), string(s.Generated))...),
+			SHA256After: hash(s.Generated),
+		}
+		if !s.Missing() {
+			f.SHA256Before = hash(s.Current)
+		}
+		report.Files = append(report.Files, f)
+	}
+	return report
+}
+
+// WriteJSONReport writes the report of stale files returned by NewJSONReport
+// to w as indented JSON, for tools such as bots that annotate pull requests.
+func WriteJSONReport(w io.Writer, stale []*StaleFile) error {
+	out, err := json.MarshalIndent(NewJSONReport(stale), "", "\t")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(out, '\n'))
+	return err
+}
+
+func hash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
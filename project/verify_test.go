@@ -0,0 +1,258 @@
+package project
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/meta-programming/go-codegenutil"
+	"github.com/meta-programming/go-codegenutil/debugutil"
+)
+
+func TestProject_Verify(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "same.txt"), []byte("same\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "changed.txt"), []byte("old\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	p := New(root)
+	for path, content := range map[string]string{"same.txt": "same\n", "changed.txt": "new\n", "dir/missing.txt": "missing\n"} {
+		if err := p.AddFile(path, []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stale, err := p.Verify()
+	if err != nil {
+		t.Fatalf("Verify() error: %v", err)
+	}
+	var report bytes.Buffer
+	if err := WriteStaleReport(&report, stale); err != nil {
+		t.Fatal(err)
+	}
+	want := `changed.txt is out of date:
+--- a/changed.txt
++++ b/changed.txt
+@@ -1 +1 @@
+-old
++new
+
+dir/missing.txt is missing:
+--- /dev/null
++++ b/dir/missing.txt
+@@ -0,0 +1 @@
++missing
+
+`
+	if report.String() != want {
+		t.Errorf("WriteStaleReport() wrote\n%s\nwant\n%s", report.String(), want)
+	}
+	err = p.CheckUpToDate()
+	var staleErr *StaleError
+	if !errors.Is(err, ErrVerifyStale) || !errors.As(err, &staleErr) || len(staleErr.Files) != 2 {
+		t.Errorf("CheckUpToDate() got error %v, want *StaleError with 2 files", err)
+	}
+	if want := "generated files are out of date: changed.txt, dir/missing.txt"; err == nil || err.Error() != want {
+		t.Errorf("CheckUpToDate() got error %v, want %q", err, want)
+	}
+
+	if err := p.Write(); err != nil {
+		t.Fatal(err)
+	}
+	if stale, err := p.Verify(); err != nil || len(stale) != 0 {
+		t.Errorf("Verify() after Write() got %d stale files, error %v; want none", len(stale), err)
+	}
+	if err := p.CheckUpToDate(); err != nil {
+		t.Errorf("CheckUpToDate() after Write() got error %v", err)
+	}
+}
+
+func TestProject_Verify_ignoreVolatileLines(t *testing.T) {
+	root := t.TempDir()
+	current := "// Code generated by gen v1.0.0. DO NOT EDIT.\n\npackage p\n"
+	if err := os.WriteFile(filepath.Join(root, "p.go"), []byte(current), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	p := New(root)
+	if err := p.AddFile("p.go", []byte(strings.Replace(current, "v1.0.0", "v1.1.0", 1))); err != nil {
+		t.Fatal(err)
+	}
+	if stale, err := p.Verify(); err != nil || len(stale) != 1 {
+		t.Errorf("Verify() got %d stale files, error %v; want 1", len(stale), err)
+	}
+	if stale, err := p.Verify(debugutil.IgnoreVolatileLines()); err != nil || len(stale) != 0 {
+		t.Errorf("Verify(IgnoreVolatileLines()) got %d stale files, error %v; want none", len(stale), err)
+	}
+}
+
+func TestManifest(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"gen/enum.go.tmpl": "{{header}}\n\nvar {{.name}} = {{.marshal}}\n",
+		"gen/data.json":    `{"name": "encode", "marshal": {"$symbol": "encoding/json.Marshal"}}`,
+		"codegen.yaml": `templates:
+- template: gen/enum.go.tmpl
+  data: [gen/data.json]
+  package: abc.xyz/mypkg
+  output: mypkg/enum.go
+`,
+	}
+	for name, content := range files {
+		name = filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m, err := LoadManifest(filepath.Join(dir, "codegen.yaml"))
+	if err != nil {
+		t.Fatalf("LoadManifest() error: %v", err)
+	}
+	p := New(dir)
+	if err := Run(context.Background(), p, nil, m); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	f, ok := p.File("mypkg/enum.go")
+	if !ok {
+		t.Fatalf("Run() didn't produce mypkg/enum.go; got %d files", len(p.Files()))
+	}
+	want := "package mypkg\n\nimport (\n\t\"encoding/json\"\n)\n\nvar encode = json.Marshal\n"
+	if string(f.Content) != want {
+		t.Errorf("Run() produced\n%s\nwant\n%s", f.Content, want)
+	}
+	if f.Generator != m.Name() {
+		t.Errorf("file generated by %q, want %q", f.Generator, m.Name())
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "bad.json"), []byte(`{"templates": [{"template": "x"}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadManifest(filepath.Join(dir, "bad.json")); err == nil || !strings.Contains(err.Error(), "required") {
+		t.Errorf("LoadManifest() of an incomplete manifest got error %v, want an error about required fields", err)
+	}
+}
+
+func TestManifest_goVersion(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"gen/any.go.tmpl":     "{{header}}\n\nvar X {{.any}}\n",
+		"gen/generic.go.tmpl": "{{header}}\n\ntype List[T any] []T\n",
+		"gen/data.json":       `{"any": {"$symbol": "any"}}`,
+		"codegen.yaml": `templates:
+- template: gen/any.go.tmpl
+  data: [gen/data.json]
+  package: abc.xyz/mypkg
+  output: mypkg/any.go
+  goVersion: "1.17"
+`,
+		"generic.yaml": `templates:
+- template: gen/generic.go.tmpl
+  package: abc.xyz/mypkg
+  output: mypkg/generic.go
+  goVersion: go1.17
+`,
+		"bad.yaml": "templates:\n- {template: x, package: x, output: x.go, goVersion: latest}\n",
+	}
+	for name, content := range files {
+		name = filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m, err := LoadManifest(filepath.Join(dir, "codegen.yaml"))
+	if err != nil {
+		t.Fatalf("LoadManifest() error: %v", err)
+	}
+	p := New(dir)
+	if err := Run(context.Background(), p, nil, m); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	want := "package mypkg\n\nimport ()\n\nvar X interface{}\n"
+	if f, ok := p.File("mypkg/any.go"); !ok || string(f.Content) != want {
+		t.Errorf("Run() didn't produce mypkg/any.go with\n%s", want)
+	}
+
+	m, err = LoadManifest(filepath.Join(dir, "generic.yaml"))
+	if err != nil {
+		t.Fatalf("LoadManifest() error: %v", err)
+	}
+	if err := Run(context.Background(), New(dir), nil, m); !errors.Is(err, codegenutil.ErrGoVersion) {
+		t.Errorf("Run() of a generic type for go1.17 got error %v, want ErrGoVersion", err)
+	}
+
+	if _, err := LoadManifest(filepath.Join(dir, "bad.yaml")); err == nil || !strings.Contains(err.Error(), `invalid goVersion "latest"`) {
+		t.Errorf("LoadManifest() of an invalid goVersion got error %v", err)
+	}
+}
+
+func TestRunMain(t *testing.T) {
+	root := t.TempDir()
+	gen := GeneratorFunc("gen", func(ctx context.Context, p *Project, inputs any) error {
+		return p.AddFile("a.txt", []byte("a\n"))
+	})
+	var stdout, stderr bytes.Buffer
+	if code := runMain(context.Background(), []string{"-C", root, "-verify"}, &stdout, &stderr, nil, []Generator{gen}); code != 1 {
+		t.Errorf("runMain(-verify) before writing exited with %d, want 1; stderr:\n%s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), `a.txt is missing (generated by "gen")`) {
+		t.Errorf("runMain(-verify) printed %q, want a report that a.txt is missing", stdout.String())
+	}
+	if code := runMain(context.Background(), []string{"-C", root}, &stdout, &stderr, nil, []Generator{gen}); code != 0 {
+		t.Errorf("runMain() exited with %d, want 0; stderr:\n%s", code, stderr.String())
+	}
+	stdout.Reset()
+	if code := runMain(context.Background(), []string{"-C", root, "-verify"}, &stdout, &stderr, nil, []Generator{gen}); code != 0 || stdout.Len() != 0 {
+		t.Errorf("runMain(-verify) after writing exited with %d and printed %q, want 0 and nothing", code, stdout.String())
+	}
+}
+
+func TestWriteJSONReport(t *testing.T) {
+	stale := []*StaleFile{
+		{Path: "a.txt", Current: []byte("old\n"), Generated: []byte("new\n"), Generator: "gen"},
+		{Path: "b.txt", Generated: []byte("hello\n")},
+	}
+	var out bytes.Buffer
+	if err := WriteJSONReport(&out, stale); err != nil {
+		t.Fatalf("WriteJSONReport() error: %v", err)
+	}
+	var got JSONReport
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("WriteJSONReport() wrote invalid JSON: %v\n%s", err, out.String())
+	}
+	want := JSONReport{Files: []*JSONReportFile{
+		{
+			Path:         "a.txt",
+			Status:       Modified,
+			Generator:    "gen",
+			Hunks:        []string{"@@ -1 +1 @@\n-old\n+new\n"},
+			SHA256Before: "01d09d19c2139a46aebfb577780d123d7396e97201bc7ead210a2ebff8239dee",
+			SHA256After:  "7aa7a5359173d05b63cfd682e3c38487f3cb4f7f1d60659fe59fab1505977d4c",
+		},
+		{
+			Path:        "b.txt",
+			Status:      Missing,
+			Hunks:       []string{"@@ -0,0 +1 @@\n+hello\n"},
+			SHA256After: "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03",
+		},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		gotJSON, _ := json.MarshalIndent(got, "", "\t")
+		wantJSON, _ := json.MarshalIndent(want, "", "\t")
+		t.Errorf("WriteJSONReport() got != want:\n%s", debugutil.SideBySide(string(gotJSON), string(wantJSON)))
+	}
+}
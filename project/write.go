@@ -0,0 +1,261 @@
+package project
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go/format"
+	"io"
+	"io/fs"
+	"path"
+	"sync"
+
+	"github.com/meta-programming/go-codegenutil"
+	"github.com/meta-programming/go-codegenutil/parallel"
+)
+
+// WriteOption customizes Write.
+type WriteOption struct {
+	apply func(*writeConfig)
+}
+
+type writeConfig struct {
+	limit      int
+	onProgress func(WriteProgress)
+	formatGo   bool
+	orphans    bool
+	atomic     bool
+	logger     codegenutil.Logger
+	symbols    io.Writer
+}
+
+// WriteParallelism returns an option that sets the maximum number of files
+// that Write formats and writes at once. The default is
+// runtime.GOMAXPROCS(0), and 1 writes the files one after the other.
+func WriteParallelism(n int) WriteOption {
+	return WriteOption{func(c *writeConfig) { c.limit = n }}
+}
+
+// OnWriteProgress returns an option that makes Write call fn after each file
+// is written or found to be up to date, for example to print a progress bar.
+// The calls are not concurrent.
+func OnWriteProgress(fn func(WriteProgress)) WriteOption {
+	return WriteOption{func(c *writeConfig) { c.onProgress = fn }}
+}
+
+// FormatGoFiles returns an option that makes Write format the .go files with
+// gofmt before writing them, concurrently, so that generators of large
+// projects can add unformatted files instead of formatting them one after the
+// other. Pass the option to Plan as well to plan such a write. Verify doesn't
+// format files, so generators whose files are verified, such as those run by
+// Main, should add formatted files instead.
+func FormatGoFiles() WriteOption {
+	return WriteOption{func(c *writeConfig) { c.formatGo = true }}
+}
+
+// DeleteOrphans returns an option that makes Write delete the orphaned
+// generated files that Plan reports, once the files of the project are
+// written, such as those of a type that a generator no longer produces. By
+// default, Write leaves them in place.
+func DeleteOrphans() WriteOption {
+	return WriteOption{func(c *writeConfig) { c.orphans = true }}
+}
+
+// Transactional returns an option that makes Write all or nothing: the files
+// are first formatted, validated and staged in a temporary directory below the
+// root of the project, and only moved into place once every one of them is
+// staged, so that a failure halfway through doesn't leave a half-regenerated
+// package that doesn't compile. Go files that don't parse fail validation.
+// If moving a file into place fails, the files already moved are restored to
+// their previous content.
+//
+// The progress reported by OnWriteProgress is that of staging the files.
+func Transactional() WriteOption {
+	return WriteOption{func(c *writeConfig) { c.atomic = true }}
+}
+
+// WithLogger returns an option that makes Write log, at the debug level, the
+// files it writes, skips because they are up to date or deletes, and the
+// outcome of Transactional writes.
+func WithLogger(l codegenutil.Logger) WriteOption {
+	return WriteOption{func(c *writeConfig) { c.logger = l }}
+}
+
+// WithSymbolsReport returns an option that makes Write write the report of the
+// symbols of other packages that the generated Go files refer to, as returned
+// by SymbolsUsed, to w as JSON once the files are written. Write fails before
+// writing anything if a Go file doesn't parse.
+func WithSymbolsReport(w io.Writer) WriteOption {
+	return WriteOption{func(c *writeConfig) { c.symbols = w }}
+}
+
+// debug logs an event with the logger of c, if any.
+func (c *writeConfig) debug(msg string, args ...any) {
+	if c.logger != nil {
+		c.logger.Debug(msg, args...)
+	}
+}
+
+// WriteProgress reports that Write is done with a file.
+type WriteProgress struct {
+	// Path is the path of the file.
+	Path string
+	// UpToDate reports whether the file already had the generated content,
+	// in which case it wasn't rewritten.
+	UpToDate bool
+	// Done is the number of files that Write is done with, including this
+	// one, and Total is the number of files of the project.
+	Done, Total int
+}
+
+// Write writes the files of the project below its root directory, or to the
+// file system given to NewFS, creating directories as needed. Files whose
+// content on disk is already up to date are not rewritten, so that their
+// modification times are preserved.
+//
+// Files are written concurrently; see WriteParallelism. If writing a file
+// fails, the files that haven't been started are skipped and Write returns
+// the error, leaving the files already written in place unless the write is
+// Transactional.
+func (p *Project) Write(opts ...WriteOption) error {
+	return p.WriteContext(context.Background(), opts...)
+}
+
+// WriteContext is like Write, but stops once ctx is done: the files that
+// haven't been started are skipped and WriteContext returns ctx.Err(). A
+// Transactional write that is stopped before all of its files are staged
+// writes nothing.
+func (p *Project) WriteContext(ctx context.Context, opts ...WriteOption) error {
+	c := &writeConfig{}
+	for _, opt := range opts {
+		opt.apply(c)
+	}
+	// Orphans are found first so that Write fails before writing anything if
+	// the directories of the project can't be read.
+	var orphans []string
+	if c.orphans {
+		var err error
+		if orphans, err = p.orphans(); err != nil {
+			return err
+		}
+	}
+
+	var symbols []*FileSymbols
+	if c.symbols != nil {
+		var err error
+		if symbols, err = p.SymbolsUsed(); err != nil {
+			return err
+		}
+	}
+
+	if err := p.write(ctx, c, orphans); err != nil {
+		return err
+	}
+	if c.symbols != nil {
+		return WriteJSONSymbols(c.symbols, symbols)
+	}
+	return nil
+}
+
+func (p *Project) write(ctx context.Context, c *writeConfig, orphans []string) error {
+	if c.atomic {
+		return p.writeTransaction(ctx, c, orphans)
+	}
+
+	err := p.forEachFile(ctx, c, func(f *File) (bool, error) {
+		return p.writeFile(f, c.formatGo)
+	})
+	if err != nil {
+		return err
+	}
+	for _, orphan := range orphans {
+		if err := p.fsys.Remove(orphan); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		c.debug("orphan deleted", "file", orphan)
+	}
+	return nil
+}
+
+// forEachFile calls do for each file of p concurrently, as configured by c,
+// and reports the progress of the files that do returns without error. do
+// reports whether the file was up to date. The first error is returned.
+func (p *Project) forEachFile(
+	ctx context.Context, c *writeConfig, do func(*File) (upToDate bool, err error),
+) error {
+	files := p.Files()
+	var mu sync.Mutex
+	done := 0
+	var tasks []*parallel.Task
+	for _, f := range files {
+		f := f
+		run := func(ctx context.Context) error {
+			upToDate, err := do(f)
+			switch {
+			case err != nil:
+				c.debug("file failed", "file", f.Path, "error", err)
+			case upToDate:
+				c.debug("file skipped unchanged", "file", f.Path)
+			case c.atomic:
+				c.debug("file staged", "file", f.Path)
+			default:
+				c.debug("file written", "file", f.Path)
+			}
+			if err != nil || c.onProgress == nil {
+				return err
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			done++
+			c.onProgress(WriteProgress{
+				Path:     f.Path,
+				UpToDate: upToDate,
+				Done:     done,
+				Total:    len(files),
+			})
+			return nil
+		}
+		tasks = append(tasks, &parallel.Task{Name: f.Path, Run: run})
+	}
+	var parallelOpts []parallel.Option
+	if c.limit > 0 {
+		parallelOpts = append(parallelOpts, parallel.WithLimit(c.limit))
+	}
+	err := parallel.Run(ctx, tasks, parallelOpts...)
+	var taskErr *parallel.TaskError
+	if errors.As(err, &taskErr) {
+		return taskErr.Err
+	}
+	return err
+}
+
+// writeFile writes f below the root of p, formatting it first if formatGo is
+// set and it is a .go file, and reports whether it was already up to date.
+func (p *Project) writeFile(f *File, formatGo bool) (upToDate bool, err error) {
+	content, err := fileContent(f, formatGo)
+	if err != nil {
+		return false, err
+	}
+	existing, err := fs.ReadFile(p.fsys, f.Path)
+	if err == nil && bytes.Equal(existing, content) {
+		return true, nil
+	}
+	if err := p.fsys.MkdirAll(path.Dir(f.Path), 0o755); err != nil {
+		return false, err
+	}
+	return false, p.fsys.WriteFile(f.Path, content, 0o644)
+}
+
+// fileContent returns the content of f to write, formatted with gofmt if
+// formatGo is set and f is a .go file.
+func fileContent(f *File, formatGo bool) ([]byte, error) {
+	if !formatGo || path.Ext(f.Path) != ".go" {
+		return f.Content, nil
+	}
+	content, err := format.Source(f.Content)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", f.Path, err)
+	}
+	return content, nil
+}
@@ -0,0 +1,214 @@
+package project
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/meta-programming/go-codegenutil/internal/logtest"
+)
+
+func TestProject_Write_parallel(t *testing.T) {
+	root := t.TempDir()
+	p := New(root)
+	const n = 50
+	for i := 0; i < n; i++ {
+		if err := p.AddFile(fmt.Sprintf("d%d/f%d.go", i%5, i), []byte(fmt.Sprintf("package d\nvar   x%d = %d\n", i, i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := p.AddFile("notes.txt", []byte("var   x\n")); err != nil {
+		t.Fatal(err)
+	}
+	// An up-to-date file isn't rewritten.
+	if err := os.MkdirAll(filepath.Join(root, "d0"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "d0", "f0.go"), []byte("package d\n\nvar x0 = 0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var progress []WriteProgress
+	err := p.Write(WriteParallelism(4), FormatGoFiles(), OnWriteProgress(func(wp WriteProgress) {
+		progress = append(progress, wp)
+	}))
+	if err != nil {
+		t.Fatalf("Write() error: %v", err)
+	}
+	if len(progress) != n+1 {
+		t.Fatalf("OnWriteProgress called %d times, want %d", len(progress), n+1)
+	}
+	upToDate := 0
+	for i, wp := range progress {
+		if wp.Done != i+1 || wp.Total != n+1 {
+			t.Errorf("progress %d = %+v, want Done %d of %d", i, wp, i+1, n+1)
+		}
+		if wp.UpToDate {
+			upToDate++
+			if wp.Path != "d0/f0.go" {
+				t.Errorf("%s reported up to date", wp.Path)
+			}
+		}
+	}
+	if upToDate != 1 {
+		t.Errorf("%d files reported up to date, want 1", upToDate)
+	}
+	got, err := os.ReadFile(filepath.Join(root, "d3", "f13.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "package d\n\nvar x13 = 13\n"; string(got) != want {
+		t.Errorf("Write() wrote %q, want %q", got, want)
+	}
+	if got, _ := os.ReadFile(filepath.Join(root, "notes.txt")); string(got) != "var   x\n" {
+		t.Errorf("Write() wrote notes.txt as %q, want it unformatted", got)
+	}
+
+	bad := New(t.TempDir())
+	if err := bad.AddFile("bad.go", []byte("package bad\nvar = )\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := bad.Write(FormatGoFiles()); err == nil || !strings.HasPrefix(err.Error(), "bad.go: ") {
+		t.Errorf("Write() of an invalid Go file got error %v, want an error for bad.go", err)
+	}
+}
+
+func TestRunMain_progress(t *testing.T) {
+	gen := GeneratorFunc("gen", func(ctx context.Context, p *Project, inputs any) error {
+		for _, name := range []string{"a.txt", "b.txt"} {
+			if err := p.AddFile(name, []byte(name)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	var stdout, stderr bytes.Buffer
+	if code := runMain(context.Background(), []string{"-C", t.TempDir(), "-progress"}, &stdout, &stderr, nil, []Generator{gen}); code != 0 {
+		t.Fatalf("runMain() exited with %d; stderr:\n%s", code, stderr.String())
+	}
+	if want := "\rwriting files: 1/2\rwriting files: 2/2\n"; stderr.String() != want {
+		t.Errorf("runMain(-progress) printed %q, want %q", stderr.String(), want)
+	}
+}
+
+func TestProject_Write_transactional(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "a.go"), []byte("package p\n\nvar a = 0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	p := New(root)
+	for path, content := range map[string]string{
+		"a.go":     "package p\n\nvar a = 1\n",
+		"b/b.go":   "package b\n",
+		"c/c.go":   "package c\nvar = )\n",
+		"d/d.json": "{",
+	} {
+		if err := p.AddFile(path, []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := p.Write(Transactional()); err == nil || !strings.HasPrefix(err.Error(), "c/c.go:") {
+		t.Fatalf("Write(Transactional()) got error %v, want an error for c/c.go", err)
+	}
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "a.go" {
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		t.Errorf("Write(Transactional()) left %q in the root, want only a.go", names)
+	}
+	if got, _ := os.ReadFile(filepath.Join(root, "a.go")); string(got) != "package p\n\nvar a = 0\n" {
+		t.Errorf("Write(Transactional()) changed a.go to %q", got)
+	}
+
+	ok := New(root)
+	for _, f := range p.Files() {
+		if f.Path == "c/c.go" {
+			continue
+		}
+		if err := ok.AddFile(f.Path, f.Content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ok.Write(Transactional()); err != nil {
+		t.Fatalf("Write(Transactional()) error: %v", err)
+	}
+	if stale, err := ok.Verify(); err != nil || len(stale) != 0 {
+		t.Errorf("Verify() after Write(Transactional()) got %d stale files, error %v; want none", len(stale), err)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(root, ".codegen-stage-*")); len(matches) != 0 {
+		t.Errorf("Write(Transactional()) left the staging directories %q", matches)
+	}
+}
+
+func TestProject_Write_logger(t *testing.T) {
+	m := &MemFS{}
+	if err := m.WriteFile("old.go", []byte("// Code generated by gen. DO NOT EDIT.\n\npackage p\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.WriteFile("same.go", []byte("package p\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	p := NewFS(m)
+	for path, content := range map[string]string{"new.go": "package p\n\nvar x = 1\n", "same.go": "package p\n"} {
+		if err := p.AddFile(path, []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	logger := &logtest.Recorder{}
+	if err := p.Write(WriteParallelism(1), DeleteOrphans(), WithLogger(logger)); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"file written file=new.go",
+		"file skipped unchanged file=same.go",
+		"orphan deleted file=old.go",
+	}
+	if got := logger.Events(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("logged events:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestProject_WriteContext(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		opts      []WriteOption
+		wantPaths []string
+	}{
+		{"direct", nil, []string{"a.go"}},
+		{"transactional", []WriteOption{Transactional()}, nil},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := &MemFS{}
+			p := NewFS(fsys)
+			for _, name := range []string{"a.go", "b.go", "c.go"} {
+				if err := p.AddFile(name, []byte("package p\n")); err != nil {
+					t.Fatal(err)
+				}
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			// Cancelling after the first file skips the others.
+			opts := append([]WriteOption{WriteParallelism(1), OnWriteProgress(func(WriteProgress) { cancel() })}, tt.opts...)
+			if err := p.WriteContext(ctx, opts...); !errors.Is(err, context.Canceled) {
+				t.Fatalf("WriteContext() got error %v, want context.Canceled", err)
+			}
+			if got := fsys.Paths(); !reflect.DeepEqual(got, tt.wantPaths) {
+				t.Errorf("WriteContext() left %q, want %q", got, tt.wantPaths)
+			}
+			if err := p.WriteContext(ctx, tt.opts...); !errors.Is(err, context.Canceled) {
+				t.Errorf("WriteContext() with a done context got error %v, want context.Canceled", err)
+			}
+		})
+	}
+}
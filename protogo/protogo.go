@@ -0,0 +1,208 @@
+// Package protogo helps authors of protoc plugins that generate Go code to
+// resolve proto files and types to Go packages and symbols the way
+// protoc-gen-go does, without depending on the protobuf runtime.
+//
+// A plugin parses its parameter with ParseParams, determines the Go package of
+// each proto file from its M flags and go_package option with
+// Params.GoPackage, and registers the files and the types they declare with a
+// Resolver. Each generated file then gets its own *codegenutil.FileImports
+// from Resolver.FileImports, and references to proto types are turned into
+// symbols with Resolver.Symbol, so that imports are added as the types are
+// printed.
+package protogo
+
+import (
+	"fmt"
+	"go/token"
+	"path"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/meta-programming/go-codegenutil"
+)
+
+// ParseGoPackage returns the Go package described by the value of a go_package
+// file option, which is an import path optionally followed by a semicolon and
+// the package name, such as "example.com/foo/v1;foopb". Without an explicit
+// name, the name is derived from the last element of the import path as
+// protoc-gen-go does, which may differ from codegenutil.AssumedPackageName.
+func ParseGoPackage(option string) (*codegenutil.Package, error) {
+	importPath, name := option, ""
+	if i := strings.Index(option, ";"); i >= 0 {
+		importPath, name = option[:i], option[i+1:]
+		if !token.IsIdentifier(name) {
+			return nil, fmt.Errorf("invalid go_package %q: package name %q is %w", option, name, codegenutil.ErrInvalidIdentifier)
+		}
+	}
+	if importPath == "" {
+		return nil, fmt.Errorf("invalid go_package %q: missing import path", option)
+	}
+	if name == "" {
+		name = sanitize(path.Base(importPath))
+	}
+	return codegenutil.ExplicitPackageName(importPath, name), nil
+}
+
+// sanitize converts s into a valid Go identifier like protoc-gen-go's
+// GoSanitized.
+func sanitize(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return '_'
+	}, s)
+	r, _ := utf8.DecodeRuneInString(s)
+	if token.Lookup(s).IsKeyword() || !unicode.IsLetter(r) {
+		return "_" + s
+	}
+	return s
+}
+
+// PathsMode determines where generated files are written, as set by the
+// "paths" parameter of protoc-gen-go.
+type PathsMode string
+
+// Paths modes.
+const (
+	// PathsImport places generated files in the directory named after the Go
+	// import path of their package. It is the default.
+	PathsImport PathsMode = "import"
+	// PathsSourceRelative places generated files in the same relative
+	// directory as their proto files.
+	PathsSourceRelative PathsMode = "source_relative"
+)
+
+// Params are the parameters of a protoc plugin in the conventions of
+// protoc-gen-go.
+type Params struct {
+	// Mappings maps proto file names to Go packages, as given by M flags such
+	// as "Mfoo/bar.proto=example.com/foo;foo".
+	Mappings map[string]*codegenutil.Package
+	// Paths is the value of the "paths" parameter.
+	Paths PathsMode
+	// Module is the value of the "module" parameter: a prefix that is removed
+	// from the names of files generated with PathsImport.
+	Module string
+	// Other holds the parameters not listed above, which are specific to the
+	// plugin. A parameter without a value maps to the empty string.
+	Other map[string]string
+}
+
+// ParseParams parses the comma-separated parameter passed to a protoc plugin,
+// such as "paths=source_relative,Mfoo.proto=example.com/foo".
+func ParseParams(param string) (*Params, error) {
+	p := &Params{Mappings: map[string]*codegenutil.Package{}, Paths: PathsImport, Other: map[string]string{}}
+	for _, kv := range strings.Split(param, ",") {
+		if kv == "" {
+			continue
+		}
+		key, value := kv, ""
+		if i := strings.Index(kv, "="); i >= 0 {
+			key, value = kv[:i], kv[i+1:]
+		}
+		switch {
+		case strings.HasPrefix(key, "M"):
+			pkg, err := ParseGoPackage(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid mapping %q: %w", kv, err)
+			}
+			p.Mappings[key[1:]] = pkg
+		case key == "paths":
+			switch mode := PathsMode(value); mode {
+			case PathsImport, PathsSourceRelative:
+				p.Paths = mode
+			default:
+				return nil, fmt.Errorf(`invalid paths %q: want "import" or "source_relative"`, value)
+			}
+		case key == "module":
+			p.Module = value
+		default:
+			p.Other[key] = value
+		}
+	}
+	return p, nil
+}
+
+// GoPackage returns the Go package of a proto file from its M flag if there is
+// one, or else from the value of its go_package option, which may be empty.
+func (p *Params) GoPackage(protoFile, goPackageOption string) (*codegenutil.Package, error) {
+	if pkg, ok := p.Mappings[protoFile]; ok {
+		return pkg, nil
+	}
+	if goPackageOption == "" {
+		return nil, fmt.Errorf("unable to determine the Go import path of %q: add a go_package option or an M%s= flag", protoFile, protoFile)
+	}
+	return ParseGoPackage(goPackageOption)
+}
+
+// GeneratedFilename returns the slash-separated name of the file generated
+// for a proto file in the Go package pkg: the name of the proto file without
+// its .proto extension followed by suffix, such as ".pb.go", in the directory
+// chosen by the Paths and Module parameters.
+func (p *Params) GeneratedFilename(protoFile string, pkg *codegenutil.Package, suffix string) (string, error) {
+	prefix := strings.TrimSuffix(protoFile, ".proto")
+	if p.Paths == PathsSourceRelative {
+		return prefix + suffix, nil
+	}
+	name := path.Join(pkg.ImportPath(), path.Base(prefix)) + suffix
+	if p.Module != "" {
+		trimmed := strings.TrimPrefix(name, p.Module+"/")
+		if trimmed == name {
+			return "", fmt.Errorf("%s: generated file %s is not in module %s", protoFile, name, p.Module)
+		}
+		name = trimmed
+	}
+	return name, nil
+}
+
+// GoName returns the name of the Go type generated for a message or an enum
+// by protoc-gen-go, given its name relative to its proto package, such as
+// "Outer.Inner" for a message Inner nested in a message Outer, which becomes
+// "Outer_Inner".
+func GoName(relativeName string) string {
+	parts := strings.Split(relativeName, ".")
+	for i, part := range parts {
+		parts[i] = goCamelCase(part)
+	}
+	return strings.Join(parts, "_")
+}
+
+// goCamelCase converts a proto identifier into a Go identifier like
+// protoc-gen-go's GoCamelCase.
+func goCamelCase(s string) string {
+	var b []byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '.' && i+1 < len(s) && isASCIILower(s[i+1]):
+			// Skip over '.' in ".{{lowercase}}".
+		case c == '.':
+			b = append(b, '_')
+		case c == '_' && (i == 0 || s[i-1] == '.'):
+			// Convert an initial '_' to ensure that the name starts with a
+			// capital letter.
+			b = append(b, 'X')
+		case c == '_' && i+1 < len(s) && isASCIILower(s[i+1]):
+			// Skip over '_' in "_{{lowercase}}".
+		case isASCIIDigit(c):
+			b = append(b, c)
+		default:
+			// Start a word, which begins with an upper case letter and
+			// continues with the lower case letters that follow.
+			if isASCIILower(c) {
+				c -= 'a' - 'A'
+			}
+			b = append(b, c)
+			for ; i+1 < len(s) && isASCIILower(s[i+1]); i++ {
+				b = append(b, s[i+1])
+			}
+		}
+	}
+	return string(b)
+}
+
+func isASCIILower(c byte) bool { return 'a' <= c && c <= 'z' }
+
+func isASCIIDigit(c byte) bool { return '0' <= c && c <= '9' }
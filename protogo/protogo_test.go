@@ -0,0 +1,125 @@
+package protogo
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/meta-programming/go-codegenutil"
+)
+
+func TestParseGoPackage(t *testing.T) {
+	tests := []struct {
+		option   string
+		wantPath string
+		wantName string
+		wantErr  bool
+	}{
+		{option: "example.com/foo/v1;foopb", wantPath: "example.com/foo/v1", wantName: "foopb"},
+		{option: "example.com/foo/v1", wantPath: "example.com/foo/v1", wantName: "v1"},
+		{option: "example.com/go-foo", wantPath: "example.com/go-foo", wantName: "go_foo"},
+		{option: "example.com/type", wantPath: "example.com/type", wantName: "_type"},
+		{option: "example.com/2fa", wantPath: "example.com/2fa", wantName: "_2fa"},
+		{option: ";foo", wantErr: true},
+		{option: "example.com/foo;not-a-name", wantErr: true},
+	}
+	for _, tt := range tests {
+		pkg, err := ParseGoPackage(tt.option)
+		if gotErr := err != nil; gotErr != tt.wantErr {
+			t.Errorf("ParseGoPackage(%q) got error %v, wantErr = %v", tt.option, err, tt.wantErr)
+			continue
+		}
+		if err == nil && (pkg.ImportPath() != tt.wantPath || pkg.Name() != tt.wantName) {
+			t.Errorf("ParseGoPackage(%q) = %s (%s), want %s (%s)", tt.option, pkg.ImportPath(), pkg.Name(), tt.wantPath, tt.wantName)
+		}
+	}
+}
+
+func TestParams(t *testing.T) {
+	p, err := ParseParams("Mfoo/foo.proto=example.com/gen/foo;foopb,paths=source_relative,plugins,custom=x")
+	if err != nil {
+		t.Fatalf("ParseParams() error: %v", err)
+	}
+	if p.Paths != PathsSourceRelative {
+		t.Errorf("Paths = %q, want %q", p.Paths, PathsSourceRelative)
+	}
+	if want := map[string]string{"plugins": "", "custom": "x"}; !reflect.DeepEqual(p.Other, want) {
+		t.Errorf("Other = %v, want %v", p.Other, want)
+	}
+
+	pkg, err := p.GoPackage("foo/foo.proto", "example.com/ignored")
+	if err != nil || pkg.ImportPath() != "example.com/gen/foo" || pkg.Name() != "foopb" {
+		t.Errorf("GoPackage() of a mapped file = %v, %v; want the M flag's package", pkg, err)
+	}
+	if _, err := p.GoPackage("bar.proto", ""); err == nil {
+		t.Errorf("GoPackage() without go_package or M flag succeeded, want error")
+	}
+	if name, _ := p.GeneratedFilename("foo/foo.proto", pkg, ".pb.go"); name != "foo/foo.pb.go" {
+		t.Errorf("GeneratedFilename() with source_relative = %q, want foo/foo.pb.go", name)
+	}
+
+	p, err = ParseParams("module=example.com/gen")
+	if err != nil {
+		t.Fatalf("ParseParams() error: %v", err)
+	}
+	if name, err := p.GeneratedFilename("foo/foo.proto", pkg, ".pb.go"); err != nil || name != "foo/foo.pb.go" {
+		t.Errorf("GeneratedFilename() with module = %q, %v; want foo/foo.pb.go", name, err)
+	}
+	other := codegenutil.AssumedPackageName("other.com/x")
+	if _, err := p.GeneratedFilename("x.proto", other, ".pb.go"); err == nil {
+		t.Errorf("GeneratedFilename() outside of the module succeeded, want error")
+	}
+
+	if _, err := ParseParams("paths=bogus"); err == nil {
+		t.Errorf("ParseParams() with invalid paths succeeded, want error")
+	}
+}
+
+func TestGoName(t *testing.T) {
+	for name, want := range map[string]string{
+		"Foo":             "Foo",
+		"foo_bar":         "FooBar",
+		"Outer.Inner":     "Outer_Inner",
+		"outer.inner_msg": "Outer_InnerMsg",
+		"_private":        "XPrivate",
+		"HTTPRequest":     "HTTPRequest",
+		"message2fa":      "Message2Fa",
+	} {
+		if got := GoName(name); got != want {
+			t.Errorf("GoName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestResolver(t *testing.T) {
+	r := NewResolver()
+	foo := codegenutil.ExplicitPackageName("example.com/gen/foo", "foopb")
+	bar := codegenutil.ExplicitPackageName("example.com/gen/bar", "barpb")
+	if err := r.AddFile(&File{Name: "foo.proto", Package: "foo.v1", GoPackage: foo, Types: []string{".foo.v1.Foo", ".foo.v1.Foo.Kind"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.AddFile(&File{Name: "bar.proto", Package: "bar", GoPackage: bar, Types: []string{"bar.Bar"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.AddFile(&File{Name: "dup.proto", Package: "bar", GoPackage: bar, Types: []string{"bar.Bar"}}); err == nil {
+		t.Errorf("AddFile() of a duplicate type succeeded, want error")
+	}
+
+	imports, err := r.FileImports("bar.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, name := range []string{".foo.v1.Foo.Kind", ".bar.Bar"} {
+		sym, err := r.Symbol(name)
+		if err != nil {
+			t.Fatalf("Symbol(%q) error: %v", name, err)
+		}
+		got = append(got, sym.GoCode(imports))
+	}
+	if want := []string{"foopb.Foo_Kind", "Bar"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("symbols printed in bar.proto's file = %q, want %q", got, want)
+	}
+	if _, err := r.Symbol(".unknown.Type"); err == nil {
+		t.Errorf("Symbol() of an unknown type succeeded, want error")
+	}
+}
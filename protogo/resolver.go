@@ -0,0 +1,120 @@
+package protogo
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/meta-programming/go-codegenutil"
+)
+
+// File describes a proto file for a Resolver.
+type File struct {
+	// Name is the name of the proto file as given to protoc, such as
+	// "foo/v1/foo.proto".
+	Name string
+	// Package is the proto package of the file, such as "foo.v1". It may be
+	// empty.
+	Package string
+	// GoPackage is the Go package that the code of the file is generated into.
+	GoPackage *codegenutil.Package
+	// Types are the fully-qualified names of the messages and enums declared
+	// in the file, including nested ones, such as "foo.v1.Outer.Inner". A
+	// leading dot is allowed.
+	Types []string
+}
+
+// Resolver maps proto files and fully-qualified type names to Go packages and
+// symbols. It is safe for concurrent use.
+type Resolver struct {
+	mu    sync.Mutex
+	files map[string]*File
+	// types maps fully-qualified type names, without leading dots, to the
+	// files that declare them.
+	types map[string]*File
+}
+
+// NewResolver returns an empty resolver.
+func NewResolver() *Resolver {
+	return &Resolver{files: map[string]*File{}, types: map[string]*File{}}
+}
+
+// AddFile registers a proto file and the types it declares. It returns an
+// error if the file was already added or declares a type that another file
+// declares.
+func (r *Resolver) AddFile(f *File) error {
+	if f.GoPackage == nil {
+		return fmt.Errorf("%s: missing Go package", f.Name)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.files[f.Name]; ok {
+		return fmt.Errorf("%s: file added more than once", f.Name)
+	}
+	for _, name := range f.Types {
+		name = strings.TrimPrefix(name, ".")
+		if other, ok := r.types[name]; ok {
+			return fmt.Errorf("%s: type %s is also declared in %s", f.Name, name, other.Name)
+		}
+	}
+	r.files[f.Name] = f
+	for _, name := range f.Types {
+		r.types[strings.TrimPrefix(name, ".")] = f
+	}
+	return nil
+}
+
+// GoPackage returns the Go package of a registered proto file.
+func (r *Resolver) GoPackage(protoFile string) (*codegenutil.Package, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f, ok := r.files[protoFile]
+	if !ok {
+		return nil, false
+	}
+	return f.GoPackage, true
+}
+
+// Symbol returns the symbol of the Go type generated for a message or an
+// enum, given its fully-qualified name as found in descriptors, such as
+// ".foo.v1.Outer.Inner". The symbol belongs to the Go package of the file
+// that declares the type and is named by GoName.
+func (r *Resolver) Symbol(fullName string) (*codegenutil.Symbol, error) {
+	name := strings.TrimPrefix(fullName, ".")
+	r.mu.Lock()
+	f, ok := r.types[name]
+	r.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown proto type %s", fullName)
+	}
+	relative := name
+	if f.Package != "" {
+		relative = strings.TrimPrefix(name, f.Package+".")
+	}
+	return f.GoPackage.Symbol(GoName(relative)), nil
+}
+
+// FileImports returns a new *codegenutil.FileImports for a Go file generated
+// for a registered proto file. Each generated file needs its own FileImports,
+// since the imports of a file depend on the symbols printed into it; symbols
+// of the file's own Go package are printed unqualified.
+func (r *Resolver) FileImports(protoFile string) (*codegenutil.FileImports, error) {
+	pkg, ok := r.GoPackage(protoFile)
+	if !ok {
+		return nil, fmt.Errorf("unknown proto file %s", protoFile)
+	}
+	return codegenutil.NewFileImports(pkg), nil
+}
+
+// Files returns the registered files sorted by name.
+func (r *Resolver) Files() []*File {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	files := make([]*File, 0, len(r.files))
+	for _, f := range r.files {
+		files = append(files, f)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+	return files
+}
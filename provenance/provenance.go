@@ -0,0 +1,145 @@
+// Package provenance records how generated files were produced: the command
+// line of the generator, the module it was built from, and hashes of its
+// inputs.
+//
+// The information is meant to be written into the header of generated files,
+// or into a manifest next to them, so that a generated file can be traced back
+// to the exact generator and inputs that produced it:
+//
+//	prov, err := provenance.Capture("schema.yaml")
+//	...
+//	f := codebuilder.NewFile(pkg).Doc(prov.String())
+//
+// Only values that are the same for every run with the same generator and
+// inputs are included, so that regenerating files doesn't change their
+// headers needlessly.
+package provenance
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strconv"
+	"strings"
+)
+
+// Provenance describes how a generated file was produced.
+type Provenance struct {
+	// Command is the command line of the generator. The first element is the
+	// base name of the program. Arguments that contain spaces or quotes are
+	// quoted in String.
+	Command []string `json:"command"`
+	// Module is the path of the module that the generator was built from, if
+	// known.
+	Module string `json:"module,omitempty"`
+	// Version is the version of that module. It is "(devel)" for generators
+	// built from a working copy, such as with "go run".
+	Version string `json:"version,omitempty"`
+	// GoVersion is the version of Go that built the generator. It is not part
+	// of String, since it changes whenever the toolchain is upgraded.
+	GoVersion string `json:"goVersion,omitempty"`
+	// Inputs are the inputs of the generation, in the order they were added.
+	Inputs []Input `json:"inputs,omitempty"`
+}
+
+// Input is an input of a generation.
+type Input struct {
+	// Name identifies the input, usually by its path.
+	Name string `json:"name"`
+	// SHA256 is the hex-encoded SHA-256 hash of the input's content.
+	SHA256 string `json:"sha256"`
+}
+
+// Capture returns the provenance of the running generator: its command line,
+// from os.Args, and its module, from debug.ReadBuildInfo. The named files are
+// read and added as inputs.
+func Capture(inputFiles ...string) (*Provenance, error) {
+	info, _ := debug.ReadBuildInfo()
+	p := capture(os.Args, info)
+	for _, name := range inputFiles {
+		content, err := os.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		p.AddInput(filepath.ToSlash(name), content)
+	}
+	return p, nil
+}
+
+func capture(args []string, info *debug.BuildInfo) *Provenance {
+	p := &Provenance{}
+	if len(args) > 0 {
+		p.Command = append([]string{filepath.Base(args[0])}, args[1:]...)
+	}
+	if info != nil {
+		p.Module = info.Main.Path
+		p.Version = info.Main.Version
+		p.GoVersion = info.GoVersion
+	}
+	return p
+}
+
+// AddInput adds an input with the given name and content.
+func (p *Provenance) AddInput(name string, content []byte) {
+	sum := sha256.Sum256(content)
+	p.Inputs = append(p.Inputs, Input{Name: name, SHA256: hex.EncodeToString(sum[:])})
+}
+
+// String returns the provenance as the text of a header comment, starting
+// with a line that marks the file as generated by the convention of
+// "go help generate":
+//
+//	Code generated by mygen DO NOT EDIT.
+//
+//	Command: mygen -type Color
+//	Module: example.com/tools@v1.2.3
+//	Inputs:
+//		schema.yaml sha256:5891b5b5...
+func (p *Provenance) String() string {
+	var b strings.Builder
+	program := "a generator"
+	if len(p.Command) > 0 {
+		program = p.Command[0]
+	}
+	fmt.Fprintf(&b, "Code generated by %s DO NOT EDIT.\n", program)
+	if len(p.Command) > 0 || p.Module != "" || len(p.Inputs) > 0 {
+		b.WriteString("\n")
+	}
+	if len(p.Command) > 0 {
+		args := make([]string, len(p.Command))
+		for i, arg := range p.Command {
+			args[i] = arg
+			if arg == "" || strings.ContainsAny(arg, " \t\n\"") {
+				args[i] = strconv.Quote(arg)
+			}
+		}
+		fmt.Fprintf(&b, "Command: %s\n", strings.Join(args, " "))
+	}
+	if p.Module != "" {
+		module := p.Module
+		if p.Version != "" {
+			module += "@" + p.Version
+		}
+		fmt.Fprintf(&b, "Module: %s\n", module)
+	}
+	if len(p.Inputs) > 0 {
+		b.WriteString("Inputs:\n")
+		for _, in := range p.Inputs {
+			fmt.Fprintf(&b, "\t%s sha256:%s\n", in.Name, in.SHA256)
+		}
+	}
+	return b.String()
+}
+
+// Comment returns String as line comments, for templates that print the
+// header of a file themselves.
+func (p *Provenance) Comment() string {
+	lines := strings.Split(strings.TrimSuffix(p.String(), "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight("// "+line, " ")
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
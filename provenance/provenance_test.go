@@ -0,0 +1,58 @@
+package provenance
+
+import (
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"testing"
+
+	"github.com/meta-programming/go-codegenutil/debugutil"
+)
+
+func TestProvenance_String(t *testing.T) {
+	info := &debug.BuildInfo{
+		GoVersion: "go1.22.0",
+		Main:      debug.Module{Path: "example.com/tools", Version: "v1.2.3"},
+	}
+	p := capture([]string{"/tmp/go-build123/exe/mygen", "-type", "Color", "-comment", "a b"}, info)
+	p.AddInput("schema.yaml", []byte("hello\n"))
+
+	want := `Code generated by mygen DO NOT EDIT.
+
+Command: mygen -type Color -comment "a b"
+Module: example.com/tools@v1.2.3
+Inputs:
+	schema.yaml sha256:5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03
+`
+	if got := p.String(); got != want {
+		t.Errorf("String() got != want:\n%s", debugutil.SideBySide(got, want))
+	}
+	if p.GoVersion != "go1.22.0" {
+		t.Errorf("GoVersion = %q, want go1.22.0", p.GoVersion)
+	}
+
+	wantComment := "// Code generated by a generator DO NOT EDIT.\n"
+	if got := (&Provenance{}).Comment(); got != wantComment {
+		t.Errorf("Comment() of empty provenance = %q, want %q", got, wantComment)
+	}
+}
+
+func TestCapture(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "in.txt")
+	if err := os.WriteFile(name, []byte("hello\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	p, err := Capture(name)
+	if err != nil {
+		t.Fatalf("Capture() error: %v", err)
+	}
+	if len(p.Command) == 0 || p.Command[0] != filepath.Base(os.Args[0]) {
+		t.Errorf("Capture().Command = %q, want it to start with %q", p.Command, filepath.Base(os.Args[0]))
+	}
+	if len(p.Inputs) != 1 || p.Inputs[0].SHA256 != "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03" {
+		t.Errorf("Capture().Inputs = %+v, want the hash of in.txt", p.Inputs)
+	}
+	if _, err := Capture(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Errorf("Capture() of a missing input succeeded, want error")
+	}
+}
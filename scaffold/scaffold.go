@@ -0,0 +1,99 @@
+// Package scaffold creates the skeletons of new code generators built on this
+// module, so that generators start out with the same layout: a main package
+// that executes embedded templates, wires them into a project.Project and
+// supports the -verify flag of project.Main, and a test that fails when the
+// generated files are out of date.
+package scaffold
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"go/token"
+	"path"
+	"strings"
+
+	"github.com/meta-programming/go-codegenutil/project"
+)
+
+//go:embed skeleton
+var skeleton embed.FS
+
+// Config describes a new generator.
+type Config struct {
+	// Name is the name of the generator, such as "colorgen". It defaults to
+	// the last element of Dir.
+	Name string
+	// Module is the path of the module that the generator belongs to.
+	Module string
+	// Dir is the slash-separated directory of the generator's main package,
+	// relative to the root of the module.
+	Dir string
+	// OutputDir is the slash-separated directory of the package that the
+	// generator generates files into, relative to the root of the module.
+	OutputDir string
+}
+
+// files maps the files of the skeleton directory to the paths of the files
+// they become, relative to the generator's directory.
+var files = map[string]string{
+	"skeleton/main.go.txt":         "main.go",
+	"skeleton/main_test.go.txt":    "main_test.go",
+	"skeleton/example.go.tmpl.txt": "templates/example.go.tmpl",
+}
+
+// Generator returns a generator that adds the files of a new generator to a
+// project rooted at the root of the module. The generator fails if the
+// configuration is invalid.
+func Generator(cfg Config) project.Generator {
+	return project.GeneratorFunc("scaffold", func(ctx context.Context, p *project.Project, inputs any) error {
+		if err := cfg.validate(); err != nil {
+			return err
+		}
+		name := cfg.Name
+		if name == "" {
+			name = path.Base(cfg.Dir)
+		}
+		replacer := strings.NewReplacer(
+			"GENERATOR_NAME", name,
+			"OUTPUT_PACKAGE", path.Join(cfg.Module, cfg.OutputDir),
+			"OUTPUT_DIR", path.Clean(cfg.OutputDir),
+			"MODULE_ROOT", moduleRoot(cfg.Dir),
+		)
+		for src, dst := range files {
+			content, err := skeleton.ReadFile(src)
+			if err != nil {
+				return err
+			}
+			if err := p.AddFile(path.Join(cfg.Dir, dst), []byte(replacer.Replace(string(content)))); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (cfg *Config) validate() error {
+	if cfg.Module == "" {
+		return fmt.Errorf("missing module path")
+	}
+	for _, dir := range []string{cfg.Dir, cfg.OutputDir} {
+		clean := path.Clean(dir)
+		if dir == "" || clean == "." || path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+			return fmt.Errorf("invalid directory %q: must be a subdirectory of the module root", dir)
+		}
+	}
+	if path.Clean(cfg.Dir) == path.Clean(cfg.OutputDir) {
+		return fmt.Errorf("the generator and its output must be in different directories")
+	}
+	if cfg.Name != "" && !token.IsIdentifier(strings.ReplaceAll(cfg.Name, "-", "_")) {
+		return fmt.Errorf("invalid generator name %q", cfg.Name)
+	}
+	return nil
+}
+
+// moduleRoot returns the relative path from dir to the root of the module.
+func moduleRoot(dir string) string {
+	n := strings.Count(path.Clean(dir), "/") + 1
+	return strings.TrimSuffix(strings.Repeat("../", n), "/")
+}
@@ -0,0 +1,98 @@
+package scaffold
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/meta-programming/go-codegenutil/project"
+)
+
+func TestGenerator(t *testing.T) {
+	p := project.New(t.TempDir())
+	cfg := Config{Module: "example.com/m", Dir: "internal/gen/colorgen", OutputDir: "colors"}
+	if err := project.Run(context.Background(), p, nil, Generator(cfg)); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	var paths []string
+	for _, f := range p.Files() {
+		paths = append(paths, f.Path)
+	}
+	want := "internal/gen/colorgen/main.go internal/gen/colorgen/main_test.go internal/gen/colorgen/templates/example.go.tmpl"
+	if got := strings.Join(paths, " "); got != want {
+		t.Errorf("Run() produced %s, want %s", got, want)
+	}
+	main, _ := p.File("internal/gen/colorgen/main.go")
+	for _, want := range []string{
+		"// Command colorgen generates the files of package example.com/m/colors\n",
+		"//go:generate go run . -C ../../..\n",
+		`outputDir = "colors"`,
+	} {
+		if !strings.Contains(string(main.Content), want) {
+			t.Errorf("main.go doesn't contain %q:\n%s", want, main.Content)
+		}
+	}
+
+	for _, bad := range []Config{
+		{Dir: "gen", OutputDir: "out"},
+		{Module: "m", Dir: "../gen", OutputDir: "out"},
+		{Module: "m", Dir: "gen", OutputDir: "gen"},
+		{Module: "m", Dir: "gen", OutputDir: "out", Name: "not a name"},
+	} {
+		if err := project.Run(context.Background(), project.New(t.TempDir()), nil, Generator(bad)); err == nil {
+			t.Errorf("Run() with config %+v succeeded, want error", bad)
+		}
+	}
+}
+
+// TestGenerator_build checks that the skeleton builds, fails its test until
+// the files are generated, and passes it afterwards.
+func TestGenerator_build(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs the go command")
+	}
+	repo, err := filepath.Abs("..")
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := t.TempDir()
+	goMod := "module example.com/m\n\ngo 1.18\n\nrequire github.com/meta-programming/go-codegenutil v0.0.0\n\nreplace github.com/meta-programming/go-codegenutil => " + repo + "\n"
+	if err := os.WriteFile(filepath.Join(root, "go.mod"), []byte(goMod), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	p := project.New(root)
+	cfg := Config{Module: "example.com/m", Dir: "internal/gen/colorgen", OutputDir: "colors"}
+	if err := project.Run(context.Background(), p, nil, Generator(cfg)); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Write(); err != nil {
+		t.Fatal(err)
+	}
+
+	goCmd := func(args ...string) (string, error) {
+		cmd := exec.Command("go", args...)
+		cmd.Dir = filepath.Join(root, "internal", "gen", "colorgen")
+		cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod")
+		out, err := cmd.CombinedOutput()
+		return string(out), err
+	}
+	if out, err := goCmd("test", "."); err == nil || !strings.Contains(out, "colors/example.go is missing") {
+		t.Fatalf("go test before go generate got error %v, want a failure about colors/example.go:\n%s", err, out)
+	}
+	if out, err := goCmd("generate", "."); err != nil {
+		t.Fatalf("go generate failed: %v\n%s", err, out)
+	}
+	if out, err := goCmd("test", "."); err != nil {
+		t.Fatalf("go test after go generate failed: %v\n%s", err, out)
+	}
+	generated, err := os.ReadFile(filepath.Join(root, "colors", "example.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(generated), "// Code generated by colorgen DO NOT EDIT.\n\npackage colors\n") {
+		t.Errorf("generated file starts unexpectedly:\n%s", generated)
+	}
+}
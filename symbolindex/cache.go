@@ -0,0 +1,182 @@
+package symbolindex
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/meta-programming/go-codegenutil"
+	"golang.org/x/mod/modfile"
+)
+
+// WithPersistentCache returns an option that makes PackageNameResolver cache
+// the names it looks up in the directory cacheDir, so that they are shared by
+// the generators run in separate processes, such as by "go generate" in each
+// package of a large repository, instead of being listed again by each of them.
+// DefaultCacheDir returns a suitable directory.
+//
+// Names are cached by import path and by the version of the module that
+// provides the package, which is read from the go.mod file of the module of
+// the resolver's directory when the resolver is created: changing the version
+// of a requirement in go.mod invalidates the names of its packages. The names
+// of the packages of the standard library are cached by the version of Go
+// that the generator was built with. The names of packages whose sources can
+// change without a new version, those of the main module and of modules
+// replaced by directories, aren't cached on disk.
+func WithPersistentCache(cacheDir string) Option {
+	return Option{func(c *config) { c.cacheDir = cacheDir }}
+}
+
+// DefaultCacheDir returns the default directory of the cache of
+// WithPersistentCache, in the user's cache directory.
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "go-codegenutil", "package-names"), nil
+}
+
+// diskCache is the persistent cache of package names of a directory.
+type diskCache struct {
+	dir string
+	// mainModule is the path of the main module, if any.
+	mainModule string
+	// versions maps the paths of the modules required by the main module to
+	// the versions that provide them, such as "example.com/mod@v1.2.3", which
+	// are empty for modules replaced by directories.
+	versions map[string]string
+}
+
+// newDiskCache returns the cache in cacheDir of the names of the packages
+// imported from the directory dir.
+func newDiskCache(cacheDir, dir string) *diskCache {
+	c := &diskCache{dir: cacheDir, versions: map[string]string{}}
+	goMod := findGoMod(dir)
+	if goMod == "" {
+		return c
+	}
+	content, err := os.ReadFile(goMod)
+	if err != nil {
+		return c
+	}
+	// Packages of the main module are never cached on disk, even if go.mod
+	// has directives that this version of modfile doesn't know, in which case
+	// only the names of the packages of the standard library are cached.
+	c.mainModule = modfile.ModulePath(content)
+	f, err := modfile.Parse(goMod, content, nil)
+	if err != nil {
+		return c
+	}
+	required := map[string]string{}
+	for _, r := range f.Require {
+		required[r.Mod.Path] = r.Mod.Version
+		c.versions[r.Mod.Path] = r.Mod.String()
+	}
+	for _, r := range f.Replace {
+		if version, ok := required[r.Old.Path]; !ok || r.Old.Version != "" && r.Old.Version != version {
+			continue
+		}
+		// A replacement by a directory has no version.
+		c.versions[r.Old.Path] = ""
+		if r.New.Version != "" {
+			c.versions[r.Old.Path] = r.New.String()
+		}
+	}
+	return c
+}
+
+// findGoMod returns the go.mod file of the module of the directory dir, or the
+// empty string if there is none.
+func findGoMod(dir string) string {
+	for {
+		goMod := filepath.Join(dir, "go.mod")
+		if info, err := os.Stat(goMod); err == nil && !info.IsDir() {
+			return goMod
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// file returns the name of the file of the cache that holds the name of the
+// package with the given import path, or the empty string if the name isn't
+// cached on disk.
+func (c *diskCache) file(importPath string) string {
+	version := c.version(importPath)
+	if version == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(version + "\x00" + importPath))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:]))
+}
+
+// version returns the version of the module that provides the package with
+// the given import path, or the empty string if it has none.
+func (c *diskCache) version(importPath string) string {
+	if c.mainModule != "" && (importPath == c.mainModule || strings.HasPrefix(importPath, c.mainModule+"/")) {
+		return ""
+	}
+	if first, _, _ := strings.Cut(importPath, "/"); !strings.Contains(first, ".") {
+		return "std@" + runtime.Version()
+	}
+	// The module that provides a package is the required module with the
+	// longest path that is a prefix of its import path.
+	for prefix := importPath; ; {
+		if version, ok := c.versions[prefix]; ok {
+			return version
+		}
+		i := strings.LastIndex(prefix, "/")
+		if i < 0 {
+			return ""
+		}
+		prefix = prefix[:i]
+	}
+}
+
+// get returns the cached name of the package with the given import path.
+func (c *diskCache) get(importPath string) (string, bool) {
+	file := c.file(importPath)
+	if file == "" {
+		return "", false
+	}
+	content, err := os.ReadFile(file)
+	if err != nil || !codegenutil.IsValidIdentifier(string(content)) {
+		return "", false
+	}
+	return string(content), true
+}
+
+// put caches the name of the package with the given import path. Failures are
+// ignored, since the name is only looked up again.
+func (c *diskCache) put(importPath, name string) {
+	file := c.file(importPath)
+	if file == "" {
+		return
+	}
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		return
+	}
+	// Writing to a temporary file first keeps other processes from reading
+	// a partial name.
+	tmp, err := os.CreateTemp(c.dir, ".tmp-")
+	if err != nil {
+		return
+	}
+	_, err = tmp.WriteString(name)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), file)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+}
@@ -0,0 +1,78 @@
+package symbolindex
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestDiskCache_version(t *testing.T) {
+	dir := t.TempDir()
+	goMod := `module example.com/main
+
+go 1.18
+
+require (
+	example.com/dep v1.2.0
+	example.com/dep/nested v0.1.0
+	example.com/local v1.0.0
+	example.com/fork v1.0.0
+)
+
+replace example.com/local => ../local
+
+replace example.com/fork v1.0.0 => example.com/forked v1.0.1
+`
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte(goMod), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	c := newDiskCache(t.TempDir(), sub)
+	tests := []struct {
+		importPath string
+		want       string
+	}{
+		{"example.com/main/sub", ""},
+		{"example.com/dep/pkg", "example.com/dep@v1.2.0"},
+		{"example.com/dep/nested/pkg", "example.com/dep/nested@v0.1.0"},
+		{"example.com/local/pkg", ""},
+		{"example.com/fork", "example.com/forked@v1.0.1"},
+		{"example.com/unknown", ""},
+		{"encoding/json", "std@" + runtime.Version()},
+	}
+	for _, tt := range tests {
+		if got := c.version(tt.importPath); got != tt.want {
+			t.Errorf("version(%q) = %q, want %q", tt.importPath, got, tt.want)
+		}
+	}
+}
+
+func TestPackageNameResolver_persistentCache(t *testing.T) {
+	cacheDir := t.TempDir()
+	if got := PackageNameResolver("..", WithPersistentCache(cacheDir))("gopkg.in/yaml.v3"); got != "yaml" {
+		t.Fatalf("resolver(%q) = %q, want %q", "gopkg.in/yaml.v3", got, "yaml")
+	}
+	if got := PackageNameResolver("..", WithPersistentCache(cacheDir))("github.com/meta-programming/go-codegenutil"); got != "codegenutil" {
+		t.Fatalf("resolver(%q) = %q, want %q", "github.com/meta-programming/go-codegenutil", got, "codegenutil")
+	}
+	entries, err := os.ReadDir(cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("the cache has %d entries, want 1 for gopkg.in/yaml.v3 but none for the main module", len(entries))
+	}
+
+	// A new resolver, as in another process, reads the name from the cache
+	// instead of listing the package.
+	if err := os.WriteFile(filepath.Join(cacheDir, entries[0].Name()), []byte("cachedyaml"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := PackageNameResolver("..", WithPersistentCache(cacheDir))("gopkg.in/yaml.v3"); got != "cachedyaml" {
+		t.Errorf("resolver(%q) = %q, want the cached %q", "gopkg.in/yaml.v3", got, "cachedyaml")
+	}
+}
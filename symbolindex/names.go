@@ -0,0 +1,114 @@
+package symbolindex
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/meta-programming/go-codegenutil"
+	"golang.org/x/sync/singleflight"
+)
+
+// PackageNameResolver returns a function that looks up the names that packages
+// declare in their package clauses with "go list" run in dir. It can be passed
+// to codegenutil.SetPackageNameHeuristic.
+//
+// Nothing is loaded until a name is needed, and each import path is only
+// looked up once per process: the names are cached for all the functions
+// returned for the same directory, and concurrent lookups of the same import
+// path share a single "go list" run. If a package can't be listed, the
+// function returns codegenutil.ImportPathToAssumedName(importPath).
+//
+// With WithOverlay, the names are looked up as if the files of the overlay
+// had the given contents. With WithPersistentCache, they are also cached on
+// disk, across processes. With either option, the names are only cached in
+// memory for the returned function. With WithContext, the lookups stop once
+// the context is done.
+func PackageNameResolver(dir string, opts ...Option) func(importPath string) string {
+	if absDir, err := filepath.Abs(dir); err == nil {
+		dir = absDir
+	}
+	c := newConfig(opts)
+	names := packageNames
+	if len(c.overlay) > 0 || c.cacheDir != "" {
+		names = &nameCache{names: map[string]string{}, overlay: absOverlay(dir, c.overlay)}
+		if c.cacheDir != "" {
+			names.disk = newDiskCache(c.cacheDir, dir)
+		}
+	}
+	return func(importPath string) string {
+		return names.lookup(c.ctx, dir, importPath)
+	}
+}
+
+// packageNames is the process-wide cache of PackageNameResolver.
+var packageNames = &nameCache{names: map[string]string{}}
+
+type nameCache struct {
+	mu    sync.RWMutex
+	names map[string]string // keyed by dir + "\x00" + import path
+	group singleflight.Group
+	// overlay is the overlay of the names, with absolute paths.
+	overlay map[string][]byte
+	// disk is the persistent cache of the names, if any.
+	disk *diskCache
+}
+
+func (c *nameCache) lookup(ctx context.Context, dir, importPath string) string {
+	key := dir + "\x00" + importPath
+	c.mu.RLock()
+	name, ok := c.names[key]
+	c.mu.RUnlock()
+	if ok {
+		return name
+	}
+	v, _, _ := c.group.Do(key, func() (interface{}, error) {
+		name, ok := "", false
+		if c.disk != nil {
+			name, ok = c.disk.get(importPath)
+		}
+		if !ok {
+			name, ok = listPackageName(ctx, dir, importPath, c.overlay)
+			// The name assumed when the lookup is stopped isn't cached, so
+			// that other lookups list the package again.
+			if ctx.Err() != nil {
+				return name, nil
+			}
+			// Assumed names aren't cached on disk, since the package may
+			// become listable without a new version, such as once it is
+			// downloaded to the module cache.
+			if ok && c.disk != nil {
+				c.disk.put(importPath, name)
+			}
+		}
+		c.mu.Lock()
+		c.names[key] = name
+		c.mu.Unlock()
+		return name, nil
+	})
+	return v.(string)
+}
+
+// listPackageName returns the name of the package with the given import path
+// and true, or its assumed name and false if it can't be listed.
+func listPackageName(ctx context.Context, dir, importPath string, overlay map[string][]byte) (string, bool) {
+	overlayFlags, cleanup, err := writeOverlay(overlay)
+	if err != nil {
+		return codegenutil.ImportPathToAssumedName(importPath), false
+	}
+	defer cleanup()
+	args := append(append([]string{"list", "-e", "-f", "{{.Name}}"}, overlayFlags...), "--", importPath)
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	name := strings.TrimSpace(string(out))
+	if err != nil || !codegenutil.IsValidIdentifier(name) || name == "main" {
+		return codegenutil.ImportPathToAssumedName(importPath), false
+	}
+	return name, true
+}
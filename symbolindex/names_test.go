@@ -0,0 +1,72 @@
+package symbolindex
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/meta-programming/go-codegenutil"
+)
+
+func TestPackageNameResolver(t *testing.T) {
+	resolve := PackageNameResolver("..")
+	tests := []struct {
+		importPath string
+		want       string
+	}{
+		{"github.com/meta-programming/go-codegenutil", "codegenutil"},
+		{"gopkg.in/yaml.v3", "yaml"},
+		{"strings", "strings"},
+		{"abc.xyz/nonexistent/go-thing", "thing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.importPath, func(t *testing.T) {
+			var wg sync.WaitGroup
+			got := make([]string, 4)
+			for i := range got {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					got[i] = resolve(tt.importPath)
+				}(i)
+			}
+			wg.Wait()
+			for _, name := range got {
+				if name != tt.want {
+					t.Errorf("resolver(%q) = %q, want %q", tt.importPath, name, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestPackageNameResolver_heuristic(t *testing.T) {
+	codegenutil.SetPackageNameHeuristic(PackageNameResolver(".."))
+	defer codegenutil.SetPackageNameHeuristic(nil)
+	if got := codegenutil.Sym("github.com/meta-programming/go-codegenutil", "Sym").GoCode(codegenutil.NewFileImports(codegenutil.AssumedPackageName("abc.xyz/mypkg"))); got != "codegenutil.Sym" {
+		t.Errorf("GoCode() = %q, want %q", got, "codegenutil.Sym")
+	}
+}
+
+func TestPackageNameResolver_context(t *testing.T) {
+	dir, err := filepath.Abs("..")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const greeting = "github.com/meta-programming/go-codegenutil/internal/generated/greeting"
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	names := &nameCache{names: map[string]string{}, overlay: absOverlay(dir, testOverlay)}
+	if got := names.lookup(ctx, dir, greeting); got != "greeting" {
+		t.Errorf("lookup() with a done context = %q, want the assumed name %q", got, "greeting")
+	}
+	if got := names.lookup(context.Background(), dir, greeting); got != "hello" {
+		t.Errorf("lookup() after a lookup with a done context = %q, want %q", got, "hello")
+	}
+
+	resolve := PackageNameResolver("..", WithOverlay(testOverlay), WithContext(ctx))
+	if got := resolve(greeting); got != "greeting" {
+		t.Errorf("resolver(greeting) with a done context = %q, want the assumed name %q", got, "greeting")
+	}
+}
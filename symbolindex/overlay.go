@@ -0,0 +1,268 @@
+package symbolindex
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+)
+
+// Option customizes PackageNameResolver and NewResolver.
+type Option struct {
+	apply func(*config)
+}
+
+type config struct {
+	overlay  map[string][]byte
+	cacheDir string
+	ctx      context.Context
+}
+
+// WithOverlay returns an option that makes packages be loaded as if the files
+// of overlay, keyed by their paths, had the given contents, like the Overlay
+// of golang.org/x/tools/go/packages. Relative paths are relative to the
+// directory that packages are loaded from. Files of the overlay may replace
+// existing files or add new ones, even in directories that don't exist, so
+// that generated code can refer to packages that are generated but not yet
+// written, or to the unsaved buffers of an editor. The overlay must not be
+// modified afterwards.
+func WithOverlay(overlay map[string][]byte) Option {
+	return Option{func(c *config) { c.overlay = overlay }}
+}
+
+// WithContext returns an option that makes PackageNameResolver stop looking up
+// names once ctx is done, after which it returns the assumed names of the
+// packages that weren't looked up yet without caching them. The methods of a
+// Resolver take their context as an argument instead.
+func WithContext(ctx context.Context) Option {
+	return Option{func(c *config) { c.ctx = ctx }}
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{ctx: context.Background()}
+	for _, opt := range opts {
+		opt.apply(c)
+	}
+	return c
+}
+
+// absOverlay returns overlay with its paths made absolute relative to dir,
+// which must be absolute.
+func absOverlay(dir string, overlay map[string][]byte) map[string][]byte {
+	abs := make(map[string][]byte, len(overlay))
+	for name, content := range overlay {
+		if !filepath.IsAbs(name) {
+			name = filepath.Join(dir, name)
+		}
+		abs[filepath.Clean(name)] = content
+	}
+	return abs
+}
+
+// writeOverlay writes overlay, whose paths must be absolute, to a temporary
+// directory in the format of the -overlay flag of the go command. It returns
+// the flags that make the go command use it, which are empty if the overlay
+// is, and a function that removes the directory.
+func writeOverlay(overlay map[string][]byte) (flags []string, cleanup func(), err error) {
+	if len(overlay) == 0 {
+		return nil, func() {}, nil
+	}
+	tmp, err := os.MkdirTemp("", "symbolindex-overlay-")
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup = func() { os.RemoveAll(tmp) }
+	replace := map[string]string{}
+	for name, content := range overlay {
+		// The go command decides how to compile a file by its extension.
+		replacement := filepath.Join(tmp, strconv.Itoa(len(replace))+filepath.Ext(name))
+		if err := os.WriteFile(replacement, content, 0o644); err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+		replace[name] = replacement
+	}
+	out, err := json.Marshal(struct{ Replace map[string]string }{replace})
+	if err == nil {
+		err = os.WriteFile(filepath.Join(tmp, "overlay.json"), out, 0o644)
+	}
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return []string{"-overlay", filepath.Join(tmp, "overlay.json")}, cleanup, nil
+}
+
+// LoadOverlay is like Load, but loads the packages as if the files of overlay
+// had the given contents; see WithOverlay.
+//
+// The packages whose files are replaced or added by the overlay, and those that
+// depend on them, are type-checked from the overlay. The other packages are
+// loaded as by Load.
+func LoadOverlay(ctx context.Context, dir string, overlay map[string][]byte, patterns ...string) ([]*Index, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	overlay = absOverlay(absDir, overlay)
+	overlayFlags, cleanup, err := writeOverlay(overlay)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	paths, err := listPackages(ctx, dir, overlayFlags, patterns)
+	if err != nil {
+		return nil, err
+	}
+	imp := &overlayImporter{
+		fset:     token.NewFileSet(),
+		overlay:  overlay,
+		packages: map[string]*listedPackage{},
+		checked:  map[string]*types.Package{},
+		// The source importer type-checks every dependency from source.
+		// Unlike loaders that read compiler export data, it works with any
+		// version of the go command.
+		source: sourceImporter(),
+	}
+	if len(overlay) > 0 {
+		if err := imp.listDeps(ctx, dir, overlayFlags, paths); err != nil {
+			return nil, err
+		}
+	}
+	var indexes []*Index
+	for _, path := range paths {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var pkg *types.Package
+		if imp.packages[path] != nil && imp.packages[path].affected {
+			pkg, err = imp.check(path)
+		} else {
+			pkg, err = imp.source.ImportFrom(path, absDir, 0)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error loading package %s: %w", path, err)
+		}
+		indexes = append(indexes, New(pkg))
+	}
+	return indexes, nil
+}
+
+// listedPackage is a package listed by "go list -json".
+type listedPackage struct {
+	ImportPath string
+	Dir        string
+	GoFiles    []string
+	CgoFiles   []string
+	Imports    []string
+	ImportMap  map[string]string
+	Error      *struct{ Err string }
+
+	// affected is set for the packages whose files are in the overlay and for
+	// those that depend on them.
+	affected bool
+}
+
+// overlayImporter type-checks the packages affected by an overlay from source,
+// and imports the other packages with the source importer.
+type overlayImporter struct {
+	fset     *token.FileSet
+	overlay  map[string][]byte
+	packages map[string]*listedPackage
+	checked  map[string]*types.Package
+	source   types.ImporterFrom
+}
+
+// listDeps lists the packages with the given import paths and their
+// dependencies, and finds those affected by the overlay.
+func (imp *overlayImporter) listDeps(ctx context.Context, dir string, overlayFlags, paths []string) error {
+	args := append([]string{"list", "-e", "-json", "-deps"}, overlayFlags...)
+	cmd := exec.CommandContext(ctx, "go", append(append(args, "--"), paths...)...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("error listing packages: %w\n%s", err, stderr.String())
+	}
+	// Dependencies are listed before the packages that import them.
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for {
+		lp := &listedPackage{}
+		if err := dec.Decode(lp); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("error listing packages: %w", err)
+		}
+		imp.packages[lp.ImportPath] = lp
+		for _, name := range append(lp.GoFiles, lp.CgoFiles...) {
+			if _, ok := imp.overlay[filepath.Join(lp.Dir, name)]; ok {
+				lp.affected = true
+			}
+		}
+		for _, path := range lp.Imports {
+			if dep := imp.packages[path]; dep != nil && dep.affected {
+				lp.affected = true
+			}
+		}
+	}
+}
+
+// check type-checks the affected package with the given import path.
+func (imp *overlayImporter) check(path string) (*types.Package, error) {
+	if pkg, ok := imp.checked[path]; ok {
+		return pkg, nil
+	}
+	lp := imp.packages[path]
+	if lp.Error != nil {
+		return nil, fmt.Errorf("%s", lp.Error.Err)
+	}
+	var files []*ast.File
+	for _, name := range append(lp.GoFiles, lp.CgoFiles...) {
+		name = filepath.Join(lp.Dir, name)
+		// The parser reads the file if it isn't in the overlay.
+		var src any
+		if content, ok := imp.overlay[name]; ok {
+			src = content
+		}
+		f, err := parser.ParseFile(imp.fset, name, src, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	conf := &types.Config{
+		Importer: importerFunc(func(importPath string) (*types.Package, error) {
+			resolved := importPath
+			if vendored, ok := lp.ImportMap[importPath]; ok {
+				resolved = vendored
+			}
+			if dep := imp.packages[resolved]; dep != nil && dep.affected {
+				return imp.check(resolved)
+			}
+			// The source importer resolves vendored import paths itself.
+			return imp.source.ImportFrom(importPath, lp.Dir, 0)
+		}),
+		FakeImportC: true,
+	}
+	pkg, err := conf.Check(path, imp.fset, files, nil)
+	if err != nil {
+		return nil, err
+	}
+	imp.checked[path] = pkg
+	return pkg, nil
+}
+
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) { return f(path) }
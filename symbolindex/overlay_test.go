@@ -0,0 +1,67 @@
+package symbolindex
+
+import (
+	"context"
+	"testing"
+
+	"github.com/meta-programming/go-codegenutil"
+)
+
+// testOverlay adds a generated package that isn't written yet and a file to
+// the naming package. Its paths are relative to the root of the module.
+var testOverlay = map[string][]byte{
+	"internal/generated/greeting/greeting.go": []byte(`package hello
+
+import "github.com/meta-programming/go-codegenutil/naming"
+
+// Greet returns a greeting.
+func Greet(name string) string { return "Hello, " + naming.Overlaid(name) }
+`),
+	"naming/overlaid.go": []byte(`package naming
+
+// Overlaid is only declared in the overlay.
+func Overlaid(name string) string { return name }
+`),
+}
+
+func TestPackageNameResolver_overlay(t *testing.T) {
+	resolve := PackageNameResolver("..", WithOverlay(testOverlay))
+	if got := resolve("github.com/meta-programming/go-codegenutil/internal/generated/greeting"); got != "hello" {
+		t.Errorf("resolver(greeting) = %q, want %q", got, "hello")
+	}
+	if got := resolve("github.com/meta-programming/go-codegenutil/naming"); got != "naming" {
+		t.Errorf("resolver(naming) = %q, want %q", got, "naming")
+	}
+	if got := PackageNameResolver("..")("github.com/meta-programming/go-codegenutil/internal/generated/greeting"); got != "greeting" {
+		t.Errorf("resolver(greeting) without the overlay = %q, want the assumed name %q", got, "greeting")
+	}
+}
+
+func TestLoadOverlay(t *testing.T) {
+	indexes, err := LoadOverlay(context.Background(), "..", testOverlay, "./internal/generated/greeting", "./naming", "strings")
+	if err != nil {
+		t.Fatalf("LoadOverlay() error: %v", err)
+	}
+	if len(indexes) != 3 {
+		t.Fatalf("LoadOverlay() returned %d indexes, want 3", len(indexes))
+	}
+	if e, ok := indexes[0].Lookup("Greet"); !ok || e.Type != "func(name string) string" {
+		t.Errorf("Lookup(%q) in greeting = %+v, want func(name string) string", "Greet", e)
+	}
+	if name := indexes[0].Package().Name(); name != "hello" {
+		t.Errorf("Package().Name() of greeting = %q, want %q", name, "hello")
+	}
+	for _, name := range []string{"Overlaid", "Exported"} {
+		if _, ok := indexes[1].Lookup(name); !ok {
+			t.Errorf("Lookup(%q) in naming not found", name)
+		}
+	}
+
+	r := NewResolver("..", WithOverlay(testOverlay))
+	if err := r.Verify(context.Background(), codegenutil.Sym("github.com/meta-programming/go-codegenutil/internal/generated/greeting", "Greet")); err != nil {
+		t.Errorf("Verify() of a symbol of the overlay got error %v", err)
+	}
+	if err := NewResolver("..").Verify(context.Background(), codegenutil.Sym("github.com/meta-programming/go-codegenutil/naming", "Overlaid")); err == nil {
+		t.Errorf("Verify() of a symbol of the overlay without it succeeded, want error")
+	}
+}
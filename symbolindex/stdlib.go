@@ -0,0 +1,130 @@
+package symbolindex
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/meta-programming/go-codegenutil"
+)
+
+// StdlibVersions knows the versions of Go that introduced the exported
+// package-level symbols of the standard library, such as go1.21 for
+// "slices".Max. See LoadStdlibVersions.
+type StdlibVersions struct {
+	// since maps import paths and names to versions.
+	since map[[2]string]string
+}
+
+// unsafeVersions are the versions of the functions of package unsafe, which
+// the api files don't list, that are newer than go1.
+var unsafeVersions = map[string]string{
+	"Add":        "go1.17",
+	"Slice":      "go1.17",
+	"SliceData":  "go1.20",
+	"String":     "go1.20",
+	"StringData": "go1.20",
+}
+
+// apiLine matches the lines of the api files that declare package-level
+// symbols, such as "pkg slices, func Max[...]" or
+// "pkg syscall (linux-386), const AF_ALG = 38".
+var apiLine = regexp.MustCompile(`^pkg ([^ ,]+)(?: \([^)]*\))?, (?:func|type|const|var) ([A-Za-z_][A-Za-z0-9_]*)`)
+
+// LoadStdlibVersions reads the versions of the symbols of the standard library
+// from the api directory of the Go installation at goroot, which lists the API
+// added by each release of Go, or of the installation of the go command if
+// goroot is empty. The result is meant for codegenutil.WithSymbolVersions:
+//
+//	versions, err := symbolindex.LoadStdlibVersions(ctx, "")
+//	...
+//	imports := codegenutil.NewFileImports(pkg, codegenutil.WithGoVersion("go1.20"), codegenutil.WithSymbolVersions(versions.Since))
+func LoadStdlibVersions(ctx context.Context, goroot string) (*StdlibVersions, error) {
+	if goroot == "" {
+		out, err := exec.CommandContext(ctx, "go", "env", "GOROOT").Output()
+		if err != nil {
+			return nil, fmt.Errorf("error finding GOROOT: %w", err)
+		}
+		goroot = string(bytes.TrimSpace(out))
+	}
+	files, err := filepath.Glob(filepath.Join(goroot, "api", "go1*.txt"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no api files in %s", filepath.Join(goroot, "api"))
+	}
+	type apiFile struct {
+		path, version string
+		minor         int
+	}
+	var apiFiles []apiFile
+	for _, file := range files {
+		version := strings.TrimSuffix(filepath.Base(file), ".txt")
+		if minor, ok := minorVersion(version); ok {
+			apiFiles = append(apiFiles, apiFile{file, version, minor})
+		}
+	}
+	// The files are read from the oldest release on, so that the version of a
+	// symbol is the first that lists it.
+	sort.Slice(apiFiles, func(i, j int) bool { return apiFiles[i].minor < apiFiles[j].minor })
+	v := &StdlibVersions{since: map[[2]string]string{}}
+	for _, file := range apiFiles {
+		if err := v.readAPIFile(file.path, file.version); err != nil {
+			return nil, err
+		}
+	}
+	for name, version := range unsafeVersions {
+		v.since[[2]string{"unsafe", name}] = version
+	}
+	return v, nil
+}
+
+func (v *StdlibVersions) readAPIFile(file, version string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.Contains(line, "//deprecated") {
+			continue
+		}
+		m := apiLine.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		key := [2]string{m[1], m[2]}
+		if _, ok := v.since[key]; !ok {
+			v.since[key] = version
+		}
+	}
+	return scanner.Err()
+}
+
+// Since returns the version of Go that introduced sym, such as "go1.21", or
+// the empty string if sym isn't a symbol of the standard library. The symbols
+// of the first release of Go have the version "go1".
+func (v *StdlibVersions) Since(sym *codegenutil.Symbol) string {
+	return v.since[[2]string{sym.Package().ImportPath(), sym.Name()}]
+}
+
+// minorVersion returns the minor version of a release named like its api file,
+// such as "go1.21", which is 0 for "go1".
+func minorVersion(version string) (int, bool) {
+	if version == "go1" {
+		return 0, true
+	}
+	minor, err := strconv.Atoi(strings.TrimPrefix(version, "go1."))
+	return minor, err == nil && strings.HasPrefix(version, "go1.")
+}
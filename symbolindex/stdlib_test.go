@@ -0,0 +1,63 @@
+package symbolindex
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/meta-programming/go-codegenutil"
+)
+
+func TestLoadStdlibVersions(t *testing.T) {
+	goroot := t.TempDir()
+	api := map[string]string{
+		"go1.txt": `pkg bytes, func Compare([]uint8, []uint8) int
+pkg os, const O_RDONLY int
+pkg syscall (linux-386), const AF_ALG = 38
+`,
+		"go1.9.txt": `pkg math/bits, func LeadingZeros(uint) int
+pkg syscall (windows-386), const AF_ALG = 38
+`,
+		"go1.21.txt": `pkg slices, func Max[$0 interface{ ~[]$1 }, $1 cmp.Ordered]($0) $1
+pkg bytes, func Compare //deprecated
+pkg math/bits, method (*Rand) LeadingZeros() int
+`,
+		"next.txt": `pkg slices, func Future() int
+`,
+	}
+	if err := os.Mkdir(filepath.Join(goroot, "api"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range api {
+		if err := os.WriteFile(filepath.Join(goroot, "api", name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	versions, err := LoadStdlibVersions(context.Background(), goroot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		sym  *codegenutil.Symbol
+		want string
+	}{
+		{codegenutil.Sym("bytes", "Compare"), "go1"},
+		{codegenutil.Sym("os", "O_RDONLY"), "go1"},
+		{codegenutil.Sym("syscall", "AF_ALG"), "go1"},
+		{codegenutil.Sym("math/bits", "LeadingZeros"), "go1.9"},
+		{codegenutil.Sym("slices", "Max"), "go1.21"},
+		{codegenutil.Sym("slices", "Future"), ""},
+		{codegenutil.Sym("unsafe", "Slice"), "go1.17"},
+		{codegenutil.Sym("example.com/x", "Max"), ""},
+	}
+	for _, tt := range tests {
+		if got := versions.Since(tt.sym); got != tt.want {
+			t.Errorf("Since(%v) = %q, want %q", tt.sym, got, tt.want)
+		}
+	}
+
+	if _, err := LoadStdlibVersions(context.Background(), t.TempDir()); err == nil {
+		t.Errorf("LoadStdlibVersions() without api files succeeded, want error")
+	}
+}
@@ -0,0 +1,188 @@
+// Package symbolindex lists the exported API of Go packages as
+// *codegenutil.Symbol values, so that generators can enumerate and refer to
+// existing declarations instead of maintaining lists of names by hand.
+//
+// A Resolver can also verify that the symbols used by generated code exist,
+// so that a misspelled symbol is reported when the code is generated rather
+// than when it is compiled.
+package symbolindex
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/importer"
+	"go/token"
+	"go/types"
+	"io"
+	"os/exec"
+
+	"github.com/meta-programming/go-codegenutil"
+)
+
+// Kind is the kind of declaration that declares a symbol.
+type Kind int
+
+// Kinds of symbols.
+const (
+	Const Kind = iota + 1
+	Var
+	Type
+	Func
+)
+
+// String returns the keyword that declares symbols of the kind.
+func (k Kind) String() string {
+	switch k {
+	case Const:
+		return "const"
+	case Var:
+		return "var"
+	case Type:
+		return "type"
+	case Func:
+		return "func"
+	}
+	return fmt.Sprintf("Kind(%d)", int(k))
+}
+
+// Entry describes an exported symbol of a package.
+type Entry struct {
+	// Symbol is the symbol.
+	Symbol *codegenutil.Symbol
+	// Kind is the kind of declaration of the symbol.
+	Kind Kind
+	// Type describes the symbol's type as Go code: the signature of a
+	// function, the type of a constant or variable, or the underlying type of
+	// a type. Types of other packages are qualified with their package names.
+	Type string
+	// Object is the object of the declaration.
+	Object types.Object
+}
+
+// Index is the exported API of a package.
+type Index struct {
+	pkg     *codegenutil.Package
+	entries []*Entry
+	byName  map[string]*Entry
+}
+
+// Load lists the packages matching patterns with "go list" run in dir,
+// type-checks them from source and returns their indexes in the order the
+// packages are listed.
+func Load(ctx context.Context, dir string, patterns ...string) ([]*Index, error) {
+	return LoadOverlay(ctx, dir, nil, patterns...)
+}
+
+// sourceImporter returns an importer that type-checks packages and their
+// dependencies from source.
+func sourceImporter() types.ImporterFrom {
+	return importer.ForCompiler(token.NewFileSet(), "source", nil).(types.ImporterFrom)
+}
+
+// listPackages returns the import paths of the packages matching patterns.
+// overlayFlags are the flags returned by writeOverlay. The error of a package
+// without Go files, such as an import path that no module provides, wraps
+// codegenutil.ErrImportNotFound.
+func listPackages(ctx context.Context, dir string, overlayFlags, patterns []string) ([]string, error) {
+	args := append(append([]string{"list", "-e", "-json"}, overlayFlags...), "--")
+	args = append(args, patterns...)
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("error listing packages: %w\n%s", err, stderr.String())
+	}
+	var paths []string
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for {
+		lp := &listedPackage{}
+		if err := dec.Decode(lp); err == io.EOF {
+			return paths, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("error listing packages: %w", err)
+		}
+		if lp.Error != nil {
+			if len(lp.GoFiles)+len(lp.CgoFiles) == 0 {
+				return nil, fmt.Errorf("error listing packages: %s: %w", lp.Error.Err, codegenutil.ErrImportNotFound)
+			}
+			return nil, fmt.Errorf("error listing packages: %s", lp.Error.Err)
+		}
+		paths = append(paths, lp.ImportPath)
+	}
+}
+
+// New returns the index of a type-checked package.
+func New(pkg *types.Package) *Index {
+	idx := &Index{
+		pkg:    codegenutil.ExplicitPackageName(pkg.Path(), pkg.Name()),
+		byName: map[string]*Entry{},
+	}
+	qualifier := func(other *types.Package) string {
+		if other == pkg {
+			return ""
+		}
+		return other.Name()
+	}
+	scope := pkg.Scope()
+	for _, name := range scope.Names() {
+		obj := scope.Lookup(name)
+		if !obj.Exported() {
+			continue
+		}
+		e := &Entry{Symbol: idx.pkg.Symbol(name), Object: obj}
+		typ := obj.Type()
+		switch obj.(type) {
+		case *types.Const:
+			e.Kind = Const
+		case *types.Var:
+			e.Kind = Var
+		case *types.TypeName:
+			e.Kind = Type
+			typ = typ.Underlying()
+		case *types.Func:
+			e.Kind = Func
+		default:
+			continue
+		}
+		e.Type = types.TypeString(typ, qualifier)
+		idx.entries = append(idx.entries, e)
+		idx.byName[name] = e
+	}
+	return idx
+}
+
+// Package returns the indexed package.
+func (idx *Index) Package() *codegenutil.Package { return idx.pkg }
+
+// Entries returns the exported symbols of the package sorted by name.
+func (idx *Index) Entries() []*Entry { return idx.entries }
+
+// EntriesOfKind returns the exported symbols of the given kind sorted by name.
+func (idx *Index) EntriesOfKind(kind Kind) []*Entry {
+	var entries []*Entry
+	for _, e := range idx.entries {
+		if e.Kind == kind {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// Lookup returns the entry of the exported symbol with the given name, if any.
+func (idx *Index) Lookup(name string) (*Entry, bool) {
+	e, ok := idx.byName[name]
+	return e, ok
+}
+
+// Symbols returns the exported symbols of the package sorted by name.
+func (idx *Index) Symbols() []*codegenutil.Symbol {
+	syms := make([]*codegenutil.Symbol, len(idx.entries))
+	for i, e := range idx.entries {
+		syms[i] = e.Symbol
+	}
+	return syms
+}
@@ -0,0 +1,80 @@
+package symbolindex
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/meta-programming/go-codegenutil/internal/typestest"
+)
+
+func TestNew(t *testing.T) {
+	pkg := typestest.Check(t, "abc.xyz/mypkg", `package mypkg
+
+import "time"
+
+const Timeout = 5 * time.Second
+
+var Default Config
+
+type Config struct{ Name string }
+
+type Opt func(*Config)
+
+func Apply(c *Config, opts ...Opt) error { return nil }
+
+func (c Config) Method() {}
+
+func unexported() {}
+`)
+	idx := New(pkg)
+	var got []string
+	for _, e := range idx.Entries() {
+		got = append(got, e.Kind.String()+" "+e.Symbol.Name()+" "+e.Type)
+	}
+	want := []string{
+		"func Apply func(c *Config, opts ...Opt) error",
+		"type Config struct{Name string}",
+		"var Default Config",
+		"type Opt func(*Config)",
+		"const Timeout time.Duration",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Entries() got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if idx.Package().ImportPath() != "abc.xyz/mypkg" || idx.Package().Name() != "mypkg" {
+		t.Errorf("Package() = %v, want abc.xyz/mypkg", idx.Package())
+	}
+	if _, ok := idx.Lookup("unexported"); ok {
+		t.Errorf("Lookup(%q) found an unexported symbol", "unexported")
+	}
+	if e, ok := idx.Lookup("Opt"); !ok || e.Kind != Type {
+		t.Errorf("Lookup(%q) = %v, %v, want a type", "Opt", e, ok)
+	}
+	if n := len(idx.EntriesOfKind(Type)); n != 2 {
+		t.Errorf("EntriesOfKind(Type) returned %d entries, want 2", n)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	indexes, err := Load(context.Background(), "..", "encoding/json", "./naming")
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if len(indexes) != 2 {
+		t.Fatalf("Load() returned %d indexes, want 2", len(indexes))
+	}
+	if e, ok := indexes[1].Lookup("Exported"); !ok || e.Type != "func(name string) string" {
+		t.Errorf("Lookup(%q) in naming = %+v, want func(name string) string", "Exported", e)
+	}
+	if _, err := Load(context.Background(), "..", "./nonexistent"); err == nil {
+		t.Errorf("Load() of a missing package succeeded, want error")
+	}
+	e, ok := indexes[0].Lookup("Marshal")
+	if !ok {
+		t.Fatalf("Lookup(%q) not found", "Marshal")
+	}
+	if e.Kind != Func || e.Symbol.Package().ImportPath() != "encoding/json" {
+		t.Errorf("Lookup(%q) = %+v, want func of encoding/json", "Marshal", e)
+	}
+}
@@ -0,0 +1,189 @@
+package symbolindex
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/meta-programming/go-codegenutil"
+	"golang.org/x/sync/singleflight"
+)
+
+// Resolver finds the indexes of packages by import path, loading each package
+// at most once, when its index is first needed. It is safe for concurrent use.
+type Resolver struct {
+	dir     string
+	overlay map[string][]byte
+
+	mu      sync.Mutex
+	indexes map[string]*Index
+	loading singleflight.Group
+}
+
+// NewResolver returns a Resolver that loads packages as if by "go list" run in
+// dir, which is usually a directory of the module that will contain the
+// generated code. See WithOverlay for loading packages from an overlay.
+func NewResolver(dir string, opts ...Option) *Resolver {
+	return &Resolver{dir: dir, overlay: newConfig(opts).overlay, indexes: map[string]*Index{}}
+}
+
+// Add adds an index to the resolver, which is then used instead of loading
+// the package it indexes.
+func (r *Resolver) Add(idx *Index) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.indexes[idx.Package().ImportPath()] = idx
+}
+
+// Index returns the index of the package with the given import path. The error
+// wraps codegenutil.ErrImportNotFound if no package has the import path.
+func (r *Resolver) Index(ctx context.Context, importPath string) (*Index, error) {
+	r.mu.Lock()
+	idx, ok := r.indexes[importPath]
+	r.mu.Unlock()
+	if ok {
+		return idx, nil
+	}
+	// Concurrent calls for the same package share a single load.
+	v, err, _ := r.loading.Do(importPath, func() (interface{}, error) {
+		indexes, err := LoadOverlay(ctx, r.dir, r.overlay, importPath)
+		if err != nil {
+			return nil, err
+		}
+		if len(indexes) == 0 {
+			return nil, fmt.Errorf("%w: no package matches import path %q", codegenutil.ErrImportNotFound, importPath)
+		}
+		if len(indexes) != 1 {
+			return nil, fmt.Errorf("import path %q matches %d packages, want 1", importPath, len(indexes))
+		}
+		r.Add(indexes[0])
+		return indexes[0], nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*Index), nil
+}
+
+// UndefinedError is returned by Verify for symbols that aren't exported by
+// their packages.
+type UndefinedError struct {
+	// Symbols are the undefined symbols.
+	Symbols []*codegenutil.Symbol
+	// Suggestions maps the undefined symbols to the names of exported symbols
+	// of the same package with similar names, if any.
+	Suggestions map[*codegenutil.Symbol]string
+}
+
+func (e *UndefinedError) Error() string {
+	var lines []string
+	for _, sym := range e.Symbols {
+		line := fmt.Sprintf("%s.%s is not exported by package %q", sym.Package().Name(), sym.Name(), sym.Package().ImportPath())
+		if suggestion := e.Suggestions[sym]; suggestion != "" {
+			line += fmt.Sprintf(" (did you mean %s?)", suggestion)
+		}
+		lines = append(lines, line)
+	}
+	return "undefined symbols:\n\t" + strings.Join(lines, "\n\t")
+}
+
+// Verify checks that each of the symbols is exported by its package, which
+// catches misspelled symbols when code is generated rather than when it is
+// compiled. Symbols of the builtin package are not checked.
+//
+// Verify returns an *UndefinedError listing the symbols that don't exist, or
+// the error that occurred loading one of the packages.
+func (r *Resolver) Verify(ctx context.Context, syms ...*codegenutil.Symbol) error {
+	undefined := &UndefinedError{Suggestions: map[*codegenutil.Symbol]string{}}
+	for _, sym := range syms {
+		if sym.Package().IsBuiltin() {
+			continue
+		}
+		idx, err := r.Index(ctx, sym.Package().ImportPath())
+		if err != nil {
+			return err
+		}
+		if _, ok := idx.Lookup(sym.Name()); ok {
+			continue
+		}
+		undefined.Symbols = append(undefined.Symbols, sym)
+		if suggestion := idx.closestName(sym.Name()); suggestion != "" {
+			undefined.Suggestions[sym] = suggestion
+		}
+	}
+	if len(undefined.Symbols) > 0 {
+		return undefined
+	}
+	return nil
+}
+
+// VerifyImports verifies the symbols recorded by imports, which must have been
+// created with the codegenutil.RecordSymbols option. Symbols of the file's own
+// package are not checked, since they may be declared by the generated code.
+func (r *Resolver) VerifyImports(ctx context.Context, imports *codegenutil.FileImports) error {
+	var syms []*codegenutil.Symbol
+	for _, sym := range imports.Symbols() {
+		if !imports.IsFilePackage(sym.Package()) {
+			syms = append(syms, sym)
+		}
+	}
+	return r.Verify(ctx, syms...)
+}
+
+// closestName returns the exported name of the package that is most similar to
+// name, or the empty string if none is similar enough to be a likely typo.
+func (idx *Index) closestName(name string) string {
+	maxDistance := len(name) / 3
+	if maxDistance > 2 {
+		maxDistance = 2
+	}
+	var candidates []string
+	best := maxDistance + 1
+	for _, e := range idx.entries {
+		d := editDistance(strings.ToLower(name), strings.ToLower(e.Symbol.Name()))
+		switch {
+		case d < best:
+			best, candidates = d, []string{e.Symbol.Name()}
+		case d == best:
+			candidates = append(candidates, e.Symbol.Name())
+		}
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+	sort.Strings(candidates)
+	return candidates[0]
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
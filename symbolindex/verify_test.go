@@ -0,0 +1,55 @@
+package symbolindex
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/meta-programming/go-codegenutil"
+	cb "github.com/meta-programming/go-codegenutil/codebuilder"
+	"github.com/meta-programming/go-codegenutil/internal/typestest"
+)
+
+func TestResolver_VerifyImports(t *testing.T) {
+	r := NewResolver("..")
+	r.Add(New(typestest.Check(t, "abc.xyz/dep", `package dep
+
+func Marshal(v any) ([]byte, error) { return nil, nil }
+
+func MustMarshal(v any) []byte { return nil }
+`)))
+	imports := codegenutil.NewFileImports(codegenutil.AssumedPackageName("abc.xyz/mypkg"), codegenutil.RecordSymbols())
+	_, err := cb.NewFile(imports.Package()).Add(
+		cb.Var("a").Value(cb.Call(codegenutil.Sym("abc.xyz/dep", "Marshall"), cb.Lit(1))),
+		cb.Var("b").Value(cb.Call(codegenutil.Sym("abc.xyz/dep", "MustMarshal"), cb.Lit(1))),
+		cb.Var("c").Value(cb.Call(codegenutil.Sym("strings", "ToUpper"), cb.Lit("x"))),
+		cb.Var("d").Value(cb.Call(codegenutil.Sym("abc.xyz/mypkg", "generatedElsewhere"))),
+		cb.Var("e").Type(codegenutil.BuiltinPackage.Symbol("int")),
+	).Render(imports)
+	if err != nil {
+		t.Fatalf("Render() error: %v", err)
+	}
+
+	err = r.VerifyImports(context.Background(), imports)
+	var undefined *UndefinedError
+	if !errors.As(err, &undefined) {
+		t.Fatalf("VerifyImports() got error %v, want *UndefinedError", err)
+	}
+	want := "undefined symbols:\n\tdep.Marshall is not exported by package \"abc.xyz/dep\" (did you mean Marshal?)"
+	if err.Error() != want {
+		t.Errorf("VerifyImports() got error:\n%s\nwant:\n%s", err, want)
+	}
+}
+
+func TestResolver_Verify(t *testing.T) {
+	r := NewResolver("..")
+	if err := r.Verify(context.Background(), codegenutil.Sym("strings", "ToUpper"), codegenutil.Sym("strings", "Builder")); err != nil {
+		t.Errorf("Verify() of existing symbols got error %v", err)
+	}
+	if err := r.Verify(context.Background(), codegenutil.Sym("strings", "reader")); err == nil {
+		t.Errorf("Verify() of an unexported symbol succeeded, want error")
+	}
+	if err := r.Verify(context.Background(), codegenutil.Sym("abc.xyz/nonexistent", "X")); !errors.Is(err, codegenutil.ErrImportNotFound) {
+		t.Errorf("Verify() of a symbol of a missing package got error %v, want codegenutil.ErrImportNotFound", err)
+	}
+}
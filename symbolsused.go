@@ -0,0 +1,83 @@
+package codegenutil
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strconv"
+)
+
+// SymbolUse is the use of a symbol of an imported package in a Go file, as
+// reported by SymbolsUsed. Its JSON form is meant for the tools that review
+// generated code, such as those that flag calls to sensitive packages.
+type SymbolUse struct {
+	// Package is the import path of the package of the symbol.
+	Package string `json:"package"`
+	// Name is the name of the symbol. It is empty for dot imports and imports
+	// for side effects, whose symbols aren't qualified.
+	Name string `json:"name,omitempty"`
+	// Count is the number of references to the symbol in the file, or 1 for
+	// dot imports and imports for side effects.
+	Count int `json:"count"`
+}
+
+// SymbolsUsed returns the symbols of imported packages that the Go file src
+// refers to, with the number of references to each, sorted by import path and
+// name. Imports without an alias are assumed to have the name that
+// AssumedPackageName gives their packages, as in the files that FileImports
+// formats.
+//
+// References are qualified identifiers, such as json.Marshal: local
+// declarations that shadow the name of an import are not told apart.
+func SymbolsUsed(src []byte) ([]*SymbolUse, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+	// byLocalName maps the local names of the imports to their import paths.
+	byLocalName := map[string]string{}
+	uses := map[SymbolUse]int{}
+	for _, spec := range file.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		localName := AssumedPackageName(importPath).Name()
+		if spec.Name != nil {
+			localName = spec.Name.Name
+		}
+		if localName == "_" || localName == "." {
+			uses[SymbolUse{Package: importPath}] = 1
+			continue
+		}
+		byLocalName[localName] = importPath
+	}
+	ast.Inspect(file, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		if x, ok := sel.X.(*ast.Ident); ok {
+			if importPath, ok := byLocalName[x.Name]; ok {
+				uses[SymbolUse{Package: importPath, Name: sel.Sel.Name}]++
+			}
+		}
+		return true
+	})
+
+	out := make([]*SymbolUse, 0, len(uses))
+	for use, count := range uses {
+		use := use
+		use.Count = count
+		out = append(out, &use)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Package != out[j].Package {
+			return out[i].Package < out[j].Package
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out, nil
+}
@@ -0,0 +1,58 @@
+package codegenutil
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestSymbolsUsed(t *testing.T) {
+	src := `package mypkg
+
+import (
+	"encoding/json"
+	"os/exec"
+	yaml "gopkg.in/yaml.v3"
+	_ "embed"
+)
+
+func run(v any) error {
+	out, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(out, &v); err != nil {
+		return err
+	}
+	_, _ = json.Marshal(nil)
+	_ = yaml.Marshal
+	return exec.Command("true").Run()
+}
+`
+	got, err := SymbolsUsed([]byte(src))
+	if err != nil {
+		t.Fatalf("SymbolsUsed() error: %v", err)
+	}
+	want := []*SymbolUse{
+		{Package: "embed", Count: 1},
+		{Package: "encoding/json", Name: "Marshal", Count: 2},
+		{Package: "encoding/json", Name: "Unmarshal", Count: 1},
+		{Package: "gopkg.in/yaml.v3", Name: "Marshal", Count: 1},
+		{Package: "os/exec", Name: "Command", Count: 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SymbolsUsed() got:\n%s\nwant:\n%s", formatUses(got), formatUses(want))
+	}
+
+	if _, err := SymbolsUsed([]byte("package")); err == nil {
+		t.Errorf("SymbolsUsed() of invalid Go got no error")
+	}
+}
+
+func formatUses(uses []*SymbolUse) string {
+	var out string
+	for _, u := range uses {
+		out += u.Package + " " + u.Name + " " + fmt.Sprint(u.Count) + "\n"
+	}
+	return out
+}
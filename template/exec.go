@@ -5,6 +5,7 @@
 package template
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -39,6 +40,16 @@ type state struct {
 	node  parse.Node // current node, for errors
 	vars  []variable // push-down stack of variable values.
 	depth int        // the height of the stack of executing templates.
+
+	// Execution-scoped settings from ExecOptions; nil if not overridden.
+	funcs      map[string]reflect.Value
+	formatFunc FormatFunc
+	transform  func(reflect.Value) (any, bool)
+	ctx        context.Context
+
+	// missingKey is the key of the map lookup of the last command evaluated
+	// that found no entry, for the error of printing its invalid value.
+	missingKey string
 }
 
 // variable holds the dynamic value of a variable such as $, $x etc.
@@ -96,6 +107,12 @@ type missingValType struct{}
 
 var missingVal = reflect.ValueOf(missingValType{})
 
+var missingValReflectType = reflect.TypeOf(missingValType{})
+
+func isMissing(v reflect.Value) bool {
+	return v.IsValid() && v.Type() == missingValReflectType
+}
+
 // at marks the state to be on node n, for error reporting.
 func (s *state) at(node parse.Node) {
 	s.node = node
@@ -113,7 +130,8 @@ func doublePercent(str string) string {
 
 // ExecError is the custom error type returned when Execute has an
 // error evaluating its template. (If a write error occurs, the actual
-// error is returned; it will not be of type ExecError.)
+// error is returned; it will not be of type ExecError.) Other errors returned by
+// a FormatFunc are reported as an ExecError wrapping a *PrintError.
 type ExecError struct {
 	Name string // Name of template.
 	Err  error  // Pre-formatted error.
@@ -200,10 +218,37 @@ func (t *Template) ExecuteTemplate(wr io.Writer, name string, data any) error {
 // If data is a reflect.Value, the template applies to the concrete
 // value that the reflect.Value holds, as in fmt.Print.
 func (t *Template) Execute(wr io.Writer, data any) error {
-	return t.execute(wr, data)
+	return t.execute(wr, data, nil)
+}
+
+// ExecOptions customizes a single execution of a template without modifying
+// the template itself. See ExecuteWith.
+type ExecOptions struct {
+	// Printer, if non-nil, is used instead of the template's FormatFunc.
+	Printer FormatFunc
+	// TransformFirst has the same meaning as the argument of the same name
+	// to the Printer method. It is ignored if Printer is nil.
+	TransformFirst bool
+	// Funcs replaces functions of the same name in the template's function
+	// map. As with Funcs, a function must be defined before the template is
+	// parsed to be usable in it.
+	Funcs FuncMap
+	// Context, if non-nil, stops the execution with its error once it is
+	// done. It is checked before each iteration of a range action and each
+	// template invocation, which is where runaway executions spend their
+	// time.
+	Context context.Context
+}
+
+// ExecuteWith is like Execute, but uses the printer and functions given by
+// opts for this execution only. Unlike calling Printer or Funcs on a Clone of
+// the template, ExecuteWith does not copy the template, and it may be called
+// in parallel with other executions of the template.
+func (t *Template) ExecuteWith(wr io.Writer, data any, opts *ExecOptions) error {
+	return t.execute(wr, data, opts)
 }
 
-func (t *Template) execute(wr io.Writer, data any) (err error) {
+func (t *Template) execute(wr io.Writer, data any, opts *ExecOptions) (err error) {
 	defer errRecover(&err)
 	value, ok := data.(reflect.Value)
 	if !ok {
@@ -214,6 +259,23 @@ func (t *Template) execute(wr io.Writer, data any) (err error) {
 		wr:   wr,
 		vars: []variable{{"$", value}},
 	}
+	if opts != nil {
+		if len(opts.Funcs) != 0 {
+			funcs, err := valueFuncs(opts.Funcs)
+			if err != nil {
+				return err
+			}
+			state.funcs = funcs
+		}
+		state.ctx = opts.Context
+		if opts.Printer != nil {
+			state.formatFunc = opts.Printer
+			state.transform = printableValueRaw
+			if opts.TransformFirst {
+				state.transform = printableValue
+			}
+		}
+	}
 	if t.Tree == nil || t.Root == nil {
 		state.errorf("%q is an incomplete or empty template", t.Name())
 	}
@@ -329,7 +391,7 @@ func isTrue(val reflect.Value) (truth, ok bool) {
 		truth = val.Bool()
 	case reflect.Complex64, reflect.Complex128:
 		truth = val.Complex() != 0
-	case reflect.Chan, reflect.Func, reflect.Pointer, reflect.Interface:
+	case reflect.Chan, reflect.Func, reflect.Pointer, reflect.UnsafePointer, reflect.Interface:
 		truth = !val.IsNil()
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		truth = val.Int() != 0
@@ -345,6 +407,16 @@ func isTrue(val reflect.Value) (truth, ok bool) {
 	return truth, true
 }
 
+// checkContext stops the execution if the context of ExecOptions is done.
+func (s *state) checkContext() {
+	if s.ctx == nil {
+		return
+	}
+	if err := s.ctx.Err(); err != nil {
+		s.errorf("%w", err)
+	}
+}
+
 func (s *state) walkRange(dot reflect.Value, r *parse.RangeNode) {
 	s.at(r)
 	defer func() {
@@ -357,13 +429,30 @@ func (s *state) walkRange(dot reflect.Value, r *parse.RangeNode) {
 	// mark top of stack before any variables in the body are pushed.
 	mark := s.mark()
 	oneIteration := func(index, elem reflect.Value) {
-		// Set top var (lexically the second if there are two) to the element.
+		s.checkContext()
 		if len(r.Pipe.Decl) > 0 {
-			s.setTopVar(1, elem)
+			if r.Pipe.IsAssign {
+				// With two variables, index comes first.
+				// With one, we use the element.
+				if len(r.Pipe.Decl) > 1 {
+					s.setVar(r.Pipe.Decl[0].Ident[0], index)
+				} else {
+					s.setVar(r.Pipe.Decl[0].Ident[0], elem)
+				}
+			} else {
+				// Set top var (lexically the second if there
+				// are two) to the element.
+				s.setTopVar(1, elem)
+			}
 		}
-		// Set next var (lexically the first if there are two) to the index.
 		if len(r.Pipe.Decl) > 1 {
-			s.setTopVar(2, index)
+			if r.Pipe.IsAssign {
+				s.setVar(r.Pipe.Decl[1].Ident[0], elem)
+			} else {
+				// Set next var (lexically the first if there
+				// are two) to the index.
+				s.setTopVar(2, index)
+			}
 		}
 		defer s.pop(mark)
 		defer func() {
@@ -375,6 +464,17 @@ func (s *state) walkRange(dot reflect.Value, r *parse.RangeNode) {
 		s.walk(elem, r.List)
 	}
 	switch val.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if len(r.Pipe.Decl) > 1 {
+			s.errorf("can't use %v to iterate over more than one variable", val)
+			break
+		}
+		// Pass element as second value, as we do for channels.
+		if !rangeInt(val, func(v reflect.Value) { oneIteration(reflect.Value{}, v) }) {
+			break
+		}
+		return
 	case reflect.Array, reflect.Slice:
 		if val.Len() == 0 {
 			break
@@ -412,6 +512,27 @@ func (s *state) walkRange(dot reflect.Value, r *parse.RangeNode) {
 			break
 		}
 		return
+	case reflect.Func:
+		if yieldType, ok := rangeFuncYieldType(val.Type()); ok {
+			if yieldType.NumIn() == 1 && len(r.Pipe.Decl) > 1 {
+				s.errorf("can't use %v iterate over more than one variable", val)
+				break
+			}
+			if !s.rangeFunc(val, yieldType, func(args []reflect.Value) {
+				switch {
+				case len(args) == 2 && len(r.Pipe.Decl) > 1:
+					oneIteration(args[0], args[1])
+				default:
+					// If there is only one range variable,
+					// oneIteration will use the second value.
+					oneIteration(reflect.Value{}, args[0])
+				}
+			}) {
+				break
+			}
+			return
+		}
+		s.errorf("range can't iterate over %v", val)
 	case reflect.Invalid:
 		break // An invalid value is likely a nil map, etc. and acts like an empty map.
 	default:
@@ -422,6 +543,70 @@ func (s *state) walkRange(dot reflect.Value, r *parse.RangeNode) {
 	}
 }
 
+// rangeInt calls fn with each value of val's integer type from 0 up to, but
+// not including, val. It reports whether fn was called.
+func rangeInt(val reflect.Value, fn func(reflect.Value)) bool {
+	switch {
+	case val.CanInt():
+		for i := int64(0); i < val.Int(); i++ {
+			v := reflect.New(val.Type()).Elem()
+			v.SetInt(i)
+			fn(v)
+		}
+		return val.Int() > 0
+	default:
+		for i := uint64(0); i < val.Uint(); i++ {
+			v := reflect.New(val.Type()).Elem()
+			v.SetUint(i)
+			fn(v)
+		}
+		return val.Uint() > 0
+	}
+}
+
+// rangeFuncYieldType reports whether typ is a range function in the sense of
+// iter.Seq or iter.Seq2 and returns the type of its yield function.
+func rangeFuncYieldType(typ reflect.Type) (reflect.Type, bool) {
+	if typ.Kind() != reflect.Func || typ.NumIn() != 1 || typ.NumOut() != 0 || typ.IsVariadic() {
+		return nil, false
+	}
+	yield := typ.In(0)
+	if yield.Kind() != reflect.Func || yield.IsVariadic() || yield.NumOut() != 1 || yield.Out(0).Kind() != reflect.Bool {
+		return nil, false
+	}
+	if yield.NumIn() != 1 && yield.NumIn() != 2 {
+		return nil, false
+	}
+	return yield, true
+}
+
+// rangeFunc calls the range function val with a yield function that passes
+// its arguments to body. A {{break}} in body stops the iteration. It reports
+// whether body was called.
+func (s *state) rangeFunc(val reflect.Value, yieldType reflect.Type, body func(args []reflect.Value)) bool {
+	run, done := false, false
+	yield := reflect.MakeFunc(yieldType, func(args []reflect.Value) []reflect.Value {
+		if done {
+			s.errorf("range function continued iteration after loop exit")
+		}
+		run = true
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					if r != walkBreak {
+						panic(r)
+					}
+					done = true
+				}
+			}()
+			body(args)
+		}()
+		return []reflect.Value{reflect.ValueOf(!done).Convert(yieldType.Out(0))}
+	})
+	val.Call([]reflect.Value{yield})
+	return run
+}
+
 func (s *state) walkTemplate(dot reflect.Value, t *parse.TemplateNode) {
 	s.at(t)
 	tmpl := s.tmpl.Lookup(t.Name)
@@ -431,6 +616,7 @@ func (s *state) walkTemplate(dot reflect.Value, t *parse.TemplateNode) {
 	if s.depth == maxExecDepth {
 		s.errorf("exceeded maximum template depth (%v)", maxExecDepth)
 	}
+	s.checkContext()
 	// Variables declared by the pipeline persist.
 	dot = s.evalPipeline(dot, t.Pipe)
 	newState := *s
@@ -456,10 +642,11 @@ func (s *state) evalPipeline(dot reflect.Value, pipe *parse.PipeNode) (value ref
 	s.at(pipe)
 	value = missingVal
 	for _, cmd := range pipe.Cmds {
+		s.missingKey = ""
 		value = s.evalCommand(dot, cmd, value) // previous value is this one's final arg.
 		// If the object has type interface{}, dig down one level to the thing inside.
 		if value.Kind() == reflect.Interface && value.Type().NumMethod() == 0 {
-			value = reflect.ValueOf(value.Interface()) // lovely!
+			value = value.Elem()
 		}
 	}
 	for _, variable := range pipe.Decl {
@@ -473,7 +660,7 @@ func (s *state) evalPipeline(dot reflect.Value, pipe *parse.PipeNode) (value ref
 }
 
 func (s *state) notAFunction(args []parse.Node, final reflect.Value) {
-	if len(args) > 1 || final != missingVal {
+	if len(args) > 1 || !isMissing(final) {
 		s.errorf("can't give argument to non-function %s", args[0])
 	}
 }
@@ -596,7 +783,10 @@ func (s *state) evalFieldChain(dot, receiver reflect.Value, node parse.Node, ide
 func (s *state) evalFunction(dot reflect.Value, node *parse.IdentifierNode, cmd parse.Node, args []parse.Node, final reflect.Value) reflect.Value {
 	s.at(node)
 	name := node.Ident
-	function, isBuiltin, ok := findFunction(name, s.tmpl)
+	function, isBuiltin, ok := s.funcs[name], false, true
+	if !function.IsValid() {
+		function, isBuiltin, ok = findFunction(name, s.tmpl)
+	}
 	if !ok {
 		s.errorf("%q is not a defined function", name)
 	}
@@ -631,7 +821,7 @@ func (s *state) evalField(dot reflect.Value, fieldName string, node parse.Node,
 	if method := ptr.MethodByName(fieldName); method.IsValid() {
 		return s.evalCall(dot, method, false, node, fieldName, args, final)
 	}
-	hasArgs := len(args) > 1 || final != missingVal
+	hasArgs := len(args) > 1 || !isMissing(final)
 	// It's not a method; must be a field of a struct or an element of a map.
 	switch receiver.Kind() {
 	case reflect.Struct:
@@ -662,6 +852,7 @@ func (s *state) evalField(dot reflect.Value, fieldName string, node parse.Node,
 				switch s.tmpl.option.missingKey {
 				case mapInvalid:
 					// Just use the invalid value.
+					s.missingKey = fieldName
 				case mapZeroValue:
 					result = reflect.Zero(receiver.Type().Elem())
 				case mapError:
@@ -702,7 +893,7 @@ func (s *state) evalCall(dot, fun reflect.Value, isBuiltin bool, node parse.Node
 	}
 	typ := fun.Type()
 	numIn := len(args)
-	if final != missingVal {
+	if !isMissing(final) {
 		numIn++
 	}
 	numFixed := len(args)
@@ -714,9 +905,8 @@ func (s *state) evalCall(dot, fun reflect.Value, isBuiltin bool, node parse.Node
 	} else if numIn != typ.NumIn() {
 		s.errorf("wrong number of args for %s: want %d got %d", name, typ.NumIn(), numIn)
 	}
-	if !goodFunc(typ) {
-		// TODO: This could still be a confusing error; maybe goodFunc should provide info.
-		s.errorf("can't call method/function %q with %d results", name, typ.NumOut())
+	if err := goodFunc(name, typ); err != nil {
+		s.errorf("%v", err)
 	}
 
 	unwrap := func(v reflect.Value) reflect.Value {
@@ -738,7 +928,7 @@ func (s *state) evalCall(dot, fun reflect.Value, isBuiltin bool, node parse.Node
 				return v
 			}
 		}
-		if final != missingVal {
+		if !isMissing(final) {
 			// The last argument to and/or is coming from
 			// the pipeline. We didn't short circuit on an earlier
 			// argument, so we are going to return this one.
@@ -765,7 +955,7 @@ func (s *state) evalCall(dot, fun reflect.Value, isBuiltin bool, node parse.Node
 		}
 	}
 	// Add final value if necessary.
-	if final != missingVal {
+	if !isMissing(final) {
 		t := typ.In(typ.NumIn() - 1)
 		if typ.IsVariadic() {
 			if numIn-1 < numFixed {
@@ -780,6 +970,21 @@ func (s *state) evalCall(dot, fun reflect.Value, isBuiltin bool, node parse.Node
 		}
 		argv[i] = s.validateType(final, t)
 	}
+
+	// Special case for the "call" builtin.
+	// Insert the name of the callee function as the first argument.
+	if isBuiltin && name == "call" {
+		var calleeName string
+		if len(args) == 0 {
+			// final must be present or we would have errored out above.
+			calleeName = final.String()
+		} else {
+			calleeName = args[0].String()
+		}
+		argv = append([]reflect.Value{reflect.ValueOf(calleeName)}, argv...)
+		fun = reflect.ValueOf(call)
+	}
+
 	v, err := safeCall(fun, argv)
 	// If we have an error that is not nil, stop execution and return that
 	// error to the caller.
@@ -1013,18 +1218,85 @@ func indirectInterface(v reflect.Value) reflect.Value {
 // the template.
 func (s *state) printValue(n parse.Node, v reflect.Value) {
 	s.at(n)
-	printableValue := s.tmpl.transformToPrintable.Load()
+	printableValue := s.transform
+	if printableValue == nil {
+		printableValue = s.tmpl.transformToPrintable.Load()
+	}
 	iface, ok := printableValue(v)
 	if !ok {
+		if !v.IsValid() {
+			if s.missingKey != "" {
+				s.errorf("map has no entry for key %q", s.missingKey)
+			}
+			s.errorf("can't print %s: no value", n)
+		}
 		s.errorf("can't print %s of type %s", n, v.Type())
 	}
-	var err error
+	pc := PrintContext{Name: s.tmpl.Name(), Node: n, tmpl: s.tmpl}
+	pw := &printWriter{w: s.wr}
+	printf := s.formatFunc
+	if printf == nil {
+		printf = s.tmpl.formatFunc.Load()
+	}
+	if _, err := safePrint(printf, pw, iface, pc); err != nil {
+		if pw.err != nil && errors.Is(err, pw.err) {
+			s.writeError(err)
+		}
+		s.errorf("%w", &PrintError{
+			Name:     pc.Name,
+			Location: pc.Location(),
+			Type:     reflect.TypeOf(iface),
+			Err:      err,
+		})
+	}
+}
+
+// safePrint calls printf and returns the panic value as an error if it panics,
+// so that a panic while printing one value is reported with the location of
+// its action, as a panic in a function called by the template is.
+func safePrint(printf FormatFunc, w io.Writer, a any, pc PrintContext) (n int, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(error); ok {
+				err = fmt.Errorf("panic: %w", e)
+			} else {
+				err = fmt.Errorf("panic: %v", r)
+			}
+		}
+	}()
+	return printf(w, a, pc)
+}
 
-	printf := s.tmpl.formatFunc.Load()
-	_, err = printf(s.wr, iface)
+// PrintError is wrapped by the ExecError returned when a FormatFunc fails for a
+// reason other than a failure to write to the output, including a panic.
+type PrintError struct {
+	Name     string       // Name of template.
+	Location string       // Location of the action in the template.
+	Type     reflect.Type // Type of the value being printed, or nil.
+	Err      error        // Error returned by the FormatFunc.
+}
+
+func (e *PrintError) Error() string {
+	return fmt.Sprintf("error printing value of type %v: %v", e.Type, e.Err)
+}
+
+func (e *PrintError) Unwrap() error {
+	return e.Err
+}
+
+// printWriter records errors from the underlying writer so they can be told
+// apart from errors produced by the FormatFunc itself.
+type printWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (pw *printWriter) Write(p []byte) (int, error) {
+	n, err := pw.w.Write(p)
 	if err != nil {
-		s.writeError(err)
+		pw.err = err
 	}
+	return n, err
 }
 
 // printableValue returns the, possibly indirected, interface value inside v that
@@ -1051,13 +1323,17 @@ func printableValue(v reflect.Value) (any, bool) {
 }
 
 // printableValueRaw is an alternative to printableValue that performs no
-// transformation of the input before passing it to the printer function.
+// transformation of the input before passing it to the printer function. It
+// can't print invalid values, such as those of missing map keys.
 func printableValueRaw(v reflect.Value) (any, bool) {
+	if !v.IsValid() {
+		return nil, false
+	}
 	return v.Interface(), true
 }
 
 // defaultPrint is the default value printer function.
-func defaultPrint(w io.Writer, a any) (n int, err error) {
+func defaultPrint(w io.Writer, a any, _ PrintContext) (n int, err error) {
 	return fmt.Fprint(w, a)
 }
 
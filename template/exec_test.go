@@ -14,6 +14,7 @@ import (
 	"strings"
 	"sync"
 	"testing"
+	"unsafe"
 )
 
 var debug = flag.Bool("debug", false, "show the errors produced by the tests")
@@ -41,7 +42,8 @@ type T struct {
 	SIEmpty []int
 	SB      []bool
 	// Arrays
-	AI [3]int
+	AI  [3]int
+	PAI *[3]int // pointer to array
 	// Maps
 	MSI      map[string]int
 	MSIone   map[string]int // one element, for deterministic output
@@ -70,17 +72,22 @@ type T struct {
 	Str fmt.Stringer
 	Err error
 	// Pointers
-	PI  *int
-	PS  *string
-	PSI *[]int
-	NIL *int
+	PI       *int
+	PS       *string
+	PSI      *[]int
+	NIL      *int
+	UPI      unsafe.Pointer
+	EmptyUPI unsafe.Pointer
 	// Function (not method)
-	BinaryFunc      func(string, string) string
-	VariadicFunc    func(...string) string
-	VariadicFuncInt func(int, ...string) string
-	NilOKFunc       func(*int) bool
-	ErrFunc         func() (string, error)
-	PanicFunc       func() string
+	BinaryFunc             func(string, string) string
+	VariadicFunc           func(...string) string
+	VariadicFuncInt        func(int, ...string) string
+	NilOKFunc              func(*int) bool
+	ErrFunc                func() (string, error)
+	PanicFunc              func() string
+	TooFewReturnCountFunc  func()
+	TooManyReturnCountFunc func() (string, error, int)
+	InvalidReturnTypeFunc  func() (string, bool)
 	// Template to test evaluation of templates.
 	Tmpl *Template
 	// Unexported field; cannot be accessed by template.
@@ -135,6 +142,7 @@ var tVal = &T{
 	SI:     []int{3, 4, 5},
 	SICap:  make([]int, 5, 10),
 	AI:     [3]int{3, 4, 5},
+	PAI:    &[3]int{3, 4, 5},
 	SB:     []bool{true, false},
 	MSI:    map[string]int{"one": 1, "two": 2, "three": 3},
 	MSIone: map[string]int{"one": 1},
@@ -162,12 +170,16 @@ var tVal = &T{
 	PI:                        newInt(23),
 	PS:                        newString("a string"),
 	PSI:                       newIntSlice(21, 22, 23),
+	UPI:                       newUnsafePointer(23),
 	BinaryFunc:                func(a, b string) string { return fmt.Sprintf("[%s=%s]", a, b) },
 	VariadicFunc:              func(s ...string) string { return fmt.Sprint("<", strings.Join(s, "+"), ">") },
 	VariadicFuncInt:           func(a int, s ...string) string { return fmt.Sprint(a, "=<", strings.Join(s, "+"), ">") },
 	NilOKFunc:                 func(s *int) bool { return s == nil },
 	ErrFunc:                   func() (string, error) { return "bla", nil },
 	PanicFunc:                 func() string { panic("test panic") },
+	TooFewReturnCountFunc:     func() {},
+	TooManyReturnCountFunc:    func() (string, error, int) { return "", nil, 0 },
+	InvalidReturnTypeFunc:     func() (string, bool) { return "", false },
 	Tmpl:                      Must(New("x").Parse("test template")), // "x" is the value of .X
 }
 
@@ -185,6 +197,10 @@ func newInt(n int) *int {
 	return &n
 }
 
+func newUnsafePointer(n int) unsafe.Pointer {
+	return unsafe.Pointer(&n)
+}
+
 func newString(s string) *string {
 	return &s
 }
@@ -393,6 +409,9 @@ var execTests = []execTest{
 	{"Interface Call", `{{stringer .S}}`, "foozle", map[string]any{"S": bytes.NewBufferString("foozle")}, true},
 	{".ErrFunc", "{{call .ErrFunc}}", "bla", tVal, true},
 	{"call nil", "{{call nil}}", "", tVal, false},
+	{"empty call", "{{call}}", "", tVal, false},
+	{"empty call after pipe valid", "{{.ErrFunc | call}}", "bla", tVal, true},
+	{"empty call after pipe invalid", "{{1 | call}}", "", tVal, false},
 
 	// Erroneous function calls (check args).
 	{".BinaryFuncTooFew", "{{call .BinaryFunc `1`}}", "", tVal, false},
@@ -433,6 +452,10 @@ var execTests = []execTest{
 	{"if 0.0", "{{if .FloatZero}}NON-ZERO{{else}}ZERO{{end}}", "ZERO", tVal, true},
 	{"if 1.5i", "{{if 1.5i}}NON-ZERO{{else}}ZERO{{end}}", "NON-ZERO", tVal, true},
 	{"if 0.0i", "{{if .ComplexZero}}NON-ZERO{{else}}ZERO{{end}}", "ZERO", tVal, true},
+	{"if nonNilPointer", "{{if .PI}}NON-ZERO{{else}}ZERO{{end}}", "NON-ZERO", tVal, true},
+	{"if nilPointer", "{{if .NIL}}NON-ZERO{{else}}ZERO{{end}}", "ZERO", tVal, true},
+	{"if UPI", "{{if .UPI}}NON-ZERO{{else}}ZERO{{end}}", "NON-ZERO", tVal, true},
+	{"if EmptyUPI", "{{if .EmptyUPI}}NON-ZERO{{else}}ZERO{{end}}", "ZERO", tVal, true},
 	{"if emptystring", "{{if ``}}NON-EMPTY{{else}}EMPTY{{end}}", "EMPTY", tVal, true},
 	{"if string", "{{if `notempty`}}NON-EMPTY{{else}}EMPTY{{end}}", "NON-EMPTY", tVal, true},
 	{"if emptyslice", "{{if .SIEmpty}}NON-EMPTY{{else}}EMPTY{{end}}", "EMPTY", tVal, true},
@@ -520,6 +543,9 @@ var execTests = []execTest{
 	{"slice[:]", "{{slice .SI}}", "[3 4 5]", tVal, true},
 	{"slice[1:]", "{{slice .SI 1}}", "[4 5]", tVal, true},
 	{"slice[1:2]", "{{slice .SI 1 2}}", "[4]", tVal, true},
+	{"pointer to array[:]", "{{slice .PAI}}", "[3 4 5]", tVal, true},
+	{"pointer to array[1:]", "{{slice .PAI 1}}", "[4 5]", tVal, true},
+	{"pointer to array[1:2]", "{{slice .PAI 1 2}}", "[4]", tVal, true},
 	{"slice[-1:]", "{{slice .SI -1}}", "", tVal, false},
 	{"slice[1:-2]", "{{slice .SI 1 -2}}", "", tVal, false},
 	{"slice[1:2:-1]", "{{slice .SI 1 2 -1}}", "", tVal, false},
@@ -593,6 +619,32 @@ var execTests = []execTest{
 	{"declare in range", "{{range $x := .PSI}}<{{$foo:=$x}}{{$x}}>{{end}}", "<21><22><23>", tVal, true},
 	{"range count", `{{range $i, $x := count 5}}[{{$i}}]{{$x}}{{end}}`, "[0]a[1]b[2]c[3]d[4]e", tVal, true},
 	{"range nil count", `{{range $i, $x := count 0}}{{else}}empty{{end}}`, "empty", tVal, true},
+	{"range iter.Seq[int]", `{{range $i := .}}{{$i}}{{end}}`, "01", fVal1(2), true},
+	{"i = range iter.Seq[int]", `{{$i := 0}}{{range $i = .}}{{$i}}{{end}}`, "01", fVal1(2), true},
+	{"range iter.Seq[int] over two var", `{{range $i, $c := .}}{{$c}}{{end}}`, "", fVal1(2), false},
+	{"i, c := range iter.Seq2[int,int]", `{{range $i, $c := .}}{{$i}}{{$c}}{{end}}`, "0112", fVal2(2), true},
+	{"i, c = range iter.Seq2[int,int]", `{{$i := 0}}{{$c := 0}}{{range $i, $c = .}}{{$i}}{{$c}}{{end}}`, "0112", fVal2(2), true},
+	{"i = range iter.Seq2[int,int]", `{{$i := 0}}{{range $i = .}}{{$i}}{{end}}`, "01", fVal2(2), true},
+	{"i := range iter.Seq2[int,int]", `{{range $i := .}}{{$i}}{{end}}`, "01", fVal2(2), true},
+	{"i,c,x range iter.Seq2[int,int]", `{{$i := 0}}{{$c := 0}}{{$x := 0}}{{range $i, $c = .}}{{$i}}{{$c}}{{end}}`, "0112", fVal2(2), true},
+	{"i,x range iter.Seq[int]", `{{$i := 0}}{{$x := 0}}{{range $i = .}}{{$i}}{{end}}`, "01", fVal1(2), true},
+	{"range iter.Seq[int] else", `{{range $i := .}}{{$i}}{{else}}empty{{end}}`, "empty", fVal1(0), true},
+	{"range iter.Seq2[int,int] else", `{{range $i := .}}{{$i}}{{else}}empty{{end}}`, "empty", fVal2(0), true},
+	{"range iter.Seq[int] break", `{{range $i := .}}{{$i}}{{break}}{{end}}`, "0", fVal1(3), true},
+	{"range iter.Seq[int] continue", `{{range $i := .}}{{$i}}{{continue}}x{{end}}`, "012", fVal1(3), true},
+	{"range int8", rangeTestInt, rangeTestData[int8](), int8(5), true},
+	{"range int16", rangeTestInt, rangeTestData[int16](), int16(5), true},
+	{"range int32", rangeTestInt, rangeTestData[int32](), int32(5), true},
+	{"range int64", rangeTestInt, rangeTestData[int64](), int64(5), true},
+	{"range int", rangeTestInt, rangeTestData[int](), int(5), true},
+	{"range uint8", rangeTestInt, rangeTestData[uint8](), uint8(5), true},
+	{"range uint16", rangeTestInt, rangeTestData[uint16](), uint16(5), true},
+	{"range uint32", rangeTestInt, rangeTestData[uint32](), uint32(5), true},
+	{"range uint64", rangeTestInt, rangeTestData[uint64](), uint64(5), true},
+	{"range uint", rangeTestInt, rangeTestData[uint](), uint(5), true},
+	{"range uintptr", rangeTestInt, rangeTestData[uintptr](), uintptr(5), true},
+	{"range uintptr(0)", `{{range $v := .}}{{print $v}}{{else}}empty{{end}}`, "empty", uintptr(0), true},
+	{"range 5", `{{range $v := 5}}{{printf "%T%d" $v $v}}{{end}}`, rangeTestData[int](), nil, true},
 
 	// Cute examples.
 	{"or as if true", `{{or .SI "slice is empty"}}`, "[3 4 5]", tVal, true},
@@ -692,6 +744,42 @@ var execTests = []execTest{
 	{"bug18a", "{{eq . '.'}}", "true", '.', true},
 	{"bug18b", "{{eq . 'e'}}", "true", 'e', true},
 	{"bug18c", "{{eq . 'P'}}", "true", 'P', true},
+
+	{"issue56490", "{{$i := 0}}{{$x := 0}}{{range $i = .AI}}{{end}}{{$i}}", "5", tVal, true},
+	{"issue60801", "{{$k := 0}}{{$v := 0}}{{range $k, $v = .AI}}{{$k}}={{$v}} {{end}}", "0=3 1=4 2=5 ", tVal, true},
+}
+
+// fVal1 returns an iter.Seq[int] over [0, i).
+func fVal1(i int) func(yield func(int) bool) {
+	return func(yield func(int) bool) {
+		for v := 0; v < i; v++ {
+			if !yield(v) {
+				break
+			}
+		}
+	}
+}
+
+// fVal2 returns an iter.Seq2[int, int] over (v, v+1) for v in [0, i).
+func fVal2(i int) func(yield func(int, int) bool) {
+	return func(yield func(int, int) bool) {
+		for v := 0; v < i; v++ {
+			if !yield(v, v+1) {
+				break
+			}
+		}
+	}
+}
+
+const rangeTestInt = `{{range $v := .}}{{printf "%T%d" $v $v}}{{end}}`
+
+func rangeTestData[T int | int8 | int16 | int32 | int64 | uint | uint8 | uint16 | uint32 | uint64 | uintptr]() string {
+	I := T(5)
+	var buf strings.Builder
+	for i := T(0); i < I; i++ {
+		fmt.Fprintf(&buf, "%T%d", i, i)
+	}
+	return buf.String()
 }
 
 func zeroArgs() string {
@@ -939,6 +1027,108 @@ func TestExecError_CustomError(t *testing.T) {
 	}
 }
 
+// Check that a custom printer is told where the printed value came from.
+func TestPrinterContext(t *testing.T) {
+	tmpl := Must(New("top").Parse("line 1\n{{template \"sub\" .}}\n{{define \"sub\"}}x {{.}}{{end}}"))
+	var got []string
+	tmpl.Printer(true, func(w io.Writer, a any, pc PrintContext) (int, error) {
+		got = append(got, fmt.Sprintf("%s %s %d", pc.Name, pc.Location(), pc.Pos()))
+		return fmt.Fprint(w, a)
+	})
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, 7); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := b.String(), "line 1\nx 7\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+	want := []string{"sub top:3:20 48"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("printer contexts = %q, want %q", got, want)
+	}
+}
+
+// Check that a printer error is reported as an ExecError, while a failure to
+// write the output is returned as is.
+func TestPrinterError(t *testing.T) {
+	printErr := errors.New("unprintable")
+	tmpl := Must(New("top").Parse("a\n  {{.}}"))
+	tmpl.Printer(true, func(w io.Writer, a any, pc PrintContext) (int, error) {
+		return 0, printErr
+	})
+	err := tmpl.Execute(io.Discard, 3)
+	var execErr ExecError
+	if !errors.As(err, &execErr) {
+		t.Fatalf("expected ExecError; got %v", err)
+	}
+	var pe *PrintError
+	if !errors.As(err, &pe) {
+		t.Fatalf("expected PrintError; got %v", err)
+	}
+	if pe.Location != "top:2:4" || pe.Type != reflect.TypeOf(0) || !errors.Is(err, printErr) {
+		t.Errorf("unexpected PrintError %+v", pe)
+	}
+	const want = `template: top:2:4: executing "top" at <{{.}}>: error printing value of type int: unprintable`
+	if got := err.Error(); got != want {
+		t.Errorf("expected\n%q\ngot\n%q", want, got)
+	}
+
+	tmpl = Must(New("top").Parse("{{.}}"))
+	err = tmpl.Execute(ErrorWriter(0), 3)
+	if err == nil || errors.As(err, &execErr) {
+		t.Errorf("expected a plain write error; got %v", err)
+	}
+}
+
+// Check that a panicking printer is reported as a PrintError at the location
+// of the action.
+func TestPrinterPanic(t *testing.T) {
+	panicErr := errors.New("unprintable")
+	tmpl := Must(New("top").Parse("a\n  {{.x}}"))
+	tmpl.Printer(true, func(w io.Writer, a any, pc PrintContext) (int, error) {
+		panic(panicErr)
+	})
+	err := tmpl.Execute(io.Discard, map[string]int{"x": 3})
+	var pe *PrintError
+	if !errors.As(err, &pe) || pe.Location != "top:2:4" || !errors.Is(err, panicErr) {
+		t.Fatalf("expected a PrintError wrapping the panic; got %v", err)
+	}
+	const want = `template: top:2:4: executing "top" at <{{.x}}>: error printing value of type int: panic: unprintable`
+	if got := err.Error(); got != want {
+		t.Errorf("expected\n%q\ngot\n%q", want, got)
+	}
+}
+
+// Check that ExecuteWith overrides the printer and functions for a single
+// execution without modifying the template.
+func TestExecuteWith(t *testing.T) {
+	tmpl := Must(New("top").Funcs(FuncMap{"name": func() string { return "top" }}).Parse(`{{name}} {{.}}`))
+	var b bytes.Buffer
+	err := tmpl.ExecuteWith(&b, 3, &ExecOptions{
+		Printer: func(w io.Writer, a any, pc PrintContext) (int, error) {
+			return fmt.Fprintf(w, "<%v>", a)
+		},
+		Funcs: FuncMap{"name": func() string { return "override" }},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := b.String(), "<override> <3>"; got != want {
+		t.Errorf("ExecuteWith output = %q, want %q", got, want)
+	}
+	b.Reset()
+	if err := tmpl.Execute(&b, 3); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := b.String(), "top 3"; got != want {
+		t.Errorf("Execute output = %q, want %q", got, want)
+	}
+	err = tmpl.ExecuteWith(&b, 3, &ExecOptions{Funcs: FuncMap{"name": 3}})
+	if err == nil || !strings.Contains(err.Error(), "not a function") {
+		t.Errorf("expected error for invalid function; got %v", err)
+	}
+}
+
 func TestJSEscaping(t *testing.T) {
 	testCases := []struct {
 		in, exp string
@@ -947,7 +1137,7 @@ func TestJSEscaping(t *testing.T) {
 		{`'foo`, `\'foo`},
 		{`Go "jump" \`, `Go \"jump\" \\`},
 		{`Yukihiro says "今日は世界"`, `Yukihiro says \"今日は世界\"`},
-		{"unprintable \uFDFF", `unprintable \uFDFF`},
+		{"unprintable \uFFFE", `unprintable \uFFFE`},
 		{`<html>`, `\u003Chtml\u003E`},
 		{`no = in attributes`, `no \u003D in attributes`},
 		{`&#x27; does not become HTML entity`, `\u0026#x27; does not become HTML entity`},
@@ -1333,6 +1523,37 @@ func TestMissingMapKey(t *testing.T) {
 	}
 }
 
+func TestMissingMapKeyRawPrinter(t *testing.T) {
+	data := map[string]any{"x": 99, "nil": nil}
+	opts := &ExecOptions{Printer: func(w io.Writer, a any, _ PrintContext) (int, error) {
+		return fmt.Fprint(w, a)
+	}}
+	tests := []struct {
+		text    string
+		want    string
+		wantErr string
+	}{
+		{"{{.x}}{{if .y}}y{{end}}", "99", ""},
+		{"{{.x}} {{.y}}", "", `map has no entry for key "y"`},
+		{"{{(.y)}}", "", `map has no entry for key "y"`},
+		{"{{.nil}}", "", "can't print {{.nil}}: no value"},
+	}
+	for _, tt := range tests {
+		tmpl := Must(New("t").Parse(tt.text))
+		var b bytes.Buffer
+		err := tmpl.ExecuteWith(&b, data, opts)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: got error %v, want %q", tt.text, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || b.String() != tt.want {
+			t.Errorf("%s: got %q, %v; want %q", tt.text, b.String(), err, tt.want)
+		}
+	}
+}
+
 // Test that the error message for multiline unterminated string
 // refers to the line number of the opening quote.
 func TestUnterminatedStringError(t *testing.T) {
@@ -1532,6 +1753,44 @@ func TestEvalFieldErrors(t *testing.T) {
 	}
 }
 
+func TestIsTrue(t *testing.T) {
+	var nil_ptr *int
+	var nil_chan chan int
+	tests := []struct {
+		v    any
+		want bool
+	}{
+		{1, true},
+		{0, false},
+		{uint8(1), true},
+		{uint8(0), false},
+		{float64(1.0), true},
+		{float64(0.0), false},
+		{complex64(1.0), true},
+		{complex64(0.0), false},
+		{true, true},
+		{false, false},
+		{[2]int{1, 2}, true},
+		{[0]int{}, false},
+		{[]byte("abc"), true},
+		{[]byte(""), false},
+		{map[string]int{"a": 1, "b": 2}, true},
+		{map[string]int{}, false},
+		{make(chan int), true},
+		{nil_chan, false},
+		{new(int), true},
+		{nil_ptr, false},
+		{unsafe.Pointer(new(int)), true},
+		{unsafe.Pointer(nil_ptr), false},
+	}
+	for _, test_case := range tests {
+		got, _ := IsTrue(test_case.v)
+		if got != test_case.want {
+			t.Fatalf("expect result %v, got %v", test_case.want, got)
+		}
+	}
+}
+
 func TestMaxExecDepth(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping in -short mode")
@@ -1794,6 +2053,80 @@ func TestIssue39807(t *testing.T) {
 	wg.Wait()
 }
 
+func TestFunctionCheckDuringCall(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		data    any
+		wantErr string
+	}{{
+		name:    "call nothing",
+		input:   `{{call}}`,
+		data:    tVal,
+		wantErr: "wrong number of args for call: want at least 1 got 0",
+	},
+		{
+			name:    "call non-function",
+			input:   "{{call .True}}",
+			data:    tVal,
+			wantErr: "error calling call: non-function .True of type bool",
+		},
+		{
+			name:    "call func with wrong argument",
+			input:   "{{call .BinaryFunc 1}}",
+			data:    tVal,
+			wantErr: "error calling call: wrong number of args for .BinaryFunc: got 1 want 2",
+		},
+		{
+			name:    "call variadic func with wrong argument",
+			input:   `{{call .VariadicFuncInt}}`,
+			data:    tVal,
+			wantErr: "error calling call: wrong number of args for .VariadicFuncInt: got 0 want at least 1",
+		},
+		{
+			name:    "call too few return number func",
+			input:   `{{call .TooFewReturnCountFunc}}`,
+			data:    tVal,
+			wantErr: "error calling call: function .TooFewReturnCountFunc has 0 return values; should be 1 or 2",
+		},
+		{
+			name:    "call too many return number func",
+			input:   `{{call .TooManyReturnCountFunc}}`,
+			data:    tVal,
+			wantErr: "error calling call: function .TooManyReturnCountFunc has 3 return values; should be 1 or 2",
+		},
+		{
+			name:    "call invalid return type func",
+			input:   `{{call .InvalidReturnTypeFunc}}`,
+			data:    tVal,
+			wantErr: "error calling call: invalid function signature for .InvalidReturnTypeFunc: second return value should be error; is bool",
+		},
+		{
+			name:    "call pipeline",
+			input:   `{{call (len "test")}}`,
+			data:    nil,
+			wantErr: "error calling call: non-function len \"test\" of type int",
+		},
+	}
+
+	for _, tc := range tests {
+		b := new(bytes.Buffer)
+		tmpl, err := New("t").Parse(tc.input)
+		if err != nil {
+			t.Fatalf("parse error: %s", err)
+		}
+		err = tmpl.Execute(b, tc.data)
+		if err == nil {
+			t.Errorf("%s: expected error; got none", tc.name)
+		} else if tc.wantErr == "" || !strings.Contains(err.Error(), tc.wantErr) {
+			if *debug {
+				fmt.Printf("%s: test execute error: %s\n", tc.name, err)
+			}
+			t.Errorf("%s: expected error:\n%s\ngot:\n%s", tc.name, tc.wantErr, err)
+		}
+	}
+}
+
 // Issue 48215: embedded nil pointer causes panic.
 // Fixed by adding FieldByIndexErr to the reflect package.
 func TestIssue48215(t *testing.T) {
@@ -11,8 +11,37 @@ import (
 	"text/template/parse"
 )
 
-// FormatFunc is used to print values.
-type FormatFunc func(w io.Writer, a any) (n int, err error)
+// FormatFunc is used to print values. The PrintContext argument identifies the
+// action in the template that produced the value.
+type FormatFunc func(w io.Writer, a any, pc PrintContext) (n int, err error)
+
+// PrintContext describes where in a template a value being printed came from.
+type PrintContext struct {
+	// Name is the name of the template being executed.
+	Name string
+	// Node is the action whose value is being printed.
+	Node parse.Node
+
+	tmpl *Template
+}
+
+// Pos returns the byte offset of the action within the template text.
+func (pc PrintContext) Pos() parse.Pos {
+	if pc.Node == nil {
+		return 0
+	}
+	return pc.Node.Position()
+}
+
+// Location returns a "name:line:col" description of the action, as used in
+// error messages.
+func (pc PrintContext) Location() string {
+	if pc.tmpl == nil || pc.tmpl.Tree == nil || pc.Node == nil {
+		return pc.Name
+	}
+	location, _ := pc.tmpl.ErrorContext(pc.Node)
+	return location
+}
 
 // common holds the information shared by related templates.
 type common struct {
@@ -30,8 +59,8 @@ type common struct {
 }
 
 // Template is the representation of a parsed template. The *parse.Tree
-// field is exported only for use by html/template and should be treated
-// as unexported by all other clients.
+// field is exported so that tools may analyze parsed templates; it should not
+// be modified after the template has been parsed.
 type Template struct {
 	name string
 	*parse.Tree
@@ -97,6 +126,7 @@ func (t *Template) Clone() (*Template, error) {
 	if t.common == nil {
 		return nt, nil
 	}
+	nt.option = t.option
 	t.muTmpl.RLock()
 	defer t.muTmpl.RUnlock()
 	for k, v := range t.tmpl {
@@ -177,11 +207,13 @@ func (t *Template) Delims(left, right string) *Template {
 }
 
 // Funcs adds the elements of the argument map to the template's function map.
-// It must be called before the template is parsed.
+// Any function used in the template must be added before the template is
+// parsed. Funcs may be called more than once, including after parsing (for
+// example, after Clone), to replace a function of the same name;
+// the replacement is used when the template is executed.
 // It panics if a value in the map is not a function with appropriate return
 // type or if the name cannot be used syntactically as a function in a template.
-// It is legal to overwrite elements of the map. The return value is the template,
-// so calls can be chained.
+// The return value is the template, so calls can be chained.
 func (t *Template) Funcs(funcMap FuncMap) *Template {
 	t.init()
 	t.muFuncs.Lock()
@@ -249,8 +281,8 @@ func (t *Template) associate(new *Template, tree *parse.Tree) bool {
 // the value to the output of the template.
 //
 // If transformFirst is true, the default transformation of the value to be
-// printed is performed before
-func (t *Template) Printer(transformFirst bool, print func(w io.Writer, a any) (n int, err error)) *Template {
+// printed is performed before print is called.
+func (t *Template) Printer(transformFirst bool, print FormatFunc) *Template {
 	if !transformFirst {
 		t.transformToPrintable.Store(printableValueRaw)
 	}
@@ -0,0 +1,133 @@
+package typeinfo
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/printer"
+	"go/token"
+	"strconv"
+
+	"github.com/meta-programming/go-codegenutil"
+	cb "github.com/meta-programming/go-codegenutil/codebuilder"
+)
+
+// FieldsFromSyntax returns the fields of a struct type from its syntax alone,
+// for generators that work without type-checking. The struct type must belong
+// to file, whose positions are recorded in fset.
+//
+// Qualified identifiers in the types of the fields are resolved using the
+// imports of file, so that the types are qualified according to the imports
+// of the file they are generated into. Unqualified identifiers are kept as
+// they are. The Var field of the returned fields is nil.
+func FieldsFromSyntax(fset *token.FileSet, file *ast.File, st *ast.StructType) ([]*FieldInfo, error) {
+	imports := map[string]*codegenutil.Package{}
+	for _, spec := range file.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			return nil, err
+		}
+		pkg := codegenutil.AssumedPackageName(path)
+		name := pkg.Name()
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		if name != "_" && name != "." {
+			imports[name] = pkg
+		}
+	}
+
+	var fields []*FieldInfo
+	for _, field := range st.Fields.List {
+		typ, err := syntaxType(fset, field.Type, imports)
+		if err != nil {
+			return nil, err
+		}
+		var tag string
+		if field.Tag != nil {
+			if tag, err = strconv.Unquote(field.Tag.Value); err != nil {
+				return nil, fmt.Errorf("%s: invalid tag: %w", fset.Position(field.Tag.Pos()), err)
+			}
+		}
+		info := FieldInfo{Type: typ, Tag: tag, Tags: parseTag(tag), Doc: fieldDoc(field)}
+		if len(field.Names) == 0 {
+			id := embeddedIdent(field.Type)
+			if id == nil {
+				return nil, fmt.Errorf("%s: invalid embedded field", fset.Position(field.Pos()))
+			}
+			info.Name, info.Exported, info.Embedded = id.Name, id.IsExported(), true
+			fields = append(fields, &info)
+			continue
+		}
+		for _, name := range field.Names {
+			f := info
+			f.Name, f.Exported = name.Name, name.IsExported()
+			fields = append(fields, &f)
+		}
+	}
+	return fields, nil
+}
+
+// syntaxType returns Code for a type expression whose package references are
+// resolved using imports.
+func syntaxType(fset *token.FileSet, typ ast.Expr, imports map[string]*codegenutil.Package) (cb.Code, error) {
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, fset, typ); err != nil {
+		return nil, err
+	}
+	return cb.ParseSnippet(buf.String(), imports)
+}
+
+// parseTag returns the key-value pairs of a struct tag in the conventional
+// format described by reflect.StructTag. Parsing stops at the first malformed
+// pair, as it does in reflect.StructTag.Lookup.
+func parseTag(tag string) map[string]string {
+	var pairs map[string]string
+	for tag != "" {
+		// Skip leading space.
+		i := 0
+		for i < len(tag) && tag[i] == ' ' {
+			i++
+		}
+		tag = tag[i:]
+		if tag == "" {
+			break
+		}
+
+		// Scan to colon. A space, a quote or a control character is a
+		// syntax error.
+		i = 0
+		for i < len(tag) && tag[i] > ' ' && tag[i] != ':' && tag[i] != '"' && tag[i] != 0x7f {
+			i++
+		}
+		if i == 0 || i+1 >= len(tag) || tag[i] != ':' || tag[i+1] != '"' {
+			break
+		}
+		key := tag[:i]
+		tag = tag[i+1:]
+
+		// Scan quoted string to find value.
+		i = 1
+		for i < len(tag) && tag[i] != '"' {
+			if tag[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(tag) {
+			break
+		}
+		value, err := strconv.Unquote(tag[:i+1])
+		if err != nil {
+			break
+		}
+		tag = tag[i+1:]
+		if pairs == nil {
+			pairs = map[string]string{}
+		}
+		if _, ok := pairs[key]; !ok {
+			pairs[key] = value
+		}
+	}
+	return pairs
+}
@@ -0,0 +1,93 @@
+package typeinfo
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/meta-programming/go-codegenutil"
+	"github.com/meta-programming/go-codegenutil/internal/typestest"
+)
+
+const fieldsSrc = `package mypkg
+
+import (
+	js "encoding/json"
+	"time"
+)
+
+type Event struct {
+	// ID identifies the event.
+	ID, Parent int ` + "`json:\"id\" db:\"event_id\"`" + `
+	*time.Timer // fires the event
+	payload js.RawMessage
+	When map[string][]time.Time
+}
+`
+
+func describeFields(fields []*FieldInfo) string {
+	imports := codegenutil.NewFileImports(codegenutil.AssumedPackageName("abc.xyz/other"),
+		codegenutil.WithImports(codegenutil.AssumedPackageName("other/json")))
+	var lines []string
+	for _, f := range fields {
+		lines = append(lines, fmt.Sprintf("%s %s tags=%v exported=%v embedded=%v doc=%q",
+			f.Name, f.Type.GoCode(imports), f.Tags, f.Exported, f.Embedded, f.Doc))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestFields(t *testing.T) {
+	want := `ID int tags=map[db:event_id json:id] exported=true embedded=false doc="ID identifies the event."
+Parent int tags=map[db:event_id json:id] exported=true embedded=false doc="ID identifies the event."
+Timer *time.Timer tags=map[] exported=true embedded=true doc="fires the event"
+payload json2.RawMessage tags=map[] exported=false embedded=false doc=""
+When map[string][]time.Time tags=map[] exported=true embedded=false doc=""`
+
+	t.Run("types", func(t *testing.T) {
+		pkg, _, file := typestest.CheckInfo(t, "abc.xyz/mypkg", fieldsSrc)
+		typ, err := Lookup(pkg, "Event", WithSyntax(file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := describeFields(typ.Fields); got != want {
+			t.Errorf("Fields got:\n%s\nwant:\n%s", got, want)
+		}
+	})
+
+	t.Run("syntax", func(t *testing.T) {
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, "input.go", fieldsSrc, parser.ParseComments)
+		if err != nil {
+			t.Fatal(err)
+		}
+		st := file.Decls[1].(*ast.GenDecl).Specs[0].(*ast.TypeSpec).Type.(*ast.StructType)
+		fields, err := FieldsFromSyntax(fset, file, st)
+		if err != nil {
+			t.Fatalf("FieldsFromSyntax() error: %v", err)
+		}
+		if got := describeFields(fields); got != want {
+			t.Errorf("FieldsFromSyntax() got:\n%s\nwant:\n%s", got, want)
+		}
+	})
+}
+
+func TestParseTag(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want map[string]string
+	}{
+		{``, nil},
+		{`json:"a,omitempty"  xml:"b"`, map[string]string{"json": "a,omitempty", "xml": "b"}},
+		{`a:"\"quoted\"" a:"ignored"`, map[string]string{"a": `"quoted"`}},
+		{`a:"1" malformed b:"2"`, map[string]string{"a": "1"}},
+	}
+	for _, tt := range tests {
+		if got := parseTag(tt.tag); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseTag(%q) = %v, want %v", tt.tag, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package typeinfo
+
+import (
+	"fmt"
+	"go/types"
+	"strings"
+	"testing"
+
+	"github.com/meta-programming/go-codegenutil"
+	"github.com/meta-programming/go-codegenutil/internal/typestest"
+)
+
+const methodsSrc = `package mypkg
+
+import "io"
+
+// Closer closes things.
+type Closer interface {
+	// Close closes the thing.
+	Close() error
+}
+
+type Store interface {
+	io.Reader
+	Closer
+
+	// Put stores values under a key.
+	Put(key string, values ...[]byte) (n int, err error)
+}
+
+type base struct{}
+
+func (*base) Close() error { return nil }
+
+type File struct {
+	*base
+}
+
+func (f File) Name() string { return "" }
+
+type Constraint[T interface{ Get() T }] struct{}
+`
+
+func describeMethods(methods []*MethodInfo) string {
+	imports := codegenutil.NewFileImports(codegenutil.AssumedPackageName("abc.xyz/other"))
+	var lines []string
+	for _, m := range methods {
+		from := "-"
+		if m.EmbeddedFrom != nil {
+			from = m.EmbeddedFrom.GoCode(imports)
+		}
+		var params []string
+		for _, p := range m.Params {
+			params = append(params, p.GoCode(imports))
+		}
+		lines = append(lines, fmt.Sprintf("%s %s params=[%s] results=%d from=%s doc=%q",
+			m.Name, m.Signature.GoCode(imports), strings.Join(params, ", "), len(m.Results), from, m.Doc))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestMethods(t *testing.T) {
+	pkg, _, file := typestest.CheckInfo(t, "abc.xyz/mypkg", methodsSrc)
+	tests := []struct {
+		typeName string
+		want     string
+	}{
+		{
+			typeName: "Store",
+			want: `Close func() error params=[] results=1 from=mypkg.Closer doc="Close closes the thing."
+Put func(key string, values ...[]byte) (n int, err error) params=[key string, values ...[]byte] results=2 from=- doc="Put stores values under a key."
+Read func(p []byte) (n int, err error) params=[p []byte] results=2 from=io.Reader doc=""`,
+		},
+		{
+			typeName: "File",
+			want: `Close func() error params=[] results=1 from=mypkg.base doc=""
+Name func() string params=[] results=1 from=- doc=""`,
+		},
+	}
+	for _, tt := range tests {
+		typ, err := Lookup(pkg, tt.typeName, WithSyntax(file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := describeMethods(typ.Methods); got != tt.want {
+			t.Errorf("%s methods got:\n%s\nwant:\n%s", tt.typeName, got, tt.want)
+		}
+	}
+
+	constraint := pkg.Scope().Lookup("Constraint").Type().(*types.Named).TypeParams().At(0).Constraint()
+	if got, want := describeMethods(MethodsOf(constraint.Underlying().(*types.Interface))), `Get func() T params=[] results=1 from=- doc=""`; got != want {
+		t.Errorf("MethodsOf() got:\n%s\nwant:\n%s", got, want)
+	}
+}
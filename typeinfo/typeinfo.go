@@ -0,0 +1,375 @@
+// Package typeinfo extracts views of type-checked Go code that are convenient
+// inputs for generators.
+//
+// Generators driven by go/types tend to start with the same code: find a named
+// type, switch on its underlying type, and collect its fields or methods along
+// with their types as Go code. The Type, FieldInfo and MethodInfo types of this
+// package hold the result of that extraction. Their fields are plain values and
+// codebuilder.Code, so they can be passed to codetemplate templates as data or
+// used with the codebuilder package directly.
+package typeinfo
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+
+	"github.com/meta-programming/go-codegenutil"
+	cb "github.com/meta-programming/go-codegenutil/codebuilder"
+)
+
+// Option customizes the extraction of type information.
+type Option struct {
+	apply func(*config)
+}
+
+type config struct {
+	files []*ast.File
+	// docs maps the positions of field and method names to their doc
+	// comments. It is computed from files when first needed.
+	docs map[token.Pos]string
+}
+
+// WithSyntax returns an option that provides the syntax of the files that
+// were type-checked, which is used to extract doc comments.
+func WithSyntax(files ...*ast.File) Option {
+	return Option{func(c *config) { c.files = append(c.files, files...) }}
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt.apply(c)
+	}
+	return c
+}
+
+// doc returns the doc comment of the field or method declared at pos, if the
+// syntax of the files is available.
+func (c *config) doc(pos token.Pos) string {
+	if c.docs == nil {
+		c.docs = map[token.Pos]string{}
+		for _, f := range c.files {
+			ast.Inspect(f, func(n ast.Node) bool {
+				if field, ok := n.(*ast.Field); ok {
+					for _, id := range fieldIdents(field) {
+						c.docs[id.Pos()] = fieldDoc(field)
+					}
+				}
+				return true
+			})
+		}
+	}
+	return c.docs[pos]
+}
+
+// fieldIdents returns the identifiers that name the objects declared by a
+// field: its names, or the type name of an embedded field.
+func fieldIdents(field *ast.Field) []*ast.Ident {
+	if len(field.Names) > 0 {
+		return field.Names
+	}
+	if id := embeddedIdent(field.Type); id != nil {
+		return []*ast.Ident{id}
+	}
+	return nil
+}
+
+// embeddedIdent returns the identifier that names an embedded field of the
+// given type, or nil if the type can't be embedded.
+func embeddedIdent(typ ast.Expr) *ast.Ident {
+	switch t := typ.(type) {
+	case *ast.Ident:
+		return t
+	case *ast.StarExpr:
+		return embeddedIdent(t.X)
+	case *ast.SelectorExpr:
+		return t.Sel
+	case *ast.IndexExpr:
+		return embeddedIdent(t.X)
+	case *ast.IndexListExpr:
+		return embeddedIdent(t.X)
+	case *ast.ParenExpr:
+		return embeddedIdent(t.X)
+	}
+	return nil
+}
+
+// fieldDoc returns the doc comment of a field, or its line comment if it has
+// no doc comment.
+func fieldDoc(field *ast.Field) string {
+	doc := field.Doc.Text()
+	if doc == "" {
+		doc = field.Comment.Text()
+	}
+	return strings.TrimSuffix(doc, "\n")
+}
+
+// Kind is the kind of the underlying type of a type, such as "struct".
+type Kind string
+
+// Kinds of types.
+const (
+	Basic     Kind = "basic"
+	Struct    Kind = "struct"
+	Interface Kind = "interface"
+	Pointer   Kind = "pointer"
+	Slice     Kind = "slice"
+	Array     Kind = "array"
+	Map       Kind = "map"
+	Chan      Kind = "chan"
+	Func      Kind = "func"
+	TypeParam Kind = "typeparam"
+)
+
+// KindOf returns the kind of the underlying type of t.
+func KindOf(t types.Type) Kind {
+	if _, ok := t.(*types.TypeParam); ok {
+		return TypeParam
+	}
+	switch t.Underlying().(type) {
+	case *types.Struct:
+		return Struct
+	case *types.Interface:
+		return Interface
+	case *types.Pointer:
+		return Pointer
+	case *types.Slice:
+		return Slice
+	case *types.Array:
+		return Array
+	case *types.Map:
+		return Map
+	case *types.Chan:
+		return Chan
+	case *types.Signature:
+		return Func
+	}
+	return Basic
+}
+
+// Type describes a named type.
+type Type struct {
+	// Name is the name of the type.
+	Name string
+	// Symbol is the symbol of the type.
+	Symbol *codegenutil.Symbol
+	// Kind is the kind of the type's underlying type.
+	Kind Kind
+	// TypeParams are the type parameters of a generic type.
+	TypeParams []*cb.Param
+	// Underlying is the underlying type.
+	Underlying cb.Code
+	// Fields are the fields of a struct type, in declaration order.
+	Fields []*FieldInfo
+	// Methods are the methods of an interface type, or the method set of a
+	// pointer to any other type, sorted by name. Both include the methods
+	// of embedded types.
+	Methods []*MethodInfo
+	// Object is the object of the type.
+	Object *types.TypeName
+}
+
+// FieldInfo describes a field of a struct type.
+type FieldInfo struct {
+	// Name is the name of the field. The name of an embedded field is the
+	// name of its type.
+	Name string
+	// Type is the type of the field.
+	Type cb.Code
+	// Tag is the field's tag, without quotes.
+	Tag string
+	// Tags are the key-value pairs of the field's tag, which is assumed to
+	// follow the conventional format described by reflect.StructTag.
+	Tags map[string]string
+	// Doc is the doc comment of the field, or its line comment if it has no
+	// doc comment. It is empty if the syntax of the field is not available.
+	Doc string
+	// Exported reports whether the field is exported.
+	Exported bool
+	// Embedded reports whether the field is an embedded field.
+	Embedded bool
+	// Var is the object of the field. It is nil for fields extracted from
+	// syntax alone.
+	Var *types.Var
+}
+
+// MethodInfo describes a method of a type.
+type MethodInfo struct {
+	// Name is the name of the method.
+	Name string
+	// Signature is the type of the method without its receiver, such as
+	// "func(p []byte) (n int, err error)".
+	Signature cb.Code
+	// Params and Results are the parameters and results of the method. The
+	// last parameter of a variadic method is variadic.
+	Params, Results []*cb.Param
+	// Doc is the doc comment of the method, or its line comment if it has no
+	// doc comment. It is empty if the syntax of the method is not available.
+	Doc string
+	// EmbeddedFrom is the named type that declares the method if the method
+	// is promoted from an embedded interface or struct field, or nil if the
+	// method is declared by the type itself or by an unnamed interface.
+	EmbeddedFrom *codegenutil.Symbol
+	// PointerReceiver reports whether the method is only in the method set of
+	// a pointer to the type. It is always false for interface methods.
+	PointerReceiver bool
+	// Func is the object of the method.
+	Func *types.Func
+}
+
+// Lookup returns the description of the named type with the given name in pkg.
+func Lookup(pkg *types.Package, name string, opts ...Option) (*Type, error) {
+	obj, ok := pkg.Scope().Lookup(name).(*types.TypeName)
+	if !ok {
+		return nil, fmt.Errorf("no type named %q in package %q", name, pkg.Path())
+	}
+	if obj.IsAlias() {
+		return nil, fmt.Errorf("%s.%s is an alias", pkg.Path(), name)
+	}
+	return NewType(obj, opts...), nil
+}
+
+// Types returns the descriptions of the package-level named types of pkg,
+// sorted by name. Aliases are omitted.
+func Types(pkg *types.Package, opts ...Option) []*Type {
+	c := newConfig(opts)
+	var out []*Type
+	for _, name := range pkg.Scope().Names() {
+		if obj, ok := pkg.Scope().Lookup(name).(*types.TypeName); ok && !obj.IsAlias() {
+			out = append(out, newType(c, obj))
+		}
+	}
+	return out
+}
+
+// FromInfo returns the descriptions of the named types defined in the
+// type-checked code that info was recorded for, including types declared
+// inside of functions, in the order they are declared. Aliases and type
+// parameters are omitted. Info.Defs must have been recorded.
+func FromInfo(info *types.Info, opts ...Option) []*Type {
+	c := newConfig(opts)
+	var objs []*types.TypeName
+	for _, obj := range info.Defs {
+		obj, ok := obj.(*types.TypeName)
+		if !ok || obj.IsAlias() {
+			continue
+		}
+		if _, ok := obj.Type().(*types.TypeParam); !ok {
+			objs = append(objs, obj)
+		}
+	}
+	sort.Slice(objs, func(i, j int) bool { return objs[i].Pos() < objs[j].Pos() })
+	var out []*Type
+	for _, obj := range objs {
+		out = append(out, newType(c, obj))
+	}
+	return out
+}
+
+// NewType returns the description of the named type declared by obj.
+func NewType(obj *types.TypeName, opts ...Option) *Type {
+	return newType(newConfig(opts), obj)
+}
+
+func newType(c *config, obj *types.TypeName) *Type {
+	t := &Type{
+		Name:       obj.Name(),
+		Symbol:     cb.SymbolOf(obj),
+		Kind:       KindOf(obj.Type()),
+		Underlying: cb.TypeOf(obj.Type().Underlying()),
+		Object:     obj,
+	}
+	named, _ := obj.Type().(*types.Named)
+	if named != nil {
+		t.TypeParams = cb.TypeParamsOf(named.TypeParams())
+	}
+	switch u := obj.Type().Underlying().(type) {
+	case *types.Struct:
+		t.Fields = structFields(c, u)
+	case *types.Interface:
+		t.Methods = interfaceMethods(c, u, obj)
+	}
+	if t.Kind != Interface && named != nil {
+		t.Methods = methodSet(c, named)
+	}
+	return t
+}
+
+// MethodsOf returns the methods of an interface, including the methods of
+// embedded interfaces, sorted by name. It is useful for interfaces that are not
+// named types, such as those in type parameter constraints.
+func MethodsOf(iface *types.Interface, opts ...Option) []*MethodInfo {
+	return interfaceMethods(newConfig(opts), iface, nil)
+}
+
+func interfaceMethods(c *config, iface *types.Interface, owner *types.TypeName) []*MethodInfo {
+	var methods []*MethodInfo
+	for i := 0; i < iface.NumMethods(); i++ {
+		methods = append(methods, newMethodInfo(c, iface.Method(i), owner, false))
+	}
+	return methods
+}
+
+func structFields(c *config, st *types.Struct) []*FieldInfo {
+	var fields []*FieldInfo
+	for i := 0; i < st.NumFields(); i++ {
+		v := st.Field(i)
+		fields = append(fields, &FieldInfo{
+			Name:     v.Name(),
+			Type:     cb.TypeOf(v.Type()),
+			Tag:      st.Tag(i),
+			Tags:     parseTag(st.Tag(i)),
+			Doc:      c.doc(v.Pos()),
+			Exported: v.Exported(),
+			Embedded: v.Embedded(),
+			Var:      v,
+		})
+	}
+	return fields
+}
+
+// methodSet returns the methods of the method set of *named, including
+// promoted methods.
+func methodSet(c *config, named *types.Named) []*MethodInfo {
+	valueMethods := map[string]bool{}
+	valueSet := types.NewMethodSet(named)
+	for i := 0; i < valueSet.Len(); i++ {
+		valueMethods[valueSet.At(i).Obj().Name()] = true
+	}
+	var methods []*MethodInfo
+	ptrSet := types.NewMethodSet(types.NewPointer(named))
+	for i := 0; i < ptrSet.Len(); i++ {
+		fn := ptrSet.At(i).Obj().(*types.Func)
+		methods = append(methods, newMethodInfo(c, fn, named.Obj(), !valueMethods[fn.Name()]))
+	}
+	return methods
+}
+
+// newMethodInfo returns the description of a method of the type declared by
+// owner, which is nil for unnamed interfaces.
+func newMethodInfo(c *config, fn *types.Func, owner *types.TypeName, pointerReceiver bool) *MethodInfo {
+	sig := fn.Type().(*types.Signature)
+	m := &MethodInfo{
+		Name:            fn.Name(),
+		Signature:       cb.SignatureOf(sig, false),
+		Params:          cb.ParamsOf(sig.Params(), sig.Variadic()),
+		Results:         cb.ParamsOf(sig.Results(), false),
+		Doc:             c.doc(fn.Pos()),
+		PointerReceiver: pointerReceiver,
+		Func:            fn,
+	}
+	if recv := sig.Recv(); recv != nil {
+		recvType := recv.Type()
+		if ptr, ok := recvType.(*types.Pointer); ok {
+			recvType = ptr.Elem()
+		}
+		if declaring, ok := recvType.(*types.Named); ok && declaring.Obj() != owner {
+			m.EmbeddedFrom = cb.SymbolOf(declaring.Obj())
+		}
+	}
+	return m
+}
@@ -0,0 +1,124 @@
+package typeinfo
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/meta-programming/go-codegenutil"
+	"github.com/meta-programming/go-codegenutil/internal/typestest"
+)
+
+const src = `package mypkg
+
+import (
+	"io"
+	"sync"
+)
+
+type Base struct{ ID int }
+
+func (b Base) Key() int { return b.ID }
+
+type Record struct {
+	Base
+	Name  string ` + "`json:\"name\"`" + `
+	mu    sync.Mutex
+	Items []io.Reader
+}
+
+func (r *Record) Lock() { r.mu.Lock() }
+
+type Store[K comparable, V any] map[K]V
+
+type ReadCloser interface {
+	io.Reader
+	Close() error
+}
+
+func f() {
+	type local int
+}
+`
+
+func describe(t *Type) string {
+	imports := codegenutil.NewFileImports(codegenutil.AssumedPackageName("abc.xyz/mypkg"))
+	var lines []string
+	var params []string
+	for _, p := range t.TypeParams {
+		params = append(params, p.GoCode(imports))
+	}
+	lines = append(lines, fmt.Sprintf("%s %s [%s] %s", t.Name, t.Kind, strings.Join(params, ", "), t.Underlying.GoCode(imports)))
+	for _, f := range t.Fields {
+		lines = append(lines, fmt.Sprintf("  field %s %s tag=%q exported=%v embedded=%v", f.Name, f.Type.GoCode(imports), f.Tag, f.Exported, f.Embedded))
+	}
+	for _, m := range t.Methods {
+		lines = append(lines, fmt.Sprintf("  method %s pointer=%v", m.Name, m.PointerReceiver))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestLookup(t *testing.T) {
+	pkg := typestest.Check(t, "abc.xyz/mypkg", src)
+	tests := []struct {
+		name    string
+		want    string
+		wantErr string
+	}{
+		{
+			name: "Record",
+			want: `Record struct [] struct{Base; Name string "json:\"name\""; mu sync.Mutex; Items []io.Reader}
+  field Base Base tag="" exported=true embedded=true
+  field Name string tag="json:\"name\"" exported=true embedded=false
+  field mu sync.Mutex tag="" exported=false embedded=false
+  field Items []io.Reader tag="" exported=true embedded=false
+  method Key pointer=false
+  method Lock pointer=true`,
+		},
+		{
+			name: "Store",
+			want: "Store map [K comparable, V any] map[K]V",
+		},
+		{
+			name: "ReadCloser",
+			want: `ReadCloser interface [] interface{Close() error; io.Reader}
+  method Close pointer=false
+  method Read pointer=false`,
+		},
+		{
+			name:    "missing",
+			wantErr: `no type named "missing" in package "abc.xyz/mypkg"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			typ, err := Lookup(pkg, tt.name)
+			if err != nil {
+				if tt.wantErr == "" || err.Error() != tt.wantErr {
+					t.Fatalf("Lookup() got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if got := describe(typ); got != tt.want {
+				t.Errorf("Lookup() got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTypesAndFromInfo(t *testing.T) {
+	pkg, info, _ := typestest.CheckInfo(t, "abc.xyz/mypkg", src)
+	names := func(types []*Type) string {
+		var out []string
+		for _, t := range types {
+			out = append(out, t.Name+":"+string(t.Kind))
+		}
+		return strings.Join(out, " ")
+	}
+	if got, want := names(Types(pkg)), "Base:struct ReadCloser:interface Record:struct Store:map"; got != want {
+		t.Errorf("Types() = %q, want %q", got, want)
+	}
+	if got, want := names(FromInfo(info)), "Base:struct Record:struct Store:map ReadCloser:interface local:basic"; got != want {
+		t.Errorf("FromInfo() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,198 @@
+package unusedimports
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/build"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/meta-programming/go-codegenutil"
+)
+
+// DirOption customizes PruneDir.
+type DirOption struct {
+	apply func(*dirConfig)
+}
+
+type dirConfig struct {
+	dryRun       bool
+	simplify     bool
+	buildContext *build.Context
+	logger       codegenutil.Logger
+}
+
+// DryRun returns an option that makes PruneDir report the files it would
+// change without writing them.
+func DryRun() DirOption {
+	return DirOption{func(c *dirConfig) { c.dryRun = true }}
+}
+
+// SimplifyFiles returns an option that makes PruneDir also apply the
+// simplifications of "gofmt -s" to the files. See SimplifyUnparsed.
+func SimplifyFiles() DirOption {
+	return DirOption{func(c *dirConfig) { c.simplify = true }}
+}
+
+// WithBuildContext returns an option that makes PruneDir only prune the files
+// that ctxt would build, according to their names, such as foo_windows.go, and
+// their //go:build constraints. The other files are reported as skipped. By
+// default, every .go file is pruned whatever its build constraints, which
+// pruning keeps.
+func WithBuildContext(ctxt *build.Context) DirOption {
+	return DirOption{func(c *dirConfig) { c.buildContext = ctxt }}
+}
+
+// WithLogger returns an option that makes PruneDir log, at the debug level,
+// the imports it removes and the files it skips, leaves unchanged or writes.
+func WithLogger(l codegenutil.Logger) DirOption {
+	return DirOption{func(c *dirConfig) { c.logger = l }}
+}
+
+// FileResult is the result of pruning a file with PruneDir.
+type FileResult struct {
+	// Path is the path of the file: dir joined with its path relative to dir.
+	Path string
+	// Removed contains the import paths of the imports that were removed,
+	// sorted.
+	Removed []string
+	// Changed reports whether the content of the file changed, or would
+	// change with DryRun. Files are formatted like gofmt does when they
+	// change, so only a file that was neither pruned nor simplified is left
+	// as it is.
+	Changed bool
+	// Skipped reports whether the file was excluded by the build context set
+	// by WithBuildContext.
+	Skipped bool
+	// Err is the error of reading, parsing or writing the file, if any.
+	Err error
+}
+
+// PruneDir removes the unused imports of the .go files in the directory tree
+// rooted at dir, which is meant as a single cleanup pass over the files of a
+// generator that writes a whole package tree, and returns the result for each
+// file, ordered by path. Directories named testdata or vendor and those whose
+// names start with "." or "_" are skipped, as are files whose names start with
+// "." or "_".
+//
+// An error with a single file is reported in its FileResult, and the other
+// files are still pruned. PruneDir only returns an error if dir can't be
+// walked or ctx is done, along with the results of the files pruned so far.
+func PruneDir(ctx context.Context, dir string, opts ...DirOption) ([]*FileResult, error) {
+	cfg := &dirConfig{}
+	for _, opt := range opts {
+		opt.apply(cfg)
+	}
+
+	var results []*FileResult
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		base := d.Name()
+		if d.IsDir() {
+			if path != dir && (base == "testdata" || base == "vendor" || strings.HasPrefix(base, ".") || strings.HasPrefix(base, "_")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(base, ".go") || strings.HasPrefix(base, ".") || strings.HasPrefix(base, "_") {
+			return nil
+		}
+		result := pruneFile(cfg, path)
+		if cfg.logger != nil {
+			logResult(cfg.logger, result, cfg.dryRun)
+		}
+		results = append(results, result)
+		return nil
+	})
+	return results, err
+}
+
+// logResult logs the result of pruning a file.
+func logResult(l codegenutil.Logger, r *FileResult, dryRun bool) {
+	for _, importPath := range r.Removed {
+		l.Debug("import removed", "file", r.Path, "path", importPath)
+	}
+	switch {
+	case r.Err != nil:
+		l.Debug("file failed", "file", r.Path, "error", r.Err)
+	case r.Skipped:
+		l.Debug("file skipped by build constraints", "file", r.Path)
+	case !r.Changed:
+		l.Debug("file skipped unchanged", "file", r.Path)
+	case dryRun:
+		l.Debug("file would change", "file", r.Path)
+	default:
+		l.Debug("file written", "file", r.Path)
+	}
+}
+
+// pruneFile prunes the file at path according to cfg.
+func pruneFile(cfg *dirConfig, path string) *FileResult {
+	result := &FileResult{Path: path}
+	if cfg.buildContext != nil {
+		match, err := cfg.buildContext.MatchFile(filepath.Dir(path), filepath.Base(path))
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		if !match {
+			result.Skipped = true
+			return result
+		}
+	}
+
+	src, err := os.ReadFile(path)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, path, src, parseMode)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	if result.Removed, err = pruneAlreadyParsed(fset, f); err != nil {
+		result.Err = fmt.Errorf("%s: %w", path, err)
+		return result
+	}
+	if cfg.simplify {
+		simplify(f)
+	}
+	if len(result.Removed) == 0 && !cfg.simplify {
+		return result
+	}
+
+	out := &bytes.Buffer{}
+	if err := format.Node(out, fset, f); err != nil {
+		result.Err = fmt.Errorf("%s: %w", path, err)
+		return result
+	}
+	formattedSrc, err := format.Source(src)
+	if err != nil {
+		result.Err = fmt.Errorf("%s: %w", path, err)
+		return result
+	}
+	result.Changed = !bytes.Equal(formattedSrc, out.Bytes())
+	if !result.Changed || cfg.dryRun {
+		return result
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.Err = os.WriteFile(path, out.Bytes(), info.Mode().Perm())
+	return result
+}
@@ -0,0 +1,133 @@
+package unusedimports
+
+import (
+	"context"
+	"fmt"
+	"go/build"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/meta-programming/go-codegenutil/internal/logtest"
+)
+
+func TestPruneDir(t *testing.T) {
+	const unused = "package p\n\nimport (\n\t\"fmt\"\n\t\"os\"\n)\n\nvar _ = fmt.Sprint\n"
+	const pruned = "package p\n\nimport (\n\t\"fmt\"\n)\n\nvar _ = fmt.Sprint\n"
+	files := map[string]string{
+		"a.go":               unused,
+		"b_windows.go":       unused,
+		"sub/c.go":           "//go:build ignore\n\n" + unused,
+		"sub/d.go":           "package p\n\nvar _ = []struct{}{struct{}{}}\n",
+		"sub/bad.go":         "package p\n\nvar x = )\n",
+		"testdata/e.go":      unused,
+		"_skipped/f.go":      unused,
+		"sub/not_go.txt":     unused,
+		"sub/clean.go":       pruned,
+		"sub/deeper/main.go": "package main\n\nimport \"os\"\n",
+	}
+	for _, tt := range []struct {
+		name  string
+		opts  []DirOption
+		want  []string
+		files map[string]string
+	}{
+		{
+			name: "default",
+			want: []string{"a.go changed [os]", "b_windows.go changed [os]", "sub/bad.go error", "sub/c.go changed [os]", "sub/clean.go", "sub/d.go", "sub/deeper/main.go changed [os]"},
+			files: map[string]string{
+				"a.go":     pruned,
+				"sub/c.go": "//go:build ignore\n\n" + pruned,
+				"sub/d.go": files["sub/d.go"],
+			},
+		},
+		{
+			name: "dry run with simplification and a build context",
+			opts: []DirOption{DryRun(), SimplifyFiles(), WithBuildContext(&build.Context{GOOS: "linux", GOARCH: "amd64", Compiler: "gc"})},
+			want: []string{"a.go changed [os]", "b_windows.go skipped", "sub/bad.go error", "sub/c.go skipped", "sub/clean.go", "sub/d.go changed", "sub/deeper/main.go changed [os]"},
+			files: map[string]string{
+				"a.go":     unused,
+				"sub/d.go": files["sub/d.go"],
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range files {
+				name = filepath.Join(dir, filepath.FromSlash(name))
+				if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			results, err := PruneDir(context.Background(), dir, tt.opts...)
+			if err != nil {
+				t.Fatalf("PruneDir() error = %v", err)
+			}
+			var got []string
+			for _, r := range results {
+				rel, _ := filepath.Rel(dir, r.Path)
+				desc := filepath.ToSlash(rel)
+				switch {
+				case r.Err != nil:
+					desc += " error"
+				case r.Skipped:
+					desc += " skipped"
+				case r.Changed:
+					desc += " changed"
+				}
+				if len(r.Removed) > 0 {
+					desc += fmt.Sprint(" ", r.Removed)
+				}
+				got = append(got, desc)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("PruneDir() results:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+			for name, want := range tt.files {
+				content, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(content) != want {
+					t.Errorf("%s contains:\n%s\nwant:\n%s", name, content, want)
+				}
+			}
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := PruneDir(ctx, t.TempDir()); err != context.Canceled {
+		t.Errorf("PruneDir() with a canceled context error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestPruneDir_logger(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"a.go":         "package p\n\nimport (\n\t\"fmt\"\n\t\"os\"\n)\n\nvar _ = fmt.Sprint\n",
+		"b.go":         "package p\n",
+		"c_windows.go": "package p\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	logger := &logtest.Recorder{}
+	if _, err := PruneDir(context.Background(), dir, WithBuildContext(&build.Context{GOOS: "linux", GOARCH: "amd64", Compiler: "gc"}), WithLogger(logger)); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"import removed file=" + filepath.Join(dir, "a.go") + " path=os",
+		"file written file=" + filepath.Join(dir, "a.go"),
+		"file skipped unchanged file=" + filepath.Join(dir, "b.go"),
+		"file skipped by build constraints file=" + filepath.Join(dir, "c_windows.go"),
+	}
+	if got := logger.Events(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("logged events:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
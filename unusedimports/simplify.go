@@ -0,0 +1,179 @@
+package unusedimports
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"github.com/meta-programming/go-codegenutil/debugutil"
+)
+
+// SimplifyUnparsed parses a Go file and applies the simplifications of
+// "gofmt -s" to it, which generators often need because they emit the verbose
+// forms of code:
+//
+//   - composite literal types that can be elided, as in []T{T{}} and
+//     []*T{&T{}}, which become []T{{}} and []*T{{}};
+//   - slice expressions of the form s[a:len(s)], which become s[a:];
+//   - blank range variables, as in "for x, _ = range v" and "for _ = range v",
+//     which become "for x = range v" and "for range v";
+//   - empty declaration groups, such as the "import ()" of a file without
+//     imports.
+//
+// The filename argument is used only for printing error messages.
+func SimplifyUnparsed(filename, src string) (string, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, src, parseMode)
+	if err != nil {
+		return "", fmt.Errorf("parse error: %w\n%s", err, debugutil.WithLineNumbers(src))
+	}
+	simplify(f)
+	out := &strings.Builder{}
+	printer.Fprint(out, fset, f)
+	return out.String(), nil
+}
+
+// PruneAndSimplifyUnparsed is like PruneUnparsed, but also simplifies the file
+// as SimplifyUnparsed does, parsing it only once.
+func PruneAndSimplifyUnparsed(filename, src string) (string, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, src, parseMode)
+	if err != nil {
+		return "", fmt.Errorf("parse error: %w\n%s", err, debugutil.WithLineNumbers(src))
+	}
+	if _, err := pruneAlreadyParsed(fset, f); err != nil {
+		return "", err
+	}
+	simplify(f)
+	out := &strings.Builder{}
+	printer.Fprint(out, fset, f)
+	return out.String(), nil
+}
+
+// simplify applies the simplifications of "gofmt -s" to f. It is adapted from
+// cmd/gofmt/simplify.go.
+func simplify(f *ast.File) {
+	removeEmptyDeclGroups(f)
+	ast.Walk(simplifier{}, f)
+}
+
+type simplifier struct{}
+
+func (s simplifier) Visit(node ast.Node) ast.Visitor {
+	switch n := node.(type) {
+	case *ast.CompositeLit:
+		// Array, slice and map literals may be simplified.
+		var keyType, eltType ast.Expr
+		switch typ := n.Type.(type) {
+		case *ast.ArrayType:
+			eltType = typ.Elt
+		case *ast.MapType:
+			keyType = typ.Key
+			eltType = typ.Value
+		}
+		if eltType == nil {
+			break
+		}
+		for i, x := range n.Elts {
+			px := &n.Elts[i]
+			if kv, ok := x.(*ast.KeyValueExpr); ok {
+				if keyType != nil {
+					s.simplifyLiteral(keyType, kv.Key, &kv.Key)
+				}
+				x = kv.Value
+				px = &kv.Value
+			}
+			s.simplifyLiteral(eltType, x, px)
+		}
+		// The elements were walked by simplifyLiteral.
+		return nil
+
+	case *ast.SliceExpr:
+		// s[a:len(s)] is s[a:] if s is an identifier, unless len is
+		// redeclared, which generated code is very unlikely to do. 3-index
+		// slices always require their indices.
+		if n.Max != nil {
+			break
+		}
+		x, _ := n.X.(*ast.Ident)
+		call, _ := n.High.(*ast.CallExpr)
+		if x == nil || call == nil || len(call.Args) != 1 || call.Ellipsis.IsValid() {
+			break
+		}
+		if fun, _ := call.Fun.(*ast.Ident); fun == nil || fun.Name != "len" {
+			break
+		}
+		if arg, _ := call.Args[0].(*ast.Ident); arg != nil && arg.Name == x.Name {
+			n.High = nil
+		}
+
+	case *ast.RangeStmt:
+		if isBlank(n.Value) {
+			n.Value = nil
+		}
+		if isBlank(n.Key) && n.Value == nil {
+			n.Key = nil
+		}
+	}
+	return s
+}
+
+// simplifyLiteral simplifies x, an element of a composite literal whose
+// element type is typ, which px points to.
+func (s simplifier) simplifyLiteral(typ, x ast.Expr, px *ast.Expr) {
+	ast.Walk(s, x)
+
+	// T{} is {} in a literal of T elements.
+	if inner, ok := x.(*ast.CompositeLit); ok && sameType(typ, inner.Type) {
+		inner.Type = nil
+	}
+	// &T{} is {} in a literal of *T elements.
+	if ptr, ok := typ.(*ast.StarExpr); ok {
+		if addr, ok := x.(*ast.UnaryExpr); ok && addr.Op == token.AND {
+			if inner, ok := addr.X.(*ast.CompositeLit); ok && sameType(ptr.X, inner.Type) {
+				inner.Type = nil
+				*px = inner
+			}
+		}
+	}
+}
+
+// sameType reports whether the type expressions a and b are written the same
+// way.
+func sameType(a, b ast.Expr) bool {
+	return a != nil && b != nil && types.ExprString(a) == types.ExprString(b)
+}
+
+func isBlank(x ast.Expr) bool {
+	ident, ok := x.(*ast.Ident)
+	return ok && ident.Name == "_"
+}
+
+// removeEmptyDeclGroups removes the declaration groups without specs or
+// comments, such as "import ()".
+func removeEmptyDeclGroups(f *ast.File) {
+	i := 0
+	for _, d := range f.Decls {
+		if g, ok := d.(*ast.GenDecl); !ok || !isEmpty(f, g) {
+			f.Decls[i] = d
+			i++
+		}
+	}
+	f.Decls = f.Decls[:i]
+}
+
+func isEmpty(f *ast.File, g *ast.GenDecl) bool {
+	if g.Doc != nil || g.Specs != nil {
+		return false
+	}
+	for _, c := range f.Comments {
+		if g.Pos() <= c.Pos() && c.End() <= g.End() {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,84 @@
+package unusedimports
+
+import (
+	"testing"
+
+	"github.com/meta-programming/go-codegenutil/debugutil"
+)
+
+func TestSimplifyUnparsed(t *testing.T) {
+	src := `package foo
+
+import ()
+
+type T struct{ x int }
+
+var ts = []T{T{1}, T{x: 2}}
+var ptrs = []*T{&T{1}, &T{}}
+var m = map[T][]T{T{1}: []T{T{2}}}
+var other = []interface{}{T{1}}
+
+func f(s []int) {
+	_ = s[1:len(s)]
+	_ = s[1:len(s):len(s)]
+	for i, _ := range s {
+		_ = i
+	}
+	for _ = range s {
+	}
+}
+`
+	want := `package foo
+
+type T struct{ x int }
+
+var ts = []T{{1}, {x: 2}}
+var ptrs = []*T{{1}, {}}
+var m = map[T][]T{{1}: {{2}}}
+var other = []interface{}{T{1}}
+
+func f(s []int) {
+	_ = s[1:]
+	_ = s[1:len(s):len(s)]
+	for i := range s {
+		_ = i
+	}
+	for range s {
+	}
+}
+`
+	got, err := SimplifyUnparsed("foo.go", src)
+	if err != nil {
+		t.Fatalf("SimplifyUnparsed() error = %v", err)
+	}
+	if got != want {
+		t.Errorf("SimplifyUnparsed() generated unexpected output (want|got):\n%s", debugutil.SideBySide(want, got))
+	}
+}
+
+func TestPruneAndSimplifyUnparsed(t *testing.T) {
+	src := `package foo
+
+import (
+	"fmt"
+	"os"
+)
+
+var args = [][]string{[]string{os.Args[0]}}
+`
+	want := `package foo
+
+import (
+	"os"
+)
+
+var args = [][]string{{os.Args[0]}}
+`
+	got, err := PruneAndSimplifyUnparsed("foo.go", src)
+	if err != nil {
+		t.Fatalf("PruneAndSimplifyUnparsed() error = %v", err)
+	}
+	if got != want {
+		t.Errorf("PruneAndSimplifyUnparsed() generated unexpected output (want|got):\n%s", debugutil.SideBySide(want, got))
+	}
+}
@@ -1,5 +1,6 @@
 // Package unusedimports provides the import pruning features of the `goimports`
-// command in library form. Intended usage is for code generator libraries.
+// command in library form, as well as the simplifications of `gofmt -s`.
+// Intended usage is for code generator libraries.
 package unusedimports
 
 import (
@@ -8,6 +9,8 @@ import (
 	"go/parser"
 	"go/printer"
 	"go/token"
+	"io"
+	"sort"
 	"strings"
 
 	"github.com/meta-programming/go-codegenutil"
@@ -28,7 +31,7 @@ func PruneUnparsed(filename, src string) (string, error) {
 		return "", fmt.Errorf("parse error: %w\n%s", err, debugutil.WithLineNumbers(src))
 	}
 
-	if err := pruneAlreadyParsed(fset, f); err != nil {
+	if _, err := pruneAlreadyParsed(fset, f); err != nil {
 		return "", err
 	}
 
@@ -38,8 +41,36 @@ func PruneUnparsed(filename, src string) (string, error) {
 	return out.String(), nil
 }
 
-// pruneAlreadyParsed modifies fset by removing unused imports.
-func pruneAlreadyParsed(fset *token.FileSet, file *ast.File) error {
+// Prune is like PruneUnparsed, but it reads the Go file from src and writes the
+// result to dst. It is meant for very large generated files: the file is read
+// into memory once and not copied, and the output is written to dst rather than
+// built in memory. Parse errors only show the lines around each error rather
+// than the whole file.
+//
+// The filename argument is used only for printing error messages.
+func Prune(filename string, dst io.Writer, src io.Reader) error {
+	content, err := io.ReadAll(src)
+	if err != nil {
+		return err
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, content, parseMode)
+	if err != nil {
+		return fmt.Errorf("parse error: %w\n%s", err, debugutil.AnnotateError(string(content), err))
+	}
+	// The AST doesn't refer to content, which can be garbage collected while
+	// printing.
+	content = nil
+
+	if _, err := pruneAlreadyParsed(fset, f); err != nil {
+		return err
+	}
+	return printer.Fprint(dst, fset, f)
+}
+
+// pruneAlreadyParsed modifies file by removing unused imports and returns
+// their import paths, sorted.
+func pruneAlreadyParsed(fset *token.FileSet, file *ast.File) ([]string, error) {
 
 	refs := collectReferences(file)
 	imports := collectImports(file)
@@ -64,13 +95,15 @@ func pruneAlreadyParsed(fset *token.FileSet, file *ast.File) error {
 		}
 	}
 
+	var removed []string
 	for _, fix := range fixes {
 		if deleted := astutil.DeleteNamedImport(fset, file, fix.StmtInfo.Name, fix.StmtInfo.ImportPath); !deleted {
-			return fmt.Errorf("tried to delete import %s and failed", fix.StmtInfo)
+			return nil, fmt.Errorf("tried to delete import %s and failed", fix.StmtInfo)
 		}
+		removed = append(removed, fix.StmtInfo.ImportPath)
 	}
-
-	return nil
+	sort.Strings(removed)
+	return removed, nil
 }
 
 type visitFn func(node ast.Node) ast.Visitor
@@ -3,6 +3,8 @@
 package unusedimports
 
 import (
+	"bytes"
+	"strings"
 	"testing"
 
 	"github.com/meta-programming/go-codegenutil/debugutil"
@@ -96,6 +98,28 @@ func foo() {
 			if got != tt.want {
 				t.Errorf("PruneUnparsed() generated unexpected output (want|got):\n%s", debugutil.SideBySide(tt.want, got))
 			}
+
+			out := &bytes.Buffer{}
+			if err := Prune(tt.filename, out, strings.NewReader(tt.src)); err != nil {
+				t.Fatalf("Prune() error = %v", err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("Prune() generated unexpected output (want|got):\n%s", debugutil.SideBySide(tt.want, out.String()))
+			}
 		})
 	}
 }
+
+func TestPrune_parseError(t *testing.T) {
+	src := "package foo\n\n" + strings.Repeat("var _ = 1\n", 100) + "var y = )\n"
+	err := Prune("foo.go", &bytes.Buffer{}, strings.NewReader(src))
+	if err == nil {
+		t.Fatal("Prune() succeeded, want a parse error")
+	}
+	if !strings.Contains(err.Error(), "103: var y = )") {
+		t.Errorf("Prune() error = %v, want it to show the line of the error", err)
+	}
+	if strings.Count(err.Error(), "var _ = 1") > 2 {
+		t.Errorf("Prune() error = %v, want it to only show the lines around the error", err)
+	}
+}
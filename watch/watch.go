@@ -0,0 +1,133 @@
+// Package watch re-runs generation tasks when their input files change, for a
+// fast edit-generate loop while working on templates and their data.
+//
+// Each Task lists its input files. Watch waits for changes to those files and
+// runs the tasks whose inputs changed. Changes are debounced: a task runs once
+// the files have been quiet for a short while, so that editors that write a
+// file in several steps, or a save of several files at once, trigger a single
+// run.
+package watch
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultDebounce is the time Watch waits for further changes before running
+// tasks, unless WithDebounce is used.
+const DefaultDebounce = 100 * time.Millisecond
+
+// Task is a unit of work that is run again when any of its inputs changes.
+type Task struct {
+	// Name identifies the task, for example in the messages of a command.
+	Name string
+	// Inputs are the paths of the files that the task reads.
+	Inputs []string
+	// Run performs the task.
+	Run func(ctx context.Context) error
+}
+
+// Option customizes Watch.
+type Option struct {
+	apply func(*config)
+}
+
+type config struct {
+	debounce time.Duration
+	onRun    func(task *Task, err error)
+}
+
+// WithDebounce returns an option that sets how long Watch waits after a change
+// for further changes before running the affected tasks.
+func WithDebounce(d time.Duration) Option {
+	return Option{func(c *config) { c.debounce = d }}
+}
+
+// OnRun returns an option that makes Watch call fn after each run of a task
+// with the error returned by the task, which is nil if it succeeded. Watch
+// keeps watching when a task fails, so this is the only way to learn about
+// failures.
+func OnRun(fn func(task *Task, err error)) Option {
+	return Option{func(c *config) { c.onRun = fn }}
+}
+
+// Watch watches the inputs of the tasks and runs the tasks whose inputs change,
+// until ctx is done or watching fails. The tasks are not run when Watch starts.
+// When several tasks are affected by changes, they are run one at a time, in
+// the order they are given.
+//
+// The directories that contain the inputs are watched rather than the files
+// themselves, so that files replaced by editors that save by renaming a new
+// file over the old one are still watched.
+func Watch(ctx context.Context, tasks []*Task, opts ...Option) error {
+	c := &config{debounce: DefaultDebounce}
+	for _, opt := range opts {
+		opt.apply(c)
+	}
+
+	byInput := map[string][]*Task{}
+	dirs := map[string]bool{}
+	for _, t := range tasks {
+		for _, input := range t.Inputs {
+			abs, err := filepath.Abs(input)
+			if err != nil {
+				return err
+			}
+			byInput[abs] = append(byInput[abs], t)
+			dirs[filepath.Dir(abs)] = true
+		}
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+	var sortedDirs []string
+	for dir := range dirs {
+		sortedDirs = append(sortedDirs, dir)
+	}
+	sort.Strings(sortedDirs)
+	for _, dir := range sortedDirs {
+		if err := watcher.Add(dir); err != nil {
+			return err
+		}
+	}
+
+	pending := map[*Task]bool{}
+	var quiet <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-watcher.Errors:
+			return err
+		case event := <-watcher.Events:
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+			affected := byInput[filepath.Clean(event.Name)]
+			for _, t := range affected {
+				pending[t] = true
+			}
+			if len(affected) > 0 {
+				quiet = time.After(c.debounce)
+			}
+		case <-quiet:
+			quiet = nil
+			for _, t := range tasks {
+				if !pending[t] {
+					continue
+				}
+				delete(pending, t)
+				err := t.Run(ctx)
+				if c.onRun != nil {
+					c.onRun(t, err)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	tmpl := filepath.Join(dir, "a.tmpl")
+	data := filepath.Join(dir, "data.json")
+	other := filepath.Join(dir, "other.tmpl")
+	for _, name := range []string{tmpl, data, other} {
+		if err := os.WriteFile(name, []byte("initial"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ran := make(chan string, 10)
+	task := func(name string, inputs ...string) *Task {
+		return &Task{Name: name, Inputs: inputs, Run: func(context.Context) error {
+			ran <- name
+			if name == "failing" {
+				return errors.New("boom")
+			}
+			return nil
+		}}
+	}
+	tasks := []*Task{task("a", tmpl, data), task("failing", other)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	var runErrs []error
+	go func() {
+		done <- Watch(ctx, tasks, WithDebounce(10*time.Millisecond), OnRun(func(task *Task, err error) {
+			runErrs = append(runErrs, err)
+		}))
+	}()
+
+	// The watcher may not be ready yet, so write until the task runs.
+	waitFor := func(want string, write string) {
+		t.Helper()
+		deadline := time.After(10 * time.Second)
+		tick := time.NewTicker(100 * time.Millisecond)
+		defer tick.Stop()
+		for {
+			if err := os.WriteFile(write, []byte(time.Now().String()), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			select {
+			case got := <-ran:
+				// Earlier writes may still cause runs of other tasks.
+				if got == want {
+					return
+				}
+			case <-tick.C:
+			case <-deadline:
+				t.Fatalf("task %q didn't run after writing %s", want, filepath.Base(write))
+			}
+		}
+	}
+	waitFor("a", data)
+	waitFor("failing", other)
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Watch() got error %v, want context.Canceled", err)
+	}
+	if n := len(runErrs); n < 2 || runErrs[n-1] == nil {
+		t.Errorf("OnRun got errors %v, want the failing task's error last", runErrs)
+	}
+}